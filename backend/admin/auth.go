@@ -7,13 +7,14 @@ import (
 	"fmt"
 	"log"
 
+	userstore "github.com/kubestellar/ui/backend/auth"
 	"github.com/kubestellar/ui/backend/models"
 	"github.com/kubestellar/ui/backend/utils"
 )
 
 // InitializeAdminUser creates default admin user if no users exist
 func InitializeAdminUser() error {
-	users, err := models.ListAllUsers()
+	users, err := userstore.Users.ListUsers()
 	if err != nil {
 		return fmt.Errorf("failed to check existing users: %v", err)
 	}
@@ -21,22 +22,21 @@ func InitializeAdminUser() error {
 	if len(users) == 0 {
 		log.Println("No users found, creating default admin user...")
 
-		adminUser, err := models.CreateUser("admin", "admin", true)
-		if err != nil {
-			return fmt.Errorf("failed to create admin user: %v", err)
-		}
-
 		// Set admin permissions for all components
-		adminPermissions := []models.Permission{
-			{Component: "users", Permission: "write"},
-			{Component: "resources", Permission: "write"},
-			{Component: "system", Permission: "write"},
-			{Component: "dashboard", Permission: "write"},
+		adminPermissions := make(map[string]string, len(userstore.Components))
+		for _, component := range userstore.Components {
+			adminPermissions[component] = "write"
 		}
 
-		err = models.SetUserPermissions(adminUser.ID, adminPermissions)
+		_, err := userstore.Users.CreateUser(userstore.NewUser{
+			Username:           "admin",
+			Password:           "admin",
+			IsAdmin:            true,
+			Permissions:        adminPermissions,
+			MustChangePassword: true,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to set admin permissions: %v", err)
+			return fmt.Errorf("failed to create admin user: %v", err)
 		}
 
 		log.Printf("Default admin user created successfully with username: admin, password: admin")
@@ -54,45 +54,34 @@ func AddOrUpdateUser(username, password string, permissions map[string]string) e
 
 	// Validate password if provided
 	if password != "" {
-		if err := utils.ValidatePassword(password); err != nil {
+		if err := utils.ValidatePassword(username, password); err != nil {
 			return fmt.Errorf("invalid password: %v", err)
 		}
 	}
 
-	existingUser, err := models.GetUserByUsername(username)
+	existingUser, err := userstore.Users.GetUser(username)
 	if err != nil {
 		return err
 	}
 
-	var userID int
 	if existingUser == nil {
 		// Create new user
-		newUser, err := models.CreateUser(username, password, false)
-		if err != nil {
-			return err
-		}
-		userID = newUser.ID
-	} else {
-		// Update existing user
-		userID = existingUser.ID
-		if password != "" {
-			err = models.UpdateUserPassword(userID, password)
-			if err != nil {
-				return err
-			}
-		}
+		_, err := userstore.Users.CreateUser(userstore.NewUser{
+			Username:    username,
+			Password:    password,
+			Permissions: permissions,
+		})
+		return err
 	}
 
-	// Convert permissions map to slice
-	var permSlice []models.Permission
-	for component, permission := range permissions {
-		permSlice = append(permSlice, models.Permission{
-			Component:  component,
-			Permission: permission,
-		})
+	// Update existing user
+	if password != "" {
+		if err := userstore.Users.SetPassword(username, password); err != nil {
+			return err
+		}
 	}
 
-	return models.SetUserPermissions(userID, permSlice)
+	return userstore.Users.SetPermissions(username, permissions)
 }
 
 // GetUserByUsername retrieves user configuration
@@ -102,7 +91,7 @@ func GetUserByUsername(username string) (*UserConfig, bool, error) {
 		return nil, false, fmt.Errorf("invalid username: %v", err)
 	}
 
-	user, err := models.GetUserByUsername(username)
+	user, err := userstore.Users.GetUser(username)
 	if err != nil {
 		return nil, false, err
 	}
@@ -131,12 +120,12 @@ func RemoveUser(username string) error {
 		return fmt.Errorf("invalid username: %v", err)
 	}
 
-	return models.DeleteUser(username)
+	return userstore.Users.DeleteUser(username)
 }
 
 // ListUsersWithPermissions returns all users with their permissions
 func ListUsersWithPermissions() ([]*models.User, error) {
-	return models.ListAllUsers()
+	return userstore.Users.ListUsers()
 }
 
 // UserConfig represents user configuration structure
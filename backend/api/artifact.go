@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 )
 
@@ -232,6 +233,16 @@ func DeployFromArtifactHub(c *gin.Context) {
 		WorkloadLabel: req.WorkloadLabel,
 	}
 
+	wds, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
+	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	helmReq.WDSContext, helmReq.ITSContext = wds.Context, its.Context
+
 	log.Printf("[INFO] Constructed HelmDeploymentRequest: %+v", helmReq)
 
 	// Parse the "store" parameter from the query string
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// importReachabilityTimeout bounds the check that an imported API server
+// accepts the given credentials
+const importReachabilityTimeout = 15 * time.Second
+
+var unsafeClusterNameChars = regexp.MustCompile(`[^a-zA-Z0-9\-]`)
+
+// ImportByURLRequest imports a cluster from its API server URL and a token
+type ImportByURLRequest struct {
+	APIURL string `json:"api_url" binding:"required,url"`
+	Token  string `json:"token"`
+	// Name of the cluster, derived from the URL if empty
+	Name string `json:"name"`
+}
+
+// ImportClusterHandler onboards the cluster of an uploaded kubeconfig. Only
+// the selected context, or the current one, is kept; it is never written to
+// disk but handed to the onboarding pipeline, which stores it in the
+// credential vault.
+func ImportClusterHandler(c *gin.Context) {
+	startTime := time.Now()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "400").Inc()
+		return
+	}
+
+	file, err := c.FormFile("kubeconfig")
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kubeconfig file is required"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file contents"})
+		return
+	}
+
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kubeconfig format"})
+		return
+	}
+	contextName := c.PostForm("context")
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	adjustClusterServerEndpoints(cfg)
+	kubeconfigData, err := extractContextConfig(cfg, contextName)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusterName := c.PostForm("name")
+	if clusterName == "" {
+		clusterName = sanitizeClusterName(contextName)
+	}
+	startOnboarding(c, its.Context, clusterName, kubeconfigData, nil)
+	telemetry.HTTPRequestDuration.WithLabelValues("POST", "/clusters/import").Observe(time.Since(startTime).Seconds())
+}
+
+// ImportClusterByURLHandler onboards a cluster from its API server URL and a
+// bearer token, after checking that the server accepts the token
+func ImportClusterByURLHandler(c *gin.Context) {
+	startTime := time.Now()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import-by-url", "400").Inc()
+		return
+	}
+
+	var req ImportByURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import-by-url", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: api_url required and must be a valid URL"})
+		return
+	}
+
+	clusterName := req.Name
+	if clusterName == "" {
+		clusterName = deriveClusterNameFromURL(req.APIURL)
+	}
+	kubeconfigData, err := kubeconfigForURL(clusterName, req.APIURL, req.Token)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import-by-url", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build kubeconfig: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), importReachabilityTimeout)
+	defer cancel()
+	if err := checkClusterReachable(ctx, kubeconfigData); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/import-by-url", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "failed to reach cluster with provided credentials",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	startOnboarding(c, its.Context, clusterName, kubeconfigData, nil)
+	telemetry.HTTPRequestDuration.WithLabelValues("POST", "/clusters/import-by-url").Observe(time.Since(startTime).Seconds())
+}
+
+// kubeconfigForURL builds a kubeconfig with a single context for the server
+func kubeconfigForURL(name, server, token string) ([]byte, error) {
+	cfg := clientcmdapi.NewConfig()
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = server
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = token
+
+	cfg.Clusters[name] = cluster
+	cfg.AuthInfos[name] = authInfo
+	cfg.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	cfg.CurrentContext = name
+	return clientcmd.Write(*cfg)
+}
+
+// checkClusterReachable calls the health endpoint of the cluster
+func checkClusterReachable(ctx context.Context, kubeconfigData []byte) error {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.Discovery().RESTClient().Get().AbsPath("/healthz").DoRaw(ctx)
+	return err
+}
+
+func sanitizeClusterName(s string) string {
+	out := strings.Trim(unsafeClusterNameChars.ReplaceAllString(s, "-"), "-")
+	if out == "" {
+		out = "cluster"
+	}
+	if len(out) > 40 {
+		out = out[:40]
+	}
+	return strings.ToLower(out)
+}
+
+func deriveClusterNameFromURL(urlStr string) string {
+	s := strings.TrimPrefix(urlStr, "https://")
+	s = strings.TrimPrefix(s, "http://")
+	s = strings.SplitN(s, "/", 2)[0]
+	return sanitizeClusterName(s)
+}
+
+// adjustClusterServerEndpoints replaces localhost in server URLs with the
+// cluster name, which is how kind clusters are reached from another container
+func adjustClusterServerEndpoints(config *clientcmdapi.Config) {
+	for name, cluster := range config.Clusters {
+		if strings.Contains(cluster.Server, "localhost") {
+			cluster.Server = strings.Replace(cluster.Server, "localhost", name, 1)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/inventory"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+)
+
+// loadInventory returns the inventory of the selected ITS and writes the
+// error response if there is none. A failed refresh with an older inventory
+// still cached returns the older inventory and a warning.
+func loadInventory(c *gin.Context, route string, refresh bool) (*hub.Space, []inventory.ClusterInventory, gin.H, bool) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	var items []inventory.ClusterInventory
+	var refreshedAt time.Time
+	var err error
+	if refresh {
+		items, refreshedAt, err = inventory.Refresh(its.Context)
+	} else {
+		items, refreshedAt, err = inventory.Get(its.Context)
+	}
+
+	meta := gin.H{"its": its.Name, "refreshedAt": refreshedAt}
+	if err != nil {
+		log.LogWarn("Failed to collect cluster inventory", zap.String("its", its.Context), zap.Error(err))
+		if refreshedAt.IsZero() {
+			telemetry.HTTPErrorCounter.WithLabelValues(c.Request.Method, route, "500").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect cluster inventory", "details": err.Error()})
+			return nil, nil, nil, false
+		}
+		meta["warning"] = "showing the last collected inventory: " + err.Error()
+	}
+	return its, items, meta, true
+}
+
+// GetClusterInventoryHandler lists the inventory of the managed clusters of
+// an ITS, filtered by the q, provider, region, selector and available query
+// parameters and sorted by sort and order
+func GetClusterInventoryHandler(c *gin.Context) {
+	const route = "/api/clusters/inventory"
+	query := inventory.Query{
+		Search:   c.Query("q"),
+		Provider: c.Query("provider"),
+		Region:   c.Query("region"),
+		SortBy:   c.Query("sort"),
+		Desc:     c.Query("order") == "desc",
+	}
+	selector, err := inventory.ParseSelector(c.Query("selector"))
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Selector = selector
+	if value := c.Query("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "available must be true or false"})
+			return
+		}
+		query.Available = &available
+	}
+	if order := c.Query("order"); order != "" && order != "asc" && order != "desc" {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	if err := query.Validate(); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, items, response, ok := loadInventory(c, route, false)
+	if !ok {
+		return
+	}
+	clusters, _ := inventory.Search(items, query)
+
+	response["clusters"] = clusters
+	response["count"] = len(clusters)
+	response["total"] = len(items)
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, response)
+}
+
+// GetClusterInventoryItemHandler returns the inventory of one managed cluster
+func GetClusterInventoryItemHandler(c *gin.Context) {
+	const route = "/api/clusters/inventory/:name"
+	_, items, response, ok := loadInventory(c, route, false)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+	for _, item := range items {
+		if item.Name == name {
+			response["cluster"] = item
+			telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+			c.JSON(http.StatusOK, response)
+			return
+		}
+	}
+	telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "404").Inc()
+	c.JSON(http.StatusNotFound, gin.H{"error": "Cluster " + name + " not found in the inventory"})
+}
+
+// RefreshClusterInventoryHandler collects the inventory of an ITS now instead
+// of waiting for the next background refresh
+func RefreshClusterInventoryHandler(c *gin.Context) {
+	const route = "/api/clusters/inventory/refresh"
+	_, items, response, ok := loadInventory(c, route, true)
+	if !ok {
+		return
+	}
+	response["count"] = len(items)
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", route, "200").Inc()
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLabelSuggestionsHandler suggests labels for BindingPolicy cluster
+// selectors: the labels the clusters carry and the labels their inventory
+// implies, optionally restricted to keys starting with prefix
+func GetLabelSuggestionsHandler(c *gin.Context) {
+	const route = "/api/clusters/inventory/label-suggestions"
+	_, items, response, ok := loadInventory(c, route, false)
+	if !ok {
+		return
+	}
+
+	suggestions := inventory.LabelSuggestions(items, c.Query("prefix"))
+	used, err := getBindingPolicyLabelKeys()
+	if err != nil {
+		log.LogWarn("Failed to read labels used by binding policies", zap.Error(err))
+	}
+	for i := range suggestions {
+		suggestions[i].UsedByBindingPolicies = used[suggestions[i].Key]
+	}
+
+	response["suggestions"] = suggestions
+	response["count"] = len(suggestions)
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,496 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/inventory"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/labelpolicy"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+var managedClusterGVR = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "managedclusters",
+}
+
+// ClusterLabelCompliance reports how the labels of a cluster violate the
+// label schema
+type ClusterLabelCompliance struct {
+	Name       string                  `json:"name"`
+	Labels     map[string]string       `json:"labels"`
+	Compliant  bool                    `json:"compliant"`
+	Violations []labelpolicy.Violation `json:"violations"`
+}
+
+// UpdateManagedClusterLabelsHandler changes the labels of a managed cluster,
+// an empty value removing a label. Changes the label schema refuses are not
+// applied and reported as a partial success.
+func UpdateManagedClusterLabelsHandler(c *gin.Context) {
+	var req struct {
+		ContextName  string            `json:"contextName"`
+		ClusterName  string            `json:"clusterName"`
+		ClusterNames []string          `json:"clusterNames"`
+		Labels       map[string]string `json:"labels"`
+	}
+	startTime := time.Now()
+	if err := c.BindJSON(&req); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	// Validate required fields
+	if req.ContextName == "" || req.ClusterName == "" {
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "contextName and clusterName are required"})
+		return
+	}
+
+	_, restConfig, err := k8s.GetClientSetWithConfigContext(req.ContextName)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get Kubernetes client"})
+		return
+	}
+
+	editor := labelpolicy.Editor{Username: c.GetString("username"), Admin: c.GetBool("is_admin")}
+	violations, err := UpdateManagedClusterLabels(restConfig, req.ClusterName, req.Labels, editor)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update cluster labels: %v", err)})
+		return
+	}
+	// Label suggestions and selector searches must see the new labels
+	inventory.Invalidate(req.ContextName)
+	if len(violations) > 0 {
+		refused := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			refused = append(refused, violation.String())
+		}
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "207").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "PARTIAL_SUCCESS: Cannot modify labels: " + strings.Join(refused, ", "),
+			"violations": violations,
+		})
+		return
+	}
+	telemetry.HTTPRequestDuration.WithLabelValues("PATCH", "/api/managedclusters/labels").Observe(time.Since(startTime).Seconds())
+	telemetry.TotalHTTPRequests.WithLabelValues("PATCH", "/api/managedclusters/labels", "200").Inc()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cluster labels updated successfully",
+	})
+}
+
+// GetLabelSchemaHandler returns the label schema, so label editors can offer
+// the allowed values and lock protected keys
+func GetLabelSchemaHandler(c *gin.Context) {
+	const route = "/api/clusters/labels/schema"
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the label schema", "details": err.Error()})
+		return
+	}
+	bindingPolicyKeys := []string{}
+	if labelSchema.ProtectBindingPolicyLabels {
+		used, err := getBindingPolicyLabelKeys()
+		if err != nil {
+			log.Printf("[WARNING] Could not fetch labels used by binding policies: %v", err)
+		}
+		for key := range used {
+			bindingPolicyKeys = append(bindingPolicyKeys, key)
+		}
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, gin.H{"schema": labelSchema, "bindingPolicyKeys": bindingPolicyKeys})
+}
+
+// GetLabelComplianceHandler checks the labels of the managed clusters of an
+// ITS against the label schema. With noncompliant=true only the clusters
+// violating it are listed.
+func GetLabelComplianceHandler(c *gin.Context) {
+	const route = "/api/clusters/labels/compliance"
+	_, items, response, ok := loadInventory(c, route, false)
+	if !ok {
+		return
+	}
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the label schema", "details": err.Error()})
+		return
+	}
+
+	onlyNoncompliant := c.Query("noncompliant") == "true"
+	clusters := []ClusterLabelCompliance{}
+	noncompliant := 0
+	for _, item := range items {
+		violations := labelSchema.Check(item.Labels)
+		if len(violations) > 0 {
+			noncompliant++
+		} else if onlyNoncompliant {
+			continue
+		}
+		clusters = append(clusters, ClusterLabelCompliance{
+			Name:       item.Name,
+			Labels:     item.Labels,
+			Compliant:  len(violations) == 0,
+			Violations: violations,
+		})
+	}
+
+	response["clusters"] = clusters
+	response["total"] = len(items)
+	response["compliant"] = len(items) - noncompliant
+	response["noncompliant"] = noncompliant
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, response)
+}
+
+// onboardingLabels are the labels a cluster is onboarded with, before the
+// defaults of the label schema are added
+func onboardingLabels(clusterName string, labels map[string]string) map[string]string {
+	clusterLabels := map[string]string{"name": clusterName}
+	for key, value := range labels {
+		clusterLabels[key] = value
+	}
+	return clusterLabels
+}
+
+// checkOnboardingLabels writes a 400 response if the labels a cluster is
+// onboarded with violate the label schema
+func checkOnboardingLabels(c *gin.Context, route, clusterName string, labels map[string]string) bool {
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the label schema", "details": err.Error()})
+		return false
+	}
+	if violations := labelSchema.CheckOnboarding(onboardingLabels(clusterName, labels)); len(violations) > 0 {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      fmt.Sprintf("The labels of cluster '%s' violate the label schema", clusterName),
+			"violations": violations,
+		})
+		return false
+	}
+	return true
+}
+
+// applyLabelChanges applies the final label state to the cluster
+func applyLabelChanges(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, clusterName string, finalLabels map[string]string) error {
+	patches := []map[string]interface{}{
+		{
+			"op":    "replace",
+			"path":  "/metadata/labels",
+			"value": finalLabels,
+		},
+	}
+
+	patchBytes, err := json.Marshal(patches)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %v", err)
+	}
+
+	_, err = dynamicClient.Resource(gvr).Patch(
+		context.TODO(),
+		clusterName,
+		types.JSONPatchType,
+		patchBytes,
+		metav1.PatchOptions{},
+	)
+
+	return err
+}
+
+// getBindingPolicyLabelKeys returns the label keys BindingPolicies select
+// clusters by, cached for a few minutes
+var (
+	bindingPolicyLabelsCache map[string]bool
+	cacheLastUpdated         time.Time
+	cacheMutex               sync.RWMutex
+	cacheExpiry              = 5 * time.Minute
+)
+
+func getBindingPolicyLabelKeys() (map[string]bool, error) {
+	cacheMutex.RLock()
+	if bindingPolicyLabelsCache != nil && time.Since(cacheLastUpdated) < cacheExpiry {
+		defer cacheMutex.RUnlock()
+		return bindingPolicyLabelsCache, nil
+	}
+	cacheMutex.RUnlock()
+
+	// Fetch fresh data
+	labels, err := getLabelsUsedInBindingPolicies()
+	if err != nil {
+		return make(map[string]bool), err
+	}
+
+	// Update cache
+	cacheMutex.Lock()
+	bindingPolicyLabelsCache = labels
+	cacheLastUpdated = time.Now()
+	cacheMutex.Unlock()
+
+	return labels, nil
+}
+
+// getLabelsUsedInBindingPolicies fetches labels used in binding policies
+func getLabelsUsedInBindingPolicies() (map[string]bool, error) {
+	usedLabels := make(map[string]bool)
+
+	// Policies of every WDS may select the labels of managed clusters
+	resp := []map[string]interface{}{}
+	for _, space := range hub.SpacesOfType(hub.WDS) {
+		policies, err := bp.GetBindingPolicies(space.Context, "")
+		if err != nil {
+			return usedLabels, fmt.Errorf("failed to fetch binding policies of %s: %v", space.Name, err)
+		}
+		resp = append(resp, policies...)
+	}
+
+	var response struct {
+		BindingPolicies []struct {
+			Name string `json:"name"`
+			Spec struct {
+				ClusterSelectors []struct {
+					MatchLabels      map[string]string `json:"matchLabels"`
+					MatchExpressions []struct {
+						Key string `json:"key"`
+					} `json:"matchExpressions"`
+				} `json:"clusterSelectors"`
+			} `json:"spec"`
+			ClusterSelectors []map[string]string `json:"clusterSelectors"` // For stored policies
+			Clusters         []string            `json:"clusters"`         // For cluster strings like "environment=production"
+			YAML             string              `json:"yaml"`             // Raw YAML content
+		} `json:"bindingPolicies"`
+	}
+
+	// Convert the slice of maps to JSON bytes, then unmarshal into our struct
+	jsonData, err := json.Marshal(map[string]interface{}{"bindingPolicies": resp})
+	if err != nil {
+		return usedLabels, fmt.Errorf("failed to marshal binding policies: %v", err)
+	}
+
+	if err := json.Unmarshal(jsonData, &response); err != nil {
+		return usedLabels, fmt.Errorf("failed to decode binding policies response: %v", err)
+	}
+
+	// Extract labels from binding policies
+	for _, bp := range response.BindingPolicies {
+		// Method 1: Check spec.clusterSelectors.matchLabels (standard format)
+		for _, selector := range bp.Spec.ClusterSelectors {
+			for key := range selector.MatchLabels {
+				usedLabels[key] = true
+			}
+
+			// Also check matchExpressions
+			for _, expr := range selector.MatchExpressions {
+				if expr.Key != "" {
+					usedLabels[expr.Key] = true
+				}
+			}
+		}
+
+		// Method 2: Check stored clusterSelectors (for UI-created policies)
+		for _, selector := range bp.ClusterSelectors {
+			for key := range selector {
+				usedLabels[key] = true
+			}
+		}
+
+		// Method 3: Check clusters array for "key=value" format
+		for _, cluster := range bp.Clusters {
+			if strings.Contains(cluster, "=") {
+				parts := strings.Split(cluster, "=")
+				if len(parts) >= 2 {
+					key := strings.TrimSpace(parts[0])
+					if key != "" {
+						usedLabels[key] = true
+					}
+				}
+			} else if strings.Contains(cluster, ":") {
+				// Handle "env:local" format
+				parts := strings.Split(cluster, ":")
+				if len(parts) >= 2 {
+					key := strings.TrimSpace(parts[0])
+					if key != "" {
+						usedLabels[key] = true
+					}
+				}
+			}
+		}
+
+		// Method 4: Parse YAML content if available - Enhanced parsing
+		if bp.YAML != "" {
+			// Parse matchlabels sections (more flexible regex)
+			matchLabelsPattern := regexp.MustCompile(`(?i)matchlabels:\s*\n((?:\s+\S+:.+\n?)*)`)
+			matches := matchLabelsPattern.FindAllStringSubmatch(bp.YAML, -1)
+
+			for _, match := range matches {
+				if len(match) > 1 {
+					labelBlock := match[1]
+					labelLines := strings.Split(labelBlock, "\n")
+					for _, line := range labelLines {
+						line = strings.TrimSpace(line)
+						if line != "" && strings.Contains(line, ":") {
+							parts := strings.Split(line, ":")
+							if len(parts) >= 1 {
+								key := strings.TrimSpace(parts[0])
+								if key != "" {
+									usedLabels[key] = true
+								}
+							}
+						}
+					}
+				}
+			}
+
+			// Also look for direct label references in YAML like "env: local"
+			// This handles the specific case where labels are defined directly in clusterselectors
+			yamlLines := strings.Split(bp.YAML, "\n")
+			inClusterSelectors := false
+			inMatchLabels := false
+
+			for _, line := range yamlLines {
+				trimmed := strings.TrimSpace(line)
+
+				if strings.Contains(trimmed, "clusterselectors:") {
+					inClusterSelectors = true
+					continue
+				}
+
+				if inClusterSelectors && strings.Contains(trimmed, "matchlabels:") {
+					inMatchLabels = true
+					continue
+				}
+
+				if inMatchLabels && strings.Contains(trimmed, ":") && !strings.HasPrefix(trimmed, "-") {
+					// Check if this is a label definition (key: value)
+					if !strings.Contains(trimmed, "matchlabels") &&
+						!strings.Contains(trimmed, "matchexpressions") &&
+						!strings.Contains(trimmed, "downsync") &&
+						!strings.Contains(trimmed, "spec:") {
+
+						parts := strings.Split(trimmed, ":")
+						if len(parts) >= 1 {
+							key := strings.TrimSpace(parts[0])
+							// Filter out non-label keys
+							if key != "" &&
+								!strings.Contains(key, "apigroup") &&
+								!strings.Contains(key, "resources") &&
+								!strings.Contains(key, "namespaces") {
+								usedLabels[key] = true
+							}
+						}
+					}
+				}
+
+				// Reset flags when we exit sections
+				if strings.HasPrefix(trimmed, "downsync:") || strings.HasPrefix(trimmed, "spec:") {
+					inClusterSelectors = false
+					inMatchLabels = false
+				}
+			}
+		}
+	}
+
+	return usedLabels, nil
+}
+
+// getManagedClusterLabels returns the current labels of a managed cluster
+func getManagedClusterLabels(dynamicClient dynamic.Interface, clusterName string) (map[string]string, error) {
+	currentCluster, err := dynamicClient.Resource(managedClusterGVR).Get(context.TODO(), clusterName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get managed cluster %s: %v", clusterName, err)
+	}
+	currentLabels := make(map[string]string)
+	if labels, found, err := unstructured.NestedStringMap(currentCluster.Object, "metadata", "labels"); err == nil && found {
+		currentLabels = labels
+	}
+	return currentLabels, nil
+}
+
+// UpdateManagedClusterLabels applies label changes to a managed cluster on
+// behalf of editor, an empty value removing a label. Changes the label schema
+// refuses are left out and returned as violations.
+func UpdateManagedClusterLabels(config *rest.Config, clusterName string, newLabels map[string]string,
+	editor labelpolicy.Editor) ([]labelpolicy.Violation, error) {
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the label schema: %v", err)
+	}
+	bindingPolicyKeys := map[string]bool{}
+	if labelSchema.ProtectBindingPolicyLabels {
+		if bindingPolicyKeys, err = getBindingPolicyLabelKeys(); err != nil {
+			log.Printf("[WARNING] Could not fetch labels used by binding policies: %v", err)
+		}
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	currentLabels, err := getManagedClusterLabels(dynamicClient, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	finalLabels, violations := labelSchema.Plan(currentLabels, newLabels, editor, bindingPolicyKeys)
+	if err := applyLabelChanges(dynamicClient, managedClusterGVR, clusterName, finalLabels); err != nil {
+		return nil, fmt.Errorf("failed to apply label changes: %v", err)
+	}
+	return violations, nil
+}
+
+// UpdateManagedClusterLabelsForOnboarding sets the labels a cluster is
+// onboarded with and the defaults of the label schema for the keys the
+// cluster does not have yet. The labels were checked against the schema when
+// onboarding was requested.
+func UpdateManagedClusterLabelsForOnboarding(config *rest.Config, clusterName string, newLabels map[string]string) error {
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load the label schema: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	currentLabels, err := getManagedClusterLabels(dynamicClient, clusterName)
+	if err != nil {
+		return err
+	}
+
+	finalLabels := make(map[string]string, len(currentLabels)+len(newLabels))
+	for k, v := range currentLabels {
+		finalLabels[k] = v
+	}
+	for k, v := range newLabels {
+		finalLabels[k] = v
+	}
+	finalLabels = labelSchema.WithDefaults(finalLabels)
+
+	if err := applyLabelChanges(dynamicClient, managedClusterGVR, clusterName, finalLabels); err != nil {
+		return fmt.Errorf("failed to apply label changes during onboarding: %v", err)
+	}
+	return nil
+}
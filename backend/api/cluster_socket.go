@@ -221,6 +221,9 @@ func LogOnboardingEvent(clusterName, status, message string) {
 	onboardingEvents[clusterName] = append(onboardingEvents[clusterName], event)
 	eventsMutex.Unlock()
 
+	// Persist it in the progress log of the running onboarding job, if any
+	recordOnboardingJobEvent(clusterName, status, message)
+
 	// Also log to standard logger
 	log.LogInfo(
 		"Onboarding event logged",
@@ -231,6 +234,7 @@ func LogOnboardingEvent(clusterName, status, message string) {
 
 	// Broadcast to all connected clients for this cluster
 	broadcastEvent(clusterName, event)
+	forwardBulkOnboardingEvent(event)
 }
 
 // RegisterOnboardingStart marks a cluster as being onboarded and logs the initial event
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/csrapproval"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/telemetry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultCSRDecisionsPage = 100
+	maxCSRDecisionsPage     = 1000
+)
+
+// CSRDecisionRequest carries the optional reason of a manual decision
+type CSRDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListPendingCSRsHandler returns the CSRs of joining clusters that wait for a
+// decision on the selected ITS, with how the approval rules evaluate them
+func ListPendingCSRsHandler(c *gin.Context) {
+	const route = "/clusters/csr/pending"
+	startTime := time.Now()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		return
+	}
+
+	hubClients, err := csrapproval.Clients(its.Context)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the ITS", "details": err.Error()})
+		return
+	}
+	pending, err := csrapproval.Queue(c.Request.Context(), its.Context, hubClients)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pending CSRs", "details": err.Error()})
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	telemetry.HTTPRequestDuration.WithLabelValues("GET", route).Observe(time.Since(startTime).Seconds())
+	c.JSON(http.StatusOK, gin.H{"its": its.Context, "pending": pending})
+}
+
+// WatchCSRsHandler returns every CSR of the selected ITS in the shape of
+// "kubectl get csr -o json", for clients older than the pending queue
+func WatchCSRsHandler(c *gin.Context) {
+	const route = "/clusters/watch-csr"
+	startTime := time.Now()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		return
+	}
+
+	hubClients, err := csrapproval.Clients(its.Context)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the ITS", "details": err.Error()})
+		return
+	}
+	csrs, err := hubClients.Kube.CertificatesV1().CertificateSigningRequests().List(c.Request.Context(), metav1.ListOptions{})
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list CSRs", "details": err.Error()})
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	telemetry.HTTPRequestDuration.WithLabelValues("GET", route).Observe(time.Since(startTime).Seconds())
+	c.JSON(http.StatusOK, gin.H{"items": csrs.Items})
+}
+
+// ApproveCSRHandler approves a pending CSR of a joining cluster
+func ApproveCSRHandler(c *gin.Context) {
+	decideCSR(c, "/clusters/csr/:name/approve", true)
+}
+
+// DenyCSRHandler denies a pending CSR of a joining cluster
+func DenyCSRHandler(c *gin.Context) {
+	decideCSR(c, "/clusters/csr/:name/deny", false)
+}
+
+func decideCSR(c *gin.Context, route string, approve bool) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "400").Inc()
+		return
+	}
+	csrName := c.Param("name")
+
+	var req CSRDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "400").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+			return
+		}
+	}
+
+	hubClients, err := csrapproval.Clients(its.Context)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the ITS", "details": err.Error()})
+		return
+	}
+	outcome, err := csrapproval.Decide(c.Request.Context(), its.Context, hubClients, csrName, approve,
+		c.GetString("username"), req.Reason)
+	switch {
+	case errors.Is(err, csrapproval.ErrCSRNotFound):
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "404").Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("CSR '%s' of a joining cluster not found", csrName)})
+		return
+	case errors.Is(err, csrapproval.ErrCSRDecided):
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "409").Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("CSR '%s' was already approved or denied", csrName)})
+		return
+	case err != nil:
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decide CSR", "details": err.Error()})
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", route, "200").Inc()
+	c.JSON(http.StatusOK, outcome)
+}
+
+// ListCSRDecisionsHandler returns the recorded CSR decisions, newest first,
+// optionally filtered by cluster and decision
+func ListCSRDecisionsHandler(c *gin.Context) {
+	const route = "/clusters/csr/decisions"
+	filter := models.CSRDecisionFilter{
+		ClusterName: c.Query("cluster"),
+		Decision:    c.Query("decision"),
+		Limit:       defaultCSRDecisionsPage,
+	}
+	switch filter.Decision {
+	case "", models.CSRApproved, models.CSRDenied, models.CSRPending:
+	default:
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("decision must be %s, %s or %s", models.CSRApproved, models.CSRDenied, models.CSRPending),
+		})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxCSRDecisionsPage {
+			telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxCSRDecisionsPage)})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	decisions, err := models.ListCSRDecisions(filter)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve CSR decisions", "details": err.Error()})
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, gin.H{"decisions": decisions})
+}
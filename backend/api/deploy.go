@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/redis"
 	"github.com/kubestellar/ui/backend/telemetry"
@@ -453,6 +454,17 @@ func DeployHandler(c *gin.Context) {
 		return
 	}
 
+	wds, ok := hub.MustSelectWDS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/deploy", "400").Inc()
+		return
+	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/deploy", "400").Inc()
+		return
+	}
+
 	// Extract and validate query parameters
 	dryRun := c.Query("dryRun") == "true"
 	dryRunStrategy := c.Query("dryRunStrategy")
@@ -473,7 +485,7 @@ func DeployHandler(c *gin.Context) {
 	}
 
 	// Save deployment configuration in Redis with error handling
-	if err := saveDeploymentConfig(request, branch, gitToken); err != nil {
+	if err := saveDeploymentConfig(request, branch, gitToken, wds.Name, its.Name); err != nil {
 		log.Printf("Warning: Failed to save deployment config to Redis: %v", err)
 	}
 
@@ -483,7 +495,7 @@ func DeployHandler(c *gin.Context) {
 	}
 
 	// Perform deployment with better error handling
-	deploymentTree, err := performDeployment(request, branch, gitUsername, gitToken, dryRun, dryRunStrategy)
+	deploymentTree, err := performDeployment(request, wds.Context, branch, gitUsername, gitToken, dryRun, dryRunStrategy)
 	if err != nil {
 		telemetry.GithubDeploymentsTotal.WithLabelValues("manual", "failure").Inc()
 		if apiErr, ok := err.(*APIError); ok {
@@ -505,7 +517,7 @@ func DeployHandler(c *gin.Context) {
 	var storageError error
 	if createdByMe {
 		deploymentData := createDeploymentData(deploymentID, request, branch, dryRun, dryRunStrategy, deploymentTree)
-		storageError = storeDeploymentData(deploymentData)
+		storageError = storeDeploymentData(its.Context, deploymentData)
 	}
 
 	// Prepare response
@@ -537,7 +549,7 @@ func DeployHandler(c *gin.Context) {
 }
 
 // Helper function to save deployment configuration
-func saveDeploymentConfig(request DeployRequest, branch, gitToken string) error {
+func saveDeploymentConfig(request DeployRequest, branch, gitToken, wdsName, itsName string) error {
 	var errors []error
 
 	if err := redis.SetFilePath(request.FolderPath); err != nil {
@@ -555,6 +567,9 @@ func saveDeploymentConfig(request DeployRequest, branch, gitToken string) error
 	if err := redis.SetWorkloadLabel(request.WorkloadLabel); err != nil {
 		errors = append(errors, err)
 	}
+	if err := redis.SetDeploySpaces(wdsName, itsName); err != nil {
+		errors = append(errors, err)
+	}
 
 	if len(errors) > 0 {
 		return fmt.Errorf("multiple Redis errors: %v", errors)
@@ -563,7 +578,7 @@ func saveDeploymentConfig(request DeployRequest, branch, gitToken string) error
 }
 
 // Helper function to perform deployment
-func performDeployment(request DeployRequest, branch, gitUsername, gitToken string, dryRun bool, dryRunStrategy string) (interface{}, error) {
+func performDeployment(request DeployRequest, wdsContext, branch, gitUsername, gitToken string, dryRun bool, dryRunStrategy string) (interface{}, error) {
 	tempDir := fmt.Sprintf("/tmp/deploy-%d", time.Now().UnixNano())
 	cloneURL := request.RepoURL
 
@@ -606,7 +621,7 @@ func performDeployment(request DeployRequest, branch, gitUsername, gitToken stri
 	}
 
 	// Deploy manifests
-	return k8s.DeployManifests(deployPath, dryRun, dryRunStrategy, request.WorkloadLabel)
+	return k8s.DeployManifests(wdsContext, deployPath, dryRun, dryRunStrategy, request.WorkloadLabel)
 }
 
 // Helper function to create deployment data
@@ -626,9 +641,9 @@ func createDeploymentData(deploymentID string, request DeployRequest, branch str
 }
 
 // Helper function to store deployment data
-func storeDeploymentData(deploymentData map[string]interface{}) error {
+func storeDeploymentData(itsContext string, deploymentData map[string]interface{}) error {
 	// Get existing deployments
-	existingDeployments, err := k8s.GetGithubDeployments("its1")
+	existingDeployments, err := k8s.GetGithubDeployments(itsContext)
 	if err != nil {
 		existingDeployments = []interface{}{}
 	}
@@ -647,7 +662,7 @@ func storeDeploymentData(deploymentData map[string]interface{}) error {
 		"deployments": string(deploymentsJSON),
 	}
 
-	return k8s.StoreGitHubDeployment(cmData)
+	return k8s.StoreGitHubDeployment(itsContext, cmData)
 }
 
 // Improved webhook handler with better validation and error handling
@@ -756,6 +771,9 @@ type WebhookConfig struct {
 	Branch        string
 	WorkloadLabel string
 	GitToken      string
+	// WDSContext and ITSContext are the spaces of the last manual deployment
+	WDSContext string
+	ITSContext string
 }
 
 type DeploymentResult struct {
@@ -785,6 +803,18 @@ func getWebhookConfig() (*WebhookConfig, error) {
 
 	config.GitToken, _ = redis.GetGitToken() // Optional
 
+	// Webhooks deploy to the spaces the repository was last deployed to
+	wdsName, itsName, _ := redis.GetDeploySpaces()
+	wds, err := hub.Resolve(hub.WDS, wdsName)
+	if err != nil {
+		return nil, err
+	}
+	its, err := hub.Resolve(hub.ITS, itsName)
+	if err != nil {
+		return nil, err
+	}
+	config.WDSContext, config.ITSContext = wds.Context, its.Context
+
 	return config, nil
 }
 
@@ -868,7 +898,7 @@ func performWebhookDeployment(request GitHubWebhookPayload, config *WebhookConfi
 	}
 
 	// Deploy manifests (always live deployment for webhooks)
-	deploymentTree, err := k8s.DeployManifests(deployPath, false, "", config.WorkloadLabel)
+	deploymentTree, err := k8s.DeployManifests(config.WDSContext, deployPath, false, "", config.WorkloadLabel)
 	if err != nil {
 		return nil, fmt.Errorf("deployment failed: %v", err)
 	}
@@ -889,7 +919,7 @@ func performWebhookDeployment(request GitHubWebhookPayload, config *WebhookConfi
 		"workload_label": config.WorkloadLabel,
 	}
 
-	if err := storeWebhookDeployment(deploymentData, deploymentTree); err != nil {
+	if err := storeWebhookDeployment(config.ITSContext, deploymentData, deploymentTree); err != nil {
 		log.Printf("Warning: Failed to store webhook deployment data: %v", err)
 	}
 
@@ -900,9 +930,9 @@ func performWebhookDeployment(request GitHubWebhookPayload, config *WebhookConfi
 }
 
 // Helper function to store webhook deployment
-func storeWebhookDeployment(deploymentData map[string]interface{}, deploymentTree interface{}) error {
+func storeWebhookDeployment(itsContext string, deploymentData map[string]interface{}, deploymentTree interface{}) error {
 	// Get existing deployments
-	existingDeployments, err := k8s.GetGithubDeployments("its1")
+	existingDeployments, err := k8s.GetGithubDeployments(itsContext)
 	if err != nil {
 		existingDeployments = []interface{}{}
 	}
@@ -928,7 +958,7 @@ func storeWebhookDeployment(deploymentData map[string]interface{}, deploymentTre
 		"last_deployment_tree": string(deploymentTreeJSON),
 	}
 
-	return k8s.StoreGitHubDeployment(cmData)
+	return k8s.StoreGitHubDeployment(itsContext, cmData)
 }
 
 // CreateHelmActionConfig initializes the Helm action configuration with better error handling
@@ -960,9 +990,14 @@ func HealthCheckHandler(c *gin.Context) {
 		redisStatus = fmt.Sprintf("unhealthy: %v", err)
 	}
 
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+
 	// Check Kubernetes connectivity
 	k8sStatus := "healthy"
-	if _, err := k8s.GetGithubDeployments("its1"); err != nil {
+	if _, err := k8s.GetGithubDeployments(its.Context); err != nil {
 		k8sStatus = fmt.Sprintf("unhealthy: %v", err)
 	}
 
@@ -998,9 +1033,14 @@ func DeploymentStatusHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Deployment ID is required"})
 		return
 	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/:id", "400").Inc()
+		return
+	}
 
 	// Get deployments from ConfigMap
-	deployments, err := k8s.GetGithubDeployments("its1")
+	deployments, err := k8s.GetGithubDeployments(its.Context)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/:"+deploymentID, "500").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1044,8 +1084,14 @@ func ListDeploymentsHandler(c *gin.Context) {
 	webhookOnly := c.Query("webhook_only") == "true"
 	manualOnly := c.Query("manual_only") == "true"
 
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments", "400").Inc()
+		return
+	}
+
 	// Get deployments from ConfigMap
-	deployments, err := k8s.GetGithubDeployments("its1")
+	deployments, err := k8s.GetGithubDeployments(its.Context)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments", "500").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1102,9 +1148,14 @@ func DeleteDeploymentHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Deployment ID is required"})
 		return
 	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/deployments/:id", "400").Inc()
+		return
+	}
 
 	// Get existing deployments
-	deployments, err := k8s.GetGithubDeployments("its1")
+	deployments, err := k8s.GetGithubDeployments(its.Context)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/deployments/:"+deploymentID, "500").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1151,7 +1202,7 @@ func DeleteDeploymentHandler(c *gin.Context) {
 		"deployments": string(deploymentsJSON),
 	}
 
-	if err := k8s.StoreGitHubDeployment(cmData); err != nil {
+	if err := k8s.StoreGitHubDeployment(its.Context, cmData); err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/deployments/:"+deploymentID, "500").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update deployment storage",
@@ -1189,6 +1240,11 @@ func ValidateConfigHandler(c *gin.Context) {
 	if config.Branch == "" {
 		config.Branch = "main"
 	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/validate_config", "400").Inc()
+		return
+	}
 
 	// Validate repository access
 	validationResults := gin.H{
@@ -1228,7 +1284,7 @@ func ValidateConfigHandler(c *gin.Context) {
 	}
 
 	// Test Kubernetes connectivity
-	if _, err := k8s.GetGithubDeployments("its1"); err != nil {
+	if _, err := k8s.GetGithubDeployments(its.Context); err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/validate_config", "500").Inc()
 		validationResults["validations"].(gin.H)["kubernetes_connectivity"] = gin.H{
 			"status": "failed",
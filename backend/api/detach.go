@@ -2,18 +2,24 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/audit"
+	"github.com/kubestellar/ui/backend/detach"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/inventory"
+	"github.com/kubestellar/ui/backend/its/ocm"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/telemetry"
-	"k8s.io/client-go/kubernetes"
 )
 
 type WebSocketClient struct {
@@ -36,10 +42,29 @@ var (
 	wsClientMutex = sync.RWMutex{}
 )
 
-// DetachClusterHandler handles HTTP requests to detach a cluster
+const (
+	// forcedDetachAction is the audit action of a detach that skipped the
+	// workload checks
+	forcedDetachAction = "cluster.detach.force"
+
+	defaultDrainTimeout = 10 * time.Minute
+	maxDrainTimeout     = time.Hour
+	drainPollInterval   = 10 * time.Second
+)
+
+// DetachClusterHandler handles HTTP requests to detach a cluster. A cluster
+// that BindingPolicies still select or that still runs delivered workloads is
+// only detached with drain, which relabels it so the policies move their
+// workloads elsewhere and waits for them to leave, or with force.
 func DetachClusterHandler(c *gin.Context) {
 	var req struct {
 		ClusterName string `json:"clusterName" binding:"required"`
+		// Drain relabels the cluster and waits for its workloads to leave
+		Drain bool `json:"drain"`
+		// DrainTimeoutSeconds bounds the wait for the workloads to leave
+		DrainTimeoutSeconds int `json:"drainTimeoutSeconds"`
+		// Force detaches without checking what still runs on the cluster
+		Force bool `json:"force"`
 	}
 	startTime := time.Now()
 	if err := c.BindJSON(&req); err != nil {
@@ -54,16 +79,45 @@ func DetachClusterHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cluster name is required"})
 		return
 	}
+	if req.Drain && req.Force {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "drain and force cannot be combined"})
+		return
+	}
+	drainTimeout := defaultDrainTimeout
+	if req.DrainTimeoutSeconds != 0 {
+		drainTimeout = time.Duration(req.DrainTimeoutSeconds) * time.Second
+		if drainTimeout < 0 || drainTimeout > maxDrainTimeout {
+			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "400").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("drainTimeoutSeconds must be between 1 and %d", int(maxDrainTimeout.Seconds()))})
+			return
+		}
+	}
+	audit.SetResource(c, "cluster="+clusterName)
+	if req.Force {
+		audit.SetAction(c, forcedDetachAction)
+	}
+
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "400").Inc()
+		return
+	}
 
 	// Check if the cluster exists in the OCM hub
 	mutex.RLock()
 	status, exists := clusterStatuses[clusterName]
 	mutex.RUnlock()
 
+	if exists && (status == "Detaching" || status == "Draining") {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "409").Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cluster '%s' is already %s", clusterName, strings.ToLower(status))})
+		return
+	}
+
 	if !exists {
 		// Check directly with the OCM hub
-		itsContext := "its1" // Could be parameterized
-		hubClientset, _, err := k8s.GetClientSetWithConfigContext(itsContext)
+		hubClients, err := getHubClients(its.Context)
 		if err != nil {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "500").Inc()
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -73,14 +127,8 @@ func DetachClusterHandler(c *gin.Context) {
 		}
 
 		// Try to get the managed cluster resource
-		result := hubClientset.RESTClient().Get().
-			AbsPath("/apis/cluster.open-cluster-management.io/v1").
-			Resource("managedclusters").
-			Name(clusterName).
-			Do(context.TODO())
-
-		err = result.Error()
-		if err != nil {
+		exists, err := checkManagedClusterExists(hubClients, clusterName)
+		if err != nil || !exists {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "404").Inc()
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": fmt.Sprintf("Cluster '%s' not found in OCM hub", clusterName),
@@ -94,13 +142,58 @@ func DetachClusterHandler(c *gin.Context) {
 		log.Printf("Cluster '%s' status is %s, proceeding with detachment", clusterName, status)
 	}
 
+	var impact *detach.Impact
+	if req.Force {
+		log.Printf("Cluster '%s' is force-detached by %s without checking its workloads", clusterName, c.GetString("username"))
+	} else {
+		var code int
+		impact, code = analyzeDetach(c.Request.Context(), its, clusterName)
+		if impact == nil {
+			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", strconv.Itoa(code)).Inc()
+			c.JSON(code, gin.H{"error": fmt.Sprintf("Failed to analyze the workloads of cluster '%s'", clusterName)})
+			return
+		}
+		if !impact.Safe() && !req.Drain {
+			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "409").Inc()
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  fmt.Sprintf("Cluster '%s' still runs workloads of binding policies, detach it with drain or force", clusterName),
+				"impact": impact,
+			})
+			return
+		}
+		if !impact.Safe() && !impact.Drainable() {
+			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "409").Inc()
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  fmt.Sprintf("Binding policies %s keep selecting cluster '%s' after relabeling, change them or detach with force", strings.Join(impact.Drain.Blocking, ", "), clusterName),
+				"impact": impact,
+			})
+			return
+		}
+	}
+	drain := req.Drain && impact != nil && !impact.Safe()
+
 	// Start detaching the cluster
+	status = "Detaching"
+	if drain {
+		status = "Draining"
+	}
 	mutex.Lock()
-	clusterStatuses[clusterName] = "Detaching"
+	clusterStatuses[clusterName] = status
 	mutex.Unlock()
 
 	go func() {
-		err := DetachCluster(clusterName)
+		var err error
+		if drain {
+			err = DrainCluster(clusterName, its.Context, impact.Drain, drainTimeout)
+			if err == nil {
+				mutex.Lock()
+				clusterStatuses[clusterName] = "Detaching"
+				mutex.Unlock()
+				err = DetachCluster(clusterName, its.Context)
+			}
+		} else {
+			err = DetachCluster(clusterName, its.Context)
+		}
 		mutex.Lock()
 		if err != nil {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/detach", "500").Inc()
@@ -116,23 +209,124 @@ func DetachClusterHandler(c *gin.Context) {
 	telemetry.HTTPRequestDuration.WithLabelValues("POST", "/clusters/detach").Observe(time.Since(startTime).Seconds())
 	c.JSON(http.StatusOK, gin.H{
 		"message":           fmt.Sprintf("Cluster '%s' is being detached", clusterName),
-		"status":            "Detaching",
+		"status":            status,
+		"forced":            req.Force,
+		"impact":            impact,
 		"logsEndpoint":      fmt.Sprintf("/clusters/detach/logs/%s", clusterName),
 		"websocketEndpoint": fmt.Sprintf("/ws/detachment?cluster=%s", clusterName),
 	})
 }
 
+// analyzeDetach reports what detaching a cluster affects, or nil and the
+// HTTP status of the failure
+func analyzeDetach(ctx context.Context, its *hub.Space, clusterName string) (*detach.Impact, int) {
+	clients, warnings, err := detach.ClientsFor(its.Context)
+	if err != nil {
+		log.Printf("Failed to analyze cluster '%s': %v", clusterName, err)
+		return nil, http.StatusInternalServerError
+	}
+	impact, err := detach.Analyze(ctx, its.Name, clients, clusterName)
+	if errors.Is(err, detach.ErrClusterNotFound) {
+		return nil, http.StatusNotFound
+	}
+	if err != nil {
+		log.Printf("Failed to analyze cluster '%s': %v", clusterName, err)
+		return nil, http.StatusInternalServerError
+	}
+	impact.Warnings = append(warnings, impact.Warnings...)
+	return impact, http.StatusOK
+}
+
+// GetDetachImpactHandler reports the BindingPolicies that select a cluster,
+// the workloads delivered to it and how a drain would relabel it
+func GetDetachImpactHandler(c *gin.Context) {
+	const route = "/clusters/detach/impact/:cluster"
+	clusterName := c.Param("cluster")
+	if clusterName == "" {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cluster name is required"})
+		return
+	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, "400").Inc()
+		return
+	}
+
+	impact, code := analyzeDetach(c.Request.Context(), its, clusterName)
+	if impact == nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", route, strconv.Itoa(code)).Inc()
+		if code == http.StatusNotFound {
+			c.JSON(code, gin.H{"error": fmt.Sprintf("Cluster '%s' not found in OCM hub", clusterName)})
+			return
+		}
+		c.JSON(code, gin.H{"error": fmt.Sprintf("Failed to analyze the workloads of cluster '%s'", clusterName)})
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", route, "200").Inc()
+	c.JSON(http.StatusOK, gin.H{"impact": impact, "safe": impact.Safe(), "drainable": impact.Drainable()})
+}
+
+// DrainCluster removes the labels of the drain plan from a cluster so its
+// BindingPolicies stop selecting it, and waits until no workloads are
+// delivered to it. If the workloads do not leave in time the labels are put
+// back so the cluster keeps running them.
+func DrainCluster(clusterName, itsContext string, plan detach.DrainPlan, timeout time.Duration) error {
+	LogOnboardingEvent(clusterName, "Draining", "Starting to move workloads off the cluster")
+
+	hubClients, err := getHubClients(itsContext)
+	if err != nil {
+		LogOnboardingEvent(clusterName, "Error", "Failed to get hub clientset: "+err.Error())
+		return fmt.Errorf("failed to get hub clientset: %w", err)
+	}
+	cluster, err := ocm.GetManagedCluster(context.TODO(), hubClients, clusterName)
+	if err != nil || cluster == nil {
+		LogOnboardingEvent(clusterName, "Error", "Cluster not found in OCM hub")
+		return fmt.Errorf("cluster '%s' not found in OCM hub", clusterName)
+	}
+	removed := map[string]string{}
+	for _, key := range plan.RemoveLabels {
+		if value, ok := cluster.GetLabels()[key]; ok {
+			removed[key] = value
+		}
+	}
+
+	LogOnboardingEvent(clusterName, "Relabeling", "Removing labels "+strings.Join(plan.RemoveLabels, ", "))
+	if err := ocm.RemoveManagedClusterLabels(context.TODO(), hubClients, clusterName, plan.RemoveLabels); err != nil {
+		LogOnboardingEvent(clusterName, "Error", "Failed to relabel cluster: "+err.Error())
+		return err
+	}
+	inventory.Invalidate(itsContext)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = detach.WaitForDrain(ctx, hubClients.Dynamic, clusterName, drainPollInterval, func(remaining []detach.Workload) {
+		LogOnboardingEvent(clusterName, "Draining", fmt.Sprintf("Waiting for %d workloads to leave the cluster", len(remaining)))
+	})
+	if err != nil {
+		LogOnboardingEvent(clusterName, "Error", "Workloads did not leave the cluster: "+err.Error())
+		if restoreErr := ocm.SetManagedClusterLabels(context.TODO(), hubClients, clusterName, removed); restoreErr != nil {
+			LogOnboardingEvent(clusterName, "Error", "Failed to restore the removed labels: "+restoreErr.Error())
+		} else {
+			LogOnboardingEvent(clusterName, "Restored", "Restored the removed labels")
+		}
+		inventory.Invalidate(itsContext)
+		return fmt.Errorf("failed to drain cluster: %w", err)
+	}
+	LogOnboardingEvent(clusterName, "Drained", "No workloads are delivered to the cluster any more")
+	return nil
+}
+
 // DetachCluster handles the process of detaching a cluster from the OCM hub
-func DetachCluster(clusterName string) error {
+func DetachCluster(clusterName, itsContext string) error {
 	// Log the start of detachment
 	LogOnboardingEvent(clusterName, "Detaching", "Starting cluster detachment process")
 
-	// 1. Get the ITS hub context
-	itsContext := "its1" // Could be parameterized
+	// 1. Connect to the selected ITS hub
 	LogOnboardingEvent(clusterName, "Connecting", "Connecting to ITS hub context: "+itsContext)
 
 	// 2. Get clients for the hub
-	hubClientset, _, err := k8s.GetClientSetWithConfigContext(itsContext)
+	hub, err := getHubClients(itsContext)
 	if err != nil {
 		LogOnboardingEvent(clusterName, "Error", "Failed to get hub clientset: "+err.Error())
 		return fmt.Errorf("failed to get hub clientset: %w", err)
@@ -141,7 +335,7 @@ func DetachCluster(clusterName string) error {
 
 	// 3. Check if the cluster exists
 	LogOnboardingEvent(clusterName, "Checking", "Verifying cluster exists in OCM hub")
-	exists, err := checkManagedClusterExists(hubClientset, clusterName)
+	exists, err := checkManagedClusterExists(hub, clusterName)
 	if err != nil {
 		LogOnboardingEvent(clusterName, "Error", "Error checking if cluster exists: "+err.Error())
 		return fmt.Errorf("error checking if cluster exists: %w", err)
@@ -154,7 +348,7 @@ func DetachCluster(clusterName string) error {
 
 	// 4. Delete the managed cluster
 	LogOnboardingEvent(clusterName, "Executing", "Executing detachment operation via Kubernetes API")
-	if err := executeDetachCommand(itsContext, clusterName); err != nil {
+	if err := executeDetachCommand(hub, clusterName); err != nil {
 		LogOnboardingEvent(clusterName, "Error", "Failed to execute detach operation: "+err.Error())
 		return fmt.Errorf("failed to execute detach operation: %w", err)
 	}
@@ -162,7 +356,7 @@ func DetachCluster(clusterName string) error {
 
 	// 5. Wait for the cluster to be removed
 	LogOnboardingEvent(clusterName, "Waiting", "Waiting for cluster to be removed from OCM hub")
-	if err := waitForClusterRemoval(hubClientset, clusterName); err != nil {
+	if err := waitForClusterRemoval(hub, clusterName); err != nil {
 		LogOnboardingEvent(clusterName, "Error", "Failed to confirm cluster removal: "+err.Error())
 		return fmt.Errorf("failed to confirm cluster removal: %w", err)
 	}
@@ -173,42 +367,28 @@ func DetachCluster(clusterName string) error {
 	return nil
 }
 
-// checkManagedClusterExists checks if a managed cluster exists in the OCM hub
-func checkManagedClusterExists(clientset *kubernetes.Clientset, clusterName string) (bool, error) {
-	result := clientset.RESTClient().Get().
-		AbsPath("/apis/cluster.open-cluster-management.io/v1").
-		Resource("managedclusters").
-		Name(clusterName).
-		Do(context.TODO())
-
-	err := result.Error()
+// getHubClients returns the clients of the ITS hub (OCM hub) behind a kubeconfig context
+func getHubClients(itsContext string) (*ocm.Clients, error) {
+	_, hubConfig, err := k8s.GetClientSetWithConfigContext(itsContext)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return false, nil
-		}
-		return false, err
+		return nil, err
 	}
-
-	return true, nil
+	return ocm.NewClients(hubConfig)
 }
 
-// executeDetachCommand executes the detachment operation using the Kubernetes SDK
-func executeDetachCommand(itsContext, clusterName string) error {
-	// Get the hub client
-	hubClientset, _, err := k8s.GetClientSetWithConfigContext(itsContext)
+// checkManagedClusterExists checks if a managed cluster exists in the OCM hub
+func checkManagedClusterExists(hub *ocm.Clients, clusterName string) (bool, error) {
+	cluster, err := ocm.GetManagedCluster(context.TODO(), hub, clusterName)
 	if err != nil {
-		return fmt.Errorf("failed to get hub clientset: %w", err)
+		return false, err
 	}
+	return cluster != nil, nil
+}
 
-	// Delete the managed cluster resource directly using the Kubernetes API
-	result := hubClientset.RESTClient().Delete().
-		AbsPath("/apis/cluster.open-cluster-management.io/v1").
-		Resource("managedclusters").
-		Name(clusterName).
-		Do(context.TODO())
-
-	if err := result.Error(); err != nil {
-		return fmt.Errorf("failed to delete managed cluster: %w", err)
+// executeDetachCommand deletes the managed cluster resource from the OCM hub
+func executeDetachCommand(hub *ocm.Clients, clusterName string) error {
+	if err := ocm.DeleteManagedCluster(context.TODO(), hub, clusterName); err != nil {
+		return err
 	}
 
 	log.Printf("Successfully initiated deletion of managedcluster %s", clusterName)
@@ -216,7 +396,7 @@ func executeDetachCommand(itsContext, clusterName string) error {
 }
 
 // waitForClusterRemoval waits for the cluster to be removed from the OCM hub
-func waitForClusterRemoval(clientset *kubernetes.Clientset, clusterName string) error {
+func waitForClusterRemoval(hub *ocm.Clients, clusterName string) error {
 	timeout := time.After(5 * time.Minute)
 	tick := time.Tick(10 * time.Second)
 
@@ -225,7 +405,7 @@ func waitForClusterRemoval(clientset *kubernetes.Clientset, clusterName string)
 		case <-timeout:
 			return fmt.Errorf("timeout waiting for cluster removal")
 		case <-tick:
-			exists, err := checkManagedClusterExists(clientset, clusterName)
+			exists, err := checkManagedClusterExists(hub, clusterName)
 			if err != nil {
 				log.Printf("Error checking if cluster exists: %v", err)
 				continue
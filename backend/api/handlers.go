@@ -2,30 +2,24 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/csrapproval"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/models"
 	"github.com/kubestellar/ui/backend/telemetry"
-	"github.com/kubestellar/ui/backend/wds/bp"
-	certificatesv1 "k8s.io/api/certificates/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -53,8 +47,15 @@ func OnboardClusterHandler(c *gin.Context) {
 	contentType := c.GetHeader("Content-Type")
 	var kubeconfigData []byte
 	var clusterName string
+	var labels map[string]string
 	var useLocalKubeconfig bool = false
 
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "400").Inc()
+		return
+	}
+
 	// Handle form-data with file upload
 	if strings.Contains(contentType, "multipart/form-data") {
 		file, fileErr := c.FormFile("kubeconfig")
@@ -97,8 +98,9 @@ func OnboardClusterHandler(c *gin.Context) {
 	} else if strings.Contains(contentType, "application/json") {
 		// Handle JSON payload
 		var req struct {
-			Kubeconfig  string `json:"kubeconfig"`
-			ClusterName string `json:"clusterName"`
+			Kubeconfig  string            `json:"kubeconfig"`
+			ClusterName string            `json:"clusterName"`
+			Labels      map[string]string `json:"labels"`
 		}
 
 		if err := c.BindJSON(&req); err != nil {
@@ -108,6 +110,7 @@ func OnboardClusterHandler(c *gin.Context) {
 		}
 
 		clusterName = req.ClusterName
+		labels = req.Labels
 		if clusterName == "" {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "400").Inc()
 			c.JSON(http.StatusBadRequest, gin.H{"error": "ClusterName is required"})
@@ -142,6 +145,23 @@ func OnboardClusterHandler(c *gin.Context) {
 		}
 	}
 
+	startOnboarding(c, its.Context, clusterName, kubeconfigData, labels)
+	telemetry.HTTPRequestDuration.WithLabelValues("POST", "/clusters/onboard").Observe(time.Since(startTime).Seconds())
+}
+
+// startOnboarding onboards a cluster and writes the response, unless its
+// labels violate the label schema. The request is persisted as an onboarding
+// job that survives restarts when onboarding jobs are available, and runs in
+// memory otherwise.
+func startOnboarding(c *gin.Context, itsContext, clusterName string, kubeconfigData []byte, labels map[string]string) {
+	if !checkOnboardingLabels(c, "/clusters/onboard", clusterName, labels) {
+		return
+	}
+	if onboardingJobsEnabled {
+		submitOnboardingJob(c, itsContext, clusterName, kubeconfigData, labels)
+		return
+	}
+
 	// Check if the cluster is already being onboarded
 	mutex.Lock()
 	if status, exists := clusterStatuses[clusterName]; exists {
@@ -162,7 +182,7 @@ func OnboardClusterHandler(c *gin.Context) {
 
 	// Start asynchronous onboarding
 	go func() {
-		err := OnboardCluster(kubeconfigData, clusterName)
+		err := OnboardCluster(kubeconfigData, clusterName, itsContext)
 		mutex.Lock()
 		if err != nil {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "500").Inc()
@@ -175,7 +195,6 @@ func OnboardClusterHandler(c *gin.Context) {
 		mutex.Unlock()
 	}()
 	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/clusters/onboard", "200").Inc()
-	telemetry.HTTPRequestDuration.WithLabelValues("POST", "/clusters/onboard").Observe(time.Since(startTime).Seconds())
 	c.JSON(http.StatusOK, gin.H{
 		"message":           fmt.Sprintf("Cluster '%s' is being onboarded", clusterName),
 		"status":            "Pending",
@@ -255,107 +274,66 @@ func getClusterConfigFromLocal(clusterName string) ([]byte, error) {
 	return clientcmd.Write(newConfig)
 }
 
-// approveClusterCSRs finds and approves any pending CSRs for the specified cluster
-func approveClusterCSRs(clientset *kubernetes.Clientset, clusterName string) error {
-	LogOnboardingEvent(clusterName, "Searching", "Looking for Certificate Signing Requests for cluster")
-
-	// List all CSRs
-	csrList, err := clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to list CSRs: "+err.Error())
-		return fmt.Errorf("failed to list CSRs: %w", err)
-	}
+// csrWaitTimeout is how long onboarding waits for the CSRs of a joining cluster to appear
+const csrWaitTimeout = 30 * time.Second
 
-	// Check if there are any pending CSRs for our cluster
-	pendingCSRs := []string{}
+// onboardingPreregistration is how long a cluster being onboarded stays on
+// the CSR allowlist
+const onboardingPreregistration = time.Hour
 
-	for _, csr := range csrList.Items {
-		if strings.Contains(csr.Name, clusterName) && !isCSRApproved(csr) {
-			pendingCSRs = append(pendingCSRs, csr.Name)
-			LogOnboardingEvent(clusterName, "Found", fmt.Sprintf("Found pending CSR: %s", csr.Name))
-		}
+// approveClusterCSRs pre-registers the cluster and lets the CSR approval
+// rules decide the CSRs its registration agent created on the hub. CSRs no
+// rule matches are left for manual approval.
+func approveClusterCSRs(ctx context.Context, hub *ocm.Clients, itsContext, clusterName string) error {
+	if err := csrapproval.Preregister(clusterName, itsContext, onboardingPreregistration, "onboarding",
+		"pre-registered while onboarding the cluster"); err != nil {
+		LogOnboardingEvent(clusterName, "Warning", "Failed to pre-register cluster for CSR approval: "+err.Error())
 	}
+	LogOnboardingEvent(clusterName, "Searching", "Looking for Certificate Signing Requests for cluster")
 
-	if len(pendingCSRs) == 0 {
-		LogOnboardingEvent(clusterName, "Info", "No pending CSRs found for this cluster")
-
-		// Wait briefly and check again
-		LogOnboardingEvent(clusterName, "Waiting", "Waiting 30 seconds for CSRs to appear")
-		time.Sleep(30 * time.Second)
-
-		// Check again
-		csrList, err := clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
+	deadline := time.Now().Add(csrWaitTimeout)
+	var outcomes []csrapproval.Outcome
+	for {
+		csrs, err := ocm.ClusterCSRs(ctx, hub, clusterName)
 		if err != nil {
-			LogOnboardingEvent(clusterName, "Error", "Failed to list CSRs after waiting: "+err.Error())
-			return fmt.Errorf("failed to list CSRs after waiting: %w", err)
+			LogOnboardingEvent(clusterName, "Error", "Failed to list CSRs: "+err.Error())
+			return err
 		}
-
-		for _, csr := range csrList.Items {
-			if strings.Contains(csr.Name, clusterName) && !isCSRApproved(csr) {
-				pendingCSRs = append(pendingCSRs, csr.Name)
-				LogOnboardingEvent(clusterName, "Found", fmt.Sprintf("Found pending CSR after waiting: %s", csr.Name))
+		if len(csrs) > 0 {
+			outcomes, err = csrapproval.Process(ctx, itsContext, hub, clusterName)
+			if err != nil {
+				LogOnboardingEvent(clusterName, "Error", err.Error())
+				return err
 			}
+			break
 		}
-	}
-
-	// If we found pending CSRs, approve them directly using kubectl
-	if len(pendingCSRs) > 0 {
-		LogOnboardingEvent(clusterName, "Approving", fmt.Sprintf("Approving %d CSRs", len(pendingCSRs)))
-
-		// Method 1: Use kubectl directly (more reliable based on your experience)
-		approveCmd := exec.Command("kubectl", append([]string{"--context", "its1", "certificate", "approve"}, pendingCSRs...)...)
-		output, err := approveCmd.CombinedOutput()
-		if err != nil {
-			LogOnboardingEvent(clusterName, "Error", fmt.Sprintf("Failed to approve CSRs using kubectl: %v, %s", err, string(output)))
-			telemetry.InstrumentKubectlCommand(approveCmd, "approve-csr", "its1")
-
-			// Method 2: Fall back to SDK approach if kubectl fails
-			LogOnboardingEvent(clusterName, "Fallback", "Falling back to SDK approach for CSR approval")
-			for _, csrName := range pendingCSRs {
-				approvalPatch := []byte(`{"status":{"conditions":[{"type":"Approved","status":"True","reason":"ApprovedByAPI","message":"Approved via KubeStellar API"}]}}`)
-
-				_, err := clientset.CertificatesV1().CertificateSigningRequests().Patch(
-					context.TODO(),
-					csrName,
-					types.MergePatchType,
-					approvalPatch,
-					metav1.PatchOptions{},
-				)
-				if err != nil {
-					LogOnboardingEvent(clusterName, "Error", fmt.Sprintf("Failed to approve CSR %s: %v", csrName, err))
-					return fmt.Errorf("failed to approve CSR %s: %w", csrName, err)
-				}
-
-				LogOnboardingEvent(clusterName, "Approved", fmt.Sprintf("Successfully approved CSR %s", csrName))
-			}
-		} else {
-			LogOnboardingEvent(clusterName, "Approved", fmt.Sprintf("Successfully approved CSRs using kubectl: %s", string(output)))
+		if time.Now().After(deadline) {
+			LogOnboardingEvent(clusterName, "Warning", "No CSRs found to approve. Will proceed and check status later.")
+			return nil
 		}
-	} else {
-		LogOnboardingEvent(clusterName, "Warning", "No CSRs found to approve. Will proceed and check status later.")
-	}
 
-	// Also try using clusteradm to accept the cluster (with skip-approve-check)
-	acceptCmd := exec.Command("clusteradm", "--context", "its1", "accept", "--clusters", clusterName, "--skip-approve-check")
-	acceptOutput, acceptErr := acceptCmd.CombinedOutput()
-	if acceptErr != nil {
-		LogOnboardingEvent(clusterName, "Warning", fmt.Sprintf("clusteradm accept had issues: %v, %s", acceptErr, string(acceptOutput)))
-		// Continue anyway as direct CSR approval might have worked
-	} else {
-		LogOnboardingEvent(clusterName, "Accepted", fmt.Sprintf("Cluster accepted via clusteradm: %s", string(acceptOutput)))
+		LogOnboardingEvent(clusterName, "Waiting", "No CSRs found yet, waiting for them to appear")
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
-	return nil
-}
-
-// Helper function to check if a CSR is already approved
-func isCSRApproved(csr certificatesv1.CertificateSigningRequest) bool {
-	for _, condition := range csr.Status.Conditions {
-		if condition.Type == certificatesv1.CertificateApproved {
-			return true
+	for _, outcome := range outcomes {
+		name := outcome.Request.CSRName
+		switch outcome.Decision.Decision {
+		case models.CSRApproved:
+			LogOnboardingEvent(clusterName, "Approved", fmt.Sprintf("Approved CSR %s: %s", name, outcome.Decision.Reason))
+		case models.CSRDenied:
+			LogOnboardingEvent(clusterName, "Denied", fmt.Sprintf("Denied CSR %s: %s", name, outcome.Decision.Reason))
+			return fmt.Errorf("CSR %s was denied: %s", name, outcome.Decision.Reason)
+		default:
+			LogOnboardingEvent(clusterName, "Pending",
+				fmt.Sprintf("CSR %s waits for manual approval: %s", name, outcome.Decision.Reason))
 		}
 	}
-	return false
+	return nil
 }
 
 // extractContextConfig creates a kubeconfig file for a specific context
@@ -479,140 +457,11 @@ func GetClusterStatusHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, statuses)
 }
 
-func UpdateManagedClusterLabelsHandler(c *gin.Context) {
-	var req struct {
-		ContextName  string            `json:"contextName"`
-		ClusterName  string            `json:"clusterName"`
-		ClusterNames []string          `json:"clusterNames"`
-		Labels       map[string]string `json:"labels"`
-	}
-	startTime := time.Now()
-	if err := c.BindJSON(&req); err != nil {
-		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "400").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
-		return
-	}
-
-	// Validate required fields
-	if req.ContextName == "" || req.ClusterName == "" {
-		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "400").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "contextName and clusterName are required"})
-		return
-	}
-
-	clientset, restConfig, err := k8s.GetClientSetWithConfigContext(req.ContextName)
-	if err != nil {
-		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "500").Inc()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get Kubernetes client"})
-		return
-	}
-
-	err = UpdateManagedClusterLabels(clientset, restConfig, req.ClusterName, req.Labels)
-	if err != nil {
-		// Handle partial success
-		if strings.Contains(err.Error(), "PARTIAL_SUCCESS:") {
-			message := strings.Replace(err.Error(), "PARTIAL_SUCCESS: ", "", 1)
-			telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "207").Inc()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "PARTIAL_SUCCESS: " + message})
-			return
-		}
-		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/api/managedclusters/labels", "500").Inc()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update cluster labels: %v", err)})
-		return
-	}
-	telemetry.HTTPRequestDuration.WithLabelValues("PATCH", "/api/managedclusters/labels").Observe(time.Since(startTime).Seconds())
-	telemetry.TotalHTTPRequests.WithLabelValues("PATCH", "/api/managedclusters/labels", "200").Inc()
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Cluster labels updated successfully",
-	})
-}
-
-// OnboardCluster handles the entire process of onboarding a cluster
-// waitForAndApproveCSR waits for and approves the CSR using clusteradm and SDK fallback
-func waitForAndApproveCSR(clientset *kubernetes.Clientset, clusterName string) error {
-	// Try using clusteradm first for accepting the cluster (which also approves CSRs)
-	log.Printf("Attempting to accept cluster %s using clusteradm", clusterName)
-
-	cmd := exec.Command("clusteradm", "accept", "--clusters", clusterName)
-	output, err := cmd.CombinedOutput()
-
-	// Check for success or expected auto-approval message
-	if err == nil || (err != nil && strings.Contains(string(output), "ManagedClusterAutoApproval feature is enabled")) {
-		log.Printf("Cluster %s accepted using clusteradm: %s", clusterName, string(output))
-		return nil
-	}
-
-	// If clusteradm fails, log the error and fall back to SDK approach
-	log.Printf("clusteradm accept failed: %s, %v. Falling back to SDK approach.", string(output), err)
-	LogOnboardingEvent(clusterName, "Warning", fmt.Sprintf("clusteradm accept failed: %s", string(output)))
-
-	// Wait for CSR to appear
-	timeout := time.After(10 * time.Minute)
-	tick := time.Tick(10 * time.Second)
-
-	log.Printf("Waiting for CSR for cluster %s to appear...", clusterName)
-	LogOnboardingEvent(clusterName, "Waiting", "Waiting for Certificate Signing Request (CSR)")
-
-	for {
-		select {
-		case <-timeout:
-			LogOnboardingEvent(clusterName, "Error", "Timeout waiting for CSR")
-			return fmt.Errorf("timeout waiting for CSR")
-		case <-tick:
-			// Check for pending CSRs related to the cluster
-			csrList, err := clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				log.Printf("Error listing CSRs: %v", err)
-				continue
-			}
-
-			approvedAny := false
-			for _, csr := range csrList.Items {
-				// Check if this CSR is for our cluster
-				if strings.Contains(csr.Name, clusterName) && !isCSRApproved(csr) {
-					// Approve the CSR
-					log.Printf("Found CSR for cluster %s, approving...", clusterName)
-					LogOnboardingEvent(clusterName, "Approving", fmt.Sprintf("Approving CSR %s", csr.Name))
-
-					// Create approve patch
-					approvalPatch := []byte(`{"status":{"conditions":[{"type":"Approved","status":"True","reason":"ApprovedByAPI","message":"Approved via API"}]}}`)
-
-					_, err := clientset.CertificatesV1().CertificateSigningRequests().Patch(
-						context.TODO(),
-						csr.Name,
-						types.MergePatchType,
-						approvalPatch,
-						metav1.PatchOptions{},
-					)
-					if err != nil {
-						LogOnboardingEvent(clusterName, "Error", fmt.Sprintf("Failed to approve CSR: %v", err))
-						return fmt.Errorf("failed to approve CSR: %w", err)
-					}
-
-					log.Printf("CSR for cluster %s approved", clusterName)
-					LogOnboardingEvent(clusterName, "Approved", fmt.Sprintf("CSR %s approved successfully", csr.Name))
-					approvedAny = true
-				} else if strings.Contains(csr.Name, clusterName) && isCSRApproved(csr) {
-					// CSR is already approved
-					log.Printf("CSR for cluster %s is already approved", clusterName)
-					LogOnboardingEvent(clusterName, "Info", fmt.Sprintf("CSR %s is already approved", csr.Name))
-					approvedAny = true
-				}
-			}
-
-			if approvedAny {
-				// If we found any CSRs for this cluster (either approved now or previously), we're good
-				LogOnboardingEvent(clusterName, "Complete", "All CSRs for cluster are approved")
-				return nil
-			}
-		}
-	}
-}
-
 // waitForManagedCluster waits for the managed cluster to be created and accepts it
-func waitForManagedCluster(clientset *kubernetes.Clientset, clusterName string) error {
+func waitForManagedCluster(ctx context.Context, hub *ocm.Clients, clusterName string) error {
 	timeout := time.After(5 * time.Minute)
-	tick := time.Tick(10 * time.Second)
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
 
 	log.Printf("Waiting for managed cluster %s to be created...", clusterName)
 	LogOnboardingEvent(clusterName, "Waiting", "Waiting for managed cluster resource to be created")
@@ -622,210 +471,54 @@ func waitForManagedCluster(clientset *kubernetes.Clientset, clusterName string)
 		case <-timeout:
 			LogOnboardingEvent(clusterName, "Error", "Timeout waiting for managed cluster")
 			return fmt.Errorf("timeout waiting for managed cluster")
-		case <-tick:
-			// Check if the managed cluster exists
-			result := clientset.RESTClient().Get().
-				AbsPath("/apis/cluster.open-cluster-management.io/v1").
-				Resource("managedclusters").
-				Name(clusterName).
-				Do(context.TODO())
-
-			err := result.Error()
-			if err == nil {
-				log.Printf("Managed cluster %s created", clusterName)
-				LogOnboardingEvent(clusterName, "Created", "Managed cluster resource created successfully")
-
-				// Attempt to accept the managed cluster by setting hubAcceptsClient to true
-				acceptPatch := []byte(`{"spec":{"hubAcceptsClient":true}}`)
-
-				patchResult := clientset.RESTClient().Patch(types.MergePatchType).
-					AbsPath("/apis/cluster.open-cluster-management.io/v1").
-					Resource("managedclusters").
-					Name(clusterName).
-					Body(acceptPatch).
-					Do(context.TODO())
-
-				if patchErr := patchResult.Error(); patchErr != nil {
-					log.Printf("Warning: Failed to accept managed cluster: %v", patchErr)
-					LogOnboardingEvent(clusterName, "Warning", fmt.Sprintf("Failed to accept managed cluster: %v", patchErr))
-					// Continue anyway as it might already be accepted by clusteradm or auto-approval
-				} else {
-					log.Printf("Managed cluster %s accepted", clusterName)
-					LogOnboardingEvent(clusterName, "Accepted", "Managed cluster accepted successfully")
-				}
-
-				return nil
-			}
-
-			// Continue waiting if not found
-			log.Printf("Waiting for managed cluster %s to be created...", clusterName)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
 		}
-	}
-}
-
-// OnboardCluster handles the entire process of onboarding a cluster
-func OnboardCluster(kubeconfigData []byte, clusterName string) error {
-	// Register the start of onboarding and log it
-	RegisterOnboardingStart(clusterName)
-
-	// 1. Validate the cluster's connectivity
-	LogOnboardingEvent(clusterName, "Validating", "Validating cluster connectivity")
-	if err := ValidateClusterConnectivity(kubeconfigData); err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Connectivity validation failed: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("cluster validation failed: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Validated", "Cluster connectivity validated successfully")
-
-	// 2. Get the ITS hub context (OCM hub)
-	itsContext := "its1" // Can be parameterized
-	LogOnboardingEvent(clusterName, "Connecting", "Connecting to ITS hub context: "+itsContext)
-
-	// 3. Get clients for the hub
-	hubClientset, hubConfig, err := k8s.GetClientSetWithConfigContext(itsContext)
-	if err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to get hub clientset: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to get hub clientset: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Connected", "Successfully connected to ITS hub")
-
-	// 4. Create a temporary kubeconfig file for the target cluster
-	LogOnboardingEvent(clusterName, "Preparing", "Creating temporary kubeconfig for the target cluster")
-	tempPath, err := createTempKubeconfig(kubeconfigData, clusterName)
-	if err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to create temp kubeconfig: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to create temp kubeconfig: %w", err)
-	}
-	defer os.Remove(tempPath)
-	LogOnboardingEvent(clusterName, "Prepared", "Temporary kubeconfig created: "+tempPath)
-
-	// 5. Get the join command from the hub
-	LogOnboardingEvent(clusterName, "Retrieving", "Getting join token from the OCM hub")
-	joinToken, err := getClusterAdmToken(itsContext)
-	if err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to get token: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to get token: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Retrieved", "Successfully retrieved join token")
-
-	// 6. Apply the join command to the target cluster
-	LogOnboardingEvent(clusterName, "Joining", "Applying join command to the target cluster")
-	if err := joinClusterToHub(tempPath, clusterName, joinToken); err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to join cluster: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to join cluster: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Joined", "Cluster successfully joined to the hub")
-
-	// 7. Approve CSRs for the cluster - REPLACED WITH NEW FUNCTION
-	LogOnboardingEvent(clusterName, "Approving", "Looking for and approving Certificate Signing Requests (CSRs)")
-	if err := approveClusterCSRs(hubClientset, clusterName); err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to approve CSRs: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to approve CSRs: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Approved", "CSRs approved successfully")
-
-	// 8. Wait for the managed cluster to be created and accept it
-	LogOnboardingEvent(clusterName, "Waiting", "Waiting for managed cluster resource to be created")
-	if err := waitForManagedCluster(hubClientset, clusterName); err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to confirm managed cluster creation: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to confirm managed cluster creation: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Created", "Managed cluster resource created successfully")
 
-	// Wait a short time for acceptance to propagate
-	LogOnboardingEvent(clusterName, "Processing", "Waiting for acceptance to propagate")
-	time.Sleep(5 * time.Second)
-
-	// 9. Label the managed cluster - USE ONBOARDING-SPECIFIC FUNCTION
-	LogOnboardingEvent(clusterName, "Labeling", "Applying labels to the managed cluster")
-	if err := UpdateManagedClusterLabelsForOnboarding(hubClientset, hubConfig, clusterName, map[string]string{
-		"location-group": "edge",
-		"name":           clusterName,
-	}); err != nil {
-		LogOnboardingEvent(clusterName, "Error", "Failed to label managed cluster: "+err.Error())
-		RegisterOnboardingComplete(clusterName, err)
-		return fmt.Errorf("failed to label managed cluster: %w", err)
-	}
-	LogOnboardingEvent(clusterName, "Labeled", "Cluster labeled successfully")
-
-	// 10. Wait for the cluster to be fully available
-	LogOnboardingEvent(clusterName, "Verifying", "Waiting for cluster to become fully available")
-	startTime := time.Now()
-	timeout := 3 * time.Minute
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	availabilityTimedOut := true
-	for time.Since(startTime) < timeout {
-		<-ticker.C
-
-		// Check the cluster status
-		result := hubClientset.RESTClient().Get().
-			AbsPath("/apis/cluster.open-cluster-management.io/v1").
-			Resource("managedclusters").
-			Name(clusterName).
-			Do(context.TODO())
-
-		raw, err := result.Raw()
+		cluster, err := ocm.GetManagedCluster(ctx, hub, clusterName)
 		if err != nil {
-			LogOnboardingEvent(clusterName, "Warning", fmt.Sprintf("Failed to get managed cluster: %v", err))
+			log.Printf("Error checking managed cluster %s: %v", clusterName, err)
 			continue
 		}
-
-		var managedCluster map[string]interface{}
-		if err := json.Unmarshal(raw, &managedCluster); err != nil {
-			LogOnboardingEvent(clusterName, "Warning", fmt.Sprintf("Failed to unmarshal managed cluster: %v", err))
+		if cluster == nil {
+			log.Printf("Waiting for managed cluster %s to be created...", clusterName)
 			continue
 		}
 
-		status, found := managedCluster["status"].(map[string]interface{})
-		if !found {
-			continue
-		}
+		log.Printf("Managed cluster %s created", clusterName)
+		LogOnboardingEvent(clusterName, "Created", "Managed cluster resource created successfully")
 
-		conditions, found := status["conditions"].([]interface{})
-		if !found {
-			continue
+		if err := ocm.AcceptManagedCluster(ctx, hub, clusterName); err != nil {
+			log.Printf("Warning: %v", err)
+			LogOnboardingEvent(clusterName, "Warning", err.Error())
+			// Continue anyway as it might already be accepted by auto-approval
+		} else {
+			log.Printf("Managed cluster %s accepted", clusterName)
+			LogOnboardingEvent(clusterName, "Accepted", "Managed cluster accepted successfully")
 		}
+		return nil
+	}
+}
 
-		joined := false
-		available := false
-
-		for _, condI := range conditions {
-			cond, ok := condI.(map[string]interface{})
-			if !ok {
-				continue
-			}
+// OnboardCluster handles the entire process of onboarding a cluster without
+// persisting its progress. Onboarding requested through the API runs as an
+// onboarding job instead, see runOnboardingJob.
+func OnboardCluster(kubeconfigData []byte, clusterName, itsContext string) error {
+	return onboardCluster(context.Background(), kubeconfigData, clusterName, itsContext, nil)
+}
 
-			condType, _ := cond["type"].(string)
-			condStatus, _ := cond["status"].(string)
+func onboardCluster(ctx context.Context, kubeconfigData []byte, clusterName, itsContext string, labels map[string]string) error {
+	// Register the start of onboarding and log it
+	RegisterOnboardingStart(clusterName)
 
-			if condType == "ManagedClusterJoined" && condStatus == "True" {
-				joined = true
-			}
+	run := newOnboardingRun(ctx, clusterName, kubeconfigSpokeConfig(kubeconfigData), itsContext, labels)
 
-			if condType == "ManagedClusterConditionAvailable" && condStatus == "True" {
-				available = true
-			}
+	for _, step := range models.OnboardingSteps {
+		if err := run.runStep(step); err != nil {
+			RegisterOnboardingComplete(clusterName, err)
+			return err
 		}
-
-		if joined && available {
-			LogOnboardingEvent(clusterName, "Available", "Cluster is fully available and joined")
-			availabilityTimedOut = false
-			break
-		}
-
-		LogOnboardingEvent(clusterName, "Waiting", fmt.Sprintf("Cluster joined: %v, available: %v", joined, available))
-	}
-
-	if availabilityTimedOut {
-		LogOnboardingEvent(clusterName, "Warning", "Timeout waiting for cluster to become fully available, continuing anyway")
 	}
 
 	LogOnboardingEvent(clusterName, "Success", "Cluster onboarded successfully")
@@ -834,590 +527,256 @@ func OnboardCluster(kubeconfigData []byte, clusterName string) error {
 	return nil
 }
 
-// ValidateClusterConnectivity checks if the cluster is accessible
-func ValidateClusterConnectivity(kubeconfigData []byte) error {
-	// Load REST config from kubeconfig
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
-	if err != nil {
-		return fmt.Errorf("failed to parse kubeconfig: %w", err)
-	}
-
-	client, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %w", err)
-	}
-
-	// Test connectivity by listing nodes
-	_, err = client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to connect to the cluster: %w", err)
-	}
-
-	return nil
-}
-
-// getClusterAdmToken retrieves the join token using clusteradm
-func getClusterAdmToken(hubContext string) (string, error) {
-	cmd := exec.Command("clusteradm", "--context", hubContext, "get", "token")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to get token: %s, %w", string(output), err)
-	}
-
-	// Extract the join command from the output
-	outputStr := string(output)
-	for _, line := range strings.Split(outputStr, "\n") {
-		if strings.HasPrefix(line, "clusteradm join") {
-			return line, nil
-		}
-	}
-
-	return "", fmt.Errorf("join command not found in output: %s", outputStr)
+// bootstrapTokenExpiration is how long the token a cluster registers with stays valid
+const bootstrapTokenExpiration = time.Hour
+
+// onboardingRun holds what the steps of one onboarding pass to each other.
+// Only the step reached is persisted for a job, so a resumed run rebuilds the
+// hub clients and the join token by running those steps again.
+type onboardingRun struct {
+	ctx         context.Context
+	clusterName string
+	// spokeConfig builds the client config of the cluster being onboarded
+	spokeConfig func() (*rest.Config, error)
+	itsContext  string
+	labels      map[string]string
+
+	hubClientset *kubernetes.Clientset
+	hubConfig    *rest.Config
+	hub          *ocm.Clients
+	joinOptions  ocm.JoinOptions
 }
 
-// createTempKubeconfig creates a temporary kubeconfig file
-func createTempKubeconfig(kubeconfigData []byte, clusterName string) (string, error) {
-	// Create temporary file
-	tempDir := os.TempDir()
-	tempFile := filepath.Join(tempDir, fmt.Sprintf("kubeconfig-%s-%d", clusterName, time.Now().UnixNano()))
-
-	// Parse the kubeconfig
-	config, err := clientcmd.Load(kubeconfigData)
-	if err != nil {
-		return "", fmt.Errorf("invalid kubeconfig format: %w", err)
+func newOnboardingRun(ctx context.Context, clusterName string, spokeConfig func() (*rest.Config, error), itsContext string, labels map[string]string) *onboardingRun {
+	return &onboardingRun{
+		ctx:         ctx,
+		clusterName: clusterName,
+		spokeConfig: spokeConfig,
+		itsContext:  itsContext,
+		labels:      onboardingLabels(clusterName, labels),
 	}
-
-	// Adjust the config if needed (e.g., for localhost to proper hostname)
-	adjustClusterServerEndpoints(config)
-
-	// Write the modified config to the temporary file
-	if err := clientcmd.WriteToFile(*config, tempFile); err != nil {
-		return "", fmt.Errorf("failed to write temporary kubeconfig: %w", err)
-	}
-
-	return tempFile, nil
 }
 
-// adjustClusterServerEndpoints replaces localhost with proper names
-func adjustClusterServerEndpoints(config *clientcmdapi.Config) {
-	for name, cluster := range config.Clusters {
-		if strings.Contains(cluster.Server, "localhost") {
-			cluster.Server = strings.Replace(cluster.Server, "localhost", name, 1)
+// kubeconfigSpokeConfig builds the client config of the cluster being
+// onboarded from its kubeconfig
+func kubeconfigSpokeConfig(kubeconfigData []byte) func() (*rest.Config, error) {
+	return func() (*rest.Config, error) {
+		config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
 		}
+		return config, nil
 	}
 }
 
-// joinClusterToHub applies the join command to the target cluster
-func joinClusterToHub(kubeconfigPath, clusterName, joinToken string) error {
-	// Replace cluster name placeholder in join command
-	joinCmd := strings.Replace(joinToken, "<cluster_name>", clusterName, 1)
-
-	// Split the command into arguments
-	cmdParts := strings.Fields(joinCmd)
-	cmdParts = append(cmdParts, "--context", clusterName, "--singleton", "--force-internal-endpoint-lookup")
-
-	// Create the command
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
-
-	output, err := cmd.CombinedOutput()
+// spokeClients builds the clients of the cluster being onboarded
+func (r *onboardingRun) spokeClients() (*ocm.Clients, error) {
+	config, err := r.spokeConfig()
 	if err != nil {
-		return fmt.Errorf("join command failed: %s, %w", string(output), err)
-	}
-
-	log.Printf("Join command output: %s", string(output))
-	return nil
-}
-
-// isLabelProtected checks if a label is protected (with debug logging)
-func isLabelProtected(key string, protectedLabels map[string]bool) bool {
-	log.Printf("[DEBUG] Checking protection for label: %s", key)
-
-	// Check binding policy protection
-	if protectedLabels[key] {
-		log.Printf("[DEBUG] Label %s is protected by binding policy", key)
-		return true
-	}
-
-	// Check system label prefixes
-	systemPrefixes := []string{
-		"cluster.open-cluster-management.io/",
-		"feature.open-cluster-management.io/",
-		"kubernetes.io/",
-		"k8s.io/",
-		"node.openshift.io/",
-		"beta.kubernetes.io/",
-		"topology.kubernetes.io/",
-		"node-role.kubernetes.io/",
+		return nil, err
 	}
-
-	for _, prefix := range systemPrefixes {
-		if strings.HasPrefix(key, prefix) {
-			log.Printf("[DEBUG] Label %s is protected by system prefix: %s", key, prefix)
-			return true
-		}
-	}
-
-	log.Printf("[DEBUG] Label %s is NOT protected", key)
-	return false
+	return ocm.NewClients(config)
 }
 
-// applyLabelChanges applies the final label state to the cluster
-func applyLabelChanges(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, clusterName string, finalLabels map[string]string) error {
-	patches := []map[string]interface{}{
-		{
-			"op":    "replace",
-			"path":  "/metadata/labels",
-			"value": finalLabels,
-		},
-	}
-
-	patchBytes, err := json.Marshal(patches)
-	if err != nil {
-		return fmt.Errorf("failed to marshal patch: %v", err)
+// runStep runs one step of models.OnboardingSteps
+func (r *onboardingRun) runStep(step string) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
 	}
-
-	_, err = dynamicClient.Resource(gvr).Patch(
-		context.TODO(),
-		clusterName,
-		types.JSONPatchType,
-		patchBytes,
-		metav1.PatchOptions{},
-	)
-
-	return err
+	switch step {
+	case models.OnboardingStepValidate:
+		return r.validate()
+	case models.OnboardingStepConnectHub:
+		return r.connectHub()
+	case models.OnboardingStepToken:
+		return r.getToken()
+	case models.OnboardingStepJoin:
+		return r.join()
+	case models.OnboardingStepApproveCSR:
+		return r.approveCSRs()
+	case models.OnboardingStepAccept:
+		return r.accept()
+	case models.OnboardingStepLabel:
+		return r.label()
+	case models.OnboardingStepVerify:
+		return r.verify()
+	}
+	return fmt.Errorf("unknown onboarding step %q", step)
 }
 
-// getProtectedLabels fetches and caches protected labels from binding policies
-var (
-	protectedLabelsCache map[string]bool
-	cacheLastUpdated     time.Time
-	cacheMutex           sync.RWMutex
-	cacheExpiry          = 5 * time.Minute
-)
-
-func getProtectedLabels() (map[string]bool, error) {
-	cacheMutex.RLock()
-	if protectedLabelsCache != nil && time.Since(cacheLastUpdated) < cacheExpiry {
-		defer cacheMutex.RUnlock()
-		return protectedLabelsCache, nil
+func (r *onboardingRun) validate() error {
+	LogOnboardingEvent(r.clusterName, "Validating", "Validating cluster connectivity")
+	config, err := r.spokeConfig()
+	if err == nil {
+		err = checkClusterConnectivity(config)
 	}
-	cacheMutex.RUnlock()
-
-	// Fetch fresh data
-	labels, err := getLabelsUsedInBindingPolicies()
 	if err != nil {
-		return make(map[string]bool), err
+		LogOnboardingEvent(r.clusterName, "Error", "Connectivity validation failed: "+err.Error())
+		return fmt.Errorf("cluster validation failed: %w", err)
 	}
-
-	// Update cache
-	cacheMutex.Lock()
-	protectedLabelsCache = labels
-	cacheLastUpdated = time.Now()
-	cacheMutex.Unlock()
-
-	return labels, nil
+	LogOnboardingEvent(r.clusterName, "Validated", "Cluster connectivity validated successfully")
+	return nil
 }
 
-// getLabelsUsedInBindingPolicies fetches labels used in binding policies
-func getLabelsUsedInBindingPolicies() (map[string]bool, error) {
-	log.Printf("[DEBUG] Fetching labels used in binding policies...")
-
-	usedLabels := make(map[string]bool)
-
-	// Make HTTP request to get binding policies
-	resp, err := bp.GetBindingPolicies("")
+func (r *onboardingRun) connectHub() error {
+	LogOnboardingEvent(r.clusterName, "Connecting", "Connecting to ITS hub context: "+r.itsContext)
+	hubClientset, hubConfig, err := k8s.GetClientSetWithConfigContext(r.itsContext)
 	if err != nil {
-		return usedLabels, fmt.Errorf("failed to fetch binding policies: %v", err)
-	}
-
-	var response struct {
-		BindingPolicies []struct {
-			Name string `json:"name"`
-			Spec struct {
-				ClusterSelectors []struct {
-					MatchLabels      map[string]string `json:"matchLabels"`
-					MatchExpressions []struct {
-						Key string `json:"key"`
-					} `json:"matchExpressions"`
-				} `json:"clusterSelectors"`
-			} `json:"spec"`
-			ClusterSelectors []map[string]string `json:"clusterSelectors"` // For stored policies
-			Clusters         []string            `json:"clusters"`         // For cluster strings like "environment=production"
-			YAML             string              `json:"yaml"`             // Raw YAML content
-		} `json:"bindingPolicies"`
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to get hub clientset: "+err.Error())
+		return fmt.Errorf("failed to get hub clientset: %w", err)
 	}
-
-	// Convert the slice of maps to JSON bytes, then unmarshal into our struct
-	jsonData, err := json.Marshal(map[string]interface{}{"bindingPolicies": resp})
+	hub, err := ocm.NewClients(hubConfig)
 	if err != nil {
-		return usedLabels, fmt.Errorf("failed to marshal binding policies: %v", err)
-	}
-
-	if err := json.Unmarshal(jsonData, &response); err != nil {
-		return usedLabels, fmt.Errorf("failed to decode binding policies response: %v", err)
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to get hub clients: "+err.Error())
+		return fmt.Errorf("failed to get hub clients: %w", err)
 	}
-
-	log.Printf("[DEBUG] Found %d binding policies to analyze", len(response.BindingPolicies))
-
-	// Extract labels from binding policies
-	for _, bp := range response.BindingPolicies {
-		log.Printf("[DEBUG] Processing binding policy: %s", bp.Name)
-
-		// Method 1: Check spec.clusterSelectors.matchLabels (standard format)
-		for _, selector := range bp.Spec.ClusterSelectors {
-			for key := range selector.MatchLabels {
-				usedLabels[key] = true
-				log.Printf("[DEBUG] Found binding policy label from spec.clusterSelectors.matchLabels: %s", key)
-			}
-
-			// Also check matchExpressions
-			for _, expr := range selector.MatchExpressions {
-				if expr.Key != "" {
-					usedLabels[expr.Key] = true
-					log.Printf("[DEBUG] Found binding policy label from spec.clusterSelectors.matchExpressions: %s", expr.Key)
-				}
-			}
-		}
-
-		// Method 2: Check stored clusterSelectors (for UI-created policies)
-		for _, selector := range bp.ClusterSelectors {
-			for key := range selector {
-				usedLabels[key] = true
-				log.Printf("[DEBUG] Found binding policy label from stored clusterSelectors: %s", key)
-			}
-		}
-
-		// Method 3: Check clusters array for "key=value" format
-		for _, cluster := range bp.Clusters {
-			if strings.Contains(cluster, "=") {
-				parts := strings.Split(cluster, "=")
-				if len(parts) >= 2 {
-					key := strings.TrimSpace(parts[0])
-					if key != "" {
-						usedLabels[key] = true
-						log.Printf("[DEBUG] Found binding policy label from clusters array: %s", key)
-					}
-				}
-			} else if strings.Contains(cluster, ":") {
-				// Handle "env:local" format
-				parts := strings.Split(cluster, ":")
-				if len(parts) >= 2 {
-					key := strings.TrimSpace(parts[0])
-					if key != "" {
-						usedLabels[key] = true
-						log.Printf("[DEBUG] Found binding policy label from clusters array (colon format): %s", key)
-					}
-				}
-			}
-		}
-
-		// Method 4: Parse YAML content if available - Enhanced parsing
-		if bp.YAML != "" {
-			log.Printf("[DEBUG] Parsing YAML content for binding policy: %s", bp.Name)
-
-			// Parse matchlabels sections (more flexible regex)
-			matchLabelsPattern := regexp.MustCompile(`(?i)matchlabels:\s*\n((?:\s+\S+:.+\n?)*)`)
-			matches := matchLabelsPattern.FindAllStringSubmatch(bp.YAML, -1)
-
-			for _, match := range matches {
-				if len(match) > 1 {
-					labelBlock := match[1]
-					labelLines := strings.Split(labelBlock, "\n")
-					for _, line := range labelLines {
-						line = strings.TrimSpace(line)
-						if line != "" && strings.Contains(line, ":") {
-							parts := strings.Split(line, ":")
-							if len(parts) >= 1 {
-								key := strings.TrimSpace(parts[0])
-								if key != "" {
-									usedLabels[key] = true
-									log.Printf("[DEBUG] Found binding policy label from YAML matchLabels: %s", key)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			// Also look for direct label references in YAML like "env: local"
-			// This handles the specific case where labels are defined directly in clusterselectors
-			yamlLines := strings.Split(bp.YAML, "\n")
-			inClusterSelectors := false
-			inMatchLabels := false
-
-			for _, line := range yamlLines {
-				trimmed := strings.TrimSpace(line)
-
-				if strings.Contains(trimmed, "clusterselectors:") {
-					inClusterSelectors = true
-					continue
-				}
-
-				if inClusterSelectors && strings.Contains(trimmed, "matchlabels:") {
-					inMatchLabels = true
-					continue
-				}
-
-				if inMatchLabels && strings.Contains(trimmed, ":") && !strings.HasPrefix(trimmed, "-") {
-					// Check if this is a label definition (key: value)
-					if !strings.Contains(trimmed, "matchlabels") &&
-						!strings.Contains(trimmed, "matchexpressions") &&
-						!strings.Contains(trimmed, "downsync") &&
-						!strings.Contains(trimmed, "spec:") {
-
-						parts := strings.Split(trimmed, ":")
-						if len(parts) >= 1 {
-							key := strings.TrimSpace(parts[0])
-							// Filter out non-label keys
-							if key != "" &&
-								!strings.Contains(key, "apigroup") &&
-								!strings.Contains(key, "resources") &&
-								!strings.Contains(key, "namespaces") {
-								usedLabels[key] = true
-								log.Printf("[DEBUG] Found binding policy label from YAML direct parsing: %s", key)
-							}
-						}
-					}
-				}
-
-				// Reset flags when we exit sections
-				if strings.HasPrefix(trimmed, "downsync:") || strings.HasPrefix(trimmed, "spec:") {
-					inClusterSelectors = false
-					inMatchLabels = false
-				}
-			}
-		}
-	}
-
-	log.Printf("[DEBUG] Total binding policy labels found: %d", len(usedLabels))
-	for label := range usedLabels {
-		log.Printf("[DEBUG] BP Label: %s", label)
-	}
-
-	return usedLabels, nil
+	r.hubClientset, r.hubConfig, r.hub = hubClientset, hubConfig, hub
+	LogOnboardingEvent(r.clusterName, "Connected", "Successfully connected to ITS hub")
+	return nil
 }
 
-// UpdateManagedClusterLabels - Fixed version for onboarding
-func UpdateManagedClusterLabels(clientset *kubernetes.Clientset, config *rest.Config, clusterName string, newLabels map[string]string) error {
-	// Get binding policy protected labels once
-	protectedLabels, err := getProtectedLabels()
+func (r *onboardingRun) getToken() error {
+	LogOnboardingEvent(r.clusterName, "Retrieving", "Getting join token from the OCM hub")
+	token, err := ocm.BootstrapToken(r.ctx, r.hub, bootstrapTokenExpiration)
 	if err != nil {
-		log.Printf("[WARNING] Could not fetch protected labels: %v", err)
-		protectedLabels = make(map[string]bool)
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to get token: "+err.Error())
+		return fmt.Errorf("failed to get token: %w", err)
 	}
-
-	dynamicClient, err := dynamic.NewForConfig(config)
+	server, ca, err := ocm.HubEndpoint(r.ctx, r.hub, r.hubConfig, true)
 	if err != nil {
-		return fmt.Errorf("failed to create dynamic client: %v", err)
-	}
-
-	gvr := schema.GroupVersionResource{
-		Group:    "cluster.open-cluster-management.io",
-		Version:  "v1",
-		Resource: "managedclusters",
-	}
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to get hub endpoint: "+err.Error())
+		return fmt.Errorf("failed to get hub endpoint: %w", err)
+	}
+	r.joinOptions = ocm.JoinOptions{
+		ClusterName:   r.clusterName,
+		HubAPIServer:  server,
+		HubCA:         ca,
+		Token:         token,
+		Singleton:     true,
+		ImageRegistry: os.Getenv("OCM_IMAGE_REGISTRY"),
+		ImageTag:      os.Getenv("OCM_IMAGE_TAG"),
+	}
+	LogOnboardingEvent(r.clusterName, "Retrieved", "Successfully retrieved join token")
+	return nil
+}
 
-	// Get current cluster
-	currentCluster, err := dynamicClient.Resource(gvr).Get(context.TODO(), clusterName, metav1.GetOptions{})
+func (r *onboardingRun) join() error {
+	spoke, err := r.spokeClients()
 	if err != nil {
-		return fmt.Errorf("failed to get managed cluster %s: %v", clusterName, err)
-	}
-
-	// Get current labels
-	currentLabels := make(map[string]string)
-	if labels, found, err := unstructured.NestedStringMap(currentCluster.Object, "metadata", "labels"); err == nil && found {
-		currentLabels = labels
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to connect to the target cluster: "+err.Error())
+		return err
 	}
 
-	// Process label changes - Fixed version that allows onboarding labels
-	finalLabels, protectedOps := processLabelChangesForOnboarding(currentLabels, newLabels, protectedLabels)
-
-	// Apply changes
-	err = applyLabelChanges(dynamicClient, gvr, clusterName, finalLabels)
-	if err != nil {
-		return fmt.Errorf("failed to apply label changes: %v", err)
+	LogOnboardingEvent(r.clusterName, "Joining", "Deploying the klusterlet to the target cluster")
+	if err := ocm.Join(r.ctx, spoke, r.joinOptions); err != nil {
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to join cluster: "+err.Error())
+		return fmt.Errorf("failed to join cluster: %w", err)
 	}
+	LogOnboardingEvent(r.clusterName, "Joined", "Cluster successfully joined to the hub")
+	return nil
+}
 
-	// Return error if there were protected operations
-	if len(protectedOps) > 0 {
-		return fmt.Errorf("PARTIAL_SUCCESS: Cannot modify protected labels: %s", strings.Join(protectedOps, ", "))
+func (r *onboardingRun) approveCSRs() error {
+	LogOnboardingEvent(r.clusterName, "Approving", "Deciding Certificate Signing Requests (CSRs) with the approval rules")
+	if err := approveClusterCSRs(r.ctx, r.hub, r.itsContext, r.clusterName); err != nil {
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to approve CSRs: "+err.Error())
+		return fmt.Errorf("failed to approve CSRs: %w", err)
 	}
-
+	LogOnboardingEvent(r.clusterName, "Approved", "CSRs processed by the approval rules")
 	return nil
 }
 
-// processLabelChangesForOnboarding - Fixed version that allows essential onboarding labels
-func processLabelChangesForOnboarding(currentLabels, newLabels map[string]string, protectedLabels map[string]bool) (map[string]string, []string) {
-	log.Printf("[DEBUG] === processLabelChangesForOnboarding START ===")
-	log.Printf("[DEBUG] Current labels: %+v", currentLabels)
-	log.Printf("[DEBUG] New labels: %+v", newLabels)
-	log.Printf("[DEBUG] Protected labels from BP: %+v", protectedLabels)
-
-	finalLabels := make(map[string]string)
-	protectedOps := []string{}
-
-	// Start with current labels
-	for k, v := range currentLabels {
-		finalLabels[k] = v
+func (r *onboardingRun) accept() error {
+	LogOnboardingEvent(r.clusterName, "Waiting", "Waiting for managed cluster resource to be created")
+	if err := waitForManagedCluster(r.ctx, r.hub, r.clusterName); err != nil {
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to confirm managed cluster creation: "+err.Error())
+		return fmt.Errorf("failed to confirm managed cluster creation: %w", err)
 	}
+	LogOnboardingEvent(r.clusterName, "Created", "Managed cluster resource created successfully")
 
-	// Define essential onboarding labels that should always be allowed
-	essentialOnboardingLabels := map[string]bool{
-		"name":           true,
-		"location-group": true,
-		"environment":    true,
-		"cluster-type":   true,
+	// Wait a short time for acceptance to propagate
+	LogOnboardingEvent(r.clusterName, "Processing", "Waiting for acceptance to propagate")
+	select {
+	case <-time.After(5 * time.Second):
+	case <-r.ctx.Done():
+		return r.ctx.Err()
 	}
+	return nil
+}
 
-	// Process each new label operation
-	for key, value := range newLabels {
-		currentValue, exists := currentLabels[key]
-		isProtected := isLabelProtectedForOnboarding(key, protectedLabels, essentialOnboardingLabels)
-
-		log.Printf("[DEBUG] Processing label %s: value='%s', exists=%v, protected=%v", key, value, exists, isProtected)
-
-		if value == "" {
-			// Deletion request
-			if !exists {
-				log.Printf("[DEBUG] Label %s doesn't exist, skipping deletion", key)
-				continue // Nothing to delete
-			}
-
-			if isProtected {
-				log.Printf("[DEBUG] BLOCKING deletion of protected label: %s", key)
-				protectedOps = append(protectedOps, key)
-				// Keep the original value
-				finalLabels[key] = currentValue
-			} else {
-				log.Printf("[DEBUG] ALLOWING deletion of label: %s", key)
-				delete(finalLabels, key)
-			}
-		} else {
-			// Addition or modification
-			if !exists {
-				// New label
-				if isProtected {
-					log.Printf("[DEBUG] BLOCKING addition of protected label: %s", key)
-					protectedOps = append(protectedOps, key)
-					// Don't add protected labels
-				} else {
-					log.Printf("[DEBUG] ALLOWING addition of label: %s = %s", key, value)
-					finalLabels[key] = value
-				}
-			} else if currentValue != value {
-				// Modification
-				if isProtected {
-					log.Printf("[DEBUG] BLOCKING modification of protected label: %s (keeping %s)", key, currentValue)
-					protectedOps = append(protectedOps, key)
-					// Keep original value
-					finalLabels[key] = currentValue
-				} else {
-					log.Printf("[DEBUG] ALLOWING modification of label: %s = %s (was %s)", key, value, currentValue)
-					finalLabels[key] = value
-				}
-			} else {
-				log.Printf("[DEBUG] Label %s unchanged: %s", key, value)
-			}
-			// If value is same as current, no change needed
-		}
+func (r *onboardingRun) label() error {
+	LogOnboardingEvent(r.clusterName, "Labeling", "Applying labels to the managed cluster")
+	if err := UpdateManagedClusterLabelsForOnboarding(r.hubConfig, r.clusterName, r.labels); err != nil {
+		LogOnboardingEvent(r.clusterName, "Error", "Failed to label managed cluster: "+err.Error())
+		return fmt.Errorf("failed to label managed cluster: %w", err)
 	}
-
-	log.Printf("[DEBUG] Final labels: %+v", finalLabels)
-	log.Printf("[DEBUG] Protected operations: %+v", protectedOps)
-	log.Printf("[DEBUG] === processLabelChangesForOnboarding END ===")
-
-	return finalLabels, protectedOps
+	LogOnboardingEvent(r.clusterName, "Labeled", "Cluster labeled successfully")
+	return nil
 }
 
-// isLabelProtectedForOnboarding - Fixed version that allows essential onboarding labels
-func isLabelProtectedForOnboarding(key string, protectedLabels map[string]bool, essentialOnboardingLabels map[string]bool) bool {
-	log.Printf("[DEBUG] Checking protection for label: %s", key)
-
-	// Allow essential onboarding labels regardless of other protection rules
-	if essentialOnboardingLabels[key] {
-		log.Printf("[DEBUG] Label %s is an essential onboarding label, allowing", key)
-		return false
-	}
+// verify waits for the cluster to be fully available. A cluster that does not
+// become available in time is still considered onboarded.
+func (r *onboardingRun) verify() error {
+	LogOnboardingEvent(r.clusterName, "Verifying", "Waiting for cluster to become fully available")
+	startTime := time.Now()
+	timeout := 3 * time.Minute
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
 
-	// Check binding policy protection
-	if protectedLabels[key] {
-		log.Printf("[DEBUG] Label %s is protected by binding policy", key)
-		return true
-	}
+	for time.Since(startTime) < timeout {
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		}
 
-	// Check system label prefixes
-	systemPrefixes := []string{
-		"cluster.open-cluster-management.io/",
-		"feature.open-cluster-management.io/",
-		"kubernetes.io/",
-		"k8s.io/",
-		"node.openshift.io/",
-		"beta.kubernetes.io/",
-		"topology.kubernetes.io/",
-		"node-role.kubernetes.io/",
-	}
+		cluster, err := ocm.GetManagedCluster(r.ctx, r.hub, r.clusterName)
+		if err != nil {
+			LogOnboardingEvent(r.clusterName, "Warning", err.Error())
+			continue
+		}
+		if cluster == nil {
+			LogOnboardingEvent(r.clusterName, "Warning", "Managed cluster not found")
+			continue
+		}
+		joined, available := ocm.JoinedAndAvailable(cluster)
 
-	for _, prefix := range systemPrefixes {
-		if strings.HasPrefix(key, prefix) {
-			log.Printf("[DEBUG] Label %s is protected by system prefix: %s", key, prefix)
-			return true
+		if joined && available {
+			LogOnboardingEvent(r.clusterName, "Available", "Cluster is fully available and joined")
+			return nil
 		}
+
+		LogOnboardingEvent(r.clusterName, "Waiting", fmt.Sprintf("Cluster joined: %v, available: %v", joined, available))
 	}
 
-	log.Printf("[DEBUG] Label %s is NOT protected", key)
-	return false
+	LogOnboardingEvent(r.clusterName, "Warning", "Timeout waiting for cluster to become fully available, continuing anyway")
+	return nil
 }
 
-// Alternative approach: Create a separate function for onboarding that bypasses protection
-func UpdateManagedClusterLabelsForOnboarding(clientset *kubernetes.Clientset, config *rest.Config, clusterName string, newLabels map[string]string) error {
-	log.Printf("[DEBUG] Updating labels for onboarding cluster %s with labels: %+v", clusterName, newLabels)
-
-	dynamicClient, err := dynamic.NewForConfig(config)
+// ValidateClusterConnectivity checks if the cluster is accessible
+func ValidateClusterConnectivity(kubeconfigData []byte) error {
+	// Load REST config from kubeconfig
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
 	if err != nil {
-		return fmt.Errorf("failed to create dynamic client: %v", err)
-	}
-
-	gvr := schema.GroupVersionResource{
-		Group:    "cluster.open-cluster-management.io",
-		Version:  "v1",
-		Resource: "managedclusters",
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
 	}
+	return checkClusterConnectivity(config)
+}
 
-	// Get current cluster
-	currentCluster, err := dynamicClient.Resource(gvr).Get(context.TODO(), clusterName, metav1.GetOptions{})
+// checkClusterConnectivity checks if the cluster config points to is
+// accessible
+func checkClusterConnectivity(config *rest.Config) error {
+	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to get managed cluster %s: %v", clusterName, err)
-	}
-
-	// Get current labels
-	currentLabels := make(map[string]string)
-	if labels, found, err := unstructured.NestedStringMap(currentCluster.Object, "metadata", "labels"); err == nil && found {
-		currentLabels = labels
-	}
-
-	// For onboarding, we want to add the new labels without protection checks
-	// but preserve existing system labels
-	finalLabels := make(map[string]string)
-
-	// Start with current labels
-	for k, v := range currentLabels {
-		finalLabels[k] = v
-	}
-
-	// Add/update with new labels (onboarding labels are always allowed)
-	for k, v := range newLabels {
-		finalLabels[k] = v
-		log.Printf("[DEBUG] Setting onboarding label: %s = %s", k, v)
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
 	}
 
-	// Apply changes
-	err = applyLabelChanges(dynamicClient, gvr, clusterName, finalLabels)
+	// Test connectivity by listing nodes
+	_, err = client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to apply label changes during onboarding: %v", err)
+		return fmt.Errorf("failed to connect to the cluster: %w", err)
 	}
 
-	log.Printf("[DEBUG] Successfully updated labels for onboarding cluster %s", clusterName)
 	return nil
 }
 
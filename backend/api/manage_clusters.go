@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/clusterhealth"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/telemetry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -43,12 +45,19 @@ type ManagedClusterInfo struct {
 	Status            ManagedClusterStatus `json:"status,omitempty"`
 	Available         bool                 `json:"available"`
 	Joined            bool                 `json:"joined"`
+	// Health is the recorded health of the cluster, including its lease
+	// heartbeat, when health recording runs
+	Health *clusterhealth.ClusterState `json:"health,omitempty"`
 }
 
 // GetManagedClustersHandler returns a list of all managed clusters
 func GetManagedClustersHandler(c *gin.Context) {
 	// Get the hub context
-	hubContext := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	hubContext := its.Context
 	startTime := time.Now()
 	// Get client config for the hub
 	_, restConfig, err := k8s.GetClientSetWithConfigContext(hubContext)
@@ -86,6 +95,11 @@ func GetManagedClustersHandler(c *gin.Context) {
 		})
 		return
 	}
+	for i := range clusters {
+		if state, ok := clusterhealth.Current(hubContext, clusters[i].Name); ok {
+			clusters[i].Health = &state
+		}
+	}
 	telemetry.HTTPRequestDuration.WithLabelValues("GET", "/api/new/clusters").Observe(time.Since(startTime).Seconds())
 	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/new/clusters", "200").Inc()
 	c.JSON(200, gin.H{
@@ -121,7 +135,11 @@ func GetManagedClusterHandler(c *gin.Context) {
 	}
 
 	// Get the hub context
-	hubContext := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	hubContext := its.Context
 
 	// Get client config for the hub
 	_, restConfig, err := k8s.GetClientSetWithConfigContext(hubContext)
@@ -159,6 +177,9 @@ func GetManagedClusterHandler(c *gin.Context) {
 		})
 		return
 	}
+	if state, ok := clusterhealth.Current(hubContext, cluster.Name); ok {
+		cluster.Health = &state
+	}
 	telemetry.HTTPRequestDuration.WithLabelValues("GET", "/api/clusters/:name").Observe(time.Since(startTime).Seconds())
 	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/clusters/:name", "200").Inc()
 	c.JSON(200, cluster)
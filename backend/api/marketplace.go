@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/auth"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/marketplace"
 	"github.com/kubestellar/ui/backend/models"
@@ -53,7 +54,7 @@ func HandlePluginFile(c *gin.Context, file multipart.File, header *multipart.Fil
 
 	// 2. Check if plugin exists in the DB
 	// get author's ID from DB
-	author, err := models.GetUserByUsername(manifest.Metadata.Author)
+	author, err := auth.Users.GetUser(manifest.Metadata.Author)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Unable to get author from database: " + manifest.Metadata.Author,
@@ -0,0 +1,778 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/labelpolicy"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultBulkOnboardingConcurrency = 5
+	maxBulkOnboardingConcurrency     = 20
+	maxBulkOnboardingClusters        = 500
+	// bulkOnboardingPollInterval is how often a bulk onboarding checks a job
+	// that is run by another request or worker
+	bulkOnboardingPollInterval = 5 * time.Second
+	// bulkOnboardingRetention is how long a finished bulk onboarding can
+	// still be looked up
+	bulkOnboardingRetention = 24 * time.Hour
+	// bulkManifestField is the multipart field holding the manifest; every
+	// other file of the form is a kubeconfig that entries can reference
+	bulkManifestField = "manifest"
+	// maxBulkManifestSize caps the size of a bulk onboarding request, the
+	// manifest and the uploaded kubeconfigs together
+	maxBulkManifestSize = 32 << 20
+)
+
+// States of a cluster in a bulk onboarding
+const (
+	BulkOnboardingQueued           = "queued"
+	BulkOnboardingRunning          = "running"
+	BulkOnboardingSucceeded        = "succeeded"
+	BulkOnboardingFailed           = "failed"
+	BulkOnboardingNeedsCSRApproval = "needs_csr_approval"
+)
+
+// Types of BulkOnboardingEvent
+const (
+	BulkEventProgress = "progress"
+	BulkEventState    = "state"
+	BulkEventSummary  = "summary"
+)
+
+// BulkOnboardingCluster is one cluster of a bulk onboarding manifest. Its
+// kubeconfig is given inline, or by KubeconfigRef as the name of an uploaded
+// file, or else taken from the local kubeconfig. Context selects a context of
+// that kubeconfig; without it the current context is used, or for the local
+// kubeconfig the cluster of the same name.
+type BulkOnboardingCluster struct {
+	Name          string            `json:"name"`
+	Kubeconfig    string            `json:"kubeconfig,omitempty"`
+	KubeconfigRef string            `json:"kubeconfigRef,omitempty"`
+	Context       string            `json:"context,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// BulkOnboardingManifest is the request body of BulkOnboardClustersHandler,
+// in JSON or YAML
+type BulkOnboardingManifest struct {
+	// Concurrency is how many clusters are onboarded at the same time
+	Concurrency int                     `json:"concurrency,omitempty"`
+	Clusters    []BulkOnboardingCluster `json:"clusters"`
+}
+
+// BulkOnboardingResult is the progress of one cluster of a bulk onboarding
+type BulkOnboardingResult struct {
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	Message     string     `json:"message,omitempty"`
+	JobID       int64      `json:"jobId,omitempty"`
+	PendingCSRs []string   `json:"pendingCSRs,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// BulkOnboardingSummary groups the clusters of a bulk onboarding by outcome
+type BulkOnboardingSummary struct {
+	Total            int      `json:"total"`
+	Succeeded        []string `json:"succeeded"`
+	Failed           []string `json:"failed"`
+	NeedsCSRApproval []string `json:"needsCsrApproval"`
+	InProgress       []string `json:"inProgress"`
+}
+
+// BulkOnboardingEvent is one message of the aggregated progress stream of a
+// bulk onboarding: an onboarding event of one of its clusters, a cluster
+// changing state, or the final summary
+type BulkOnboardingEvent struct {
+	BatchID     string                 `json:"batchId"`
+	Type        string                 `json:"type"`
+	ClusterName string                 `json:"clusterName,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	Summary     *BulkOnboardingSummary `json:"summary,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// bulkOnboarding is a running or finished bulk onboarding. Its clusters are
+// onboarded as onboarding jobs, which are persisted; the batch itself is only
+// kept in memory.
+type bulkOnboarding struct {
+	mu          sync.Mutex
+	id          string
+	itsContext  string
+	createdBy   string
+	concurrency int
+	clusters    []BulkOnboardingCluster
+	kubeconfigs map[string][]byte
+	results     map[string]*BulkOnboardingResult
+	events      []BulkOnboardingEvent
+	subscribers map[chan BulkOnboardingEvent]struct{}
+	createdAt   time.Time
+	finishedAt  *time.Time
+}
+
+var (
+	bulkOnboardings      = make(map[string]*bulkOnboarding)
+	bulkOnboardingsMutex sync.RWMutex
+
+	// bulkOnboardingClusters maps a cluster to the bulk onboarding currently
+	// onboarding it, so that its onboarding events reach the batch stream
+	bulkOnboardingClusters      = make(map[string]*bulkOnboarding)
+	bulkOnboardingClustersMutex sync.RWMutex
+)
+
+// BulkOnboardClustersHandler onboards the clusters of a manifest with a
+// concurrency limit. The manifest is the JSON or YAML request body, or the
+// "manifest" field of a multipart form whose files are the kubeconfigs
+// referenced by the manifest. Progress is streamed by
+// WSBulkOnboardingHandler and summarized by GetBulkOnboardingHandler.
+func BulkOnboardClustersHandler(c *gin.Context) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard/bulk", "400").Inc()
+		return
+	}
+
+	manifest, files, err := readBulkOnboardingManifest(c)
+	if err == nil {
+		err = validateBulkOnboardingManifest(manifest, files)
+	}
+	if err == nil {
+		err = checkBulkOnboardingLabels(manifest)
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard/bulk", "400").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch := newBulkOnboarding(its.Context, c.GetString("username"), manifest, files)
+	bulkOnboardingsMutex.Lock()
+	pruneBulkOnboardings(time.Now())
+	bulkOnboardings[batch.id] = batch
+	bulkOnboardingsMutex.Unlock()
+
+	log.LogInfo("Starting bulk onboarding",
+		zap.String("batch", batch.id),
+		zap.String("its", its.Context),
+		zap.Int("clusters", len(batch.clusters)),
+		zap.Int("concurrency", batch.concurrency))
+	go batch.run()
+
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/clusters/onboard/bulk", "202").Inc()
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":           fmt.Sprintf("Onboarding %d clusters", len(batch.clusters)),
+		"batchId":           batch.id,
+		"concurrency":       batch.concurrency,
+		"statusEndpoint":    "/clusters/onboard/bulk/" + batch.id,
+		"websocketEndpoint": "/ws/onboarding/bulk?batch=" + batch.id,
+	})
+}
+
+// GetBulkOnboardingHandler returns the state of every cluster of a bulk
+// onboarding and its summary
+func GetBulkOnboardingHandler(c *gin.Context) {
+	batch := getBulkOnboarding(c.Param("id"))
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bulk onboarding not found"})
+		return
+	}
+	c.JSON(http.StatusOK, batch.status())
+}
+
+// WSBulkOnboardingHandler streams the progress of a bulk onboarding: the
+// events so far, then new events until the final summary
+func WSBulkOnboardingHandler(c *gin.Context) {
+	batch := getBulkOnboarding(c.Query("batch"))
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bulk onboarding not found"})
+		return
+	}
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.LogError("Failed to upgrade connection", zap.String("error", err.Error()))
+		return
+	}
+	defer ws.Close()
+
+	history, events := batch.subscribe()
+	defer batch.unsubscribe(events)
+
+	// Reading detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		ws.SetReadLimit(512)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range history {
+		ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := ws.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if !ok {
+				ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bulk onboarding finished"))
+				return
+			}
+			if err := ws.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// readBulkOnboardingManifest reads the manifest and the uploaded kubeconfig
+// files of a request
+func readBulkOnboardingManifest(c *gin.Context) (*BulkOnboardingManifest, map[string][]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkManifestSize)
+	files := map[string][]byte{}
+	var raw []byte
+
+	if strings.Contains(c.GetHeader("Content-Type"), "multipart/form-data") {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid multipart form: %v", err)
+		}
+		if values := form.Value[bulkManifestField]; len(values) > 0 {
+			raw = []byte(values[0])
+		}
+		for field, headers := range form.File {
+			for _, header := range headers {
+				f, err := header.Open()
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to open %s: %v", header.Filename, err)
+				}
+				data, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to read %s: %v", header.Filename, err)
+				}
+				if field == bulkManifestField {
+					raw = data
+					continue
+				}
+				// Files can be referenced by field or by file name
+				files[field] = data
+				files[header.Filename] = data
+			}
+		}
+	} else {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		raw = data
+	}
+
+	if len(raw) == 0 {
+		return nil, nil, errors.New("a manifest of clusters is required")
+	}
+	var manifest BulkOnboardingManifest
+	if err := yaml.Unmarshal(raw, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return &manifest, files, nil
+}
+
+// validateBulkOnboardingManifest rejects manifests that cannot be run at all.
+// Problems of single clusters, like an unreachable cluster, fail only that
+// cluster.
+func validateBulkOnboardingManifest(manifest *BulkOnboardingManifest, files map[string][]byte) error {
+	if len(manifest.Clusters) == 0 {
+		return errors.New("the manifest lists no clusters")
+	}
+	if len(manifest.Clusters) > maxBulkOnboardingClusters {
+		return fmt.Errorf("at most %d clusters can be onboarded at once", maxBulkOnboardingClusters)
+	}
+	if manifest.Concurrency < 0 || manifest.Concurrency > maxBulkOnboardingConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d", maxBulkOnboardingConcurrency)
+	}
+
+	seen := map[string]bool{}
+	for i, cluster := range manifest.Clusters {
+		if cluster.Name == "" {
+			return fmt.Errorf("cluster %d has no name", i+1)
+		}
+		if errs := validation.IsDNS1123Subdomain(cluster.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid cluster name %q: %s", cluster.Name, strings.Join(errs, ", "))
+		}
+		if seen[cluster.Name] {
+			return fmt.Errorf("cluster %q is listed more than once", cluster.Name)
+		}
+		seen[cluster.Name] = true
+		if cluster.Kubeconfig != "" && cluster.KubeconfigRef != "" {
+			return fmt.Errorf("cluster %q has both an inline kubeconfig and a kubeconfigRef", cluster.Name)
+		}
+		if cluster.KubeconfigRef != "" {
+			if _, ok := files[cluster.KubeconfigRef]; !ok {
+				return fmt.Errorf("kubeconfig %q of cluster %q was not uploaded", cluster.KubeconfigRef, cluster.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// checkBulkOnboardingLabels rejects manifests with clusters whose labels
+// violate the label schema
+func checkBulkOnboardingLabels(manifest *BulkOnboardingManifest) error {
+	labelSchema, err := labelpolicy.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load the label schema: %v", err)
+	}
+	for _, cluster := range manifest.Clusters {
+		violations := labelSchema.CheckOnboarding(onboardingLabels(cluster.Name, cluster.Labels))
+		if len(violations) == 0 {
+			continue
+		}
+		refused := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			refused = append(refused, violation.String())
+		}
+		return fmt.Errorf("the labels of cluster %q violate the label schema: %s", cluster.Name, strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+func newBulkOnboarding(itsContext, createdBy string, manifest *BulkOnboardingManifest, files map[string][]byte) *bulkOnboarding {
+	concurrency := manifest.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultBulkOnboardingConcurrency
+	}
+	batch := &bulkOnboarding{
+		id:          newBulkOnboardingID(),
+		itsContext:  itsContext,
+		createdBy:   createdBy,
+		concurrency: concurrency,
+		clusters:    manifest.Clusters,
+		kubeconfigs: files,
+		results:     make(map[string]*BulkOnboardingResult),
+		subscribers: make(map[chan BulkOnboardingEvent]struct{}),
+		createdAt:   time.Now(),
+	}
+	for _, cluster := range manifest.Clusters {
+		batch.results[cluster.Name] = &BulkOnboardingResult{Name: cluster.Name, State: BulkOnboardingQueued}
+	}
+	return batch
+}
+
+func newBulkOnboardingID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+func getBulkOnboarding(id string) *bulkOnboarding {
+	bulkOnboardingsMutex.RLock()
+	defer bulkOnboardingsMutex.RUnlock()
+	return bulkOnboardings[id]
+}
+
+// pruneBulkOnboardings forgets batches that finished more than
+// bulkOnboardingRetention ago. The caller holds bulkOnboardingsMutex.
+func pruneBulkOnboardings(now time.Time) {
+	for id, batch := range bulkOnboardings {
+		batch.mu.Lock()
+		expired := batch.finishedAt != nil && now.Sub(*batch.finishedAt) > bulkOnboardingRetention
+		batch.mu.Unlock()
+		if expired {
+			delete(bulkOnboardings, id)
+		}
+	}
+}
+
+// run onboards the clusters, at most concurrency at a time, in the order of
+// the manifest
+func (b *bulkOnboarding) run() {
+	slots := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for _, cluster := range b.clusters {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(cluster BulkOnboardingCluster) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			b.onboard(cluster)
+		}(cluster)
+	}
+	wg.Wait()
+	b.finish()
+}
+
+// onboard onboards one cluster and records its outcome
+func (b *bulkOnboarding) onboard(cluster BulkOnboardingCluster) {
+	if !claimBulkOnboardingCluster(cluster.Name, b) {
+		b.setResult(cluster.Name, BulkOnboardingFailed, "the cluster is already part of another bulk onboarding", nil)
+		return
+	}
+	defer releaseBulkOnboardingCluster(cluster.Name, b)
+
+	b.setResult(cluster.Name, BulkOnboardingRunning, "Onboarding started", nil)
+
+	kubeconfigData, err := b.kubeconfigFor(cluster)
+	if err != nil {
+		b.setResult(cluster.Name, BulkOnboardingFailed, err.Error(), nil)
+		return
+	}
+
+	if onboardingJobsEnabled {
+		err = b.runJob(cluster, kubeconfigData)
+	} else {
+		err = b.runInMemory(cluster, kubeconfigData)
+	}
+	if err != nil {
+		b.setResult(cluster.Name, BulkOnboardingFailed, err.Error(), nil)
+		return
+	}
+
+	// Onboarding does not fail when no CSR was approved in time, the cluster
+	// then waits for someone to approve it
+	pending, err := pendingClusterCSRs(b.itsContext, cluster.Name)
+	if err != nil {
+		log.LogWarn("Failed to check pending CSRs", zap.String("cluster", cluster.Name), zap.Error(err))
+	}
+	if len(pending) > 0 {
+		b.setResult(cluster.Name, BulkOnboardingNeedsCSRApproval,
+			fmt.Sprintf("%d certificate signing requests wait for manual approval", len(pending)), pending)
+		return
+	}
+	b.setResult(cluster.Name, BulkOnboardingSucceeded, "Cluster onboarded successfully", nil)
+}
+
+// kubeconfigFor returns the kubeconfig of a cluster of the manifest, reduced
+// to its context
+func (b *bulkOnboarding) kubeconfigFor(cluster BulkOnboardingCluster) ([]byte, error) {
+	var data []byte
+	switch {
+	case cluster.Kubeconfig != "":
+		data = []byte(cluster.Kubeconfig)
+	case cluster.KubeconfigRef != "":
+		data = b.kubeconfigs[cluster.KubeconfigRef]
+	case cluster.Context == "":
+		config, err := getClusterConfigFromLocal(cluster.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cluster '%s' in local kubeconfig: %v", cluster.Name, err)
+		}
+		return config, nil
+	default:
+		config, err := clientcmd.LoadFromFile(kubeconfigPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local kubeconfig: %v", err)
+		}
+		return extractContextConfig(config, cluster.Context)
+	}
+
+	if cluster.Context == "" {
+		return data, nil
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	return extractContextConfig(config, cluster.Context)
+}
+
+// runJob onboards a cluster as an onboarding job and waits for the job to
+// finish, including a job for the same request that was already running
+func (b *bulkOnboarding) runJob(cluster BulkOnboardingCluster, kubeconfigData []byte) error {
+	job, created, err := createOnboardingJob(&models.OnboardingJob{
+		ClusterName: cluster.Name,
+		RequestKey:  kubeconfigRequestKey(cluster.Name, kubeconfigData),
+		ITSContext:  b.itsContext,
+		Labels:      cluster.Labels,
+		CreatedBy:   b.createdBy,
+	}, kubeconfigData)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.results[cluster.Name].JobID = job.ID
+	b.mu.Unlock()
+
+	if created {
+		ClearOnboardingEvents(cluster.Name)
+		setOnboardingStatus(cluster.Name, "Pending")
+		LogOnboardingEvent(cluster.Name, "Initiated", "Onboarding process initiated by bulk onboarding "+b.id)
+		runOnboardingJob(job.ID, "")
+	} else {
+		b.publish(BulkOnboardingEvent{Type: BulkEventProgress, ClusterName: cluster.Name, Status: job.State,
+			Message: fmt.Sprintf("Following existing onboarding job %d", job.ID)})
+	}
+
+	// The job may be owned by another worker, e.g. after a lost lease
+	for {
+		job, err = models.GetOnboardingJob(job.ID)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return errors.New("onboarding job disappeared")
+		}
+		if !job.Active() {
+			break
+		}
+		time.Sleep(bulkOnboardingPollInterval)
+	}
+	if job.State != models.OnboardingSucceeded {
+		if job.Error != "" {
+			return errors.New(job.Error)
+		}
+		return fmt.Errorf("onboarding job %d ended %s", job.ID, job.State)
+	}
+	return nil
+}
+
+// runInMemory onboards a cluster when onboarding jobs are not available
+func (b *bulkOnboarding) runInMemory(cluster BulkOnboardingCluster, kubeconfigData []byte) error {
+	mutex.Lock()
+	if status, exists := clusterStatuses[cluster.Name]; exists && status == "Pending" {
+		mutex.Unlock()
+		return fmt.Errorf("cluster '%s' is already being onboarded", cluster.Name)
+	}
+	clusterStatuses[cluster.Name] = "Pending"
+	mutex.Unlock()
+
+	ClearOnboardingEvents(cluster.Name)
+	LogOnboardingEvent(cluster.Name, "Initiated", "Onboarding process initiated by bulk onboarding "+b.id)
+	err := onboardCluster(context.Background(), kubeconfigData, cluster.Name, b.itsContext, cluster.Labels)
+	if err != nil {
+		setOnboardingStatus(cluster.Name, "Failed")
+	} else {
+		setOnboardingStatus(cluster.Name, "Onboarded")
+	}
+	return err
+}
+
+// pendingClusterCSRs returns the CSRs of a cluster that were neither approved
+// nor denied
+func pendingClusterCSRs(itsContext, clusterName string) ([]string, error) {
+	_, hubConfig, err := k8s.GetClientSetWithConfigContext(itsContext)
+	if err != nil {
+		return nil, err
+	}
+	hubClients, err := ocm.NewClients(hubConfig)
+	if err != nil {
+		return nil, err
+	}
+	csrs, err := ocm.PendingCSRs(context.Background(), hubClients, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(csrs))
+	for _, csr := range csrs {
+		names = append(names, csr.Name)
+	}
+	return names, nil
+}
+
+func claimBulkOnboardingCluster(clusterName string, b *bulkOnboarding) bool {
+	bulkOnboardingClustersMutex.Lock()
+	defer bulkOnboardingClustersMutex.Unlock()
+	if _, exists := bulkOnboardingClusters[clusterName]; exists {
+		return false
+	}
+	bulkOnboardingClusters[clusterName] = b
+	return true
+}
+
+func releaseBulkOnboardingCluster(clusterName string, b *bulkOnboarding) {
+	bulkOnboardingClustersMutex.Lock()
+	defer bulkOnboardingClustersMutex.Unlock()
+	if bulkOnboardingClusters[clusterName] == b {
+		delete(bulkOnboardingClusters, clusterName)
+	}
+}
+
+// forwardBulkOnboardingEvent adds an onboarding event to the stream of the
+// bulk onboarding the cluster is part of, if any
+func forwardBulkOnboardingEvent(event OnboardingEvent) {
+	bulkOnboardingClustersMutex.RLock()
+	batch, ok := bulkOnboardingClusters[event.ClusterName]
+	bulkOnboardingClustersMutex.RUnlock()
+	if !ok {
+		return
+	}
+	batch.publish(BulkOnboardingEvent{
+		Type:        BulkEventProgress,
+		ClusterName: event.ClusterName,
+		Status:      event.Status,
+		Message:     event.Message,
+		Timestamp:   event.Timestamp,
+	})
+}
+
+func (b *bulkOnboarding) setResult(clusterName, state, message string, pendingCSRs []string) {
+	now := time.Now()
+	b.mu.Lock()
+	result := b.results[clusterName]
+	result.State = state
+	result.Message = message
+	result.PendingCSRs = pendingCSRs
+	if state == BulkOnboardingRunning {
+		result.StartedAt = &now
+	} else if state != BulkOnboardingQueued {
+		result.FinishedAt = &now
+	}
+	b.mu.Unlock()
+
+	b.publish(BulkOnboardingEvent{Type: BulkEventState, ClusterName: clusterName, Status: state, Message: message})
+}
+
+// finish publishes the summary and ends the streams of the batch
+func (b *bulkOnboarding) finish() {
+	summary := b.summary()
+	b.publish(BulkOnboardingEvent{Type: BulkEventSummary, Summary: &summary,
+		Message: fmt.Sprintf("%d succeeded, %d failed, %d need CSR approval",
+			len(summary.Succeeded), len(summary.Failed), len(summary.NeedsCSRApproval))})
+
+	now := time.Now()
+	b.mu.Lock()
+	b.finishedAt = &now
+	for events := range b.subscribers {
+		close(events)
+	}
+	b.subscribers = make(map[chan BulkOnboardingEvent]struct{})
+	b.mu.Unlock()
+
+	log.LogInfo("Bulk onboarding finished",
+		zap.String("batch", b.id),
+		zap.Int("succeeded", len(summary.Succeeded)),
+		zap.Int("failed", len(summary.Failed)),
+		zap.Int("needsCsrApproval", len(summary.NeedsCSRApproval)))
+}
+
+// publish records an event and sends it to the subscribers, dropping it for
+// subscribers that do not keep up
+func (b *bulkOnboarding) publish(event BulkOnboardingEvent) {
+	event.BatchID = b.id
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			log.LogInfo("Bulk onboarding client buffer full, dropping event", zap.String("batch", b.id))
+		}
+	}
+}
+
+// subscribe returns the events so far and a channel of the events to come,
+// which is closed when the batch finishes
+func (b *bulkOnboarding) subscribe() ([]BulkOnboardingEvent, chan BulkOnboardingEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	history := make([]BulkOnboardingEvent, len(b.events))
+	copy(history, b.events)
+	events := make(chan BulkOnboardingEvent, 256)
+	if b.finishedAt != nil {
+		close(events)
+	} else {
+		b.subscribers[events] = struct{}{}
+	}
+	return history, events
+}
+
+func (b *bulkOnboarding) unsubscribe(events chan BulkOnboardingEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+func (b *bulkOnboarding) summary() BulkOnboardingSummary {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	summary := BulkOnboardingSummary{
+		Total:            len(b.clusters),
+		Succeeded:        []string{},
+		Failed:           []string{},
+		NeedsCSRApproval: []string{},
+		InProgress:       []string{},
+	}
+	for _, cluster := range b.clusters {
+		switch b.results[cluster.Name].State {
+		case BulkOnboardingSucceeded:
+			summary.Succeeded = append(summary.Succeeded, cluster.Name)
+		case BulkOnboardingFailed:
+			summary.Failed = append(summary.Failed, cluster.Name)
+		case BulkOnboardingNeedsCSRApproval:
+			summary.NeedsCSRApproval = append(summary.NeedsCSRApproval, cluster.Name)
+		default:
+			summary.InProgress = append(summary.InProgress, cluster.Name)
+		}
+	}
+	return summary
+}
+
+func (b *bulkOnboarding) status() gin.H {
+	summary := b.summary()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	results := make([]BulkOnboardingResult, 0, len(b.results))
+	for _, result := range b.results {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return gin.H{
+		"batchId":     b.id,
+		"its":         b.itsContext,
+		"createdBy":   b.createdBy,
+		"concurrency": b.concurrency,
+		"createdAt":   b.createdAt,
+		"finishedAt":  b.finishedAt,
+		"finished":    b.finishedAt != nil,
+		"clusters":    results,
+		"summary":     summary,
+	}
+}
@@ -0,0 +1,572 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"github.com/kubestellar/ui/backend/vault"
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// onboardingLease is how long a job stays owned by its worker without a renewal
+	onboardingLease = 2 * time.Minute
+	// onboardingSweepInterval is how often jobs abandoned by other workers are picked up
+	onboardingSweepInterval = time.Minute
+	// unjoinTimeout is how long a rollback waits for the klusterlet to be removed
+	unjoinTimeout = 5 * time.Minute
+)
+
+var (
+	onboardingJobsOnce    sync.Once
+	onboardingJobsEnabled bool
+	onboardingWorkerID    string
+
+	// runningOnboardingJobs maps a cluster name to the job this process is
+	// running for it, so that LogOnboardingEvent can persist its events
+	runningOnboardingJobs  = make(map[string]*runningOnboardingJob)
+	runningOnboardingMutex sync.RWMutex
+)
+
+type runningOnboardingJob struct {
+	id   int64
+	step string
+}
+
+// StartOnboardingJobs enables persisted onboarding jobs and recovers the jobs
+// that were interrupted, e.g. by a restart. It must be called after the
+// database is initialized. Until then onboarding runs in memory only, so
+// handlers can be exercised in tests without a database.
+func StartOnboardingJobs() {
+	onboardingJobsOnce.Do(func() {
+		hostname, _ := os.Hostname()
+		onboardingWorkerID = fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+		onboardingJobsEnabled = true
+		go sweepOnboardingJobs()
+	})
+}
+
+// sweepOnboardingJobs resumes or rolls back pending jobs and jobs whose
+// worker stopped renewing its lease
+func sweepOnboardingJobs() {
+	for {
+		recoverOnboardingJobs()
+		time.Sleep(onboardingSweepInterval)
+	}
+}
+
+func recoverOnboardingJobs() {
+	jobs, err := models.ListRecoverableOnboardingJobs()
+	if err != nil {
+		log.LogError("Failed to list recoverable onboarding jobs", zap.Error(err))
+		return
+	}
+	for _, job := range jobs {
+		if isOnboardingJobRunning(job.ClusterName) {
+			continue
+		}
+		// Decide before claiming, which updates the job
+		action := job.RecoveryAction(time.Now())
+		log.LogInfo("Recovering onboarding job",
+			zap.Int64("job", job.ID),
+			zap.String("cluster", job.ClusterName),
+			zap.String("state", job.State),
+			zap.String("step", job.Step),
+			zap.String("action", action))
+		go runOnboardingJob(job.ID, action)
+	}
+}
+
+func isOnboardingJobRunning(clusterName string) bool {
+	runningOnboardingMutex.RLock()
+	defer runningOnboardingMutex.RUnlock()
+	_, running := runningOnboardingJobs[clusterName]
+	return running
+}
+
+// recordOnboardingJobEvent persists an onboarding event if a job of this
+// process is running for the cluster
+func recordOnboardingJobEvent(clusterName, status, message string) {
+	runningOnboardingMutex.RLock()
+	running, ok := runningOnboardingJobs[clusterName]
+	var id int64
+	var step string
+	if ok {
+		id, step = running.id, running.step
+	}
+	runningOnboardingMutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	if err := models.AddOnboardingJobEvent(id, step, status, message); err != nil {
+		log.LogWarn("Failed to persist onboarding event", zap.Int64("job", id), zap.Error(err))
+	}
+}
+
+func setRunningOnboardingStep(clusterName, step string) {
+	runningOnboardingMutex.Lock()
+	defer runningOnboardingMutex.Unlock()
+	if running, ok := runningOnboardingJobs[clusterName]; ok {
+		running.step = step
+	}
+}
+
+// runOnboardingJob claims a job and runs its remaining steps. Jobs asked to
+// cancel and interrupted jobs recovered with models.OnboardingRollback are
+// rolled back instead; recovery is empty for jobs that were not interrupted.
+func runOnboardingJob(id int64, recovery string) {
+	job, err := models.ClaimOnboardingJob(id, onboardingWorkerID, onboardingLease)
+	if err != nil {
+		log.LogError("Failed to claim onboarding job", zap.Int64("job", id), zap.Error(err))
+		return
+	}
+	if job == nil {
+		// Finished meanwhile or owned by another worker
+		return
+	}
+
+	runningOnboardingMutex.Lock()
+	if _, running := runningOnboardingJobs[job.ClusterName]; running {
+		runningOnboardingMutex.Unlock()
+		return
+	}
+	runningOnboardingJobs[job.ClusterName] = &runningOnboardingJob{id: job.ID, step: job.Step}
+	runningOnboardingMutex.Unlock()
+	defer func() {
+		runningOnboardingMutex.Lock()
+		delete(runningOnboardingJobs, job.ClusterName)
+		runningOnboardingMutex.Unlock()
+	}()
+
+	// The lease is renewed until the job is finished, including a rollback
+	// after the steps were cancelled
+	ctx, cancelSteps := context.WithCancel(context.Background())
+	defer cancelSteps()
+	leaseDone := make(chan struct{})
+	defer close(leaseDone)
+	var cancelled, leaseLost bool
+	var stateMutex sync.Mutex
+	go func() {
+		ticker := time.NewTicker(onboardingLease / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leaseDone:
+				return
+			case <-ticker.C:
+			}
+			cancelRequested, err := models.RenewOnboardingLease(job.ID, onboardingWorkerID, onboardingLease)
+			if err != nil && !errors.Is(err, models.ErrOnboardingLeaseLost) {
+				log.LogWarn("Failed to renew onboarding lease", zap.Int64("job", job.ID), zap.Error(err))
+				continue
+			}
+			stateMutex.Lock()
+			leaseLost = err != nil
+			cancelled = cancelled || cancelRequested
+			stateMutex.Unlock()
+			if leaseLost || cancelRequested {
+				cancelSteps()
+			}
+			if leaseLost {
+				return
+			}
+		}
+	}()
+
+	setOnboardingStatus(job.ClusterName, "Pending")
+	RegisterOnboardingStart(job.ClusterName)
+	run := newOnboardingRun(ctx, job.ClusterName, onboardingSpokeConfig(job), job.ITSContext, job.Labels)
+
+	if job.CancelRequested {
+		finishOnboardingJob(job, rollbackOnboardingJob(run, job, "onboarding was cancelled"))
+		return
+	}
+	if recovery != "" {
+		LogOnboardingEvent(job.ClusterName, "Recovering",
+			fmt.Sprintf("Onboarding job %d was interrupted at step %s, action: %s", job.ID, job.Step, recovery))
+		if recovery == models.OnboardingRollback {
+			finishOnboardingJob(job, rollbackOnboardingJob(run, job, "interrupted onboarding was rolled back"))
+			return
+		}
+	}
+
+	runErr := runOnboardingSteps(run, job)
+
+	stateMutex.Lock()
+	wasCancelled, wasLeaseLost := cancelled, leaseLost
+	stateMutex.Unlock()
+
+	switch {
+	case wasLeaseLost:
+		log.LogWarn("Lost the lease of onboarding job, leaving it to its new owner", zap.Int64("job", job.ID))
+		RegisterOnboardingComplete(job.ClusterName, models.ErrOnboardingLeaseLost)
+	case wasCancelled:
+		finishOnboardingJob(job, rollbackOnboardingJob(run, job, "onboarding was cancelled"))
+	case runErr != nil:
+		finishOnboardingJob(job, onboardingOutcome{state: models.OnboardingFailed, err: runErr})
+	default:
+		LogOnboardingEvent(job.ClusterName, "Success", "Cluster onboarded successfully")
+		finishOnboardingJob(job, onboardingOutcome{state: models.OnboardingSucceeded})
+	}
+}
+
+// runOnboardingSteps runs the steps of a job that did not complete yet. The
+// connect_hub and token steps only prepare the run, so they are repeated
+// whenever a later step needs what they provide.
+func runOnboardingSteps(run *onboardingRun, job *models.OnboardingJob) error {
+	for _, step := range models.OnboardingSteps {
+		if job.StepDone(step) && !onboardingStepNeeded(job, step) {
+			continue
+		}
+		if !job.StepDone(step) {
+			if err := models.AdvanceOnboardingJob(job.ID, onboardingWorkerID, step); err != nil {
+				return err
+			}
+			job.Step = step
+		}
+		setRunningOnboardingStep(job.ClusterName, step)
+		if err := run.runStep(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onboardingStepNeeded reports whether a completed preparation step has to
+// run again for the remaining steps of a resumed job
+func onboardingStepNeeded(job *models.OnboardingJob, step string) bool {
+	switch step {
+	case models.OnboardingStepConnectHub:
+		return true
+	case models.OnboardingStepToken:
+		return !job.StepDone(models.OnboardingStepJoin)
+	}
+	return false
+}
+
+// onboardingOutcome is the final state of a job and the error that led to it
+type onboardingOutcome struct {
+	state string
+	err   error
+}
+
+// rollbackOnboardingJob undoes what a job changed on the hub and the cluster.
+// Jobs that did not change anything yet are just cancelled.
+func rollbackOnboardingJob(run *onboardingRun, job *models.OnboardingJob, reason string) onboardingOutcome {
+	if !job.ChangedCluster() {
+		LogOnboardingEvent(job.ClusterName, "Cancelled", reason)
+		return onboardingOutcome{state: models.OnboardingCancelled, err: errors.New(reason)}
+	}
+
+	setRunningOnboardingStep(job.ClusterName, job.Step)
+	LogOnboardingEvent(job.ClusterName, "RollingBack", "Rolling back onboarding: "+reason)
+	if run.hub == nil {
+		if err := run.connectHub(); err != nil {
+			return onboardingOutcome{state: models.OnboardingFailed, err: fmt.Errorf("rollback failed: %w", err)}
+		}
+	}
+	if err := run.rollback(); err != nil {
+		LogOnboardingEvent(job.ClusterName, "Error", "Rollback failed: "+err.Error())
+		return onboardingOutcome{state: models.OnboardingFailed, err: fmt.Errorf("rollback failed: %w", err)}
+	}
+	LogOnboardingEvent(job.ClusterName, "RolledBack", "Onboarding rolled back")
+	return onboardingOutcome{state: models.OnboardingRolledBack, err: errors.New(reason)}
+}
+
+// rollback removes the managed cluster from the hub and the klusterlet from
+// the target cluster. It ignores the context of the run, which is cancelled
+// when the job is.
+func (r *onboardingRun) rollback() error {
+	ctx := context.Background()
+	LogOnboardingEvent(r.clusterName, "Deleting", "Deleting managed cluster from the OCM hub")
+	if err := ocm.DeleteManagedCluster(ctx, r.hub, r.clusterName); err != nil {
+		return err
+	}
+
+	spoke, err := r.spokeClients()
+	if err != nil {
+		return err
+	}
+	LogOnboardingEvent(r.clusterName, "Unjoining", "Removing the klusterlet from the target cluster")
+	return ocm.Unjoin(ctx, spoke, unjoinTimeout)
+}
+
+// finishOnboardingJob stores the final state of a job and updates the
+// in-memory status used by the logs endpoints
+func finishOnboardingJob(job *models.OnboardingJob, outcome onboardingOutcome) {
+	message := ""
+	if outcome.err != nil {
+		message = outcome.err.Error()
+	}
+	if err := models.FinishOnboardingJob(job.ID, onboardingWorkerID, outcome.state, message); err != nil {
+		log.LogError("Failed to finish onboarding job", zap.Int64("job", job.ID), zap.Error(err))
+	}
+
+	status := "Onboarded"
+	switch outcome.state {
+	case models.OnboardingFailed:
+		status = "Failed"
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "500").Inc()
+	case models.OnboardingCancelled:
+		status = "Cancelled"
+	case models.OnboardingRolledBack:
+		status = "RolledBack"
+	}
+	setOnboardingStatus(job.ClusterName, status)
+	if outcome.state == models.OnboardingSucceeded {
+		RegisterOnboardingComplete(job.ClusterName, nil)
+	} else {
+		RegisterOnboardingComplete(job.ClusterName, outcome.err)
+	}
+	log.LogInfo("Onboarding job finished",
+		zap.Int64("job", job.ID),
+		zap.String("cluster", job.ClusterName),
+		zap.String("state", outcome.state))
+}
+
+func setOnboardingStatus(clusterName, status string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	clusterStatuses[clusterName] = status
+}
+
+// onboardingRequestKey identifies an onboarding request. Clients may set an
+// Idempotency-Key header; otherwise the same cluster and kubeconfig are the
+// same request.
+func onboardingRequestKey(c *gin.Context, clusterName string, kubeconfigData []byte) string {
+	if key := c.GetHeader("Idempotency-Key"); key != "" && len(key) <= 128 {
+		return key
+	}
+	return kubeconfigRequestKey(clusterName, kubeconfigData)
+}
+
+func kubeconfigRequestKey(clusterName string, kubeconfigData []byte) string {
+	sum := sha256.Sum256(append([]byte(clusterName+"\n"), kubeconfigData...))
+	return hex.EncodeToString(sum[:])
+}
+
+// createOnboardingJob persists an onboarding job. With the credential vault
+// enabled the kubeconfig is stored encrypted under the cluster name and the
+// job only references it; otherwise it is kept in the job. The credential is
+// only replaced once the job is known to be created, so a refused or repeated
+// request does not change the kubeconfig a running job reads.
+func createOnboardingJob(job *models.OnboardingJob, kubeconfigData []byte) (*models.OnboardingJob, bool, error) {
+	if !vault.Enabled() {
+		job.Kubeconfig = kubeconfigData
+		return models.CreateOnboardingJob(job, nil)
+	}
+	job.CredentialName = job.ClusterName
+	return models.CreateOnboardingJob(job, func() error {
+		if _, err := vault.Store(job.ClusterName, kubeconfigData, vault.Access{
+			Username: job.CreatedBy,
+			Purpose:  "onboarding of cluster " + job.ClusterName,
+		}); err != nil {
+			return fmt.Errorf("failed to store the cluster credential: %v", err)
+		}
+		return nil
+	})
+}
+
+// onboardingSpokeConfig returns how the run of a job builds the client
+// config of the cluster: through the credential vault if the job references
+// a stored credential, every use being recorded in its access log, or from
+// the kubeconfig kept in the job
+func onboardingSpokeConfig(job *models.OnboardingJob) func() (*rest.Config, error) {
+	if job.CredentialName == "" {
+		return kubeconfigSpokeConfig(job.Kubeconfig)
+	}
+	return func() (*rest.Config, error) {
+		config, err := vault.RESTConfig(job.CredentialName, vault.Access{
+			Username: job.CreatedBy,
+			Purpose:  fmt.Sprintf("onboarding job %d", job.ID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the cluster credential: %v", err)
+		}
+		return config, nil
+	}
+}
+
+// submitOnboardingJob persists an onboarding request and starts it unless the
+// same request is already known
+func submitOnboardingJob(c *gin.Context, itsContext, clusterName string, kubeconfigData []byte, labels map[string]string) {
+	job, created, err := createOnboardingJob(&models.OnboardingJob{
+		ClusterName: clusterName,
+		RequestKey:  onboardingRequestKey(c, clusterName, kubeconfigData),
+		ITSContext:  itsContext,
+		Labels:      labels,
+		CreatedBy:   c.GetString("username"),
+	}, kubeconfigData)
+	if errors.Is(err, models.ErrOnboardingJobActive) {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "409").Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/clusters/onboard", "500").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("Cluster '%s' is being onboarded", clusterName)
+	if created {
+		ClearOnboardingEvents(clusterName)
+		setOnboardingStatus(clusterName, "Pending")
+		LogOnboardingEvent(clusterName, "Initiated", "Onboarding process initiated by API request")
+		go runOnboardingJob(job.ID, "")
+	} else if job.State == models.OnboardingSucceeded {
+		message = fmt.Sprintf("Cluster '%s' is already onboarded", clusterName)
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/clusters/onboard", "200").Inc()
+	c.JSON(http.StatusOK, gin.H{
+		"message":           message,
+		"status":            job.State,
+		"jobId":             job.ID,
+		"created":           created,
+		"jobEndpoint":       fmt.Sprintf("/clusters/onboard/jobs/%d", job.ID),
+		"logsEndpoint":      fmt.Sprintf("/clusters/onboard/logs/%s", clusterName),
+		"websocketEndpoint": fmt.Sprintf("/ws/onboarding?cluster=%s", clusterName),
+	})
+}
+
+// onboardingJobFromParam loads the job named by the :id parameter and writes
+// the error response if there is none
+func onboardingJobFromParam(c *gin.Context) (int64, bool) {
+	if !onboardingJobsEnabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Onboarding jobs are not available"})
+		return 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListOnboardingJobsHandler lists onboarding jobs, optionally filtered by
+// state and cluster
+func ListOnboardingJobsHandler(c *gin.Context) {
+	if !onboardingJobsEnabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Onboarding jobs are not available"})
+		return
+	}
+	filter := models.OnboardingJobFilter{
+		State:       c.Query("state"),
+		ClusterName: c.Query("cluster"),
+		Limit:       100,
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	jobs, err := models.ListOnboardingJobs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "count": len(jobs)})
+}
+
+// GetOnboardingJobHandler returns an onboarding job with its progress log
+func GetOnboardingJobHandler(c *gin.Context) {
+	id, ok := onboardingJobFromParam(c)
+	if !ok {
+		return
+	}
+	job, err := models.GetOnboardingJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Onboarding job not found"})
+		return
+	}
+	events, err := models.ListOnboardingJobEvents(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"job":       job,
+		"steps":     models.OnboardingSteps,
+		"events":    events,
+		"canRetry":  job.CanRetry(),
+		"canCancel": job.Active() && !job.CancelRequested,
+	})
+}
+
+// CancelOnboardingJobHandler asks an active onboarding job to stop. Changes
+// the job already made to the hub and the cluster are rolled back.
+func CancelOnboardingJobHandler(c *gin.Context) {
+	id, ok := onboardingJobFromParam(c)
+	if !ok {
+		return
+	}
+	job, err := models.RequestOnboardingCancel(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Onboarding job does not exist or is not active"})
+		return
+	}
+
+	LogOnboardingEvent(job.ClusterName, "Cancelling", fmt.Sprintf("Cancellation of onboarding job %d requested", job.ID))
+	if job.State == models.OnboardingPending {
+		// Nobody runs the job, so claim it to finish the cancellation
+		go runOnboardingJob(job.ID, "")
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Cancellation requested", "job": job})
+}
+
+// RetryOnboardingJobHandler runs a failed, cancelled or rolled back
+// onboarding job again
+func RetryOnboardingJobHandler(c *gin.Context) {
+	id, ok := onboardingJobFromParam(c)
+	if !ok {
+		return
+	}
+	job, err := models.RetryOnboardingJob(id)
+	if errors.Is(err, models.ErrOnboardingJobActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Onboarding job does not exist or cannot be retried"})
+		return
+	}
+
+	ClearOnboardingEvents(job.ClusterName)
+	setOnboardingStatus(job.ClusterName, "Pending")
+	LogOnboardingEvent(job.ClusterName, "Initiated", fmt.Sprintf("Onboarding job %d retried from step %s", job.ID, job.Step))
+	go runOnboardingJob(job.ID, "")
+	c.JSON(http.StatusAccepted, gin.H{"message": "Onboarding job restarted", "job": job})
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/auth"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/marketplace"
 	"github.com/kubestellar/ui/backend/models"
@@ -330,7 +331,7 @@ func InstallPluginHandler(c *gin.Context) {
 	}
 
 	// get author's ID from DB
-	author, err := models.GetUserByUsername(manifest.Metadata.Author)
+	author, err := auth.Users.GetUser(manifest.Metadata.Author)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Unable to get author from database: " + manifest.Metadata.Author,
@@ -1188,8 +1189,8 @@ func ServePluginFrontendAssets(c *gin.Context) {
 		return
 	}
 
-	author, err := models.GetUserByUsername(authorName)
-	if err != nil {
+	author, err := auth.Users.GetUser(authorName)
+	if err != nil || author == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
 		log.LogError("Author not found", zap.String("authorName", authorName), zap.String("pluginKey", pluginKey))
 		return
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+)
+
+// knownActions maps "METHOD route" to a stable action name. Routes that are
+// not listed still get audited under a name derived from the method and path.
+var knownActions = map[string]string{
+	"POST /clusters/onboard":                          "cluster.onboard",
+	"POST /clusters/onboard/bulk":                     "cluster.onboard.bulk",
+	"POST /clusters/onboard/jobs/:id/cancel":          "cluster.onboard.cancel",
+	"POST /clusters/onboard/jobs/:id/retry":           "cluster.onboard.retry",
+	"POST /clusters/detach":                           "cluster.detach",
+	"POST /clusters/import":                           "cluster.import",
+	"POST /clusters/import-by-url":                    "cluster.import",
+	"POST /clusters/csr/:name/approve":                "cluster.csr.approve",
+	"POST /clusters/csr/:name/deny":                   "cluster.csr.deny",
+	"PATCH /api/managedclusters/labels":               "cluster.labels.update",
+	"POST /api/hub/spaces/refresh":                    "hub.spaces.refresh",
+	"POST /api/clusters/inventory/refresh":            "cluster.inventory.refresh",
+	"POST /api/bp/create":                             "bindingpolicy.create",
+	"POST /api/bp/create-json":                        "bindingpolicy.create",
+	"POST /api/bp/quick-connect":                      "bindingpolicy.create",
+	"PATCH /api/bp/update/:name":                      "bindingpolicy.update",
+	"DELETE /api/bp/delete/:name":                     "bindingpolicy.delete",
+	"DELETE /api/bp/delete":                           "bindingpolicy.delete_all",
+	"POST /api/bp/revisions/:name/:revision/rollback": "bindingpolicy.rollback",
+	"POST /api/bp/templates/:name/create":             "bindingpolicy.create",
+	"POST /api/bp/import":                             "bindingpolicy.import",
+	"PUT /api/bp/update/:name/statuscollectors":       "bindingpolicy.update",
+	"POST /api/bp/statuscollectors":                   "statuscollector.create",
+	"PUT /api/bp/statuscollectors/:name":              "statuscollector.update",
+	"DELETE /api/bp/statuscollectors/:name":           "statuscollector.delete",
+	"GET /api/bp/export":                              "bindingpolicy.export",
+	"POST /api/admin/bp/templates":                    "bindingpolicy.template.create",
+	"DELETE /api/admin/bp/templates/:name":            "bindingpolicy.template.delete",
+	"POST /deploy/helm":                               "helm.deploy",
+	"POST /api/deployments/helm/deploy":               "helm.deploy",
+	"DELETE /api/deployments/helm/:id":                "helm.delete",
+	"POST /api/v1/artifact-hub/helm-deploy":           "helm.deploy",
+	"POST /api/deploy":                                "github.deploy",
+	"DELETE /api/deployments/:id":                     "github.delete",
+	"DELETE /api/deployments/github/:id":              "github.delete",
+	"GET /ws/pod/:namespace/:pod/shell/:container":    "pod.exec",
+	"POST /api/namespaces/create":                     "namespace.create",
+	"PUT /api/namespaces/update/:name":                "namespace.update",
+	"DELETE /api/namespaces/delete/:name":             "namespace.delete",
+	"POST /api/resources":                             "resource.create",
+	"POST /api/resource/upload":                       "resource.upload",
+	"PUT /api/:resourceKind/:namespace/:name":         "resource.update",
+	"DELETE /api/:resourceKind/:namespace/:name":      "resource.delete",
+	"POST /api/install":                               "kubestellar.install",
+	"POST /api/plugins/install":                       "plugin.install",
+	"DELETE /api/plugins/:id":                         "plugin.uninstall",
+	"POST /api/plugins/:id/enable":                    "plugin.enable",
+	"POST /api/plugins/:id/disable":                   "plugin.disable",
+	"POST /login":                                     "auth.login",
+	"POST /login/mfa":                                 "auth.login_mfa",
+	"PUT /api/me/password":                            "user.password.change",
+	"POST /api/admin/users":                           "user.create",
+	"PUT /api/admin/users/:username":                  "user.update",
+	"DELETE /api/admin/users/:username":               "user.delete",
+	"PUT /api/admin/users/:username/permissions":      "user.permissions.update",
+	"DELETE /api/admin/users/:username/lockout":       "user.unlock",
+	"PUT /api/admin/users/:username/mfa":              "user.mfa.require",
+	"DELETE /api/admin/users/:username/mfa":           "user.mfa.reset",
+	"PUT /api/admin/mfa/policy":                       "mfa.policy.update",
+	"PUT /api/admin/audit/settings":                   "audit.settings.update",
+	"POST /api/admin/health/alert-rules":              "cluster.alert_rule.create",
+	"PUT /api/admin/health/alert-rules/:id":           "cluster.alert_rule.update",
+	"DELETE /api/admin/health/alert-rules/:id":        "cluster.alert_rule.delete",
+	"POST /api/admin/credentials":                     "credential.store",
+	"DELETE /api/admin/credentials/:name":             "credential.delete",
+	"POST /api/admin/credentials/rotate":              "credential.rotate",
+	"POST /api/admin/csr/rules":                       "csr.rule.create",
+	"PUT /api/admin/csr/rules/:id":                    "csr.rule.update",
+	"DELETE /api/admin/csr/rules/:id":                 "csr.rule.delete",
+	"POST /api/admin/csr/allowlist":                   "csr.allowlist.add",
+	"DELETE /api/admin/csr/allowlist/:cluster":        "csr.allowlist.remove",
+	"POST /api/admin/labels/rules":                    "label.rule.create",
+	"PUT /api/admin/labels/rules/:id":                 "label.rule.update",
+	"DELETE /api/admin/labels/rules/:id":              "label.rule.delete",
+	"PUT /api/admin/labels/settings":                  "label.settings.update",
+	"POST /api/marketplace/plugins/upload":            "marketplace.plugin.upload",
+	"DELETE /api/marketplace/plugins/:id":             "marketplace.plugin.delete",
+	"PUT /api/plugins/system/configuration":           "plugin.system.update",
+	"POST /api/webhooks/config":                       "webhook.config.update",
+	"POST /api/sync-namespace/:name":                  "namespace.sync",
+	"POST /wds/set/context":                           "wds.context.set",
+}
+
+// readOnlyRoutes are non-GET routes that do not change any state
+var readOnlyRoutes = map[string]bool{
+	"POST /api/bp/generate-yaml":                         true,
+	"POST /api/bp/preview":                               true,
+	"POST /api/bp/templates/:name/render":                true,
+	"POST /api/bp/import/preview":                        true,
+	"POST /api/v1/artifact-hub/packages/search":          true,
+	"POST /api/v1/artifact-hub/packages/advanced-search": true,
+	"POST /api/validate/config":                          true,
+	"POST /api/validate/repository":                      true,
+	"POST /api/refresh":                                  true,
+}
+
+// sensitiveRoutes carry credentials in their body. Their payload is never
+// hashed because a hash of a short password is easy to brute-force offline.
+var sensitiveRoutes = map[string]bool{
+	"POST /login":                           true,
+	"POST /login/mfa":                       true,
+	"POST /login/mfa/enroll/verify":         true,
+	"PUT /api/me/password":                  true,
+	"POST /api/me/mfa/verify":               true,
+	"DELETE /api/me/mfa":                    true,
+	"POST /api/me/mfa/recovery-codes":       true,
+	"POST /api/admin/users":                 true,
+	"PUT /api/admin/users/:username":        true,
+	"POST /api/admin/credentials":           true,
+	"POST /clusters/import":                 true,
+	"POST /clusters/import-by-url":          true,
+	"PUT /api/plugins/system/configuration": true,
+}
+
+func routeKey(method, route string) string {
+	return method + " " + route
+}
+
+// ActionFor returns the audit action name for a request
+func ActionFor(method, route string) string {
+	if action, ok := knownActions[routeKey(method, route)]; ok {
+		return action
+	}
+	return strings.ToLower(method) + " " + route
+}
+
+// ShouldAudit reports whether requests to the route are recorded. Every
+// mutating method is audited unless the route is known to be read-only, and
+// GET routes are only audited when explicitly listed, such as pod exec.
+func ShouldAudit(method, route string) bool {
+	key := routeKey(method, route)
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return !readOnlyRoutes[key]
+	default:
+		_, ok := knownActions[key]
+		return ok
+	}
+}
+
+// isSensitive reports whether the payload of the route must not be hashed
+func isSensitive(method, route string) bool {
+	return sensitiveRoutes[routeKey(method, route)]
+}
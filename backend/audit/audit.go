@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"go.uber.org/zap"
+)
+
+// SettingRetentionDays is the system setting that overrides AUDIT_RETENTION_DAYS
+const SettingRetentionDays = "audit_retention_days"
+
+const (
+	defaultRetentionDays = 90
+	queueSize            = 1024
+	pruneInterval        = time.Hour
+)
+
+var (
+	queue    chan *models.AuditEntry
+	initOnce sync.Once
+)
+
+// Init starts the background writer and the retention worker. It must be
+// called after the database is initialized. Until then Record is a no-op, so
+// handlers can be exercised in tests without a database.
+func Init() {
+	initOnce.Do(func() {
+		queue = make(chan *models.AuditEntry, queueSize)
+		go writeLoop(queue)
+		go pruneLoop()
+	})
+}
+
+// Record queues an entry to be written. Writing happens asynchronously so
+// that a slow database never delays the request being audited; if the queue
+// is full the entry is logged and dropped.
+func Record(entry *models.AuditEntry) {
+	if queue == nil {
+		return
+	}
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+	if entry.Username == "" {
+		entry.Username = "anonymous"
+	}
+
+	select {
+	case queue <- entry:
+	default:
+		log.LogWarn("Audit queue full, dropping entry",
+			zap.String("action", entry.Action),
+			zap.String("username", entry.Username))
+	}
+}
+
+func writeLoop(entries <-chan *models.AuditEntry) {
+	for entry := range entries {
+		if err := models.InsertAuditEntry(entry); err != nil {
+			log.LogError("Failed to write audit entry",
+				zap.String("action", entry.Action),
+				zap.String("username", entry.Username),
+				zap.Error(err))
+		}
+	}
+}
+
+// RetentionDays returns how long entries are kept. The admin setting takes
+// precedence over AUDIT_RETENTION_DAYS; zero means entries are kept forever.
+func RetentionDays() (int, error) {
+	value, ok, err := models.GetSetting(SettingRetentionDays)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if days, err := strconv.Atoi(value); err == nil && days >= 0 {
+			return days, nil
+		}
+	}
+	return envRetentionDays(), nil
+}
+
+// SetRetentionDays stores the retention period chosen by an admin
+func SetRetentionDays(days int) error {
+	return models.SetSetting(SettingRetentionDays, strconv.Itoa(days))
+}
+
+func envRetentionDays() int {
+	value := os.Getenv("AUDIT_RETENTION_DAYS")
+	if value == "" {
+		return defaultRetentionDays
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.LogWarn("Invalid AUDIT_RETENTION_DAYS, using default",
+			zap.String("value", value),
+			zap.Int("default", defaultRetentionDays))
+		return defaultRetentionDays
+	}
+	return days
+}
+
+// Prune deletes entries older than the retention period
+func Prune() (int64, error) {
+	days, err := RetentionDays()
+	if err != nil {
+		return 0, err
+	}
+	if days == 0 {
+		return 0, nil
+	}
+	return models.DeleteAuditEntriesBefore(time.Now().AddDate(0, 0, -days))
+}
+
+func pruneLoop() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := Prune()
+		if err != nil {
+			log.LogError("Failed to prune audit log", zap.Error(err))
+		} else if deleted > 0 {
+			log.LogInfo("Pruned audit log", zap.Int64("deleted", deleted))
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kubestellar/ui/backend/models"
+)
+
+var csvHeader = []string{
+	"id", "occurred_at", "user_id", "username", "action", "method", "path", "target_context",
+	"resource", "payload_hash", "status_code", "result", "client_ip", "duration_ms", "error",
+}
+
+// WriteCSV writes the entries as CSV with a header row
+func WriteCSV(w io.Writer, entries []models.AuditEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		userID := ""
+		if entry.UserID != nil {
+			userID = strconv.Itoa(*entry.UserID)
+		}
+		record := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.OccurredAt.UTC().Format(time.RFC3339),
+			userID,
+			entry.Username,
+			entry.Action,
+			entry.Method,
+			entry.Path,
+			entry.TargetContext,
+			entry.Resource,
+			entry.PayloadHash,
+			strconv.Itoa(entry.StatusCode),
+			entry.Result,
+			entry.ClientIP,
+			strconv.FormatInt(entry.DurationMs, 10),
+			entry.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
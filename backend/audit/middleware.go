@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+// Keys under which handlers refine the audit entry of their request
+const (
+	actionKey   = "audit.action"
+	resourceKey = "audit.resource"
+)
+
+// maxHashedBody is the largest request body that is hashed. Larger bodies,
+// such as bundle uploads, are passed on without a payload hash rather than
+// read into memory twice.
+const maxHashedBody = 1 << 20
+
+// SetAction replaces the action recorded for the request, for handlers whose
+// action depends on the request body, such as a forced cluster detach
+func SetAction(c *gin.Context, action string) {
+	c.Set(actionKey, action)
+}
+
+// SetResource replaces the resource recorded for the request, for handlers
+// that take their target from the request body
+func SetResource(c *gin.Context, resource string) {
+	c.Set(resourceKey, resource)
+}
+
+// Middleware records every mutating request, and selected sensitive reads
+// such as pod exec, in the audit log
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		method := c.Request.Method
+		if route == "" || !ShouldAudit(method, route) {
+			c.Next()
+			return
+		}
+
+		entry := &models.AuditEntry{
+			OccurredAt:    time.Now(),
+			Action:        ActionFor(method, route),
+			Method:        method,
+			Path:          c.Request.URL.Path,
+			TargetContext: targetContext(c),
+			Resource:      resourceFromParams(c),
+			ClientIP:      c.ClientIP(),
+		}
+		if !isSensitive(method, route) {
+			entry.PayloadHash = hashBody(c)
+		}
+
+		// A WebSocket session can stay open for hours, so record that it
+		// started rather than waiting for it to end
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			setActor(c, entry)
+			entry.Result = models.AuditResultStarted
+			entry.StatusCode = http.StatusSwitchingProtocols
+			Record(entry)
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		// The authentication middleware of the route group runs inside
+		// c.Next, so the user is only known afterwards
+		setActor(c, entry)
+		if action := c.GetString(actionKey); action != "" {
+			entry.Action = action
+		}
+		if resource := c.GetString(resourceKey); resource != "" {
+			entry.Resource = resource
+		}
+		entry.DurationMs = time.Since(start).Milliseconds()
+		entry.StatusCode = c.Writer.Status()
+		if entry.StatusCode < http.StatusBadRequest {
+			entry.Result = models.AuditResultSuccess
+		} else {
+			entry.Result = models.AuditResultFailure
+		}
+		if len(c.Errors) > 0 {
+			entry.Error = c.Errors.String()
+		}
+
+		Record(entry)
+	}
+}
+
+// setActor fills in the user from the values set by AuthenticateMiddleware or,
+// for routes without it, from the bearer token
+func setActor(c *gin.Context, entry *models.AuditEntry) {
+	if username, ok := c.Get("username"); ok {
+		if name, ok := username.(string); ok && name != "" {
+			entry.Username = name
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(int); ok {
+				entry.UserID = &id
+			}
+		}
+		return
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return
+	}
+	claims, err := utils.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		return
+	}
+	entry.Username = claims.Username
+	userID := claims.UserID
+	entry.UserID = &userID
+}
+
+// targetContext returns the cluster or WDS the request acts on
+func targetContext(c *gin.Context) string {
+	if context := c.Query("context"); context != "" {
+		return context
+	}
+	if cluster := c.Query("cluster"); cluster != "" {
+		return cluster
+	}
+	if cookie, err := c.Cookie("ui-wds-context"); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// resourceFromParams describes the target resource using the route parameters
+func resourceFromParams(c *gin.Context) string {
+	parts := make([]string, 0, len(c.Params))
+	for _, param := range c.Params {
+		parts = append(parts, param.Key+"="+param.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// hashBody returns the SHA-256 of the request body and restores the body for
+// the handler. Bodies larger than maxHashedBody are not hashed.
+func hashBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	original := c.Request.Body
+	body, err := io.ReadAll(io.LimitReader(original, maxHashedBody+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), original), original}
+	if err != nil || len(body) == 0 || len(body) > maxHashedBody {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubestellar/ui/backend/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Default location of the ConfigMap that older releases stored users in
+const (
+	ConfigMapName = "jwt-config"
+	Namespace     = "kubestellar"
+)
+
+// Legacy permissions used by the ConfigMap format
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+// UserConfig is a single user in the ConfigMap. Older ConfigMaps hold a plain
+// Password; exports only ever contain the bcrypt PasswordHash.
+type UserConfig struct {
+	Password     string   `json:"password,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty"`
+	Permissions  []string `json:"permissions"`
+}
+
+// Config is the JSON document stored under the "config" key of the ConfigMap
+type Config struct {
+	JWTSecret string                `json:"jwt_secret,omitempty"`
+	Users     map[string]UserConfig `json:"users"`
+}
+
+// GetUser retrieves a specific user's configuration
+func (c *Config) GetUser(username string) (UserConfig, bool) {
+	userConfig, exists := c.Users[username]
+	return userConfig, exists
+}
+
+// AddUser adds or updates a user in the configuration
+func (c *Config) AddUser(username string, password string, permissions []string) {
+	if c.Users == nil {
+		c.Users = make(map[string]UserConfig)
+	}
+
+	c.Users[username] = UserConfig{
+		Password:    password,
+		Permissions: permissions,
+	}
+}
+
+// ReadConfigMap loads the user document from a ConfigMap. It returns nil if
+// the ConfigMap does not exist.
+func ReadConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*Config, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching ConfigMap: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal([]byte(cm.Data["config"]), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ConfigMap data: %v", err)
+	}
+	return &config, nil
+}
+
+// WriteConfigMap stores the user document in a ConfigMap, creating it if needed
+func WriteConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace, name string, config *Config) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error fetching ConfigMap: %v", err)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{"config": string(data)},
+		}
+		if _, err := clientset.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("error creating ConfigMap: %v", err)
+		}
+		return nil
+	}
+
+	cm.Data = map[string]string{"config": string(data)}
+	if _, err := clientset.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error updating ConfigMap: %v", err)
+	}
+	return nil
+}
+
+// ImportReport lists what ImportUsers did with each user in the ConfigMap
+type ImportReport struct {
+	Created []string          `json:"created"`
+	Updated []string          `json:"updated"`
+	Skipped []string          `json:"skipped"`
+	Failed  map[string]string `json:"failed"`
+}
+
+// ImportUsers copies the users of a ConfigMap document into the store. Users
+// that already exist are skipped unless overwrite is set, in which case their
+// password, role and permissions are replaced.
+func ImportUsers(store UserStore, config *Config, overwrite bool) *ImportReport {
+	report := &ImportReport{Failed: map[string]string{}}
+
+	usernames := make([]string, 0, len(config.Users))
+	for username := range config.Users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		userConfig := config.Users[username]
+		if userConfig.Password == "" && userConfig.PasswordHash == "" {
+			report.Failed[username] = "no password"
+			continue
+		}
+		// plaintext passwords come from a ConfigMap anyone with read access
+		// to the namespace could see, so they must meet the password policy
+		// and be replaced on first login
+		if userConfig.PasswordHash == "" {
+			if err := utils.ValidatePassword(username, userConfig.Password); err != nil {
+				report.Failed[username] = err.Error()
+				continue
+			}
+		}
+		isAdmin, permissions := ConvertPermissions(userConfig.Permissions)
+
+		existing, err := store.GetUser(username)
+		if err != nil {
+			report.Failed[username] = err.Error()
+			continue
+		}
+
+		if existing == nil {
+			_, err := store.CreateUser(NewUser{
+				Username:     username,
+				Password:     userConfig.Password,
+				PasswordHash: userConfig.PasswordHash,
+				IsAdmin:      isAdmin,
+				Permissions:  permissions,
+				// MustChangePassword only applies to plaintext passwords
+				MustChangePassword: userConfig.PasswordHash == "",
+			})
+			if err != nil {
+				report.Failed[username] = err.Error()
+				continue
+			}
+			report.Created = append(report.Created, username)
+			continue
+		}
+
+		if !overwrite {
+			report.Skipped = append(report.Skipped, username)
+			continue
+		}
+
+		if err := updateImportedUser(store, username, userConfig, isAdmin, permissions); err != nil {
+			report.Failed[username] = err.Error()
+			continue
+		}
+		report.Updated = append(report.Updated, username)
+	}
+
+	return report
+}
+
+func updateImportedUser(store UserStore, username string, userConfig UserConfig, isAdmin bool, permissions map[string]string) error {
+	var err error
+	if userConfig.PasswordHash != "" {
+		err = store.SetPasswordHash(username, userConfig.PasswordHash)
+	} else if err = store.SetPassword(username, userConfig.Password); err == nil {
+		err = store.SetMustChangePassword(username, true)
+	}
+	if err != nil {
+		return err
+	}
+	if err := store.SetAdmin(username, isAdmin); err != nil {
+		return err
+	}
+	return store.SetPermissions(username, permissions)
+}
+
+// ExportUsers builds a ConfigMap document from the store. Passwords are
+// exported as bcrypt hashes and the JWT secret is never included.
+func ExportUsers(store UserStore) (*Config, error) {
+	users, err := store.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{Users: make(map[string]UserConfig, len(users))}
+	for _, summary := range users {
+		// ListUsers does not load password hashes
+		user, err := store.GetUser(summary.Username)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			continue
+		}
+
+		var permissions []string
+		if user.IsAdmin {
+			permissions = append(permissions, PermissionAdmin)
+		}
+		for component, permission := range user.Permissions {
+			permissions = append(permissions, component+":"+permission)
+		}
+		sort.Strings(permissions)
+
+		config.Users[user.Username] = UserConfig{
+			PasswordHash: user.Password,
+			Permissions:  permissions,
+		}
+	}
+
+	return config, nil
+}
+
+// ConvertPermissions maps ConfigMap permissions to the admin flag and
+// component permissions used by the database. Both the legacy global values
+// ("read", "write", "admin") and "component:permission" pairs are accepted.
+func ConvertPermissions(legacy []string) (bool, map[string]string) {
+	isAdmin := false
+	permissions := map[string]string{}
+
+	grantAll := func(permission string) {
+		for _, component := range Components {
+			if permissions[component] != PermissionWrite {
+				permissions[component] = permission
+			}
+		}
+	}
+
+	for _, p := range legacy {
+		if component, permission, ok := strings.Cut(p, ":"); ok {
+			if permission == PermissionWrite || permissions[component] != PermissionWrite {
+				permissions[component] = permission
+			}
+			continue
+		}
+
+		switch p {
+		case PermissionAdmin:
+			isAdmin = true
+			grantAll(PermissionWrite)
+		case PermissionWrite:
+			grantAll(PermissionWrite)
+		case PermissionRead:
+			grantAll(PermissionRead)
+		}
+	}
+
+	return isAdmin, permissions
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+var (
+	// ErrUserNotFound is returned when the requested user does not exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when creating a user whose name is taken
+	ErrUserExists = errors.New("user already exists")
+	// ErrLastAdmin is returned when a change would leave no admin user
+	ErrLastAdmin = errors.New("cannot remove the last admin user")
+	// ErrInvalidPassword is returned when a password does not match the user
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
+// Components are the UI areas that per-user permissions apply to
+var Components = []string{"users", "resources", "system", "dashboard"}
+
+// NewUser describes an account to create. Exactly one of Password and
+// PasswordHash should be set; PasswordHash is used when importing accounts
+// whose plain password is not known.
+type NewUser struct {
+	Username     string
+	Password     string
+	PasswordHash string
+	IsAdmin      bool
+	Permissions  map[string]string
+	// MustChangePassword forces the user to pick a new password on first login
+	MustChangePassword bool
+}
+
+// UserStore is the only way user accounts are read and written. PostgreSQL is
+// the source of truth; ConfigMaps are only used to import and export users.
+type UserStore interface {
+	// GetUser returns the user, or nil if it does not exist
+	GetUser(username string) (*models.User, error)
+	// GetUserByID returns the user with the given ID, or ErrUserNotFound
+	GetUserByID(id int) (*models.User, error)
+	// Authenticate returns the user if password matches, ErrUserNotFound or
+	// ErrInvalidPassword otherwise
+	Authenticate(username, password string) (*models.User, error)
+	ListUsers() ([]*models.User, error)
+	CreateUser(user NewUser) (*models.User, error)
+	RenameUser(username, newUsername string) error
+	// SetPassword changes the password, recording the old one in the
+	// password history, and clears any forced rotation
+	SetPassword(username, password string) error
+	// IsPasswordReused reports whether password matches the current or a
+	// recent previous password of the user
+	IsPasswordReused(username, password string) (bool, error)
+	SetMustChangePassword(username string, mustChange bool) error
+	SetPasswordHash(username, passwordHash string) error
+	SetAdmin(username string, isAdmin bool) error
+	SetPermissions(username string, permissions map[string]string) error
+	DeleteUser(username string) error
+}
+
+// Users is the store used by the API handlers
+var Users UserStore = NewPostgresUserStore()
+
+// PostgresUserStore keeps users in the users and user_permissions tables
+type PostgresUserStore struct{}
+
+// NewPostgresUserStore returns a store backed by the global database connection
+func NewPostgresUserStore() *PostgresUserStore {
+	return &PostgresUserStore{}
+}
+
+func (s *PostgresUserStore) GetUser(username string) (*models.User, error) {
+	return models.GetUserByUsername(username)
+}
+
+func (s *PostgresUserStore) GetUserByID(id int) (*models.User, error) {
+	user, err := models.GetUserByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (s *PostgresUserStore) Authenticate(username, password string) (*models.User, error) {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CheckPasswordHash(password, user.Password) {
+		return nil, ErrInvalidPassword
+	}
+	return user, nil
+}
+
+func (s *PostgresUserStore) ListUsers() ([]*models.User, error) {
+	return models.ListAllUsers()
+}
+
+func (s *PostgresUserStore) CreateUser(user NewUser) (*models.User, error) {
+	existing, err := models.GetUserByUsername(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrUserExists
+	}
+
+	var created *models.User
+	if user.PasswordHash != "" {
+		created, err = models.CreateUserWithPasswordHash(user.Username, user.PasswordHash, user.IsAdmin)
+	} else {
+		created, err = models.CreateUser(user.Username, user.Password, user.IsAdmin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.Permissions) > 0 {
+		if err := models.SetUserPermissions(created.ID, permissionSlice(user.Permissions)); err != nil {
+			return nil, fmt.Errorf("user created but failed to set permissions: %v", err)
+		}
+		created.Permissions = user.Permissions
+	}
+
+	if user.MustChangePassword {
+		if err := models.SetMustChangePassword(created.ID, true); err != nil {
+			return nil, fmt.Errorf("user created but failed to require a password change: %v", err)
+		}
+	}
+
+	return created, nil
+}
+
+func (s *PostgresUserStore) RenameUser(username, newUsername string) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	return models.UpdateUserUsername(user.ID, newUsername)
+}
+
+func (s *PostgresUserStore) SetPassword(username, password string) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	return models.ChangeUserPassword(user.ID, password, utils.GetPasswordPolicy().HistorySize)
+}
+
+func (s *PostgresUserStore) IsPasswordReused(username, password string) (bool, error) {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return false, err
+	}
+	return models.IsPasswordReused(user.ID, password, utils.GetPasswordPolicy().HistorySize)
+}
+
+func (s *PostgresUserStore) SetMustChangePassword(username string, mustChange bool) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	return models.SetMustChangePassword(user.ID, mustChange)
+}
+
+func (s *PostgresUserStore) SetPasswordHash(username, passwordHash string) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	return models.UpdateUserPasswordHash(user.ID, passwordHash)
+}
+
+func (s *PostgresUserStore) SetAdmin(username string, isAdmin bool) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin == isAdmin {
+		return nil
+	}
+	if !isAdmin {
+		if err := s.ensureNotLastAdmin(user); err != nil {
+			return err
+		}
+	}
+	return models.SetUserAdmin(user.ID, isAdmin)
+}
+
+func (s *PostgresUserStore) SetPermissions(username string, permissions map[string]string) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	return models.SetUserPermissions(user.ID, permissionSlice(permissions))
+}
+
+func (s *PostgresUserStore) DeleteUser(username string) error {
+	user, err := s.mustGetUser(username)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureNotLastAdmin(user); err != nil {
+		return err
+	}
+	return models.DeleteUser(username)
+}
+
+func (s *PostgresUserStore) mustGetUser(username string) (*models.User, error) {
+	user, err := models.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ensureNotLastAdmin fails if user is the only remaining admin
+func (s *PostgresUserStore) ensureNotLastAdmin(user *models.User) error {
+	if !user.IsAdmin {
+		return nil
+	}
+
+	users, err := models.ListAllUsers()
+	if err != nil {
+		return fmt.Errorf("failed to check admin users: %v", err)
+	}
+
+	adminCount := 0
+	for _, u := range users {
+		if u.IsAdmin {
+			adminCount++
+		}
+	}
+	if adminCount <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// permissionSlice converts a component -> permission map, skipping "none"
+func permissionSlice(permissions map[string]string) []models.Permission {
+	var permSlice []models.Permission
+	for component, permission := range permissions {
+		if permission == "" || permission == "none" {
+			continue
+		}
+		permSlice = append(permSlice, models.Permission{
+			Component:  component,
+			Permission: permission,
+		})
+	}
+	return permSlice
+}
@@ -0,0 +1,140 @@
+package clusterhealth
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/kubestellar/ui/backend/models"
+)
+
+var conditionDescriptions = map[string]string{
+	models.AlertUnavailable:    "unavailable",
+	models.AlertNotJoined:      "not joined",
+	models.AlertHeartbeatStale: "without a heartbeat",
+}
+
+// ConditionSince reports whether condition holds for a cluster and since when
+func ConditionSince(state ClusterState, condition string) (time.Time, bool) {
+	switch condition {
+	case models.AlertUnavailable:
+		return state.AvailableSince, !state.Available
+	case models.AlertNotJoined:
+		return state.JoinedSince, !state.Joined
+	case models.AlertHeartbeatStale:
+		return state.HeartbeatSince, state.HeartbeatStale
+	}
+	return time.Time{}, false
+}
+
+// RuleApplies reports whether a rule watches a cluster
+func RuleApplies(rule *models.ClusterAlertRule, state ClusterState) bool {
+	if rule.ITSContext != "" && rule.ITSContext != state.ITSContext {
+		return false
+	}
+	if rule.ClusterPattern == "" {
+		return true
+	}
+	matched, err := path.Match(rule.ClusterPattern, state.ClusterName)
+	return err == nil && matched
+}
+
+// ValidateRule checks the settings of a rule before it is stored
+func ValidateRule(rule *models.ClusterAlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !models.ValidAlertCondition(rule.Condition) {
+		return fmt.Errorf("invalid condition %q, expected one of %v", rule.Condition, models.AlertConditions)
+	}
+	if rule.DurationSeconds < 0 {
+		return fmt.Errorf("duration_seconds must not be negative")
+	}
+	if _, err := path.Match(rule.ClusterPattern, ""); err != nil {
+		return fmt.Errorf("invalid cluster_pattern %q: %v", rule.ClusterPattern, err)
+	}
+	registered := map[string]bool{}
+	for _, name := range Notifiers() {
+		registered[name] = true
+	}
+	for _, name := range rule.Notifiers {
+		if !registered[name] {
+			return fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+	return nil
+}
+
+func alertKey(ruleID int64, itsContext, clusterName string) string {
+	return fmt.Sprintf("%d/%s/%s", ruleID, itsContext, clusterName)
+}
+
+func alertMessage(rule *models.ClusterAlertRule, state ClusterState, since, now time.Time) string {
+	return fmt.Sprintf("Cluster %s on %s has been %s for %s (rule %s)",
+		state.ClusterName, state.ITSContext, conditionDescriptions[rule.Condition],
+		now.Sub(since).Round(time.Second), rule.Name)
+}
+
+// Evaluate compares the enabled rules with the cluster states. It returns the
+// alerts to raise and the open alerts to resolve, which are those whose rule
+// is gone or disabled, whose cluster is gone, or whose condition cleared.
+func Evaluate(rules []*models.ClusterAlertRule, states []ClusterState, open []*models.ClusterAlert, now time.Time) ([]*models.ClusterAlert, []*models.ClusterAlert) {
+	enabled := map[int64]*models.ClusterAlertRule{}
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabled[rule.ID] = rule
+		}
+	}
+	byKey := map[string]ClusterState{}
+	for _, state := range states {
+		byKey[stateKey(state.ITSContext, state.ClusterName)] = state
+	}
+
+	openKeys := map[string]bool{}
+	resolve := []*models.ClusterAlert{}
+	for _, alert := range open {
+		if alert.RuleID == nil {
+			resolve = append(resolve, alert)
+			continue
+		}
+		openKeys[alertKey(*alert.RuleID, alert.ITSContext, alert.ClusterName)] = true
+
+		rule, ok := enabled[*alert.RuleID]
+		state, known := byKey[stateKey(alert.ITSContext, alert.ClusterName)]
+		if !ok || !known || !RuleApplies(rule, state) {
+			resolve = append(resolve, alert)
+			continue
+		}
+		if _, holds := ConditionSince(state, rule.Condition); !holds {
+			resolve = append(resolve, alert)
+		}
+	}
+
+	fire := []*models.ClusterAlert{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		for _, state := range states {
+			if !RuleApplies(rule, state) || openKeys[alertKey(rule.ID, state.ITSContext, state.ClusterName)] {
+				continue
+			}
+			since, holds := ConditionSince(state, rule.Condition)
+			if !holds || now.Sub(since) < time.Duration(rule.DurationSeconds)*time.Second {
+				continue
+			}
+			ruleID := rule.ID
+			fire = append(fire, &models.ClusterAlert{
+				RuleID:      &ruleID,
+				RuleName:    rule.Name,
+				Condition:   rule.Condition,
+				ITSContext:  state.ITSContext,
+				ClusterName: state.ClusterName,
+				Message:     alertMessage(rule, state, since, now),
+				Since:       since,
+				FiredAt:     now,
+			})
+		}
+	}
+	return fire, resolve
+}
@@ -0,0 +1,276 @@
+// Package clusterhealth records the health history of managed clusters and raises
+// alerts when a cluster stays unhealthy. Every ITS is polled periodically;
+// changes of the Available and Joined conditions and of the lease heartbeat
+// are stored as samples, and the alert rules are evaluated against the
+// current state of every cluster.
+package clusterhealth
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/health"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultPollInterval   = 30 * time.Second
+	defaultStaleAfter     = 3 * time.Minute
+	defaultSampleInterval = 5 * time.Minute
+	defaultRetentionDays  = 30
+	pollTimeout           = 20 * time.Second
+	pruneInterval         = time.Hour
+)
+
+// PollerComponentPrefix prefixes the name under which the poller of each ITS
+// reports its health
+const PollerComponentPrefix = "cluster-health-poller/"
+
+var (
+	tracker  *Tracker
+	initOnce sync.Once
+	// alertsMu serializes alert evaluation with itself
+	alertsMu sync.Mutex
+)
+
+// Init restores the last known state of every cluster and starts polling the
+// hub. It must be called after the database is initialized. Until then the
+// current state is unknown and nothing is recorded, so handlers can be
+// exercised in tests without a database.
+func Init() {
+	initOnce.Do(func() {
+		tracker = NewTracker(
+			envDuration("HEALTH_HEARTBEAT_STALE_SECONDS", defaultStaleAfter),
+			envDuration("HEALTH_SAMPLE_INTERVAL_SECONDS", defaultSampleInterval))
+
+		samples, err := models.LatestClusterHealthSamples()
+		if err != nil {
+			log.LogError("Failed to restore cluster health", zap.Error(err))
+		} else {
+			tracker.Restore(samples)
+		}
+
+		if url := os.Getenv("HEALTH_ALERT_WEBHOOK_URL"); url != "" {
+			RegisterNotifier(NewWebhookNotifier("webhook", url))
+		}
+
+		go pollLoop(envDuration("HEALTH_POLL_INTERVAL_SECONDS", defaultPollInterval))
+		go pruneLoop()
+	})
+}
+
+// Current returns the state of a cluster, and false if the cluster is not
+// tracked or recording has not started
+func Current(itsContext, clusterName string) (ClusterState, bool) {
+	if tracker == nil {
+		return ClusterState{}, false
+	}
+	return tracker.Get(itsContext, clusterName)
+}
+
+// CurrentStates returns the state of every tracked cluster
+func CurrentStates() []ClusterState {
+	if tracker == nil {
+		return []ClusterState{}
+	}
+	return tracker.States()
+}
+
+func envDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		log.LogWarn("Invalid "+name+", using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// RetentionDays returns how long samples are kept, from HEALTH_RETENTION_DAYS;
+// zero means they are kept forever
+func RetentionDays() int {
+	value := os.Getenv("HEALTH_RETENTION_DAYS")
+	if value == "" {
+		return defaultRetentionDays
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.LogWarn("Invalid HEALTH_RETENTION_DAYS, using default",
+			zap.String("value", value),
+			zap.Int("default", defaultRetentionDays))
+		return defaultRetentionDays
+	}
+	return days
+}
+
+func pollLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, space := range hub.SpacesOfType(hub.ITS) {
+			component := health.ComponentHealth{
+				Status:   "healthy",
+				Message:  "recording managed cluster health",
+				Metadata: map[string]interface{}{"its_context": space.Context},
+			}
+			if err := pollITS(space.Context); err != nil {
+				log.LogWarn("Failed to poll cluster health",
+					zap.String("its", space.Context),
+					zap.Error(err))
+				component.Status = "degraded"
+				component.Message = "cannot reach the ITS"
+				component.Error = err.Error()
+			}
+			health.ReportComponent(PollerComponentPrefix+space.Context, component)
+		}
+		EvaluateAlerts()
+		<-ticker.C
+	}
+}
+
+// pollITS observes every managed cluster of an ITS and records the samples
+// the tracker asks for
+func pollITS(itsContext string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+
+	_, restConfig, err := k8s.GetClientSetWithConfigContext(itsContext)
+	if err != nil {
+		return err
+	}
+	clients, err := ocm.NewClients(restConfig)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := clients.Dynamic.Resource(ocm.ManagedClusterGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	// The leases live in the cluster namespaces, one list finds all of them
+	leases := map[string]*coordinationv1.Lease{}
+	leaseList, err := clients.Kube.CoordinationV1().Leases("").List(ctx, metav1.ListOptions{
+		FieldSelector: "metadata.name=" + LeaseName,
+	})
+	if err != nil {
+		log.LogWarn("Failed to list cluster leases", zap.String("its", itsContext), zap.Error(err))
+	} else {
+		for i := range leaseList.Items {
+			leases[leaseList.Items[i].Namespace] = &leaseList.Items[i]
+		}
+	}
+
+	now := time.Now()
+	seen := map[string]bool{}
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		seen[cluster.GetName()] = true
+		sample := tracker.Observe(ObservationFromCluster(itsContext, cluster, leases[cluster.GetName()]), now)
+		if sample == nil {
+			continue
+		}
+		if err := models.InsertClusterHealthSample(sample); err != nil {
+			log.LogError("Failed to record cluster health",
+				zap.String("cluster", sample.ClusterName),
+				zap.Error(err))
+		}
+	}
+	tracker.Forget(itsContext, seen)
+	return nil
+}
+
+// EvaluateAlerts raises and resolves alerts for the current cluster states
+// and notifies the notifiers of the rules
+func EvaluateAlerts() {
+	if tracker == nil {
+		return
+	}
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	rules, err := models.ListClusterAlertRules()
+	if err != nil {
+		log.LogError("Failed to load alert rules", zap.Error(err))
+		return
+	}
+	open, err := models.ListClusterAlerts(models.ClusterAlertFilter{OpenOnly: true})
+	if err != nil {
+		log.LogError("Failed to load open alerts", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	fire, resolve := Evaluate(rules, tracker.States(), open, now)
+
+	notifiersOf := map[int64][]string{}
+	for _, rule := range rules {
+		notifiersOf[rule.ID] = rule.Notifiers
+	}
+	targets := func(alert *models.ClusterAlert) []string {
+		if alert.RuleID == nil {
+			return nil
+		}
+		return notifiersOf[*alert.RuleID]
+	}
+
+	for _, alert := range resolve {
+		if err := models.ResolveClusterAlert(alert.ID, now); err != nil {
+			log.LogError("Failed to resolve cluster alert", zap.Int64("alert", alert.ID), zap.Error(err))
+			continue
+		}
+		resolvedAt := now
+		alert.ResolvedAt = &resolvedAt
+		notify(targets(alert), Notification{Status: AlertResolved, Alert: alert})
+	}
+	for _, alert := range fire {
+		if err := models.InsertClusterAlert(alert); err != nil {
+			log.LogError("Failed to record cluster alert",
+				zap.String("rule", alert.RuleName),
+				zap.String("cluster", alert.ClusterName),
+				zap.Error(err))
+			continue
+		}
+		notify(targets(alert), Notification{Status: AlertFiring, Alert: alert})
+	}
+}
+
+// Prune deletes samples older than the retention period
+func Prune() (int64, error) {
+	days := RetentionDays()
+	if days == 0 {
+		return 0, nil
+	}
+	return models.DeleteClusterHealthSamplesBefore(time.Now().AddDate(0, 0, -days))
+}
+
+func pruneLoop() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := Prune()
+		if err != nil {
+			log.LogError("Failed to prune cluster health history", zap.Error(err))
+		} else if deleted > 0 {
+			log.LogInfo("Pruned cluster health history", zap.Int64("deleted", deleted))
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,71 @@
+package clusterhealth
+
+import (
+	"time"
+
+	"github.com/kubestellar/ui/backend/models"
+)
+
+// Availability summarizes how long a cluster was available within a window
+type Availability struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	AvailableSeconds   int64     `json:"availableSeconds"`
+	UnavailableSeconds int64     `json:"unavailableSeconds"`
+	// UnknownSeconds is the time before the first sample of the cluster
+	UnknownSeconds int64 `json:"unknownSeconds"`
+	// Percent is the share of the known time the cluster was available, nil
+	// when nothing is known about the window
+	Percent     *float64 `json:"percent,omitempty"`
+	Transitions int      `json:"transitions"`
+}
+
+// ComputeAvailability adds up the time a cluster was available between from
+// and to. initial is the last sample before from, or nil; samples are the
+// samples within the window, oldest first. Each sample holds until the next.
+func ComputeAvailability(initial *models.ClusterHealthSample, samples []*models.ClusterHealthSample, from, to time.Time) Availability {
+	result := Availability{From: from, To: to}
+	if !to.After(from) {
+		return result
+	}
+
+	var available, unavailable, unknown time.Duration
+	current := initial
+	cursor := from
+	add := func(until time.Time) {
+		if !until.After(cursor) {
+			return
+		}
+		span := until.Sub(cursor)
+		switch {
+		case current == nil:
+			unknown += span
+		case current.Available:
+			available += span
+		default:
+			unavailable += span
+		}
+		cursor = until
+	}
+
+	for _, sample := range samples {
+		if sample.ObservedAt.After(to) {
+			break
+		}
+		add(sample.ObservedAt)
+		if sample.Transition {
+			result.Transitions++
+		}
+		current = sample
+	}
+	add(to)
+
+	result.AvailableSeconds = int64(available.Seconds())
+	result.UnavailableSeconds = int64(unavailable.Seconds())
+	result.UnknownSeconds = int64(unknown.Seconds())
+	if known := available + unavailable; known > 0 {
+		percent := float64(available) / float64(known) * 100
+		result.Percent = &percent
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package clusterhealth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"go.uber.org/zap"
+)
+
+// Status of a Notification
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Notification tells a notifier that an alert was raised or resolved
+type Notification struct {
+	Status string               `json:"status"`
+	Alert  *models.ClusterAlert `json:"alert"`
+}
+
+// Notifier delivers alert notifications, e.g. to a chat or paging system.
+// Rules refer to notifiers by name.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, notification Notification) error
+}
+
+const notifyTimeout = 10 * time.Second
+
+var (
+	notifiersMu sync.RWMutex
+	notifiers   = map[string]Notifier{}
+)
+
+func init() {
+	RegisterNotifier(LogNotifier{})
+}
+
+// RegisterNotifier makes a notifier available to alert rules, replacing any
+// notifier registered under the same name
+func RegisterNotifier(notifier Notifier) {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	notifiers[notifier.Name()] = notifier
+}
+
+// Notifiers returns the names of the registered notifiers
+func Notifiers() []string {
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// notify sends a notification to the named notifiers, or to all of them if
+// names is empty. Failures are logged since alerting must not stop polling.
+func notify(names []string, notification Notification) {
+	notifiersMu.RLock()
+	targets := []Notifier{}
+	if len(names) == 0 {
+		for _, notifier := range notifiers {
+			targets = append(targets, notifier)
+		}
+	} else {
+		for _, name := range names {
+			if notifier, ok := notifiers[name]; ok {
+				targets = append(targets, notifier)
+			} else {
+				log.LogWarn("Alert rule refers to an unknown notifier", zap.String("notifier", name))
+			}
+		}
+	}
+	notifiersMu.RUnlock()
+
+	for _, notifier := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		if err := notifier.Notify(ctx, notification); err != nil {
+			log.LogError("Failed to send alert notification",
+				zap.String("notifier", notifier.Name()),
+				zap.String("cluster", notification.Alert.ClusterName),
+				zap.String("rule", notification.Alert.RuleName),
+				zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// LogNotifier writes alerts to the backend log. It is always registered.
+type LogNotifier struct{}
+
+// Name implements Notifier
+func (LogNotifier) Name() string { return "log" }
+
+// Notify implements Notifier
+func (LogNotifier) Notify(_ context.Context, notification Notification) error {
+	fields := []zap.Field{
+		zap.String("status", notification.Status),
+		zap.String("rule", notification.Alert.RuleName),
+		zap.String("its", notification.Alert.ITSContext),
+		zap.String("cluster", notification.Alert.ClusterName),
+		zap.String("message", notification.Alert.Message),
+	}
+	if notification.Status == AlertFiring {
+		log.LogWarn("Cluster alert firing", fields...)
+	} else {
+		log.LogInfo("Cluster alert resolved", fields...)
+	}
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a URL
+type WebhookNotifier struct {
+	NotifierName string
+	URL          string
+	Client       *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to url
+func NewWebhookNotifier(name, url string) *WebhookNotifier {
+	return &WebhookNotifier{NotifierName: name, URL: url, Client: &http.Client{Timeout: notifyTimeout}}
+}
+
+// Name implements Notifier
+func (w *WebhookNotifier) Name() string { return w.NotifierName }
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
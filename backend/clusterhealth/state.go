@@ -0,0 +1,268 @@
+package clusterhealth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/models"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Condition types of a ManagedCluster
+const (
+	ConditionAvailable = "ManagedClusterConditionAvailable"
+	ConditionJoined    = "ManagedClusterJoined"
+)
+
+// LeaseName is the lease the registration agent of a managed cluster renews
+// in the cluster namespace on the hub
+const LeaseName = "managed-cluster-lease"
+
+// Observation is what one poll of the hub saw of a managed cluster
+type Observation struct {
+	ITSContext  string
+	ClusterName string
+	Available   bool
+	Joined      bool
+	// AvailableChanged and JoinedChanged are the last transition times of
+	// the conditions, zero when unknown
+	AvailableChanged time.Time
+	JoinedChanged    time.Time
+	// LeaseRenewed is the last heartbeat, nil when the cluster has no lease
+	LeaseRenewed *time.Time
+}
+
+// ClusterState is the current health of a managed cluster
+type ClusterState struct {
+	ITSContext          string `json:"itsContext"`
+	ClusterName         string `json:"clusterName"`
+	Available           bool   `json:"available"`
+	Joined              bool   `json:"joined"`
+	HeartbeatStale      bool   `json:"heartbeatStale"`
+	HeartbeatAgeSeconds *int64 `json:"heartbeatAgeSeconds,omitempty"`
+	// AvailableSince, JoinedSince and HeartbeatSince are when the flags
+	// last changed
+	AvailableSince time.Time `json:"availableSince"`
+	JoinedSince    time.Time `json:"joinedSince"`
+	HeartbeatSince time.Time `json:"heartbeatSince"`
+	ObservedAt     time.Time `json:"observedAt"`
+
+	recordedAt time.Time
+}
+
+// ObservationFromCluster reads the health of a ManagedCluster and its lease,
+// which may be nil
+func ObservationFromCluster(itsContext string, cluster *unstructured.Unstructured, lease *coordinationv1.Lease) Observation {
+	obs := Observation{ITSContext: itsContext, ClusterName: cluster.GetName()}
+
+	conditions, _, _ := unstructured.NestedSlice(cluster.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, _ := condition["status"].(string)
+		changed := time.Time{}
+		if value, ok := condition["lastTransitionTime"].(string); ok {
+			changed, _ = time.Parse(time.RFC3339, value)
+		}
+		switch condition["type"] {
+		case ConditionAvailable:
+			obs.Available = status == "True"
+			obs.AvailableChanged = changed
+		case ConditionJoined:
+			obs.Joined = status == "True"
+			obs.JoinedChanged = changed
+		}
+	}
+
+	if lease != nil && lease.Spec.RenewTime != nil {
+		renewed := lease.Spec.RenewTime.Time
+		obs.LeaseRenewed = &renewed
+	}
+	return obs
+}
+
+// Tracker keeps the current state of every cluster and decides which
+// observations are recorded: the first one of a cluster, every change of
+// Available, Joined or heartbeat staleness, and otherwise one sample per
+// SampleInterval
+type Tracker struct {
+	StaleAfter     time.Duration
+	SampleInterval time.Duration
+
+	mu     sync.Mutex
+	states map[string]*ClusterState
+}
+
+// NewTracker returns a tracker that treats heartbeats older than staleAfter
+// as stale
+func NewTracker(staleAfter, sampleInterval time.Duration) *Tracker {
+	return &Tracker{
+		StaleAfter:     staleAfter,
+		SampleInterval: sampleInterval,
+		states:         map[string]*ClusterState{},
+	}
+}
+
+func stateKey(itsContext, clusterName string) string {
+	return itsContext + "/" + clusterName
+}
+
+// Restore seeds the tracker with the last recorded samples, e.g. after a
+// restart. Since the older history is not read, the flags are assumed to have
+// changed at the time of the sample.
+func (t *Tracker) Restore(samples []*models.ClusterHealthSample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, sample := range samples {
+		t.states[stateKey(sample.ITSContext, sample.ClusterName)] = &ClusterState{
+			ITSContext:          sample.ITSContext,
+			ClusterName:         sample.ClusterName,
+			Available:           sample.Available,
+			Joined:              sample.Joined,
+			HeartbeatStale:      sample.HeartbeatStale,
+			HeartbeatAgeSeconds: sample.HeartbeatAgeSeconds,
+			AvailableSince:      sample.ObservedAt,
+			JoinedSince:         sample.ObservedAt,
+			HeartbeatSince:      sample.ObservedAt,
+			ObservedAt:          sample.ObservedAt,
+			recordedAt:          sample.ObservedAt,
+		}
+	}
+}
+
+// changedAt returns when a flag changed: the reported time if it is newer
+// than the previous sample and not in the future, otherwise now
+func changedAt(reported, previous, now time.Time) time.Time {
+	if reported.IsZero() || reported.After(now) || !reported.After(previous) {
+		return now
+	}
+	return reported
+}
+
+// Observe updates the state of a cluster and returns the sample to record,
+// or nil if nothing needs to be recorded
+func (t *Tracker) Observe(obs Observation, now time.Time) *models.ClusterHealthSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var age *int64
+	stale := false
+	staleChanged := now
+	if obs.LeaseRenewed != nil {
+		seconds := int64(now.Sub(*obs.LeaseRenewed).Seconds())
+		if seconds < 0 {
+			seconds = 0
+		}
+		age = &seconds
+		stale = now.Sub(*obs.LeaseRenewed) > t.StaleAfter
+		if stale {
+			staleChanged = obs.LeaseRenewed.Add(t.StaleAfter)
+		} else {
+			staleChanged = *obs.LeaseRenewed
+		}
+	}
+
+	key := stateKey(obs.ITSContext, obs.ClusterName)
+	state, known := t.states[key]
+	previous := time.Time{}
+	if known {
+		previous = state.ObservedAt
+	} else {
+		state = &ClusterState{ITSContext: obs.ITSContext, ClusterName: obs.ClusterName}
+		t.states[key] = state
+	}
+
+	transition := !known
+	observedAt := time.Time{}
+	if !known || state.Available != obs.Available {
+		state.AvailableSince = changedAt(obs.AvailableChanged, previous, now)
+		observedAt = latest(observedAt, state.AvailableSince)
+		transition = true
+	}
+	if !known || state.Joined != obs.Joined {
+		state.JoinedSince = changedAt(obs.JoinedChanged, previous, now)
+		observedAt = latest(observedAt, state.JoinedSince)
+		transition = true
+	}
+	if !known || state.HeartbeatStale != stale {
+		state.HeartbeatSince = changedAt(staleChanged, previous, now)
+		observedAt = latest(observedAt, state.HeartbeatSince)
+		transition = true
+	}
+
+	state.Available = obs.Available
+	state.Joined = obs.Joined
+	state.HeartbeatStale = stale
+	state.HeartbeatAgeSeconds = age
+	state.ObservedAt = now
+
+	if !transition && now.Sub(state.recordedAt) < t.SampleInterval {
+		return nil
+	}
+	if !transition {
+		observedAt = now
+	}
+	state.recordedAt = now
+	return &models.ClusterHealthSample{
+		ITSContext:          obs.ITSContext,
+		ClusterName:         obs.ClusterName,
+		Available:           obs.Available,
+		Joined:              obs.Joined,
+		HeartbeatStale:      stale,
+		HeartbeatAgeSeconds: age,
+		Transition:          transition,
+		ObservedAt:          observedAt,
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// Forget drops the clusters of an ITS that are not in seen, e.g. because
+// they were detached
+func (t *Tracker) Forget(itsContext string, seen map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, state := range t.states {
+		if state.ITSContext == itsContext && !seen[state.ClusterName] {
+			delete(t.states, key)
+		}
+	}
+}
+
+// Get returns the state of a cluster
+func (t *Tracker) Get(itsContext, clusterName string) (ClusterState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[stateKey(itsContext, clusterName)]
+	if !ok {
+		return ClusterState{}, false
+	}
+	return *state, true
+}
+
+// States returns the state of every cluster ordered by ITS and name
+func (t *Tracker) States() []ClusterState {
+	t.mu.Lock()
+	states := make([]ClusterState, 0, len(t.states))
+	for _, state := range t.states {
+		states = append(states, *state)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].ITSContext != states[j].ITSContext {
+			return states[i].ITSContext < states[j].ITSContext
+		}
+		return states[i].ClusterName < states[j].ClusterName
+	})
+	return states
+}
@@ -0,0 +1,109 @@
+// Command migrate-users moves the users that older releases kept in the
+// jwt-config ConfigMap into PostgreSQL, which is now the only user store. It
+// can also export the database users back into a ConfigMap for backups.
+//
+// Usage:
+//
+//	go run ./cmd/migrate-users -context its1 [-overwrite] [-dry-run] [-delete-configmap]
+//	go run ./cmd/migrate-users -context its1 -export -name jwt-config-backup
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/kubestellar/ui/backend/auth"
+	"github.com/kubestellar/ui/backend/k8s"
+	config "github.com/kubestellar/ui/backend/pkg/config"
+	"github.com/kubestellar/ui/backend/postgresql"
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func main() {
+	kubeContext := flag.String("context", "its1", "kubeconfig context of the cluster holding the ConfigMap")
+	namespace := flag.String("namespace", auth.Namespace, "namespace of the ConfigMap")
+	name := flag.String("name", auth.ConfigMapName, "name of the ConfigMap")
+	overwrite := flag.Bool("overwrite", false, "replace users that already exist in the database")
+	dryRun := flag.Bool("dry-run", false, "print the users that would be imported without changing anything")
+	deleteConfigMap := flag.Bool("delete-configmap", false, "delete the ConfigMap after a successful import")
+	export := flag.Bool("export", false, "export database users to the ConfigMap instead of importing")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: No .env file found, using environment")
+	}
+
+	cfg := config.LoadConfig()
+	if err := database.InitDatabase(cfg.DatabaseURL); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.CloseDatabase()
+
+	if err := postgresql.RunMigration(); err != nil {
+		log.Fatalf("Failed to run database migrations: %v", err)
+	}
+
+	clientset, _, err := k8s.GetClientSetWithContext(*kubeContext)
+	if err != nil {
+		log.Fatalf("Failed to get Kubernetes client for context %s: %v", *kubeContext, err)
+	}
+	ctx := context.Background()
+
+	if *export {
+		exported, err := auth.ExportUsers(auth.Users)
+		if err != nil {
+			log.Fatalf("Failed to export users: %v", err)
+		}
+		if *dryRun {
+			fmt.Printf("Would export %d users to %s/%s\n", len(exported.Users), *namespace, *name)
+			return
+		}
+		if err := auth.WriteConfigMap(ctx, clientset, *namespace, *name, exported); err != nil {
+			log.Fatalf("Failed to write ConfigMap: %v", err)
+		}
+		fmt.Printf("Exported %d users to %s/%s\n", len(exported.Users), *namespace, *name)
+		return
+	}
+
+	legacy, err := auth.ReadConfigMap(ctx, clientset, *namespace, *name)
+	if err != nil {
+		log.Fatalf("Failed to read ConfigMap: %v", err)
+	}
+	if legacy == nil {
+		fmt.Printf("ConfigMap %s/%s not found, nothing to migrate\n", *namespace, *name)
+		return
+	}
+	if legacy.JWTSecret != "" {
+		fmt.Println("Note: the ConfigMap contains a JWT secret, which is not migrated. Set JWT_SECRET instead.")
+	}
+
+	if *dryRun {
+		for username, user := range legacy.Users {
+			isAdmin, permissions := auth.ConvertPermissions(user.Permissions)
+			fmt.Printf("%s: admin=%t permissions=%v\n", username, isAdmin, permissions)
+		}
+		return
+	}
+
+	report := auth.ImportUsers(auth.Users, legacy, *overwrite)
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+
+	if *deleteConfigMap {
+		err := clientset.CoreV1().ConfigMaps(*namespace).Delete(ctx, *name, metav1.DeleteOptions{})
+		if err != nil {
+			log.Fatalf("Users imported but failed to delete ConfigMap: %v", err)
+		}
+		fmt.Printf("Deleted ConfigMap %s/%s\n", *namespace, *name)
+	}
+}
@@ -0,0 +1,291 @@
+// Package csrapproval decides the certificate signing requests the
+// registration agent of a joining cluster creates on an ITS. Configurable
+// rules match the cluster name, the requesting identity, a time window and a
+// pre-registration allowlist; CSRs no rule matches wait in a queue for manual
+// approval. Every ITS is swept periodically and every decision is recorded
+// with its reason.
+package csrapproval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/health"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"go.uber.org/zap"
+	certificatesv1 "k8s.io/api/certificates/v1"
+)
+
+const (
+	defaultSweepInterval = 15 * time.Second
+	sweepTimeout         = 20 * time.Second
+)
+
+// SweeperComponentPrefix prefixes the name under which the sweeper of each
+// ITS reports its health
+const SweeperComponentPrefix = "csr-approval/"
+
+// ErrCSRNotFound is returned when a CSR to decide manually does not exist
+var ErrCSRNotFound = errors.New("CSR not found")
+
+// ErrCSRDecided is returned when a CSR to decide manually was already decided
+var ErrCSRDecided = errors.New("CSR was already approved or denied")
+
+var (
+	initOnce sync.Once
+	enabled  bool
+	// processMu serializes the decisions of this process, so that the sweeper
+	// and onboarding do not decide the same CSR twice
+	processMu sync.Mutex
+)
+
+// Outcome is the decision taken on a CSR
+type Outcome struct {
+	Request  Request  `json:"request"`
+	Decision Decision `json:"decision"`
+}
+
+// Init starts sweeping the pending CSRs of every ITS. It must be called after
+// the database is initialized. Until then no rule is loaded and every CSR
+// stays pending, so handlers can be exercised in tests without a database.
+func Init() {
+	initOnce.Do(func() {
+		enabled = true
+		go sweepLoop(sweepInterval())
+	})
+}
+
+func sweepInterval() time.Duration {
+	value := os.Getenv("CSR_APPROVAL_INTERVAL_SECONDS")
+	if value == "" {
+		return defaultSweepInterval
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		log.LogWarn("Invalid CSR_APPROVAL_INTERVAL_SECONDS, using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultSweepInterval))
+		return defaultSweepInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, space := range hub.SpacesOfType(hub.ITS) {
+			component := health.ComponentHealth{
+				Status:   "healthy",
+				Message:  "deciding CSRs of joining clusters",
+				Metadata: map[string]interface{}{"its_context": space.Context},
+			}
+			if err := sweepITS(space.Context); err != nil {
+				log.LogWarn("Failed to sweep CSRs", zap.String("its", space.Context), zap.Error(err))
+				component.Status = "degraded"
+				component.Message = "cannot decide CSRs of the ITS"
+				component.Error = err.Error()
+			}
+			health.ReportComponent(SweeperComponentPrefix+space.Context, component)
+		}
+		<-ticker.C
+	}
+}
+
+func sweepITS(itsContext string) error {
+	hubClients, err := Clients(itsContext)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+	defer cancel()
+	_, err = Process(ctx, itsContext, hubClients, "")
+	return err
+}
+
+// Clients connects to an ITS
+func Clients(itsContext string) (*ocm.Clients, error) {
+	_, config, err := k8s.GetClientSetWithConfigContext(itsContext)
+	if err != nil {
+		return nil, err
+	}
+	return ocm.NewClients(config)
+}
+
+// policy loads the rules and the allowlist
+func policy() ([]*models.CSRApprovalRule, []*models.CSRAllowlistEntry, error) {
+	if !enabled {
+		return []*models.CSRApprovalRule{}, []*models.CSRAllowlistEntry{}, nil
+	}
+	rules, err := models.ListCSRApprovalRules()
+	if err != nil {
+		return nil, nil, err
+	}
+	allowlist, err := models.ListCSRAllowlist()
+	if err != nil {
+		return nil, nil, err
+	}
+	return rules, allowlist, nil
+}
+
+func record(request Request, decision Decision, mode, decidedBy string) {
+	if !enabled {
+		return
+	}
+	entry := &models.CSRDecision{
+		ITSContext:      request.ITSContext,
+		CSRName:         request.CSRName,
+		ClusterName:     request.ClusterName,
+		Requester:       request.Username,
+		RequesterGroups: request.Groups,
+		Decision:        decision.Decision,
+		Mode:            mode,
+		Reason:          decision.Reason,
+		DecidedBy:       decidedBy,
+	}
+	if decision.Rule != nil {
+		id := decision.Rule.ID
+		entry.RuleID = &id
+		entry.RuleName = decision.Rule.Name
+	}
+	if _, err := models.InsertCSRDecision(entry); err != nil {
+		log.LogError("Failed to record CSR decision",
+			zap.String("csr", request.CSRName),
+			zap.String("decision", decision.Decision),
+			zap.Error(err))
+	}
+}
+
+func apply(ctx context.Context, hubClients *ocm.Clients, csr *certificatesv1.CertificateSigningRequest, decision Decision) error {
+	switch decision.Decision {
+	case models.CSRApproved:
+		return ocm.ApproveCSR(ctx, hubClients, csr, decision.Reason)
+	case models.CSRDenied:
+		return ocm.DenyCSR(ctx, hubClients, csr, decision.Reason)
+	}
+	return nil
+}
+
+// Process evaluates the pending CSRs of a cluster, or of every cluster if
+// clusterName is empty, approves or denies those a rule matches and queues
+// the others for manual approval. CSRs that could not be decided are left out
+// of the outcomes and the last such error is returned.
+func Process(ctx context.Context, itsContext string, hubClients *ocm.Clients, clusterName string) ([]Outcome, error) {
+	processMu.Lock()
+	defer processMu.Unlock()
+
+	csrs, err := ocm.PendingCSRs(ctx, hubClients, clusterName)
+	if err != nil || len(csrs) == 0 {
+		return []Outcome{}, err
+	}
+	rules, allowlist, err := policy()
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]Outcome, 0, len(csrs))
+	var applyErr error
+	now := time.Now()
+	for i := range csrs {
+		request := RequestFromCSR(itsContext, &csrs[i])
+		decision := Evaluate(rules, allowlist, request, now)
+		if err := apply(ctx, hubClients, &csrs[i], decision); err != nil {
+			// Possibly decided meanwhile by another replica, the next sweep sees it
+			applyErr = err
+			continue
+		}
+		record(request, decision, models.CSRDecisionAuto, "")
+		if decision.Decision != models.CSRPending {
+			log.LogInfo("Decided CSR",
+				zap.String("its", itsContext),
+				zap.String("csr", request.CSRName),
+				zap.String("cluster", request.ClusterName),
+				zap.String("decision", decision.Decision),
+				zap.String("reason", decision.Reason))
+		}
+		outcomes = append(outcomes, Outcome{Request: request, Decision: decision})
+	}
+	return outcomes, applyErr
+}
+
+// Queue returns the pending CSRs of an ITS and how the rules evaluate them
+func Queue(ctx context.Context, itsContext string, hubClients *ocm.Clients) ([]Outcome, error) {
+	csrs, err := ocm.PendingCSRs(ctx, hubClients, "")
+	if err != nil {
+		return nil, err
+	}
+	rules, allowlist, err := policy()
+	if err != nil {
+		return nil, err
+	}
+	queue := make([]Outcome, 0, len(csrs))
+	now := time.Now()
+	for i := range csrs {
+		request := RequestFromCSR(itsContext, &csrs[i])
+		queue = append(queue, Outcome{Request: request, Decision: Evaluate(rules, allowlist, request, now)})
+	}
+	return queue, nil
+}
+
+// Decide approves or denies a pending CSR of a joining cluster on behalf of
+// username
+func Decide(ctx context.Context, itsContext string, hubClients *ocm.Clients, csrName string, approve bool,
+	username, reason string) (*Outcome, error) {
+	processMu.Lock()
+	defer processMu.Unlock()
+
+	csr, err := ocm.GetCSR(ctx, hubClients, csrName)
+	if err != nil {
+		return nil, err
+	}
+	if csr == nil || csr.Labels[ocm.ClusterNameLabel] == "" {
+		return nil, fmt.Errorf("%w: %s", ErrCSRNotFound, csrName)
+	}
+	if ocm.CSRDecided(csr) {
+		return nil, fmt.Errorf("%w: %s", ErrCSRDecided, csrName)
+	}
+
+	decision := Decision{Decision: models.CSRDenied, Reason: "denied by " + username}
+	if approve {
+		decision = Decision{Decision: models.CSRApproved, Reason: "approved by " + username}
+	}
+	if reason != "" {
+		decision.Reason += ": " + reason
+	}
+	if err := apply(ctx, hubClients, csr, decision); err != nil {
+		return nil, err
+	}
+	request := RequestFromCSR(itsContext, csr)
+	record(request, decision, models.CSRDecisionManual, username)
+	log.LogInfo("Decided CSR manually",
+		zap.String("its", itsContext),
+		zap.String("csr", csrName),
+		zap.String("decision", decision.Decision),
+		zap.String("username", username))
+	return &Outcome{Request: request, Decision: decision}, nil
+}
+
+// Preregister puts a cluster on the allowlist of an ITS for ttl
+func Preregister(clusterName, itsContext string, ttl time.Duration, createdBy, note string) error {
+	if !enabled {
+		return nil
+	}
+	expiresAt := time.Now().Add(ttl)
+	return models.SaveCSRAllowlistEntry(&models.CSRAllowlistEntry{
+		ClusterName: clusterName,
+		ITSContext:  itsContext,
+		Note:        note,
+		ExpiresAt:   &expiresAt,
+		CreatedBy:   createdBy,
+	})
+}
@@ -0,0 +1,238 @@
+package csrapproval
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/models"
+	certificatesv1 "k8s.io/api/certificates/v1"
+)
+
+// identityPrefix prefixes the common name the registration agent of a
+// cluster requests, system:open-cluster-management:<cluster>:<agent>
+const identityPrefix = "system:open-cluster-management:"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Request is what the rules see of a CSR
+type Request struct {
+	ITSContext  string    `json:"its_context"`
+	CSRName     string    `json:"csr_name"`
+	ClusterName string    `json:"cluster_name"`
+	Username    string    `json:"username"`
+	Groups      []string  `json:"groups"`
+	SignerName  string    `json:"signer_name"`
+	CommonName  string    `json:"common_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Decision is the outcome of evaluating the rules for a request
+type Decision struct {
+	// Decision is models.CSRApproved, models.CSRDenied or models.CSRPending
+	Decision string                  `json:"decision"`
+	Rule     *models.CSRApprovalRule `json:"rule,omitempty"`
+	Reason   string                  `json:"reason"`
+	// Mismatches explain why the enabled rules did not match a pending request
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// RequestFromCSR reads a request from a CSR of the registration agent
+func RequestFromCSR(itsContext string, csr *certificatesv1.CertificateSigningRequest) Request {
+	request := Request{
+		ITSContext:  itsContext,
+		CSRName:     csr.Name,
+		ClusterName: csr.Labels[ocm.ClusterNameLabel],
+		Username:    csr.Spec.Username,
+		Groups:      csr.Spec.Groups,
+		SignerName:  csr.Spec.SignerName,
+		CreatedAt:   csr.CreationTimestamp.Time,
+	}
+	if request.Groups == nil {
+		request.Groups = []string{}
+	}
+	if block, _ := pem.Decode(csr.Spec.Request); block != nil {
+		if parsed, err := x509.ParseCertificateRequest(block.Bytes); err == nil {
+			request.CommonName = parsed.Subject.CommonName
+		}
+	}
+	return request
+}
+
+// Evaluate decides a request. A CSR whose requested identity does not belong
+// to its cluster is never decided automatically; otherwise the first enabled
+// rule matching it decides, and it stays pending if none does. allowlist
+// holds the entries that did not expire.
+func Evaluate(rules []*models.CSRApprovalRule, allowlist []*models.CSRAllowlistEntry, request Request, now time.Time) Decision {
+	if !strings.HasPrefix(request.CommonName, identityPrefix+request.ClusterName+":") {
+		return Decision{
+			Decision: models.CSRPending,
+			Reason: fmt.Sprintf("the requested identity %q does not belong to cluster %s, it must be approved manually",
+				request.CommonName, request.ClusterName),
+		}
+	}
+
+	mismatches := []string{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		if reason := mismatch(rule, allowlist, request, now); reason != "" {
+			mismatches = append(mismatches, fmt.Sprintf("rule %s: %s", rule.Name, reason))
+			continue
+		}
+		decision := models.CSRApproved
+		if rule.Action == models.CSRActionDeny {
+			decision = models.CSRDenied
+		}
+		return Decision{Decision: decision, Rule: rule, Reason: fmt.Sprintf("matched rule %s", rule.Name)}
+	}
+	return Decision{Decision: models.CSRPending, Reason: "no approval rule matched", Mismatches: mismatches}
+}
+
+// mismatch returns why a rule does not match a request, or an empty string
+// if it does
+func mismatch(rule *models.CSRApprovalRule, allowlist []*models.CSRAllowlistEntry, request Request, now time.Time) string {
+	if rule.ITSContext != "" && rule.ITSContext != request.ITSContext {
+		return "it only applies to ITS " + rule.ITSContext
+	}
+	if rule.ClusterPattern != "" {
+		if matched, err := path.Match(rule.ClusterPattern, request.ClusterName); err != nil || !matched {
+			return fmt.Sprintf("cluster name does not match %q", rule.ClusterPattern)
+		}
+	}
+	if len(rule.Identities) > 0 && !identityMatches(rule.Identities, request) {
+		return fmt.Sprintf("requester %s is not one of %v", request.Username, rule.Identities)
+	}
+	if inWindow, err := InWindow(rule, now); err != nil || !inWindow {
+		return "outside of its time window"
+	}
+	if rule.RequireAllowlist && !Allowlisted(allowlist, request.ITSContext, request.ClusterName) {
+		return "cluster is not on the allowlist"
+	}
+	return ""
+}
+
+func identityMatches(patterns []string, request Request) bool {
+	identities := append([]string{request.Username}, request.Groups...)
+	for _, pattern := range patterns {
+		for _, identity := range identities {
+			if matched, err := path.Match(pattern, identity); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allowlisted reports whether a cluster is pre-registered to join the ITS
+func Allowlisted(allowlist []*models.CSRAllowlistEntry, itsContext, clusterName string) bool {
+	for _, entry := range allowlist {
+		if entry.ClusterName == clusterName && (entry.ITSContext == "" || entry.ITSContext == itsContext) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// InWindow reports whether now is within the time window of a rule. A rule
+// without a window always applies.
+func InWindow(rule *models.CSRApprovalRule, now time.Time) (bool, error) {
+	location := time.UTC
+	if rule.Timezone != "" {
+		loaded, err := time.LoadLocation(rule.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("invalid timezone %q: %v", rule.Timezone, err)
+		}
+		location = loaded
+	}
+	local := now.In(location)
+
+	if len(rule.WindowDays) > 0 {
+		today := false
+		for _, day := range rule.WindowDays {
+			if weekday, ok := weekdays[strings.ToLower(day)]; ok && weekday == local.Weekday() {
+				today = true
+			}
+		}
+		if !today {
+			return false, nil
+		}
+	}
+	if rule.WindowStart == "" && rule.WindowEnd == "" {
+		return true, nil
+	}
+
+	start, err := parseClock(rule.WindowStart)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(rule.WindowEnd)
+	if err != nil {
+		return false, err
+	}
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// ValidateRule checks the settings of a rule before it is stored
+func ValidateRule(rule *models.CSRApprovalRule) error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.Action != models.CSRActionApprove && rule.Action != models.CSRActionDeny {
+		return fmt.Errorf("invalid action %q, expected %s or %s", rule.Action, models.CSRActionApprove, models.CSRActionDeny)
+	}
+	if _, err := path.Match(rule.ClusterPattern, ""); err != nil {
+		return fmt.Errorf("invalid cluster_pattern %q: %v", rule.ClusterPattern, err)
+	}
+	for _, identity := range rule.Identities {
+		if _, err := path.Match(identity, ""); err != nil || identity == "" {
+			return fmt.Errorf("invalid identity pattern %q", identity)
+		}
+	}
+	for _, day := range rule.WindowDays {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid window day %q, expected one of mon, tue, wed, thu, fri, sat, sun", day)
+		}
+	}
+	if (rule.WindowStart == "") != (rule.WindowEnd == "") {
+		return errors.New("window_start and window_end must be set together")
+	}
+	if rule.WindowStart == rule.WindowEnd && rule.WindowStart != "" {
+		return errors.New("window_start and window_end must differ")
+	}
+	for _, value := range []string{rule.WindowStart, rule.WindowEnd} {
+		if value == "" {
+			continue
+		}
+		if _, err := parseClock(value); err != nil {
+			return err
+		}
+	}
+	if rule.Timezone != "" {
+		if _, err := time.LoadLocation(rule.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %v", rule.Timezone, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package detach
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// Clients are the clients an impact is read from
+type Clients struct {
+	// ITS holds the managed clusters and the ManifestWorks in their namespaces
+	ITS *ocm.Clients
+	// WDS holds the BindingPolicies of each WDS, by space name
+	WDS map[string]dynamic.Interface
+}
+
+// ClientsFor builds the clients of an ITS and of every registered WDS. The
+// WDS spaces that cannot be reached are returned as warnings.
+func ClientsFor(itsContext string) (*Clients, []string, error) {
+	_, config, err := k8s.GetClientSetWithConfigContext(itsContext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ITS %s: %v", itsContext, err)
+	}
+	itsClients, err := ocm.NewClients(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ITS %s: %v", itsContext, err)
+	}
+
+	clients := &Clients{ITS: itsClients, WDS: map[string]dynamic.Interface{}}
+	warnings := []string{}
+	for _, space := range hub.SpacesOfType(hub.WDS) {
+		_, client, err := k8s.GetClientSetWithContext(space.Context)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to connect to WDS %s: %v", space.Name, err))
+			continue
+		}
+		clients.WDS[space.Name] = client
+	}
+	return clients, warnings, nil
+}
+
+// Analyze reports the BindingPolicies of every WDS that select a cluster and
+// the workloads delivered to it. A WDS whose policies cannot be listed is
+// reported as a warning rather than failing the analysis.
+func Analyze(ctx context.Context, itsName string, clients *Clients, clusterName string) (*Impact, error) {
+	cluster, err := ocm.GetManagedCluster(ctx, clients.ITS, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, ErrClusterNotFound
+	}
+	clusters, err := clients.ITS.Dynamic.Resource(ocm.ManagedClusterGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed clusters: %v", err)
+	}
+
+	policies := []PolicyImpact{}
+	warnings := []string{}
+	for wds, client := range clients.WDS {
+		list, err := client.Resource(BindingPolicyGVR).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to list binding policies of WDS %s: %v", wds, err))
+			continue
+		}
+		matched, err := MatchPolicies(wds, list.Items, cluster, clusters.Items)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("WDS %s: %v", wds, err))
+			continue
+		}
+		policies = append(policies, matched...)
+	}
+
+	workloads, err := DeliveredWorkloads(ctx, clients.ITS.Dynamic, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := NewImpact(itsName, cluster, policies, workloads)
+	impact.Warnings = warnings
+	return impact, nil
+}
+
+// DeliveredWorkloads lists the workloads KubeStellar delivered to a cluster
+func DeliveredWorkloads(ctx context.Context, its dynamic.Interface, clusterName string) ([]Workload, error) {
+	works, err := its.Resource(ManifestWorkGVR).Namespace(clusterName).List(ctx, metav1.ListOptions{LabelSelector: BindingOwnerLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifest works of cluster %s: %v", clusterName, err)
+	}
+	return WorkloadsFromManifestWorks(works.Items), nil
+}
+
+// WaitForDrain polls the workloads delivered to a cluster until none are
+// left or ctx is done. progress, if set, is called with the workloads still
+// delivered after each poll.
+func WaitForDrain(ctx context.Context, its dynamic.Interface, clusterName string, interval time.Duration, progress func([]Workload)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		workloads, err := DeliveredWorkloads(ctx, its, clusterName)
+		if err == nil && len(workloads) == 0 {
+			return nil
+		}
+		if err == nil && progress != nil {
+			progress(workloads)
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("cluster %s did not drain: %v", clusterName, err)
+			}
+			return fmt.Errorf("cluster %s did not drain, %d workloads are still delivered", clusterName, len(workloads))
+		case <-ticker.C:
+		}
+	}
+}
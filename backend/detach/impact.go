@@ -0,0 +1,284 @@
+// Package detach works out what a managed cluster still runs before it is
+// detached from its ITS: the BindingPolicies that select it and the workloads
+// KubeStellar delivered to it. It also plans and waits for a drain, which
+// relabels the cluster so the policies stop selecting it.
+package detach
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// BindingOwnerLabel is set by the KubeStellar transport controller on the
+// ManifestWorks it creates for a Binding. Bindings are named after their
+// BindingPolicy.
+const BindingOwnerLabel = "transport.kubestellar.io/originOwnerReferenceBindingKey"
+
+var (
+	// BindingPolicyGVR is the resource of BindingPolicies in a WDS
+	BindingPolicyGVR = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindingpolicies"}
+	// ManifestWorkGVR is the resource of ManifestWorks in the cluster namespaces of an ITS
+	ManifestWorkGVR = schema.GroupVersionResource{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"}
+)
+
+// ErrClusterNotFound is returned when the cluster is not a managed cluster of the ITS
+var ErrClusterNotFound = errors.New("managed cluster not found")
+
+// PolicyImpact is a BindingPolicy that selects the cluster
+type PolicyImpact struct {
+	WDS  string `json:"wds"`
+	Name string `json:"name"`
+	// Selectors are the cluster selectors of the policy that match the cluster
+	Selectors []string `json:"selectors"`
+	// OtherClusters are the other clusters the policy selects, where its
+	// workloads keep running after the cluster is detached
+	OtherClusters []string `json:"otherClusters"`
+	// Workloads is the number of objects the policy delivered to the cluster
+	Workloads int `json:"workloads"`
+
+	selectors []metav1.LabelSelector
+}
+
+// Workload is an object KubeStellar delivered to the cluster
+type Workload struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Policy is the BindingPolicy the object was delivered for
+	Policy       string `json:"policy"`
+	ManifestWork string `json:"manifestWork"`
+}
+
+// DrainPlan is how the cluster is relabeled so its policies stop selecting it
+type DrainPlan struct {
+	// RemoveLabels are the labels removed from the cluster
+	RemoveLabels []string `json:"removeLabels"`
+	// Blocking are the policies, as wds/name, that keep selecting the cluster
+	// without those labels, for example with an empty or NotIn selector
+	Blocking []string `json:"blocking,omitempty"`
+	// Stranded are the policies, as wds/name, that select no other cluster:
+	// draining removes their workloads instead of moving them
+	Stranded []string `json:"stranded,omitempty"`
+}
+
+// Impact is what detaching a cluster affects
+type Impact struct {
+	Cluster   string            `json:"cluster"`
+	ITS       string            `json:"its"`
+	Labels    map[string]string `json:"labels"`
+	Policies  []PolicyImpact    `json:"policies"`
+	Workloads []Workload        `json:"workloads"`
+	Drain     DrainPlan         `json:"drain"`
+	// Warnings are the WDS spaces whose policies could not be read
+	Warnings  []string  `json:"warnings,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Safe reports whether no policy selects the cluster and nothing is
+// delivered to it, so detaching it affects no workload
+func (i *Impact) Safe() bool {
+	return len(i.Policies) == 0 && len(i.Workloads) == 0
+}
+
+// Drainable reports whether relabeling the cluster stops every policy from
+// selecting it
+func (i *Impact) Drainable() bool {
+	return len(i.Drain.Blocking) == 0
+}
+
+// clusterSelectors reads spec.clusterSelectors of a BindingPolicy
+func clusterSelectors(policy *unstructured.Unstructured) ([]metav1.LabelSelector, error) {
+	var spec struct {
+		ClusterSelectors []metav1.LabelSelector `json:"clusterSelectors"`
+	}
+	raw, _, _ := unstructured.NestedMap(policy.Object, "spec")
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &spec); err != nil {
+		return nil, fmt.Errorf("invalid cluster selectors in binding policy %s: %v", policy.GetName(), err)
+	}
+	return spec.ClusterSelectors, nil
+}
+
+// matching returns the selectors that match the labels
+func matching(selectors []metav1.LabelSelector, clusterLabels map[string]string) ([]metav1.LabelSelector, error) {
+	matched := []metav1.LabelSelector{}
+	for _, selector := range selectors {
+		parsed, err := metav1.LabelSelectorAsSelector(&selector)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Matches(labels.Set(clusterLabels)) {
+			matched = append(matched, selector)
+		}
+	}
+	return matched, nil
+}
+
+// MatchPolicies returns the policies of a WDS that select the cluster.
+// clusters are all managed clusters of the ITS, used to find the other
+// clusters each policy selects.
+func MatchPolicies(wds string, policies []unstructured.Unstructured, cluster *unstructured.Unstructured, clusters []unstructured.Unstructured) ([]PolicyImpact, error) {
+	impacts := []PolicyImpact{}
+	for i := range policies {
+		selectors, err := clusterSelectors(&policies[i])
+		if err != nil {
+			return nil, err
+		}
+		matched, err := matching(selectors, cluster.GetLabels())
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster selector in binding policy %s: %v", policies[i].GetName(), err)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		impact := PolicyImpact{WDS: wds, Name: policies[i].GetName(), OtherClusters: []string{}, selectors: matched}
+		for j := range matched {
+			impact.Selectors = append(impact.Selectors, metav1.FormatLabelSelector(&matched[j]))
+		}
+		for j := range clusters {
+			if clusters[j].GetName() == cluster.GetName() {
+				continue
+			}
+			if other, _ := matching(selectors, clusters[j].GetLabels()); len(other) > 0 {
+				impact.OtherClusters = append(impact.OtherClusters, clusters[j].GetName())
+			}
+		}
+		sort.Strings(impact.OtherClusters)
+		impacts = append(impacts, impact)
+	}
+	return impacts, nil
+}
+
+// WorkloadsFromManifestWorks lists the objects of the ManifestWorks
+// KubeStellar created in the namespace of a cluster. ManifestWorks of other
+// controllers, such as add-ons, are skipped.
+func WorkloadsFromManifestWorks(works []unstructured.Unstructured) []Workload {
+	workloads := []Workload{}
+	for _, work := range works {
+		policy := work.GetLabels()[BindingOwnerLabel]
+		if policy == "" {
+			continue
+		}
+		manifests, _, _ := unstructured.NestedSlice(work.Object, "spec", "workload", "manifests")
+		for _, manifest := range manifests {
+			object, ok := manifest.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			obj := unstructured.Unstructured{Object: object}
+			gv, _ := schema.ParseGroupVersion(obj.GetAPIVersion())
+			workloads = append(workloads, Workload{
+				Group:        gv.Group,
+				Version:      gv.Version,
+				Kind:         obj.GetKind(),
+				Namespace:    obj.GetNamespace(),
+				Name:         obj.GetName(),
+				Policy:       policy,
+				ManifestWork: work.GetName(),
+			})
+		}
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return workloads
+}
+
+// NewImpact combines the policies selecting a cluster and the workloads
+// delivered to it, and plans the drain
+func NewImpact(itsName string, cluster *unstructured.Unstructured, policies []PolicyImpact, workloads []Workload) *Impact {
+	impact := &Impact{
+		Cluster:   cluster.GetName(),
+		ITS:       itsName,
+		Labels:    cluster.GetLabels(),
+		Policies:  policies,
+		Workloads: workloads,
+		CheckedAt: time.Now(),
+	}
+	if impact.Labels == nil {
+		impact.Labels = map[string]string{}
+	}
+	sort.Slice(impact.Policies, func(i, j int) bool {
+		if impact.Policies[i].WDS != impact.Policies[j].WDS {
+			return impact.Policies[i].WDS < impact.Policies[j].WDS
+		}
+		return impact.Policies[i].Name < impact.Policies[j].Name
+	})
+	for i := range impact.Policies {
+		for _, workload := range workloads {
+			if workload.Policy == impact.Policies[i].Name {
+				impact.Policies[i].Workloads++
+			}
+		}
+	}
+	impact.Drain = PlanDrain(impact.Labels, impact.Policies)
+	return impact
+}
+
+// isReservedLabel reports whether a label is managed by Open Cluster
+// Management, which would put it back after it is removed
+func isReservedLabel(key string) bool {
+	return strings.Contains(key, "open-cluster-management.io/")
+}
+
+// PlanDrain chooses the labels to remove so that none of the policies
+// selects the cluster any more. A selector stops matching once a label it
+// requires through matchLabels, In or Exists is gone; policies whose
+// selectors still match without the removable labels are blocking.
+func PlanDrain(clusterLabels map[string]string, policies []PolicyImpact) DrainPlan {
+	remove := map[string]bool{}
+	for _, policy := range policies {
+		for _, selector := range policy.selectors {
+			for key := range selector.MatchLabels {
+				remove[key] = true
+			}
+			for _, expr := range selector.MatchExpressions {
+				if expr.Operator == metav1.LabelSelectorOpIn || expr.Operator == metav1.LabelSelectorOpExists {
+					remove[expr.Key] = true
+				}
+			}
+		}
+	}
+
+	plan := DrainPlan{RemoveLabels: []string{}}
+	remaining := map[string]string{}
+	for key, value := range clusterLabels {
+		if remove[key] && !isReservedLabel(key) {
+			plan.RemoveLabels = append(plan.RemoveLabels, key)
+			continue
+		}
+		remaining[key] = value
+	}
+	sort.Strings(plan.RemoveLabels)
+
+	for _, policy := range policies {
+		id := policy.WDS + "/" + policy.Name
+		if still, _ := matching(policy.selectors, remaining); len(still) > 0 {
+			plan.Blocking = append(plan.Blocking, id)
+		} else if len(policy.OtherClusters) == 0 {
+			plan.Stranded = append(plan.Stranded, id)
+		}
+	}
+	return plan
+}
@@ -138,6 +138,14 @@ func (hc *HealthChecker) HealthHandler() gin.HandlerFunc {
 			}
 		}
 
+		// Add the components that report their own health
+		for name, reported := range ReportedComponents() {
+			health.Components[name] = reported
+			if reported.Status == "unhealthy" {
+				overallHealthy = false
+			}
+		}
+
 		// Set overall status
 		statusCode := http.StatusOK
 		if overallHealthy {
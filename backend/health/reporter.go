@@ -0,0 +1,38 @@
+package health
+
+import "sync"
+
+// Components that run in the background, such as watchers, cannot be probed
+// on demand, so they report their own health here and HealthHandler includes
+// the last report of each in its response
+var (
+	reportedMu         sync.RWMutex
+	reportedComponents = map[string]ComponentHealth{}
+)
+
+// ReportComponent records the current health of a self-reporting component
+func ReportComponent(name string, health ComponentHealth) {
+	reportedMu.Lock()
+	defer reportedMu.Unlock()
+	reportedComponents[name] = health
+}
+
+// RemoveComponent forgets a self-reporting component that has stopped
+func RemoveComponent(name string) {
+	reportedMu.Lock()
+	defer reportedMu.Unlock()
+	delete(reportedComponents, name)
+}
+
+// ReportedComponents returns the last reported health of every
+// self-reporting component
+func ReportedComponents() map[string]ComponentHealth {
+	reportedMu.RLock()
+	defer reportedMu.RUnlock()
+
+	components := make(map[string]ComponentHealth, len(reportedComponents))
+	for name, health := range reportedComponents {
+		components[name] = health
+	}
+	return components
+}
@@ -0,0 +1,175 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubestellar/ui/backend/log"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+)
+
+const discoveryTimeout = 5 * time.Second
+
+// controlPlaneGVR is the KubeFlex resource every KubeStellar space is created from
+var controlPlaneGVR = schema.GroupVersionResource{Group: "tenancy.kflex.kubestellar.org", Version: "v1alpha1", Resource: "controlplanes"}
+
+// kubeflexContexts are the contexts of the KubeFlex hosting cluster created
+// by the KubeStellar getting-started setup
+var kubeflexContexts = []string{"kind-kubeflex", "k3d-kubeflex"}
+
+func kubeconfigPath() string {
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(homedir.HomeDir(), ".kube", "config")
+}
+
+func loadKubeconfig() (*clientcmdapi.Config, error) {
+	return clientcmd.LoadFromFile(kubeconfigPath())
+}
+
+// hostingContext returns the kubeconfig context of the KubeFlex hosting cluster
+func hostingContext(kubeconfig *clientcmdapi.Config) string {
+	if name := os.Getenv(discoveryCtxEnv); name != "" {
+		return name
+	}
+	for _, name := range kubeflexContexts {
+		if _, ok := kubeconfig.Contexts[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// discoverControlPlanes registers the ITS and WDS ControlPlanes of the
+// KubeFlex hosting cluster. Following kflex, the kubeconfig context of a
+// space is named after its ControlPlane.
+func discoverControlPlanes(kubeconfig *clientcmdapi.Config) ([]Space, error) {
+	hosting := hostingContext(kubeconfig)
+	if hosting == "" {
+		return nil, nil
+	}
+	config, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{CurrentContext: hosting}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load KubeFlex context %s: %v", hosting, err)
+	}
+	config.Timeout = discoveryTimeout
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s: %v", hosting, err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s: %v", hosting, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	list, err := dynamicClient.Resource(controlPlaneGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KubeFlex ControlPlanes in %s: %v", hosting, err)
+	}
+
+	spaces := []Space{}
+	for i := range list.Items {
+		cp := &list.Items[i]
+		spaceType, ok := controlPlaneType(cp)
+		if !ok {
+			continue
+		}
+		space := Space{Name: cp.GetName(), Type: spaceType, Context: cp.GetName(), Source: SourceKubeFlex}
+		space.APIServer = contextServer(kubeconfig, space.Context)
+		if space.APIServer == "" {
+			space.APIServer = controlPlaneServer(ctx, clientset, cp)
+		}
+		spaces = append(spaces, space)
+	}
+	log.LogDebug("Discovered KubeFlex ControlPlanes", zap.String("context", hosting), zap.Int("spaces", len(spaces)))
+	return spaces, nil
+}
+
+// controlPlaneType infers the space type of a ControlPlane from the
+// post-create hook KubeStellar installed it with, falling back to its name
+func controlPlaneType(cp *unstructured.Unstructured) (SpaceType, bool) {
+	hook, _, _ := unstructured.NestedString(cp.Object, "spec", "postCreateHook")
+	for _, candidate := range []string{strings.ToLower(hook), strings.ToLower(cp.GetName())} {
+		switch {
+		case strings.Contains(candidate, "its"):
+			return ITS, true
+		case strings.Contains(candidate, "wds"):
+			return WDS, true
+		}
+	}
+	return "", false
+}
+
+// controlPlaneServer reads the API endpoint from the kubeconfig secret
+// KubeFlex publishes for a ControlPlane
+func controlPlaneServer(ctx context.Context, clientset kubernetes.Interface, cp *unstructured.Unstructured) string {
+	ref, _, _ := unstructured.NestedStringMap(cp.Object, "status", "secretRef")
+	if ref["name"] == "" || ref["namespace"] == "" {
+		return ""
+	}
+	secret, err := clientset.CoreV1().Secrets(ref["namespace"]).Get(ctx, ref["name"], metav1.GetOptions{})
+	if err != nil {
+		log.LogDebug("Failed to read ControlPlane kubeconfig", zap.String("controlplane", cp.GetName()), zap.Error(err))
+		return ""
+	}
+	key := ref["key"]
+	if key == "" {
+		key = "kubeconfig"
+	}
+	config, err := clientcmd.Load(secret.Data[key])
+	if err != nil {
+		return ""
+	}
+	return contextServer(config, config.CurrentContext)
+}
+
+// kubeconfigSpaces registers the contexts named like KubeStellar spaces, as
+// the UI did before spaces were configurable
+func kubeconfigSpaces(kubeconfig *clientcmdapi.Config) []Space {
+	spaces := []Space{}
+	for name := range kubeconfig.Contexts {
+		var spaceType SpaceType
+		switch {
+		case strings.HasPrefix(name, "its"):
+			spaceType = ITS
+		case strings.HasPrefix(name, "wds"):
+			spaceType = WDS
+		default:
+			continue
+		}
+		spaces = append(spaces, Space{
+			Name:      name,
+			Type:      spaceType,
+			Context:   name,
+			APIServer: contextServer(kubeconfig, name),
+			Source:    SourceKubeconfig,
+		})
+	}
+	return spaces
+}
+
+func contextServer(kubeconfig *clientcmdapi.Config, contextName string) string {
+	ctx, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return ""
+	}
+	if cluster, ok := kubeconfig.Clusters[ctx.Cluster]; ok {
+		return cluster.Server
+	}
+	return ""
+}
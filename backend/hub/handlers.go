@@ -0,0 +1,34 @@
+package hub
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSpacesHandler lists the registered spaces, optionally of one type
+func ListSpacesHandler(c *gin.Context) {
+	spaces := Spaces()
+	if spaceType := SpaceType(c.Query("type")); spaceType != "" {
+		if spaceType != ITS && spaceType != WDS {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be its or wds"})
+			return
+		}
+		spaces = SpacesOfType(spaceType)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"spaces":     spaces,
+		"defaultITS": Default(ITS).Name,
+		"defaultWDS": Default(WDS).Name,
+	})
+}
+
+// RefreshSpacesHandler rebuilds the registry, e.g. after a ControlPlane was created
+func RefreshSpacesHandler(c *gin.Context) {
+	spaces, err := Refresh()
+	response := gin.H{"spaces": spaces}
+	if err != nil {
+		response["warning"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,374 @@
+// Package hub keeps the registry of the KubeStellar spaces the UI works with:
+// the inventory and transport spaces (ITS) clusters are onboarded to and the
+// workload description spaces (WDS) workloads are deployed to. Spaces come
+// from a configuration file, from the KubeFlex ControlPlanes of the hosting
+// cluster and from the contexts of the kubeconfig, in that order.
+package hub
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/log"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// SpaceType is the kind of a KubeStellar space
+type SpaceType string
+
+const (
+	// ITS is an inventory and transport space, the OCM hub of managed clusters
+	ITS SpaceType = "its"
+	// WDS is a workload description space holding workloads and BindingPolicies
+	WDS SpaceType = "wds"
+)
+
+// Sources a space can be registered from
+const (
+	SourceConfig     = "config"
+	SourceKubeFlex   = "kubeflex"
+	SourceKubeconfig = "kubeconfig"
+	SourceBuiltin    = "builtin"
+)
+
+const (
+	spacesFileEnv     = "HUB_SPACES_FILE"
+	discoveryEnv      = "HUB_DISCOVERY"
+	discoveryCtxEnv   = "HUB_DISCOVERY_CONTEXT"
+	defaultITSEnv     = "HUB_DEFAULT_ITS"
+	defaultWDSEnv     = "HUB_DEFAULT_WDS"
+	registryTTL       = 5 * time.Minute
+	builtinITS        = "its1"
+	builtinWDS        = "wds1"
+	builtinITSAddress = "https://its1.localtest.me:9443"
+)
+
+// Space is a KubeStellar space and how to reach it
+type Space struct {
+	Name string    `json:"name"`
+	Type SpaceType `json:"type"`
+	// Context is the kubeconfig context clients of the space are built from
+	Context string `json:"context"`
+	// APIServer is the endpoint clusters and users outside the hosting
+	// cluster reach the space at
+	APIServer string `json:"apiServer,omitempty"`
+	Default   bool   `json:"default"`
+	Source    string `json:"source"`
+}
+
+// spacesFile is the format of HUB_SPACES_FILE
+type spacesFile struct {
+	Spaces []Space `json:"spaces"`
+}
+
+type registry struct {
+	mu        sync.RWMutex
+	spaces    []Space
+	loadedAt  time.Time
+	signature string
+	err       error
+}
+
+var current = &registry{}
+
+// Spaces returns all registered spaces, ordered by type and name
+func Spaces() []Space {
+	spaces := load()
+	out := make([]Space, len(spaces))
+	copy(out, spaces)
+	return out
+}
+
+// SpacesOfType returns the registered spaces of one type
+func SpacesOfType(spaceType SpaceType) []Space {
+	out := []Space{}
+	for _, space := range load() {
+		if space.Type == spaceType {
+			out = append(out, space)
+		}
+	}
+	return out
+}
+
+// Get returns the space with the given name, or else the space behind the
+// kubeconfig context of that name
+func Get(name string) (*Space, bool) {
+	spaces := load()
+	for _, space := range spaces {
+		if space.Name == name {
+			s := space
+			return &s, true
+		}
+	}
+	for _, space := range spaces {
+		if space.Context == name {
+			s := space
+			return &s, true
+		}
+	}
+	return nil, false
+}
+
+// Default returns the default space of a type. The registry always holds at
+// least one space of each type.
+func Default(spaceType SpaceType) *Space {
+	var first *Space
+	for _, space := range load() {
+		if space.Type != spaceType {
+			continue
+		}
+		s := space
+		if s.Default {
+			return &s
+		}
+		if first == nil {
+			first = &s
+		}
+	}
+	if first == nil {
+		return builtinSpace(spaceType)
+	}
+	return first
+}
+
+// DefaultITS returns the kubeconfig context of the default ITS
+func DefaultITS() string {
+	return Default(ITS).Context
+}
+
+// DefaultWDS returns the kubeconfig context of the default WDS
+func DefaultWDS() string {
+	return Default(WDS).Context
+}
+
+// Resolve returns the space of a type with the given name, or the default
+// space of the type when name is empty
+func Resolve(spaceType SpaceType, name string) (*Space, error) {
+	if name == "" {
+		return Default(spaceType), nil
+	}
+	space, ok := Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown %s space %q", spaceType, name)
+	}
+	if space.Type != spaceType {
+		return nil, fmt.Errorf("space %q is a %s, not a %s", name, space.Type, spaceType)
+	}
+	return space, nil
+}
+
+// ContextNames returns the kubeconfig contexts of the registered spaces,
+// WDSes first
+func ContextNames() []string {
+	names := []string{}
+	for _, spaceType := range []SpaceType{WDS, ITS} {
+		for _, space := range SpacesOfType(spaceType) {
+			names = append(names, space.Context)
+		}
+	}
+	return names
+}
+
+// Refresh rebuilds the registry, picking up new or removed ControlPlanes
+func Refresh() ([]Space, error) {
+	current.mu.Lock()
+	current.loadedAt = time.Time{}
+	current.mu.Unlock()
+	spaces := load()
+	current.mu.RLock()
+	defer current.mu.RUnlock()
+	return spaces, current.err
+}
+
+// load returns the registered spaces, rebuilding them once they are older
+// than registryTTL or the kubeconfig or configuration changed. A failed
+// rebuild keeps the previous spaces.
+func load() []Space {
+	signature := sourceSignature()
+	fresh := func() bool {
+		return !current.loadedAt.IsZero() && time.Since(current.loadedAt) < registryTTL && current.signature == signature
+	}
+	current.mu.RLock()
+	if fresh() {
+		defer current.mu.RUnlock()
+		return current.spaces
+	}
+	current.mu.RUnlock()
+
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	if fresh() {
+		return current.spaces
+	}
+	spaces, err := build()
+	current.err = err
+	current.loadedAt = time.Now()
+	current.signature = signature
+	if err != nil {
+		log.LogWarn("Failed to build hub registry", zap.Error(err))
+		if len(current.spaces) > 0 {
+			return current.spaces
+		}
+	}
+	current.spaces = spaces
+	return current.spaces
+}
+
+// sourceSignature identifies the configuration the registry is built from,
+// so that switching or editing the kubeconfig, e.g. by kflex, is picked up
+// without waiting for registryTTL
+func sourceSignature() string {
+	signature := []string{}
+	for _, path := range []string{kubeconfigPath(), os.Getenv(spacesFileEnv)} {
+		modified := ""
+		if info, err := os.Stat(path); err == nil {
+			modified = info.ModTime().String()
+		}
+		signature = append(signature, path, modified)
+	}
+	for _, env := range []string{discoveryEnv, discoveryCtxEnv, defaultITSEnv, defaultWDSEnv} {
+		signature = append(signature, os.Getenv(env))
+	}
+	return strings.Join(signature, "\x00")
+}
+
+// build collects the spaces of all sources. A space registered by an earlier
+// source is not overridden by a later one.
+func build() ([]Space, error) {
+	byName := map[string]Space{}
+	add := func(spaces []Space) {
+		for _, space := range spaces {
+			if _, exists := byName[space.Name]; !exists {
+				byName[space.Name] = space
+			}
+		}
+	}
+
+	var errs []string
+	configured, err := loadSpacesFile()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	add(configured)
+
+	kubeconfig, err := loadKubeconfig()
+	if err != nil {
+		log.LogDebug("No kubeconfig to discover hub spaces from", zap.Error(err))
+	}
+	if kubeconfig != nil {
+		if discoveryEnabled() {
+			discovered, err := discoverControlPlanes(kubeconfig)
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			add(discovered)
+		}
+		add(kubeconfigSpaces(kubeconfig))
+	}
+
+	for _, spaceType := range []SpaceType{ITS, WDS} {
+		found := false
+		for _, space := range byName {
+			found = found || space.Type == spaceType
+		}
+		if !found {
+			add([]Space{*builtinSpace(spaceType)})
+		}
+	}
+
+	spaces := make([]Space, 0, len(byName))
+	for _, space := range byName {
+		spaces = append(spaces, space)
+	}
+	sort.Slice(spaces, func(i, j int) bool {
+		if spaces[i].Type != spaces[j].Type {
+			return spaces[i].Type < spaces[j].Type
+		}
+		return spaces[i].Name < spaces[j].Name
+	})
+	markDefaults(spaces)
+
+	if len(errs) > 0 {
+		return spaces, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return spaces, nil
+}
+
+// markDefaults leaves exactly one default per type: the one named by
+// HUB_DEFAULT_ITS or HUB_DEFAULT_WDS, else the configured default, else the
+// built-in its1 or wds1, else the first by name
+func markDefaults(spaces []Space) {
+	for spaceType, env := range map[SpaceType]string{ITS: defaultITSEnv, WDS: defaultWDSEnv} {
+		chosen := -1
+		pick := func(match func(Space) bool) {
+			for i, space := range spaces {
+				if chosen < 0 && space.Type == spaceType && match(space) {
+					chosen = i
+				}
+			}
+		}
+		if name := os.Getenv(env); name != "" {
+			pick(func(s Space) bool { return s.Name == name })
+			if chosen < 0 {
+				log.LogWarn("Configured default space is not registered",
+					zap.String("env", env), zap.String("space", name))
+			}
+		}
+		pick(func(s Space) bool { return s.Default })
+		pick(func(s Space) bool { return s.Name == builtinSpace(spaceType).Name })
+		pick(func(Space) bool { return true })
+		for i := range spaces {
+			if spaces[i].Type == spaceType {
+				spaces[i].Default = i == chosen
+			}
+		}
+	}
+}
+
+// builtinSpace is the space of the KubeStellar getting-started setup, used
+// when no space of a type is registered
+func builtinSpace(spaceType SpaceType) *Space {
+	if spaceType == ITS {
+		return &Space{Name: builtinITS, Type: ITS, Context: builtinITS, APIServer: builtinITSAddress, Source: SourceBuiltin}
+	}
+	return &Space{Name: builtinWDS, Type: WDS, Context: builtinWDS, Source: SourceBuiltin}
+}
+
+func loadSpacesFile() ([]Space, error) {
+	path := os.Getenv(spacesFileEnv)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", spacesFileEnv, err)
+	}
+	var file spacesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", spacesFileEnv, err)
+	}
+
+	spaces := []Space{}
+	for _, space := range file.Spaces {
+		if space.Name == "" || (space.Type != ITS && space.Type != WDS) {
+			log.LogWarn("Ignoring invalid hub space", zap.String("name", space.Name), zap.String("type", string(space.Type)))
+			continue
+		}
+		if space.Context == "" {
+			space.Context = space.Name
+		}
+		space.Source = SourceConfig
+		spaces = append(spaces, space)
+	}
+	return spaces, nil
+}
+
+func discoveryEnabled() bool {
+	value := strings.ToLower(os.Getenv(discoveryEnv))
+	return value != "false" && value != "0"
+}
@@ -0,0 +1,80 @@
+package hub
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WDSCookie is the cookie the UI stores the selected WDS in
+const WDSCookie = "ui-wds-context"
+
+// SelectITS returns the ITS a request works on, taken from the "its" query
+// parameter, or the "context" parameter older clients send, or the default ITS
+func SelectITS(c *gin.Context) (*Space, error) {
+	return SelectITSFromRequest(c.Request)
+}
+
+// SelectWDS returns the WDS a request works on, taken from the "wds" query
+// parameter, the "context" parameter, the UI's WDS cookie or the default WDS
+func SelectWDS(c *gin.Context) (*Space, error) {
+	return SelectWDSFromRequest(c.Request)
+}
+
+// SelectITSFromRequest is SelectITS for handlers outside gin, e.g. websockets
+func SelectITSFromRequest(r *http.Request) (*Space, error) {
+	if name := r.URL.Query().Get("its"); name != "" {
+		return Resolve(ITS, name)
+	}
+	return Resolve(ITS, legacyContext(r, ITS))
+}
+
+// SelectWDSFromRequest is SelectWDS for handlers outside gin, e.g. websockets
+func SelectWDSFromRequest(r *http.Request) (*Space, error) {
+	if name := r.URL.Query().Get("wds"); name != "" {
+		return Resolve(WDS, name)
+	}
+	if name := legacyContext(r, WDS); name != "" {
+		return Resolve(WDS, name)
+	}
+	// A stale cookie is not the caller's explicit choice, so it falls back
+	// to the default WDS instead of failing the request
+	if cookie, err := r.Cookie(WDSCookie); err == nil && cookie.Value != "" {
+		if space, err := Resolve(WDS, cookie.Value); err == nil {
+			return space, nil
+		}
+	}
+	return Default(WDS), nil
+}
+
+// legacyContext returns the "context" query parameter unless it names a space
+// of the other type. Handlers working on both an ITS and a WDS received only
+// one of them in that parameter.
+func legacyContext(r *http.Request, spaceType SpaceType) string {
+	name := r.URL.Query().Get("context")
+	if space, ok := Get(name); ok && space.Type != spaceType {
+		return ""
+	}
+	return name
+}
+
+// MustSelectITS is SelectITS for handlers; on an unknown space it responds
+// with 400 and returns false
+func MustSelectITS(c *gin.Context) (*Space, bool) {
+	return mustSelect(c, SelectITS)
+}
+
+// MustSelectWDS is SelectWDS for handlers; on an unknown space it responds
+// with 400 and returns false
+func MustSelectWDS(c *gin.Context) (*Space, bool) {
+	return mustSelect(c, SelectWDS)
+}
+
+func mustSelect(c *gin.Context, selectSpace func(*gin.Context) (*Space, error)) (*Space, bool) {
+	space, err := selectSpace(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return space, true
+}
@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/log"
+	"go.uber.org/zap"
+)
+
+const defaultRefreshInterval = 5 * time.Minute
+
+// snapshot is the cached inventory of one ITS
+type snapshot struct {
+	items       []ClusterInventory
+	refreshedAt time.Time
+	err         error
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   = map[string]*snapshot{}
+	// collectMu keeps one collection per ITS running at a time
+	collectMu sync.Map
+	initOnce  sync.Once
+)
+
+// RefreshInterval is how long a cached inventory stays fresh, from
+// INVENTORY_REFRESH_SECONDS
+func RefreshInterval() time.Duration {
+	value := os.Getenv("INVENTORY_REFRESH_SECONDS")
+	if value == "" {
+		return defaultRefreshInterval
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		log.LogWarn("Invalid INVENTORY_REFRESH_SECONDS, using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultRefreshInterval))
+		return defaultRefreshInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Init starts refreshing the inventory of every ITS in the background so that
+// requests are served from the cache
+func Init() {
+	initOnce.Do(func() {
+		go refreshLoop(RefreshInterval())
+	})
+}
+
+func refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, space := range hub.SpacesOfType(hub.ITS) {
+			if _, _, err := Refresh(space.Context); err != nil {
+				log.LogWarn("Failed to refresh cluster inventory",
+					zap.String("its", space.Context),
+					zap.Error(err))
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// Get returns the inventory of an ITS and when it was collected. A cached
+// inventory is returned while it is fresh; otherwise it is collected again.
+// If collecting fails the last inventory is returned along with the error.
+func Get(itsContext string) ([]ClusterInventory, time.Time, error) {
+	cacheMu.RLock()
+	cached := cache[itsContext]
+	cacheMu.RUnlock()
+	if cached != nil && cached.err == nil && time.Since(cached.refreshedAt) < RefreshInterval() {
+		return cached.items, cached.refreshedAt, nil
+	}
+	return Refresh(itsContext)
+}
+
+// Refresh collects the inventory of an ITS now. Concurrent refreshes of the
+// same ITS wait for one collection. On failure the previous items are
+// returned and kept.
+func Refresh(itsContext string) ([]ClusterInventory, time.Time, error) {
+	lock, _ := collectMu.LoadOrStore(itsContext, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Another caller may have refreshed while this one waited
+	cacheMu.RLock()
+	cached := cache[itsContext]
+	cacheMu.RUnlock()
+	if cached != nil && cached.err == nil && time.Since(cached.refreshedAt) < time.Second {
+		return cached.items, cached.refreshedAt, nil
+	}
+
+	items, err := Collect(itsContext)
+	refreshed := &snapshot{items: items, refreshedAt: time.Now(), err: err}
+	if err != nil {
+		refreshed.items = []ClusterInventory{}
+		if cached != nil {
+			refreshed.items = cached.items
+			refreshed.refreshedAt = cached.refreshedAt
+		}
+	}
+
+	cacheMu.Lock()
+	cache[itsContext] = refreshed
+	cacheMu.Unlock()
+	return refreshed.items, refreshed.refreshedAt, err
+}
+
+// Invalidate drops the cached inventory of an ITS, e.g. after the labels of
+// one of its clusters changed
+func Invalidate(itsContext string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, itsContext)
+}
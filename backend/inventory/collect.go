@@ -0,0 +1,182 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/vault"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	collectTimeout = 30 * time.Second
+	nodesTimeout   = 5 * time.Second
+	// collectWorkers bounds how many clusters are enriched at the same time
+	collectWorkers = 8
+)
+
+// Build assembles the inventory of a cluster. info is the ManagedClusterInfo
+// of the cluster and nodes are its nodes; either is nil when unknown.
+func Build(its string, cluster, info *unstructured.Unstructured, nodes []corev1.Node) ClusterInventory {
+	inv := FromManagedCluster(its, cluster)
+	if info != nil {
+		ApplyClusterInfo(&inv, info)
+	}
+	if nodes != nil {
+		ApplyNodes(&inv, nodes)
+	}
+	finish(&inv)
+	return inv
+}
+
+// Collect builds the inventory of every managed cluster of an ITS. Nodes are
+// read with the stored credential of the cluster, or through a kubeconfig
+// context of the cluster when there is none, since the hub does not know
+// them.
+func Collect(itsContext string) ([]ClusterInventory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	_, restConfig, err := k8s.GetClientSetWithConfigContext(itsContext)
+	if err != nil {
+		return nil, err
+	}
+	hub, err := ocm.NewClients(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := hub.Dynamic.Resource(ocm.ManagedClusterGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed clusters: %w", err)
+	}
+
+	credentials := storedCredentials()
+	contexts := clusterContexts()
+	items := make([]ClusterInventory, len(clusters.Items))
+	sem := make(chan struct{}, collectWorkers)
+	var wg sync.WaitGroup
+	for i := range clusters.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cluster := &clusters.Items[i]
+			info, err := hub.Dynamic.Resource(ManagedClusterInfoGVR).Namespace(cluster.GetName()).
+				Get(ctx, cluster.GetName(), metav1.GetOptions{})
+			if err != nil {
+				info = nil
+			}
+			var nodes []corev1.Node
+			if config := clusterConfig(cluster.GetName(), credentials, contexts); config != nil {
+				nodes = listNodes(ctx, cluster.GetName(), config)
+			}
+			items[i] = Build(itsContext, cluster, info, nodes)
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// storedCredentials returns the names of the credentials in the credential
+// vault, which are the names of the clusters they connect to
+func storedCredentials() map[string]bool {
+	names := map[string]bool{}
+	if !vault.Enabled() {
+		return names
+	}
+	credentials, err := vault.List()
+	if err != nil {
+		log.LogWarn("Failed to list cluster credentials", zap.Error(err))
+		return names
+	}
+	for _, credential := range credentials {
+		names[credential.Name] = true
+	}
+	return names
+}
+
+// clusterConfig returns the client config of a cluster, built from its
+// stored credential or else from the kubeconfig context that reaches it, or
+// nil if there is neither
+func clusterConfig(clusterName string, credentials map[string]bool, contexts map[string]string) *rest.Config {
+	if credentials[clusterName] {
+		config, err := vault.RESTConfig(clusterName, vault.Access{Purpose: "cluster inventory", Background: true})
+		if err == nil {
+			return config
+		}
+		log.LogWarn("Failed to read cluster credential", zap.String("cluster", clusterName), zap.Error(err))
+	}
+	contextName, ok := contexts[clusterName]
+	if !ok {
+		return nil
+	}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: contextName},
+	).ClientConfig()
+	if err != nil {
+		return nil
+	}
+	return config
+}
+
+// clusterContexts maps cluster names to the kubeconfig contexts that reach
+// them. Imported clusters are merged into the kubeconfig under their own
+// name; kind and k3d prefix theirs.
+func clusterContexts() map[string]string {
+	config, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return map[string]string{}
+	}
+	contexts := map[string]string{}
+	for name := range config.Contexts {
+		contexts[name] = name
+	}
+	for name := range config.Contexts {
+		for _, prefix := range []string{"kind-", "k3d-"} {
+			cluster := strings.TrimPrefix(name, prefix)
+			if cluster == name || cluster == "" {
+				continue
+			}
+			if _, taken := contexts[cluster]; !taken {
+				contexts[cluster] = name
+			}
+		}
+	}
+	return contexts
+}
+
+// listNodes returns the nodes of a cluster, or nil if they cannot be listed
+func listNodes(ctx context.Context, clusterName string, config *rest.Config) []corev1.Node {
+	config.Timeout = nodesTimeout
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nodesTimeout)
+	defer cancel()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.LogDebug("Cannot list nodes of cluster", zap.String("cluster", clusterName), zap.Error(err))
+		return nil
+	}
+	return nodes.Items
+}
@@ -0,0 +1,281 @@
+// Package inventory collects what is known about every managed cluster: its
+// ClusterClaims, Kubernetes version, node count and allocatable resources,
+// and the cloud provider and region inferred from them. The inventory of each
+// ITS is cached and refreshed in the background.
+package inventory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubestellar/ui/backend/its/ocm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Well-known ClusterClaims set by the OCM registration agent
+const (
+	ClaimID          = "id.k8s.io"
+	ClaimKubeVersion = "kubeversion.open-cluster-management.io"
+	ClaimPlatform    = "platform.open-cluster-management.io"
+	ClaimProduct     = "product.open-cluster-management.io"
+	ClaimRegion      = "region.open-cluster-management.io"
+)
+
+// Sources of inventory data, see ClusterInventory.Sources
+const (
+	SourceManagedCluster     = "managedcluster"
+	SourceManagedClusterInfo = "managedclusterinfo"
+	SourceNodes              = "nodes"
+	SourceLabels             = "labels"
+)
+
+// ManagedClusterInfoGVR is the resource some OCM distributions keep node and
+// vendor details of a cluster in, in the cluster namespace on the hub
+var ManagedClusterInfoGVR = schema.GroupVersionResource{
+	Group:    "internal.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "managedclusterinfos",
+}
+
+// Resources are the CPU and memory of a cluster
+type Resources struct {
+	CPU         string `json:"cpu,omitempty"`
+	Memory      string `json:"memory,omitempty"`
+	CPUMillis   int64  `json:"cpuMillis"`
+	MemoryBytes int64  `json:"memoryBytes"`
+}
+
+// ClusterInventory is everything known about one managed cluster
+type ClusterInventory struct {
+	Name              string            `json:"name"`
+	ITS               string            `json:"its"`
+	Labels            map[string]string `json:"labels"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	Available         bool              `json:"available"`
+	Joined            bool              `json:"joined"`
+	KubernetesVersion string            `json:"kubernetesVersion,omitempty"`
+	// NodeCount is nil when the nodes of the cluster cannot be seen
+	NodeCount   *int              `json:"nodeCount,omitempty"`
+	Allocatable Resources         `json:"allocatable"`
+	Capacity    Resources         `json:"capacity"`
+	Provider    string            `json:"provider,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	Claims      map[string]string `json:"claims"`
+	// Sources lists where the data came from
+	Sources     []string  `json:"sources"`
+	CollectedAt time.Time `json:"collectedAt"`
+}
+
+func (inv *ClusterInventory) addSource(source string) {
+	for _, s := range inv.Sources {
+		if s == source {
+			return
+		}
+	}
+	inv.Sources = append(inv.Sources, source)
+}
+
+// resourcesOf reads the cpu and memory quantities of a resource list
+func resourcesOf(values map[string]interface{}) Resources {
+	var r Resources
+	if value, ok := values["cpu"].(string); ok {
+		if quantity, err := resource.ParseQuantity(value); err == nil {
+			r.CPU = value
+			r.CPUMillis = quantity.MilliValue()
+		}
+	}
+	if value, ok := values["memory"].(string); ok {
+		if quantity, err := resource.ParseQuantity(value); err == nil {
+			r.Memory = value
+			r.MemoryBytes = quantity.Value()
+		}
+	}
+	return r
+}
+
+// FromManagedCluster reads the inventory the hub keeps in a ManagedCluster:
+// labels, conditions, version, capacity and ClusterClaims
+func FromManagedCluster(its string, cluster *unstructured.Unstructured) ClusterInventory {
+	inv := ClusterInventory{
+		Name:              cluster.GetName(),
+		ITS:               its,
+		Labels:            cluster.GetLabels(),
+		CreationTimestamp: cluster.GetCreationTimestamp().Time,
+		Claims:            map[string]string{},
+		Sources:           []string{SourceManagedCluster},
+		CollectedAt:       time.Now(),
+	}
+	if inv.Labels == nil {
+		inv.Labels = map[string]string{}
+	}
+	inv.Joined, inv.Available = ocm.JoinedAndAvailable(cluster)
+
+	inv.KubernetesVersion, _, _ = unstructured.NestedString(cluster.Object, "status", "version", "kubernetes")
+	if values, ok, _ := unstructured.NestedMap(cluster.Object, "status", "allocatable"); ok {
+		inv.Allocatable = resourcesOf(values)
+	}
+	if values, ok, _ := unstructured.NestedMap(cluster.Object, "status", "capacity"); ok {
+		inv.Capacity = resourcesOf(values)
+	}
+
+	claims, _, _ := unstructured.NestedSlice(cluster.Object, "status", "clusterClaims")
+	for _, c := range claims {
+		claim, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := claim["name"].(string)
+		value, _ := claim["value"].(string)
+		if name != "" {
+			inv.Claims[name] = value
+		}
+	}
+
+	if inv.KubernetesVersion == "" {
+		inv.KubernetesVersion = inv.Claims[ClaimKubeVersion]
+	}
+	inv.Provider = normalizeProvider(inv.Claims[ClaimPlatform])
+	inv.Region = inv.Claims[ClaimRegion]
+	return inv
+}
+
+// ApplyClusterInfo adds the details of a ManagedClusterInfo
+func ApplyClusterInfo(inv *ClusterInventory, info *unstructured.Unstructured) {
+	inv.addSource(SourceManagedClusterInfo)
+	if nodes, ok, _ := unstructured.NestedSlice(info.Object, "status", "nodeList"); ok {
+		count := len(nodes)
+		inv.NodeCount = &count
+	}
+	if inv.KubernetesVersion == "" {
+		inv.KubernetesVersion, _, _ = unstructured.NestedString(info.Object, "status", "version")
+	}
+	if inv.Provider == "" {
+		vendor, _, _ := unstructured.NestedString(info.Object, "status", "cloudVendor")
+		inv.Provider = normalizeProvider(vendor)
+	}
+}
+
+// ApplyNodes adds what the nodes of a cluster tell: their number, their
+// allocatable resources if the hub does not report them, and the provider and
+// region from their provider IDs and topology labels
+func ApplyNodes(inv *ClusterInventory, nodes []corev1.Node) {
+	inv.addSource(SourceNodes)
+	count := len(nodes)
+	inv.NodeCount = &count
+
+	if inv.Allocatable.CPUMillis == 0 && inv.Allocatable.MemoryBytes == 0 {
+		cpu, memory := resource.Quantity{}, resource.Quantity{}
+		for _, node := range nodes {
+			if q, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+				cpu.Add(q)
+			}
+			if q, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+				memory.Add(q)
+			}
+		}
+		inv.Allocatable = Resources{
+			CPU:         cpu.String(),
+			Memory:      memory.String(),
+			CPUMillis:   cpu.MilliValue(),
+			MemoryBytes: memory.Value(),
+		}
+	}
+
+	for _, node := range nodes {
+		if inv.Provider == "" {
+			inv.Provider = providerFromID(node.Spec.ProviderID)
+		}
+		if inv.Region == "" {
+			inv.Region = regionFromLabels(node.Labels)
+		}
+	}
+}
+
+// finish fills the provider and region from the cluster labels when nothing
+// better is known
+func finish(inv *ClusterInventory) {
+	if inv.Provider == "" {
+		for _, key := range []string{ProviderLabel, "cloud-provider", "provider"} {
+			if value := normalizeProvider(inv.Labels[key]); value != "" {
+				inv.Provider = value
+				inv.addSource(SourceLabels)
+				break
+			}
+		}
+	}
+	if inv.Region == "" {
+		if region := regionFromLabels(inv.Labels); region != "" {
+			inv.Region = region
+			inv.addSource(SourceLabels)
+		}
+	}
+}
+
+// providerAliases maps the names platforms are reported under to the
+// provider names used in the inventory
+var providerAliases = map[string]string{
+	"aws":          "aws",
+	"amazon":       "aws",
+	"eks":          "aws",
+	"gcp":          "gcp",
+	"gce":          "gcp",
+	"google":       "gcp",
+	"gke":          "gcp",
+	"azure":        "azure",
+	"aks":          "azure",
+	"ibm":          "ibm",
+	"ibmcloud":     "ibm",
+	"openstack":    "openstack",
+	"vsphere":      "vsphere",
+	"alibaba":      "alibaba",
+	"alicloud":     "alibaba",
+	"digitalocean": "digitalocean",
+	"kind":         "kind",
+	"k3s":          "k3s",
+	"k3d":          "k3s",
+	"minikube":     "minikube",
+}
+
+func normalizeProvider(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "other" || value == "unknown" {
+		return ""
+	}
+	if provider, ok := providerAliases[value]; ok {
+		return provider
+	}
+	return value
+}
+
+// providerFromID reads the provider from a node provider ID like
+// "aws:///us-east-1a/i-0abc"
+func providerFromID(providerID string) string {
+	scheme, _, found := strings.Cut(providerID, "://")
+	if !found {
+		return ""
+	}
+	return normalizeProvider(scheme)
+}
+
+func regionFromLabels(labels map[string]string) string {
+	for _, key := range []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region", RegionLabel, "region"} {
+		if value := labels[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
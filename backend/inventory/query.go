@@ -0,0 +1,198 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Keys the inventory can be sorted by
+var SortKeys = []string{"name", "version", "nodes", "cpu", "memory", "provider", "region", "created"}
+
+// Query filters an inventory. Empty fields are ignored.
+type Query struct {
+	// Search matches the name, version, provider, region, labels and claims
+	// of a cluster, case-insensitively
+	Search   string
+	Provider string
+	Region   string
+	// Selector matches the labels of a cluster, in label selector syntax
+	Selector  labels.Selector
+	Available *bool
+	SortBy    string
+	Desc      bool
+}
+
+// ParseSelector parses a label selector such as "env=prod,tier in (edge)"
+func ParseSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %v", selector, err)
+	}
+	return parsed, nil
+}
+
+func (q Query) matches(inv ClusterInventory) bool {
+	if q.Provider != "" && !strings.EqualFold(q.Provider, inv.Provider) {
+		return false
+	}
+	if q.Region != "" && !strings.EqualFold(q.Region, inv.Region) {
+		return false
+	}
+	if q.Available != nil && *q.Available != inv.Available {
+		return false
+	}
+	if q.Selector != nil && !q.Selector.Matches(labels.Set(inv.Labels)) {
+		return false
+	}
+	if q.Search == "" {
+		return true
+	}
+
+	search := strings.ToLower(q.Search)
+	fields := []string{inv.Name, inv.KubernetesVersion, inv.Provider, inv.Region}
+	for key, value := range inv.Labels {
+		fields = append(fields, key+"="+value)
+	}
+	for _, value := range inv.Claims {
+		fields = append(fields, value)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the sort key of the query
+func (q Query) Validate() error {
+	_, err := lessFunc(q.SortBy)
+	return err
+}
+
+// Search returns the clusters matching the query in the requested order
+func Search(items []ClusterInventory, q Query) ([]ClusterInventory, error) {
+	less, err := lessFunc(q.SortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ClusterInventory{}
+	for _, inv := range items {
+		if q.matches(inv) {
+			result = append(result, inv)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if q.Desc {
+			return less(result[j], result[i])
+		}
+		return less(result[i], result[j])
+	})
+	return result, nil
+}
+
+func lessFunc(sortBy string) (func(a, b ClusterInventory) bool, error) {
+	byName := func(a, b ClusterInventory) bool { return a.Name < b.Name }
+	then := func(cmp func(a, b ClusterInventory) int) func(a, b ClusterInventory) bool {
+		return func(a, b ClusterInventory) bool {
+			if c := cmp(a, b); c != 0 {
+				return c < 0
+			}
+			return byName(a, b)
+		}
+	}
+
+	switch sortBy {
+	case "", "name":
+		return byName, nil
+	case "version":
+		return then(func(a, b ClusterInventory) int { return compareVersions(a.KubernetesVersion, b.KubernetesVersion) }), nil
+	case "nodes":
+		return then(func(a, b ClusterInventory) int { return compareInts(nodeCount(a), nodeCount(b)) }), nil
+	case "cpu":
+		return then(func(a, b ClusterInventory) int {
+			return compareInts(a.Allocatable.CPUMillis, b.Allocatable.CPUMillis)
+		}), nil
+	case "memory":
+		return then(func(a, b ClusterInventory) int {
+			return compareInts(a.Allocatable.MemoryBytes, b.Allocatable.MemoryBytes)
+		}), nil
+	case "provider":
+		return then(func(a, b ClusterInventory) int { return strings.Compare(a.Provider, b.Provider) }), nil
+	case "region":
+		return then(func(a, b ClusterInventory) int { return strings.Compare(a.Region, b.Region) }), nil
+	case "created":
+		return then(func(a, b ClusterInventory) int { return a.CreationTimestamp.Compare(b.CreationTimestamp) }), nil
+	}
+	return nil, fmt.Errorf("invalid sort key %q, expected one of %v", sortBy, SortKeys)
+}
+
+// nodeCount sorts clusters with unknown nodes before any known count
+func nodeCount(inv ClusterInventory) int64 {
+	if inv.NodeCount == nil {
+		return -1
+	}
+	return int64(*inv.NodeCount)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareVersions compares versions like "v1.30.2+k3s1" by their numeric
+// parts; unknown versions come first
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int64 = -1, -1
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if c := compareInts(x, y); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func versionParts(version string) []int64 {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "+-"); i >= 0 {
+		version = version[:i]
+	}
+	parts := []int64{}
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// MinorVersion returns the major and minor part of a version, like "v1.30"
+func MinorVersion(version string) string {
+	parts := versionParts(version)
+	if len(parts) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("v%d.%d", parts[0], parts[1])
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels the inventory proposes for clusters that do not carry them yet. They
+// use their own prefix, the well-known Kubernetes keys are protected by the
+// label schema.
+const (
+	ProviderLabel = "inventory.kubestellar.io/provider"
+	RegionLabel   = "inventory.kubestellar.io/region"
+	VersionLabel  = "inventory.kubestellar.io/kubernetes-version"
+)
+
+// Sources of a LabelSuggestion
+const (
+	SuggestionLabel    = "label"
+	SuggestionInferred = "inferred"
+)
+
+// LabelSuggestion is a key=value a BindingPolicy cluster selector can use.
+// Suggestions from labels select Clusters today; inferred ones describe
+// Clusters by their inventory and select them once the label is applied.
+type LabelSuggestion struct {
+	Key      string   `json:"key"`
+	Value    string   `json:"value"`
+	Selector string   `json:"selector"`
+	Source   string   `json:"source"`
+	Clusters []string `json:"clusters"`
+	Count    int      `json:"count"`
+	// UsedByBindingPolicies is set when a BindingPolicy already selects on the key
+	UsedByBindingPolicies bool `json:"usedByBindingPolicies"`
+}
+
+// inferredLabels are the labels the inventory of a cluster suggests
+func inferredLabels(inv ClusterInventory) map[string]string {
+	inferred := map[string]string{}
+	if inv.Provider != "" {
+		inferred[ProviderLabel] = inv.Provider
+	}
+	if inv.Region != "" {
+		inferred[RegionLabel] = inv.Region
+	}
+	if version := MinorVersion(inv.KubernetesVersion); version != "" {
+		inferred[VersionLabel] = version
+	}
+	return inferred
+}
+
+// LabelSuggestions lists the labels of the clusters and the labels their
+// inventory suggests, most common first. With a prefix only keys starting
+// with it are returned.
+func LabelSuggestions(items []ClusterInventory, prefix string) []LabelSuggestion {
+	byKey := map[string]*LabelSuggestion{}
+	add := func(source, key, value, cluster string) {
+		if prefix != "" && !strings.HasPrefix(key, prefix) {
+			return
+		}
+		id := source + "\x00" + key + "\x00" + value
+		suggestion, ok := byKey[id]
+		if !ok {
+			suggestion = &LabelSuggestion{Key: key, Value: value, Selector: key + "=" + value, Source: source}
+			byKey[id] = suggestion
+		}
+		suggestion.Clusters = append(suggestion.Clusters, cluster)
+		suggestion.Count++
+	}
+
+	for _, inv := range items {
+		for _, key := range sortedKeys(inv.Labels) {
+			add(SuggestionLabel, key, inv.Labels[key], inv.Name)
+		}
+		inferred := inferredLabels(inv)
+		for _, key := range sortedKeys(inferred) {
+			value := inferred[key]
+			if _, labeled := inv.Labels[key]; labeled || len(validation.IsValidLabelValue(value)) > 0 {
+				continue
+			}
+			add(SuggestionInferred, key, value, inv.Name)
+		}
+	}
+
+	suggestions := make([]LabelSuggestion, 0, len(byKey))
+	for _, suggestion := range byKey {
+		sort.Strings(suggestion.Clusters)
+		suggestions = append(suggestions, *suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Source != b.Source {
+			return a.Source == SuggestionLabel
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Value < b.Value
+	})
+	return suggestions
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os/exec"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/k8s"
 )
 
 // GenerateCommandRequest represents the request payload.
@@ -19,6 +20,7 @@ type GenerateCommandRequest struct {
 // GenerateCommandResponse represents the response payload.
 type GenerateCommandResponse struct {
 	ClusterName   string `json:"clusterName"`
+	ITS           string `json:"its"`
 	Token         string `json:"token"`
 	Command       string `json:"command"`       // This is the join command.
 	AcceptCommand string `json:"acceptCommand"` // This is the accept command.
@@ -32,50 +34,50 @@ func GenerateCommandHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
 		return
 	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
 
 	// Create a context with a timeout to prevent hanging.
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	// Run the command to get the token.
-	cmd := exec.CommandContext(ctx, "clusteradm", "--context", "its1", "get", "token")
-	output, err := cmd.CombinedOutput()
-	outputStr := strings.TrimSpace(string(output))
+	// Request a bootstrap token from the hub.
+	_, hubConfig, err := k8s.GetClientSetWithConfigContext(its.Context)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to get token: %s, output: %s", err.Error(), outputStr),
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the hub: " + err.Error()})
 		return
 	}
-
-	// Look for the line that starts with "token=" and extract the token.
-	var token string
-	lines := strings.Split(outputStr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "token=") {
-			token = strings.TrimSpace(strings.TrimPrefix(line, "token="))
-			break
-		}
+	hubClients, err := ocm.NewClients(hubConfig)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the hub: " + err.Error()})
+		return
 	}
-
-	if token == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token not found in command output"})
+	token, err := ocm.BootstrapToken(ctx, hubClients, 24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get token: %s", err.Error())})
 		return
 	}
 
-	// Build the join command.
+	// Build the join command against the endpoint the ITS is reachable at
+	// from outside the hosting cluster.
+	apiServer := its.APIServer
+	if apiServer == "" {
+		apiServer = hubConfig.Host
+	}
 	joinCommand := fmt.Sprintf(
-		"clusteradm join --hub-token %s --hub-apiserver https://its1.localtest.me:9443 --cluster-name %s --force-internal-endpoint-lookup",
-		token, req.ClusterName,
+		"clusteradm join --hub-token %s --hub-apiserver %s --cluster-name %s --force-internal-endpoint-lookup",
+		token, apiServer, req.ClusterName,
 	)
 
 	// Build the accept command.
-	acceptCommand := fmt.Sprintf("clusteradm accept --context its1 --clusters %s", req.ClusterName)
+	acceptCommand := fmt.Sprintf("clusteradm accept --context %s --clusters %s", its.Context, req.ClusterName)
 
 	// Prepare the response.
 	response := GenerateCommandResponse{
 		ClusterName:   req.ClusterName,
+		ITS:           its.Name,
 		Token:         token,
 		Command:       joinCommand,
 		AcceptCommand: acceptCommand,
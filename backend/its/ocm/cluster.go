@@ -0,0 +1,180 @@
+package ocm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CSRDecided reports whether a CSR was approved or denied
+func CSRDecided(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateApproved || condition.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterCSRs returns the CSRs the registration agent of a cluster created on
+// the hub. An empty clusterName returns those of every cluster.
+func ClusterCSRs(ctx context.Context, hub *Clients, clusterName string) ([]certificatesv1.CertificateSigningRequest, error) {
+	selector := ClusterNameLabel
+	if clusterName != "" {
+		selector += "=" + clusterName
+	}
+	list, err := hub.Kube.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSRs: %w", err)
+	}
+	return list.Items, nil
+}
+
+// PendingCSRs returns the CSRs of a cluster, or of every cluster if
+// clusterName is empty, that were neither approved nor denied
+func PendingCSRs(ctx context.Context, hub *Clients, clusterName string) ([]certificatesv1.CertificateSigningRequest, error) {
+	csrs, err := ClusterCSRs(ctx, hub, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	pending := []certificatesv1.CertificateSigningRequest{}
+	for i := range csrs {
+		if !CSRDecided(&csrs[i]) {
+			pending = append(pending, csrs[i])
+		}
+	}
+	return pending, nil
+}
+
+// GetCSR returns a CSR, or nil if it does not exist
+func GetCSR(ctx context.Context, hub *Clients, name string) (*certificatesv1.CertificateSigningRequest, error) {
+	csr, err := hub.Kube.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CSR %s: %w", name, err)
+	}
+	return csr, nil
+}
+
+// ApproveCSR approves a CSR on the hub
+func ApproveCSR(ctx context.Context, hub *Clients, csr *certificatesv1.CertificateSigningRequest, message string) error {
+	return decideCSR(ctx, hub, csr, certificatesv1.CertificateApproved, "KubestellarUIApprove", message)
+}
+
+// DenyCSR denies a CSR on the hub
+func DenyCSR(ctx context.Context, hub *Clients, csr *certificatesv1.CertificateSigningRequest, message string) error {
+	return decideCSR(ctx, hub, csr, certificatesv1.CertificateDenied, "KubestellarUIDeny", message)
+}
+
+func decideCSR(ctx context.Context, hub *Clients, csr *certificatesv1.CertificateSigningRequest,
+	decision certificatesv1.RequestConditionType, reason, message string) error {
+	decided := csr.DeepCopy()
+	decided.Status.Conditions = append(decided.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           decision,
+		Status:         corev1.ConditionTrue,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+	_, err := hub.Kube.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, decided, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to set %s on CSR %s: %w", decision, csr.Name, err)
+	}
+	return nil
+}
+
+// GetManagedCluster returns a managed cluster, or nil if it does not exist
+func GetManagedCluster(ctx context.Context, hub *Clients, clusterName string) (*unstructured.Unstructured, error) {
+	cluster, err := hub.Dynamic.Resource(ManagedClusterGVR).Get(ctx, clusterName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get managed cluster %s: %w", clusterName, err)
+	}
+	return cluster, nil
+}
+
+// AcceptManagedCluster lets the hub accept a registered cluster
+func AcceptManagedCluster(ctx context.Context, hub *Clients, clusterName string) error {
+	patch := []byte(`{"spec":{"hubAcceptsClient":true}}`)
+	_, err := hub.Dynamic.Resource(ManagedClusterGVR).Patch(ctx, clusterName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to accept managed cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+// SetManagedClusterLabels adds or overwrites labels of a managed cluster
+func SetManagedClusterLabels(ctx context.Context, hub *Clients, clusterName string, labels map[string]string) error {
+	patch := map[string]interface{}{}
+	for key, value := range labels {
+		patch[key] = value
+	}
+	return patchManagedClusterLabels(ctx, hub, clusterName, patch)
+}
+
+// RemoveManagedClusterLabels removes labels from a managed cluster. Keys the
+// cluster does not carry are ignored.
+func RemoveManagedClusterLabels(ctx context.Context, hub *Clients, clusterName string, keys []string) error {
+	patch := map[string]interface{}{}
+	for _, key := range keys {
+		patch[key] = nil
+	}
+	return patchManagedClusterLabels(ctx, hub, clusterName, patch)
+}
+
+func patchManagedClusterLabels(ctx context.Context, hub *Clients, clusterName string, labels map[string]interface{}) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}})
+	if err != nil {
+		return fmt.Errorf("failed to encode label patch: %w", err)
+	}
+	_, err = hub.Dynamic.Resource(ManagedClusterGVR).Patch(ctx, clusterName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update labels of managed cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+// DeleteManagedCluster removes a cluster from the hub. A cluster that does
+// not exist is not an error.
+func DeleteManagedCluster(ctx context.Context, hub *Clients, clusterName string) error {
+	err := hub.Dynamic.Resource(ManagedClusterGVR).Delete(ctx, clusterName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete managed cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+// JoinedAndAvailable reads the Joined and Available conditions of a managed cluster
+func JoinedAndAvailable(cluster *unstructured.Unstructured) (bool, bool) {
+	conditions, _, _ := unstructured.NestedSlice(cluster.Object, "status", "conditions")
+	joined, available := false, false
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["status"] != "True" {
+			continue
+		}
+		switch condition["type"] {
+		case "ManagedClusterJoined":
+			joined = true
+		case "ManagedClusterConditionAvailable":
+			available = true
+		}
+	}
+	return joined, available
+}
@@ -0,0 +1,292 @@
+package ocm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// DefaultImageRegistry hosts the OCM agent images
+	DefaultImageRegistry = "quay.io/open-cluster-management"
+	// DefaultImageTag is the OCM release the agents are deployed from
+	DefaultImageTag = "v0.16.0"
+)
+
+// pollInterval is how often Unjoin checks whether the Klusterlet is gone
+var pollInterval = 2 * time.Second
+
+// JoinOptions describe how a managed cluster registers with the hub
+type JoinOptions struct {
+	ClusterName  string
+	HubAPIServer string
+	HubCA        []byte
+	Token        string
+	// Singleton runs the registration and work agents in a single pod
+	Singleton bool
+	// ImageRegistry and ImageTag default to DefaultImageRegistry and DefaultImageTag
+	ImageRegistry string
+	ImageTag      string
+}
+
+func (o *JoinOptions) image(name string) string {
+	registry, tag := o.ImageRegistry, o.ImageTag
+	if registry == "" {
+		registry = DefaultImageRegistry
+	}
+	if tag == "" {
+		tag = DefaultImageTag
+	}
+	return fmt.Sprintf("%s/%s:%s", registry, name, tag)
+}
+
+// BootstrapKubeconfig returns the kubeconfig the registration agent uses to
+// create its CSR on the hub
+func BootstrapKubeconfig(opts JoinOptions) ([]byte, error) {
+	config := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"hub": {Server: opts.HubAPIServer, CertificateAuthorityData: opts.HubCA},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"bootstrap": {Token: opts.Token},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"bootstrap": {Cluster: "hub", AuthInfo: "bootstrap"},
+		},
+		CurrentContext: "bootstrap",
+	}
+	return clientcmd.Write(config)
+}
+
+// Join deploys the klusterlet operator on a managed cluster and creates the
+// Klusterlet that registers the cluster with the hub. It is idempotent, so a
+// join that was interrupted can simply run again.
+func Join(ctx context.Context, spoke *Clients, opts JoinOptions) error {
+	if opts.ClusterName == "" || opts.HubAPIServer == "" || opts.Token == "" {
+		return fmt.Errorf("cluster name, hub API server and token are required")
+	}
+	bootstrapKubeconfig, err := BootstrapKubeconfig(opts)
+	if err != nil {
+		return fmt.Errorf("failed to build bootstrap kubeconfig: %w", err)
+	}
+
+	if err := ensureNamespace(ctx, spoke, OperatorNamespace); err != nil {
+		return err
+	}
+	if err := createIfMissing(ctx, spoke.Dynamic.Resource(crdGVR), klusterletCRD()); err != nil {
+		return fmt.Errorf("failed to create Klusterlet CRD: %w", err)
+	}
+	if err := deployOperator(ctx, spoke, opts.image("registration-operator")); err != nil {
+		return err
+	}
+
+	if err := ensureNamespace(ctx, spoke, AgentNamespace); err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: BootstrapKubeconfigSecret, Namespace: AgentNamespace},
+		Data:       map[string][]byte{"kubeconfig": bootstrapKubeconfig},
+	}
+	if _, err := spoke.Kube.CoreV1().Secrets(AgentNamespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create bootstrap kubeconfig secret: %w", err)
+		}
+		if _, err := spoke.Kube.CoreV1().Secrets(AgentNamespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update bootstrap kubeconfig secret: %w", err)
+		}
+	}
+
+	if err := apply(ctx, spoke.Dynamic.Resource(KlusterletGVR), klusterlet(opts)); err != nil {
+		return fmt.Errorf("failed to apply Klusterlet: %w", err)
+	}
+	return nil
+}
+
+// Unjoin removes the klusterlet from a managed cluster. The operator removes
+// the agents when the Klusterlet is deleted, so the operator itself is only
+// removed once the Klusterlet is gone.
+func Unjoin(ctx context.Context, spoke *Clients, timeout time.Duration) error {
+	klusterlets := spoke.Dynamic.Resource(KlusterletGVR)
+	if err := klusterlets.Delete(ctx, KlusterletName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Klusterlet: %w", err)
+	}
+	err := wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, err := klusterlets.Get(ctx, KlusterletName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timeout waiting for the Klusterlet to be removed: %w", err)
+	}
+
+	deletions := []func() error{
+		func() error {
+			return spoke.Kube.AppsV1().Deployments(OperatorNamespace).Delete(ctx, KlusterletName, metav1.DeleteOptions{})
+		},
+		func() error {
+			return spoke.Kube.RbacV1().ClusterRoleBindings().Delete(ctx, KlusterletName, metav1.DeleteOptions{})
+		},
+		func() error {
+			return spoke.Kube.CoreV1().Namespaces().Delete(ctx, AgentNamespace, metav1.DeleteOptions{})
+		},
+		func() error {
+			return spoke.Kube.CoreV1().Namespaces().Delete(ctx, OperatorNamespace, metav1.DeleteOptions{})
+		},
+	}
+	for _, deletion := range deletions {
+		if err := deletion(); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove klusterlet operator: %w", err)
+		}
+	}
+	return nil
+}
+
+func ensureNamespace(ctx context.Context, clients *Clients, name string) error {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	_, err := clients.Kube.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// deployOperator installs the klusterlet operator. It creates the RBAC of the
+// agents it deploys, which requires it to hold every permission it grants.
+func deployOperator(ctx context.Context, spoke *Clients, image string) error {
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: KlusterletName, Namespace: OperatorNamespace}}
+	if _, err := spoke.Kube.CoreV1().ServiceAccounts(OperatorNamespace).Create(ctx, serviceAccount, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create klusterlet service account: %w", err)
+	}
+
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: KlusterletName},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: KlusterletName, Namespace: OperatorNamespace}},
+	}
+	if _, err := spoke.Kube.RbacV1().ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create klusterlet cluster role binding: %w", err)
+	}
+
+	labels := map[string]string{"app": "klusterlet"}
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: KlusterletName, Namespace: OperatorNamespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: KlusterletName,
+					Containers: []corev1.Container{{
+						Name:  "klusterlet",
+						Image: image,
+						Args:  []string{"/registration-operator", "operator", "klusterlet"},
+						Env: []corev1.EnvVar{{
+							Name:      "POD_NAME",
+							ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	deployments := spoke.Kube.AppsV1().Deployments(OperatorNamespace)
+	if _, err := deployments.Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create klusterlet operator: %w", err)
+		}
+		if _, err := deployments.Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update klusterlet operator: %w", err)
+		}
+	}
+	return nil
+}
+
+func klusterlet(opts JoinOptions) *unstructured.Unstructured {
+	mode := "Default"
+	if opts.Singleton {
+		mode = "Singleton"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operator.open-cluster-management.io/v1",
+		"kind":       "Klusterlet",
+		"metadata":   map[string]interface{}{"name": KlusterletName},
+		"spec": map[string]interface{}{
+			"clusterName":               opts.ClusterName,
+			"namespace":                 AgentNamespace,
+			"deployOption":              map[string]interface{}{"mode": mode},
+			"registrationImagePullSpec": opts.image("registration"),
+			"workImagePullSpec":         opts.image("work"),
+			"imagePullSpec":             opts.image("registration-operator"),
+		},
+	}}
+}
+
+// klusterletCRD is created when the cluster does not have the Klusterlet CRD
+// yet. Its schema accepts any fields; the operator validates the spec.
+func klusterletCRD() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": KlusterletGVR.Resource + "." + KlusterletGVR.Group},
+		"spec": map[string]interface{}{
+			"group": KlusterletGVR.Group,
+			"scope": "Cluster",
+			"names": map[string]interface{}{
+				"kind":     "Klusterlet",
+				"listKind": "KlusterletList",
+				"plural":   KlusterletGVR.Resource,
+				"singular": "klusterlet",
+			},
+			"versions": []interface{}{map[string]interface{}{
+				"name":         KlusterletGVR.Version,
+				"served":       true,
+				"storage":      true,
+				"subresources": map[string]interface{}{"status": map[string]interface{}{}},
+				"schema": map[string]interface{}{
+					"openAPIV3Schema": map[string]interface{}{
+						"type":                                 "object",
+						"x-kubernetes-preserve-unknown-fields": true,
+					},
+				},
+			}},
+		},
+	}}
+}
+
+func createIfMissing(ctx context.Context, resource dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	_, err := resource.Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// apply creates obj or replaces the spec of the existing object
+func apply(ctx context.Context, resource dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = resource.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	existing.Object["spec"] = obj.Object["spec"]
+	_, err = resource.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,68 @@
+// Package ocm joins, accepts and removes Open Cluster Management managed
+// clusters directly through the Kubernetes API, replacing the clusteradm
+// binary. All operations take Clients so they can run against fake clients.
+package ocm
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	// ManagedClusterGVR is the resource of managed clusters on the hub
+	ManagedClusterGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	// KlusterletGVR is the resource the klusterlet operator on a managed cluster reconciles
+	KlusterletGVR = schema.GroupVersionResource{Group: "operator.open-cluster-management.io", Version: "v1", Resource: "klusterlets"}
+
+	crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+)
+
+const (
+	// HubNamespace holds the cluster-bootstrap service account on the hub
+	HubNamespace = "open-cluster-management"
+	// BootstrapServiceAccount is the service account joining clusters authenticate as
+	BootstrapServiceAccount = "cluster-bootstrap"
+	// OperatorNamespace holds the klusterlet operator on a managed cluster
+	OperatorNamespace = "open-cluster-management"
+	// AgentNamespace holds the registration and work agents on a managed cluster
+	AgentNamespace = "open-cluster-management-agent"
+	// BootstrapKubeconfigSecret is the secret the registration agent uses to reach the hub
+	BootstrapKubeconfigSecret = "bootstrap-hub-kubeconfig"
+	// KlusterletName is the name of the Klusterlet on a managed cluster
+	KlusterletName = "klusterlet"
+	// ClusterNameLabel is set by the registration agent on the CSRs it creates
+	ClusterNameLabel = "open-cluster-management.io/cluster-name"
+)
+
+// Clients are the clients of one cluster, either the hub or a managed cluster
+type Clients struct {
+	Kube    kubernetes.Interface
+	Dynamic dynamic.Interface
+}
+
+// NewClients builds the clients of the cluster config points to
+func NewClients(config *rest.Config) (*Clients, error) {
+	kube, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &Clients{Kube: kube, Dynamic: dyn}, nil
+}
+
+// ClientsFromKubeconfig builds the clients of the current context of a kubeconfig
+func ClientsFromKubeconfig(kubeconfigData []byte) (*Clients, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	return NewClients(config)
+}
@@ -0,0 +1,93 @@
+package ocm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// bootstrapTokenSelector selects the bootstrap tokens created by
+// "clusteradm init --use-bootstrap-token"
+const bootstrapTokenSelector = "app=cluster-manager"
+
+// BootstrapToken returns a token joining clusters register with. Like
+// clusteradm it prefers a bootstrap token of the cluster manager and
+// otherwise requests a token for the cluster-bootstrap service account that
+// expires after expiration.
+func BootstrapToken(ctx context.Context, hub *Clients, expiration time.Duration) (string, error) {
+	secrets, err := hub.Kube.CoreV1().Secrets("kube-system").List(ctx, metav1.ListOptions{LabelSelector: bootstrapTokenSelector})
+	if err != nil {
+		return "", fmt.Errorf("failed to list bootstrap tokens: %w", err)
+	}
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeBootstrapToken {
+			continue
+		}
+		id, tokenSecret := secret.Data["token-id"], secret.Data["token-secret"]
+		if len(id) > 0 && len(tokenSecret) > 0 {
+			return string(id) + "." + string(tokenSecret), nil
+		}
+	}
+
+	seconds := int64(expiration.Seconds())
+	request, err := hub.Kube.CoreV1().ServiceAccounts(HubNamespace).CreateToken(ctx, BootstrapServiceAccount,
+		&authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to request token for %s/%s: %w", HubNamespace, BootstrapServiceAccount, err)
+	}
+	if request.Status.Token == "" {
+		return "", fmt.Errorf("hub returned an empty token for %s/%s", HubNamespace, BootstrapServiceAccount)
+	}
+	return request.Status.Token, nil
+}
+
+// HubEndpoint returns the API server URL and CA bundle klusterlets use to
+// reach the hub. With internal set the endpoint published in the
+// kube-public/cluster-info ConfigMap is preferred, as with clusteradm's
+// --force-internal-endpoint-lookup.
+func HubEndpoint(ctx context.Context, hub *Clients, config *rest.Config, internal bool) (string, []byte, error) {
+	server := config.Host
+	ca := config.CAData
+	if len(ca) == 0 && config.CAFile != "" {
+		data, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read hub CA: %w", err)
+		}
+		ca = data
+	}
+
+	if internal {
+		clusterInfo, err := hub.Kube.CoreV1().ConfigMaps("kube-public").Get(ctx, "cluster-info", metav1.GetOptions{})
+		if err == nil {
+			if published, err := clientcmd.Load([]byte(clusterInfo.Data["kubeconfig"])); err == nil {
+				for _, cluster := range published.Clusters {
+					server = cluster.Server
+					if len(cluster.CertificateAuthorityData) > 0 {
+						ca = cluster.CertificateAuthorityData
+					}
+					break
+				}
+			}
+		}
+	}
+
+	if len(ca) == 0 {
+		rootCA, err := hub.Kube.CoreV1().ConfigMaps("kube-public").Get(ctx, "kube-root-ca.crt", metav1.GetOptions{})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get hub CA: %w", err)
+		}
+		ca = []byte(rootCA.Data["ca.crt"])
+	}
+	if server == "" {
+		return "", nil, fmt.Errorf("hub API server is unknown")
+	}
+	return server, ca, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"go.uber.org/zap"
@@ -21,62 +22,9 @@ func homeDir() string {
 	return os.Getenv("USERPROFILE") // Windows
 }
 
-// GetClientSet retrieves a Kubernetes clientset and dynamic client
+// GetClientSet retrieves a Kubernetes clientset and dynamic client for the default WDS
 func GetClientSet() (*kubernetes.Clientset, dynamic.Interface, error) {
-	log.LogInfo("Getting Kubernetes client set")
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		if home := homeDir(); home != "" {
-			kubeconfig = fmt.Sprintf("%s/.kube/config", home)
-		}
-	}
-
-	// Load the kubeconfig file
-	config, err := clientcmd.LoadFromFile(kubeconfig)
-	if err != nil {
-		log.LogError("Failed to load kubeconfig", zap.Error(err))
-		telemetry.K8sClientErrorCounter.WithLabelValues("GetClientSet", "load_kubeconfig", "500").Inc()
-		return nil, nil, fmt.Errorf("failed to load kubeconfig: %v", err)
-	}
-
-	// Use WDS1 context specifically
-	ctxContext := config.Contexts["wds1"]
-	if ctxContext == nil {
-		log.LogError("Failed to find context 'wds1'")
-		telemetry.K8sClientErrorCounter.WithLabelValues("GetClientSet", "find_context", "500").Inc()
-		return nil, nil, fmt.Errorf("failed to find context 'wds1'")
-	}
-
-	// Create config for WDS cluster
-	clientConfig := clientcmd.NewDefaultClientConfig(
-		*config,
-		&clientcmd.ConfigOverrides{
-			CurrentContext: "wds1",
-		},
-	)
-
-	restConfig, err := clientConfig.ClientConfig()
-	if err != nil {
-		log.LogError("Failed to create restconfig", zap.Error(err))
-		telemetry.K8sClientErrorCounter.WithLabelValues("GetClientSet", "create_restconfig", "500").Inc()
-		return nil, nil, fmt.Errorf("failed to create restconfig: %v", err)
-	}
-
-	clientset, err := kubernetes.NewForConfig(restConfig)
-	if err != nil {
-		log.LogError("Failed to create Kubernetes client", zap.Error(err))
-		telemetry.K8sClientErrorCounter.WithLabelValues("GetClientSet", "create_k8s_client", "500").Inc()
-		return nil, nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
-	}
-	dynamicClient, err := dynamic.NewForConfig(restConfig)
-	if err != nil {
-		log.LogError("Failed to create dynamic client", zap.Error(err))
-		telemetry.K8sClientErrorCounter.WithLabelValues("GetClientSet", "create_dynamic_client", "500").Inc()
-		return nil, nil, fmt.Errorf("failed to create dynamic client: %v", err)
-	}
-
-	log.LogInfo("Successfully created Kubernetes clients")
-	return clientset, dynamicClient, nil
+	return GetClientSetWithContext(hub.DefaultWDS())
 }
 
 // GetClientSetWithContext retrieves a Kubernetes clientset and dynamic client for a specified context
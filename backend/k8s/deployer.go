@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"go.uber.org/zap"
@@ -38,8 +39,6 @@ import (
 const (
 	// KubeStellarNamespace is the default namespace for KubeStellar deployments
 	KubeStellarNamespace = "kubestellar"
-	// DefaultContext is the default Kubernetes context for KubeStellar
-	DefaultContext = "wds1"
 	// GitHubConfigMapName is the ConfigMap name for storing GitHub repository data
 	GitHubConfigMapName = "kubestellar-github"
 	// HelmConfigMapName is the ConfigMap name for storing Helm chart data
@@ -65,6 +64,10 @@ type HelmDeploymentRequest struct {
 	Version       string            `json:"version"`
 	Values        map[string]string `json:"values,omitempty"`
 	ConfigMaps    []ConfigMapRef    `json:"configMaps,omitempty"`
+	// WDSContext is the space the chart is installed to and ITSContext the
+	// space the deployment is recorded in; empty means the default space
+	WDSContext string `json:"-"`
+	ITSContext string `json:"-"`
 }
 
 // HelmDeploymentData represents data about a Helm deployment to be stored
@@ -116,14 +119,15 @@ func getResourceGVR(discoveryClient discovery.DiscoveryInterface, kind string) (
 
 // DeployManifests applies Kubernetes manifests from a directory with optional dry-run mode
 // and adds the specified workload label to all resources
-func DeployManifests(deployPath string, dryRun bool, dryRunStrategy string, workloadLabel string) (*DeploymentTree, error) {
+func DeployManifests(wdsContext, deployPath string, dryRun bool, dryRunStrategy string, workloadLabel string) (*DeploymentTree, error) {
 	log.LogInfo("Starting manifest deployment",
+		zap.String("wds", wdsContext),
 		zap.String("deploy_path", deployPath),
 		zap.Bool("dry_run", dryRun),
 		zap.String("dry_run_strategy", dryRunStrategy),
 		zap.String("workload_label", workloadLabel))
 
-	clientSet, dynamicClient, err := GetClientSet()
+	clientSet, dynamicClient, err := GetClientSetWithContext(wdsContext)
 	if err != nil {
 		log.LogError("Failed to get Kubernetes client", zap.Error(err))
 		return nil, fmt.Errorf("failed to get Kubernetes client: %v", err)
@@ -404,18 +408,18 @@ func PrettyPrint(tree *DeploymentTree) {
 }
 
 // Store Manifests deployment data to a ConfigMap
-func StoreManifestsDeployment(data map[string]string) error {
-	return storeConfigMapData("kubestellar-manifests", data)
+func StoreManifestsDeployment(itsContext string, data map[string]string) error {
+	return storeConfigMapData(itsContext, "kubestellar-manifests", data)
 }
 
 // storeConfigMapData creates or updates a ConfigMap with the provided data
-func storeConfigMapData(configMapName string, data map[string]string) error {
+func storeConfigMapData(itsContext, configMapName string, data map[string]string) error {
 	log.LogInfo("Storing ConfigMap data",
 		zap.String("configmap_name", configMapName),
 		zap.Int("data_entries", len(data)))
 
 	// Ensure namespace exists first
-	clientset, dynamicClient, err := GetClientSetWithContext("its1")
+	clientset, dynamicClient, err := GetClientSetWithContext(itsContext)
 	if err != nil {
 		log.LogError("Failed to get Kubernetes client for ConfigMap storage", zap.Error(err))
 		return fmt.Errorf("failed to get Kubernetes client: %v", err)
@@ -494,8 +498,8 @@ func mustMarshalToString(obj interface{}) string {
 }
 
 // StoreHelmDeployment stores Helm deployment data as a new entry in a multi-deployment ConfigMap
-func StoreHelmDeployment(deploymentData map[string]string) error {
-	clientset, dynamicClient, err := GetClientSetWithContext("its1")
+func StoreHelmDeployment(itsContext string, deploymentData map[string]string) error {
+	clientset, dynamicClient, err := GetClientSetWithContext(itsContext)
 	if err != nil {
 		return fmt.Errorf("failed to get Kubernetes client: %v", err)
 	}
@@ -604,8 +608,8 @@ func StoreHelmDeployment(deploymentData map[string]string) error {
 	})
 }
 
-func StoreGitHubDeployment(deploymentData map[string]string) error {
-	clientset, dynamicClient, err := GetClientSetWithContext("its1")
+func StoreGitHubDeployment(itsContext string, deploymentData map[string]string) error {
+	clientset, dynamicClient, err := GetClientSetWithContext(itsContext)
 	if err != nil {
 		return fmt.Errorf("failed to get Kubernetes client: %v", err)
 	}
@@ -755,6 +759,12 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 	if req.WorkloadLabel == "" {
 		req.WorkloadLabel = req.ChartName
 	}
+	if req.WDSContext == "" {
+		req.WDSContext = hub.DefaultWDS()
+	}
+	if req.ITSContext == "" {
+		req.ITSContext = hub.DefaultITS()
+	}
 
 	log.LogInfo("Starting Helm chart deployment",
 		zap.String("repo_name", req.RepoName),
@@ -764,6 +774,7 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 		zap.String("namespace", req.Namespace),
 		zap.String("version", req.Version),
 		zap.String("workload_label", req.WorkloadLabel),
+		zap.String("wds", req.WDSContext),
 		zap.Bool("store", store))
 
 	// Check current context first to avoid unnecessary switching
@@ -776,7 +787,7 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 	}
 
 	currentContext := strings.TrimSpace(string(output))
-	needsContextSwitch := currentContext != "wds1"
+	needsContextSwitch := currentContext != req.WDSContext
 
 	log.LogDebug("Kubectl context check",
 		zap.String("current_context", currentContext),
@@ -786,16 +797,16 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 	if needsContextSwitch {
 		log.LogInfo("Switching kubectl context",
 			zap.String("from", currentContext),
-			zap.String("to", "wds1"))
+			zap.String("to", req.WDSContext))
 
-		cmd = exec.CommandContext(ctx, "kubectl", "config", "use-context", "wds1")
+		cmd = exec.CommandContext(ctx, "kubectl", "config", "use-context", req.WDSContext)
 		if err := cmd.Run(); err != nil {
-			telemetry.InstrumentKubectlCommand(cmd, "DeployHelmChart", "wds1")
+			telemetry.InstrumentKubectlCommand(cmd, "DeployHelmChart", req.WDSContext)
 			telemetry.K8sClientErrorCounter.WithLabelValues("DeployHelmChart", "switch_context", "500").Inc()
 			log.LogError("Failed to switch kubectl context",
-				zap.String("target_context", "wds1"),
+				zap.String("target_context", req.WDSContext),
 				zap.Error(err))
-			return nil, fmt.Errorf("failed to switch to wds1 context: %v", err)
+			return nil, fmt.Errorf("failed to switch to %s context: %v", req.WDSContext, err)
 		}
 
 		// Ensure the original context is restored after execution
@@ -812,7 +823,7 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 	}
 
 	// Get Kubernetes client to check/create namespace
-	_, dynamicClient, err := GetClientSet()
+	_, dynamicClient, err := GetClientSetWithContext(req.WDSContext)
 	if err != nil {
 		log.LogError("Failed to get Kubernetes client for namespace operations", zap.Error(err))
 		return nil, fmt.Errorf("failed to get Kubernetes client: %v", err)
@@ -1167,7 +1178,7 @@ func DeployHelmChart(req HelmDeploymentRequest, store bool) (*release.Release, e
 		}
 
 		// Store deployment data in ConfigMap
-		err = StoreHelmDeployment(helmDeployData)
+		err = StoreHelmDeployment(req.ITSContext, helmDeployData)
 		if err != nil {
 			telemetry.K8sClientErrorCounter.WithLabelValues("DeployHelmChart", "store_helm_deployment", "500").Inc()
 			log.LogWarn("Failed to store Helm deployment data in ConfigMap",
@@ -1289,6 +1300,16 @@ func HelmDeployHandler(c *gin.Context) {
 		req.WorkloadLabel = req.ChartName
 	}
 
+	wds, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
+	}
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	req.WDSContext, req.ITSContext = wds.Context, its.Context
+
 	// Parse the "store" parameter from the query string
 	storeQuery := c.Query("store")
 	store := false
@@ -1322,7 +1343,11 @@ func HelmDeployHandler(c *gin.Context) {
 }
 
 func ListGithubDeployments(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 
 	log.LogInfo("Listing GitHub deployments", zap.String("context", contextName))
 
@@ -1350,7 +1375,11 @@ func ListGithubDeployments(c *gin.Context) {
 
 // ListHelmDeploymentsHandler handles API requests to list all Helm deployments
 func ListHelmDeploymentsHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 
 	log.LogInfo("Listing Helm deployments", zap.String("context", contextName))
 
@@ -1377,7 +1406,11 @@ func ListHelmDeploymentsHandler(c *gin.Context) {
 }
 
 func ListGithubDeploymentsHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 
 	deployments, err := GetGithubDeployments(contextName)
 	if err != nil {
@@ -1395,7 +1428,11 @@ func ListGithubDeploymentsHandler(c *gin.Context) {
 
 // GetHelmDeploymentHandler handles API requests to get a specific Helm deployment by ID
 func GetHelmDeploymentHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 	deploymentID := c.Param("id")
 
 	if deploymentID == "" {
@@ -1419,7 +1456,11 @@ func GetHelmDeploymentHandler(c *gin.Context) {
 
 // ListHelmDeploymentsByNamespaceHandler handles API requests to list deployments by namespace
 func ListHelmDeploymentsByNamespaceHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 	namespace := c.Param("namespace")
 
 	if namespace == "" {
@@ -1445,7 +1486,11 @@ func ListHelmDeploymentsByNamespaceHandler(c *gin.Context) {
 
 // ListHelmDeploymentsByReleaseHandler handles API requests to list deployments by release name
 func ListHelmDeploymentsByReleaseHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 	releaseName := c.Param("release")
 
 	if releaseName == "" {
@@ -1595,7 +1640,11 @@ func DeleteGitHubDeploymentByID(contextName, deploymentID string) error {
 
 // DeleteHelmDeploymentHandler handles API requests to delete a specific Helm deployment by ID
 func DeleteHelmDeploymentHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 	deploymentID := c.Param("id")
 
 	log.LogInfo("Deleting Helm deployment",
@@ -1637,7 +1686,11 @@ func DeleteHelmDeploymentHandler(c *gin.Context) {
 
 // DeleteGitHubDeploymentHandler handles API requests to delete a specific GitHub deployment by ID
 func DeleteGitHubDeploymentHandler(c *gin.Context) {
-	contextName := c.DefaultQuery("context", "its1")
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	contextName := its.Context
 	deploymentID := c.Param("id")
 
 	if deploymentID == "" {
@@ -18,6 +18,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -221,10 +222,11 @@ func autoLabelling(obj *unstructured.Unstructured, labelName string) {
 
 // CreateResource creates a Kubernetes resource
 func CreateResource(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -250,10 +252,11 @@ func CreateResource(c *gin.Context) {
 
 // GetResource retrieves a resource
 func GetResource(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -309,10 +312,11 @@ func ListClusterResources(c *gin.Context) {
 }
 
 func listResourcesCommon(c *gin.Context, namespace string, namespaceProvided bool) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -398,10 +402,11 @@ func listResourcesCommon(c *gin.Context, namespace string, namespaceProvided boo
 
 // UpdateResource updates an existing Kubernetes resource with retry logic
 func UpdateResource(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -468,10 +473,11 @@ func UpdateResource(c *gin.Context) {
 
 // DeleteResource deletes a resource
 func DeleteResource(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -506,10 +512,11 @@ func DeleteResource(c *gin.Context) {
 }
 
 func UploadYAMLFile(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -549,10 +556,11 @@ var upgrader = websocket.Upgrader{
 }
 
 func LogWorkloads(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 
 	// CRITICAL: Get all parameters and validate BEFORE WebSocket upgrade
 	resourceKind := c.Param("resourceKind")
@@ -1214,10 +1222,11 @@ func LogWorkloads(c *gin.Context) {
 
 // GetResourceKinds returns a list of all available resource kinds in the cluster
 func GetResourceKinds(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, _, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1268,10 +1277,11 @@ func GetResourceKinds(c *gin.Context) {
 
 // GetNamespaces returns a list of all namespaces in the cluster
 func GetNamespaces(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, _, err := GetClientSetWithContext(cookieContext)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
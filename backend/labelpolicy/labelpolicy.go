@@ -0,0 +1,69 @@
+// Package labelpolicy enforces the label schema of managed clusters. Admins
+// configure rules for label keys: keys every cluster must carry, the values a
+// label may take as an enumeration or a regular expression, keys nobody or
+// only their owners may change, and defaults set when a cluster is onboarded.
+// The labels BindingPolicies select clusters by can be protected as well.
+package labelpolicy
+
+import (
+	"strconv"
+
+	"github.com/kubestellar/ui/backend/models"
+)
+
+var enabled bool
+
+// Init reads the label schema from the database from now on. It must be
+// called after the database is initialized; until then the built-in rules
+// apply, so handlers can be exercised in tests without a database.
+func Init() {
+	enabled = true
+}
+
+// DefaultRules are the rules of a new installation: the labels Open Cluster
+// Management and Kubernetes set are protected and onboarded clusters are put
+// in the edge location group
+func DefaultRules() []*models.LabelRule {
+	rules := []*models.LabelRule{}
+	for _, key := range []string{
+		"cluster.open-cluster-management.io/*",
+		"feature.open-cluster-management.io/*",
+		"kubernetes.io/*",
+		"k8s.io/*",
+		"beta.kubernetes.io/*",
+		"topology.kubernetes.io/*",
+		"node-role.kubernetes.io/*",
+		"node.openshift.io/*",
+	} {
+		rules = append(rules, &models.LabelRule{Key: key, Protected: true, AllowedValues: []string{}, Owners: []string{}})
+	}
+	return append(rules, &models.LabelRule{
+		Key:           "location-group",
+		Description:   "Location group of the cluster",
+		Default:       "edge",
+		AllowedValues: []string{},
+		Owners:        []string{},
+	})
+}
+
+// Load returns the current label schema
+func Load() (*Schema, error) {
+	if !enabled {
+		return &Schema{Rules: DefaultRules(), ProtectBindingPolicyLabels: true}, nil
+	}
+	rules, err := models.ListLabelRules()
+	if err != nil {
+		return nil, err
+	}
+	protect, err := models.GetBoolSetting(models.SettingProtectBindingPolicyLabels, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Rules: rules, ProtectBindingPolicyLabels: protect}, nil
+}
+
+// SetProtectBindingPolicyLabels sets whether the labels BindingPolicies
+// select clusters by are protected
+func SetProtectBindingPolicyLabels(protect bool) error {
+	return models.SetSetting(models.SettingProtectBindingPolicyLabels, strconv.FormatBool(protect))
+}
@@ -0,0 +1,280 @@
+package labelpolicy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubestellar/ui/backend/models"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Violation explains why a label does not comply with the schema or why a
+// change of it was refused
+type Violation struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	// Rule is the key pattern of the violated rule, empty if the label is
+	// protected because BindingPolicies use it or is not a valid label
+	Rule   string `json:"rule,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s)", v.Key, v.Reason)
+}
+
+// Editor is the user changing the labels of a cluster
+type Editor struct {
+	Username string
+	Admin    bool
+}
+
+// Schema is the label schema of managed clusters
+type Schema struct {
+	Rules []*models.LabelRule `json:"rules"`
+	// ProtectBindingPolicyLabels protects the label keys BindingPolicies
+	// select clusters by
+	ProtectBindingPolicyLabels bool `json:"protect_binding_policy_labels"`
+}
+
+func isPattern(key string) bool {
+	return strings.ContainsAny(key, `*?[\`)
+}
+
+func keyMatches(pattern, key string) bool {
+	if pattern == key {
+		return true
+	}
+	matched, err := path.Match(pattern, key)
+	return err == nil && matched
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compileValuePattern anchors a value pattern, it must match whole values
+func compileValuePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// RulesFor returns the rules that apply to a label key
+func (s *Schema) RulesFor(key string) []*models.LabelRule {
+	rules := []*models.LabelRule{}
+	for _, rule := range s.Rules {
+		if keyMatches(rule.Key, key) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func valueViolation(rule *models.LabelRule, key, value string) *Violation {
+	if len(rule.AllowedValues) > 0 && !contains(rule.AllowedValues, value) {
+		return &Violation{Key: key, Value: value, Rule: rule.Key,
+			Reason: "value must be one of " + strings.Join(rule.AllowedValues, ", ")}
+	}
+	if rule.ValuePattern != "" {
+		re, err := compileValuePattern(rule.ValuePattern)
+		if err != nil || !re.MatchString(value) {
+			return &Violation{Key: key, Value: value, Rule: rule.Key,
+				Reason: fmt.Sprintf("value must match %s", rule.ValuePattern)}
+		}
+	}
+	return nil
+}
+
+// invalidLabel reports a key or value Kubernetes does not accept
+func invalidLabel(key, value string) *Violation {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return &Violation{Key: key, Value: value, Reason: "invalid label key: " + strings.Join(errs, ", ")}
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return &Violation{Key: key, Value: value, Reason: "invalid label value: " + strings.Join(errs, ", ")}
+	}
+	return nil
+}
+
+// CheckValue returns how a label value violates the rules of its key
+func (s *Schema) CheckValue(key, value string) []Violation {
+	violations := []Violation{}
+	for _, rule := range s.RulesFor(key) {
+		if violation := valueViolation(rule, key, value); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	return violations
+}
+
+// Check returns how the labels of a cluster violate the schema: required
+// keys that are missing and values that are not allowed
+func (s *Schema) Check(labels map[string]string) []Violation {
+	violations := []Violation{}
+	for _, rule := range s.Rules {
+		if _, ok := labels[rule.Key]; rule.Required && !ok {
+			violations = append(violations, Violation{Key: rule.Key, Rule: rule.Key, Reason: "required label is missing"})
+		}
+	}
+	for _, key := range sortedKeys(labels) {
+		violations = append(violations, s.CheckValue(key, labels[key])...)
+	}
+	return violations
+}
+
+// WithDefaults returns the labels with the defaults of the schema added for
+// the keys they do not set
+func (s *Schema) WithDefaults(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		result[key] = value
+	}
+	for _, rule := range s.Rules {
+		if _, ok := result[rule.Key]; rule.Default != "" && !ok {
+			result[rule.Key] = rule.Default
+		}
+	}
+	return result
+}
+
+// CheckOnboarding returns how the labels a cluster is onboarded with violate
+// the schema once the defaults are added. Protected and owner-only keys may
+// be set when a cluster is onboarded.
+func (s *Schema) CheckOnboarding(labels map[string]string) []Violation {
+	violations := []Violation{}
+	for _, key := range sortedKeys(labels) {
+		if violation := invalidLabel(key, labels[key]); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	return append(violations, s.Check(s.WithDefaults(labels))...)
+}
+
+// Plan applies changes to the current labels of a cluster on behalf of
+// editor, an empty value removing a label. Changes the schema refuses are
+// left out of the returned labels and reported as violations.
+// bindingPolicyKeys are the label keys BindingPolicies select clusters by.
+func (s *Schema) Plan(current, changes map[string]string, editor Editor,
+	bindingPolicyKeys map[string]bool) (map[string]string, []Violation) {
+	final := make(map[string]string, len(current))
+	for key, value := range current {
+		final[key] = value
+	}
+
+	violations := []Violation{}
+	for _, key := range sortedKeys(changes) {
+		value := changes[key]
+		currentValue, exists := current[key]
+		if (value == "" && !exists) || (exists && value == currentValue) {
+			continue
+		}
+		if violation := s.changeViolation(key, value, editor, bindingPolicyKeys); violation != nil {
+			violations = append(violations, *violation)
+			continue
+		}
+		if value == "" {
+			delete(final, key)
+		} else {
+			final[key] = value
+		}
+	}
+	return final, violations
+}
+
+// changeViolation returns why a label may not be set to value, or removed if
+// value is empty, or nil if it may
+func (s *Schema) changeViolation(key, value string, editor Editor, bindingPolicyKeys map[string]bool) *Violation {
+	if value != "" {
+		if violation := invalidLabel(key, value); violation != nil {
+			return violation
+		}
+	}
+	if s.ProtectBindingPolicyLabels && bindingPolicyKeys[key] {
+		return &Violation{Key: key, Value: value, Reason: "used by binding policies to select clusters"}
+	}
+	for _, rule := range s.RulesFor(key) {
+		reason := ""
+		switch {
+		case rule.Protected:
+			reason = "protected label"
+		case len(rule.Owners) > 0 && !editor.Admin && !contains(rule.Owners, editor.Username):
+			reason = "only " + strings.Join(rule.Owners, ", ") + " may change this label"
+		case value == "" && rule.Required:
+			reason = "required label cannot be removed"
+		}
+		if reason != "" {
+			return &Violation{Key: key, Value: value, Rule: rule.Key, Reason: reason}
+		}
+		if value != "" {
+			if violation := valueViolation(rule, key, value); violation != nil {
+				return violation
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateRule checks the settings of a label rule before it is stored
+func ValidateRule(rule *models.LabelRule) error {
+	if rule.Key == "" {
+		return errors.New("key is required")
+	}
+	if _, err := path.Match(rule.Key, ""); err != nil {
+		return fmt.Errorf("invalid key pattern %q: %v", rule.Key, err)
+	}
+	if isPattern(rule.Key) {
+		if rule.Required {
+			return errors.New("only exact keys can be required, not key patterns")
+		}
+		if rule.Default != "" {
+			return errors.New("only exact keys can have a default, not key patterns")
+		}
+	} else if errs := validation.IsQualifiedName(rule.Key); len(errs) > 0 {
+		return fmt.Errorf("invalid label key %q: %s", rule.Key, strings.Join(errs, ", "))
+	}
+
+	for _, value := range rule.AllowedValues {
+		if errs := validation.IsValidLabelValue(value); value == "" || len(errs) > 0 {
+			return fmt.Errorf("invalid allowed value %q", value)
+		}
+	}
+	if rule.ValuePattern != "" {
+		if _, err := compileValuePattern(rule.ValuePattern); err != nil {
+			return fmt.Errorf("invalid value_pattern %q: %v", rule.ValuePattern, err)
+		}
+	}
+	for _, owner := range rule.Owners {
+		if strings.TrimSpace(owner) == "" {
+			return errors.New("owners must not be empty")
+		}
+	}
+	if rule.Protected && len(rule.Owners) > 0 {
+		return errors.New("a protected label cannot be changed by anyone, it has no owners")
+	}
+	if rule.Default != "" {
+		if errs := validation.IsValidLabelValue(rule.Default); len(errs) > 0 {
+			return fmt.Errorf("invalid default %q: %s", rule.Default, strings.Join(errs, ", "))
+		}
+		if violation := valueViolation(rule, rule.Key, rule.Default); violation != nil {
+			return fmt.Errorf("default %q is not allowed: %s", rule.Default, violation.Reason)
+		}
+	}
+	return nil
+}
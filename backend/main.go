@@ -15,6 +15,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv" // Add this import
 	"github.com/kubestellar/ui/backend/api"
+	"github.com/kubestellar/ui/backend/audit"
+	"github.com/kubestellar/ui/backend/clusterhealth"
+	"github.com/kubestellar/ui/backend/csrapproval"
+	"github.com/kubestellar/ui/backend/inventory"
+	"github.com/kubestellar/ui/backend/labelpolicy"
 	"github.com/kubestellar/ui/backend/marketplace"
 	"github.com/kubestellar/ui/backend/models"
 	config "github.com/kubestellar/ui/backend/pkg/config"
@@ -24,6 +29,7 @@ import (
 	"github.com/kubestellar/ui/backend/routes"
 	_ "github.com/kubestellar/ui/backend/routes"
 	"github.com/kubestellar/ui/backend/utils"
+	"github.com/kubestellar/ui/backend/vault"
 	"go.uber.org/zap"
 )
 
@@ -73,6 +79,28 @@ func main() {
 		logger.Fatal("Failed to initialize admin user", zap.Error(err))
 	}
 
+	// Start writing the audit log and pruning old entries
+	audit.Init()
+
+	// Load the master key of the cluster credential vault, which onboarding
+	// jobs read their kubeconfig from
+	vault.Init()
+
+	// Enforce the label schema stored in the database
+	labelpolicy.Init()
+
+	// Resume or roll back cluster onboarding interrupted by a restart
+	api.StartOnboardingJobs()
+
+	// Decide the CSRs of joining clusters with the approval rules
+	csrapproval.Init()
+
+	// Record the health history of managed clusters and raise alerts
+	clusterhealth.Init()
+
+	// Keep the inventory of managed clusters fresh in the background
+	inventory.Init()
+
 	// Debug: Check if admin user exists
 	logger.Info("Checking admin user in database...")
 	if err := debugCheckAdminUser(); err != nil {
@@ -85,6 +113,9 @@ func main() {
 	// Add Zap middleware first
 	router.Use(ZapMiddleware())
 
+	// Record user actions in the audit log
+	router.Use(audit.Middleware())
+
 	// Setup metrics routes
 	api.SetupMetricsRoutes(router, logger)
 
@@ -168,7 +199,7 @@ func main() {
 			zap.String("port", cfg.Port),
 			zap.String("mode", cfg.GinMode),
 			zap.String("cors_origin", os.Getenv("CORS_ALLOWED_ORIGIN")))
-		logger.Info("Default admin credentials: admin/admin - CHANGE IMMEDIATELY!")
+		logger.Info("Default admin credentials: admin/admin - a new password is required on first login")
 		logger.Info("Health endpoints available:")
 		logger.Info("  - Comprehensive health: http://localhost:" + cfg.Port + "/health")
 		logger.Info("  - Kubernetes liveness: http://localhost:" + cfg.Port + "/healthz")
@@ -333,12 +364,12 @@ func homeDir() string {
 // initializeAdminUser creates default admin user if no users exist
 func initializeAdminUser() error {
 	// First check if admin user specifically exists
-	adminQuery := "SELECT id, username, is_admin FROM users WHERE username = $1"
+	adminQuery := "SELECT id, username, password, is_admin FROM users WHERE username = $1"
 	var adminID int
-	var adminUsername string
+	var adminUsername, adminPassword string
 	var isAdmin bool
 
-	err := database.DB.QueryRow(adminQuery, "admin").Scan(&adminID, &adminUsername, &isAdmin)
+	err := database.DB.QueryRow(adminQuery, "admin").Scan(&adminID, &adminUsername, &adminPassword, &isAdmin)
 	if err == nil {
 		// Admin user exists
 		logger.Info("Admin user already exists",
@@ -346,6 +377,14 @@ func initializeAdminUser() error {
 			zap.String("username", adminUsername),
 			zap.Bool("is_admin", isAdmin))
 
+		// Installations from before forced rotation may still use admin/admin
+		if models.CheckPasswordHash("admin", adminPassword) {
+			logger.Warn("Admin user still has the default password, requiring a change on next login")
+			if err := models.SetMustChangePassword(adminID, true); err != nil {
+				return fmt.Errorf("failed to require admin password change: %v", err)
+			}
+		}
+
 		// Verify admin has proper permissions
 		return ensureAdminPermissions(adminID)
 	}
@@ -371,10 +410,10 @@ func initializeAdminUser() error {
 		return fmt.Errorf("failed to hash password: %v", err)
 	}
 
-	// Insert admin user
+	// Insert admin user, which must pick a new password on first login
 	insertQuery := `
-		INSERT INTO users (username, password, is_admin) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO users (username, password, is_admin, must_change_password)
+		VALUES ($1, $2, $3, TRUE)
 		RETURNING id`
 
 	var userID int
@@ -391,7 +430,7 @@ func initializeAdminUser() error {
 		return err
 	}
 
-	log.Printf("Default admin user created successfully with username: admin, password: admin")
+	log.Printf("Default admin user created successfully with username: admin, password: admin (must be changed on first login)")
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kubestellar/ui/backend/auth"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/models"
 	"go.uber.org/zap"
@@ -74,7 +75,7 @@ func NewMarketplaceManager(store StorageProvider) (*MarketplaceManager, error) {
 			return nil, fmt.Errorf("failed to get plugin details: %w", err)
 		}
 		// get author name
-		author, err := models.GetUserByID(pluginDetails.AuthorID)
+		author, err := auth.Users.GetUserByID(pluginDetails.AuthorID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get author: %w", err)
 		}
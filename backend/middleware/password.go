@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+// passwordChangeAllowedRoutes stay reachable while a password change is pending,
+// so the user can see who they are and pick a new password
+var passwordChangeAllowedRoutes = map[string]bool{
+	"/api/me":                 true,
+	"/api/me/password":        true,
+	"/api/me/password/policy": true,
+}
+
+// RequireCurrentPassword blocks users whose password has expired or who were
+// told to change it, except for the routes needed to change it. It must run
+// after AuthenticateMiddleware.
+func RequireCurrentPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if passwordChangeAllowedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		id, ok := userID.(int)
+		if !exists || !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		status, err := models.GetPasswordStatus(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password status"})
+			c.Abort()
+			return
+		}
+
+		if required, reason := utils.GetPasswordPolicy().ChangeRequired(status.MustChange, status.ChangedAt, time.Now()); required {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":  "Password change required",
+				"code":   "PASSWORD_CHANGE_REQUIRED",
+				"reason": reason,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Possible values of AuditEntry.Result
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+	AuditResultStarted = "started"
+)
+
+// AuditEntry is a single recorded user action
+type AuditEntry struct {
+	ID            int64     `json:"id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	UserID        *int      `json:"user_id,omitempty"`
+	Username      string    `json:"username"`
+	Action        string    `json:"action"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	TargetContext string    `json:"target_context"`
+	Resource      string    `json:"resource"`
+	PayloadHash   string    `json:"payload_hash"`
+	StatusCode    int       `json:"status_code"`
+	Result        string    `json:"result"`
+	ClientIP      string    `json:"client_ip"`
+	DurationMs    int64     `json:"duration_ms"`
+	Error         string    `json:"error"`
+}
+
+// AuditFilter restricts which entries ListAuditEntries returns. Empty fields
+// are ignored.
+type AuditFilter struct {
+	Username      string
+	Action        string
+	Result        string
+	TargetContext string
+	Resource      string
+	From          *time.Time
+	To            *time.Time
+	Limit         int
+	Offset        int
+}
+
+// InsertAuditEntry stores an audit entry
+func InsertAuditEntry(entry *AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (occurred_at, user_id, username, action, method, path, target_context,
+			resource, payload_hash, status_code, result, client_ip, duration_ms, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`
+
+	var userID sql.NullInt64
+	if entry.UserID != nil {
+		userID = sql.NullInt64{Int64: int64(*entry.UserID), Valid: true}
+	}
+
+	err := database.DB.QueryRow(query, entry.OccurredAt, userID, entry.Username, entry.Action, entry.Method,
+		entry.Path, entry.TargetContext, entry.Resource, entry.PayloadHash, entry.StatusCode, entry.Result,
+		entry.ClientIP, entry.DurationMs, entry.Error).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %v", err)
+	}
+	return nil
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		clauses = append(clauses, fmt.Sprintf(clause, len(args)))
+	}
+
+	if f.Username != "" {
+		add("username = $%d", f.Username)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Result != "" {
+		add("result = $%d", f.Result)
+	}
+	if f.TargetContext != "" {
+		add("target_context = $%d", f.TargetContext)
+	}
+	if f.Resource != "" {
+		add("resource ILIKE '%%' || $%d || '%%'", f.Resource)
+	}
+	if f.From != nil {
+		add("occurred_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("occurred_at <= $%d", *f.To)
+	}
+
+	if len(clauses) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// ListAuditEntries returns the entries matching the filter, newest first,
+// together with the total number of matching entries
+func ListAuditEntries(filter AuditFilter) ([]AuditEntry, int, error) {
+	where, args := filter.where()
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %v", err)
+	}
+
+	query := `SELECT id, occurred_at, user_id, username, action, method, path, target_context, resource,
+		payload_hash, status_code, result, client_ip, duration_ms, error FROM audit_log` + where +
+		" ORDER BY occurred_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var userID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.OccurredAt, &userID, &entry.Username, &entry.Action, &entry.Method,
+			&entry.Path, &entry.TargetContext, &entry.Resource, &entry.PayloadHash, &entry.StatusCode,
+			&entry.Result, &entry.ClientIP, &entry.DurationMs, &entry.Error); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			entry.UserID = &id
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %v", err)
+	}
+
+	return entries, total, nil
+}
+
+// DeleteAuditEntriesBefore removes entries older than cutoff and returns how many were removed
+func DeleteAuditEntriesBefore(cutoff time.Time) (int64, error) {
+	result, err := database.DB.Exec("DELETE FROM audit_log WHERE occurred_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete audit entries: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Possible values of BPRevision.Action
+const (
+	BPRevisionCreate   = "create"
+	BPRevisionUpdate   = "update"
+	BPRevisionRollback = "rollback"
+	BPRevisionDelete   = "delete"
+)
+
+// BPRevisionExternalAuthor is the author of changes seen by the policy
+// watcher but not made through the API, such as kubectl edits
+const BPRevisionExternalAuthor = "external"
+
+// BPRevision is a snapshot of a BindingPolicy after one change
+type BPRevision struct {
+	ID             int64           `json:"id"`
+	WDSContext     string          `json:"wds_context"`
+	PolicyName     string          `json:"policy_name"`
+	Revision       int             `json:"revision"`
+	Action         string          `json:"action"`
+	Author         string          `json:"author"`
+	RolledBackFrom *int            `json:"rolled_back_from,omitempty"`
+	Snapshot       json.RawMessage `json:"snapshot,omitempty"`
+	SnapshotHash   string          `json:"snapshot_hash"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// BPRevisionChange describes a change to record
+type BPRevisionChange struct {
+	WDSContext string
+	PolicyName string
+	Author     string
+	// InitialAuthor replaces an external author on the first revision of a
+	// policy, which for policies older than their history is the only
+	// record of who created them
+	InitialAuthor string
+	Snapshot      json.RawMessage
+	SnapshotHash  string
+	// Deleted records the removal of the policy
+	Deleted bool
+	// RollbackOf is the revision the policy was rolled back to, if any
+	RollbackOf *int
+}
+
+// RecordBPRevision stores change as the next revision of the policy and
+// returns it. Changes are recorded both by the API handlers and by the
+// policy watcher, so a change whose snapshot matches the latest revision is
+// not recorded again and nil is returned.
+func RecordBPRevision(change *BPRevisionChange) (*BPRevision, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize writers of the same policy so revision numbers stay dense
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", change.WDSContext+"/"+change.PolicyName); err != nil {
+		return nil, fmt.Errorf("failed to lock policy revisions: %v", err)
+	}
+
+	var latestRevision int
+	var latestAction, latestHash, latestAuthor string
+	err = tx.QueryRow(`
+		SELECT revision, action, snapshot_hash, author FROM bp_revisions
+		WHERE wds_context = $1 AND policy_name = $2
+		ORDER BY revision DESC LIMIT 1`,
+		change.WDSContext, change.PolicyName).Scan(&latestRevision, &latestAction, &latestHash, &latestAuthor)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get latest revision: %v", err)
+	}
+	exists := err == nil
+
+	action := BPRevisionUpdate
+	switch {
+	case change.Deleted:
+		if !exists || latestAction == BPRevisionDelete {
+			return nil, nil
+		}
+		action = BPRevisionDelete
+	case exists && latestAction != BPRevisionDelete && latestHash == change.SnapshotHash:
+		// The watcher can see an API change before its handler records it,
+		// in which case the handler knows the actual author
+		if latestAuthor == BPRevisionExternalAuthor && change.Author != "" && change.Author != BPRevisionExternalAuthor {
+			if _, err := tx.Exec(`
+				UPDATE bp_revisions SET author = $1
+				WHERE wds_context = $2 AND policy_name = $3 AND revision = $4`,
+				change.Author, change.WDSContext, change.PolicyName, latestRevision); err != nil {
+				return nil, fmt.Errorf("failed to update revision author: %v", err)
+			}
+			return nil, tx.Commit()
+		}
+		return nil, nil
+	case change.RollbackOf != nil:
+		action = BPRevisionRollback
+	case !exists || latestAction == BPRevisionDelete:
+		action = BPRevisionCreate
+	}
+
+	author := change.Author
+	if !exists && change.InitialAuthor != "" {
+		author = change.InitialAuthor
+	}
+	revision := &BPRevision{
+		WDSContext:     change.WDSContext,
+		PolicyName:     change.PolicyName,
+		Revision:       latestRevision + 1,
+		Action:         action,
+		Author:         author,
+		Snapshot:       change.Snapshot,
+		SnapshotHash:   change.SnapshotHash,
+		RolledBackFrom: change.RollbackOf,
+	}
+	if action != BPRevisionRollback {
+		revision.RolledBackFrom = nil
+	}
+
+	var rolledBackFrom sql.NullInt64
+	if revision.RolledBackFrom != nil {
+		rolledBackFrom = sql.NullInt64{Int64: int64(*revision.RolledBackFrom), Valid: true}
+	}
+	err = tx.QueryRow(`
+		INSERT INTO bp_revisions (wds_context, policy_name, revision, action, author, rolled_back_from, snapshot, snapshot_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		revision.WDSContext, revision.PolicyName, revision.Revision, revision.Action, revision.Author,
+		rolledBackFrom, []byte(revision.Snapshot), revision.SnapshotHash).Scan(&revision.ID, &revision.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert revision: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ListBPRevisions returns the revisions of a policy, newest first, without
+// their snapshots
+func ListBPRevisions(wdsContext, policyName string) ([]*BPRevision, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, wds_context, policy_name, revision, action, author, rolled_back_from, snapshot_hash, created_at
+		FROM bp_revisions
+		WHERE wds_context = $1 AND policy_name = $2
+		ORDER BY revision DESC`, wdsContext, policyName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []*BPRevision{}
+	for rows.Next() {
+		revision := &BPRevision{}
+		var rolledBackFrom sql.NullInt64
+		if err := rows.Scan(&revision.ID, &revision.WDSContext, &revision.PolicyName, &revision.Revision,
+			&revision.Action, &revision.Author, &rolledBackFrom, &revision.SnapshotHash, &revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %v", err)
+		}
+		if rolledBackFrom.Valid {
+			from := int(rolledBackFrom.Int64)
+			revision.RolledBackFrom = &from
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetBPRevision returns one revision with its snapshot, or nil if it does not exist
+func GetBPRevision(wdsContext, policyName string, revisionNumber int) (*BPRevision, error) {
+	revision := &BPRevision{}
+	var rolledBackFrom sql.NullInt64
+	var snapshot []byte
+	err := database.DB.QueryRow(`
+		SELECT id, wds_context, policy_name, revision, action, author, rolled_back_from, snapshot, snapshot_hash, created_at
+		FROM bp_revisions
+		WHERE wds_context = $1 AND policy_name = $2 AND revision = $3`,
+		wdsContext, policyName, revisionNumber).Scan(&revision.ID, &revision.WDSContext, &revision.PolicyName,
+		&revision.Revision, &revision.Action, &revision.Author, &rolledBackFrom, &snapshot,
+		&revision.SnapshotHash, &revision.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %v", err)
+	}
+
+	revision.Snapshot = snapshot
+	if rolledBackFrom.Valid {
+		from := int(rolledBackFrom.Int64)
+		revision.RolledBackFrom = &from
+	}
+	return revision, nil
+}
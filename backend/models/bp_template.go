@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// BPTemplate is one version of a BindingPolicy template. Versions are
+// immutable: changing a template stores a new version.
+type BPTemplate struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Version     int             `json:"version"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+	Body        string          `json:"body"`
+	CreatedBy   string          `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+const bpTemplateColumns = "id, name, version, description, parameters, body, created_by, created_at"
+
+func scanBPTemplate(scanner interface{ Scan(...interface{}) error }) (*BPTemplate, error) {
+	template := &BPTemplate{}
+	var parameters []byte
+	if err := scanner.Scan(&template.ID, &template.Name, &template.Version, &template.Description,
+		&parameters, &template.Body, &template.CreatedBy, &template.CreatedAt); err != nil {
+		return nil, err
+	}
+	template.Parameters = parameters
+	return template, nil
+}
+
+// CreateBPTemplateVersion stores template as the next version of the template
+// with its name and returns the stored version
+func CreateBPTemplateVersion(template *BPTemplate) (*BPTemplate, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize writers of the same template so versions stay dense
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", "bp_template/"+template.Name); err != nil {
+		return nil, fmt.Errorf("failed to lock template: %v", err)
+	}
+
+	var latest int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM bp_templates WHERE name = $1", template.Name).Scan(&latest); err != nil {
+		return nil, fmt.Errorf("failed to get latest template version: %v", err)
+	}
+
+	parameters := []byte(template.Parameters)
+	if len(parameters) == 0 {
+		parameters = []byte("[]")
+	}
+	stored, err := scanBPTemplate(tx.QueryRow(`
+		INSERT INTO bp_templates (name, version, description, parameters, body, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+bpTemplateColumns,
+		template.Name, latest+1, template.Description, parameters, template.Body, template.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert template: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListBPTemplates returns the latest version of every template, by name
+func ListBPTemplates() ([]*BPTemplate, error) {
+	return queryBPTemplates(`
+		SELECT DISTINCT ON (name) ` + bpTemplateColumns + `
+		FROM bp_templates
+		ORDER BY name, version DESC`)
+}
+
+// ListBPTemplateVersions returns every version of a template, newest first
+func ListBPTemplateVersions(name string) ([]*BPTemplate, error) {
+	return queryBPTemplates(`
+		SELECT `+bpTemplateColumns+`
+		FROM bp_templates
+		WHERE name = $1
+		ORDER BY version DESC`, name)
+}
+
+func queryBPTemplates(query string, args ...interface{}) ([]*BPTemplate, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %v", err)
+	}
+	defer rows.Close()
+
+	templates := []*BPTemplate{}
+	for rows.Next() {
+		template, err := scanBPTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %v", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// GetBPTemplate returns a version of a template, the latest one when version
+// is 0, or nil if it does not exist
+func GetBPTemplate(name string, version int) (*BPTemplate, error) {
+	var row *sql.Row
+	if version > 0 {
+		row = database.DB.QueryRow(`
+			SELECT `+bpTemplateColumns+` FROM bp_templates
+			WHERE name = $1 AND version = $2`, name, version)
+	} else {
+		row = database.DB.QueryRow(`
+			SELECT `+bpTemplateColumns+` FROM bp_templates
+			WHERE name = $1
+			ORDER BY version DESC LIMIT 1`, name)
+	}
+
+	template, err := scanBPTemplate(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %v", err)
+	}
+	return template, nil
+}
+
+// DeleteBPTemplate removes every version of a template and reports whether
+// it existed
+func DeleteBPTemplate(name string) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM bp_templates WHERE name = $1", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete template: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
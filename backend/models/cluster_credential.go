@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Actions recorded in the access log of a credential
+const (
+	CredentialStored  = "store"
+	CredentialRead    = "read"
+	CredentialRotated = "rotate"
+	CredentialDeleted = "delete"
+)
+
+// ClusterCredential is an encrypted kubeconfig of a cluster. Only the
+// metadata is ever returned to clients.
+type ClusterCredential struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Server      string `json:"server"`
+	ContextName string `json:"context_name"`
+	// KeyID identifies the master key the data key is wrapped with
+	KeyID      string     `json:"key_id"`
+	WrappedKey []byte     `json:"-"`
+	Ciphertext []byte     `json:"-"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CredentialAccess is one use of a credential
+type CredentialAccess struct {
+	ID int64 `json:"id"`
+	// CredentialID is nil once the credential was deleted
+	CredentialID   *int64    `json:"credential_id,omitempty"`
+	CredentialName string    `json:"credential_name"`
+	Action         string    `json:"action"`
+	Purpose        string    `json:"purpose"`
+	Username       string    `json:"username"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+const clusterCredentialColumns = `id, name, server, context_name, key_id, wrapped_key, ciphertext, created_by,
+	created_at, updated_at, rotated_at, last_used_at`
+
+func scanClusterCredential(scanner interface{ Scan(...interface{}) error }) (*ClusterCredential, error) {
+	credential := &ClusterCredential{}
+	var rotatedAt, lastUsedAt sql.NullTime
+	if err := scanner.Scan(&credential.ID, &credential.Name, &credential.Server, &credential.ContextName,
+		&credential.KeyID, &credential.WrappedKey, &credential.Ciphertext, &credential.CreatedBy,
+		&credential.CreatedAt, &credential.UpdatedAt, &rotatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if rotatedAt.Valid {
+		credential.RotatedAt = &rotatedAt.Time
+	}
+	if lastUsedAt.Valid {
+		credential.LastUsedAt = &lastUsedAt.Time
+	}
+	return credential, nil
+}
+
+// SaveClusterCredential stores a credential, replacing the one of the same name
+func SaveClusterCredential(credential *ClusterCredential) (*ClusterCredential, error) {
+	stored, err := scanClusterCredential(database.DB.QueryRow(`
+		INSERT INTO cluster_credentials (name, server, context_name, key_id, wrapped_key, ciphertext, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (name) DO UPDATE SET server = EXCLUDED.server, context_name = EXCLUDED.context_name,
+			key_id = EXCLUDED.key_id, wrapped_key = EXCLUDED.wrapped_key, ciphertext = EXCLUDED.ciphertext,
+			created_by = EXCLUDED.created_by, updated_at = NOW(), rotated_at = NULL
+		RETURNING `+clusterCredentialColumns,
+		credential.Name, credential.Server, credential.ContextName, credential.KeyID, credential.WrappedKey,
+		credential.Ciphertext, credential.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save credential: %v", err)
+	}
+	return stored, nil
+}
+
+// GetClusterCredential returns a credential, or nil if it does not exist
+func GetClusterCredential(name string) (*ClusterCredential, error) {
+	credential, err := scanClusterCredential(database.DB.QueryRow(
+		"SELECT "+clusterCredentialColumns+" FROM cluster_credentials WHERE name = $1", name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %v", err)
+	}
+	return credential, nil
+}
+
+// ListClusterCredentials returns all credentials ordered by name
+func ListClusterCredentials() ([]*ClusterCredential, error) {
+	return queryClusterCredentials("SELECT " + clusterCredentialColumns + " FROM cluster_credentials ORDER BY name")
+}
+
+// ListClusterCredentialsNotWrappedWith returns the credentials whose data key
+// is wrapped with another master key than keyID
+func ListClusterCredentialsNotWrappedWith(keyID string) ([]*ClusterCredential, error) {
+	return queryClusterCredentials(
+		"SELECT "+clusterCredentialColumns+" FROM cluster_credentials WHERE key_id <> $1 ORDER BY id", keyID)
+}
+
+func queryClusterCredentials(query string, args ...interface{}) ([]*ClusterCredential, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %v", err)
+	}
+	defer rows.Close()
+
+	credentials := []*ClusterCredential{}
+	for rows.Next() {
+		credential, err := scanClusterCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credential: %v", err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// RewrapClusterCredential replaces the wrapped data key of a credential. It
+// returns false if the credential changed since it was read.
+func RewrapClusterCredential(id int64, oldKeyID, keyID string, wrappedKey []byte) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE cluster_credentials SET key_id = $3, wrapped_key = $4, rotated_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND key_id = $2`, id, oldKeyID, keyID, wrappedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate credential: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
+
+// TouchClusterCredential records that a credential was used
+func TouchClusterCredential(id int64) error {
+	_, err := database.DB.Exec("UPDATE cluster_credentials SET last_used_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to update credential: %v", err)
+	}
+	return nil
+}
+
+// DeleteClusterCredential removes a credential and reports whether it existed
+func DeleteClusterCredential(name string) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM cluster_credentials WHERE name = $1", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete credential: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// InsertCredentialAccess appends an entry to the access log of a credential
+func InsertCredentialAccess(access *CredentialAccess) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO cluster_credential_access (credential_id, credential_name, action, purpose, username)
+		VALUES ($1, $2, $3, $4, $5)`,
+		access.CredentialID, access.CredentialName, access.Action, access.Purpose, access.Username)
+	if err != nil {
+		return fmt.Errorf("failed to record credential access: %v", err)
+	}
+	return nil
+}
+
+// DeleteCredentialAccessBefore removes access log entries older than cutoff
+func DeleteCredentialAccessBefore(cutoff time.Time) (int64, error) {
+	result, err := database.DB.Exec("DELETE FROM cluster_credential_access WHERE occurred_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete credential access entries: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListCredentialAccess returns the access log of a credential, newest first
+func ListCredentialAccess(name string, limit int) ([]CredentialAccess, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, credential_id, credential_name, action, purpose, username, occurred_at
+		FROM cluster_credential_access WHERE credential_name = $1
+		ORDER BY occurred_at DESC, id DESC LIMIT $2`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credential access: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []CredentialAccess{}
+	for rows.Next() {
+		var entry CredentialAccess
+		var credentialID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &credentialID, &entry.CredentialName, &entry.Action, &entry.Purpose,
+			&entry.Username, &entry.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan credential access: %v", err)
+		}
+		if credentialID.Valid {
+			entry.CredentialID = &credentialID.Int64
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
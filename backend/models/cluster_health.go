@@ -0,0 +1,389 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Conditions a ClusterAlertRule can watch
+const (
+	AlertUnavailable    = "unavailable"
+	AlertNotJoined      = "not_joined"
+	AlertHeartbeatStale = "heartbeat_stale"
+)
+
+// AlertConditions are the valid values of ClusterAlertRule.Condition
+var AlertConditions = []string{AlertUnavailable, AlertNotJoined, AlertHeartbeatStale}
+
+// ClusterHealthSample is the health of a managed cluster at one point in time
+type ClusterHealthSample struct {
+	ID             int64  `json:"id"`
+	ITSContext     string `json:"its_context"`
+	ClusterName    string `json:"cluster_name"`
+	Available      bool   `json:"available"`
+	Joined         bool   `json:"joined"`
+	HeartbeatStale bool   `json:"heartbeat_stale"`
+	// HeartbeatAgeSeconds is nil when the cluster has no lease on the hub
+	HeartbeatAgeSeconds *int64 `json:"heartbeat_age_seconds,omitempty"`
+	// Transition is set when the sample changed Available, Joined or
+	// HeartbeatStale compared to the sample before
+	Transition bool      `json:"transition"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// ClusterAlertRule raises an alert when Condition holds for a cluster for at
+// least DurationSeconds
+type ClusterAlertRule struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Condition       string `json:"condition"`
+	DurationSeconds int    `json:"duration_seconds"`
+	// ClusterPattern is a glob matched against cluster names, empty matches all
+	ClusterPattern string `json:"cluster_pattern"`
+	// ITSContext restricts the rule to one ITS, empty matches all
+	ITSContext string `json:"its_context"`
+	// Notifiers are the names of the notifiers alerts are sent to, empty
+	// sends them to every registered notifier
+	Notifiers []string  `json:"notifiers"`
+	Enabled   bool      `json:"enabled"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClusterAlert is an alert raised by a rule for one cluster
+type ClusterAlert struct {
+	ID          int64      `json:"id"`
+	RuleID      *int64     `json:"rule_id,omitempty"`
+	RuleName    string     `json:"rule_name"`
+	Condition   string     `json:"condition"`
+	ITSContext  string     `json:"its_context"`
+	ClusterName string     `json:"cluster_name"`
+	Message     string     `json:"message"`
+	Since       time.Time  `json:"since"`
+	FiredAt     time.Time  `json:"fired_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ClusterAlertFilter restricts which alerts ListClusterAlerts returns. Empty
+// fields are ignored.
+type ClusterAlertFilter struct {
+	ClusterName string
+	OpenOnly    bool
+	Limit       int
+}
+
+// ValidAlertCondition reports whether condition is one of AlertConditions
+func ValidAlertCondition(condition string) bool {
+	for _, c := range AlertConditions {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
+
+const clusterHealthSampleColumns = `id, its_context, cluster_name, available, joined, heartbeat_stale,
+	heartbeat_age_seconds, transition, observed_at`
+
+func scanClusterHealthSample(scanner interface{ Scan(...interface{}) error }) (*ClusterHealthSample, error) {
+	sample := &ClusterHealthSample{}
+	var age sql.NullInt64
+	if err := scanner.Scan(&sample.ID, &sample.ITSContext, &sample.ClusterName, &sample.Available, &sample.Joined,
+		&sample.HeartbeatStale, &age, &sample.Transition, &sample.ObservedAt); err != nil {
+		return nil, err
+	}
+	if age.Valid {
+		sample.HeartbeatAgeSeconds = &age.Int64
+	}
+	return sample, nil
+}
+
+// InsertClusterHealthSample stores a health sample
+func InsertClusterHealthSample(sample *ClusterHealthSample) error {
+	err := database.DB.QueryRow(`
+		INSERT INTO cluster_health_samples (its_context, cluster_name, available, joined, heartbeat_stale,
+			heartbeat_age_seconds, transition, observed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		sample.ITSContext, sample.ClusterName, sample.Available, sample.Joined, sample.HeartbeatStale,
+		sample.HeartbeatAgeSeconds, sample.Transition, sample.ObservedAt).Scan(&sample.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert cluster health sample: %v", err)
+	}
+	return nil
+}
+
+// LatestClusterHealthSamples returns the newest sample of every cluster
+func LatestClusterHealthSamples() ([]*ClusterHealthSample, error) {
+	return queryClusterHealthSamples(`
+		SELECT DISTINCT ON (its_context, cluster_name) ` + clusterHealthSampleColumns + `
+		FROM cluster_health_samples
+		ORDER BY its_context, cluster_name, observed_at DESC, id DESC`)
+}
+
+// ClusterHealthSampleBefore returns the newest sample of a cluster taken
+// before t, or nil if there is none
+func ClusterHealthSampleBefore(itsContext, clusterName string, t time.Time) (*ClusterHealthSample, error) {
+	sample, err := scanClusterHealthSample(database.DB.QueryRow(`
+		SELECT `+clusterHealthSampleColumns+` FROM cluster_health_samples
+		WHERE its_context = $1 AND cluster_name = $2 AND observed_at < $3
+		ORDER BY observed_at DESC, id DESC LIMIT 1`,
+		itsContext, clusterName, t))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster health sample: %v", err)
+	}
+	return sample, nil
+}
+
+// ListClusterHealthSamples returns the samples of a cluster taken between
+// from and to, oldest first. With transitionsOnly only state changes are
+// returned.
+func ListClusterHealthSamples(itsContext, clusterName string, from, to time.Time, transitionsOnly bool) ([]*ClusterHealthSample, error) {
+	query := `
+		SELECT ` + clusterHealthSampleColumns + ` FROM cluster_health_samples
+		WHERE its_context = $1 AND cluster_name = $2 AND observed_at >= $3 AND observed_at <= $4`
+	if transitionsOnly {
+		query += " AND transition"
+	}
+	query += " ORDER BY observed_at, id"
+	return queryClusterHealthSamples(query, itsContext, clusterName, from, to)
+}
+
+// DeleteClusterHealthSamplesBefore removes samples taken before t
+func DeleteClusterHealthSamplesBefore(t time.Time) (int64, error) {
+	result, err := database.DB.Exec("DELETE FROM cluster_health_samples WHERE observed_at < $1", t)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune cluster health samples: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+func queryClusterHealthSamples(query string, args ...interface{}) ([]*ClusterHealthSample, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster health samples: %v", err)
+	}
+	defer rows.Close()
+
+	samples := []*ClusterHealthSample{}
+	for rows.Next() {
+		sample, err := scanClusterHealthSample(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cluster health sample: %v", err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+const clusterAlertRuleColumns = `id, name, condition, duration_seconds, cluster_pattern, its_context, notifiers,
+	enabled, created_by, created_at, updated_at`
+
+func scanClusterAlertRule(scanner interface{ Scan(...interface{}) error }) (*ClusterAlertRule, error) {
+	rule := &ClusterAlertRule{}
+	var notifiers []byte
+	if err := scanner.Scan(&rule.ID, &rule.Name, &rule.Condition, &rule.DurationSeconds, &rule.ClusterPattern,
+		&rule.ITSContext, &notifiers, &rule.Enabled, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(notifiers, &rule.Notifiers); err != nil {
+		return nil, fmt.Errorf("invalid notifiers of alert rule %d: %v", rule.ID, err)
+	}
+	if rule.Notifiers == nil {
+		rule.Notifiers = []string{}
+	}
+	return rule, nil
+}
+
+func notifiersJSON(notifiers []string) ([]byte, error) {
+	if notifiers == nil {
+		notifiers = []string{}
+	}
+	return json.Marshal(notifiers)
+}
+
+// ListClusterAlertRules returns every alert rule ordered by name
+func ListClusterAlertRules() ([]*ClusterAlertRule, error) {
+	rows, err := database.DB.Query("SELECT " + clusterAlertRuleColumns + " FROM cluster_alert_rules ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []*ClusterAlertRule{}
+	for rows.Next() {
+		rule, err := scanClusterAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetClusterAlertRule returns a rule, or nil if it does not exist
+func GetClusterAlertRule(id int64) (*ClusterAlertRule, error) {
+	rule, err := scanClusterAlertRule(database.DB.QueryRow(
+		"SELECT "+clusterAlertRuleColumns+" FROM cluster_alert_rules WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %v", err)
+	}
+	return rule, nil
+}
+
+// CreateClusterAlertRule stores a new rule
+func CreateClusterAlertRule(rule *ClusterAlertRule) (*ClusterAlertRule, error) {
+	notifiers, err := notifiersJSON(rule.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanClusterAlertRule(database.DB.QueryRow(`
+		INSERT INTO cluster_alert_rules (name, condition, duration_seconds, cluster_pattern, its_context, notifiers,
+			enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+clusterAlertRuleColumns,
+		rule.Name, rule.Condition, rule.DurationSeconds, rule.ClusterPattern, rule.ITSContext, notifiers,
+		rule.Enabled, rule.CreatedBy))
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("alert rule %s already exists", rule.Name)
+		}
+		return nil, fmt.Errorf("failed to create alert rule: %v", err)
+	}
+	return stored, nil
+}
+
+// UpdateClusterAlertRule replaces the settings of a rule and returns it, or
+// nil if it does not exist
+func UpdateClusterAlertRule(rule *ClusterAlertRule) (*ClusterAlertRule, error) {
+	notifiers, err := notifiersJSON(rule.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanClusterAlertRule(database.DB.QueryRow(`
+		UPDATE cluster_alert_rules
+		SET name = $2, condition = $3, duration_seconds = $4, cluster_pattern = $5, its_context = $6,
+			notifiers = $7, enabled = $8, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+clusterAlertRuleColumns,
+		rule.ID, rule.Name, rule.Condition, rule.DurationSeconds, rule.ClusterPattern, rule.ITSContext, notifiers,
+		rule.Enabled))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("alert rule %s already exists", rule.Name)
+		}
+		return nil, fmt.Errorf("failed to update alert rule: %v", err)
+	}
+	return stored, nil
+}
+
+// DeleteClusterAlertRule removes a rule and reports whether it existed. The
+// alerts it raised are kept.
+func DeleteClusterAlertRule(id int64) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM cluster_alert_rules WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alert rule: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+const clusterAlertColumns = `id, rule_id, rule_name, condition, its_context, cluster_name, message, since,
+	fired_at, resolved_at`
+
+func scanClusterAlert(scanner interface{ Scan(...interface{}) error }) (*ClusterAlert, error) {
+	alert := &ClusterAlert{}
+	var ruleID sql.NullInt64
+	var resolvedAt sql.NullTime
+	if err := scanner.Scan(&alert.ID, &ruleID, &alert.RuleName, &alert.Condition, &alert.ITSContext,
+		&alert.ClusterName, &alert.Message, &alert.Since, &alert.FiredAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	if ruleID.Valid {
+		alert.RuleID = &ruleID.Int64
+	}
+	if resolvedAt.Valid {
+		alert.ResolvedAt = &resolvedAt.Time
+	}
+	return alert, nil
+}
+
+// InsertClusterAlert stores a newly raised alert
+func InsertClusterAlert(alert *ClusterAlert) error {
+	err := database.DB.QueryRow(`
+		INSERT INTO cluster_alerts (rule_id, rule_name, condition, its_context, cluster_name, message, since, fired_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		alert.RuleID, alert.RuleName, alert.Condition, alert.ITSContext, alert.ClusterName, alert.Message,
+		alert.Since, alert.FiredAt).Scan(&alert.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert cluster alert: %v", err)
+	}
+	return nil
+}
+
+// ResolveClusterAlert marks an open alert resolved at t
+func ResolveClusterAlert(id int64, t time.Time) error {
+	if _, err := database.DB.Exec(
+		"UPDATE cluster_alerts SET resolved_at = $2 WHERE id = $1 AND resolved_at IS NULL", id, t); err != nil {
+		return fmt.Errorf("failed to resolve cluster alert: %v", err)
+	}
+	return nil
+}
+
+// ListClusterAlerts returns the alerts matching the filter, newest first
+func ListClusterAlerts(filter ClusterAlertFilter) ([]*ClusterAlert, error) {
+	var clauses []string
+	var args []interface{}
+	if filter.ClusterName != "" {
+		args = append(args, filter.ClusterName)
+		clauses = append(clauses, fmt.Sprintf("cluster_name = $%d", len(args)))
+	}
+	if filter.OpenOnly {
+		clauses = append(clauses, "resolved_at IS NULL")
+	}
+
+	query := "SELECT " + clusterAlertColumns + " FROM cluster_alerts"
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY fired_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster alerts: %v", err)
+	}
+	defer rows.Close()
+
+	alerts := []*ClusterAlert{}
+	for rows.Next() {
+		alert, err := scanClusterAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cluster alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
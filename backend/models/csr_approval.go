@@ -0,0 +1,368 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Actions of a CSRApprovalRule
+const (
+	CSRActionApprove = "approve"
+	CSRActionDeny    = "deny"
+)
+
+// Decisions recorded for a CSR
+const (
+	CSRApproved = "approved"
+	CSRDenied   = "denied"
+	CSRPending  = "pending"
+)
+
+// How a CSR decision was made
+const (
+	CSRDecisionAuto   = "auto"
+	CSRDecisionManual = "manual"
+)
+
+// CSRApprovalRule approves or denies the CSRs of joining clusters that match
+// all of its conditions. Empty conditions match every CSR.
+type CSRApprovalRule struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Priority orders the rules, the matching rule with the lowest one decides
+	Priority int `json:"priority"`
+	// ClusterPattern is a glob matched against cluster names
+	ClusterPattern string `json:"cluster_pattern"`
+	// ITSContext restricts the rule to one ITS
+	ITSContext string `json:"its_context"`
+	// Identities are globs matched against the username and the groups of
+	// the requester of the CSR
+	Identities []string `json:"identities"`
+	// WindowStart and WindowEnd are HH:MM times in Timezone between which the
+	// rule applies, on WindowDays (mon, tue, ...) if any. A window whose end
+	// is before its start spans midnight.
+	WindowStart string   `json:"window_start"`
+	WindowEnd   string   `json:"window_end"`
+	WindowDays  []string `json:"window_days"`
+	Timezone    string   `json:"timezone"`
+	// RequireAllowlist only matches clusters on the pre-registration allowlist
+	RequireAllowlist bool      `json:"require_allowlist"`
+	Enabled          bool      `json:"enabled"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// CSRAllowlistEntry pre-registers a cluster to join
+type CSRAllowlistEntry struct {
+	ClusterName string `json:"cluster_name"`
+	// ITSContext restricts the entry to one ITS, empty allows every ITS
+	ITSContext string     `json:"its_context"`
+	Note       string     `json:"note"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CSRDecision records a decision on a CSR and its reason
+type CSRDecision struct {
+	ID              int64    `json:"id"`
+	ITSContext      string   `json:"its_context"`
+	CSRName         string   `json:"csr_name"`
+	ClusterName     string   `json:"cluster_name"`
+	Requester       string   `json:"requester"`
+	RequesterGroups []string `json:"requester_groups"`
+	Decision        string   `json:"decision"`
+	Mode            string   `json:"mode"`
+	// RuleID is nil for manual decisions and once the rule was deleted
+	RuleID    *int64    `json:"rule_id,omitempty"`
+	RuleName  string    `json:"rule_name"`
+	Reason    string    `json:"reason"`
+	DecidedBy string    `json:"decided_by"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// CSRDecisionFilter restricts which decisions ListCSRDecisions returns.
+// Empty fields are ignored.
+type CSRDecisionFilter struct {
+	ClusterName string
+	Decision    string
+	Limit       int
+}
+
+func stringsJSON(values []string) ([]byte, error) {
+	if values == nil {
+		values = []string{}
+	}
+	return json.Marshal(values)
+}
+
+func parseStringsJSON(data []byte) ([]string, error) {
+	values := []string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []string{}
+	}
+	return values, nil
+}
+
+const csrApprovalRuleColumns = `id, name, action, priority, cluster_pattern, its_context, identities, window_start,
+	window_end, window_days, timezone, require_allowlist, enabled, created_by, created_at, updated_at`
+
+func scanCSRApprovalRule(scanner interface{ Scan(...interface{}) error }) (*CSRApprovalRule, error) {
+	rule := &CSRApprovalRule{}
+	var identities, days []byte
+	if err := scanner.Scan(&rule.ID, &rule.Name, &rule.Action, &rule.Priority, &rule.ClusterPattern,
+		&rule.ITSContext, &identities, &rule.WindowStart, &rule.WindowEnd, &days, &rule.Timezone,
+		&rule.RequireAllowlist, &rule.Enabled, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if rule.Identities, err = parseStringsJSON(identities); err != nil {
+		return nil, fmt.Errorf("invalid identities of CSR approval rule %d: %v", rule.ID, err)
+	}
+	if rule.WindowDays, err = parseStringsJSON(days); err != nil {
+		return nil, fmt.Errorf("invalid window days of CSR approval rule %d: %v", rule.ID, err)
+	}
+	return rule, nil
+}
+
+// ListCSRApprovalRules returns every rule in the order they are evaluated
+func ListCSRApprovalRules() ([]*CSRApprovalRule, error) {
+	rows, err := database.DB.Query("SELECT " + csrApprovalRuleColumns + " FROM csr_approval_rules ORDER BY priority, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSR approval rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []*CSRApprovalRule{}
+	for rows.Next() {
+		rule, err := scanCSRApprovalRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan CSR approval rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateCSRApprovalRule stores a new rule
+func CreateCSRApprovalRule(rule *CSRApprovalRule) (*CSRApprovalRule, error) {
+	identities, err := stringsJSON(rule.Identities)
+	if err != nil {
+		return nil, err
+	}
+	days, err := stringsJSON(rule.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanCSRApprovalRule(database.DB.QueryRow(`
+		INSERT INTO csr_approval_rules (name, action, priority, cluster_pattern, its_context, identities,
+			window_start, window_end, window_days, timezone, require_allowlist, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING `+csrApprovalRuleColumns,
+		rule.Name, rule.Action, rule.Priority, rule.ClusterPattern, rule.ITSContext, identities, rule.WindowStart,
+		rule.WindowEnd, days, rule.Timezone, rule.RequireAllowlist, rule.Enabled, rule.CreatedBy))
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("CSR approval rule %s already exists", rule.Name)
+		}
+		return nil, fmt.Errorf("failed to create CSR approval rule: %v", err)
+	}
+	return stored, nil
+}
+
+// UpdateCSRApprovalRule replaces the settings of a rule and returns it, or
+// nil if it does not exist
+func UpdateCSRApprovalRule(rule *CSRApprovalRule) (*CSRApprovalRule, error) {
+	identities, err := stringsJSON(rule.Identities)
+	if err != nil {
+		return nil, err
+	}
+	days, err := stringsJSON(rule.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanCSRApprovalRule(database.DB.QueryRow(`
+		UPDATE csr_approval_rules
+		SET name = $2, action = $3, priority = $4, cluster_pattern = $5, its_context = $6, identities = $7,
+			window_start = $8, window_end = $9, window_days = $10, timezone = $11, require_allowlist = $12,
+			enabled = $13, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+csrApprovalRuleColumns,
+		rule.ID, rule.Name, rule.Action, rule.Priority, rule.ClusterPattern, rule.ITSContext, identities,
+		rule.WindowStart, rule.WindowEnd, days, rule.Timezone, rule.RequireAllowlist, rule.Enabled))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("CSR approval rule %s already exists", rule.Name)
+		}
+		return nil, fmt.Errorf("failed to update CSR approval rule: %v", err)
+	}
+	return stored, nil
+}
+
+// DeleteCSRApprovalRule removes a rule and reports whether it existed. The
+// decisions it made are kept.
+func DeleteCSRApprovalRule(id int64) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM csr_approval_rules WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete CSR approval rule: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// ListCSRAllowlist returns the entries of the allowlist that did not expire
+func ListCSRAllowlist() ([]*CSRAllowlistEntry, error) {
+	rows, err := database.DB.Query(`
+		SELECT cluster_name, its_context, note, expires_at, created_by, created_at FROM csr_allowlist
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY cluster_name, its_context`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSR allowlist: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []*CSRAllowlistEntry{}
+	for rows.Next() {
+		entry := &CSRAllowlistEntry{}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&entry.ClusterName, &entry.ITSContext, &entry.Note, &expiresAt, &entry.CreatedBy,
+			&entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan CSR allowlist entry: %v", err)
+		}
+		if expiresAt.Valid {
+			entry.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveCSRAllowlistEntry adds a cluster to the allowlist, replacing its entry
+// for the same ITS
+func SaveCSRAllowlistEntry(entry *CSRAllowlistEntry) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO csr_allowlist (cluster_name, its_context, note, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cluster_name, its_context) DO UPDATE SET note = EXCLUDED.note,
+			expires_at = EXCLUDED.expires_at, created_by = EXCLUDED.created_by, created_at = NOW()`,
+		entry.ClusterName, entry.ITSContext, entry.Note, entry.ExpiresAt, entry.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save CSR allowlist entry: %v", err)
+	}
+	return nil
+}
+
+// DeleteCSRAllowlistEntries removes a cluster from the allowlist for every
+// ITS and reports whether it was on it
+func DeleteCSRAllowlistEntries(clusterName string) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM csr_allowlist WHERE cluster_name = $1", clusterName)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete CSR allowlist entry: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+const csrDecisionColumns = `id, its_context, csr_name, cluster_name, requester, requester_groups, decision, mode,
+	rule_id, rule_name, reason, decided_by, decided_at`
+
+func scanCSRDecision(scanner interface{ Scan(...interface{}) error }) (*CSRDecision, error) {
+	decision := &CSRDecision{}
+	var groups []byte
+	var ruleID sql.NullInt64
+	if err := scanner.Scan(&decision.ID, &decision.ITSContext, &decision.CSRName, &decision.ClusterName,
+		&decision.Requester, &groups, &decision.Decision, &decision.Mode, &ruleID, &decision.RuleName,
+		&decision.Reason, &decision.DecidedBy, &decision.DecidedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if decision.RequesterGroups, err = parseStringsJSON(groups); err != nil {
+		return nil, fmt.Errorf("invalid groups of CSR decision %d: %v", decision.ID, err)
+	}
+	if ruleID.Valid {
+		decision.RuleID = &ruleID.Int64
+	}
+	return decision, nil
+}
+
+// InsertCSRDecision records a decision. A pending decision is only recorded
+// the first time a CSR is queued; it reports whether the decision was stored.
+func InsertCSRDecision(decision *CSRDecision) (bool, error) {
+	groups, err := stringsJSON(decision.RequesterGroups)
+	if err != nil {
+		return false, err
+	}
+	result, err := database.DB.Exec(`
+		INSERT INTO csr_decisions (its_context, csr_name, cluster_name, requester, requester_groups, decision, mode,
+			rule_id, rule_name, reason, decided_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (its_context, csr_name) WHERE decision = 'pending' DO NOTHING`,
+		decision.ITSContext, decision.CSRName, decision.ClusterName, decision.Requester, groups, decision.Decision,
+		decision.Mode, decision.RuleID, decision.RuleName, decision.Reason, decision.DecidedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to record CSR decision: %v", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, nil
+}
+
+// ListCSRDecisions returns recorded decisions, newest first
+func ListCSRDecisions(filter CSRDecisionFilter) ([]*CSRDecision, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.ClusterName != "" {
+		args = append(args, filter.ClusterName)
+		conditions = append(conditions, fmt.Sprintf("cluster_name = $%d", len(args)))
+	}
+	if filter.Decision != "" {
+		args = append(args, filter.Decision)
+		conditions = append(conditions, fmt.Sprintf("decision = $%d", len(args)))
+	}
+	query := "SELECT " + csrDecisionColumns + " FROM csr_decisions"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY decided_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSR decisions: %v", err)
+	}
+	defer rows.Close()
+
+	decisions := []*CSRDecision{}
+	for rows.Next() {
+		decision, err := scanCSRDecision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan CSR decision: %v", err)
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, rows.Err()
+}
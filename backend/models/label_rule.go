@@ -0,0 +1,146 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// LabelRule governs the cluster labels whose keys match its key pattern.
+// Empty settings do not restrict the labels.
+type LabelRule struct {
+	ID int64 `json:"id"`
+	// Key is a label key, or a glob matched against label keys
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	// Required keys must be set on every cluster. Only exact keys can be
+	// required.
+	Required bool `json:"required"`
+	// AllowedValues enumerates the values the label may take
+	AllowedValues []string `json:"allowed_values"`
+	// ValuePattern is a regular expression the whole value must match
+	ValuePattern string `json:"value_pattern"`
+	// Protected labels cannot be changed once a cluster is onboarded
+	Protected bool `json:"protected"`
+	// Owners are the only users, besides admins, who may change the label
+	Owners []string `json:"owners"`
+	// Default is set at onboarding on clusters without the label. Only exact
+	// keys can have a default.
+	Default   string    `json:"default"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const labelRuleColumns = `id, key_pattern, description, required, allowed_values, value_pattern, protected, owners,
+	default_value, created_by, created_at, updated_at`
+
+func scanLabelRule(scanner interface{ Scan(...interface{}) error }) (*LabelRule, error) {
+	rule := &LabelRule{}
+	var allowedValues, owners []byte
+	if err := scanner.Scan(&rule.ID, &rule.Key, &rule.Description, &rule.Required, &allowedValues,
+		&rule.ValuePattern, &rule.Protected, &owners, &rule.Default, &rule.CreatedBy, &rule.CreatedAt,
+		&rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if rule.AllowedValues, err = parseStringsJSON(allowedValues); err != nil {
+		return nil, fmt.Errorf("invalid allowed values of label rule %d: %v", rule.ID, err)
+	}
+	if rule.Owners, err = parseStringsJSON(owners); err != nil {
+		return nil, fmt.Errorf("invalid owners of label rule %d: %v", rule.ID, err)
+	}
+	return rule, nil
+}
+
+// ListLabelRules returns every label rule ordered by key
+func ListLabelRules() ([]*LabelRule, error) {
+	rows, err := database.DB.Query("SELECT " + labelRuleColumns + " FROM label_rules ORDER BY key_pattern")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list label rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []*LabelRule{}
+	for rows.Next() {
+		rule, err := scanLabelRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan label rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateLabelRule stores a new label rule
+func CreateLabelRule(rule *LabelRule) (*LabelRule, error) {
+	allowedValues, err := stringsJSON(rule.AllowedValues)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := stringsJSON(rule.Owners)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanLabelRule(database.DB.QueryRow(`
+		INSERT INTO label_rules (key_pattern, description, required, allowed_values, value_pattern, protected,
+			owners, default_value, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+labelRuleColumns,
+		rule.Key, rule.Description, rule.Required, allowedValues, rule.ValuePattern, rule.Protected, owners,
+		rule.Default, rule.CreatedBy))
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("a label rule for %s already exists", rule.Key)
+		}
+		return nil, fmt.Errorf("failed to create label rule: %v", err)
+	}
+	return stored, nil
+}
+
+// UpdateLabelRule replaces the settings of a label rule and returns it, or
+// nil if it does not exist
+func UpdateLabelRule(rule *LabelRule) (*LabelRule, error) {
+	allowedValues, err := stringsJSON(rule.AllowedValues)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := stringsJSON(rule.Owners)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := scanLabelRule(database.DB.QueryRow(`
+		UPDATE label_rules
+		SET key_pattern = $2, description = $3, required = $4, allowed_values = $5, value_pattern = $6,
+			protected = $7, owners = $8, default_value = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+labelRuleColumns,
+		rule.ID, rule.Key, rule.Description, rule.Required, allowedValues, rule.ValuePattern, rule.Protected,
+		owners, rule.Default))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("a label rule for %s already exists", rule.Key)
+		}
+		return nil, fmt.Errorf("failed to update label rule: %v", err)
+	}
+	return stored, nil
+}
+
+// DeleteLabelRule removes a label rule and reports whether it existed
+func DeleteLabelRule(id int64) (bool, error) {
+	result, err := database.DB.Exec("DELETE FROM label_rules WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete label rule: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+// UserMFA holds the two-factor authentication state of a user
+type UserMFA struct {
+	UserID                 int    `json:"-"`
+	Secret                 string `json:"-"`
+	Enabled                bool   `json:"enabled"`
+	Required               bool   `json:"required"`
+	LastStep               int64  `json:"-"`
+	RecoveryCodesRemaining int    `json:"recovery_codes_remaining"`
+}
+
+// GetUserMFA returns the two-factor state of the user
+func GetUserMFA(userID int) (*UserMFA, error) {
+	query := `SELECT totp_secret, totp_enabled, totp_required, totp_last_step FROM users WHERE id = $1`
+
+	var secret sql.NullString
+	mfa := &UserMFA{UserID: userID}
+	err := database.DB.QueryRow(query, userID).Scan(&secret, &mfa.Enabled, &mfa.Required, &mfa.LastStep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA state: %v", err)
+	}
+	mfa.Secret = secret.String
+
+	count, err := CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	mfa.RecoveryCodesRemaining = count
+
+	return mfa, nil
+}
+
+// IsMFARequiredGlobally reports whether admins enforce two-factor authentication for everyone
+func IsMFARequiredGlobally() (bool, error) {
+	return GetBoolSetting(SettingMFARequired, false)
+}
+
+// SetMFARequiredGlobally enables or disables global enforcement of two-factor authentication
+func SetMFARequiredGlobally(required bool) error {
+	return SetSetting(SettingMFARequired, strconv.FormatBool(required))
+}
+
+// IsMFARequiredForUser reports whether the user must use two-factor
+// authentication, either individually or because of the global setting
+func IsMFARequiredForUser(mfa *UserMFA) (bool, error) {
+	if mfa.Required {
+		return true, nil
+	}
+	return IsMFARequiredGlobally()
+}
+
+// SetPendingTOTPSecret stores a new secret for a user that is enrolling. The
+// secret is not used for login until EnableTOTP is called.
+func SetPendingTOTPSecret(userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = FALSE, totp_last_step = 0, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := database.DB.Exec(query, secret, userID)
+	return err
+}
+
+// EnableTOTP marks enrollment as complete and replaces the user's recovery codes
+func EnableTOTP(userID int, step int64, recoveryCodes []string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_enabled = TRUE, totp_last_step = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable TOTP: %v", err)
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP removes the user's secret and recovery codes
+func DisableTOTP(userID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable TOTP: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetMFARequired enforces or relaxes two-factor authentication for a single user
+func SetMFARequired(userID int, required bool) error {
+	query := `UPDATE users SET totp_required = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := database.DB.Exec(query, required, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// ConsumeTOTPStep records that the code for step has been used. It returns
+// false if that step, or a later one, was already used, which means the code
+// is being replayed.
+func ConsumeTOTPStep(userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	result, err := database.DB.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes discards all existing recovery codes of the user and stores new ones
+func ReplaceRecoveryCodes(userID int, codes []string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codes []string) error {
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+	for _, code := range codes {
+		hashed := hashRecoveryCode(code)
+		if _, err := tx.Exec("INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hashed); err != nil {
+			return fmt.Errorf("failed to store recovery code: %v", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a matching unused recovery code as used and reports
+// whether one was found
+func UseRecoveryCode(userID int, code string) (bool, error) {
+	query := `UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := database.DB.Exec(query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected == 1, nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left
+func CountUnusedRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := database.DB.QueryRow("SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %v", err)
+	}
+	return count, nil
+}
+
+func hashRecoveryCode(code string) string {
+	// Recovery codes are random and high-entropy, so the same unsalted SHA-256
+	// scheme used for refresh tokens is sufficient
+	return hashRefreshToken(utils.NormalizeRecoveryCode(code))
+}
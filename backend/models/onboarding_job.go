@@ -0,0 +1,525 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Possible values of OnboardingJob.State
+const (
+	OnboardingPending    = "pending"
+	OnboardingRunning    = "running"
+	OnboardingSucceeded  = "succeeded"
+	OnboardingFailed     = "failed"
+	OnboardingCancelled  = "cancelled"
+	OnboardingRolledBack = "rolled_back"
+)
+
+// Onboarding steps, see OnboardingSteps for their order
+const (
+	OnboardingStepValidate   = "validate"
+	OnboardingStepConnectHub = "connect_hub"
+	OnboardingStepToken      = "token"
+	OnboardingStepJoin       = "join"
+	OnboardingStepApproveCSR = "approve_csr"
+	OnboardingStepAccept     = "accept"
+	OnboardingStepLabel      = "label"
+	OnboardingStepVerify     = "verify"
+)
+
+// OnboardingSteps are the steps of an onboarding job in the order they run
+var OnboardingSteps = []string{
+	OnboardingStepValidate,
+	OnboardingStepConnectHub,
+	OnboardingStepToken,
+	OnboardingStepJoin,
+	OnboardingStepApproveCSR,
+	OnboardingStepAccept,
+	OnboardingStepLabel,
+	OnboardingStepVerify,
+}
+
+// Possible results of OnboardingJob.RecoveryAction
+const (
+	OnboardingResume   = "resume"
+	OnboardingRollback = "rollback"
+)
+
+// OnboardingResumeWindow is how long after its last progress an interrupted
+// job that already changed the cluster is still resumed. Older jobs are
+// rolled back instead.
+const OnboardingResumeWindow = 30 * time.Minute
+
+var (
+	// ErrOnboardingJobActive is returned when another job is already
+	// onboarding the cluster
+	ErrOnboardingJobActive = errors.New("another onboarding job is already running for this cluster")
+	// ErrOnboardingLeaseLost is returned when a job is no longer owned by the
+	// worker updating it
+	ErrOnboardingLeaseLost = errors.New("onboarding job is no longer owned by this worker")
+)
+
+// OnboardingJob is one request to onboard a cluster
+type OnboardingJob struct {
+	ID          int64  `json:"id"`
+	ClusterName string `json:"cluster_name"`
+	RequestKey  string `json:"request_key"`
+	State       string `json:"state"`
+	// Step is the step that runs next, or the step that failed
+	Step       string            `json:"step"`
+	Attempts   int               `json:"attempts"`
+	Error      string            `json:"error"`
+	ITSContext string            `json:"its_context"`
+	Labels     map[string]string `json:"labels"`
+	Kubeconfig []byte            `json:"-"`
+	// CredentialName refers to the stored credential the job onboards the
+	// cluster with; jobs created without a credential vault keep the
+	// kubeconfig itself
+	CredentialName  string     `json:"credential_name,omitempty"`
+	CancelRequested bool       `json:"cancel_requested"`
+	LeaseOwner      string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt  *time.Time `json:"lease_expires_at,omitempty"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// OnboardingJobEvent is one entry of the progress log of a job
+type OnboardingJobEvent struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	Step      string    `json:"step"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OnboardingJobFilter restricts which jobs ListOnboardingJobs returns. Empty
+// fields are ignored.
+type OnboardingJobFilter struct {
+	State       string
+	ClusterName string
+	Limit       int
+}
+
+// OnboardingStepIndex returns the position of step in OnboardingSteps, or -1
+func OnboardingStepIndex(step string) int {
+	for i, s := range OnboardingSteps {
+		if s == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextOnboardingStep returns the step after step and false if step is the last one
+func NextOnboardingStep(step string) (string, bool) {
+	i := OnboardingStepIndex(step)
+	if i < 0 || i+1 >= len(OnboardingSteps) {
+		return "", false
+	}
+	return OnboardingSteps[i+1], true
+}
+
+// Active reports whether the job is waiting to run or running
+func (j *OnboardingJob) Active() bool {
+	return j.State == OnboardingPending || j.State == OnboardingRunning
+}
+
+// StepDone reports whether step has already completed for the job
+func (j *OnboardingJob) StepDone(step string) bool {
+	if j.State == OnboardingSucceeded {
+		return true
+	}
+	return OnboardingStepIndex(step) < OnboardingStepIndex(j.Step)
+}
+
+// ChangedCluster reports whether the job may already have changed the
+// cluster or the hub, which is the case once the join step started
+func (j *OnboardingJob) ChangedCluster() bool {
+	return OnboardingStepIndex(j.Step) >= OnboardingStepIndex(OnboardingStepJoin)
+}
+
+// CanRetry reports whether the job can be run again
+func (j *OnboardingJob) CanRetry() bool {
+	switch j.State {
+	case OnboardingFailed, OnboardingCancelled, OnboardingRolledBack:
+		return len(j.Kubeconfig) > 0 || j.CredentialName != ""
+	}
+	return false
+}
+
+// RecoveryAction decides what to do with a job that was interrupted, e.g. by
+// a restart of the backend. Jobs that did not change anything yet and recent
+// jobs are resumed; cancelled and stale ones are rolled back.
+func (j *OnboardingJob) RecoveryAction(now time.Time) string {
+	switch {
+	case j.CancelRequested:
+		return OnboardingRollback
+	case !j.ChangedCluster():
+		return OnboardingResume
+	case now.Sub(j.UpdatedAt) > OnboardingResumeWindow:
+		return OnboardingRollback
+	}
+	return OnboardingResume
+}
+
+const onboardingJobColumns = `id, cluster_name, request_key, state, step, attempts, error, its_context, labels,
+	kubeconfig, credential_name, cancel_requested, lease_owner, lease_expires_at, created_by, created_at, updated_at,
+	finished_at`
+
+func scanOnboardingJob(scanner interface{ Scan(...interface{}) error }) (*OnboardingJob, error) {
+	job := &OnboardingJob{}
+	var labels []byte
+	var credentialName sql.NullString
+	var leaseExpiresAt, finishedAt sql.NullTime
+	if err := scanner.Scan(&job.ID, &job.ClusterName, &job.RequestKey, &job.State, &job.Step, &job.Attempts,
+		&job.Error, &job.ITSContext, &labels, &job.Kubeconfig, &credentialName, &job.CancelRequested,
+		&job.LeaseOwner, &leaseExpiresAt, &job.CreatedBy, &job.CreatedAt, &job.UpdatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	job.CredentialName = credentialName.String
+	if err := json.Unmarshal(labels, &job.Labels); err != nil {
+		return nil, fmt.Errorf("invalid labels of onboarding job %d: %v", job.ID, err)
+	}
+	if leaseExpiresAt.Valid {
+		job.LeaseExpiresAt = &leaseExpiresAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
+
+// CreateOnboardingJob stores a new pending job unless the same request is
+// already known. A request is the same when it has the same request key and
+// its job is active or succeeded; that job is returned with created false.
+// ErrOnboardingJobActive is returned when a different request is onboarding
+// the cluster. prepare, if set, runs once these checks passed, while the
+// cluster is still locked and before the job is inserted; the job is not
+// created if it fails.
+func CreateOnboardingJob(job *OnboardingJob, prepare func() error) (*OnboardingJob, bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Serialize requests for the same cluster
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", "onboarding/"+job.ClusterName); err != nil {
+		return nil, false, fmt.Errorf("failed to lock cluster: %v", err)
+	}
+
+	existing, err := scanOnboardingJob(tx.QueryRow(`
+		SELECT `+onboardingJobColumns+` FROM onboarding_jobs
+		WHERE request_key = $1 AND cluster_name = $2 AND state IN ($3, $4, $5)
+		ORDER BY id DESC LIMIT 1`,
+		job.RequestKey, job.ClusterName, OnboardingPending, OnboardingRunning, OnboardingSucceeded))
+	if err == nil {
+		return existing, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to look up onboarding job: %v", err)
+	}
+
+	var active int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM onboarding_jobs WHERE cluster_name = $1 AND state IN ($2, $3)",
+		job.ClusterName, OnboardingPending, OnboardingRunning).Scan(&active); err != nil {
+		return nil, false, fmt.Errorf("failed to look up onboarding jobs: %v", err)
+	}
+	if active > 0 {
+		return nil, false, ErrOnboardingJobActive
+	}
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	labels, err := json.Marshal(job.Labels)
+	if err != nil {
+		return nil, false, err
+	}
+	if job.Labels == nil {
+		labels = []byte("{}")
+	}
+	stored, err := scanOnboardingJob(tx.QueryRow(`
+		INSERT INTO onboarding_jobs (cluster_name, request_key, state, step, its_context, labels, kubeconfig,
+			credential_name, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+		RETURNING `+onboardingJobColumns,
+		job.ClusterName, job.RequestKey, OnboardingPending, OnboardingSteps[0], job.ITSContext, labels,
+		job.Kubeconfig, job.CredentialName, job.CreatedBy))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert onboarding job: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return stored, true, nil
+}
+
+// GetOnboardingJob returns a job, or nil if it does not exist
+func GetOnboardingJob(id int64) (*OnboardingJob, error) {
+	job, err := scanOnboardingJob(database.DB.QueryRow(
+		"SELECT "+onboardingJobColumns+" FROM onboarding_jobs WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get onboarding job: %v", err)
+	}
+	return job, nil
+}
+
+// ListOnboardingJobs returns the jobs matching the filter, newest first
+func ListOnboardingJobs(filter OnboardingJobFilter) ([]*OnboardingJob, error) {
+	var clauses []string
+	var args []interface{}
+	if filter.State != "" {
+		args = append(args, filter.State)
+		clauses = append(clauses, fmt.Sprintf("state = $%d", len(args)))
+	}
+	if filter.ClusterName != "" {
+		args = append(args, filter.ClusterName)
+		clauses = append(clauses, fmt.Sprintf("cluster_name = $%d", len(args)))
+	}
+
+	query := "SELECT " + onboardingJobColumns + " FROM onboarding_jobs"
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return queryOnboardingJobs(query, args...)
+}
+
+// ListRecoverableOnboardingJobs returns the jobs that wait to run and the
+// running jobs whose worker stopped renewing its lease
+func ListRecoverableOnboardingJobs() ([]*OnboardingJob, error) {
+	return queryOnboardingJobs(`
+		SELECT `+onboardingJobColumns+` FROM onboarding_jobs
+		WHERE state = $1 OR (state = $2 AND (lease_expires_at IS NULL OR lease_expires_at < NOW()))
+		ORDER BY id`, OnboardingPending, OnboardingRunning)
+}
+
+func queryOnboardingJobs(query string, args ...interface{}) ([]*OnboardingJob, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list onboarding jobs: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := []*OnboardingJob{}
+	for rows.Next() {
+		job, err := scanOnboardingJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan onboarding job: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ListPlaintextOnboardingJobs returns the jobs that keep their kubeconfig in
+// plain text instead of referring to a stored credential, newest first
+func ListPlaintextOnboardingJobs() ([]*OnboardingJob, error) {
+	return queryOnboardingJobs(`
+		SELECT ` + onboardingJobColumns + ` FROM onboarding_jobs
+		WHERE kubeconfig IS NOT NULL AND credential_name IS NULL
+		ORDER BY id DESC`)
+}
+
+// ReferenceOnboardingCredential makes the jobs of a cluster that keep their
+// kubeconfig in plain text refer to a stored credential instead and clears
+// their kubeconfig
+func ReferenceOnboardingCredential(clusterName, credentialName string) error {
+	_, err := database.DB.Exec(`
+		UPDATE onboarding_jobs SET credential_name = $2, kubeconfig = NULL, updated_at = NOW()
+		WHERE cluster_name = $1 AND kubeconfig IS NOT NULL AND credential_name IS NULL`,
+		clusterName, credentialName)
+	if err != nil {
+		return fmt.Errorf("failed to update onboarding jobs: %v", err)
+	}
+	return nil
+}
+
+// ClaimOnboardingJob makes owner the worker of a pending job, or of a running
+// job whose lease expired, and marks it running. It returns nil if the job
+// cannot be claimed.
+func ClaimOnboardingJob(id int64, owner string, lease time.Duration) (*OnboardingJob, error) {
+	job, err := scanOnboardingJob(database.DB.QueryRow(`
+		UPDATE onboarding_jobs
+		SET state = $2, lease_owner = $3, lease_expires_at = NOW() + $4 * INTERVAL '1 second',
+			attempts = attempts + 1, updated_at = NOW()
+		WHERE id = $1 AND (state = $5 OR (state = $2 AND (lease_expires_at IS NULL OR lease_expires_at < NOW())))
+		RETURNING `+onboardingJobColumns,
+		id, OnboardingRunning, owner, lease.Seconds(), OnboardingPending))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim onboarding job: %v", err)
+	}
+	return job, nil
+}
+
+// RenewOnboardingLease extends the lease owner holds on a running job and
+// reports whether the job was asked to cancel
+func RenewOnboardingLease(id int64, owner string, lease time.Duration) (bool, error) {
+	var cancelRequested bool
+	err := database.DB.QueryRow(`
+		UPDATE onboarding_jobs SET lease_expires_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $1 AND lease_owner = $2 AND state = $4
+		RETURNING cancel_requested`,
+		id, owner, lease.Seconds(), OnboardingRunning).Scan(&cancelRequested)
+	if err == sql.ErrNoRows {
+		return false, ErrOnboardingLeaseLost
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to renew onboarding lease: %v", err)
+	}
+	return cancelRequested, nil
+}
+
+// AdvanceOnboardingJob records that a running job moves on to step
+func AdvanceOnboardingJob(id int64, owner, step string) error {
+	return updateOwnedOnboardingJob(id, owner, "step = $3", step)
+}
+
+// FinishOnboardingJob moves a running job to a final state. The kubeconfig of
+// a succeeded job is dropped since it is no longer needed.
+func FinishOnboardingJob(id int64, owner, state, message string) error {
+	return updateOwnedOnboardingJob(id, owner, `state = $3, error = $4, lease_owner = '', lease_expires_at = NULL,
+		finished_at = NOW(), kubeconfig = CASE WHEN $3 = '`+OnboardingSucceeded+`' THEN NULL ELSE kubeconfig END`,
+		state, message)
+}
+
+func updateOwnedOnboardingJob(id int64, owner, set string, args ...interface{}) error {
+	result, err := database.DB.Exec(
+		"UPDATE onboarding_jobs SET "+set+", updated_at = NOW() WHERE id = $1 AND lease_owner = $2 AND state = '"+OnboardingRunning+"'",
+		append([]interface{}{id, owner}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update onboarding job: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrOnboardingLeaseLost
+	}
+	return nil
+}
+
+// RequestOnboardingCancel asks an active job to stop and returns it, or nil
+// if there is no such active job
+func RequestOnboardingCancel(id int64) (*OnboardingJob, error) {
+	job, err := scanOnboardingJob(database.DB.QueryRow(`
+		UPDATE onboarding_jobs SET cancel_requested = TRUE, updated_at = NOW()
+		WHERE id = $1 AND state IN ($2, $3)
+		RETURNING `+onboardingJobColumns,
+		id, OnboardingPending, OnboardingRunning))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel onboarding job: %v", err)
+	}
+	return job, nil
+}
+
+// RetryOnboardingJob makes a failed, cancelled or rolled back job pending
+// again. A failed job resumes at the step that failed, the others start over.
+// It returns nil if the job cannot be retried and ErrOnboardingJobActive if
+// another job is onboarding the cluster.
+func RetryOnboardingJob(id int64) (*OnboardingJob, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var clusterName string
+	err = tx.QueryRow("SELECT cluster_name FROM onboarding_jobs WHERE id = $1", id).Scan(&clusterName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get onboarding job: %v", err)
+	}
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", "onboarding/"+clusterName); err != nil {
+		return nil, fmt.Errorf("failed to lock cluster: %v", err)
+	}
+
+	var active int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM onboarding_jobs WHERE cluster_name = $1 AND id <> $2 AND state IN ($3, $4)",
+		clusterName, id, OnboardingPending, OnboardingRunning).Scan(&active); err != nil {
+		return nil, fmt.Errorf("failed to look up onboarding jobs: %v", err)
+	}
+	if active > 0 {
+		return nil, ErrOnboardingJobActive
+	}
+
+	job, err := scanOnboardingJob(tx.QueryRow(`
+		UPDATE onboarding_jobs
+		SET state = $2, step = CASE WHEN state = $3 THEN step ELSE $4 END, error = '', cancel_requested = FALSE,
+			lease_owner = '', lease_expires_at = NULL, finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND state IN ($3, $5, $6) AND (kubeconfig IS NOT NULL OR credential_name IS NOT NULL)
+		RETURNING `+onboardingJobColumns,
+		id, OnboardingPending, OnboardingFailed, OnboardingSteps[0], OnboardingCancelled, OnboardingRolledBack))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry onboarding job: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// AddOnboardingJobEvent appends an entry to the progress log of a job
+func AddOnboardingJobEvent(jobID int64, step, status, message string) error {
+	_, err := database.DB.Exec(
+		"INSERT INTO onboarding_job_events (job_id, step, status, message) VALUES ($1, $2, $3, $4)",
+		jobID, step, status, message)
+	if err != nil {
+		return fmt.Errorf("failed to insert onboarding job event: %v", err)
+	}
+	return nil
+}
+
+// ListOnboardingJobEvents returns the progress log of a job, oldest first
+func ListOnboardingJobEvents(jobID int64) ([]OnboardingJobEvent, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, job_id, step, status, message, created_at FROM onboarding_job_events
+		WHERE job_id = $1 ORDER BY id`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list onboarding job events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []OnboardingJobEvent{}
+	for rows.Next() {
+		var event OnboardingJobEvent
+		if err := rows.Scan(&event.ID, &event.JobID, &event.Step, &event.Status, &event.Message, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan onboarding job event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// PasswordStatus holds what is needed to decide whether a user must rotate their password
+type PasswordStatus struct {
+	MustChange bool      `json:"must_change_password"`
+	ChangedAt  time.Time `json:"password_changed_at"`
+}
+
+// GetPasswordStatus returns the password age and forced-rotation flag of a user
+func GetPasswordStatus(userID int) (*PasswordStatus, error) {
+	query := `SELECT must_change_password, password_changed_at FROM users WHERE id = $1`
+
+	status := &PasswordStatus{}
+	if err := database.DB.QueryRow(query, userID).Scan(&status.MustChange, &status.ChangedAt); err != nil {
+		return nil, fmt.Errorf("failed to get password status: %v", err)
+	}
+	return status, nil
+}
+
+// SetMustChangePassword forces or clears a password change on the user's next login
+func SetMustChangePassword(userID int, mustChange bool) error {
+	query := `UPDATE users SET must_change_password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := database.DB.Exec(query, mustChange, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// IsPasswordReused reports whether password matches the current password or
+// one of the last historySize previous passwords of the user
+func IsPasswordReused(userID int, password string, historySize int) (bool, error) {
+	var current string
+	if err := database.DB.QueryRow("SELECT password FROM users WHERE id = $1", userID).Scan(&current); err != nil {
+		return false, fmt.Errorf("failed to get current password: %v", err)
+	}
+	if CheckPasswordHash(password, current) {
+		return true, nil
+	}
+	if historySize <= 0 {
+		return false, nil
+	}
+
+	rows, err := database.DB.Query(
+		"SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		userID, historySize)
+	if err != nil {
+		return false, fmt.Errorf("failed to get password history: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return false, err
+		}
+		if CheckPasswordHash(password, hash) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// ChangeUserPassword sets a new password, moves the old hash into the
+// password history, keeping at most historySize entries, and clears the
+// forced-rotation flag
+func ChangeUserPassword(userID int, newPassword string, historySize int) error {
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow("SELECT password FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get current password: %v", err)
+	}
+
+	if historySize > 0 {
+		if _, err := tx.Exec("INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)", userID, current); err != nil {
+			return fmt.Errorf("failed to record password history: %v", err)
+		}
+	}
+	_, err = tx.Exec(`
+		DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+		)`, userID, historySize)
+	if err != nil {
+		return fmt.Errorf("failed to trim password history: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET password = $1, password_changed_at = CURRENT_TIMESTAMP, must_change_password = FALSE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, hashedPassword, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return tx.Commit()
+}
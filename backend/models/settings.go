@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+)
+
+// Keys of settings stored in the system_settings table
+const (
+	SettingMFARequired = "mfa_required"
+	// SettingProtectBindingPolicyLabels protects the label keys BindingPolicies
+	// select clusters by
+	SettingProtectBindingPolicyLabels = "labels_protect_binding_policy_keys"
+)
+
+// GetSetting returns the value stored for key and whether it exists
+func GetSetting(key string) (string, bool, error) {
+	var value string
+	err := database.DB.QueryRow("SELECT value FROM system_settings WHERE key = $1", key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get setting %s: %v", key, err)
+	}
+	return value, true, nil
+}
+
+// SetSetting creates or updates the value stored for key
+func SetSetting(key, value string) error {
+	query := `
+		INSERT INTO system_settings (key, value, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP`
+	if _, err := database.DB.Exec(query, key, value); err != nil {
+		return fmt.Errorf("failed to set setting %s: %v", key, err)
+	}
+	return nil
+}
+
+// GetBoolSetting returns a boolean setting, falling back to defaultValue when
+// the setting is missing or cannot be parsed
+func GetBoolSetting(key string, defaultValue bool) (bool, error) {
+	value, exists, err := GetSetting(key)
+	if err != nil || !exists {
+		return defaultValue, err
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, nil
+	}
+	return parsed, nil
+}
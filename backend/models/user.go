@@ -50,13 +50,19 @@ func CreateUser(username, password string, isAdmin bool) (*User, error) {
 		return nil, fmt.Errorf("failed to hash password: %v", err)
 	}
 
+	return CreateUserWithPasswordHash(username, hashedPassword, isAdmin)
+}
+
+// CreateUserWithPasswordHash creates a user whose password is already hashed,
+// for example when importing accounts from another store
+func CreateUserWithPasswordHash(username, passwordHash string, isAdmin bool) (*User, error) {
 	query := `
-		INSERT INTO users (username, password, is_admin) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO users (username, password, is_admin)
+		VALUES ($1, $2, $3)
 		RETURNING id, username, is_admin, created_at, updated_at`
 
 	user := &User{Permissions: make(map[string]string)}
-	err = database.DB.QueryRow(query, username, hashedPassword, isAdmin).Scan(
+	err := database.DB.QueryRow(query, username, passwordHash, isAdmin).Scan(
 		&user.ID, &user.Username, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -66,6 +72,20 @@ func CreateUser(username, password string, isAdmin bool) (*User, error) {
 	return user, nil
 }
 
+// SetUserAdmin grants or revokes the admin role
+func SetUserAdmin(userID int, isAdmin bool) error {
+	query := `UPDATE users SET is_admin = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := database.DB.Exec(query, isAdmin, userID)
+	return err
+}
+
+// UpdateUserPasswordHash replaces the stored password hash of a user
+func UpdateUserPasswordHash(userID int, passwordHash string) error {
+	query := `UPDATE users SET password = $1, password_changed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := database.DB.Exec(query, passwordHash, userID)
+	return err
+}
+
 // GetUserByUsername retrieves a user by username
 func GetUserByUsername(username string) (*User, error) {
 	query := `SELECT id, username, password, is_admin, created_at, updated_at FROM users WHERE username = $1`
@@ -102,7 +122,7 @@ func GetUserByID(userID int) (*User, error) {
 		&user.ID, &user.Username, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by ID: %v", err)
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 
 	permissions, err := GetUserPermissions(userID)
@@ -185,7 +205,7 @@ func UpdateUserPassword(userID int, newPassword string) error {
 		return err
 	}
 
-	query := `UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	query := `UPDATE users SET password = $1, password_changed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
 	_, err = database.DB.Exec(query, hashedPassword, userID)
 	return err
 }
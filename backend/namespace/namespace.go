@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/models"
 	"github.com/kubestellar/ui/backend/redis"
@@ -30,8 +31,11 @@ const (
 	maxConcurrentRequests = 5
 )
 
-// AvailableContexts defines the list of available Kubernetes contexts
-var AvailableContexts = []string{"wds1", "its1"}
+// AvailableContexts returns the kubeconfig contexts of the spaces in the hub
+// registry, WDSes first
+func AvailableContexts() []string {
+	return hub.ContextNames()
+}
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(_ *http.Request) bool { return true },
@@ -54,7 +58,7 @@ type ExtendedNamespaceDetails struct {
 
 // HasContextPrefix checks if a namespace has a context prefix
 func HasContextPrefix(namespace string) (bool, string, string) {
-	for _, ctxPrefix := range AvailableContexts {
+	for _, ctxPrefix := range AvailableContexts() {
 		prefix := ctxPrefix + "-"
 		if strings.HasPrefix(namespace, prefix) {
 			cleanName := strings.TrimPrefix(namespace, prefix)
@@ -735,8 +739,8 @@ func getMinimalNamespaceDataWithContext(contextName string) ([]NamespaceDetails,
 func MultiContextWebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract context parameter
 	contextName := r.URL.Query().Get("context")
-	if contextName == "" && len(AvailableContexts) > 0 {
-		contextName = AvailableContexts[0] // Default to first context
+	if contextName == "" && len(AvailableContexts()) > 0 {
+		contextName = hub.DefaultWDS() // Default to the default WDS
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -819,24 +823,24 @@ func MultiContextWebSocketHandler(w http.ResponseWriter, r *http.Request) {
 // CreateNamespace creates a new namespace using default context
 func CreateNamespace(namespace models.Namespace) error {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return fmt.Errorf("no available contexts defined")
 	}
 
-	return CreateNamespaceWithContext(AvailableContexts[0], namespace)
+	return CreateNamespaceWithContext(hub.DefaultWDS(), namespace)
 }
 
 // GetAllNamespaces fetches all namespaces along with their pods using default context
 func GetAllNamespaces() ([]models.Namespace, error) {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return nil, fmt.Errorf("no available contexts defined")
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
-	contextName := AvailableContexts[0]
+	contextName := hub.DefaultWDS()
 	clientset, _, err := k8s.GetClientSetWithContext(contextName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Kubernetes client: %w", err)
@@ -885,41 +889,41 @@ func GetAllNamespaces() ([]models.Namespace, error) {
 // UpdateNamespace updates namespace labels using default context
 func UpdateNamespace(namespaceName string, labels map[string]string) error {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return fmt.Errorf("no available contexts defined")
 	}
 
-	return UpdateNamespaceWithContext(AvailableContexts[0], namespaceName, labels)
+	return UpdateNamespaceWithContext(hub.DefaultWDS(), namespaceName, labels)
 }
 
 // DeleteNamespace removes a namespace using default context
 func DeleteNamespace(name string) error {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return fmt.Errorf("no available contexts defined")
 	}
 
-	return DeleteNamespaceWithContext(AvailableContexts[0], name)
+	return DeleteNamespaceWithContext(hub.DefaultWDS(), name)
 }
 
 // GetAllNamespacesWithResources retrieves all namespaces with their resources using default context
 func GetAllNamespacesWithResources() ([]NamespaceDetails, error) {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return nil, fmt.Errorf("no available contexts defined")
 	}
 
-	return GetAllNamespacesWithContext(AvailableContexts[0])
+	return GetAllNamespacesWithContext(hub.DefaultWDS())
 }
 
 // GetNamespaceResources fetches resources for a namespace using discovery API with default context
 func GetNamespaceResources(namespace string) (*ExtendedNamespaceDetails, error) {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return nil, fmt.Errorf("no available contexts defined")
 	}
 
-	contextName := AvailableContexts[0]
+	contextName := hub.DefaultWDS()
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
@@ -969,7 +973,7 @@ func GetAllContextNamespaces() (map[string][]NamespaceDetails, error) {
 	var mu sync.Mutex
 	var errs []error
 
-	for _, ctx := range AvailableContexts {
+	for _, ctx := range AvailableContexts() {
 		wg.Add(1)
 		go func(contextName string) {
 			defer wg.Done()
@@ -1112,7 +1116,7 @@ func WatchAllContextsNamespaces(w http.ResponseWriter, r *http.Request) {
 	// Start data fetching in background
 	go func() {
 		// Send initial data in chunks for faster response
-		for _, contextName := range AvailableContexts {
+		for _, contextName := range AvailableContexts() {
 			go func(ctx context.Context, contextName string) {
 				namespaces, err := getMinimalNamespaceDataWithContext(contextName)
 				if err != nil {
@@ -1130,7 +1134,7 @@ func WatchAllContextsNamespaces(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Watch for changes in each context
-		for _, contextName := range AvailableContexts {
+		for _, contextName := range AvailableContexts() {
 			go func(ctx context.Context, contextName string) {
 				clientset, _, err := k8s.GetClientSetWithContext(contextName)
 				if err != nil {
@@ -1223,21 +1227,21 @@ func shouldHideNamespace(name string) bool {
 // getLatestNamespaceData tries multiple ways to get namespace data
 func getLatestNamespaceData() ([]NamespaceDetails, error) {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return nil, fmt.Errorf("no available contexts defined")
 	}
 
-	return getLatestNamespaceDataWithContext(AvailableContexts[0])
+	return getLatestNamespaceDataWithContext(hub.DefaultWDS())
 }
 
 // getMinimalNamespaceData gets just namespace names without heavy resource details
 func getMinimalNamespaceData() ([]NamespaceDetails, error) {
 	// Use first available context as default
-	if len(AvailableContexts) == 0 {
+	if len(AvailableContexts()) == 0 {
 		return nil, fmt.Errorf("no available contexts defined")
 	}
 
-	return getMinimalNamespaceDataWithContext(AvailableContexts[0])
+	return getMinimalNamespaceDataWithContext(hub.DefaultWDS())
 }
 
 // WatchNamespaceInContext sets up watch for resources in a namespace in a specific context
@@ -1251,8 +1255,8 @@ func WatchNamespaceInContext(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use default context if not specified
-	if contextName == "" && len(AvailableContexts) > 0 {
-		contextName = AvailableContexts[0]
+	if contextName == "" && len(AvailableContexts()) > 0 {
+		contextName = hub.DefaultWDS()
 	}
 
 	// Store original namespace (without prefix) for display purposes
@@ -1529,7 +1533,7 @@ func GetAllContextsNamespaces(w http.ResponseWriter, r *http.Request) {
 	detailed := r.URL.Query().Get("detailed") == "true"
 
 	// For each available context, fetch namespaces concurrently
-	for _, ctxName := range AvailableContexts {
+	for _, ctxName := range AvailableContexts() {
 		wg.Add(1)
 		go func(contextName string) {
 			defer wg.Done()
@@ -1598,8 +1602,8 @@ func GetContextNamespace(w http.ResponseWriter, r *http.Request) {
 
 	// Extract context from query parameters
 	contextName := r.URL.Query().Get("context")
-	if contextName == "" && len(AvailableContexts) > 0 {
-		contextName = AvailableContexts[0]
+	if contextName == "" && len(AvailableContexts()) > 0 {
+		contextName = hub.DefaultWDS()
 	}
 
 	// Get namespace details with resources
@@ -1647,7 +1651,7 @@ func GetMultiContextNamespaceComparison(w http.ResponseWriter, r *http.Request)
 		fmt.Printf("Using specified contexts: %v\n", contextsToCheck)
 	} else {
 		// Use all available contexts
-		contextsToCheck = AvailableContexts
+		contextsToCheck = AvailableContexts()
 		fmt.Printf("Using all available contexts: %v\n", contextsToCheck)
 	}
 
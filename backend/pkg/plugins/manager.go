@@ -18,9 +18,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/auth"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/middleware"
-	"github.com/kubestellar/ui/backend/models"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
@@ -203,11 +203,14 @@ func (pm *PluginManager) LoadPlugin(pluginPath string) error {
 		return errors.New("plugin name, author name and version are required in manifest")
 	}
 
-	author, err := models.GetUserByUsername(authorName)
+	author, err := auth.Users.GetUser(authorName)
 	if err != nil {
 		log.LogError("error get authorID", zap.String("error", err.Error()))
 		return err
 	}
+	if author == nil {
+		return fmt.Errorf("author not found: %s", authorName)
+	}
 
 	// Get pluginDetailsID from database
 	pluginID, err := GetPluginIDByNameAuthorVersion(pluginName, author.ID, pluginVersion)
@@ -475,7 +478,7 @@ func (pm *PluginManager) RegisterPlugin(plugin *Plugin, userIDAuth int) {
 
 	if !exist {
 		// Get userID
-		author, err := models.GetUserByUsername(plugin.Manifest.Metadata.Author)
+		author, err := auth.Users.GetUser(plugin.Manifest.Metadata.Author)
 		if err != nil {
 			log.LogError("Failed to get user ID", zap.Error(err))
 			return
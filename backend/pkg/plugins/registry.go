@@ -6,7 +6,7 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/auth"
 	"gopkg.in/yaml.v3"
 )
 
@@ -115,7 +115,7 @@ func (pr *PluginRegistry) discoverPluginInDirectory(dirPath string) (*PluginInfo
 		return nil, fmt.Errorf("plugin name, author name and version are required in manifest")
 	}
 
-	author, err := models.GetUserByUsername(authorName)
+	author, err := auth.Users.GetUser(authorName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get author by username: %v", err)
 	}
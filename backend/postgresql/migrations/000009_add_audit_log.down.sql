@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_username;
+DROP INDEX IF EXISTS idx_audit_log_occurred_at;
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,22 @@
+-- Create audit_log table recording every mutating call made through the UI backend
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id INTEGER NULL,
+    username VARCHAR(255) NOT NULL DEFAULT 'anonymous',
+    action VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    target_context VARCHAR(255) NOT NULL DEFAULT '',
+    resource TEXT NOT NULL DEFAULT '',
+    payload_hash VARCHAR(64) NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL,
+    result VARCHAR(20) NOT NULL CHECK (result IN ('success', 'failure', 'started')),
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_username ON audit_log(username);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
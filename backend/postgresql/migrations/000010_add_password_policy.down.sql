@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS password_history;
+
+ALTER TABLE users DROP COLUMN IF EXISTS must_change_password;
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bp_revisions_created_at;
+DROP TABLE IF EXISTS bp_revisions;
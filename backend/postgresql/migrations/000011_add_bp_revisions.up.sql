@@ -0,0 +1,16 @@
+-- Create bp_revisions table holding a snapshot of every BindingPolicy change
+CREATE TABLE IF NOT EXISTS bp_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    wds_context VARCHAR(255) NOT NULL,
+    policy_name VARCHAR(253) NOT NULL,
+    revision INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('create', 'update', 'rollback', 'delete')),
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    rolled_back_from INTEGER NULL,
+    snapshot JSONB NOT NULL,
+    snapshot_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (wds_context, policy_name, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bp_revisions_created_at ON bp_revisions(created_at);
@@ -0,0 +1,13 @@
+-- Create bp_templates table holding every version of the admin-managed
+-- BindingPolicy templates
+CREATE TABLE IF NOT EXISTS bp_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(253) NOT NULL,
+    version INTEGER NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parameters JSONB NOT NULL DEFAULT '[]',
+    body TEXT NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (name, version)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS onboarding_job_events;
+DROP TABLE IF EXISTS onboarding_jobs;
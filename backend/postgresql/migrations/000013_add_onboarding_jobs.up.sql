@@ -0,0 +1,39 @@
+-- Create onboarding_jobs table tracking every cluster onboarding as a step
+-- state machine that survives backend restarts
+CREATE TABLE IF NOT EXISTS onboarding_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    cluster_name VARCHAR(253) NOT NULL,
+    request_key VARCHAR(128) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'running', 'succeeded', 'failed', 'cancelled', 'rolled_back')),
+    step VARCHAR(32) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    its_context VARCHAR(255) NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}',
+    kubeconfig BYTEA NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    lease_owner VARCHAR(255) NOT NULL DEFAULT '',
+    lease_expires_at TIMESTAMP WITH TIME ZONE NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+-- A cluster is onboarded by at most one job at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_onboarding_jobs_active_cluster ON onboarding_jobs(cluster_name)
+    WHERE state IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_onboarding_jobs_request_key ON onboarding_jobs(request_key);
+CREATE INDEX IF NOT EXISTS idx_onboarding_jobs_state ON onboarding_jobs(state);
+
+-- Create onboarding_job_events table keeping the progress log of every job
+CREATE TABLE IF NOT EXISTS onboarding_job_events (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES onboarding_jobs(id) ON DELETE CASCADE,
+    step VARCHAR(32) NOT NULL DEFAULT '',
+    status VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_onboarding_job_events_job_id ON onboarding_job_events(job_id);
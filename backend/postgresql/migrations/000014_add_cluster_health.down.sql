@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS cluster_alerts;
+DROP TABLE IF EXISTS cluster_alert_rules;
+DROP TABLE IF EXISTS cluster_health_samples;
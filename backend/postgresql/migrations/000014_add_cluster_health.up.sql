@@ -0,0 +1,55 @@
+-- Create cluster_health_samples table keeping the health history of every
+-- managed cluster. A sample is written whenever Available, Joined or the
+-- lease heartbeat changes state and periodically in between.
+CREATE TABLE IF NOT EXISTS cluster_health_samples (
+    id BIGSERIAL PRIMARY KEY,
+    its_context VARCHAR(255) NOT NULL,
+    cluster_name VARCHAR(253) NOT NULL,
+    available BOOLEAN NOT NULL,
+    joined BOOLEAN NOT NULL,
+    heartbeat_stale BOOLEAN NOT NULL DEFAULT FALSE,
+    heartbeat_age_seconds BIGINT NULL,
+    transition BOOLEAN NOT NULL DEFAULT FALSE,
+    observed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_health_samples_cluster ON cluster_health_samples(its_context, cluster_name, observed_at);
+CREATE INDEX IF NOT EXISTS idx_cluster_health_samples_observed_at ON cluster_health_samples(observed_at);
+
+-- Create cluster_alert_rules table with the conditions that raise alerts
+CREATE TABLE IF NOT EXISTS cluster_alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    condition VARCHAR(32) NOT NULL CHECK (condition IN ('unavailable', 'not_joined', 'heartbeat_stale')),
+    duration_seconds INTEGER NOT NULL DEFAULT 300 CHECK (duration_seconds >= 0),
+    cluster_pattern VARCHAR(253) NOT NULL DEFAULT '',
+    its_context VARCHAR(255) NOT NULL DEFAULT '',
+    notifiers JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO cluster_alert_rules (name, condition, duration_seconds, created_by)
+VALUES ('cluster-unavailable', 'unavailable', 300, 'system')
+ON CONFLICT (name) DO NOTHING;
+
+-- Create cluster_alerts table recording every alert raised by a rule
+CREATE TABLE IF NOT EXISTS cluster_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NULL REFERENCES cluster_alert_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(255) NOT NULL,
+    condition VARCHAR(32) NOT NULL,
+    its_context VARCHAR(255) NOT NULL,
+    cluster_name VARCHAR(253) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    since TIMESTAMP WITH TIME ZONE NOT NULL,
+    fired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+-- A rule raises at most one open alert per cluster
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cluster_alerts_open ON cluster_alerts(rule_id, its_context, cluster_name)
+    WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_cluster_alerts_cluster ON cluster_alerts(cluster_name, fired_at);
@@ -0,0 +1,3 @@
+ALTER TABLE onboarding_jobs DROP COLUMN IF EXISTS credential_name;
+DROP TABLE IF EXISTS cluster_credential_access;
+DROP TABLE IF EXISTS cluster_credentials;
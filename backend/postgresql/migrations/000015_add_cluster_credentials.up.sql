@@ -0,0 +1,37 @@
+-- Create cluster_credentials table keeping the kubeconfigs of imported and
+-- onboarded clusters encrypted. Each kubeconfig is sealed with its own data
+-- key, which is stored wrapped by the master key identified by key_id.
+CREATE TABLE IF NOT EXISTS cluster_credentials (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(253) NOT NULL UNIQUE,
+    server TEXT NOT NULL DEFAULT '',
+    context_name VARCHAR(255) NOT NULL DEFAULT '',
+    key_id VARCHAR(64) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP WITH TIME ZONE NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_credentials_key_id ON cluster_credentials(key_id);
+
+-- Create cluster_credential_access table recording every use of a
+-- credential. Entries outlive the credential they refer to.
+CREATE TABLE IF NOT EXISTS cluster_credential_access (
+    id BIGSERIAL PRIMARY KEY,
+    credential_id INTEGER NULL REFERENCES cluster_credentials(id) ON DELETE SET NULL,
+    credential_name VARCHAR(253) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('store', 'read', 'rotate', 'delete')),
+    purpose VARCHAR(255) NOT NULL DEFAULT '',
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_credential_access_name ON cluster_credential_access(credential_name, occurred_at);
+
+-- Onboarding jobs refer to a stored credential instead of keeping the
+-- kubeconfig in plain text
+ALTER TABLE onboarding_jobs ADD COLUMN IF NOT EXISTS credential_name VARCHAR(253) NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS csr_decisions;
+DROP TABLE IF EXISTS csr_allowlist;
+DROP TABLE IF EXISTS csr_approval_rules;
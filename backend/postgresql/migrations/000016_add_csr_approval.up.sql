@@ -0,0 +1,62 @@
+-- Create csr_approval_rules table with the rules that approve or deny the
+-- certificate signing requests of joining clusters. The enabled rule with the
+-- lowest priority that matches a CSR decides it; CSRs no rule matches wait
+-- for manual approval.
+CREATE TABLE IF NOT EXISTS csr_approval_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('approve', 'deny')),
+    priority INTEGER NOT NULL DEFAULT 100,
+    cluster_pattern VARCHAR(253) NOT NULL DEFAULT '',
+    its_context VARCHAR(255) NOT NULL DEFAULT '',
+    identities JSONB NOT NULL DEFAULT '[]',
+    window_start VARCHAR(5) NOT NULL DEFAULT '',
+    window_end VARCHAR(5) NOT NULL DEFAULT '',
+    window_days JSONB NOT NULL DEFAULT '[]',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    require_allowlist BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Clusters onboarded through the UI are pre-registered, so this keeps them
+-- joining without manual approval
+INSERT INTO csr_approval_rules (name, action, priority, require_allowlist, created_by)
+VALUES ('pre-registered-clusters', 'approve', 100, TRUE, 'system')
+ON CONFLICT (name) DO NOTHING;
+
+-- Create csr_allowlist table with the clusters pre-registered to join
+CREATE TABLE IF NOT EXISTS csr_allowlist (
+    cluster_name VARCHAR(253) NOT NULL,
+    its_context VARCHAR(255) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cluster_name, its_context)
+);
+
+-- Create csr_decisions table recording every decision on a CSR and why
+CREATE TABLE IF NOT EXISTS csr_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    its_context VARCHAR(255) NOT NULL,
+    csr_name VARCHAR(253) NOT NULL,
+    cluster_name VARCHAR(253) NOT NULL,
+    requester VARCHAR(255) NOT NULL DEFAULT '',
+    requester_groups JSONB NOT NULL DEFAULT '[]',
+    decision VARCHAR(16) NOT NULL CHECK (decision IN ('approved', 'denied', 'pending')),
+    mode VARCHAR(16) NOT NULL CHECK (mode IN ('auto', 'manual')),
+    rule_id BIGINT NULL REFERENCES csr_approval_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(255) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    decided_by VARCHAR(255) NOT NULL DEFAULT '',
+    decided_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A CSR is queued for manual approval at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_csr_decisions_pending ON csr_decisions(its_context, csr_name)
+    WHERE decision = 'pending';
+CREATE INDEX IF NOT EXISTS idx_csr_decisions_cluster ON csr_decisions(cluster_name, decided_at);
+CREATE INDEX IF NOT EXISTS idx_csr_decisions_decided_at ON csr_decisions(decided_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS label_rules;
+DELETE FROM system_settings WHERE key = 'labels_protect_binding_policy_keys';
@@ -0,0 +1,36 @@
+-- Create label_rules table with the label schema of managed clusters. A rule
+-- applies to the label keys its key pattern matches: it can require the key,
+-- restrict its values to an enumeration or a regular expression, protect it
+-- from changes or let only its owners change it, and give it a default
+-- applied when a cluster is onboarded.
+CREATE TABLE IF NOT EXISTS label_rules (
+    id BIGSERIAL PRIMARY KEY,
+    key_pattern VARCHAR(317) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_values JSONB NOT NULL DEFAULT '[]',
+    value_pattern TEXT NOT NULL DEFAULT '',
+    protected BOOLEAN NOT NULL DEFAULT FALSE,
+    owners JSONB NOT NULL DEFAULT '[]',
+    default_value VARCHAR(63) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Labels set by Open Cluster Management and Kubernetes stay protected, and
+-- onboarded clusters keep being placed in the edge location group
+INSERT INTO label_rules (key_pattern, description, protected, created_by) VALUES
+    ('cluster.open-cluster-management.io/*', 'Set by Open Cluster Management', TRUE, 'system'),
+    ('feature.open-cluster-management.io/*', 'Set by Open Cluster Management', TRUE, 'system'),
+    ('kubernetes.io/*', 'Reserved for Kubernetes', TRUE, 'system'),
+    ('k8s.io/*', 'Reserved for Kubernetes', TRUE, 'system'),
+    ('beta.kubernetes.io/*', 'Reserved for Kubernetes', TRUE, 'system'),
+    ('topology.kubernetes.io/*', 'Reserved for Kubernetes', TRUE, 'system'),
+    ('node-role.kubernetes.io/*', 'Reserved for Kubernetes', TRUE, 'system'),
+    ('node.openshift.io/*', 'Reserved for OpenShift', TRUE, 'system')
+ON CONFLICT (key_pattern) DO NOTHING;
+
+INSERT INTO label_rules (key_pattern, description, default_value, created_by)
+VALUES ('location-group', 'Location group of the cluster', 'edge', 'system')
+ON CONFLICT (key_pattern) DO NOTHING;
@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
 	"github.com/kubestellar/ui/backend/log"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -303,10 +304,26 @@ func GetWorkloadLabel() (string, error) {
 	return rdb.Get(ctx, "workload_label").Result()
 }
 
+// SetDeploySpaces stores the WDS and ITS a repository was deployed to, which
+// webhook deployments of the repository reuse
+func SetDeploySpaces(wds, its string) error {
+	return rdb.HSet(ctx, "deploy_spaces", "wds", wds, "its", its).Err()
+}
+
+// GetDeploySpaces gets the WDS and ITS stored by SetDeploySpaces
+func GetDeploySpaces() (string, string, error) {
+	spaces, err := rdb.HGetAll(ctx, "deploy_spaces").Result()
+	if err != nil {
+		return "", "", err
+	}
+	return spaces["wds"], spaces["its"], nil
+}
+
 // BindingPolicyCache represents a binding policy in the cache
 type BindingPolicyCache struct {
 	Name              string              `json:"name"`
 	Namespace         string              `json:"namespace"`
+	WDSContext        string              `json:"wdsContext,omitempty"`
 	ClusterSelectors  []map[string]string `json:"clusterSelectors"`
 	APIGroups         []string            `json:"apiGroups"`
 	Resources         []string            `json:"resources"`
@@ -314,10 +331,12 @@ type BindingPolicyCache struct {
 	SpecificWorkloads []WorkloadInfo      `json:"specificWorkloads"`
 	RawYAML           string              `json:"rawYAML"`
 	Status            string              `json:"status"`
-	BindingMode       string              `json:"bindingMode"`
-	Clusters          []string            `json:"clusters"`
-	Workloads         []string            `json:"workloads"`
-	CreationTimestamp string              `json:"creationTimestamp"`
+	// Conditions are the BindingPolicy status conditions reported by KubeStellar
+	Conditions        []v1alpha1.BindingPolicyCondition `json:"conditions,omitempty"`
+	BindingMode       string                            `json:"bindingMode"`
+	Clusters          []string                          `json:"clusters"`
+	Workloads         []string                          `json:"workloads"`
+	CreationTimestamp string                            `json:"creationTimestamp"`
 }
 
 type WorkloadInfo struct {
@@ -332,11 +351,21 @@ const (
 	DefaultExpiration    = 24 * time.Hour
 )
 
-// StoreBindingPolicy stores a binding policy in Redis with proper type handling
+// bindingPolicyHashKey returns the hash holding the binding policies of one
+// WDS, so policies with the same name in different WDSes do not overwrite
+// each other
+func bindingPolicyHashKey(wdsContext string) string {
+	return BindingPolicyHashKey + ":" + wdsContext
+}
+
+// StoreBindingPolicy stores a binding policy of the WDS named by
+// policy.WDSContext in Redis with proper type handling
 func StoreBindingPolicy(policy *BindingPolicyCache) error {
 	log.LogInfo("Storing binding policy",
 		zap.String("name", policy.Name),
-		zap.String("namespace", policy.Namespace))
+		zap.String("namespace", policy.Namespace),
+		zap.String("wdsContext", policy.WDSContext))
+	hashKey := bindingPolicyHashKey(policy.WDSContext)
 
 	jsonData, err := json.Marshal(policy)
 	if err != nil {
@@ -346,7 +375,7 @@ func StoreBindingPolicy(policy *BindingPolicyCache) error {
 		return fmt.Errorf("failed to marshal binding policy: %v", err)
 	}
 
-	if err := rdb.HSet(ctx, "binding_policies", policy.Name, string(jsonData)).Err(); err != nil {
+	if err := rdb.HSet(ctx, hashKey, policy.Name, string(jsonData)).Err(); err != nil {
 		log.LogError("Failed to store binding policy in Redis",
 			zap.String("name", policy.Name),
 			zap.Error(err))
@@ -354,7 +383,7 @@ func StoreBindingPolicy(policy *BindingPolicyCache) error {
 	}
 
 	// Set expiration for the hash
-	err = rdb.Expire(ctx, BindingPolicyHashKey, DefaultExpiration).Err()
+	err = rdb.Expire(ctx, hashKey, DefaultExpiration).Err()
 	if err != nil {
 		log.LogWarn("failed to set expiration for binding policy", zap.Error(err))
 	}
@@ -363,14 +392,14 @@ func StoreBindingPolicy(policy *BindingPolicyCache) error {
 	return nil
 }
 
-// GetBindingPolicy retrieves a binding policy from Redis by name
-func GetBindingPolicy(name string) (*BindingPolicyCache, error) {
+// GetBindingPolicy retrieves a binding policy of a WDS from Redis by name
+func GetBindingPolicy(wdsContext, name string) (*BindingPolicyCache, error) {
 	// Check if Redis is available
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("redis not available: %v", err)
 	}
 
-	val, err := rdb.HGet(ctx, BindingPolicyHashKey, name).Result()
+	val, err := rdb.HGet(ctx, bindingPolicyHashKey(wdsContext), name).Result()
 	if err == redis.Nil {
 		log.LogInfo("Binding policy not found", zap.String("name", name))
 		return nil, nil
@@ -402,14 +431,14 @@ func GetBindingPolicy(name string) (*BindingPolicyCache, error) {
 	return &policy, nil
 }
 
-// GetAllBindingPolicies retrieves all binding policies from Redis
-func GetAllBindingPolicies() ([]*BindingPolicyCache, error) {
+// GetAllBindingPolicies retrieves all binding policies of a WDS from Redis
+func GetAllBindingPolicies(wdsContext string) ([]*BindingPolicyCache, error) {
 	// Check if Redis is available
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("redis not available: %v", err)
 	}
 
-	values, err := rdb.HGetAll(ctx, BindingPolicyHashKey).Result()
+	values, err := rdb.HGetAll(ctx, bindingPolicyHashKey(wdsContext)).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all binding policies from Redis: %v", err)
 	}
@@ -442,8 +471,8 @@ func GetAllBindingPolicies() ([]*BindingPolicyCache, error) {
 	return policies, nil
 }
 
-// DeleteBindingPolicy removes a binding policy from Redis
-func DeleteBindingPolicy(name string) error {
+// DeleteBindingPolicy removes a binding policy of a WDS from Redis
+func DeleteBindingPolicy(wdsContext, name string) error {
 	// Check if Redis is available
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		log.LogWarn("redis not available, skipping cache delete", zap.Error(err))
@@ -452,7 +481,7 @@ func DeleteBindingPolicy(name string) error {
 
 	log.LogInfo("Deleting binding policy", zap.String("name", name))
 
-	if err := rdb.HDel(ctx, BindingPolicyHashKey, name).Err(); err != nil {
+	if err := rdb.HDel(ctx, bindingPolicyHashKey(wdsContext), name).Err(); err != nil {
 		log.LogError("Failed to delete binding policy",
 			zap.String("name", name),
 			zap.Error(err))
@@ -462,15 +491,15 @@ func DeleteBindingPolicy(name string) error {
 	return nil
 }
 
-// DeleteAllBindingPolicies removes all binding policies from Redis
-func DeleteAllBindingPolicies() error {
+// DeleteAllBindingPolicies removes all binding policies of a WDS from Redis
+func DeleteAllBindingPolicies(wdsContext string) error {
 	// Check if Redis is available
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		log.LogWarn("redis not available, skipping cache delete", zap.Error(err))
 		return nil // Don't fail the operation if Redis is down
 	}
 
-	err := rdb.Del(ctx, BindingPolicyHashKey).Err()
+	err := rdb.Del(ctx, bindingPolicyHashKey(wdsContext)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to delete all binding policies from Redis: %v", err)
 	}
@@ -479,7 +508,7 @@ func DeleteAllBindingPolicies() error {
 	return nil
 }
 
-// ClearBindingPolicyCache clears the entire binding policy cache
-func ClearBindingPolicyCache() error {
-	return DeleteAllBindingPolicies()
+// ClearBindingPolicyCache clears the binding policy cache of a WDS
+func ClearBindingPolicyCache(wdsContext string) error {
+	return DeleteAllBindingPolicies(wdsContext)
 }
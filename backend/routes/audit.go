@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/audit"
+	"github.com/kubestellar/ui/backend/models"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+	maxAuditExportSize   = 100000
+)
+
+// parseAuditFilter reads the filter query parameters shared by the list and
+// export endpoints
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Username:      c.Query("user"),
+		Action:        c.Query("action"),
+		Result:        c.Query("result"),
+		TargetContext: c.Query("context"),
+		Resource:      c.Query("resource"),
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s time %q, expected RFC3339", name, value)
+		}
+		*target = &parsed
+	}
+
+	return filter, nil
+}
+
+// ListAuditLogHandler returns audit entries matching the query filters (admin only)
+func ListAuditLogHandler(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.Limit = defaultAuditPageSize
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAuditPageSize)})
+			return
+		}
+		filter.Limit = limit
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	entries, total, err := models.ListAuditEntries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve audit log",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// ExportAuditLogHandler downloads audit entries matching the query filters as
+// JSON or CSV (admin only)
+func ExportAuditLogHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Limit = maxAuditExportSize
+
+	entries, _, err := models.ListAuditEntries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export audit log",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		if err := audit.WriteCSV(c.Writer, entries); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	c.Status(http.StatusOK)
+	if err := json.NewEncoder(c.Writer).Encode(entries); err != nil {
+		c.Error(err)
+	}
+}
+
+// GetAuditSettingsHandler returns the audit log retention period (admin only)
+func GetAuditSettingsHandler(c *gin.Context) {
+	days, err := audit.RetentionDays()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve audit settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retention_days": days})
+}
+
+// SetAuditSettingsHandler changes the audit log retention period. A value of
+// 0 keeps entries forever (admin only)
+func SetAuditSettingsHandler(c *gin.Context) {
+	var payload struct {
+		RetentionDays *int `json:"retention_days" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || *payload.RetentionDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be a non-negative integer"})
+		return
+	}
+
+	if err := audit.SetRetentionDays(*payload.RetentionDays); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update audit settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Audit settings updated successfully",
+		"retention_days": *payload.RetentionDays,
+	})
+}
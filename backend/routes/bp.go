@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/middleware"
 	"github.com/kubestellar/ui/backend/wds/bp"
 )
 
@@ -15,5 +16,51 @@ func setupBindingPolicyRoutes(router *gin.Engine) {
 	router.DELETE("/api/bp/delete/:name", bp.DeleteBp)
 	router.DELETE("/api/bp/delete", bp.DeleteAllBp)
 	router.PATCH("/api/bp/update/:name", bp.UpdateBp)
+	router.PUT("/api/bp/update/:name/statuscollectors",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.SetBpStatusCollectors)
+	router.POST("/api/bp/preview", bp.PreviewBp)
+	router.GET("/api/bp/lint", bp.LintBps)
+	router.GET("/api/bp/export", bp.ExportBps)
+	router.GET("/api/bp/statuscollectors", bp.ListStatusCollectors)
+	router.GET("/api/bp/statuscollectors/:name", bp.GetStatusCollector)
+	router.POST("/api/bp/statuscollectors",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.CreateStatusCollector)
+	router.PUT("/api/bp/statuscollectors/:name",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.UpdateStatusCollector)
+	router.DELETE("/api/bp/statuscollectors/:name",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.DeleteStatusCollector)
+	router.GET("/api/bp/combinedstatus", bp.GetCombinedStatusHandler)
+	router.POST("/api/bp/import/preview",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.PreviewBpImport)
+	// Imports create and update objects in the WDS, so they need resources
+	// write permission like detaching a cluster
+	router.POST("/api/bp/import",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.ImportBps)
+	router.GET("/api/bp/templates", bp.ListBpTemplates)
+	router.GET("/api/bp/templates/:name", bp.GetBpTemplate)
+	router.GET("/api/bp/templates/:name/versions", bp.ListBpTemplateVersions)
+	router.POST("/api/bp/templates/:name/render", bp.RenderBpTemplate)
+	router.POST("/api/bp/templates/:name/create", bp.CreateBpFromTemplate)
+	router.GET("/api/bp/propagation/:name", bp.GetBpPropagation)
+	router.GET("/api/bp/propagation/:name/watch", bp.WatchBpPropagation)
+	router.GET("/api/bp/revisions/:name", bp.ListBpRevisions)
+	router.GET("/api/bp/revisions/:name/diff", bp.DiffBpRevisions)
+	router.GET("/api/bp/revisions/:name/:revision", bp.GetBpRevision)
+	router.POST("/api/bp/revisions/:name/:revision/rollback",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		bp.RollbackBp)
 
 }
@@ -26,16 +26,51 @@ func setupClusterRoutes(router *gin.Engine) {
 
 	// Cluster onboarding, status, and detachment
 	router.POST("/clusters/onboard", api.OnboardClusterHandler)
+	// Bulk onboarding can use contexts of the backend's own kubeconfig
+	router.POST("/clusters/onboard/bulk",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.BulkOnboardClustersHandler)
+	router.GET("/clusters/onboard/bulk/:id", api.GetBulkOnboardingHandler)
 	router.GET("/clusters/status", api.GetClusterStatusHandler)
-	router.POST("/clusters/detach", api.DetachClusterHandler)
+	// Detaching, forced mode in particular, requires resources write permission
+	router.POST("/clusters/detach",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.DetachClusterHandler)
+	router.GET("/clusters/detach/impact/:cluster", api.GetDetachImpactHandler)
+
+	// Persisted onboarding jobs
+	router.GET("/clusters/onboard/jobs", api.ListOnboardingJobsHandler)
+	router.GET("/clusters/onboard/jobs/:id", api.GetOnboardingJobHandler)
+	router.POST("/clusters/onboard/jobs/:id/cancel",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.CancelOnboardingJobHandler)
+	router.POST("/clusters/onboard/jobs/:id/retry",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.RetryOnboardingJobHandler)
 
 	// Logs and WebSocket
 	router.GET("/clusters/onboard/logs/:cluster", api.OnboardingLogsHandler)
 	router.GET("/clusters/detach/logs/:cluster", api.GetDetachmentLogsHandler)
 	router.GET("/ws/onboarding", api.WSOnboardingHandler)
+	router.GET("/ws/onboarding/bulk", api.WSBulkOnboardingHandler)
 
-	// Certificate Signing Requests
-	router.GET("/clusters/watch-csr", handlers.GetCSRsExecHandler)
+	// Certificate Signing Requests of joining clusters. watch-csr keeps the
+	// kubectl CSR list shape for older clients.
+	router.GET("/clusters/watch-csr", api.WatchCSRsHandler)
+	router.GET("/clusters/csr/pending", api.ListPendingCSRsHandler)
+	router.GET("/clusters/csr/decisions", api.ListCSRDecisionsHandler)
+	router.POST("/clusters/csr/:name/approve",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.ApproveCSRHandler)
+	router.POST("/clusters/csr/:name/deny",
+		middleware.AuthenticateMiddleware(),
+		middleware.RequirePermission("resources", "write"),
+		api.DenyCSRHandler)
 
 	// Available clusters
 	router.GET("/api/clusters/available", handlers.GetAvailableClustersHandler)
@@ -49,12 +84,25 @@ func setupClusterRoutes(router *gin.Engine) {
 	router.GET("/ws/detachment", api.HandleDetachmentWebSocket)
 
 	// Import cluster
-	router.POST("/clusters/import", handlers.ImportClusterHandler)
-	router.POST("/clusters/import-by-url", handlers.ImportClusterByURLHandler)
+	router.POST("/clusters/import", api.ImportClusterHandler)
+	router.POST("/clusters/import-by-url", api.ImportClusterByURLHandler)
 
 	// Remote Tree View Cluster details
 	router.GET("/api/cluster/details/:name", handlers.GetClusterDetailsHandler)
 
 	router.GET("api/new/clusters", api.GetManagedClustersHandler)
 	router.GET("api/clusters/:name", api.GetManagedClusterHandler)
+
+	// Cluster inventory and label suggestions for BindingPolicy selectors
+	router.GET("/api/clusters/inventory", api.GetClusterInventoryHandler)
+	router.GET("/api/clusters/inventory/label-suggestions", api.GetLabelSuggestionsHandler)
+	router.GET("/api/clusters/inventory/:name", api.GetClusterInventoryItemHandler)
+	router.POST("/api/clusters/inventory/refresh", api.RefreshClusterInventoryHandler)
+
+	// Label schema and the compliance of the managed clusters with it
+	router.GET("/api/clusters/labels/schema", api.GetLabelSchemaHandler)
+	router.GET("/api/clusters/labels/compliance", api.GetLabelComplianceHandler)
+
+	// Recorded health and availability of a managed cluster
+	router.GET("/api/clusters/:name/health/history", GetClusterHealthHistoryHandler)
 }
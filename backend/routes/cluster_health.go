@@ -0,0 +1,277 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/clusterhealth"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/models"
+)
+
+const (
+	defaultHealthWindow = 24 * time.Hour
+	maxHealthWindow     = 90 * 24 * time.Hour
+	defaultAlertPage    = 100
+	maxAlertPage        = 1000
+)
+
+// parseHealthWindow reads the from and to query parameters, defaulting to
+// the last 24 hours
+func parseHealthWindow(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := now
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to time %q, expected RFC3339", value)
+		}
+		to = parsed
+	}
+	if to.After(now) {
+		to = now
+	}
+
+	from := to.Add(-defaultHealthWindow)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from time %q, expected RFC3339", value)
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > maxHealthWindow {
+		return time.Time{}, time.Time{}, fmt.Errorf("the window must not be longer than %d days", int(maxHealthWindow.Hours()/24))
+	}
+	return from, to, nil
+}
+
+// GetClusterHealthHistoryHandler returns the recorded health samples of a
+// managed cluster and its availability within a time window
+func GetClusterHealthHistoryHandler(c *gin.Context) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	clusterName := c.Param("name")
+
+	from, to, err := parseHealthWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	transitionsOnly := c.Query("transitions") == "true"
+
+	initial, err := models.ClusterHealthSampleBefore(its.Context, clusterName, from)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve cluster health history",
+			"details": err.Error(),
+		})
+		return
+	}
+	samples, err := models.ListClusterHealthSamples(its.Context, clusterName, from, to, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve cluster health history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	availability := clusterhealth.ComputeAvailability(initial, samples, from, to)
+	if transitionsOnly {
+		transitions := []*models.ClusterHealthSample{}
+		for _, sample := range samples {
+			if sample.Transition {
+				transitions = append(transitions, sample)
+			}
+		}
+		samples = transitions
+	}
+
+	response := gin.H{
+		"cluster":      clusterName,
+		"its":          its.Name,
+		"availability": availability,
+		"samples":      samples,
+	}
+	if state, ok := clusterhealth.Current(its.Context, clusterName); ok {
+		response["current"] = state
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type alertRulePayload struct {
+	Name            string   `json:"name" binding:"required"`
+	Condition       string   `json:"condition" binding:"required"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	ClusterPattern  string   `json:"cluster_pattern"`
+	ITSContext      string   `json:"its_context"`
+	Notifiers       []string `json:"notifiers"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+// bindAlertRule reads and validates a rule from the request body
+func bindAlertRule(c *gin.Context) (*models.ClusterAlertRule, bool) {
+	var payload alertRulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return nil, false
+	}
+
+	rule := &models.ClusterAlertRule{
+		Name:            payload.Name,
+		Condition:       payload.Condition,
+		DurationSeconds: 300,
+		ClusterPattern:  payload.ClusterPattern,
+		ITSContext:      payload.ITSContext,
+		Notifiers:       payload.Notifiers,
+		Enabled:         true,
+	}
+	if payload.DurationSeconds != nil {
+		rule.DurationSeconds = *payload.DurationSeconds
+	}
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+	if rule.ITSContext != "" {
+		its, err := hub.Resolve(hub.ITS, rule.ITSContext)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		rule.ITSContext = its.Context
+	}
+	if err := clusterhealth.ValidateRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rule, true
+}
+
+func alertRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListAlertRulesHandler returns every cluster alert rule (admin only)
+func ListAlertRulesHandler(c *gin.Context) {
+	rules, err := models.ListClusterAlertRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve alert rules",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "conditions": models.AlertConditions})
+}
+
+// CreateAlertRuleHandler adds a cluster alert rule (admin only)
+func CreateAlertRuleHandler(c *gin.Context) {
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+	rule.CreatedBy = c.GetString("username")
+
+	stored, err := models.CreateClusterAlertRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+// UpdateAlertRuleHandler replaces the settings of a cluster alert rule (admin only)
+func UpdateAlertRuleHandler(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	stored, err := models.UpdateClusterAlertRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if stored == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return
+	}
+	// Alerts of a disabled or narrowed rule are resolved right away
+	go clusterhealth.EvaluateAlerts()
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteAlertRuleHandler removes a cluster alert rule (admin only)
+func DeleteAlertRuleHandler(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+	deleted, err := models.DeleteClusterAlertRule(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return
+	}
+	go clusterhealth.EvaluateAlerts()
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted successfully"})
+}
+
+// ListClusterAlertsHandler returns raised alerts, newest first, optionally
+// only the open ones or those of one cluster (admin only)
+func ListClusterAlertsHandler(c *gin.Context) {
+	filter := models.ClusterAlertFilter{
+		ClusterName: c.Query("cluster"),
+		OpenOnly:    c.Query("open") == "true",
+		Limit:       defaultAlertPage,
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAlertPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAlertPage)})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	alerts, err := models.ListClusterAlerts(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve cluster alerts",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// ListAlertNotifiersHandler returns the names alert rules can send to (admin only)
+func ListAlertNotifiersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"notifiers": clusterhealth.Notifiers()})
+}
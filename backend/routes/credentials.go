@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/vault"
+)
+
+const (
+	defaultCredentialAccessPage = 100
+	maxCredentialAccessPage     = 1000
+	maxCredentialNameLength     = 253
+)
+
+func credentialAccess(c *gin.Context, purpose string) vault.Access {
+	return vault.Access{Username: c.GetString("username"), Purpose: purpose}
+}
+
+// requireVault writes a 503 response if the credential vault has no master key
+func requireVault(c *gin.Context) bool {
+	if !vault.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": fmt.Sprintf("The credential vault is not enabled, set %s or %s", vault.MasterKeyEnv, vault.MasterKeyFileEnv),
+		})
+		return false
+	}
+	return true
+}
+
+// readCredentialRequest reads the name and kubeconfig of a credential from a
+// JSON body or a multipart form with a kubeconfig file
+func readCredentialRequest(c *gin.Context) (string, []byte, error) {
+	if strings.Contains(c.GetHeader("Content-Type"), "multipart/form-data") {
+		file, err := c.FormFile("kubeconfig")
+		if err != nil {
+			return "", nil, errors.New("kubeconfig file is required")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open uploaded file: %v", err)
+		}
+		defer src.Close()
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read uploaded file: %v", err)
+		}
+		return c.PostForm("name"), data, nil
+	}
+
+	var req struct {
+		Name       string `json:"name"`
+		Kubeconfig string `json:"kubeconfig"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return "", nil, fmt.Errorf("invalid request payload: %v", err)
+	}
+	if req.Kubeconfig == "" {
+		return "", nil, errors.New("kubeconfig is required")
+	}
+	return req.Name, []byte(req.Kubeconfig), nil
+}
+
+// ListCredentialsHandler returns the metadata of the stored cluster
+// credentials, never the credentials themselves (admin only)
+func ListCredentialsHandler(c *gin.Context) {
+	credentials, err := vault.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credentials": credentials, "enabled": vault.Enabled()})
+}
+
+// StoreCredentialHandler stores a kubeconfig in the credential vault,
+// replacing the credential of the same name (admin only)
+func StoreCredentialHandler(c *gin.Context) {
+	if !requireVault(c) {
+		return
+	}
+	name, kubeconfig, err := readCredentialRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if name == "" || len(name) > maxCredentialNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name is required and at most %d characters", maxCredentialNameLength)})
+		return
+	}
+	if _, _, err := vault.Describe(kubeconfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := vault.Store(name, kubeconfig, credentialAccess(c, "stored by an administrator"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to store credential",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, credential)
+}
+
+// DeleteCredentialHandler removes a stored credential (admin only)
+func DeleteCredentialHandler(c *gin.Context) {
+	err := vault.Delete(c.Param("name"), credentialAccess(c, "deleted by an administrator"))
+	if errors.Is(err, vault.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credential not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete credential",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Credential deleted successfully"})
+}
+
+// GetCredentialAccessHandler returns who used a credential and why, newest
+// first (admin only)
+func GetCredentialAccessHandler(c *gin.Context) {
+	limit := defaultCredentialAccessPage
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxCredentialAccessPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxCredentialAccessPage)})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := vault.AccessLog(c.Param("name"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve credential access log",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"access": entries})
+}
+
+// RotateCredentialsHandler rewraps every credential with the current master
+// key (admin only). Credentials wrapped with a key that is no longer
+// configured are reported as failed.
+func RotateCredentialsHandler(c *gin.Context) {
+	if !requireVault(c) {
+		return
+	}
+	result, err := vault.Rotate(credentialAccess(c, "master key rotation"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rotate credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
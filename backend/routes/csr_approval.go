@@ -0,0 +1,244 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/csrapproval"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/models"
+)
+
+const defaultCSRRulePriority = 100
+
+type csrRulePayload struct {
+	Name             string   `json:"name" binding:"required"`
+	Action           string   `json:"action" binding:"required"`
+	Priority         *int     `json:"priority"`
+	ClusterPattern   string   `json:"cluster_pattern"`
+	ITSContext       string   `json:"its_context"`
+	Identities       []string `json:"identities"`
+	WindowStart      string   `json:"window_start"`
+	WindowEnd        string   `json:"window_end"`
+	WindowDays       []string `json:"window_days"`
+	Timezone         string   `json:"timezone"`
+	RequireAllowlist bool     `json:"require_allowlist"`
+	Enabled          *bool    `json:"enabled"`
+}
+
+type csrAllowlistPayload struct {
+	ClusterName string `json:"cluster_name" binding:"required"`
+	ITSContext  string `json:"its_context"`
+	Note        string `json:"note"`
+	// TTLSeconds expires the entry, it never expires if zero
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+// resolveITSContext replaces an ITS name by its context, leaving an empty
+// name, which applies to every ITS, as is
+func resolveITSContext(c *gin.Context, name string) (string, bool) {
+	if name == "" {
+		return "", true
+	}
+	its, err := hub.Resolve(hub.ITS, name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return its.Context, true
+}
+
+// bindCSRRule reads and validates a CSR approval rule from the request body
+func bindCSRRule(c *gin.Context) (*models.CSRApprovalRule, bool) {
+	var payload csrRulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return nil, false
+	}
+
+	rule := &models.CSRApprovalRule{
+		Name:             payload.Name,
+		Action:           payload.Action,
+		Priority:         defaultCSRRulePriority,
+		ClusterPattern:   payload.ClusterPattern,
+		Identities:       payload.Identities,
+		WindowStart:      payload.WindowStart,
+		WindowEnd:        payload.WindowEnd,
+		WindowDays:       payload.WindowDays,
+		Timezone:         payload.Timezone,
+		RequireAllowlist: payload.RequireAllowlist,
+		Enabled:          true,
+	}
+	if payload.Priority != nil {
+		rule.Priority = *payload.Priority
+	}
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+	itsContext, ok := resolveITSContext(c, payload.ITSContext)
+	if !ok {
+		return nil, false
+	}
+	rule.ITSContext = itsContext
+	if err := csrapproval.ValidateRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rule, true
+}
+
+func csrRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSR approval rule ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListCSRRulesHandler returns the CSR approval rules in the order they are
+// evaluated (admin only)
+func ListCSRRulesHandler(c *gin.Context) {
+	rules, err := models.ListCSRApprovalRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve CSR approval rules",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateCSRRuleHandler adds a CSR approval rule (admin only)
+func CreateCSRRuleHandler(c *gin.Context) {
+	rule, ok := bindCSRRule(c)
+	if !ok {
+		return
+	}
+	rule.CreatedBy = c.GetString("username")
+
+	stored, err := models.CreateCSRApprovalRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+// UpdateCSRRuleHandler replaces the settings of a CSR approval rule (admin only)
+func UpdateCSRRuleHandler(c *gin.Context) {
+	id, ok := csrRuleID(c)
+	if !ok {
+		return
+	}
+	rule, ok := bindCSRRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	stored, err := models.UpdateCSRApprovalRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if stored == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CSR approval rule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteCSRRuleHandler removes a CSR approval rule, keeping the decisions it
+// made (admin only)
+func DeleteCSRRuleHandler(c *gin.Context) {
+	id, ok := csrRuleID(c)
+	if !ok {
+		return
+	}
+	deleted, err := models.DeleteCSRApprovalRule(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete CSR approval rule",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CSR approval rule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "CSR approval rule deleted successfully"})
+}
+
+// ListCSRAllowlistHandler returns the clusters pre-registered to join
+// (admin only)
+func ListCSRAllowlistHandler(c *gin.Context) {
+	entries, err := models.ListCSRAllowlist()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve CSR allowlist",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"allowlist": entries})
+}
+
+// AddCSRAllowlistHandler pre-registers a cluster to join, optionally only one
+// ITS and for a limited time (admin only)
+func AddCSRAllowlistHandler(c *gin.Context) {
+	var payload csrAllowlistPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if payload.TTLSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_seconds must not be negative"})
+		return
+	}
+	itsContext, ok := resolveITSContext(c, payload.ITSContext)
+	if !ok {
+		return
+	}
+
+	entry := &models.CSRAllowlistEntry{
+		ClusterName: payload.ClusterName,
+		ITSContext:  itsContext,
+		Note:        payload.Note,
+		CreatedBy:   c.GetString("username"),
+	}
+	if payload.TTLSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(payload.TTLSeconds) * time.Second)
+		entry.ExpiresAt = &expiresAt
+	}
+	if err := models.SaveCSRAllowlistEntry(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save CSR allowlist entry",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// RemoveCSRAllowlistHandler removes a cluster from the allowlist of every
+// ITS (admin only)
+func RemoveCSRAllowlistHandler(c *gin.Context) {
+	deleted, err := models.DeleteCSRAllowlistEntries(c.Param("cluster"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove CSR allowlist entry",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster is not on the CSR allowlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cluster removed from the CSR allowlist"})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"github.com/kubestellar/ui/backend/wds"
 	"github.com/kubestellar/ui/backend/wds/deployment"
@@ -40,7 +41,12 @@ func setupDeploymentRoutes(router *gin.Engine) {
 		telemetry.WebsocketConnectionUpgradedSuccess.WithLabelValues("GET", "/api/wds/logs", "upgrade_success").Inc()
 		//defer conn.Close()
 
-		clientset, err := wds.GetClientSetKubeConfig()
+		space, err := hub.SelectWDS(ctx)
+		if err != nil {
+			conn.WriteMessage(websocket.TextMessage, []byte(err.Error()))
+			return
+		}
+		clientset, err := wds.GetClientSetKubeConfig(space.Context)
 		if err != nil {
 			telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/wds/logs", "500").Inc()
 			log.Println("Failed to get Kubernetes client:", err)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kubestellar/ui/backend/api"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/redis"
 	"github.com/kubestellar/ui/backend/telemetry"
@@ -80,8 +81,12 @@ func setupGitHubRoutes(router *gin.Engine) {
 
 		// Get specific deployment
 		github.GET("/:id", func(c *gin.Context) {
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
 			deploymentID := c.Param("id")
-			deployments, err := k8s.GetGithubDeployments("its1")
+			deployments, err := k8s.GetGithubDeployments(its.Context)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/github/:id", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -116,7 +121,11 @@ func setupGitHubRoutes(router *gin.Engine) {
 
 		// Filter routes
 		github.GET("/webhook", func(c *gin.Context) {
-			deployments, err := k8s.GetGithubDeployments("its1")
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			deployments, err := k8s.GetGithubDeployments(its.Context)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/github/webhook", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -140,7 +149,11 @@ func setupGitHubRoutes(router *gin.Engine) {
 		})
 
 		github.GET("/manual", func(c *gin.Context) {
-			deployments, err := k8s.GetGithubDeployments("its1")
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			deployments, err := k8s.GetGithubDeployments(its.Context)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/github/manual", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -313,7 +326,11 @@ func setupValidationRoutes(router *gin.Engine) {
 
 		// Test Kubernetes connectivity
 		validation.GET("/kubernetes", func(c *gin.Context) {
-			_, err := k8s.GetGithubDeployments("its1")
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			_, err := k8s.GetGithubDeployments(its.Context)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/validate/kubernetes", "503").Inc()
 				c.JSON(http.StatusServiceUnavailable, gin.H{
@@ -356,7 +373,11 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 	{
 		// GitHub config routes - for viewing stored deployment data
 		history.GET("/github", func(c *gin.Context) {
-			config, err := k8s.GetConfigMapData("its1", k8s.GitHubConfigMapName)
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			config, err := k8s.GetConfigMapData(its.Context, k8s.GitHubConfigMapName)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/github", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -374,7 +395,11 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 
 		// Helm config routes - for viewing stored deployment data
 		history.GET("/helm", func(c *gin.Context) {
-			config, err := k8s.GetConfigMapData("its1", k8s.HelmConfigMapName)
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			config, err := k8s.GetConfigMapData(its.Context, k8s.HelmConfigMapName)
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/helm", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -392,7 +417,11 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 
 		// Manifests config routes - for viewing stored deployment data
 		history.GET("/manifests", func(c *gin.Context) {
-			config, err := k8s.GetConfigMapData("its1", "kubestellar-manifests")
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
+			config, err := k8s.GetConfigMapData(its.Context, "kubestellar-manifests")
 			if err != nil {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/manifests", "500").Inc()
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -410,6 +439,10 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 
 		// Combined deployment history
 		history.GET("/all", func(c *gin.Context) {
+			its, ok := hub.MustSelectITS(c)
+			if !ok {
+				return
+			}
 			result := gin.H{
 				"github":    nil,
 				"helm":      nil,
@@ -418,7 +451,7 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 			}
 
 			// Get GitHub deployments
-			if githubConfig, err := k8s.GetConfigMapData("its1", k8s.GitHubConfigMapName); err == nil {
+			if githubConfig, err := k8s.GetConfigMapData(its.Context, k8s.GitHubConfigMapName); err == nil {
 				result["github"] = githubConfig
 			} else {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/all", "500").Inc()
@@ -426,7 +459,7 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 			}
 
 			// Get Helm deployments
-			if helmConfig, err := k8s.GetConfigMapData("its1", k8s.HelmConfigMapName); err == nil {
+			if helmConfig, err := k8s.GetConfigMapData(its.Context, k8s.HelmConfigMapName); err == nil {
 				result["helm"] = helmConfig
 			} else {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/all", "500").Inc()
@@ -434,7 +467,7 @@ func setupDeploymentHistoryRoutes(router *gin.Engine) {
 			}
 
 			// Get Manifests deployments
-			if manifestsConfig, err := k8s.GetConfigMapData("its1", "kubestellar-manifests"); err == nil {
+			if manifestsConfig, err := k8s.GetConfigMapData(its.Context, "kubestellar-manifests"); err == nil {
 				result["manifests"] = manifestsConfig
 			} else {
 				telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/deployments/all", "500").Inc()
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+)
+
+func setupHubRoutes(router *gin.Engine) {
+	router.GET("/api/hub/spaces", hub.ListSpacesHandler)             // ITS and WDS spaces the UI can work with
+	router.POST("/api/hub/spaces/refresh", hub.RefreshSpacesHandler) // rediscover KubeFlex ControlPlanes
+}
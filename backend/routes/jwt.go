@@ -2,16 +2,19 @@ package routes
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/auth"
 	jwtconfig "github.com/kubestellar/ui/backend/jwt"
 	"github.com/kubestellar/ui/backend/middleware"
 	"github.com/kubestellar/ui/backend/models"
 	database "github.com/kubestellar/ui/backend/postgresql/Database"
 	"github.com/kubestellar/ui/backend/utils"
+	"github.com/kubestellar/ui/backend/wds/bp"
 )
 
 // SetupRoutes initializes all routes - THIS IS THE MISSING FUNCTION!
@@ -117,13 +120,25 @@ func setupAuthRoutes(router *gin.Engine) {
 	router.POST("/login", LoginHandler)
 	router.POST("/api/refresh", RefreshTokenHandler)
 
+	// Second-factor login steps, authenticated by the interim token from /login
+	router.POST("/login/mfa", MFALoginHandler)
+	router.POST("/login/mfa/enroll", MFALoginEnrollHandler)
+	router.POST("/login/mfa/enroll/verify", MFALoginEnrollVerifyHandler)
+
 	// API group - ALL endpoints require authentication
 	api := router.Group("/api")
 	api.Use(middleware.AuthenticateMiddleware()) // Apply authentication to ALL API routes
+	api.Use(middleware.RequireCurrentPassword()) // Block expired or must-change passwords
 	{
 		// Basic authenticated user endpoints
 		api.GET("/me", CurrentUserHandler)
 		api.PUT("/me/password", ChangePasswordHandler)
+		api.GET("/me/password/policy", GetPasswordPolicyHandler)
+		api.GET("/me/mfa", GetMyMFAHandler)
+		api.POST("/me/mfa/enroll", StartMyMFAEnrollmentHandler)
+		api.POST("/me/mfa/verify", VerifyMyMFAEnrollmentHandler)
+		api.DELETE("/me/mfa", DisableMyMFAHandler)
+		api.POST("/me/mfa/recovery-codes", RegenerateMyRecoveryCodesHandler)
 
 		// Component-based permission routes - ALL require authentication FIRST
 		setupComponentRoutes(api)
@@ -139,6 +154,41 @@ func setupAuthRoutes(router *gin.Engine) {
 			admin.GET("/users/deleted", ListDeletedUsersHandler)
 			admin.GET("/users/:username/permissions", GetUserPermissionsHandler)
 			admin.PUT("/users/:username/permissions", SetUserPermissionsHandler)
+			admin.GET("/users/:username/lockout", GetUserLockoutHandler)
+			admin.DELETE("/users/:username/lockout", UnlockUserHandler)
+			admin.GET("/users/:username/mfa", GetUserMFAHandler)
+			admin.PUT("/users/:username/mfa", SetUserMFARequirementHandler)
+			admin.DELETE("/users/:username/mfa", ResetUserMFAHandler)
+			admin.GET("/mfa/policy", GetMFAPolicyHandler)
+			admin.PUT("/mfa/policy", SetMFAPolicyHandler)
+			admin.GET("/audit", ListAuditLogHandler)
+			admin.GET("/audit/export", ExportAuditLogHandler)
+			admin.GET("/audit/settings", GetAuditSettingsHandler)
+			admin.PUT("/audit/settings", SetAuditSettingsHandler)
+			admin.GET("/health/alert-rules", ListAlertRulesHandler)
+			admin.POST("/health/alert-rules", CreateAlertRuleHandler)
+			admin.PUT("/health/alert-rules/:id", UpdateAlertRuleHandler)
+			admin.DELETE("/health/alert-rules/:id", DeleteAlertRuleHandler)
+			admin.GET("/health/alerts", ListClusterAlertsHandler)
+			admin.GET("/health/notifiers", ListAlertNotifiersHandler)
+			admin.GET("/credentials", ListCredentialsHandler)
+			admin.POST("/credentials", StoreCredentialHandler)
+			admin.POST("/credentials/rotate", RotateCredentialsHandler)
+			admin.DELETE("/credentials/:name", DeleteCredentialHandler)
+			admin.GET("/credentials/:name/access", GetCredentialAccessHandler)
+			admin.GET("/csr/rules", ListCSRRulesHandler)
+			admin.POST("/csr/rules", CreateCSRRuleHandler)
+			admin.PUT("/csr/rules/:id", UpdateCSRRuleHandler)
+			admin.DELETE("/csr/rules/:id", DeleteCSRRuleHandler)
+			admin.GET("/csr/allowlist", ListCSRAllowlistHandler)
+			admin.POST("/csr/allowlist", AddCSRAllowlistHandler)
+			admin.DELETE("/csr/allowlist/:cluster", RemoveCSRAllowlistHandler)
+			admin.POST("/labels/rules", CreateLabelRuleHandler)
+			admin.PUT("/labels/rules/:id", UpdateLabelRuleHandler)
+			admin.DELETE("/labels/rules/:id", DeleteLabelRuleHandler)
+			admin.PUT("/labels/settings", SetLabelSettingsHandler)
+			admin.POST("/bp/templates", bp.CreateBpTemplate)
+			admin.DELETE("/bp/templates/:name", bp.DeleteBpTemplate)
 		}
 	}
 }
@@ -209,111 +259,56 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// TEMPORARY: Try direct database authentication for debugging
-	if loginData.Username == "admin" && loginData.Password == "admin" {
-		// Get user directly from database
-		query := "SELECT id, username, password, is_admin FROM users WHERE username = $1"
-		var id int
-		var username, dbPassword string
-		var isAdmin bool
-
-		err := database.DB.QueryRow(query, "admin").Scan(&id, &username, &dbPassword, &isAdmin)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in database"})
-			return
-		}
-
-		// Check if password matches using bcrypt
-		if models.CheckPasswordHash(loginData.Password, dbPassword) {
-			// Create a simple user object for response
-			user := struct {
-				ID          int               `json:"id"`
-				Username    string            `json:"username"`
-				IsAdmin     bool              `json:"is_admin"`
-				Permissions map[string]string `json:"permissions"`
-			}{
-				ID:       id,
-				Username: username,
-				IsAdmin:  isAdmin,
-				Permissions: map[string]string{
-					"users":     "write",
-					"resources": "write",
-					"system":    "write",
-					"dashboard": "write",
-				},
-			}
-
-			accessToken, refreshToken, err := issueTokens(user.ID, user.Username, user.IsAdmin, user.Permissions)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
-				return
-			}
-
-			sendLoginResponse(c, accessToken, refreshToken, user.Username, user.IsAdmin, user.Permissions)
-			return
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Password mismatch",
-				"debug": "Bcrypt verification failed for stored hash: " + dbPassword,
-			})
-			return
-		}
+	clientIP := c.ClientIP()
+	if wait := checkLoginAllowed(loginData.Username, clientIP); wait > 0 {
+		sendTooManyAttempts(c, wait)
+		return
 	}
 
-	// Get user from database
-	query := "SELECT id, username, password, is_admin FROM users WHERE username = $1"
-	var id int
-	var username, dbPassword string
-	var isAdmin bool
-
-	err := database.DB.QueryRow(query, loginData.Username).Scan(&id, &username, &dbPassword, &isAdmin)
+	user, err := auth.Users.Authenticate(loginData.Username, loginData.Password)
 	if err != nil {
+		reason := "unknown_user"
+		if errors.Is(err, auth.ErrInvalidPassword) {
+			reason = "invalid_password"
+		}
+		recordLoginFailure(loginData.Username, clientIP, reason)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
+	id, username := user.ID, user.Username
 
-	// Verify password using bcrypt
-	if !models.CheckPasswordHash(loginData.Password, dbPassword) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	// Users with two-factor authentication get an interim token instead of
+	// access tokens; issueTokens only runs once the second factor is verified
+	mfaState, err := models.GetUserMFA(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve two-factor state"})
 		return
 	}
-
-	// Get user permissions
-	permissionsQuery := "SELECT component, permission FROM user_permissions WHERE user_id = $1"
-	permRows, err := database.DB.Query(permissionsQuery, id)
+	if mfaState.Enabled {
+		sendMFAChallenge(c, id, username, utils.TokenTypeMFAPending)
+		return
+	}
+	mfaRequired, err := models.IsMFARequiredForUser(mfaState)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user permissions"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve two-factor policy"})
 		return
 	}
-	defer permRows.Close()
-
-	permissions := make(map[string]string)
-	for permRows.Next() {
-		var component, permission string
-		err := permRows.Scan(&component, &permission)
-		if err != nil {
-			continue
-		}
-		permissions[component] = permission
+	if mfaRequired {
+		sendMFAChallenge(c, id, username, utils.TokenTypeMFAEnroll)
+		return
 	}
 
-	// If admin user has no specific permissions, give them all permissions
-	if isAdmin && len(permissions) == 0 {
-		permissions = map[string]string{
-			"users":     "write",
-			"resources": "write",
-			"system":    "write",
-			"dashboard": "write",
-		}
-	}
+	completeLogin(c, id, nil)
+}
 
-	accessToken, refreshToken, err := issueTokens(id, username, isAdmin, permissions)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
-		return
+// defaultAdminPermissions is the permission set granted to admins without explicit permissions
+func defaultAdminPermissions() map[string]string {
+	return map[string]string{
+		"users":     "write",
+		"resources": "write",
+		"system":    "write",
+		"dashboard": "write",
 	}
-
-	sendLoginResponse(c, accessToken, refreshToken, username, isAdmin, permissions)
 }
 
 func issueTokens(userID int, username string, isAdmin bool, permissions map[string]string) (string, string, error) {
@@ -341,19 +336,6 @@ func issueTokens(userID int, username string, isAdmin bool, permissions map[stri
 	return accessToken, refreshToken, nil
 }
 
-func sendLoginResponse(c *gin.Context, accessToken, refreshToken, username string, isAdmin bool, permissions map[string]string) {
-	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"token":        accessToken,
-		"refreshToken": refreshToken,
-		"user": gin.H{
-			"username":    username,
-			"is_admin":    isAdmin,
-			"permissions": permissions,
-		},
-	})
-}
-
 // RefreshTokenHandler exchanges a valid refresh token for a new access token
 func RefreshTokenHandler(c *gin.Context) {
 	var payload struct {
@@ -398,7 +380,7 @@ func RefreshTokenHandler(c *gin.Context) {
 		return
 	}
 
-	user, err := models.GetUserByID(claims.UserID)
+	user, err := auth.Users.GetUserByID(claims.UserID)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
@@ -452,14 +434,35 @@ func ChangePasswordHandler(c *gin.Context) {
 	username, _ := c.Get("username")
 
 	// Verify current password
-	user, err := models.AuthenticateUser(username.(string), passwordData.CurrentPassword)
+	user, err := auth.Users.Authenticate(username.(string), passwordData.CurrentPassword)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
 		return
 	}
 
-	// Update password
-	err = models.UpdateUserPassword(user.ID, passwordData.NewPassword)
+	if err := utils.ValidatePassword(user.Username, passwordData.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid password",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	reused, err := auth.Users.IsPasswordReused(user.Username, passwordData.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password history"})
+		return
+	}
+	if reused {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid password",
+			"details": fmt.Sprintf("password must differ from the last %d passwords", utils.GetPasswordPolicy().HistorySize+1),
+		})
+		return
+	}
+
+	// Update password, which also clears any forced rotation
+	err = auth.Users.SetPassword(user.Username, passwordData.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
@@ -468,6 +471,11 @@ func ChangePasswordHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
+// GetPasswordPolicyHandler describes the rules a new password must satisfy
+func GetPasswordPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"policy": utils.GetPasswordPolicy()})
+}
+
 // ===================================
 // Admin User Management Handlers
 // ===================================
@@ -566,10 +574,11 @@ func ListUsersHandler(c *gin.Context) {
 // CreateUserHandler creates a new user (admin only)
 func CreateUserHandler(c *gin.Context) {
 	var userData struct {
-		Username    string            `json:"username" binding:"required"`
-		Password    string            `json:"password" binding:"required"`
-		IsAdmin     bool              `json:"is_admin"`
-		Permissions map[string]string `json:"permissions"`
+		Username           string            `json:"username" binding:"required"`
+		Password           string            `json:"password" binding:"required"`
+		IsAdmin            bool              `json:"is_admin"`
+		Permissions        map[string]string `json:"permissions"`
+		MustChangePassword bool              `json:"must_change_password"`
 	}
 
 	if err := c.ShouldBindJSON(&userData); err != nil {
@@ -587,7 +596,7 @@ func CreateUserHandler(c *gin.Context) {
 	}
 
 	// Validate password
-	if err := utils.ValidatePassword(userData.Password); err != nil {
+	if err := utils.ValidatePassword(userData.Username, userData.Password); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid password",
 			"details": err.Error(),
@@ -595,36 +604,26 @@ func CreateUserHandler(c *gin.Context) {
 		return
 	}
 
-	// Create user
-	user, err := models.CreateUser(userData.Username, userData.Password, userData.IsAdmin)
+	// Create user with its permissions
+	_, err := auth.Users.CreateUser(auth.NewUser{
+		Username:           userData.Username,
+		Password:           userData.Password,
+		IsAdmin:            userData.IsAdmin,
+		Permissions:        userData.Permissions,
+		MustChangePassword: userData.MustChangePassword,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrUserExists) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
 			"error":   "Failed to create user",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Set permissions if provided
-	if userData.Permissions != nil {
-		var permSlice []models.Permission
-		for component, permission := range userData.Permissions {
-			permSlice = append(permSlice, models.Permission{
-				Component:  component,
-				Permission: permission,
-			})
-		}
-
-		err = models.SetUserPermissions(user.ID, permSlice)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "User created but failed to set permissions",
-				"details": err.Error(),
-			})
-			return
-		}
-	}
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "User created successfully",
 		"username": userData.Username,
@@ -644,10 +643,11 @@ func UpdateUserHandler(c *gin.Context) {
 	}
 
 	var userData struct {
-		Username    string            `json:"username"`
-		Password    string            `json:"password"`
-		IsAdmin     bool              `json:"is_admin"`
-		Permissions map[string]string `json:"permissions"`
+		Username           string            `json:"username"`
+		Password           string            `json:"password"`
+		IsAdmin            bool              `json:"is_admin"`
+		Permissions        map[string]string `json:"permissions"`
+		MustChangePassword *bool             `json:"must_change_password"`
 	}
 
 	if err := c.ShouldBindJSON(&userData); err != nil {
@@ -668,7 +668,7 @@ func UpdateUserHandler(c *gin.Context) {
 
 	// Validate password if provided
 	if userData.Password != "" {
-		if err := utils.ValidatePassword(userData.Password); err != nil {
+		if err := utils.ValidatePassword(username, userData.Password); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "Invalid password",
 				"details": err.Error(),
@@ -678,7 +678,7 @@ func UpdateUserHandler(c *gin.Context) {
 	}
 
 	// Get existing user
-	user, err := models.GetUserByUsername(username)
+	user, err := auth.Users.GetUser(username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
@@ -690,7 +690,7 @@ func UpdateUserHandler(c *gin.Context) {
 
 	// Update username if provided and different
 	if userData.Username != "" && userData.Username != username {
-		err = models.UpdateUserUsername(user.ID, userData.Username)
+		err = auth.Users.RenameUser(username, userData.Username)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to update username",
@@ -704,7 +704,7 @@ func UpdateUserHandler(c *gin.Context) {
 
 	// Update password if provided
 	if userData.Password != "" {
-		err = models.UpdateUserPassword(user.ID, userData.Password)
+		err = auth.Users.SetPassword(username, userData.Password)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to update password",
@@ -714,10 +714,26 @@ func UpdateUserHandler(c *gin.Context) {
 		}
 	}
 
+	// Force or clear a password change on next login if provided. This runs
+	// after the password update, which always clears the flag.
+	if userData.MustChangePassword != nil {
+		err = auth.Users.SetMustChangePassword(username, *userData.MustChangePassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update password change requirement",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
 	// Update admin status if provided
 	if userData.IsAdmin != user.IsAdmin {
-		query := `UPDATE users SET is_admin = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-		_, err = database.DB.Exec(query, userData.IsAdmin, user.ID)
+		err = auth.Users.SetAdmin(username, userData.IsAdmin)
+		if errors.Is(err, auth.ErrLastAdmin) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove admin role from the last admin user"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to update admin status",
@@ -729,15 +745,7 @@ func UpdateUserHandler(c *gin.Context) {
 
 	// Update permissions if provided
 	if userData.Permissions != nil {
-		var permSlice []models.Permission
-		for component, permission := range userData.Permissions {
-			permSlice = append(permSlice, models.Permission{
-				Component:  component,
-				Permission: permission,
-			})
-		}
-
-		err = models.SetUserPermissions(user.ID, permSlice)
+		err = auth.Users.SetPermissions(username, userData.Permissions)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to update permissions",
@@ -765,29 +773,16 @@ func DeleteUserHandler(c *gin.Context) {
 		return
 	}
 
-	// Prevent deleting the last admin user
-	if username == "admin" {
-		users, err := models.ListAllUsers()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to check admin users",
-				"details": err.Error(),
-			})
-			return
-		}
-		adminCount := 0
-		for _, user := range users {
-			if user.IsAdmin {
-				adminCount++
-			}
-		}
-		if adminCount <= 1 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete the last admin user"})
-			return
-		}
+	// The store refuses to delete the last admin user
+	err := auth.Users.DeleteUser(username)
+	if errors.Is(err, auth.ErrLastAdmin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete the last admin user"})
+		return
+	}
+	if errors.Is(err, auth.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
 	}
-
-	err := models.DeleteUser(username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete user",
@@ -821,7 +816,7 @@ func ListDeletedUsersHandler(c *gin.Context) {
 func GetUserPermissionsHandler(c *gin.Context) {
 	username := c.Param("username")
 
-	user, err := models.GetUserByUsername(username)
+	user, err := auth.Users.GetUser(username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
@@ -850,7 +845,7 @@ func SetUserPermissionsHandler(c *gin.Context) {
 		return
 	}
 
-	user, err := models.GetUserByUsername(username)
+	user, err := auth.Users.GetUser(username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
@@ -860,21 +855,14 @@ func SetUserPermissionsHandler(c *gin.Context) {
 		return
 	}
 
-	var permSlice []models.Permission
-	for component, permission := range permData.Permissions {
+	for _, permission := range permData.Permissions {
 		if permission != "read" && permission != "write" && permission != "none" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid permission value. Must be 'read', 'write', or 'none'"})
 			return
 		}
-		if permission != "none" {
-			permSlice = append(permSlice, models.Permission{
-				Component:  component,
-				Permission: permission,
-			})
-		}
 	}
 
-	err = models.SetUserPermissions(user.ID, permSlice)
+	err = auth.Users.SetPermissions(user.Username, permData.Permissions)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to set permissions",
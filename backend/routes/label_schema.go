@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/labelpolicy"
+	"github.com/kubestellar/ui/backend/models"
+)
+
+type labelRulePayload struct {
+	Key           string   `json:"key" binding:"required"`
+	Description   string   `json:"description"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowed_values"`
+	ValuePattern  string   `json:"value_pattern"`
+	Protected     bool     `json:"protected"`
+	Owners        []string `json:"owners"`
+	Default       string   `json:"default"`
+}
+
+// bindLabelRule reads and validates a label rule from the request body
+func bindLabelRule(c *gin.Context) (*models.LabelRule, bool) {
+	var payload labelRulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return nil, false
+	}
+
+	rule := &models.LabelRule{
+		Key:           payload.Key,
+		Description:   payload.Description,
+		Required:      payload.Required,
+		AllowedValues: payload.AllowedValues,
+		ValuePattern:  payload.ValuePattern,
+		Protected:     payload.Protected,
+		Owners:        payload.Owners,
+		Default:       payload.Default,
+	}
+	if err := labelpolicy.ValidateRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rule, true
+}
+
+func labelRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label rule ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateLabelRuleHandler adds a rule to the label schema (admin only)
+func CreateLabelRuleHandler(c *gin.Context) {
+	rule, ok := bindLabelRule(c)
+	if !ok {
+		return
+	}
+	rule.CreatedBy = c.GetString("username")
+
+	stored, err := models.CreateLabelRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+// UpdateLabelRuleHandler replaces the settings of a label rule (admin only)
+func UpdateLabelRuleHandler(c *gin.Context) {
+	id, ok := labelRuleID(c)
+	if !ok {
+		return
+	}
+	rule, ok := bindLabelRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	stored, err := models.UpdateLabelRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if stored == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label rule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteLabelRuleHandler removes a rule from the label schema (admin only)
+func DeleteLabelRuleHandler(c *gin.Context) {
+	id, ok := labelRuleID(c)
+	if !ok {
+		return
+	}
+	deleted, err := models.DeleteLabelRule(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete label rule",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label rule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Label rule deleted successfully"})
+}
+
+// SetLabelSettingsHandler sets whether the labels BindingPolicies select
+// clusters by are protected (admin only)
+func SetLabelSettingsHandler(c *gin.Context) {
+	var req struct {
+		ProtectBindingPolicyLabels *bool `json:"protect_binding_policy_labels" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if err := labelpolicy.SetProtectBindingPolicyLabels(*req.ProtectBindingPolicyLabels); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update label settings",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"protect_binding_policy_labels": *req.ProtectBindingPolicyLabels})
+}
@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/redis"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"github.com/kubestellar/ui/backend/utils"
+	"go.uber.org/zap"
+)
+
+// checkLoginAllowed returns how long the caller has to wait before it may try to
+// log in again. A zero duration means the attempt may proceed. Redis errors are
+// logged and the attempt is allowed so that a cache outage does not lock
+// everyone out.
+func checkLoginAllowed(username, clientIP string) time.Duration {
+	var wait time.Duration
+
+	checks := []struct {
+		subjectType string
+		subject     string
+		get         func(string, string) (time.Duration, error)
+	}{
+		{redis.LoginSubjectUser, username, redis.GetLoginLockout},
+		{redis.LoginSubjectIP, clientIP, redis.GetLoginLockout},
+		{redis.LoginSubjectUser, username, redis.GetLoginThrottle},
+		{redis.LoginSubjectIP, clientIP, redis.GetLoginThrottle},
+	}
+
+	for _, check := range checks {
+		remaining, err := check.get(check.subjectType, check.subject)
+		if err != nil {
+			log.LogWarn("login protection unavailable, allowing attempt", zap.Error(err))
+			return 0
+		}
+		if remaining > wait {
+			wait = remaining
+		}
+	}
+
+	return wait
+}
+
+// recordLoginFailure bumps the per-user and per-IP failure counters and applies
+// the progressive delay or lockout that the login policy prescribes.
+func recordLoginFailure(username, clientIP, reason string) {
+	telemetry.LoginAttemptsTotal.WithLabelValues("failure", reason).Inc()
+
+	loginPolicy := utils.GetLoginPolicy()
+	subjects := []struct {
+		subjectType string
+		subject     string
+		shouldLock  func(int64) bool
+	}{
+		{redis.LoginSubjectUser, username, loginPolicy.ShouldLockUser},
+		{redis.LoginSubjectIP, clientIP, loginPolicy.ShouldLockIP},
+	}
+
+	for _, s := range subjects {
+		failures, err := redis.IncrLoginFailures(s.subjectType, s.subject, loginPolicy.FailureWindow)
+		if err != nil {
+			log.LogWarn("failed to record login failure", zap.String("subjectType", s.subjectType), zap.Error(err))
+			continue
+		}
+
+		if s.shouldLock(failures) {
+			if err := redis.SetLoginLockout(s.subjectType, s.subject, loginPolicy.LockoutDuration); err != nil {
+				log.LogWarn("failed to lock out login subject", zap.String("subjectType", s.subjectType), zap.Error(err))
+				continue
+			}
+			telemetry.AccountLockoutsTotal.WithLabelValues(s.subjectType).Inc()
+			log.LogWarn("login temporarily locked after repeated failures",
+				zap.String("subjectType", s.subjectType),
+				zap.String("subject", s.subject),
+				zap.Int64("failures", failures),
+				zap.Duration("lockout", loginPolicy.LockoutDuration))
+			continue
+		}
+
+		if delay := loginPolicy.DelayFor(failures); delay > 0 {
+			if err := redis.SetLoginThrottle(s.subjectType, s.subject, delay); err != nil {
+				log.LogWarn("failed to throttle login subject", zap.String("subjectType", s.subjectType), zap.Error(err))
+			}
+		}
+	}
+}
+
+// recordLoginSuccess resets the failure history of the user. The per-IP
+// counter is left to expire on its own so that one valid account cannot be
+// used to reset attempts against others from the same address.
+func recordLoginSuccess(username string) {
+	telemetry.LoginAttemptsTotal.WithLabelValues("success", "").Inc()
+	if err := redis.ClearLoginFailures(redis.LoginSubjectUser, username); err != nil {
+		log.LogWarn("failed to reset login failures", zap.String("username", username), zap.Error(err))
+	}
+}
+
+func sendTooManyAttempts(c *gin.Context, wait time.Duration) {
+	telemetry.LoginAttemptsTotal.WithLabelValues("blocked", "throttled").Inc()
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts. Please try again later.",
+		"retry_after": retryAfter,
+	})
+}
+
+// GetUserLockoutHandler reports the failed login state of a user (admin only)
+func GetUserLockoutHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	failures, err := redis.GetLoginFailures(redis.LoginSubjectUser, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read login failures",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	lockout, err := redis.GetLoginLockout(redis.LoginSubjectUser, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read lockout state",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"username":          username,
+		"failed_attempts":   failures,
+		"locked":            lockout > 0,
+		"lockout_remaining": int(math.Ceil(lockout.Seconds())),
+	})
+}
+
+// UnlockUserHandler clears the failed login history and lockout of a user (admin only)
+func UnlockUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	if err := redis.ClearLoginFailures(redis.LoginSubjectUser, username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unlock user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adminName, _ := c.Get("username")
+	log.LogInfo("user login lockout cleared",
+		zap.String("username", username),
+		zap.Any("by", adminName))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "User unlocked successfully",
+		"username": username,
+	})
+}
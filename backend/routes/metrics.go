@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kubestellar/ui/backend/api"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/redis"
 )
@@ -102,6 +103,10 @@ func setupMetricsRoutes(router *gin.Engine) {
 
 // getDeploymentMetrics returns comprehensive deployment statistics
 func getDeploymentMetrics(c *gin.Context) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
 	stats := DeploymentStats{
 		GitHub: GitHubStats{},
 		Helm:   HelmStats{},
@@ -109,7 +114,7 @@ func getDeploymentMetrics(c *gin.Context) {
 	}
 
 	// Get GitHub deployment statistics
-	githubStats, err := getGitHubDeploymentStats()
+	githubStats, err := getGitHubDeploymentStats(its.Context)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get GitHub deployment stats",
@@ -121,7 +126,7 @@ func getDeploymentMetrics(c *gin.Context) {
 	stats.Total += githubStats.Count
 
 	// Get Helm deployment statistics
-	helmStats, err := getHelmDeploymentStats()
+	helmStats, err := getHelmDeploymentStats(its.Context)
 	if err != nil {
 		// Don't fail if Helm stats are unavailable, just log the error
 		stats.Helm = HelmStats{Count: 0}
@@ -137,11 +142,11 @@ func getDeploymentMetrics(c *gin.Context) {
 }
 
 // getGitHubDeploymentStats calculates GitHub deployment statistics
-func getGitHubDeploymentStats() (GitHubStats, error) {
+func getGitHubDeploymentStats(itsContext string) (GitHubStats, error) {
 	stats := GitHubStats{}
 
 	// Get GitHub deployments from ConfigMap
-	deployments, err := k8s.GetGithubDeployments("its1")
+	deployments, err := k8s.GetGithubDeployments(itsContext)
 	if err != nil {
 		return stats, fmt.Errorf("failed to get GitHub deployments: %v", err)
 	}
@@ -171,13 +176,13 @@ func getGitHubDeploymentStats() (GitHubStats, error) {
 }
 
 // getHelmDeploymentStats calculates Helm deployment statistics
-func getHelmDeploymentStats() (HelmStats, error) {
+func getHelmDeploymentStats(itsContext string) (HelmStats, error) {
 	stats := HelmStats{}
 
 	// Try to get Helm deployments from ConfigMap
 	helmConfigMapName := "kubestellar-helm-deployments" // Adjust based on your actual ConfigMap name
 
-	helmData, err := k8s.GetConfigMapData("its1", helmConfigMapName)
+	helmData, err := k8s.GetConfigMapData(itsContext, helmConfigMapName)
 	if err != nil {
 		return stats, fmt.Errorf("failed to get Helm deployment data: %v", err)
 	}
@@ -211,6 +216,10 @@ func getHelmDeploymentStats() (HelmStats, error) {
 
 // getSystemMetrics returns comprehensive system metrics
 func getSystemMetrics(c *gin.Context) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
 	// Get component statuses
 	components := make(map[string]ComponentStatus)
 
@@ -218,7 +227,7 @@ func getSystemMetrics(c *gin.Context) {
 	components["redis"] = checkRedisStatus()
 
 	// Check Kubernetes
-	components["kubernetes"] = checkKubernetesStatus()
+	components["kubernetes"] = checkKubernetesStatus(its.Context)
 
 	// Check GitHub API access (if configured)
 	components["github_api"] = checkGitHubAPIStatus()
@@ -264,13 +273,13 @@ func checkRedisStatus() ComponentStatus {
 }
 
 // checkKubernetesStatus checks Kubernetes connectivity
-func checkKubernetesStatus() ComponentStatus {
+func checkKubernetesStatus(itsContext string) ComponentStatus {
 	status := ComponentStatus{
 		LastChecked: time.Now(),
 	}
 
 	// Test Kubernetes connection by trying to get deployments
-	if _, err := k8s.GetGithubDeployments("its1"); err != nil {
+	if _, err := k8s.GetGithubDeployments(itsContext); err != nil {
 		status.Status = "unhealthy"
 		status.Error = err.Error()
 		return status
@@ -327,6 +336,10 @@ func getRuntimeMetrics() RuntimeMetrics {
 
 // getComponentHealth returns detailed component health information
 func getComponentHealth(c *gin.Context) {
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
 	health := gin.H{
 		"overall_status": "healthy",
 		"timestamp":      time.Now().Format(time.RFC3339),
@@ -336,7 +349,7 @@ func getComponentHealth(c *gin.Context) {
 
 	// Check each component
 	redisStatus := checkRedisStatus()
-	k8sStatus := checkKubernetesStatus()
+	k8sStatus := checkKubernetesStatus(its.Context)
 	githubStatus := checkGitHubAPIStatus()
 
 	health["components"] = gin.H{
@@ -382,7 +395,11 @@ func getComponentHealth(c *gin.Context) {
 
 // getGitHubMetrics returns detailed GitHub deployment metrics
 func getGitHubMetrics(c *gin.Context) {
-	stats, err := getGitHubDeploymentStats()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	stats, err := getGitHubDeploymentStats(its.Context)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get GitHub metrics",
@@ -407,7 +424,7 @@ func getGitHubMetrics(c *gin.Context) {
 	}
 
 	// Get recent deployments (last 10)
-	if deployments, err := k8s.GetGithubDeployments("its1"); err == nil && len(deployments) > 0 {
+	if deployments, err := k8s.GetGithubDeployments(its.Context); err == nil && len(deployments) > 0 {
 		recentCount := 10
 		if len(deployments) < recentCount {
 			recentCount = len(deployments)
@@ -422,7 +439,11 @@ func getGitHubMetrics(c *gin.Context) {
 
 // getHelmMetrics returns detailed Helm deployment metrics
 func getHelmMetrics(c *gin.Context) {
-	stats, err := getHelmDeploymentStats()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	stats, err := getHelmDeploymentStats(its.Context)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get Helm metrics",
@@ -466,7 +487,11 @@ func getRedisMetrics(c *gin.Context) {
 
 // getKubernetesMetrics returns Kubernetes-specific metrics
 func getKubernetesMetrics(c *gin.Context) {
-	status := checkKubernetesStatus()
+	its, ok := hub.MustSelectITS(c)
+	if !ok {
+		return
+	}
+	status := checkKubernetesStatus(its.Context)
 
 	// Try to get additional Kubernetes information
 	metrics := gin.H{
@@ -478,14 +503,14 @@ func getKubernetesMetrics(c *gin.Context) {
 	configMaps := gin.H{}
 
 	// Check GitHub ConfigMap
-	if _, err := k8s.GetConfigMapData("its1", k8s.GitHubConfigMapName); err == nil {
+	if _, err := k8s.GetConfigMapData(its.Context, k8s.GitHubConfigMapName); err == nil {
 		configMaps["github"] = "accessible"
 	} else {
 		configMaps["github"] = "not_found"
 	}
 
 	// Check Helm ConfigMap
-	if _, err := k8s.GetConfigMapData("its1", k8s.HelmConfigMapName); err == nil {
+	if _, err := k8s.GetConfigMapData(its.Context, k8s.HelmConfigMapName); err == nil {
 		configMaps["helm"] = "accessible"
 	} else {
 		configMaps["helm"] = "not_found"
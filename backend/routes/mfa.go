@@ -0,0 +1,606 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/auth"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/pkg/config"
+	"github.com/kubestellar/ui/backend/utils"
+	"go.uber.org/zap"
+)
+
+// recoveryCodeCount is the number of recovery codes handed out on enrollment
+const recoveryCodeCount = 10
+
+var errTOTPNotPending = errors.New("no pending TOTP enrollment")
+
+// mfaIssuer is the issuer name shown by authenticator apps
+func mfaIssuer() string {
+	return config.GetEnv("MFA_ISSUER", "KubeStellar")
+}
+
+// sendMFAChallenge responds to a correct password with an interim token that
+// has to be upgraded through /login/mfa or /login/mfa/enroll before any access
+// token is issued.
+func sendMFAChallenge(c *gin.Context, userID int, username, tokenType string) {
+	mfaToken, err := utils.GenerateMFAToken(username, userID, tokenType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             false,
+		"mfa_required":        true,
+		"enrollment_required": tokenType == utils.TokenTypeMFAEnroll,
+		"mfa_token":           mfaToken,
+		"expires_in":          int(utils.MFATokenExpiration.Seconds()),
+	})
+}
+
+// startTOTPEnrollment generates and stores a pending secret for the user
+func startTOTPEnrollment(userID int, username string) (string, string, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := models.SetPendingTOTPSecret(userID, secret); err != nil {
+		return "", "", err
+	}
+	return secret, utils.TOTPProvisioningURI(mfaIssuer(), username, secret), nil
+}
+
+// confirmTOTPEnrollment checks the first code from the authenticator app
+// against the pending secret, enables TOTP and returns fresh recovery codes
+func confirmTOTPEnrollment(userID int, code string) ([]string, error) {
+	mfa, err := models.GetUserMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled || mfa.Secret == "" {
+		return nil, errTOTPNotPending
+	}
+
+	step, ok := utils.ValidateTOTPCode(mfa.Secret, code, time.Now())
+	if !ok {
+		return nil, nil
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.EnableTOTP(userID, step, recoveryCodes); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. TOTP codes can only be used once.
+func verifySecondFactor(mfa *models.UserMFA, code string) (bool, error) {
+	if !mfa.Enabled || mfa.Secret == "" {
+		return false, nil
+	}
+
+	if step, ok := utils.ValidateTOTPCode(mfa.Secret, code, time.Now()); ok {
+		return models.ConsumeTOTPStep(mfa.UserID, step)
+	}
+
+	used, err := models.UseRecoveryCode(mfa.UserID, code)
+	if err != nil {
+		return false, err
+	}
+	if used {
+		log.LogInfo("recovery code used for login", zap.Int("userID", mfa.UserID))
+	}
+	return used, nil
+}
+
+// completeLogin issues access and refresh tokens once all factors are verified
+func completeLogin(c *gin.Context, userID int, extra gin.H) {
+	user, err := auth.Users.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	permissions := user.Permissions
+	if user.IsAdmin && len(permissions) == 0 {
+		permissions = defaultAdminPermissions()
+	}
+
+	recordLoginSuccess(user.Username)
+
+	accessToken, refreshToken, err := issueTokens(user.ID, user.Username, user.IsAdmin, permissions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	response := gin.H{
+		"success":      true,
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"user": gin.H{
+			"username":    user.Username,
+			"is_admin":    user.IsAdmin,
+			"permissions": permissions,
+		},
+	}
+	// The UI sends the user to the change-password screen; every other API
+	// call is refused by RequireCurrentPassword until then
+	if status, err := models.GetPasswordStatus(user.ID); err == nil {
+		required, reason := utils.GetPasswordPolicy().ChangeRequired(status.MustChange, status.ChangedAt, time.Now())
+		response["password_change_required"] = required
+		if required {
+			response["password_change_reason"] = reason
+		}
+	}
+	for k, v := range extra {
+		response[k] = v
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ===================================
+// Login-time MFA Handlers
+// ===================================
+
+// MFALoginHandler exchanges an interim MFA token and a valid code for access tokens
+func MFALoginHandler(c *gin.Context) {
+	var payload struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	claims, err := utils.ValidateMFAToken(strings.TrimSpace(payload.MFAToken), utils.TokenTypeMFAPending)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	if wait := checkLoginAllowed(claims.Username, clientIP); wait > 0 {
+		sendTooManyAttempts(c, wait)
+		return
+	}
+
+	mfa, err := models.GetUserMFA(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	ok, err := verifySecondFactor(mfa, payload.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		recordLoginFailure(claims.Username, clientIP, "invalid_mfa_code")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	completeLogin(c, claims.UserID, nil)
+}
+
+// MFALoginEnrollHandler starts TOTP enrollment for a user who is required to
+// use two-factor authentication but has not enrolled yet
+func MFALoginEnrollHandler(c *gin.Context) {
+	var payload struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	claims, err := utils.ValidateMFAToken(strings.TrimSpace(payload.MFAToken), utils.TokenTypeMFAEnroll)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	secret, uri, err := startTOTPEnrollment(claims.UserID, claims.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// MFALoginEnrollVerifyHandler completes enrollment started during login and
+// issues access tokens together with the new recovery codes
+func MFALoginEnrollVerifyHandler(c *gin.Context) {
+	var payload struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	claims, err := utils.ValidateMFAToken(strings.TrimSpace(payload.MFAToken), utils.TokenTypeMFAEnroll)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	if wait := checkLoginAllowed(claims.Username, clientIP); wait > 0 {
+		sendTooManyAttempts(c, wait)
+		return
+	}
+
+	recoveryCodes, err := confirmTOTPEnrollment(claims.UserID, payload.Code)
+	if errors.Is(err, errTOTPNotPending) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No enrollment in progress"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete enrollment"})
+		return
+	}
+	if recoveryCodes == nil {
+		recordLoginFailure(claims.Username, clientIP, "invalid_mfa_code")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	completeLogin(c, claims.UserID, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// ===================================
+// Self-service MFA Handlers
+// ===================================
+
+func currentUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := userID.(int)
+	return id, ok
+}
+
+// GetMyMFAHandler returns the two-factor state of the current user
+func GetMyMFAHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	mfa, err := models.GetUserMFA(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA state"})
+		return
+	}
+
+	required, err := models.IsMFARequiredForUser(mfa)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA policy"})
+		return
+	}
+	mfa.Required = required
+
+	c.JSON(http.StatusOK, gin.H{"mfa": mfa})
+}
+
+// StartMyMFAEnrollmentHandler generates a new TOTP secret for the current user
+func StartMyMFAEnrollmentHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	username := c.GetString("username")
+
+	mfa, err := models.GetUserMFA(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA state"})
+		return
+	}
+	if mfa.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, uri, err := startTOTPEnrollment(userID, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// VerifyMyMFAEnrollmentHandler enables TOTP for the current user after checking
+// the first code from the authenticator app
+func VerifyMyMFAEnrollmentHandler(c *gin.Context) {
+	var payload struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	recoveryCodes, err := confirmTOTPEnrollment(userID, payload.Code)
+	if errors.Is(err, errTOTPNotPending) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No enrollment in progress"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete enrollment"})
+		return
+	}
+	if recoveryCodes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableMyMFAHandler turns off TOTP for the current user unless it is enforced
+func DisableMyMFAHandler(c *gin.Context) {
+	var payload struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	mfa, err := models.GetUserMFA(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA state"})
+		return
+	}
+
+	required, err := models.IsMFARequiredForUser(mfa)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA policy"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for this account"})
+		return
+	}
+
+	valid, err := verifySecondFactor(mfa, payload.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if err := models.DisableTOTP(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateMyRecoveryCodesHandler replaces the current user's recovery codes
+func RegenerateMyRecoveryCodesHandler(c *gin.Context) {
+	var payload struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	mfa, err := models.GetUserMFA(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA state"})
+		return
+	}
+
+	valid, err := verifySecondFactor(mfa, payload.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	if err := models.ReplaceRecoveryCodes(userID, recoveryCodes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// ===================================
+// Admin MFA Handlers
+// ===================================
+
+// GetMFAPolicyHandler returns whether two-factor authentication is enforced globally
+func GetMFAPolicyHandler(c *gin.Context) {
+	required, err := models.IsMFARequiredGlobally()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve MFA policy",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"required": required})
+}
+
+// SetMFAPolicyHandler enforces or relaxes two-factor authentication for all users
+func SetMFAPolicyHandler(c *gin.Context) {
+	var payload struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if err := models.SetMFARequiredGlobally(*payload.Required); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update MFA policy",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "MFA policy updated successfully",
+		"required": *payload.Required,
+	})
+}
+
+// GetUserMFAHandler returns the two-factor state of a user (admin only)
+func GetUserMFAHandler(c *gin.Context) {
+	user, ok := lookupUserParam(c)
+	if !ok {
+		return
+	}
+
+	mfa, err := models.GetUserMFA(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"username": user.Username,
+		"mfa":      mfa,
+	})
+}
+
+// SetUserMFARequirementHandler enforces or relaxes two-factor authentication for a user (admin only)
+func SetUserMFARequirementHandler(c *gin.Context) {
+	var payload struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	user, ok := lookupUserParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.SetMFARequired(user.ID, *payload.Required); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update MFA requirement",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "MFA requirement updated successfully",
+		"username": user.Username,
+		"required": *payload.Required,
+	})
+}
+
+// ResetUserMFAHandler removes a user's TOTP enrollment, e.g. after a lost device (admin only)
+func ResetUserMFAHandler(c *gin.Context) {
+	user, ok := lookupUserParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DisableTOTP(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reset two-factor authentication",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adminName, _ := c.Get("username")
+	log.LogInfo("two-factor authentication reset",
+		zap.String("username", user.Username),
+		zap.Any("by", adminName))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Two-factor authentication reset successfully",
+		"username": user.Username,
+	})
+}
+
+func lookupUserParam(c *gin.Context) (*models.User, bool) {
+	user, err := auth.Users.GetUser(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil, false
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
@@ -25,6 +25,8 @@ func init() {
 	prometheus.MustRegister(telemetry.GithubDeploymentsTotal)
 	prometheus.MustRegister(telemetry.WebsocketConnectionUpgradedSuccess)
 	prometheus.MustRegister(telemetry.WebsocketConnectionUpgradedFailed)
+	prometheus.MustRegister(telemetry.LoginAttemptsTotal)
+	prometheus.MustRegister(telemetry.AccountLockoutsTotal)
 }
 
 func SetupRoutes(router *gin.Engine) {
@@ -37,6 +39,7 @@ func SetupRoutes(router *gin.Engine) {
 	getWecsResources(router)
 	setupInstallerRoutes(router)
 	setupWdsCookiesRoute(router)
+	setupHubRoutes(router)
 	setupGitopsRoutes(router)
 	setupHelmRoutes(router)
 	setupGitHubRoutes(router)
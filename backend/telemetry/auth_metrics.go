@@ -0,0 +1,25 @@
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// These metrics track authentication attempts against the UI backend.
+
+var (
+	LoginAttemptsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_attempts_total",
+			Help: "Total number of login attempts by result",
+		},
+		[]string{"result", "reason"},
+	)
+
+	AccountLockoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_account_lockouts_total",
+			Help: "Total number of temporary login lockouts",
+		},
+		[]string{"subject"},
+	)
+)
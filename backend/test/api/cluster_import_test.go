@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/clusters/import", api.ImportClusterHandler)
+	router.POST("/clusters/import-by-url", api.ImportClusterByURLHandler)
+	return router
+}
+
+func postKubeconfig(t *testing.T, router *gin.Engine, fields map[string]string, kubeconfig string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	if kubeconfig != "" {
+		part, err := writer.CreateFormFile("kubeconfig", "kubeconfig")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(kubeconfig))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/clusters/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportClusterHandler_Validation(t *testing.T) {
+	router := importRouter()
+
+	w := postKubeconfig(t, router, map[string]string{"name": "edge"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "kubeconfig file is required")
+
+	w = postKubeconfig(t, router, nil, "not: [a kubeconfig")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postKubeconfig(t, router, map[string]string{"context": "missing"}, unreachableKubeconfig)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "context 'missing' not found")
+}
+
+func TestImportClusterByURLHandler_Unreachable(t *testing.T) {
+	router := importRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/clusters/import-by-url", strings.NewReader(`{"api_url": "not a url"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/clusters/import-by-url",
+		strings.NewReader(`{"api_url": "https://127.0.0.1:1", "token": "token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "failed to reach cluster with provided credentials", response["error"])
+}
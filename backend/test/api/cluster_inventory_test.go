@@ -0,0 +1,25 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterInventoryRejectsInvalidQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/clusters/inventory", api.GetClusterInventoryHandler)
+
+	for _, query := range []string{"sort=size", "order=up", "available=maybe", "selector=env+in+prod", "its=its9"} {
+		t.Run(query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/clusters/inventory?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -44,6 +44,26 @@ func TestDetachClusterHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "Invalid request payload, clusterName is required", // Match actual error
 		},
+		{
+			name: "Drain and force combined",
+			requestBody: map[string]interface{}{
+				"clusterName": "test-cluster",
+				"drain":       true,
+				"force":       true,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "drain and force cannot be combined",
+		},
+		{
+			name: "Drain timeout out of range",
+			requestBody: map[string]interface{}{
+				"clusterName":         "test-cluster",
+				"drain":               true,
+				"drainTimeoutSeconds": 7200,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "drainTimeoutSeconds",
+		},
 		{
 			name:           "Invalid request body",
 			requestBody:    nil,
@@ -134,6 +154,17 @@ func TestGetDetachmentLogsHandler(t *testing.T) {
 	}
 }
 
+func TestGetDetachImpactHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "cluster", Value: ""}}
+	c.Request, _ = http.NewRequest(http.MethodGet, "/clusters/detach/impact/", nil)
+
+	api.GetDetachImpactHandler(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // Note: WebSocket handlers are more complex to test and would require special setup
 // For now, we'll focus on the main HTTP handlers. WebSocket testing would require
 // additional tools and setup to properly test the WebSocket connections.
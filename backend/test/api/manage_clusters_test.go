@@ -26,7 +26,7 @@ func TestGetManagedClustersHandler(t *testing.T) {
 		{
 			name:           "Custom context",
 			context:        "test-context",
-			expectedStatus: http.StatusInternalServerError, // Expected to fail in test environment
+			expectedStatus: http.StatusBadRequest, // Not a registered ITS
 			expectError:    true,
 		},
 	}
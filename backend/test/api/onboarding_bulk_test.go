@@ -0,0 +1,184 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unreachableKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://127.0.0.1:1
+  name: edge
+contexts:
+- context:
+    cluster: edge
+    user: edge
+  name: edge
+current-context: edge
+users:
+- name: edge
+  user:
+    token: token
+`
+
+func bulkRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/clusters/onboard/bulk", api.BulkOnboardClustersHandler)
+	router.GET("/clusters/onboard/bulk/:id", api.GetBulkOnboardingHandler)
+	router.GET("/ws/onboarding/bulk", api.WSBulkOnboardingHandler)
+	return router
+}
+
+func postBulk(t *testing.T, router *gin.Engine, manifest string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodPost, "/clusters/onboard/bulk", strings.NewReader(manifest))
+	req.Header.Set("Content-Type", "application/yaml")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w, response
+}
+
+// waitForBulk polls a bulk onboarding until it finished and returns its summary
+func waitForBulk(t *testing.T, router *gin.Engine, batchID string) map[string]interface{} {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clusters/onboard/bulk/"+batchID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var status map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		if status["finished"] == true {
+			return status["summary"].(map[string]interface{})
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("bulk onboarding did not finish")
+	return nil
+}
+
+func TestBulkOnboardRejectsInvalidManifests(t *testing.T) {
+	router := bulkRouter()
+	tests := []struct {
+		name     string
+		manifest string
+		errorMsg string
+	}{
+		{name: "empty", manifest: "", errorMsg: "manifest of clusters is required"},
+		{name: "no clusters", manifest: "clusters: []", errorMsg: "no clusters"},
+		{name: "missing name", manifest: "clusters:\n- labels: {env: edge}", errorMsg: "has no name"},
+		{name: "invalid name", manifest: "clusters:\n- name: Edge_1", errorMsg: "invalid cluster name"},
+		{name: "duplicate", manifest: "clusters:\n- name: edge1\n- name: edge1", errorMsg: "more than once"},
+		{name: "missing upload", manifest: "clusters:\n- name: edge1\n  kubeconfigRef: edge.yaml", errorMsg: "was not uploaded"},
+		{name: "concurrency", manifest: "concurrency: 100\nclusters:\n- name: edge1", errorMsg: "concurrency"},
+		{name: "invalid label", manifest: "clusters:\n- name: edge1\n  labels: {\"not a key\": edge}", errorMsg: "violate the label schema"},
+		{name: "too large", manifest: strings.Repeat("# padding\n", 4<<20), errorMsg: "request body too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, response := postBulk(t, router, tt.manifest)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, response["error"], tt.errorMsg)
+		})
+	}
+}
+
+func TestBulkOnboardReportsFailedClusters(t *testing.T) {
+	router := bulkRouter()
+	manifest, err := json.Marshal(api.BulkOnboardingManifest{
+		Concurrency: 2,
+		Clusters: []api.BulkOnboardingCluster{
+			{Name: "bulk-invalid", Kubeconfig: "not a kubeconfig"},
+			{Name: "bulk-no-context", Kubeconfig: unreachableKubeconfig, Context: "missing"},
+			{Name: "bulk-unreachable", Kubeconfig: unreachableKubeconfig, Labels: map[string]string{"env": "edge"}},
+		},
+	})
+	require.NoError(t, err)
+
+	w, response := postBulk(t, router, string(manifest))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, float64(2), response["concurrency"])
+	batchID := response["batchId"].(string)
+
+	summary := waitForBulk(t, router, batchID)
+	assert.Equal(t, float64(3), summary["total"])
+	assert.ElementsMatch(t, []interface{}{"bulk-invalid", "bulk-no-context", "bulk-unreachable"}, summary["failed"])
+	assert.Empty(t, summary["succeeded"])
+	assert.Empty(t, summary["needsCsrApproval"])
+}
+
+func TestBulkOnboardMultipartAndStream(t *testing.T) {
+	router := bulkRouter()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("manifest", "clusters:\n- name: bulk-uploaded\n  kubeconfigRef: edge.yaml\n  context: missing"))
+	file, err := form.CreateFormFile("kubeconfigs", "edge.yaml")
+	require.NoError(t, err)
+	_, err = file.Write([]byte(unreachableKubeconfig))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/clusters/onboard/bulk", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	batchID := response["batchId"].(string)
+	waitForBulk(t, router, batchID)
+
+	// A finished batch replays its events and closes the stream
+	server := httptest.NewServer(router)
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/onboarding/bulk?batch="+batchID, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var events []api.BulkOnboardingEvent
+	for {
+		var event api.BulkOnboardingEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			break
+		}
+		events = append(events, event)
+	}
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, api.BulkEventSummary, last.Type)
+	require.NotNil(t, last.Summary)
+	assert.Equal(t, []string{"bulk-uploaded"}, last.Summary.Failed)
+
+	states := []string{}
+	for _, event := range events {
+		assert.Equal(t, batchID, event.BatchID)
+		if event.Type == api.BulkEventState {
+			states = append(states, event.Status)
+		}
+	}
+	assert.Equal(t, []string{api.BulkOnboardingRunning, api.BulkOnboardingFailed}, states)
+}
+
+func TestBulkOnboardingNotFound(t *testing.T) {
+	router := bulkRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clusters/onboard/bulk/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/audit"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionFor(t *testing.T) {
+	assert.Equal(t, "cluster.onboard", audit.ActionFor(http.MethodPost, "/clusters/onboard"))
+	assert.Equal(t, "bindingpolicy.delete", audit.ActionFor(http.MethodDelete, "/api/bp/delete/:name"))
+	assert.Equal(t, "pod.exec", audit.ActionFor(http.MethodGet, "/ws/pod/:namespace/:pod/shell/:container"))
+	assert.Equal(t, "post /api/unknown", audit.ActionFor(http.MethodPost, "/api/unknown"))
+}
+
+func TestShouldAudit(t *testing.T) {
+	assert.True(t, audit.ShouldAudit(http.MethodPatch, "/api/managedclusters/labels"))
+	assert.True(t, audit.ShouldAudit(http.MethodDelete, "/api/unknown/:id"))
+	assert.True(t, audit.ShouldAudit(http.MethodGet, "/ws/pod/:namespace/:pod/shell/:container"))
+	assert.False(t, audit.ShouldAudit(http.MethodGet, "/api/bp"))
+	assert.False(t, audit.ShouldAudit(http.MethodPost, "/api/bp/generate-yaml"))
+}
+
+func TestMiddlewarePreservesBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(audit.Middleware())
+
+	var received string
+	router.POST("/api/namespaces/create", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = string(body)
+		c.Status(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/namespaces/create", bytes.NewBufferString(`{"name":"demo"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"name":"demo"}`, received)
+}
+
+func TestMiddlewarePreservesLargeBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(audit.Middleware())
+
+	var received int
+	router.POST("/api/bp/import", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = len(body)
+		c.Status(http.StatusOK)
+	})
+
+	payload := strings.Repeat("a", 3<<20)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/bp/import", strings.NewReader(payload))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, len(payload), received)
+}
+
+func TestWriteCSV(t *testing.T) {
+	userID := 7
+	entries := []models.AuditEntry{
+		{
+			ID:         1,
+			OccurredAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			UserID:     &userID,
+			Username:   "alice",
+			Action:     "cluster.detach",
+			Method:     http.MethodPost,
+			Path:       "/clusters/detach",
+			StatusCode: http.StatusOK,
+			Result:     models.AuditResultSuccess,
+			Error:      "contains, a comma",
+		},
+		{ID: 2, Username: "anonymous", Action: "auth.login", Result: models.AuditResultFailure},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, audit.WriteCSV(&buf, entries))
+
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "id", records[0][0])
+	assert.Equal(t, []string{"1", "2025-01-02T03:04:05Z", "7", "alice", "cluster.detach"}, records[1][:5])
+	assert.Equal(t, "contains, a comma", records[1][14])
+	assert.Equal(t, "", records[2][2])
+}
@@ -0,0 +1,186 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	auth "github.com/kubestellar/ui/backend/auth"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryStore is an in-memory UserStore for exercising import and export
+type memoryStore struct {
+	users      map[string]*models.User
+	mustChange map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: map[string]*models.User{}, mustChange: map[string]bool{}}
+}
+
+func (m *memoryStore) GetUser(username string) (*models.User, error) {
+	return m.users[username], nil
+}
+
+func (m *memoryStore) GetUserByID(id int) (*models.User, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, auth.ErrUserNotFound
+}
+
+func (m *memoryStore) Authenticate(username, password string) (*models.User, error) {
+	u := m.users[username]
+	if u == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	if u.Password != "hashed:"+password {
+		return nil, auth.ErrInvalidPassword
+	}
+	return u, nil
+}
+
+func (m *memoryStore) ListUsers() ([]*models.User, error) {
+	var users []*models.User
+	for _, u := range m.users {
+		users = append(users, &models.User{Username: u.Username, IsAdmin: u.IsAdmin, Permissions: u.Permissions})
+	}
+	return users, nil
+}
+
+func (m *memoryStore) CreateUser(user auth.NewUser) (*models.User, error) {
+	if _, ok := m.users[user.Username]; ok {
+		return nil, auth.ErrUserExists
+	}
+	password := user.PasswordHash
+	if password == "" {
+		password = "hashed:" + user.Password
+	}
+	created := &models.User{Username: user.Username, Password: password, IsAdmin: user.IsAdmin, Permissions: user.Permissions}
+	m.users[user.Username] = created
+	m.mustChange[user.Username] = user.MustChangePassword
+	return created, nil
+}
+
+func (m *memoryStore) RenameUser(username, newUsername string) error {
+	m.users[newUsername] = m.users[username]
+	delete(m.users, username)
+	return nil
+}
+
+func (m *memoryStore) SetPassword(username, password string) error {
+	m.users[username].Password = "hashed:" + password
+	return nil
+}
+
+func (m *memoryStore) IsPasswordReused(username, password string) (bool, error) {
+	return m.users[username].Password == "hashed:"+password, nil
+}
+
+func (m *memoryStore) SetMustChangePassword(username string, mustChange bool) error {
+	m.mustChange[username] = mustChange
+	return nil
+}
+
+func (m *memoryStore) SetPasswordHash(username, passwordHash string) error {
+	m.users[username].Password = passwordHash
+	return nil
+}
+
+func (m *memoryStore) SetAdmin(username string, isAdmin bool) error {
+	m.users[username].IsAdmin = isAdmin
+	return nil
+}
+
+func (m *memoryStore) SetPermissions(username string, permissions map[string]string) error {
+	m.users[username].Permissions = permissions
+	return nil
+}
+
+func (m *memoryStore) DeleteUser(username string) error {
+	delete(m.users, username)
+	return nil
+}
+
+func TestConvertPermissions(t *testing.T) {
+	isAdmin, perms := auth.ConvertPermissions([]string{"read", "write", "admin"})
+	assert.True(t, isAdmin)
+	for _, component := range auth.Components {
+		assert.Equal(t, "write", perms[component])
+	}
+
+	isAdmin, perms = auth.ConvertPermissions([]string{"read", "dashboard:write"})
+	assert.False(t, isAdmin)
+	assert.Equal(t, "read", perms["resources"])
+	assert.Equal(t, "write", perms["dashboard"])
+}
+
+func TestImportUsers(t *testing.T) {
+	store := newMemoryStore()
+	store.users["admin"] = &models.User{Username: "admin", Password: "existing", IsAdmin: true}
+
+	cfg := &auth.Config{}
+	cfg.AddUser("admin", "Rotated-Pass2", []string{"admin"})
+	cfg.AddUser("alice", "Secret-Pass1", []string{"read"})
+	cfg.AddUser("broken", "", []string{"read"})
+	cfg.AddUser("weak", "secret", []string{"read"})
+
+	report := auth.ImportUsers(store, cfg, false)
+	assert.Equal(t, []string{"alice"}, report.Created)
+	assert.Equal(t, []string{"admin"}, report.Skipped)
+	assert.Contains(t, report.Failed, "broken")
+	assert.Contains(t, report.Failed, "weak")
+	assert.NotContains(t, store.users, "weak")
+	assert.Equal(t, "existing", store.users["admin"].Password)
+	assert.Equal(t, "hashed:Secret-Pass1", store.users["alice"].Password)
+	assert.Equal(t, "read", store.users["alice"].Permissions["resources"])
+	assert.True(t, store.mustChange["alice"], "plaintext passwords must be changed on first login")
+
+	report = auth.ImportUsers(store, cfg, true)
+	assert.ElementsMatch(t, []string{"admin", "alice"}, report.Updated)
+	assert.Equal(t, "hashed:Rotated-Pass2", store.users["admin"].Password)
+	assert.True(t, store.mustChange["admin"])
+}
+
+func TestExportAndReadConfigMap(t *testing.T) {
+	store := newMemoryStore()
+	store.users["alice"] = &models.User{
+		Username:    "alice",
+		Password:    "$2a$14$hash",
+		IsAdmin:     true,
+		Permissions: map[string]string{"resources": "write"},
+	}
+
+	exported, err := auth.ExportUsers(store)
+	assert.NoError(t, err)
+
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	missing, err := auth.ReadConfigMap(ctx, clientset, auth.Namespace, auth.ConfigMapName)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	assert.NoError(t, auth.WriteConfigMap(ctx, clientset, auth.Namespace, auth.ConfigMapName, exported))
+	// Writing again updates the existing ConfigMap
+	assert.NoError(t, auth.WriteConfigMap(ctx, clientset, auth.Namespace, auth.ConfigMapName, exported))
+
+	loaded, err := auth.ReadConfigMap(ctx, clientset, auth.Namespace, auth.ConfigMapName)
+	assert.NoError(t, err)
+	user, ok := loaded.GetUser("alice")
+	assert.True(t, ok)
+	assert.Empty(t, user.Password)
+	assert.Equal(t, "$2a$14$hash", user.PasswordHash)
+	assert.Equal(t, []string{"admin", "resources:write"}, user.Permissions)
+
+	// Round-tripping the export keeps the hash and role
+	target := newMemoryStore()
+	report := auth.ImportUsers(target, loaded, false)
+	assert.Equal(t, []string{"alice"}, report.Created)
+	assert.Equal(t, "$2a$14$hash", target.users["alice"].Password)
+	assert.True(t, target.users["alice"].IsAdmin)
+}
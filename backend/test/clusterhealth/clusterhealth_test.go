@@ -0,0 +1,251 @@
+package clusterhealth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/clusterhealth"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var base = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func managedCluster(name string, available, joined string, changed time.Time) *unstructured.Unstructured {
+	condition := func(conditionType, status string) interface{} {
+		return map[string]interface{}{
+			"type":               conditionType,
+			"status":             status,
+			"lastTransitionTime": changed.Format(time.RFC3339),
+		}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				condition(clusterhealth.ConditionJoined, joined),
+				condition(clusterhealth.ConditionAvailable, available),
+			},
+		},
+	}}
+}
+
+func TestObservationFromCluster(t *testing.T) {
+	renewed := metav1.NewMicroTime(base.Add(-10 * time.Second))
+	lease := &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{RenewTime: &renewed}}
+
+	obs := clusterhealth.ObservationFromCluster("its1", managedCluster("edge1", "False", "True", base.Add(-time.Minute)), lease)
+	assert.Equal(t, "edge1", obs.ClusterName)
+	assert.False(t, obs.Available)
+	assert.True(t, obs.Joined)
+	assert.True(t, obs.AvailableChanged.Equal(base.Add(-time.Minute)))
+	require.NotNil(t, obs.LeaseRenewed)
+	assert.True(t, obs.LeaseRenewed.Equal(renewed.Time))
+
+	obs = clusterhealth.ObservationFromCluster("its1", managedCluster("edge2", "True", "True", base), nil)
+	assert.Nil(t, obs.LeaseRenewed)
+}
+
+func TestTrackerRecordsTransitions(t *testing.T) {
+	tracker := clusterhealth.NewTracker(3*time.Minute, 5*time.Minute)
+	renewed := base.Add(-5 * time.Second)
+	obs := clusterhealth.Observation{
+		ITSContext: "its1", ClusterName: "edge1", Available: true, Joined: true,
+		AvailableChanged: base.Add(-time.Hour), JoinedChanged: base.Add(-time.Hour), LeaseRenewed: &renewed,
+	}
+
+	// The first observation is always recorded
+	sample := tracker.Observe(obs, base)
+	require.NotNil(t, sample)
+	assert.True(t, sample.Transition)
+	require.NotNil(t, sample.HeartbeatAgeSeconds)
+	assert.Equal(t, int64(5), *sample.HeartbeatAgeSeconds)
+	state, ok := tracker.Get("its1", "edge1")
+	require.True(t, ok)
+	assert.True(t, state.AvailableSince.Equal(base.Add(-time.Hour)))
+
+	// Nothing changed
+	renewed = base.Add(25 * time.Second)
+	assert.Nil(t, tracker.Observe(obs, base.Add(30*time.Second)))
+
+	// The cluster becomes unavailable; the condition time is used
+	obs.Available = false
+	obs.AvailableChanged = base.Add(50 * time.Second)
+	sample = tracker.Observe(obs, base.Add(time.Minute))
+	require.NotNil(t, sample)
+	assert.True(t, sample.Transition)
+	assert.False(t, sample.Available)
+	assert.True(t, sample.ObservedAt.Equal(base.Add(50*time.Second)))
+
+	// The heartbeat goes stale once the lease is older than the threshold
+	sample = tracker.Observe(obs, base.Add(5*time.Minute))
+	require.NotNil(t, sample)
+	assert.True(t, sample.HeartbeatStale)
+	state, _ = tracker.Get("its1", "edge1")
+	assert.True(t, state.HeartbeatSince.Equal(renewed.Add(3*time.Minute)))
+
+	// Unchanged clusters are sampled once per sample interval
+	assert.Nil(t, tracker.Observe(obs, base.Add(9*time.Minute)))
+	sample = tracker.Observe(obs, base.Add(11*time.Minute))
+	require.NotNil(t, sample)
+	assert.False(t, sample.Transition)
+	assert.True(t, sample.ObservedAt.Equal(base.Add(11*time.Minute)))
+}
+
+func TestTrackerRestoreAndForget(t *testing.T) {
+	tracker := clusterhealth.NewTracker(3*time.Minute, 5*time.Minute)
+	tracker.Restore([]*models.ClusterHealthSample{
+		{ITSContext: "its1", ClusterName: "edge1", Available: false, Joined: true, ObservedAt: base},
+		{ITSContext: "its1", ClusterName: "edge2", Available: true, Joined: true, ObservedAt: base},
+	})
+
+	// A restored state that did not change is not recorded again
+	obs := clusterhealth.Observation{ITSContext: "its1", ClusterName: "edge1", Joined: true}
+	assert.Nil(t, tracker.Observe(obs, base.Add(time.Minute)))
+
+	tracker.Forget("its1", map[string]bool{"edge1": true})
+	states := tracker.States()
+	require.Len(t, states, 1)
+	assert.Equal(t, "edge1", states[0].ClusterName)
+}
+
+func TestEvaluate(t *testing.T) {
+	now := base
+	states := []clusterhealth.ClusterState{
+		{ITSContext: "its1", ClusterName: "edge1", Joined: true, AvailableSince: now.Add(-10 * time.Minute), JoinedSince: now.Add(-time.Hour)},
+		{ITSContext: "its1", ClusterName: "edge2", Joined: true, AvailableSince: now.Add(-time.Minute), JoinedSince: now.Add(-time.Hour)},
+		{ITSContext: "its1", ClusterName: "core1", Available: true, Joined: true, AvailableSince: now.Add(-time.Hour)},
+	}
+	rules := []*models.ClusterAlertRule{
+		{ID: 1, Name: "unavailable", Condition: models.AlertUnavailable, DurationSeconds: 300, Enabled: true},
+		{ID: 2, Name: "edge-stale", Condition: models.AlertHeartbeatStale, ClusterPattern: "edge*", Enabled: true},
+		{ID: 3, Name: "disabled", Condition: models.AlertUnavailable, Enabled: false},
+	}
+
+	fire, resolve := clusterhealth.Evaluate(rules, states, nil, now)
+	assert.Empty(t, resolve)
+	require.Len(t, fire, 1)
+	assert.Equal(t, "edge1", fire[0].ClusterName)
+	assert.Equal(t, "unavailable", fire[0].RuleName)
+	assert.True(t, fire[0].Since.Equal(now.Add(-10*time.Minute)))
+	assert.Contains(t, fire[0].Message, "unavailable for 10m0s")
+
+	// An open alert is not raised twice and resolves when the condition clears
+	ruleID := int64(1)
+	open := []*models.ClusterAlert{{ID: 7, RuleID: &ruleID, ITSContext: "its1", ClusterName: "edge1"}}
+	fire, resolve = clusterhealth.Evaluate(rules, states, open, now)
+	assert.Empty(t, fire)
+	assert.Empty(t, resolve)
+
+	states[0].Available = true
+	fire, resolve = clusterhealth.Evaluate(rules, states, open, now)
+	assert.Empty(t, fire)
+	require.Len(t, resolve, 1)
+	assert.Equal(t, int64(7), resolve[0].ID)
+
+	// Alerts of deleted rules and gone clusters are resolved
+	open = []*models.ClusterAlert{
+		{ID: 8, ITSContext: "its1", ClusterName: "edge2"},
+		{ID: 9, RuleID: &ruleID, ITSContext: "its1", ClusterName: "gone"},
+	}
+	_, resolve = clusterhealth.Evaluate(rules, states, open, now)
+	assert.Len(t, resolve, 2)
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := &models.ClusterAlertRule{Name: "r", Condition: models.AlertNotJoined, Notifiers: []string{"log"}}
+	assert.NoError(t, clusterhealth.ValidateRule(valid))
+
+	tests := []struct {
+		name string
+		rule models.ClusterAlertRule
+	}{
+		{name: "no name", rule: models.ClusterAlertRule{Condition: models.AlertUnavailable}},
+		{name: "bad condition", rule: models.ClusterAlertRule{Name: "r", Condition: "down"}},
+		{name: "negative duration", rule: models.ClusterAlertRule{Name: "r", Condition: models.AlertUnavailable, DurationSeconds: -1}},
+		{name: "bad pattern", rule: models.ClusterAlertRule{Name: "r", Condition: models.AlertUnavailable, ClusterPattern: "["}},
+		{name: "unknown notifier", rule: models.ClusterAlertRule{Name: "r", Condition: models.AlertUnavailable, Notifiers: []string{"pager"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, clusterhealth.ValidateRule(&tt.rule))
+		})
+	}
+}
+
+func TestComputeAvailability(t *testing.T) {
+	from, to := base, base.Add(4*time.Hour)
+	samples := []*models.ClusterHealthSample{
+		{Available: true, Transition: true, ObservedAt: base.Add(time.Hour)},
+		{Available: false, Transition: true, ObservedAt: base.Add(2 * time.Hour)},
+		{Available: false, ObservedAt: base.Add(150 * time.Minute)},
+		{Available: true, Transition: true, ObservedAt: base.Add(3 * time.Hour)},
+	}
+
+	// Without an earlier sample the first hour is unknown
+	result := clusterhealth.ComputeAvailability(nil, samples, from, to)
+	assert.Equal(t, int64(3600), result.UnknownSeconds)
+	assert.Equal(t, int64(7200), result.AvailableSeconds)
+	assert.Equal(t, int64(3600), result.UnavailableSeconds)
+	assert.Equal(t, 3, result.Transitions)
+	require.NotNil(t, result.Percent)
+	assert.InDelta(t, 66.67, *result.Percent, 0.01)
+
+	result = clusterhealth.ComputeAvailability(&models.ClusterHealthSample{Available: false}, samples, from, to)
+	assert.Equal(t, int64(0), result.UnknownSeconds)
+	assert.InDelta(t, 50, *result.Percent, 0.01)
+
+	result = clusterhealth.ComputeAvailability(nil, nil, from, to)
+	assert.Nil(t, result.Percent)
+}
+
+type recordingNotifier struct {
+	notifications []clusterhealth.Notification
+}
+
+func (r *recordingNotifier) Name() string { return "recording" }
+
+func (r *recordingNotifier) Notify(_ context.Context, notification clusterhealth.Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func TestRegisterNotifier(t *testing.T) {
+	clusterhealth.RegisterNotifier(&recordingNotifier{})
+	assert.Contains(t, clusterhealth.Notifiers(), "log")
+	assert.Contains(t, clusterhealth.Notifiers(), "recording")
+	assert.NoError(t, clusterhealth.ValidateRule(&models.ClusterAlertRule{
+		Name: "r", Condition: models.AlertUnavailable, Notifiers: []string{"recording"},
+	}))
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received clusterhealth.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := clusterhealth.NewWebhookNotifier("webhook", server.URL)
+	alert := &models.ClusterAlert{RuleName: "unavailable", ClusterName: "edge1", Message: "down"}
+	require.NoError(t, notifier.Notify(context.Background(), clusterhealth.Notification{Status: clusterhealth.AlertFiring, Alert: alert}))
+	assert.Equal(t, clusterhealth.AlertFiring, received.Status)
+	assert.Equal(t, "edge1", received.Alert.ClusterName)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.Error(t, clusterhealth.NewWebhookNotifier("webhook", failing.URL).Notify(
+		context.Background(), clusterhealth.Notification{Status: clusterhealth.AlertResolved, Alert: alert}))
+}
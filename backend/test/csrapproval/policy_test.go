@@ -0,0 +1,198 @@
+package csrapproval_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/csrapproval"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Thursday, 1 January 2026, noon UTC
+var now = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func agentCSR(t *testing.T, cluster, commonName string) *certificatesv1.CertificateSigningRequest {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, key)
+	require.NoError(t, err)
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster + "-abc", Labels: map[string]string{ocm.ClusterNameLabel: cluster}},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			Username:   "system:serviceaccount:open-cluster-management:cluster-bootstrap",
+			Groups:     []string{"system:bootstrappers:managedcluster", "system:authenticated"},
+			SignerName: "kubernetes.io/kube-apiserver-client",
+		},
+	}
+}
+
+func request(t *testing.T, cluster string) csrapproval.Request {
+	return csrapproval.RequestFromCSR("its1", agentCSR(t, cluster, "system:open-cluster-management:"+cluster+":agent1"))
+}
+
+func rule(name string) *models.CSRApprovalRule {
+	return &models.CSRApprovalRule{Name: name, Action: models.CSRActionApprove, Priority: 100, Enabled: true}
+}
+
+func TestRequestFromCSR(t *testing.T) {
+	req := request(t, "cluster1")
+	assert.Equal(t, "its1", req.ITSContext)
+	assert.Equal(t, "cluster1-abc", req.CSRName)
+	assert.Equal(t, "cluster1", req.ClusterName)
+	assert.Equal(t, "system:open-cluster-management:cluster1:agent1", req.CommonName)
+	assert.Contains(t, req.Groups, "system:bootstrappers:managedcluster")
+}
+
+func TestEvaluateNeverApprovesForeignIdentity(t *testing.T) {
+	req := csrapproval.RequestFromCSR("its1", agentCSR(t, "cluster1", "system:open-cluster-management:cluster2:agent1"))
+
+	decision := csrapproval.Evaluate([]*models.CSRApprovalRule{rule("all")}, nil, req, now)
+	assert.Equal(t, models.CSRPending, decision.Decision)
+	assert.Nil(t, decision.Rule)
+	assert.Contains(t, decision.Reason, "does not belong to cluster cluster1")
+}
+
+func TestEvaluateFirstMatchingRuleDecides(t *testing.T) {
+	deny := rule("deny-test")
+	deny.Action = models.CSRActionDeny
+	deny.ClusterPattern = "test-*"
+	approve := rule("approve-all")
+	disabled := rule("disabled")
+	disabled.Action = models.CSRActionDeny
+	disabled.Enabled = false
+	rules := []*models.CSRApprovalRule{disabled, deny, approve}
+
+	decision := csrapproval.Evaluate(rules, nil, request(t, "test-1"), now)
+	assert.Equal(t, models.CSRDenied, decision.Decision)
+	assert.Equal(t, "deny-test", decision.Rule.Name)
+
+	decision = csrapproval.Evaluate(rules, nil, request(t, "prod-1"), now)
+	assert.Equal(t, models.CSRApproved, decision.Decision)
+	assert.Equal(t, "approve-all", decision.Rule.Name)
+	assert.Equal(t, "matched rule approve-all", decision.Reason)
+}
+
+func TestEvaluateQueuesUnmatchedRequests(t *testing.T) {
+	prod := rule("prod")
+	prod.ClusterPattern = "prod-*"
+	otherITS := rule("other-its")
+	otherITS.ITSContext = "its2"
+
+	decision := csrapproval.Evaluate([]*models.CSRApprovalRule{prod, otherITS}, nil, request(t, "edge-1"), now)
+	assert.Equal(t, models.CSRPending, decision.Decision)
+	assert.Equal(t, "no approval rule matched", decision.Reason)
+	assert.Len(t, decision.Mismatches, 2)
+	assert.Contains(t, decision.Mismatches[0], "rule prod: cluster name does not match")
+	assert.Contains(t, decision.Mismatches[1], "only applies to ITS its2")
+
+	decision = csrapproval.Evaluate(nil, nil, request(t, "edge-1"), now)
+	assert.Equal(t, models.CSRPending, decision.Decision)
+}
+
+func TestEvaluateMatchesIdentities(t *testing.T) {
+	byGroup := rule("bootstrappers")
+	byGroup.Identities = []string{"system:bootstrappers:*"}
+	decision := csrapproval.Evaluate([]*models.CSRApprovalRule{byGroup}, nil, request(t, "cluster1"), now)
+	assert.Equal(t, models.CSRApproved, decision.Decision)
+
+	byUser := rule("other-user")
+	byUser.Identities = []string{"system:serviceaccount:other:*"}
+	decision = csrapproval.Evaluate([]*models.CSRApprovalRule{byUser}, nil, request(t, "cluster1"), now)
+	assert.Equal(t, models.CSRPending, decision.Decision)
+	assert.Contains(t, decision.Mismatches[0], "is not one of")
+}
+
+func TestEvaluateRequiresAllowlist(t *testing.T) {
+	preregistered := rule("pre-registered")
+	preregistered.RequireAllowlist = true
+	rules := []*models.CSRApprovalRule{preregistered}
+	allowlist := []*models.CSRAllowlistEntry{
+		{ClusterName: "cluster1", ITSContext: "its1"},
+		{ClusterName: "cluster2", ITSContext: "its2"},
+		{ClusterName: "cluster3"},
+	}
+
+	assert.Equal(t, models.CSRApproved, csrapproval.Evaluate(rules, allowlist, request(t, "cluster1"), now).Decision)
+	assert.Equal(t, models.CSRPending, csrapproval.Evaluate(rules, allowlist, request(t, "cluster2"), now).Decision)
+	assert.Equal(t, models.CSRApproved, csrapproval.Evaluate(rules, allowlist, request(t, "cluster3"), now).Decision)
+	assert.Equal(t, models.CSRPending, csrapproval.Evaluate(rules, allowlist, request(t, "cluster4"), now).Decision)
+}
+
+func TestInWindow(t *testing.T) {
+	office := rule("office-hours")
+	office.WindowStart = "09:00"
+	office.WindowEnd = "17:00"
+	office.WindowDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+	inWindow, err := csrapproval.InWindow(office, now)
+	require.NoError(t, err)
+	assert.True(t, inWindow)
+	inWindow, err = csrapproval.InWindow(office, now.Add(6*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, inWindow)
+	// Saturday
+	inWindow, err = csrapproval.InWindow(office, now.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, inWindow)
+
+	office.Timezone = "Asia/Tokyo"
+	inWindow, err = csrapproval.InWindow(office, now)
+	require.NoError(t, err)
+	assert.False(t, inWindow, "noon UTC is 21:00 in Tokyo")
+
+	overnight := rule("overnight")
+	overnight.WindowStart = "22:00"
+	overnight.WindowEnd = "06:00"
+	for hour, expected := range map[int]bool{23: true, 3: true, 6: false, 12: false} {
+		inWindow, err := csrapproval.InWindow(overnight, time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, expected, inWindow, "hour %d", hour)
+	}
+
+	always := rule("always")
+	inWindow, err = csrapproval.InWindow(always, now)
+	require.NoError(t, err)
+	assert.True(t, inWindow)
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := rule("valid")
+	valid.ClusterPattern = "prod-*"
+	valid.Identities = []string{"system:bootstrappers:*"}
+	valid.WindowStart = "22:00"
+	valid.WindowEnd = "06:00"
+	valid.WindowDays = []string{"Sat", "sun"}
+	valid.Timezone = "Europe/Berlin"
+	assert.NoError(t, csrapproval.ValidateRule(valid))
+
+	invalid := map[string]func(r *models.CSRApprovalRule){
+		"name":         func(r *models.CSRApprovalRule) { r.Name = "" },
+		"action":       func(r *models.CSRApprovalRule) { r.Action = "allow" },
+		"pattern":      func(r *models.CSRApprovalRule) { r.ClusterPattern = "prod-[" },
+		"identity":     func(r *models.CSRApprovalRule) { r.Identities = []string{""} },
+		"day":          func(r *models.CSRApprovalRule) { r.WindowDays = []string{"monday"} },
+		"start only":   func(r *models.CSRApprovalRule) { r.WindowStart = "09:00" },
+		"empty window": func(r *models.CSRApprovalRule) { r.WindowStart, r.WindowEnd = "09:00", "09:00" },
+		"clock":        func(r *models.CSRApprovalRule) { r.WindowStart, r.WindowEnd = "9am", "17:00" },
+		"timezone":     func(r *models.CSRApprovalRule) { r.Timezone = "Mars/Olympus" },
+	}
+	for name, mutate := range invalid {
+		r := rule("rule")
+		mutate(r)
+		assert.Error(t, csrapproval.ValidateRule(r), name)
+	}
+}
@@ -0,0 +1,194 @@
+package detach_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/detach"
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func managedCluster(name string, labels map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.open-cluster-management.io/v1",
+		"kind":       "ManagedCluster",
+		"metadata":   map[string]interface{}{"name": name, "labels": labels},
+	}}
+}
+
+func bindingPolicy(name string, selectors ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "control.kubestellar.io/v1alpha1",
+		"kind":       "BindingPolicy",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"clusterSelectors": selectors},
+	}}
+}
+
+func manifestWork(cluster, name, policy string, manifests ...interface{}) *unstructured.Unstructured {
+	labels := map[string]interface{}{}
+	if policy != "" {
+		labels[detach.BindingOwnerLabel] = policy
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "work.open-cluster-management.io/v1",
+		"kind":       "ManifestWork",
+		"metadata":   map[string]interface{}{"name": name, "namespace": cluster, "labels": labels},
+		"spec":       map[string]interface{}{"workload": map[string]interface{}{"manifests": manifests}},
+	}}
+}
+
+func object(apiVersion, kind, namespace, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}
+}
+
+func matchLabels(labels map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"matchLabels": labels}
+}
+
+func fakeDynamic(objects ...runtime.Object) dynamic.Interface {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ocm.ManagedClusterGVR:   "ManagedClusterList",
+		detach.BindingPolicyGVR: "BindingPolicyList",
+		detach.ManifestWorkGVR:  "ManifestWorkList",
+	}, objects...)
+}
+
+func items(objects ...*unstructured.Unstructured) []unstructured.Unstructured {
+	result := []unstructured.Unstructured{}
+	for _, obj := range objects {
+		result = append(result, *obj)
+	}
+	return result
+}
+
+func TestMatchPolicies(t *testing.T) {
+	edge1 := managedCluster("edge1", map[string]interface{}{"env": "prod", "location-group": "edge", "name": "edge1"})
+	edge2 := managedCluster("edge2", map[string]interface{}{"env": "prod"})
+	policies := items(
+		bindingPolicy("prod", matchLabels(map[string]interface{}{"env": "prod"})),
+		bindingPolicy("dev", matchLabels(map[string]interface{}{"env": "dev"})),
+		bindingPolicy("pinned", matchLabels(map[string]interface{}{"name": "edge1"})),
+		bindingPolicy("edge", map[string]interface{}{"matchExpressions": []interface{}{
+			map[string]interface{}{"key": "location-group", "operator": "In", "values": []interface{}{"edge"}},
+		}}),
+	)
+
+	impacts, err := detach.MatchPolicies("wds1", policies, edge1, items(edge1, edge2))
+	require.NoError(t, err)
+	require.Len(t, impacts, 3)
+
+	byName := map[string]detach.PolicyImpact{}
+	for _, impact := range impacts {
+		byName[impact.Name] = impact
+	}
+	assert.Equal(t, "wds1", byName["prod"].WDS)
+	assert.Equal(t, []string{"env=prod"}, byName["prod"].Selectors)
+	assert.Equal(t, []string{"edge2"}, byName["prod"].OtherClusters)
+	assert.Empty(t, byName["pinned"].OtherClusters)
+	assert.Equal(t, []string{"location-group in (edge)"}, byName["edge"].Selectors)
+	assert.NotContains(t, byName, "dev")
+}
+
+func TestWorkloadsFromManifestWorks(t *testing.T) {
+	workloads := detach.WorkloadsFromManifestWorks(items(
+		manifestWork("edge1", "work-prod", "prod",
+			object("apps/v1", "Deployment", "shop", "web"),
+			object("v1", "Namespace", "", "shop"),
+		),
+		manifestWork("edge1", "addon-work", ""),
+	))
+
+	require.Len(t, workloads, 2)
+	assert.Equal(t, detach.Workload{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "shop", Name: "web", Policy: "prod", ManifestWork: "work-prod"}, workloads[0])
+	assert.Equal(t, "Namespace", workloads[1].Kind)
+	assert.Empty(t, workloads[1].Group)
+}
+
+func TestPlanDrain(t *testing.T) {
+	edge1 := managedCluster("edge1", map[string]interface{}{
+		"env":  "prod",
+		"tier": "edge",
+		"team": "shop",
+		"cluster.open-cluster-management.io/clusterset": "default",
+	})
+	edge2 := managedCluster("edge2", map[string]interface{}{"env": "prod"})
+	policies := items(
+		bindingPolicy("prod", matchLabels(map[string]interface{}{"env": "prod", "tier": "edge"})),
+		bindingPolicy("everywhere", map[string]interface{}{}),
+		bindingPolicy("not-dev", map[string]interface{}{"matchExpressions": []interface{}{
+			map[string]interface{}{"key": "env", "operator": "NotIn", "values": []interface{}{"dev"}},
+		}}),
+		bindingPolicy("clusterset", matchLabels(map[string]interface{}{"cluster.open-cluster-management.io/clusterset": "default"})),
+	)
+	impacts, err := detach.MatchPolicies("wds1", policies, edge1, items(edge1, edge2))
+	require.NoError(t, err)
+
+	impact := detach.NewImpact("its1", edge1, impacts, nil)
+	assert.Equal(t, []string{"env", "tier"}, impact.Drain.RemoveLabels, "labels of OCM are never removed")
+	assert.ElementsMatch(t, []string{"wds1/everywhere", "wds1/not-dev", "wds1/clusterset"}, impact.Drain.Blocking)
+	assert.Equal(t, []string{"wds1/prod"}, impact.Drain.Stranded, "no other cluster has tier=edge")
+	assert.False(t, impact.Drainable())
+	assert.False(t, impact.Safe())
+
+	impacts, err = detach.MatchPolicies("wds1", policies[:1], edge1, items(edge1, edge2))
+	require.NoError(t, err)
+	assert.True(t, detach.NewImpact("its1", edge1, impacts, nil).Drainable())
+}
+
+func TestAnalyze(t *testing.T) {
+	edge1 := managedCluster("edge1", map[string]interface{}{"env": "prod"})
+	edge2 := managedCluster("edge2", map[string]interface{}{"env": "prod"})
+	clients := &detach.Clients{
+		ITS: &ocm.Clients{Dynamic: fakeDynamic(edge1, edge2,
+			manifestWork("edge1", "work-prod", "prod", object("apps/v1", "Deployment", "shop", "web")),
+			manifestWork("edge2", "work-prod", "prod", object("apps/v1", "Deployment", "shop", "web")),
+		)},
+		WDS: map[string]dynamic.Interface{
+			"wds1": fakeDynamic(bindingPolicy("prod", matchLabels(map[string]interface{}{"env": "prod"}))),
+		},
+	}
+
+	impact, err := detach.Analyze(context.Background(), "its1", clients, "edge1")
+	require.NoError(t, err)
+	require.Len(t, impact.Policies, 1)
+	assert.Equal(t, 1, impact.Policies[0].Workloads)
+	assert.Equal(t, []string{"edge2"}, impact.Policies[0].OtherClusters)
+	require.Len(t, impact.Workloads, 1)
+	assert.Equal(t, "web", impact.Workloads[0].Name)
+	assert.Equal(t, []string{"env"}, impact.Drain.RemoveLabels)
+	assert.True(t, impact.Drainable())
+
+	_, err = detach.Analyze(context.Background(), "its1", clients, "missing")
+	assert.ErrorIs(t, err, detach.ErrClusterNotFound)
+}
+
+func TestWaitForDrain(t *testing.T) {
+	its := fakeDynamic(manifestWork("edge1", "work-prod", "prod", object("apps/v1", "Deployment", "shop", "web")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	polls := 0
+	err := detach.WaitForDrain(ctx, its, "edge1", 10*time.Millisecond, func(remaining []detach.Workload) {
+		polls++
+		assert.Len(t, remaining, 1)
+	})
+	assert.Error(t, err)
+	assert.Positive(t, polls)
+
+	require.NoError(t, its.Resource(detach.ManifestWorkGVR).Namespace("edge1").Delete(context.Background(), "work-prod", metav1.DeleteOptions{}))
+	assert.NoError(t, detach.WaitForDrain(context.Background(), its, "edge1", 10*time.Millisecond, nil))
+}
@@ -0,0 +1,221 @@
+package hub_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://its1.test.me:9443
+  name: its1-cluster
+- cluster:
+    server: https://wds1.test.me:9443
+  name: wds1-cluster
+- cluster:
+    server: https://wds2.test.me:9443
+  name: wds2-cluster
+contexts:
+- context:
+    cluster: its1-cluster
+    user: test-user
+  name: its1
+- context:
+    cluster: wds1-cluster
+    user: test-user
+  name: wds1
+- context:
+    cluster: wds2-cluster
+    user: test-user
+  name: wds2
+- context:
+    cluster: its1-cluster
+    user: test-user
+  name: other
+current-context: wds1
+users:
+- name: test-user
+  user:
+    token: test-token
+`
+
+// setupRegistry points the registry at a temporary kubeconfig and, if given,
+// a spaces file, with KubeFlex discovery disabled
+func setupRegistry(t *testing.T, spacesFile string) {
+	dir := t.TempDir()
+	kubeconfigPath := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0600))
+	t.Setenv("KUBECONFIG", kubeconfigPath)
+	t.Setenv("HUB_DISCOVERY", "false")
+	t.Setenv("HUB_DEFAULT_ITS", "")
+	t.Setenv("HUB_DEFAULT_WDS", "")
+	t.Setenv("HUB_SPACES_FILE", "")
+	if spacesFile != "" {
+		path := filepath.Join(dir, "spaces.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(spacesFile), 0600))
+		t.Setenv("HUB_SPACES_FILE", path)
+	}
+	_, err := hub.Refresh()
+	require.NoError(t, err)
+}
+
+func TestSpacesFromKubeconfig(t *testing.T) {
+	setupRegistry(t, "")
+
+	names := []string{}
+	for _, space := range hub.Spaces() {
+		names = append(names, space.Name)
+		assert.Equal(t, hub.SourceKubeconfig, space.Source)
+	}
+	assert.Equal(t, []string{"its1", "wds1", "wds2"}, names)
+	assert.Equal(t, []string{"wds1", "wds2", "its1"}, hub.ContextNames())
+
+	its, ok := hub.Get("its1")
+	require.True(t, ok)
+	assert.Equal(t, hub.ITS, its.Type)
+	assert.Equal(t, "https://its1.test.me:9443", its.APIServer)
+	assert.True(t, its.Default)
+	assert.Equal(t, "wds1", hub.DefaultWDS())
+}
+
+func TestSpacesFile(t *testing.T) {
+	setupRegistry(t, `spaces:
+- name: prod-its
+  type: its
+  context: other
+  apiServer: https://prod-its.example.com
+  default: true
+- name: prod-wds
+  type: wds
+  context: wds2
+- name: broken
+  type: unknown
+`)
+
+	its := hub.Default(hub.ITS)
+	assert.Equal(t, "prod-its", its.Name)
+	assert.Equal(t, "other", its.Context)
+	assert.Equal(t, hub.SourceConfig, its.Source)
+	assert.Equal(t, "other", hub.DefaultITS())
+
+	// Configured spaces come first, kubeconfig contexts are still registered
+	_, ok := hub.Get("its1")
+	assert.True(t, ok)
+	_, ok = hub.Get("broken")
+	assert.False(t, ok)
+
+	// A space can be looked up by its context
+	space, ok := hub.Get("other")
+	require.True(t, ok)
+	assert.Equal(t, "prod-its", space.Name)
+}
+
+func TestDefaultFromEnv(t *testing.T) {
+	setupRegistry(t, "")
+	t.Setenv("HUB_DEFAULT_WDS", "wds2")
+
+	assert.Equal(t, "wds2", hub.DefaultWDS())
+	assert.Len(t, hub.SpacesOfType(hub.WDS), 2)
+}
+
+func TestBuiltinSpacesWithoutKubeconfig(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("HUB_DISCOVERY", "false")
+	t.Setenv("HUB_SPACES_FILE", "")
+	t.Setenv("HUB_DEFAULT_ITS", "")
+	t.Setenv("HUB_DEFAULT_WDS", "")
+	_, err := hub.Refresh()
+	require.NoError(t, err)
+
+	its := hub.Default(hub.ITS)
+	assert.Equal(t, "its1", its.Name)
+	assert.Equal(t, hub.SourceBuiltin, its.Source)
+	assert.Equal(t, "wds1", hub.DefaultWDS())
+}
+
+func TestResolve(t *testing.T) {
+	setupRegistry(t, "")
+
+	space, err := hub.Resolve(hub.WDS, "")
+	require.NoError(t, err)
+	assert.Equal(t, "wds1", space.Name)
+
+	space, err = hub.Resolve(hub.WDS, "wds2")
+	require.NoError(t, err)
+	assert.Equal(t, "wds2", space.Name)
+
+	_, err = hub.Resolve(hub.WDS, "its1")
+	assert.Error(t, err)
+	_, err = hub.Resolve(hub.ITS, "nope")
+	assert.Error(t, err)
+}
+
+func TestSelectSpaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRegistry(t, "")
+
+	tests := []struct {
+		name      string
+		url       string
+		cookie    string
+		spaceType hub.SpaceType
+		expected  string
+		wantErr   bool
+	}{
+		{name: "default ITS", url: "/", spaceType: hub.ITS, expected: "its1"},
+		{name: "default WDS", url: "/", spaceType: hub.WDS, expected: "wds1"},
+		{name: "WDS from query", url: "/?wds=wds2", spaceType: hub.WDS, expected: "wds2"},
+		{name: "WDS from legacy context", url: "/?context=wds2", spaceType: hub.WDS, expected: "wds2"},
+		{name: "WDS from cookie", url: "/", cookie: "wds2", spaceType: hub.WDS, expected: "wds2"},
+		{name: "stale cookie falls back", url: "/", cookie: "wds9", spaceType: hub.WDS, expected: "wds1"},
+		{name: "ITS ignores a WDS context", url: "/?context=wds2", spaceType: hub.ITS, expected: "its1"},
+		{name: "WDS ignores an ITS context", url: "/?context=its1", spaceType: hub.WDS, expected: "wds1"},
+		{name: "unknown ITS", url: "/?its=its9", spaceType: hub.ITS, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: hub.WDSCookie, Value: tt.cookie})
+			}
+
+			var space *hub.Space
+			var err error
+			if tt.spaceType == hub.ITS {
+				space, err = hub.SelectITSFromRequest(req)
+			} else {
+				space, err = hub.SelectWDSFromRequest(req)
+			}
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, space.Name)
+		})
+	}
+}
+
+func TestMustSelectRespondsBadRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRegistry(t, "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?wds=its1", nil)
+
+	_, ok := hub.MustSelectWDS(c)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
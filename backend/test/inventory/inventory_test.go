@@ -0,0 +1,214 @@
+package inventory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/inventory"
+	"github.com/kubestellar/ui/backend/labelpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func managedCluster(name string, labels map[string]interface{}, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":              name,
+			"labels":            labels,
+			"creationTimestamp": "2026-01-01T00:00:00Z",
+		},
+		"status": status,
+	}}
+}
+
+func node(providerID, region, cpu, memory string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"topology.kubernetes.io/region": region}},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}},
+	}
+}
+
+func TestBuildFromManagedCluster(t *testing.T) {
+	cluster := managedCluster("edge1", map[string]interface{}{"env": "prod"}, map[string]interface{}{
+		"version":     map[string]interface{}{"kubernetes": "v1.30.2"},
+		"allocatable": map[string]interface{}{"cpu": "3800m", "memory": "15Gi"},
+		"capacity":    map[string]interface{}{"cpu": "4", "memory": "16Gi"},
+		"clusterClaims": []interface{}{
+			map[string]interface{}{"name": inventory.ClaimPlatform, "value": "AWS"},
+			map[string]interface{}{"name": inventory.ClaimRegion, "value": "us-east-1"},
+			map[string]interface{}{"name": inventory.ClaimID, "value": "abc"},
+		},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "ManagedClusterJoined", "status": "True"},
+			map[string]interface{}{"type": "ManagedClusterConditionAvailable", "status": "True"},
+		},
+	})
+
+	inv := inventory.Build("its1", cluster, nil, nil)
+	assert.Equal(t, "edge1", inv.Name)
+	assert.Equal(t, "its1", inv.ITS)
+	assert.True(t, inv.Available)
+	assert.True(t, inv.Joined)
+	assert.Equal(t, "v1.30.2", inv.KubernetesVersion)
+	assert.Equal(t, int64(3800), inv.Allocatable.CPUMillis)
+	assert.Equal(t, int64(15*1024*1024*1024), inv.Allocatable.MemoryBytes)
+	assert.Equal(t, int64(4000), inv.Capacity.CPUMillis)
+	assert.Equal(t, "aws", inv.Provider)
+	assert.Equal(t, "us-east-1", inv.Region)
+	assert.Equal(t, "abc", inv.Claims[inventory.ClaimID])
+	assert.Nil(t, inv.NodeCount)
+	assert.Equal(t, []string{inventory.SourceManagedCluster}, inv.Sources)
+}
+
+func TestBuildWithClusterInfoAndNodes(t *testing.T) {
+	cluster := managedCluster("edge2", nil, map[string]interface{}{})
+	info := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"version":     "v1.29.0",
+			"cloudVendor": "Other",
+			"nodeList":    []interface{}{map[string]interface{}{}, map[string]interface{}{}},
+		},
+	}}
+
+	inv := inventory.Build("its1", cluster, info, nil)
+	require.NotNil(t, inv.NodeCount)
+	assert.Equal(t, 2, *inv.NodeCount)
+	assert.Equal(t, "v1.29.0", inv.KubernetesVersion)
+	assert.Empty(t, inv.Provider, "an unknown vendor is not a provider")
+
+	nodes := []corev1.Node{
+		node("gce://project/europe-west1-b/node-1", "europe-west1", "2", "4Gi"),
+		node("gce://project/europe-west1-c/node-2", "europe-west1", "2", "4Gi"),
+		node("gce://project/europe-west1-d/node-3", "europe-west1", "2", "4Gi"),
+	}
+	inv = inventory.Build("its1", cluster, info, nodes)
+	assert.Equal(t, 3, *inv.NodeCount)
+	assert.Equal(t, int64(6000), inv.Allocatable.CPUMillis)
+	assert.Equal(t, int64(12*1024*1024*1024), inv.Allocatable.MemoryBytes)
+	assert.Equal(t, "gcp", inv.Provider)
+	assert.Equal(t, "europe-west1", inv.Region)
+	assert.Equal(t, []string{inventory.SourceManagedCluster, inventory.SourceManagedClusterInfo, inventory.SourceNodes}, inv.Sources)
+}
+
+func TestBuildFallsBackToLabels(t *testing.T) {
+	cluster := managedCluster("edge3", map[string]interface{}{"cloud-provider": "Azure", "region": "westeurope"}, map[string]interface{}{})
+	inv := inventory.Build("its1", cluster, nil, []corev1.Node{node("kind://docker/kind/node", "", "1", "1Gi")})
+	assert.Equal(t, "kind", inv.Provider, "nodes win over labels")
+	assert.Equal(t, "westeurope", inv.Region)
+	assert.Contains(t, inv.Sources, inventory.SourceLabels)
+}
+
+func sampleInventory() []inventory.ClusterInventory {
+	count := func(n int) *int { return &n }
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []inventory.ClusterInventory{
+		{
+			Name: "edge-b", Labels: map[string]string{"env": "prod", "tier": "edge"}, Available: true,
+			KubernetesVersion: "v1.30.2", NodeCount: count(3), Provider: "aws", Region: "us-east-1",
+			Allocatable: inventory.Resources{CPUMillis: 6000, MemoryBytes: 8 << 30}, CreationTimestamp: created,
+		},
+		{
+			Name: "edge-a", Labels: map[string]string{"env": "dev"}, Available: false,
+			KubernetesVersion: "v1.9.0", Provider: "gcp", Region: "europe-west1",
+			Allocatable: inventory.Resources{CPUMillis: 2000, MemoryBytes: 4 << 30}, CreationTimestamp: created.Add(time.Hour),
+		},
+		{
+			Name: "core", Labels: map[string]string{"env": "prod", inventory.ProviderLabel: "aws"}, Available: true,
+			KubernetesVersion: "v1.30.0+k3s1", NodeCount: count(1), Provider: "aws",
+			Claims: map[string]string{"product.open-cluster-management.io": "K3s"},
+		},
+	}
+}
+
+func names(items []inventory.ClusterInventory) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.Name)
+	}
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	items := sampleInventory()
+	available := true
+	selector, err := inventory.ParseSelector("env=prod,tier in (edge)")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    inventory.Query
+		expected []string
+	}{
+		{name: "all by name", query: inventory.Query{}, expected: []string{"core", "edge-a", "edge-b"}},
+		{name: "search name", query: inventory.Query{Search: "EDGE"}, expected: []string{"edge-a", "edge-b"}},
+		{name: "search label", query: inventory.Query{Search: "env=dev"}, expected: []string{"edge-a"}},
+		{name: "search claim", query: inventory.Query{Search: "k3s"}, expected: []string{"core"}},
+		{name: "provider", query: inventory.Query{Provider: "AWS"}, expected: []string{"core", "edge-b"}},
+		{name: "region", query: inventory.Query{Region: "europe-west1"}, expected: []string{"edge-a"}},
+		{name: "available", query: inventory.Query{Available: &available}, expected: []string{"core", "edge-b"}},
+		{name: "selector", query: inventory.Query{Selector: selector}, expected: []string{"edge-b"}},
+		{name: "version", query: inventory.Query{SortBy: "version"}, expected: []string{"edge-a", "core", "edge-b"}},
+		{name: "nodes desc", query: inventory.Query{SortBy: "nodes", Desc: true}, expected: []string{"edge-b", "core", "edge-a"}},
+		{name: "cpu", query: inventory.Query{SortBy: "cpu"}, expected: []string{"core", "edge-a", "edge-b"}},
+		{name: "created", query: inventory.Query{SortBy: "created"}, expected: []string{"core", "edge-b", "edge-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := inventory.Search(items, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(result))
+		})
+	}
+
+	_, err = inventory.Search(items, inventory.Query{SortBy: "size"})
+	assert.Error(t, err)
+	_, err = inventory.ParseSelector("env in prod")
+	assert.Error(t, err)
+}
+
+func TestMinorVersion(t *testing.T) {
+	assert.Equal(t, "v1.30", inventory.MinorVersion("v1.30.2+k3s1"))
+	assert.Equal(t, "v1.9", inventory.MinorVersion("1.9.0"))
+	assert.Equal(t, "", inventory.MinorVersion("unknown"))
+}
+
+func TestLabelSuggestions(t *testing.T) {
+	suggestions := inventory.LabelSuggestions(sampleInventory(), "")
+	require.NotEmpty(t, suggestions)
+
+	// Existing labels come first, the most common first
+	assert.Equal(t, inventory.SuggestionLabel, suggestions[0].Source)
+	assert.Equal(t, "env=prod", suggestions[0].Selector)
+	assert.Equal(t, []string{"core", "edge-b"}, suggestions[0].Clusters)
+
+	inferred := map[string]inventory.LabelSuggestion{}
+	for _, suggestion := range suggestions {
+		if suggestion.Source == inventory.SuggestionInferred {
+			inferred[suggestion.Selector] = suggestion
+		}
+	}
+	// core already carries the provider label, so only edge-b is suggested
+	assert.Equal(t, []string{"edge-b"}, inferred[inventory.ProviderLabel+"=aws"].Clusters)
+	assert.Equal(t, []string{"edge-a"}, inferred[inventory.RegionLabel+"=europe-west1"].Clusters)
+	assert.Equal(t, []string{"core", "edge-b"}, inferred[inventory.VersionLabel+"=v1.30"].Clusters)
+
+	for _, suggestion := range inventory.LabelSuggestions(sampleInventory(), "inventory.kubestellar.io/") {
+		assert.Contains(t, suggestion.Key, "inventory.kubestellar.io/")
+	}
+
+	// Inferred labels must be applicable under the default label schema
+	schema := &labelpolicy.Schema{Rules: labelpolicy.DefaultRules()}
+	for _, suggestion := range inferred {
+		_, violations := schema.Plan(map[string]string{}, map[string]string{suggestion.Key: suggestion.Value},
+			labelpolicy.Editor{}, nil)
+		assert.Empty(t, violations, suggestion.Key)
+	}
+}
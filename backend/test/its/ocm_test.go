@@ -0,0 +1,267 @@
+package its
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/its/ocm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func fakeClients(kubeObjects []runtime.Object, dynamicObjects ...runtime.Object) *ocm.Clients {
+	listKinds := map[schema.GroupVersionResource]string{
+		ocm.ManagedClusterGVR: "ManagedClusterList",
+		ocm.KlusterletGVR:     "KlusterletList",
+		{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}: "CustomResourceDefinitionList",
+	}
+	return &ocm.Clients{
+		Kube:    fake.NewSimpleClientset(kubeObjects...),
+		Dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, dynamicObjects...),
+	}
+}
+
+func managedCluster(name string, conditions ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.open-cluster-management.io/v1",
+		"kind":       "ManagedCluster",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"hubAcceptsClient": false},
+		"status":     map[string]interface{}{"conditions": conditions},
+	}}
+}
+
+func TestBootstrapTokenPrefersBootstrapTokenSecret(t *testing.T) {
+	hub := fakeClients([]runtime.Object{&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: "kube-system", Labels: map[string]string{"app": "cluster-manager"}},
+		Type:       corev1.SecretTypeBootstrapToken,
+		Data:       map[string][]byte{"token-id": []byte("abcdef"), "token-secret": []byte("0123456789abcdef")},
+	}})
+
+	token, err := ocm.BootstrapToken(context.Background(), hub, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef.0123456789abcdef", token)
+}
+
+func TestBootstrapTokenRequestsServiceAccountToken(t *testing.T) {
+	hub := fakeClients(nil)
+	var expiration int64
+	hub.Kube.(*fake.Clientset).PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if action.GetSubresource() != "token" || action.GetNamespace() != ocm.HubNamespace {
+			return false, nil, nil
+		}
+		request := create.GetObject().(*authenticationv1.TokenRequest)
+		expiration = *request.Spec.ExpirationSeconds
+		request.Status.Token = "sa-token"
+		return true, request, nil
+	})
+
+	token, err := ocm.BootstrapToken(context.Background(), hub, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "sa-token", token)
+	assert.Equal(t, int64(3600), expiration)
+}
+
+func TestHubEndpointUsesClusterInfo(t *testing.T) {
+	published, err := ocm.BootstrapKubeconfig(ocm.JoinOptions{HubAPIServer: "https://its1-internal:443", HubCA: []byte("internal-ca")})
+	require.NoError(t, err)
+	hub := fakeClients([]runtime.Object{&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-info", Namespace: "kube-public"},
+		Data:       map[string]string{"kubeconfig": string(published)},
+	}})
+	config := &rest.Config{Host: "https://its1.localtest.me:9443", TLSClientConfig: rest.TLSClientConfig{CAData: []byte("external-ca")}}
+
+	server, ca, err := ocm.HubEndpoint(context.Background(), hub, config, true)
+	require.NoError(t, err)
+	assert.Equal(t, "https://its1-internal:443", server)
+	assert.Equal(t, []byte("internal-ca"), ca)
+
+	server, ca, err = ocm.HubEndpoint(context.Background(), hub, config, false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://its1.localtest.me:9443", server)
+	assert.Equal(t, []byte("external-ca"), ca)
+}
+
+func TestJoinDeploysKlusterletAndIsIdempotent(t *testing.T) {
+	spoke := fakeClients(nil)
+	ctx := context.Background()
+	opts := ocm.JoinOptions{
+		ClusterName:  "cluster1",
+		HubAPIServer: "https://its1.localtest.me:9443",
+		HubCA:        []byte("ca"),
+		Token:        "token",
+		Singleton:    true,
+	}
+	require.NoError(t, ocm.Join(ctx, spoke, opts))
+
+	secret, err := spoke.Kube.CoreV1().Secrets(ocm.AgentNamespace).Get(ctx, ocm.BootstrapKubeconfigSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	bootstrap, err := clientcmd.Load(secret.Data["kubeconfig"])
+	require.NoError(t, err)
+	assert.Equal(t, "https://its1.localtest.me:9443", bootstrap.Clusters["hub"].Server)
+	assert.Equal(t, "token", bootstrap.AuthInfos["bootstrap"].Token)
+
+	deployment, err := spoke.Kube.AppsV1().Deployments(ocm.OperatorNamespace).Get(ctx, ocm.KlusterletName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ocm.DefaultImageRegistry+"/registration-operator:"+ocm.DefaultImageTag, deployment.Spec.Template.Spec.Containers[0].Image)
+
+	klusterlet, err := spoke.Dynamic.Resource(ocm.KlusterletGVR).Get(ctx, ocm.KlusterletName, metav1.GetOptions{})
+	require.NoError(t, err)
+	clusterName, _, _ := unstructured.NestedString(klusterlet.Object, "spec", "clusterName")
+	mode, _, _ := unstructured.NestedString(klusterlet.Object, "spec", "deployOption", "mode")
+	assert.Equal(t, "cluster1", clusterName)
+	assert.Equal(t, "Singleton", mode)
+
+	// Running the join again, e.g. when a job resumes, updates the klusterlet
+	opts.Token = "new-token"
+	opts.ClusterName = "cluster1-renamed"
+	require.NoError(t, ocm.Join(ctx, spoke, opts))
+	klusterlet, err = spoke.Dynamic.Resource(ocm.KlusterletGVR).Get(ctx, ocm.KlusterletName, metav1.GetOptions{})
+	require.NoError(t, err)
+	clusterName, _, _ = unstructured.NestedString(klusterlet.Object, "spec", "clusterName")
+	assert.Equal(t, "cluster1-renamed", clusterName)
+	secret, err = spoke.Kube.CoreV1().Secrets(ocm.AgentNamespace).Get(ctx, ocm.BootstrapKubeconfigSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(secret.Data["kubeconfig"]), "new-token")
+}
+
+func TestJoinRequiresToken(t *testing.T) {
+	err := ocm.Join(context.Background(), fakeClients(nil), ocm.JoinOptions{ClusterName: "cluster1", HubAPIServer: "https://hub"})
+	assert.Error(t, err)
+}
+
+func TestUnjoinRemovesKlusterlet(t *testing.T) {
+	spoke := fakeClients(nil)
+	ctx := context.Background()
+	require.NoError(t, ocm.Join(ctx, spoke, ocm.JoinOptions{ClusterName: "cluster1", HubAPIServer: "https://hub", Token: "token"}))
+
+	require.NoError(t, ocm.Unjoin(ctx, spoke, time.Second))
+	_, err := spoke.Dynamic.Resource(ocm.KlusterletGVR).Get(ctx, ocm.KlusterletName, metav1.GetOptions{})
+	assert.Error(t, err)
+	_, err = spoke.Kube.CoreV1().Namespaces().Get(ctx, ocm.OperatorNamespace, metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// Nothing left to remove is not an error
+	assert.NoError(t, ocm.Unjoin(ctx, spoke, time.Second))
+}
+
+func TestApprovePendingCSRs(t *testing.T) {
+	csr := func(name, cluster string, conditions ...certificatesv1.CertificateSigningRequestCondition) *certificatesv1.CertificateSigningRequest {
+		return &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{ocm.ClusterNameLabel: cluster}},
+			Status:     certificatesv1.CertificateSigningRequestStatus{Conditions: conditions},
+		}
+	}
+	hub := fakeClients([]runtime.Object{
+		csr("cluster1-abc", "cluster1"),
+		csr("cluster1-old", "cluster1", certificatesv1.CertificateSigningRequestCondition{Type: certificatesv1.CertificateDenied}),
+		csr("cluster10-xyz", "cluster10"),
+	})
+	ctx := context.Background()
+
+	pending, err := ocm.PendingCSRs(ctx, hub, "cluster1")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "cluster1-abc", pending[0].Name)
+
+	require.NoError(t, ocm.ApproveCSR(ctx, hub, &pending[0], "approved in test"))
+	approved, err := hub.Kube.CertificatesV1().CertificateSigningRequests().Get(ctx, "cluster1-abc", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, ocm.CSRDecided(approved))
+
+	pending, err = ocm.PendingCSRs(ctx, hub, "cluster1")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestDenyCSRAndListAllClusters(t *testing.T) {
+	hub := fakeClients([]runtime.Object{
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-abc", Labels: map[string]string{ocm.ClusterNameLabel: "cluster1"}},
+		},
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster2-abc", Labels: map[string]string{ocm.ClusterNameLabel: "cluster2"}},
+		},
+		&certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "kubelet-serving"}},
+	})
+	ctx := context.Background()
+
+	pending, err := ocm.PendingCSRs(ctx, hub, "")
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	missing, err := ocm.GetCSR(ctx, hub, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	csr, err := ocm.GetCSR(ctx, hub, "cluster2-abc")
+	require.NoError(t, err)
+	require.NotNil(t, csr)
+	require.NoError(t, ocm.DenyCSR(ctx, hub, csr, "denied in test"))
+
+	denied, err := ocm.GetCSR(ctx, hub, "cluster2-abc")
+	require.NoError(t, err)
+	require.Len(t, denied.Status.Conditions, 1)
+	assert.Equal(t, certificatesv1.CertificateDenied, denied.Status.Conditions[0].Type)
+	assert.Equal(t, "denied in test", denied.Status.Conditions[0].Message)
+
+	all, err := ocm.ClusterCSRs(ctx, hub, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	pending, err = ocm.PendingCSRs(ctx, hub, "")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "cluster1-abc", pending[0].Name)
+}
+
+func TestAcceptAndDeleteManagedCluster(t *testing.T) {
+	hub := fakeClients(nil, managedCluster("cluster1",
+		map[string]interface{}{"type": "ManagedClusterJoined", "status": "True"},
+		map[string]interface{}{"type": "ManagedClusterConditionAvailable", "status": "False"},
+	))
+	ctx := context.Background()
+
+	require.NoError(t, ocm.AcceptManagedCluster(ctx, hub, "cluster1"))
+	cluster, err := ocm.GetManagedCluster(ctx, hub, "cluster1")
+	require.NoError(t, err)
+	require.NotNil(t, cluster)
+	accepted, _, _ := unstructured.NestedBool(cluster.Object, "spec", "hubAcceptsClient")
+	assert.True(t, accepted)
+
+	joined, available := ocm.JoinedAndAvailable(cluster)
+	assert.True(t, joined)
+	assert.False(t, available)
+
+	require.NoError(t, ocm.DeleteManagedCluster(ctx, hub, "cluster1"))
+	cluster, err = ocm.GetManagedCluster(ctx, hub, "cluster1")
+	require.NoError(t, err)
+	assert.Nil(t, cluster)
+	assert.NoError(t, ocm.DeleteManagedCluster(ctx, hub, "cluster1"))
+}
+
+func TestSetAndRemoveManagedClusterLabels(t *testing.T) {
+	hub := fakeClients(nil, managedCluster("cluster1"))
+	ctx := context.Background()
+
+	require.NoError(t, ocm.SetManagedClusterLabels(ctx, hub, "cluster1", map[string]string{"env": "prod", "tier": "edge"}))
+	require.NoError(t, ocm.RemoveManagedClusterLabels(ctx, hub, "cluster1", []string{"tier", "missing"}))
+	cluster, err := ocm.GetManagedCluster(ctx, hub, "cluster1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, cluster.GetLabels())
+
+	assert.NoError(t, ocm.RemoveManagedClusterLabels(ctx, hub, "cluster1", nil))
+}
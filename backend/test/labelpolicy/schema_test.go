@@ -0,0 +1,156 @@
+package labelpolicy_test
+
+import (
+	"testing"
+
+	"github.com/kubestellar/ui/backend/labelpolicy"
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() *labelpolicy.Schema {
+	return &labelpolicy.Schema{
+		Rules: append(labelpolicy.DefaultRules(),
+			&models.LabelRule{Key: "environment", Required: true, AllowedValues: []string{"dev", "prod"}},
+			&models.LabelRule{Key: "team", ValuePattern: "[a-z]+(-[a-z]+)*"},
+			&models.LabelRule{Key: "billing.example.com/*", Owners: []string{"finance"}},
+		),
+		ProtectBindingPolicyLabels: true,
+	}
+}
+
+func keys(violations []labelpolicy.Violation) []string {
+	result := []string{}
+	for _, violation := range violations {
+		result = append(result, violation.Key)
+	}
+	return result
+}
+
+func TestLoadWithoutDatabaseUsesDefaultRules(t *testing.T) {
+	schema, err := labelpolicy.Load()
+	require.NoError(t, err)
+	assert.True(t, schema.ProtectBindingPolicyLabels)
+	assert.Len(t, schema.RulesFor("cluster.open-cluster-management.io/clusterset"), 1)
+	assert.Equal(t, "edge", schema.WithDefaults(map[string]string{})["location-group"])
+}
+
+func TestCheck(t *testing.T) {
+	schema := testSchema()
+
+	assert.Empty(t, schema.Check(map[string]string{"environment": "prod", "team": "edge-ops"}))
+
+	violations := schema.Check(map[string]string{"team": "Edge_Ops"})
+	require.Len(t, violations, 2)
+	assert.Equal(t, "environment", violations[0].Key)
+	assert.Equal(t, "required label is missing", violations[0].Reason)
+	assert.Equal(t, "team", violations[1].Key)
+	assert.Contains(t, violations[1].Reason, "must match")
+
+	violations = schema.Check(map[string]string{"environment": "staging"})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "value must be one of dev, prod", violations[0].Reason)
+}
+
+func TestWithDefaultsKeepsExistingLabels(t *testing.T) {
+	schema := testSchema()
+	labels := schema.WithDefaults(map[string]string{"location-group": "cloud"})
+	assert.Equal(t, "cloud", labels["location-group"])
+
+	labels = schema.WithDefaults(map[string]string{"environment": "dev"})
+	assert.Equal(t, map[string]string{"environment": "dev", "location-group": "edge"}, labels)
+}
+
+func TestCheckOnboarding(t *testing.T) {
+	schema := testSchema()
+
+	assert.Empty(t, schema.CheckOnboarding(map[string]string{
+		"name":        "edge1",
+		"environment": "dev",
+		"cluster.open-cluster-management.io/clusterset": "default",
+	}), "protected keys may be set at onboarding")
+
+	violations := schema.CheckOnboarding(map[string]string{"name": "edge1", "not a key": "x"})
+	assert.Equal(t, []string{"not a key", "environment"}, keys(violations))
+}
+
+func TestPlan(t *testing.T) {
+	schema := testSchema()
+	current := map[string]string{
+		"name":                     "edge1",
+		"environment":              "dev",
+		"kubernetes.io/os":         "linux",
+		"app":                      "web",
+		"billing.example.com/cost": "a1",
+	}
+	changes := map[string]string{
+		"environment":              "prod",
+		"team":                     "Edge_Ops",
+		"kubernetes.io/os":         "",
+		"app":                      "api",
+		"region":                   "eu",
+		"billing.example.com/cost": "b2",
+		"missing":                  "",
+		"name":                     "edge1",
+	}
+
+	final, violations := schema.Plan(current, changes, labelpolicy.Editor{Username: "alice"}, map[string]bool{"app": true})
+	assert.Equal(t, map[string]string{
+		"name":                     "edge1",
+		"environment":              "prod",
+		"kubernetes.io/os":         "linux",
+		"app":                      "web",
+		"region":                   "eu",
+		"billing.example.com/cost": "a1",
+	}, final)
+	assert.Equal(t, []string{"app", "billing.example.com/cost", "kubernetes.io/os", "team"}, keys(violations))
+	assert.Equal(t, "used by binding policies to select clusters", violations[0].Reason)
+	assert.Equal(t, "only finance may change this label", violations[1].Reason)
+	assert.Equal(t, "protected label", violations[2].Reason)
+	assert.Equal(t, "kubernetes.io/*", violations[2].Rule)
+
+	final, violations = schema.Plan(current, map[string]string{"billing.example.com/cost": "b2", "environment": ""},
+		labelpolicy.Editor{Username: "finance"}, nil)
+	assert.Equal(t, "b2", final["billing.example.com/cost"])
+	assert.Equal(t, "dev", final["environment"])
+	require.Len(t, violations, 1)
+	assert.Equal(t, "required label cannot be removed", violations[0].Reason)
+
+	_, violations = schema.Plan(current, map[string]string{"billing.example.com/cost": "b2"},
+		labelpolicy.Editor{Username: "bob", Admin: true}, nil)
+	assert.Empty(t, violations)
+
+	schema.ProtectBindingPolicyLabels = false
+	final, violations = schema.Plan(current, map[string]string{"app": "api"}, labelpolicy.Editor{}, map[string]bool{"app": true})
+	assert.Empty(t, violations)
+	assert.Equal(t, "api", final["app"])
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := []*models.LabelRule{
+		{Key: "environment", Required: true, AllowedValues: []string{"dev", "prod"}, Default: "dev"},
+		{Key: "example.com/*", ValuePattern: "[a-z]+", Owners: []string{"alice"}},
+		{Key: "kubernetes.io/*", Protected: true},
+	}
+	for _, rule := range valid {
+		assert.NoError(t, labelpolicy.ValidateRule(rule), rule.Key)
+	}
+
+	invalid := map[string]*models.LabelRule{
+		"empty key":          {},
+		"bad pattern":        {Key: "example.com/["},
+		"bad key":            {Key: "not a key"},
+		"required pattern":   {Key: "example.com/*", Required: true},
+		"default pattern":    {Key: "example.com/*", Default: "x"},
+		"bad allowed value":  {Key: "env", AllowedValues: []string{"not valid!"}},
+		"bad value pattern":  {Key: "env", ValuePattern: "("},
+		"empty owner":        {Key: "env", Owners: []string{" "}},
+		"protected owned":    {Key: "env", Protected: true, Owners: []string{"alice"}},
+		"default not listed": {Key: "env", AllowedValues: []string{"dev"}, Default: "prod"},
+		"default no match":   {Key: "env", ValuePattern: "[a-z]+", Default: "Prod"},
+	}
+	for name, rule := range invalid {
+		assert.Error(t, labelpolicy.ValidateRule(rule), name)
+	}
+}
@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextOnboardingStep(t *testing.T) {
+	next, ok := models.NextOnboardingStep(models.OnboardingStepValidate)
+	assert.True(t, ok)
+	assert.Equal(t, models.OnboardingStepConnectHub, next)
+
+	_, ok = models.NextOnboardingStep(models.OnboardingStepVerify)
+	assert.False(t, ok)
+
+	_, ok = models.NextOnboardingStep("unknown")
+	assert.False(t, ok)
+}
+
+func TestOnboardingJobStepDone(t *testing.T) {
+	job := &models.OnboardingJob{State: models.OnboardingRunning, Step: models.OnboardingStepJoin}
+	assert.True(t, job.StepDone(models.OnboardingStepToken))
+	assert.False(t, job.StepDone(models.OnboardingStepJoin))
+	assert.False(t, job.StepDone(models.OnboardingStepLabel))
+
+	job.State = models.OnboardingSucceeded
+	assert.True(t, job.StepDone(models.OnboardingStepVerify))
+}
+
+func TestOnboardingJobRecoveryAction(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		job      models.OnboardingJob
+		expected string
+	}{
+		{
+			name:     "nothing changed yet",
+			job:      models.OnboardingJob{Step: models.OnboardingStepToken, UpdatedAt: now.Add(-time.Hour)},
+			expected: models.OnboardingResume,
+		},
+		{
+			name:     "recently interrupted join",
+			job:      models.OnboardingJob{Step: models.OnboardingStepApproveCSR, UpdatedAt: now.Add(-time.Minute)},
+			expected: models.OnboardingResume,
+		},
+		{
+			name:     "stale join",
+			job:      models.OnboardingJob{Step: models.OnboardingStepJoin, UpdatedAt: now.Add(-time.Hour)},
+			expected: models.OnboardingRollback,
+		},
+		{
+			name:     "cancel requested",
+			job:      models.OnboardingJob{Step: models.OnboardingStepValidate, UpdatedAt: now, CancelRequested: true},
+			expected: models.OnboardingRollback,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.job.RecoveryAction(now))
+		})
+	}
+}
+
+func TestOnboardingJobCanRetry(t *testing.T) {
+	job := &models.OnboardingJob{State: models.OnboardingFailed, Kubeconfig: []byte("apiVersion: v1")}
+	assert.True(t, job.CanRetry())
+
+	job.State = models.OnboardingRunning
+	assert.False(t, job.CanRetry())
+
+	job.State = models.OnboardingSucceeded
+	assert.False(t, job.CanRetry())
+
+	job = &models.OnboardingJob{State: models.OnboardingRolledBack}
+	assert.False(t, job.CanRetry(), "jobs without a kubeconfig cannot run again")
+}
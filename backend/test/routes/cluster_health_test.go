@@ -0,0 +1,72 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterHealthHistoryRejectsInvalidWindows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/clusters/:name/health/history", routes.GetClusterHealthHistoryHandler)
+
+	tests := []struct {
+		name     string
+		query    string
+		errorMsg string
+	}{
+		{name: "invalid from", query: "from=yesterday", errorMsg: "invalid from time"},
+		{name: "invalid to", query: "to=now", errorMsg: "invalid to time"},
+		{name: "reversed", query: "from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z", errorMsg: "from must be before to"},
+		{name: "too long", query: "from=2025-01-01T00:00:00Z&to=2026-01-01T00:00:00Z", errorMsg: "must not be longer"},
+		{name: "unknown ITS", query: "its=its9", errorMsg: "its9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/clusters/edge1/health/history?"+tt.query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.True(t, strings.Contains(response["error"].(string), tt.errorMsg), response["error"])
+		})
+	}
+}
+
+func TestAlertRuleValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/admin/health/alert-rules", routes.CreateAlertRuleHandler)
+	router.PUT("/api/admin/health/alert-rules/:id", routes.UpdateAlertRuleHandler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "missing fields", method: http.MethodPost, path: "/api/admin/health/alert-rules", body: `{"name":"r"}`},
+		{name: "unknown condition", method: http.MethodPost, path: "/api/admin/health/alert-rules", body: `{"name":"r","condition":"down"}`},
+		{name: "unknown notifier", method: http.MethodPost, path: "/api/admin/health/alert-rules", body: `{"name":"r","condition":"unavailable","notifiers":["pager"]}`},
+		{name: "invalid id", method: http.MethodPut, path: "/api/admin/health/alert-rules/abc", body: `{"name":"r","condition":"unavailable"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+func TestLoginPolicyDelayFor(t *testing.T) {
+	policy := utils.LoginPolicy{
+		DelayAfter: 2,
+		DelayBase:  time.Second,
+		DelayMax:   10 * time.Second,
+	}
+
+	tests := []struct {
+		failures int64
+		expected time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.DelayFor(tt.failures); got != tt.expected {
+			t.Errorf("DelayFor(%d) = %v, expected %v", tt.failures, got, tt.expected)
+		}
+	}
+
+	// A zero DelayMax leaves the delay uncapped rather than disabling it
+	uncapped := utils.LoginPolicy{DelayAfter: 2, DelayBase: time.Second}
+	if got := uncapped.DelayFor(7); got != 16*time.Second {
+		t.Errorf("DelayFor(7) without a cap = %v, expected %v", got, 16*time.Second)
+	}
+	if got := uncapped.DelayFor(200); got <= 0 {
+		t.Errorf("DelayFor(200) without a cap = %v, expected a positive delay", got)
+	}
+}
+
+func TestLoginPolicyLockout(t *testing.T) {
+	policy := utils.LoginPolicy{MaxFailuresPerUser: 5, MaxFailuresPerIP: 20}
+
+	if policy.ShouldLockUser(4) {
+		t.Error("expected user not to be locked after 4 failures")
+	}
+	if !policy.ShouldLockUser(5) {
+		t.Error("expected user to be locked after 5 failures")
+	}
+	if policy.ShouldLockIP(19) {
+		t.Error("expected IP not to be locked after 19 failures")
+	}
+	if !policy.ShouldLockIP(20) {
+		t.Error("expected IP to be locked after 20 failures")
+	}
+
+	disabled := utils.LoginPolicy{}
+	if disabled.ShouldLockUser(100) || disabled.ShouldLockIP(100) {
+		t.Error("expected a zero limit to disable lockout")
+	}
+}
+
+func TestLoadLoginPolicyFromEnv(t *testing.T) {
+	t.Setenv(utils.LoginMaxFailuresPerUserEnv, "3")
+	t.Setenv(utils.LoginLockoutDurationEnv, "30")
+	t.Setenv(utils.LoginDelayMaxEnv, "not-a-number")
+
+	policy := utils.LoadLoginPolicy()
+	defaults := utils.DefaultLoginPolicy()
+
+	if policy.MaxFailuresPerUser != 3 {
+		t.Errorf("expected MaxFailuresPerUser 3, got %d", policy.MaxFailuresPerUser)
+	}
+	if policy.LockoutDuration != 30*time.Minute {
+		t.Errorf("expected LockoutDuration 30m, got %v", policy.LockoutDuration)
+	}
+	if policy.DelayMax != defaults.DelayMax {
+		t.Errorf("expected invalid DelayMax to fall back to %v, got %v", defaults.DelayMax, policy.DelayMax)
+	}
+}
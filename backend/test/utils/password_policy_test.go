@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := utils.DefaultPasswordPolicy()
+
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		violations int
+	}{
+		{"valid", "alice", "Correct1Horse", 0},
+		{"too short", "alice", "Ab1", 1},
+		{"missing classes", "alice", "alllowercase", 2},
+		{"contains username", "alice", "Alice12345", 1},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.username, tt.password)
+		if tt.violations == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+
+		var policyErr *utils.PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Fatalf("%s: expected PasswordPolicyError, got %v", tt.name, err)
+		}
+		if len(policyErr.Violations) != tt.violations {
+			t.Errorf("%s: expected %d violations, got %v", tt.name, tt.violations, policyErr.Violations)
+		}
+	}
+
+	policy.RequireSymbol = true
+	if err := policy.Validate("", "Correct1Horse"); err == nil {
+		t.Error("expected password without symbol to be rejected")
+	}
+	if err := policy.Validate("", "Correct1Horse!"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "# common passwords\nPassword1\n\nletMeIn123\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	breached, err := utils.LoadBreachedPasswords(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(breached) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(breached))
+	}
+
+	policy := utils.DefaultPasswordPolicy().WithBreachedPasswords(breached)
+	if err := policy.Validate("", "LetMeIn123"); err == nil {
+		t.Error("expected breached password to be rejected regardless of case")
+	}
+	if err := policy.Validate("", "Unlisted123"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPasswordChangeRequired(t *testing.T) {
+	now := time.Now()
+	policy := utils.DefaultPasswordPolicy()
+
+	if required, _ := policy.ChangeRequired(false, now.AddDate(-5, 0, 0), now); required {
+		t.Error("expected no expiry when max age is disabled")
+	}
+	if required, reason := policy.ChangeRequired(true, now, now); !required || reason != utils.PasswordChangeReasonForced {
+		t.Errorf("expected forced change, got %v %q", required, reason)
+	}
+
+	policy.MaxAge = 90 * 24 * time.Hour
+	if required, reason := policy.ChangeRequired(false, now.AddDate(0, 0, -91), now); !required || reason != utils.PasswordChangeReasonExpired {
+		t.Errorf("expected expired password, got %v %q", required, reason)
+	}
+	if required, _ := policy.ChangeRequired(false, now.AddDate(0, 0, -30), now); required {
+		t.Error("expected recent password to be valid")
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/ui/backend/utils"
+)
+
+// Secret "12345678901234567890" from the RFC 6238 test vectors, base32 encoded
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := utils.GenerateTOTPCode(rfcTestSecret, utils.TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("code at %d = %s, expected %s", tt.unix, code, tt.expected)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	step, ok := utils.ValidateTOTPCode(rfcTestSecret, "081804", now)
+	if !ok || step != utils.TOTPStep(now) {
+		t.Errorf("expected current code to validate at step %d, got %d (%v)", utils.TOTPStep(now), step, ok)
+	}
+
+	// One period of clock skew is tolerated
+	if _, ok := utils.ValidateTOTPCode(rfcTestSecret, "081804", now.Add(utils.TOTPPeriod*time.Second)); !ok {
+		t.Error("expected code from previous step to validate")
+	}
+
+	if _, ok := utils.ValidateTOTPCode(rfcTestSecret, "081804", now.Add(3*utils.TOTPPeriod*time.Second)); ok {
+		t.Error("expected stale code to be rejected")
+	}
+
+	if _, ok := utils.ValidateTOTPCode(rfcTestSecret, "12345", now); ok {
+		t.Error("expected short code to be rejected")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("expected 32 character secret, got %d", len(secret))
+	}
+	if _, err := utils.GenerateTOTPCode(secret, 1); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := utils.TOTPProvisioningURI("KubeStellar", "alice", rfcTestSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI: %v", err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	if !strings.HasSuffix(parsed.Path, "KubeStellar:alice") {
+		t.Errorf("unexpected label: %s", parsed.Path)
+	}
+	if parsed.Query().Get("secret") != rfcTestSecret || parsed.Query().Get("issuer") != "KubeStellar" {
+		t.Errorf("unexpected query: %s", parsed.RawQuery)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := utils.GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code: %s", code)
+		}
+		seen[code] = true
+	}
+
+	if utils.NormalizeRecoveryCode(" ABCDE-fghij ") != "abcdefghij" {
+		t.Error("expected recovery code to be normalized")
+	}
+}
@@ -0,0 +1,163 @@
+package vault_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubestellar/ui/backend/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://edge.example.com:6443
+  name: edge
+contexts:
+- context:
+    cluster: edge
+    user: edge
+  name: edge-admin
+current-context: edge-admin
+users:
+- name: edge
+  user:
+    token: secret-token
+`
+
+func newKey(t *testing.T) (string, *vault.Key) {
+	material := make([]byte, 32)
+	_, err := rand.Read(material)
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(material)
+	key, err := vault.ParseKey(encoded)
+	require.NoError(t, err)
+	return encoded, key
+}
+
+func TestParseKey(t *testing.T) {
+	material := make([]byte, 32)
+	_, err := rand.Read(material)
+	require.NoError(t, err)
+
+	fromBase64, err := vault.ParseKey(base64.StdEncoding.EncodeToString(material) + "\n")
+	require.NoError(t, err)
+	fromHex, err := vault.ParseKey(hex.EncodeToString(material))
+	require.NoError(t, err)
+	fromURL, err := vault.ParseKey(base64.URLEncoding.EncodeToString(material))
+	require.NoError(t, err)
+	assert.Equal(t, fromBase64.ID, fromHex.ID)
+	assert.Equal(t, fromBase64.ID, fromURL.ID)
+	assert.Len(t, fromBase64.ID, 16)
+
+	_, err = vault.ParseKey(base64.StdEncoding.EncodeToString(material[:16]))
+	assert.Error(t, err, "a key must be 32 bytes")
+	_, err = vault.ParseKey("not a key!")
+	assert.Error(t, err)
+}
+
+func TestSealAndOpen(t *testing.T) {
+	_, key := newKey(t)
+	keyring := vault.NewKeyring(key)
+
+	sealed, err := keyring.Seal("edge", []byte(kubeconfig))
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, sealed.KeyID)
+	assert.NotContains(t, string(sealed.Ciphertext), "secret-token")
+
+	opened, err := keyring.Open("edge", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig, string(opened))
+
+	_, err = keyring.Open("other", sealed)
+	assert.Error(t, err, "a sealed credential is bound to its name")
+
+	tampered := *sealed
+	tampered.Ciphertext = append([]byte{}, sealed.Ciphertext...)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	_, err = keyring.Open("edge", &tampered)
+	assert.Error(t, err)
+
+	_, otherKey := newKey(t)
+	_, err = vault.NewKeyring(otherKey).Open("edge", sealed)
+	assert.ErrorIs(t, err, vault.ErrUnknownKey)
+}
+
+func TestRewrap(t *testing.T) {
+	_, oldKey := newKey(t)
+	_, nextKey := newKey(t)
+	sealed, err := vault.NewKeyring(oldKey).Seal("edge", []byte(kubeconfig))
+	require.NoError(t, err)
+
+	rotated := vault.NewKeyring(nextKey, oldKey)
+	assert.Equal(t, []string{nextKey.ID, oldKey.ID}, rotated.KeyIDs())
+	rewrapped, err := rotated.Rewrap("edge", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, nextKey.ID, rewrapped.KeyID)
+	assert.Equal(t, sealed.Ciphertext, rewrapped.Ciphertext, "only the data key is rewrapped")
+
+	opened, err := vault.NewKeyring(nextKey).Open("edge", rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig, string(opened))
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	t.Setenv(vault.MasterKeyEnv, "")
+	t.Setenv(vault.MasterKeyFileEnv, "")
+	t.Setenv(vault.PreviousMasterKeysEnv, "")
+	t.Setenv(vault.PreviousMasterKeyFileEnv, "")
+	_, err := vault.KeyringFromEnv()
+	assert.ErrorIs(t, err, vault.ErrNoMasterKey)
+
+	current, currentKey := newKey(t)
+	previous1, previousKey1 := newKey(t)
+	previous2, previousKey2 := newKey(t)
+	path := filepath.Join(t.TempDir(), "master-key")
+	require.NoError(t, os.WriteFile(path, []byte(current+"\n"), 0600))
+	t.Setenv(vault.MasterKeyFileEnv, path)
+	t.Setenv(vault.PreviousMasterKeysEnv, previous1+",\n"+previous2)
+
+	keyring, err := vault.KeyringFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, currentKey.ID, keyring.CurrentKeyID())
+	assert.ElementsMatch(t, []string{currentKey.ID, previousKey1.ID, previousKey2.ID}, keyring.KeyIDs())
+
+	t.Setenv(vault.PreviousMasterKeysEnv, "short")
+	_, err = vault.KeyringFromEnv()
+	assert.Error(t, err)
+}
+
+func TestDescribe(t *testing.T) {
+	server, contextName, err := vault.Describe([]byte(kubeconfig))
+	require.NoError(t, err)
+	assert.Equal(t, "https://edge.example.com:6443", server)
+	assert.Equal(t, "edge-admin", contextName)
+
+	_, _, err = vault.Describe([]byte("apiVersion: v1\nkind: Config\n"))
+	assert.Error(t, err)
+}
+
+func TestDisabledVault(t *testing.T) {
+	assert.False(t, vault.Enabled())
+	_, err := vault.Kubeconfig("edge", vault.Access{Username: "admin", Purpose: "test"})
+	assert.ErrorIs(t, err, vault.ErrDisabled)
+	_, err = vault.Store("edge", []byte(kubeconfig), vault.Access{Username: "admin"})
+	assert.ErrorIs(t, err, vault.ErrDisabled)
+}
+
+func TestAccessRetentionDays(t *testing.T) {
+	t.Setenv("CREDENTIAL_ACCESS_RETENTION_DAYS", "")
+	assert.Equal(t, 90, vault.AccessRetentionDays())
+
+	t.Setenv("CREDENTIAL_ACCESS_RETENTION_DAYS", "0")
+	assert.Equal(t, 0, vault.AccessRetentionDays())
+
+	t.Setenv("CREDENTIAL_ACCESS_RETENTION_DAYS", "-3")
+	assert.Equal(t, 90, vault.AccessRetentionDays())
+}
@@ -0,0 +1,234 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	bundleDeploymentGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	bundleNamespaceGVR  = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+func bundlePolicy(t *testing.T, name, clusterGroup string) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(lintPolicy(name, clusterGroup, deploymentsIn("nginx", false)))
+	require.NoError(t, err)
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetAPIVersion("control.kubestellar.io/v1alpha1")
+	obj.SetKind("BindingPolicy")
+	return obj
+}
+
+func bundleClients(objects ...runtime.Object) bp.PreviewClients {
+	wds := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			bundleNamespaceGVR:  "NamespaceList",
+			bundleDeploymentGVR: "DeploymentList",
+			watcherPolicyGVR:    "BindingPolicyList",
+		},
+		objects...,
+	)
+
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+	}
+	return bp.PreviewClients{WDS: wds, WDSDiscovery: discovery}
+}
+
+func objectNames(objects []*unstructured.Unstructured) []string {
+	names := []string{}
+	for _, obj := range objects {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+	return names
+}
+
+func TestExportBundleIsDeterministic(t *testing.T) {
+	deployment := previewObject("apps/v1", "Deployment", "nginx", "nginx", nil)
+	deployment.SetResourceVersion("42")
+	deployment.SetUID("1234")
+	deployment.Object["status"] = map[string]interface{}{"replicas": int64(1)}
+
+	clients := bundleClients(
+		previewObject("v1", "Namespace", "", "nginx", nil),
+		previewObject("v1", "Namespace", "", "other", nil),
+		deployment,
+		previewObject("apps/v1", "Deployment", "other", "nginx", nil),
+		bundlePolicy(t, "b-bp", "edge"),
+		bundlePolicy(t, "a-bp", "core"),
+	)
+
+	policiesOnly, err := bp.ExportBundle(context.Background(), clients, bp.ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BindingPolicy/a-bp", "BindingPolicy/b-bp"}, objectNames(policiesOnly.Objects))
+
+	bundle, err := bp.ExportBundle(context.Background(), clients, bp.ExportOptions{IncludeWorkloads: true, IncludeNamespaces: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Namespace/nginx", "Deployment/nginx", "BindingPolicy/a-bp", "BindingPolicy/b-bp"}, objectNames(bundle.Objects))
+
+	exported := bundle.Objects[1]
+	assert.Empty(t, exported.GetResourceVersion())
+	assert.Empty(t, exported.GetUID())
+	assert.NotContains(t, exported.Object, "status")
+
+	encoded, err := bp.EncodeBundleYAML(bundle)
+	require.NoError(t, err)
+	again, err := bp.ExportBundle(context.Background(), clients, bp.ExportOptions{IncludeWorkloads: true, IncludeNamespaces: true})
+	require.NoError(t, err)
+	encodedAgain, err := bp.EncodeBundleYAML(again)
+	require.NoError(t, err)
+	assert.Equal(t, string(encoded), string(encodedAgain))
+
+	tarball, err := bp.EncodeBundleTarball(bundle)
+	require.NoError(t, err)
+	tarballAgain, err := bp.EncodeBundleTarball(again)
+	require.NoError(t, err)
+	assert.Equal(t, tarball, tarballAgain)
+
+	// Both formats decode back to the same objects
+	for _, data := range [][]byte{encoded, tarball} {
+		decoded, err := bp.DecodeBundle(data)
+		require.NoError(t, err)
+		assert.Equal(t, bundle.Objects, decoded.Objects)
+	}
+}
+
+func TestDecodeBundleRejectsInvalidObjects(t *testing.T) {
+	_, err := bp.DecodeBundle([]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: nginx
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: nginx
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {}
+---
+apiVersion: control.kubestellar.io/v1alpha1
+kind: BindingPolicy
+metadata:
+  name: broken
+spec:
+  clusterSelectors:
+  - matchExpressions:
+    - {key: env, operator: Bogus}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: everyone-admin
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+  namespace: nginx
+`))
+	var invalid *bp.InvalidBundleError
+	require.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Problems, 5)
+}
+
+func TestImportBundle(t *testing.T) {
+	existing := bundlePolicy(t, "changed-bp", "core")
+	existing.SetResourceVersion("7")
+	clients := bundleClients(
+		previewObject("v1", "Namespace", "", "nginx", nil),
+		existing,
+		bundlePolicy(t, "stale-bp", "edge"),
+	)
+
+	bundle := &bp.Bundle{Objects: []*unstructured.Unstructured{
+		previewObject("v1", "Namespace", "", "nginx", nil),
+		previewObject("apps/v1", "Deployment", "nginx", "nginx", nil),
+		bundlePolicy(t, "changed-bp", "edge"),
+		bundlePolicy(t, "new-bp", "edge"),
+	}}
+
+	preview, err := bp.ImportBundle(context.Background(), clients, bundle, bp.ImportOptions{Prune: true, DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, bp.BundleSummary{Create: 2, Update: 1, Unchanged: 1, Prune: 1}, preview.Summary)
+
+	actions := map[string]string{}
+	for _, change := range preview.Changes {
+		actions[change.Kind+"/"+change.Name] = change.Action
+	}
+	assert.Equal(t, map[string]string{
+		"Namespace/nginx":          bp.BundleUnchanged,
+		"Deployment/nginx":         bp.BundleCreate,
+		"BindingPolicy/changed-bp": bp.BundleUpdate,
+		"BindingPolicy/new-bp":     bp.BundleCreate,
+		"BindingPolicy/stale-bp":   bp.BundlePrune,
+	}, actions)
+	assert.Equal(t, "BindingPolicy/stale-bp", preview.Changes[len(preview.Changes)-1].Kind+"/"+preview.Changes[len(preview.Changes)-1].Name)
+	require.Len(t, preview.Changes[2].Changes, 1)
+	assert.Equal(t, "spec.clusterSelectors[0].matchLabels.location-group", preview.Changes[2].Changes[0].Path)
+
+	// A dry run changes nothing
+	_, err = clients.WDS.Resource(bundleDeploymentGVR).Namespace("nginx").Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	report, err := bp.ImportBundle(context.Background(), clients, bundle, bp.ImportOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Equal(t, preview.Summary, report.Summary)
+
+	_, err = clients.WDS.Resource(bundleDeploymentGVR).Namespace("nginx").Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	policies, err := clients.WDS.Resource(watcherPolicyGVR).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"changed-bp", "new-bp"}, []string{policies.Items[0].GetName(), policies.Items[1].GetName()})
+
+	// Importing the same bundle again is a no-op
+	again, err := bp.ImportBundle(context.Background(), clients, bundle, bp.ImportOptions{Prune: true, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, bp.BundleSummary{Unchanged: 4}, again.Summary)
+}
+
+func TestImportBundleRefusesToPruneWithoutPolicies(t *testing.T) {
+	clients := bundleClients(bundlePolicy(t, "kept-bp", "edge"))
+	bundle := &bp.Bundle{Objects: []*unstructured.Unstructured{
+		previewObject("apps/v1", "Deployment", "nginx", "nginx", nil),
+	}}
+
+	_, err := bp.ImportBundle(context.Background(), clients, bundle, bp.ImportOptions{Prune: true})
+	var invalid *bp.InvalidBundleError
+	require.ErrorAs(t, err, &invalid)
+
+	policies, err := clients.WDS.Resource(watcherPolicyGVR).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, policies.Items, 1)
+
+	// Without prune the workloads are imported as usual
+	report, err := bp.ImportBundle(context.Background(), clients, bundle, bp.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, bp.BundleSummary{Create: 1}, report.Summary)
+}
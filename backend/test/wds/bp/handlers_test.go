@@ -93,27 +93,52 @@ func TestBindingPolicyWithStatusStruct(t *testing.T) {
 	assert.Contains(t, bpWithStatus.Workloads, "core/v1/services")
 }
 
-// Test the UICreatedPolicies map
-func TestUICreatedPoliciesMap(t *testing.T) {
-	// Test that the map is initialized
-	assert.NotNil(t, bp.UICreatedPolicies)
-
-	// Test adding a policy to the map
-	testPolicy := &bp.StoredBindingPolicy{
-		Name:      "test-policy",
-		Namespace: "default",
+// Test that authoring metadata round-trips through annotations
+func TestPolicyMetadataAnnotations(t *testing.T) {
+	policy := &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-policy",
+			Namespace: "default",
+			Annotations: map[string]string{
+				bp.AnnotationDescription: "from yaml",
+				bp.AnnotationAuthor:      "mallory",
+				bp.AnnotationCreatedAt:   "2001-01-01T00:00:00Z",
+			},
+		},
 	}
 
-	bp.UICreatedPolicies["test-policy"] = testPolicy
+	_, exists := bp.StoredPolicyFor(policy)
+	assert.False(t, exists)
+
+	bp.SetPolicyMetadata(policy, &bp.StoredBindingPolicy{
+		Name:             "test-policy",
+		Namespace:        "default",
+		ClusterSelectors: []map[string]string{{"kubernetes.io/cluster-name": "cluster1"}},
+		Resources:        []string{"deployments"},
+		RawYAML:          "kind: BindingPolicy",
+		Author:           "alice",
+		Description:      "from request",
+	})
 
-	// Test retrieving the policy
-	retrieved, exists := bp.UICreatedPolicies["test-policy"]
+	stored, exists := bp.StoredPolicyFor(policy)
 	assert.True(t, exists)
-	assert.Equal(t, "test-policy", retrieved.Name)
-	assert.Equal(t, "default", retrieved.Namespace)
+	assert.Equal(t, "kind: BindingPolicy", stored.RawYAML)
+	// The author and creation time in the uploaded YAML are replaced
+	assert.Equal(t, "alice", stored.Author)
+	assert.NotEqual(t, "2001-01-01T00:00:00Z", stored.CreatedAt)
+	assert.NotEmpty(t, stored.CreatedAt)
+	// Other annotations set in the uploaded YAML win
+	assert.Equal(t, "from yaml", stored.Description)
 
-	// Clean up
-	delete(bp.UICreatedPolicies, "test-policy")
+	// Without an authenticated user the uploaded author is dropped
+	anonymous := &v1alpha1.BindingPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:        "anonymous-policy",
+		Annotations: map[string]string{bp.AnnotationAuthor: "mallory"},
+	}}
+	bp.SetPolicyMetadata(anonymous, &bp.StoredBindingPolicy{Name: "anonymous-policy", RawYAML: "kind: BindingPolicy"})
+	assert.NotContains(t, anonymous.Annotations, bp.AnnotationAuthor)
+	assert.Equal(t, "cluster1", stored.ClusterSelectors[0]["kubernetes.io/cluster-name"])
+	assert.Equal(t, []string{"deployments"}, stored.Resources)
 }
 
 // Test the contains function logic (since it's unexported)
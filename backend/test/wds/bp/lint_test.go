@@ -0,0 +1,143 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func lintPolicy(name, clusterGroup string, clauses ...v1alpha1.DownsyncPolicyClause) *v1alpha1.BindingPolicy {
+	return &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.BindingPolicySpec{
+			ClusterSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"location-group": clusterGroup}}},
+			Downsync:         clauses,
+		},
+	}
+}
+
+func deploymentsIn(namespace string, createOnly bool, collectors ...string) v1alpha1.DownsyncPolicyClause {
+	apps := "apps"
+	return v1alpha1.DownsyncPolicyClause{
+		DownsyncObjectTest: v1alpha1.DownsyncObjectTest{APIGroup: &apps, Resources: []string{"deployments"}, Namespaces: []string{namespace}},
+		DownsyncModulation: v1alpha1.DownsyncModulation{CreateOnly: createOnly, StatusCollectors: collectors},
+	}
+}
+
+func findingsByRule(report *bp.LintReport, rule string) []bp.LintFinding {
+	var findings []bp.LintFinding
+	for _, finding := range report.Findings {
+		if finding.Rule == rule {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func TestLintBindingPoliciesConflicts(t *testing.T) {
+	policies := []*v1alpha1.BindingPolicy{
+		lintPolicy("a-bp", "edge", deploymentsIn("nginx", false, "replicas")),
+		lintPolicy("b-bp", "edge", deploymentsIn("nginx", true, "replicas")),
+		lintPolicy("c-bp", "edge", deploymentsIn("nginx", false, "health")),
+		// Same objects, but for clusters none of the others select
+		lintPolicy("d-bp", "core", deploymentsIn("nginx", true)),
+	}
+
+	report, err := bp.LintBindingPolicies(context.Background(), policies, previewClients())
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Policies)
+
+	createOnly := findingsByRule(report, bp.RuleConflictingCreateOnly)
+	require.Len(t, createOnly, 2)
+	assert.Equal(t, bp.SeverityError, createOnly[0].Severity)
+	assert.Equal(t, "a-bp", createOnly[0].Policy)
+	assert.Equal(t, []string{"b-bp"}, createOnly[0].RelatedPolicies)
+	assert.Equal(t, []string{"cluster1", "cluster2"}, createOnly[0].Clusters)
+	require.Len(t, createOnly[0].Objects, 2)
+	assert.Equal(t, "nginx", createOnly[0].Objects[0].Name)
+	assert.Equal(t, "b-bp", createOnly[1].Policy)
+	assert.Equal(t, []string{"c-bp"}, createOnly[1].RelatedPolicies)
+
+	collectors := findingsByRule(report, bp.RuleConflictingStatusCollectors)
+	require.Len(t, collectors, 2)
+	assert.Equal(t, bp.SeverityWarning, collectors[0].Severity)
+
+	for _, finding := range report.Findings {
+		assert.NotContains(t, finding.RelatedPolicies, "d-bp")
+	}
+	assert.Equal(t, 2, report.Summary.Errors)
+	assert.Equal(t, bp.SeverityError, report.Findings[0].Severity)
+
+	assert.Len(t, report.FindingsForPolicy("c-bp"), 3)
+}
+
+func TestLintBindingPoliciesSinglePolicy(t *testing.T) {
+	widgets := "example.com"
+	singleton := deploymentsIn("nginx", false)
+	singleton.WantSingletonReportedState = true
+
+	policies := []*v1alpha1.BindingPolicy{
+		lintPolicy("nothing-bp", "moon", deploymentsIn("missing", false)),
+		lintPolicy("crd-bp", "core", v1alpha1.DownsyncPolicyClause{
+			DownsyncObjectTest: v1alpha1.DownsyncObjectTest{APIGroup: &widgets, Resources: []string{"widgets"}},
+		}),
+		lintPolicy("flags-bp", "edge", singleton, deploymentsIn("nginx", true)),
+		lintPolicy("empty-bp", "core"),
+	}
+
+	report, err := bp.LintBindingPolicies(context.Background(), policies, previewClients())
+	require.NoError(t, err)
+
+	rulesFor := func(policy string) []string {
+		rules := []string{}
+		for _, finding := range report.FindingsForPolicy(policy) {
+			rules = append(rules, finding.Rule)
+		}
+		return rules
+	}
+
+	assert.ElementsMatch(t, []string{bp.RuleNoMatchingClusters, bp.RuleMissingNamespace, bp.RuleNoMatchingObjects}, rulesFor("nothing-bp"))
+	assert.ElementsMatch(t, []string{bp.RuleUnknownResource, bp.RuleNoMatchingObjects}, rulesFor("crd-bp"))
+	assert.ElementsMatch(t, []string{bp.RuleContradictoryCreateOnly, bp.RuleSingletonMultipleClusters}, rulesFor("flags-bp"))
+	assert.ElementsMatch(t, []string{bp.RuleNoDownsyncRules}, rulesFor("empty-bp"))
+
+	unknown := findingsByRule(report, bp.RuleUnknownResource)
+	require.Len(t, unknown, 1)
+	assert.Equal(t, bp.SeverityError, unknown[0].Severity)
+	assert.Contains(t, unknown[0].Message, `"widgets"`)
+}
+
+func TestLintBindingPolicyListsOnlyItsResources(t *testing.T) {
+	configMaps := v1alpha1.DownsyncPolicyClause{
+		DownsyncObjectTest: v1alpha1.DownsyncObjectTest{Resources: []string{"configmaps"}, Namespaces: []string{"nginx"}},
+	}
+	policies := []*v1alpha1.BindingPolicy{
+		lintPolicy("a-bp", "edge", deploymentsIn("nginx", false, "replicas")),
+		lintPolicy("b-bp", "edge", deploymentsIn("nginx", true, "replicas")),
+		lintPolicy("c-bp", "edge", deploymentsIn("nginx", false, "health"), configMaps),
+		lintPolicy("d-bp", "core", deploymentsIn("nginx", true)),
+	}
+
+	full, err := bp.LintBindingPolicies(context.Background(), policies, previewClients())
+	require.NoError(t, err)
+
+	clients := previewClients()
+	report, err := bp.LintBindingPolicy(context.Background(), "b-bp", policies, clients)
+	require.NoError(t, err)
+	assert.Equal(t, full.FindingsForPolicy("b-bp"), report.Findings)
+	assert.Equal(t, 2, report.Summary.Errors)
+
+	for _, action := range clients.WDS.(*dynamicfake.FakeDynamicClient).Actions() {
+		assert.NotEqual(t, "configmaps", action.GetResource().Resource, "c-bp's config maps are not listed")
+	}
+
+	report, err = bp.LintBindingPolicy(context.Background(), "missing-bp", policies, clients)
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings)
+}
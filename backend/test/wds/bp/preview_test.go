@@ -0,0 +1,173 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func previewObject(apiVersion, kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func previewClients() bp.PreviewClients {
+	its := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}: "ManagedClusterList",
+		},
+		previewObject("cluster.open-cluster-management.io/v1", "ManagedCluster", "", "cluster1", map[string]string{"location-group": "edge"}),
+		previewObject("cluster.open-cluster-management.io/v1", "ManagedCluster", "", "cluster2", map[string]string{"location-group": "edge"}),
+		previewObject("cluster.open-cluster-management.io/v1", "ManagedCluster", "", "cluster3", map[string]string{"location-group": "core"}),
+	)
+
+	binding := previewObject("control.kubestellar.io/v1alpha1", "Binding", "", "nginx-bp", nil)
+	binding.Object["spec"] = map[string]interface{}{
+		"destinations": []interface{}{
+			map[string]interface{}{"clusterId": "cluster1"},
+			map[string]interface{}{"clusterId": "cluster3"},
+		},
+		"workload": map[string]interface{}{
+			"namespaceScope": []interface{}{
+				map[string]interface{}{"group": "apps", "version": "v1", "resource": "deployments", "namespace": "nginx", "name": "nginx"},
+				map[string]interface{}{"group": "apps", "version": "v1", "resource": "deployments", "namespace": "nginx", "name": "old"},
+			},
+		},
+	}
+
+	wds := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Version: "v1", Resource: "namespaces"}:                                      "NamespaceList",
+			{Version: "v1", Resource: "configmaps"}:                                      "ConfigMapList",
+			{Group: "apps", Version: "v1", Resource: "deployments"}:                      "DeploymentList",
+			{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindings"}: "BindingList",
+		},
+		previewObject("v1", "Namespace", "", "nginx", map[string]string{"app": "nginx"}),
+		previewObject("v1", "Namespace", "", "other", nil),
+		previewObject("apps/v1", "Deployment", "nginx", "nginx", map[string]string{"app": "nginx"}),
+		previewObject("apps/v1", "Deployment", "nginx", "sidecar", map[string]string{"app": "nginx"}),
+		previewObject("apps/v1", "Deployment", "other", "nginx", map[string]string{"app": "nginx"}),
+		previewObject("v1", "ConfigMap", "nginx", "settings", map[string]string{"app": "nginx"}),
+		binding,
+	)
+
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Verbs: []string{"get", "list"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get", "list"}},
+				{Name: "deployments/status", Kind: "Deployment", Namespaced: true, Verbs: []string{"get"}},
+			},
+		},
+	}
+
+	return bp.PreviewClients{ITS: its, WDS: wds, WDSDiscovery: discovery}
+}
+
+func TestPreviewBindingPolicy(t *testing.T) {
+	apps := "apps"
+	policy := &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-bp"},
+		Spec: v1alpha1.BindingPolicySpec{
+			ClusterSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"location-group": "edge"}}},
+			Downsync: []v1alpha1.DownsyncPolicyClause{{
+				DownsyncObjectTest: v1alpha1.DownsyncObjectTest{
+					APIGroup:           &apps,
+					Resources:          []string{"deployments"},
+					NamespaceSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"app": "nginx"}}},
+					ObjectSelectors:    []metav1.LabelSelector{{MatchLabels: map[string]string{"app": "nginx"}}},
+				},
+			}},
+		},
+	}
+
+	result, err := bp.PreviewBindingPolicy(context.Background(), policy, previewClients())
+	require.NoError(t, err)
+
+	assert.True(t, result.CurrentSelectionFound)
+	assert.Equal(t, []string{"cluster1", "cluster2"}, result.Clusters.Matched)
+	assert.Equal(t, []string{"cluster2"}, result.Clusters.Added)
+	assert.Equal(t, []string{"cluster3"}, result.Clusters.Removed)
+	assert.Equal(t, []string{"cluster1"}, result.Clusters.Unchanged)
+
+	require.Len(t, result.Objects.Matched, 2)
+	assert.Equal(t, "nginx", result.Objects.Matched[0].Name)
+	assert.Equal(t, "sidecar", result.Objects.Matched[1].Name)
+	require.Len(t, result.Objects.Added, 1)
+	assert.Equal(t, "sidecar", result.Objects.Added[0].Name)
+	require.Len(t, result.Objects.Removed, 1)
+	assert.Equal(t, "old", result.Objects.Removed[0].Name)
+	assert.False(t, result.Objects.Truncated)
+}
+
+func TestPreviewBindingPolicyWithoutBinding(t *testing.T) {
+	core := "core"
+	policy := &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-bp"},
+		Spec: v1alpha1.BindingPolicySpec{
+			ClusterSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"location-group": "core"}}},
+			Downsync: []v1alpha1.DownsyncPolicyClause{{
+				DownsyncObjectTest: v1alpha1.DownsyncObjectTest{
+					APIGroup:   &core,
+					Resources:  []string{"*"},
+					Namespaces: []string{"nginx"},
+				},
+			}},
+		},
+	}
+
+	result, err := bp.PreviewBindingPolicy(context.Background(), policy, previewClients())
+	require.NoError(t, err)
+
+	assert.False(t, result.CurrentSelectionFound)
+	assert.Equal(t, []string{"cluster3"}, result.Clusters.Added)
+	assert.Empty(t, result.Clusters.Removed)
+
+	// Namespaces are cluster-scoped, so the namespace test does not apply to them
+	var names []string
+	for _, obj := range result.Objects.Added {
+		names = append(names, obj.Resource+"/"+obj.Name)
+	}
+	assert.ElementsMatch(t, []string{"configmaps/settings", "namespaces/nginx", "namespaces/other"}, names)
+}
+
+func TestPreviewBindingPolicyInvalidSelector(t *testing.T) {
+	policy := &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "bad-bp"},
+		Spec: v1alpha1.BindingPolicySpec{
+			ClusterSelectors: []metav1.LabelSelector{{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Bogus"}},
+			}},
+		},
+	}
+
+	_, err := bp.PreviewBindingPolicy(context.Background(), policy, previewClients())
+	var selectorErr *bp.InvalidSelectorError
+	require.ErrorAs(t, err, &selectorErr)
+	assert.Len(t, selectorErr.Problems, 1)
+	assert.Contains(t, selectorErr.Problems[0], "invalid cluster selector")
+}
@@ -0,0 +1,154 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func condition(conditionType, status string) map[string]interface{} {
+	return map[string]interface{}{"type": conditionType, "status": status}
+}
+
+func propagationClients(objects ...runtime.Object) bp.PropagationClients {
+	policy := previewObject("control.kubestellar.io/v1alpha1", "BindingPolicy", "", "nginx-bp", nil)
+	policy.SetGeneration(2)
+	policy.Object["status"] = map[string]interface{}{
+		"observedGeneration": int64(2),
+		"conditions":         []interface{}{condition("Ready", "True")},
+	}
+
+	binding := previewObject("control.kubestellar.io/v1alpha1", "Binding", "", "nginx-bp", nil)
+	binding.Object["spec"] = map[string]interface{}{
+		"destinations": []interface{}{
+			map[string]interface{}{"clusterId": "cluster2"},
+			map[string]interface{}{"clusterId": "cluster1"},
+		},
+		"workload": map[string]interface{}{
+			"clusterScope": []interface{}{
+				map[string]interface{}{"group": "", "version": "v1", "resource": "namespaces", "name": "nginx"},
+			},
+			"namespaceScope": []interface{}{
+				map[string]interface{}{"group": "apps", "version": "v1", "resource": "deployments", "namespace": "nginx", "name": "nginx"},
+			},
+		},
+	}
+
+	wds := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindingpolicies"}: "BindingPolicyList",
+			{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindings"}:        "BindingList",
+		},
+		policy, binding,
+	)
+
+	its := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"}: "ManifestWorkList",
+			{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "workstatuses"}:     "WorkStatusList",
+		},
+		objects...,
+	)
+
+	return bp.PropagationClients{ITS: its, WDS: wds}
+}
+
+func manifestWork(cluster string, manifests []interface{}, statuses []interface{}) *unstructured.Unstructured {
+	work := previewObject("work.open-cluster-management.io/v1", "ManifestWork", cluster, "nginx-bp-work", map[string]string{
+		"transport.kubestellar.io/originOwnerReferenceBindingKey": "nginx-bp",
+	})
+	work.Object["spec"] = map[string]interface{}{"workload": map[string]interface{}{"manifests": manifests}}
+	if statuses != nil {
+		work.Object["status"] = map[string]interface{}{
+			"resourceStatus": map[string]interface{}{"manifests": statuses},
+		}
+	}
+	return work
+}
+
+func manifestStatus(group, resource, namespace, name string, conditions ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"resourceMeta": map[string]interface{}{
+			"group": group, "version": "v1", "resource": resource, "namespace": namespace, "name": name,
+		},
+		"conditions": conditions,
+	}
+}
+
+func TestGetPropagationStatus(t *testing.T) {
+	namespaceManifest := map[string]interface{}{
+		"apiVersion": "v1", "kind": "Namespace", "metadata": map[string]interface{}{"name": "nginx"},
+	}
+	deploymentManifest := map[string]interface{}{
+		"apiVersion": "apps/v1", "kind": "Deployment", "metadata": map[string]interface{}{"name": "nginx", "namespace": "nginx"},
+	}
+
+	// cluster1 applied everything, but the deployment is not ready
+	work1 := manifestWork("cluster1",
+		[]interface{}{namespaceManifest, deploymentManifest},
+		[]interface{}{
+			manifestStatus("", "namespaces", "", "nginx", condition("Applied", "True"), condition("Available", "True")),
+			manifestStatus("apps", "deployments", "nginx", "nginx", condition("Applied", "True"), condition("Available", "True")),
+		})
+	workStatus := previewObject("control.kubestellar.io/v1alpha1", "WorkStatus", "cluster1", "nginx-deployment", nil)
+	workStatus.Object["spec"] = map[string]interface{}{
+		"sourceRef": map[string]interface{}{
+			"group": "apps", "version": "v1", "resource": "deployments", "namespace": "nginx", "name": "nginx",
+		},
+	}
+	workStatus.Object["status"] = map[string]interface{}{"replicas": int64(3), "readyReplicas": int64(1)}
+
+	// cluster2 received the work but has not reported status yet
+	work2 := manifestWork("cluster2", []interface{}{namespaceManifest, deploymentManifest}, nil)
+
+	status, err := bp.GetPropagationStatus(context.Background(), "nginx-bp", propagationClients(work1, workStatus, work2))
+	require.NoError(t, err)
+
+	assert.True(t, status.Reconciled)
+	assert.Len(t, status.Conditions, 1)
+	assert.Equal(t, bp.PolicyPhaseDegraded, status.Phase)
+	assert.Equal(t, bp.PropagationSummary{Clusters: 2, Objects: 4, Delivered: 4, Applied: 2, Healthy: 1, Degraded: 1}, status.Summary)
+
+	require.Len(t, status.Clusters, 2)
+	cluster1 := status.Clusters[0]
+	assert.Equal(t, "cluster1", cluster1.Cluster)
+	assert.Equal(t, bp.PolicyPhaseDegraded, cluster1.Phase)
+	require.Len(t, cluster1.Objects, 2)
+	assert.Equal(t, "namespaces", cluster1.Objects[0].Resource)
+	assert.Equal(t, bp.PhaseHealthy, cluster1.Objects[0].Phase)
+	assert.Equal(t, "deployments", cluster1.Objects[1].Resource)
+	assert.Equal(t, bp.PhaseDegraded, cluster1.Objects[1].Phase)
+	assert.Equal(t, "1/3 replicas ready", cluster1.Objects[1].Message)
+
+	cluster2 := status.Clusters[1]
+	assert.Equal(t, bp.PolicyPhaseProgressing, cluster2.Phase)
+	for _, obj := range cluster2.Objects {
+		assert.True(t, obj.Delivered)
+		assert.Equal(t, bp.PhaseDelivered, obj.Phase)
+	}
+}
+
+func TestGetPropagationStatusPending(t *testing.T) {
+	status, err := bp.GetPropagationStatus(context.Background(), "nginx-bp", propagationClients())
+	require.NoError(t, err)
+
+	assert.Equal(t, bp.PolicyPhaseProgressing, status.Phase)
+	assert.Equal(t, 0, status.Summary.Delivered)
+	for _, cluster := range status.Clusters {
+		for _, obj := range cluster.Objects {
+			assert.Equal(t, bp.PhasePending, obj.Phase)
+		}
+	}
+}
+
+func TestGetPropagationStatusNotFound(t *testing.T) {
+	_, err := bp.GetPropagationStatus(context.Background(), "missing", propagationClients())
+	assert.ErrorIs(t, err, bp.ErrPolicyNotFound)
+}
@@ -0,0 +1,71 @@
+package bp_test
+
+import (
+	"testing"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func revisionPolicy(cluster string) *v1alpha1.BindingPolicy {
+	return &v1alpha1.BindingPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "nginx-bp",
+			ResourceVersion: "42",
+			Annotations:     map[string]string{"yaml": "kind: BindingPolicy", "owner": "team-a"},
+		},
+		Spec: v1alpha1.BindingPolicySpec{
+			ClusterSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"name": cluster}}},
+			Downsync: []v1alpha1.DownsyncPolicyClause{{
+				DownsyncObjectTest: v1alpha1.DownsyncObjectTest{Resources: []string{"deployments"}},
+			}},
+		},
+	}
+}
+
+func TestPolicySnapshotIgnoresServerFields(t *testing.T) {
+	first, firstHash, err := bp.PolicySnapshot(revisionPolicy("cluster1"))
+	require.NoError(t, err)
+
+	changed := revisionPolicy("cluster1")
+	changed.ResourceVersion = "43"
+	changed.Status.ObservedGeneration = 3
+	changed.Annotations["yaml"] = "something else"
+	_, changedHash, err := bp.PolicySnapshot(changed)
+	require.NoError(t, err)
+
+	assert.Equal(t, firstHash, changedHash)
+	assert.NotContains(t, string(first), "resourceVersion")
+	assert.NotContains(t, string(first), "kind: BindingPolicy")
+
+	_, otherHash, err := bp.PolicySnapshot(revisionPolicy("cluster2"))
+	require.NoError(t, err)
+	assert.NotEqual(t, firstHash, otherHash)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from, _, err := bp.PolicySnapshot(revisionPolicy("cluster1"))
+	require.NoError(t, err)
+
+	policy := revisionPolicy("cluster2")
+	delete(policy.Annotations, "owner")
+	policy.Labels = map[string]string{"env": "prod"}
+	to, _, err := bp.PolicySnapshot(policy)
+	require.NoError(t, err)
+
+	changes, err := bp.DiffSnapshots(from, to)
+	require.NoError(t, err)
+
+	assert.Equal(t, []bp.FieldChange{
+		{Path: "metadata.annotations", Type: bp.ChangeRemoved, From: map[string]interface{}{"owner": "team-a"}},
+		{Path: "metadata.labels", Type: bp.ChangeAdded, To: map[string]interface{}{"env": "prod"}},
+		{Path: "spec.clusterSelectors[0].matchLabels.name", Type: bp.ChangeChanged, From: "cluster1", To: "cluster2"},
+	}, changes)
+
+	changes, err = bp.DiffSnapshots(from, from)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,94 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func singletonBinding(name string, clusters ...string) *unstructured.Unstructured {
+	destinations := []interface{}{}
+	for _, cluster := range clusters {
+		destinations = append(destinations, map[string]interface{}{"clusterId": cluster})
+	}
+	binding := previewObject("control.kubestellar.io/v1alpha1", "Binding", "", name, nil)
+	binding.Object["spec"] = map[string]interface{}{"destinations": destinations}
+	return binding
+}
+
+func TestGetSingletonStatus(t *testing.T) {
+	singleton := deploymentsIn("nginx", false)
+	singleton.WantSingletonReportedState = true
+
+	reported := previewObject("apps/v1", "Deployment", "nginx", "nginx", nil)
+	reported.SetGeneration(3)
+	reported.Object["status"] = map[string]interface{}{"observedGeneration": int64(3), "readyReplicas": int64(1)}
+	clientWithDeployment := func(deployment *unstructured.Unstructured, objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+		objects = append(objects,
+			previewObject("v1", "Namespace", "", "nginx", nil),
+			deployment,
+		)
+		return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				bundleNamespaceGVR:  "NamespaceList",
+				bundleDeploymentGVR: "DeploymentList",
+				watcherPolicyGVR:    "BindingPolicyList",
+				{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindings"}: "BindingList",
+			},
+			objects...,
+		)
+	}
+	clientWith := func(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+		return clientWithDeployment(reported.DeepCopy(), objects...)
+	}
+	policy := func(name string, clause bool) *unstructured.Unstructured {
+		obj := bundlePolicy(t, name, "edge")
+		if clause {
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(lintPolicy(name, "edge", singleton))
+			require.NoError(t, err)
+			obj.Object["spec"] = content["spec"]
+		}
+		return obj
+	}
+	nginx := bp.PreviewObject{Group: "apps", Version: "v1", Resource: "deployments", Namespace: "nginx", Name: "nginx"}
+
+	status, err := bp.GetSingletonStatus(context.Background(), clientWith(policy("plain-bp", false), singletonBinding("plain-bp", "cluster1")), nginx, true)
+	require.NoError(t, err)
+	assert.False(t, status.Requested)
+	assert.False(t, status.Reported)
+
+	status, err = bp.GetSingletonStatus(context.Background(), clientWith(policy("single-bp", true), singletonBinding("single-bp", "cluster1")), nginx, true)
+	require.NoError(t, err)
+	assert.True(t, status.Requested)
+	assert.True(t, status.Reported)
+	assert.Equal(t, []string{"single-bp"}, status.Policies)
+	assert.Equal(t, "cluster1", status.Cluster)
+
+	// Until the WEC writes the status back the object is only waiting for it
+	for _, pending := range []map[string]interface{}{nil, {"observedGeneration": int64(2)}} {
+		deployment := reported.DeepCopy()
+		delete(deployment.Object, "status")
+		if pending != nil {
+			deployment.Object["status"] = pending
+		}
+		status, err = bp.GetSingletonStatus(context.Background(), clientWithDeployment(deployment, policy("single-bp", true), singletonBinding("single-bp", "cluster1")), nginx, true)
+		require.NoError(t, err)
+		assert.True(t, status.Requested)
+		assert.False(t, status.Reported)
+		assert.Equal(t, "cluster1", status.Cluster)
+		assert.Contains(t, status.Message, "waiting")
+	}
+
+	status, err = bp.GetSingletonStatus(context.Background(), clientWith(policy("single-bp", true), singletonBinding("single-bp", "cluster1", "cluster2")), nginx, true)
+	require.NoError(t, err)
+	assert.True(t, status.Requested)
+	assert.False(t, status.Reported)
+	assert.Equal(t, []string{"cluster1", "cluster2"}, status.Clusters)
+}
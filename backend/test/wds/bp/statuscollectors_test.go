@@ -0,0 +1,130 @@
+package bp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestNewStatusCollector(t *testing.T) {
+	collector, err := bp.NewStatusCollector(&bp.StatusCollectorRequest{
+		Name: "ready-replicas",
+		StatusCollectorSpec: bp.StatusCollectorSpec{
+			GroupBy: []bp.NamedExpression{{Name: "ready", Def: "returned.status.readyReplicas"}},
+			CombinedFields: []bp.NamedAggregator{
+				{Name: "count", Type: "COUNT"},
+				{Name: "total", Type: "SUM", Subject: "returned.status.replicas"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "StatusCollector", collector.GetKind())
+	assert.Equal(t, "ready-replicas", collector.GetName())
+	assert.Equal(t, int64(20), collector.Object["spec"].(map[string]interface{})["limit"])
+
+	_, err = bp.NewStatusCollector(&bp.StatusCollectorRequest{
+		Name: "Bad_Name",
+		StatusCollectorSpec: bp.StatusCollectorSpec{
+			Select: []bp.NamedExpression{{Name: "phase", Def: "returned.status.phase"}},
+			CombinedFields: []bp.NamedAggregator{
+				{Name: "phase", Type: "COUNT", Subject: "x"},
+				{Name: "avg", Type: "AVG"},
+				{Name: "median", Type: "MEDIAN"},
+			},
+		},
+	})
+	require.Error(t, err)
+	for _, problem := range []string{
+		"invalid name",
+		"select cannot be combined",
+		"column phase is defined twice",
+		"COUNT takes no subject",
+		"AVG needs a subject",
+		"median: type must be one of",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+}
+
+func TestAttachStatusCollectors(t *testing.T) {
+	policy := lintPolicy("nginx-bp", "edge", deploymentsIn("nginx", false), deploymentsIn("other", false, "health"))
+
+	require.NoError(t, bp.AttachStatusCollectors(policy, []string{"replicas", "replicas", "ready"}, []int{0}))
+	assert.Equal(t, []string{"replicas", "ready"}, policy.Spec.Downsync[0].StatusCollectors)
+	assert.Equal(t, []string{"health"}, policy.Spec.Downsync[1].StatusCollectors)
+
+	other := lintPolicy("other-bp", "core", deploymentsIn("nginx", false, "ready"))
+	assert.Equal(t, map[string][]string{
+		"replicas": {"nginx-bp"},
+		"ready":    {"nginx-bp", "other-bp"},
+		"health":   {"nginx-bp"},
+	}, bp.StatusCollectorUsage([]*v1alpha1.BindingPolicy{policy, other}))
+
+	// Without rule indexes every rule is changed; no collectors detaches them
+	require.NoError(t, bp.AttachStatusCollectors(policy, nil, nil))
+	assert.Nil(t, policy.Spec.Downsync[0].StatusCollectors)
+	assert.Nil(t, policy.Spec.Downsync[1].StatusCollectors)
+
+	assert.Error(t, bp.AttachStatusCollectors(policy, []string{"ready"}, []int{2}))
+}
+
+func TestGetCombinedStatus(t *testing.T) {
+	combined := func(name, policy, deployment string, rows ...interface{}) *unstructured.Unstructured {
+		obj := previewObject("control.kubestellar.io/v1alpha1", "CombinedStatus", "nginx", name, map[string]string{
+			"status.kubestellar.io/api-group":     "apps",
+			"status.kubestellar.io/resource":      "deployments",
+			"status.kubestellar.io/namespace":     "nginx",
+			"status.kubestellar.io/name":          deployment,
+			"status.kubestellar.io/bindingpolicy": policy,
+		})
+		obj.Object["results"] = []interface{}{
+			map[string]interface{}{
+				"name":        "ready-replicas",
+				"columnNames": []interface{}{"ready", "count"},
+				"rows":        rows,
+			},
+		}
+		return obj
+	}
+	row := func(ready bool, count string) interface{} {
+		return map[string]interface{}{"columns": []interface{}{
+			map[string]interface{}{"type": "Boolean", "bool": ready},
+			map[string]interface{}{"type": "Number", "number": count},
+		}}
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "combinedstatuses"}: "CombinedStatusList",
+		},
+		combined("uid1.bp2", "second-bp", "nginx", row(true, "2")),
+		combined("uid1.bp1", "first-bp", "nginx", row(true, "3"), row(false, "1")),
+		combined("uid2.bp1", "first-bp", "sidecar", row(true, "1")),
+	)
+
+	results, err := bp.GetCombinedStatus(context.Background(), client, bp.CombinedStatusQuery{
+		APIGroup: "apps", Resource: "deployments", Namespace: "nginx", Name: "nginx",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "first-bp", results[0].Policy)
+	require.Len(t, results[0].Tables, 1)
+	assert.Equal(t, "ready-replicas", results[0].Tables[0].Collector)
+	assert.Equal(t, []string{"ready", "count"}, results[0].Tables[0].Columns)
+	assert.Equal(t, [][]interface{}{{true, 3.0}, {false, 1.0}}, results[0].Tables[0].Rows)
+
+	results, err = bp.GetCombinedStatus(context.Background(), client, bp.CombinedStatusQuery{
+		APIGroup: "apps", Resource: "deployments", Namespace: "nginx", Name: "nginx", Policy: "second-bp",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, [][]interface{}{{true, 2.0}}, results[0].Tables[0].Rows)
+}
@@ -0,0 +1,126 @@
+package bp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kubestellar/ui/backend/models"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const teamTemplateBody = `apiVersion: control.kubestellar.io/v1alpha1
+kind: BindingPolicy
+metadata:
+  name: {{ .team }}-{{ .region }}
+spec:
+  clusterSelectors:
+  - matchLabels:
+      region: {{ quote .region }}
+{{- range $key, $value := .extraClusterLabels }}
+      {{ $key }}: {{ quote $value }}
+{{- end }}
+  downsync:
+  - resources: ["deployments", "services"]
+    namespaces: {{ toJSON .namespaces }}
+    createOnly: {{ .createOnly }}
+`
+
+func teamTemplate() *bp.BPTemplateDefinition {
+	return &bp.BPTemplateDefinition{
+		Name: "team-to-region",
+		Parameters: []bp.TemplateParameter{
+			{Name: "team", Type: bp.ParamString, Required: true, Pattern: "^[a-z][a-z0-9-]*$"},
+			{Name: "region", Type: bp.ParamString, Required: true, Enum: []string{"eu", "us"}},
+			{Name: "namespaces", Type: bp.ParamStringList, Default: []interface{}{"default"}},
+			{Name: "createOnly", Type: bp.ParamBoolean},
+			{Name: "extraClusterLabels", Type: bp.ParamLabels},
+		},
+		Body: teamTemplateBody,
+	}
+}
+
+func storedTemplate(t *testing.T, def *bp.BPTemplateDefinition, version int) *models.BPTemplate {
+	parameters, err := json.Marshal(def.Parameters)
+	require.NoError(t, err)
+	return &models.BPTemplate{Name: def.Name, Version: version, Parameters: parameters, Body: def.Body}
+}
+
+func TestValidateTemplateDefinition(t *testing.T) {
+	require.NoError(t, bp.ValidateTemplateDefinition(teamTemplate()))
+
+	broken := teamTemplate()
+	broken.Name = "Not_A_Name"
+	broken.Parameters = append(broken.Parameters,
+		bp.TemplateParameter{Name: "team", Type: bp.ParamString},
+		bp.TemplateParameter{Name: "replicas", Type: "float"},
+		bp.TemplateParameter{Name: "count", Type: bp.ParamInteger, Default: 1.5},
+		bp.TemplateParameter{Name: "flag", Type: bp.ParamBoolean, Enum: []string{"yes"}},
+	)
+	broken.Body = "{{ .team "
+
+	err := bp.ValidateTemplateDefinition(broken)
+	var validationErr *bp.TemplateValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Problems, 6)
+
+	// A body that parses but does not produce a BindingPolicy is rejected too
+	noClusters := teamTemplate()
+	noClusters.Parameters = nil
+	noClusters.Body = "apiVersion: control.kubestellar.io/v1alpha1\nkind: BindingPolicy\nmetadata:\n  name: x\n"
+	err = bp.ValidateTemplateDefinition(noClusters)
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Error(), "no cluster selectors")
+}
+
+func TestResolveTemplateParameters(t *testing.T) {
+	params := []bp.TemplateParameter{
+		{Name: "name", Type: bp.ParamString, Required: true},
+		{Name: "replicas", Type: bp.ParamInteger, Default: 2.0},
+		{Name: "labels", Type: bp.ParamLabels},
+	}
+
+	resolved, err := bp.ResolveTemplateParameters(params, map[string]interface{}{"name": "web"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "web", "replicas": int64(2), "labels": map[string]string{}}, resolved)
+
+	_, err = bp.ResolveTemplateParameters(params, map[string]interface{}{
+		"replicas": 2.5,
+		"labels":   map[string]interface{}{"bad key!": "x"},
+		"extra":    true,
+	})
+	var validationErr *bp.TemplateValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Problems, 4)
+	assert.Equal(t, "parameter name is required", validationErr.Problems[0])
+	assert.Equal(t, "parameter replicas: expected an integer", validationErr.Problems[1])
+	assert.Contains(t, validationErr.Problems[2], `invalid label key "bad key!"`)
+	assert.Equal(t, "unknown parameter extra", validationErr.Problems[3])
+}
+
+func TestRenderBPTemplate(t *testing.T) {
+	stored := storedTemplate(t, teamTemplate(), 3)
+
+	rendered, err := bp.RenderBPTemplate(stored, map[string]interface{}{
+		"team":               "payments",
+		"region":             "eu",
+		"namespaces":         []interface{}{"payments", "payments-jobs"},
+		"extraClusterLabels": map[string]interface{}{"tier": "prod"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "team-to-region@v3", rendered.Template)
+	assert.Equal(t, "payments-eu", rendered.Policy.Name)
+	require.Len(t, rendered.Policy.Spec.ClusterSelectors, 1)
+	assert.Equal(t, map[string]string{"region": "eu", "tier": "prod"}, rendered.Policy.Spec.ClusterSelectors[0].MatchLabels)
+	require.Len(t, rendered.Policy.Spec.Downsync, 1)
+	assert.Equal(t, []string{"payments", "payments-jobs"}, rendered.Policy.Spec.Downsync[0].Namespaces)
+	assert.False(t, rendered.Policy.Spec.Downsync[0].CreateOnly)
+	assert.Equal(t, []string{"payments", "payments-jobs"}, rendered.Parameters["namespaces"])
+
+	_, err = bp.RenderBPTemplate(stored, map[string]interface{}{"team": "payments", "region": "apac"})
+	var validationErr *bp.TemplateValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Problems[0], `"apac" is not one of eu, us`)
+}
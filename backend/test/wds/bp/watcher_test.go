@@ -0,0 +1,168 @@
+package bp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/health"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var watcherPolicyGVR = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindingpolicies"}
+
+type recordedEvent struct {
+	eventType  watch.EventType
+	wdsContext string
+	name       string
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+func (r *eventRecorder) handle(eventType watch.EventType, wdsContext string, policy *v1alpha1.BindingPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{eventType, wdsContext, policy.Name})
+}
+
+func (r *eventRecorder) has(event recordedEvent) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func watcherClient(names ...string) *dynamicfake.FakeDynamicClient {
+	objects := []runtime.Object{}
+	for _, name := range names {
+		objects = append(objects, previewObject("control.kubestellar.io/v1alpha1", "BindingPolicy", "", name, nil))
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{watcherPolicyGVR: "BindingPolicyList"}, objects...)
+}
+
+func componentStatus(wdsContext string) string {
+	return health.ReportedComponents()[bp.PolicyWatcherComponentPrefix+wdsContext].Status
+}
+
+func TestPolicyWatchSupervisorWatchesEveryWDS(t *testing.T) {
+	clients := map[string]*dynamicfake.FakeDynamicClient{
+		"wds-a": watcherClient("shared-bp"),
+		"wds-b": watcherClient("shared-bp", "only-b"),
+	}
+	recorder := &eventRecorder{}
+	var syncedMu sync.Mutex
+	synced := map[string]bool{}
+
+	supervisor := bp.NewPolicyWatchSupervisor(bp.PolicyWatchConfig{
+		ListContexts: func() ([]string, error) { return []string{"wds-a", "wds-b"}, nil },
+		NewClient:    func(wdsContext string) (dynamic.Interface, error) { return clients[wdsContext], nil },
+		Handler:      recorder.handle,
+		OnSynced: func(wdsContext string) {
+			syncedMu.Lock()
+			defer syncedMu.Unlock()
+			synced[wdsContext] = true
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		supervisor.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return componentStatus("wds-a") == "healthy" && componentStatus("wds-b") == "healthy"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"wds-a", "wds-b"}, supervisor.Contexts())
+	assert.True(t, recorder.has(recordedEvent{watch.Added, "wds-a", "shared-bp"}))
+	assert.True(t, recorder.has(recordedEvent{watch.Added, "wds-b", "shared-bp"}))
+	assert.True(t, recorder.has(recordedEvent{watch.Added, "wds-b", "only-b"}))
+	syncedMu.Lock()
+	assert.Equal(t, map[string]bool{"wds-a": true, "wds-b": true}, synced)
+	syncedMu.Unlock()
+
+	policies, ok := supervisor.Policies("wds-b")
+	require.True(t, ok)
+	require.Len(t, policies, 2)
+	assert.Equal(t, "only-b", policies[0].Name)
+
+	// Changes after the initial list are delivered to the right WDS
+	added := previewObject("control.kubestellar.io/v1alpha1", "BindingPolicy", "", "new-bp", nil)
+	_, err := clients["wds-a"].Resource(watcherPolicyGVR).Create(ctx, added, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, clients["wds-b"].Resource(watcherPolicyGVR).Delete(ctx, "only-b", metav1.DeleteOptions{}))
+
+	assert.Eventually(t, func() bool {
+		return recorder.has(recordedEvent{watch.Added, "wds-a", "new-bp"}) &&
+			recorder.has(recordedEvent{watch.Deleted, "wds-b", "only-b"})
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, recorder.has(recordedEvent{watch.Added, "wds-b", "new-bp"}))
+
+	cancel()
+	<-done
+	_, reported := health.ReportedComponents()[bp.PolicyWatcherComponentPrefix+"wds-a"]
+	assert.False(t, reported)
+}
+
+func TestPolicyWatchSupervisorRetriesWithBackoff(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	recorder := &eventRecorder{}
+
+	supervisor := bp.NewPolicyWatchSupervisor(bp.PolicyWatchConfig{
+		ListContexts: func() ([]string, error) { return []string{"wds-retry"}, nil },
+		NewClient: func(string) (dynamic.Interface, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts < 3 {
+				return nil, errors.New("connection refused")
+			}
+			return watcherClient("nginx-bp"), nil
+		},
+		Handler: recorder.handle,
+		Backoff: wait.Backoff{Duration: 50 * time.Millisecond, Factor: 2, Steps: 10},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go supervisor.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return componentStatus("wds-retry") == "degraded"
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Contains(t, health.ReportedComponents()[bp.PolicyWatcherComponentPrefix+"wds-retry"].Error, "connection refused")
+
+	require.Eventually(t, func() bool {
+		return componentStatus("wds-retry") == "healthy"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, recorder.has(recordedEvent{watch.Added, "wds-retry", "nginx-bp"}))
+
+	component := health.ReportedComponents()[bp.PolicyWatcherComponentPrefix+"wds-retry"]
+	assert.Equal(t, 2, component.Metadata["restarts"])
+	mu.Lock()
+	assert.Equal(t, 3, attempts)
+	mu.Unlock()
+}
@@ -66,7 +66,7 @@ func TestGetClientSetKubeConfig(t *testing.T) {
 	kubeconfigPath := setupTestKubeconfig(t)
 	t.Setenv("KUBECONFIG", kubeconfigPath)
 
-	clientset, err := wds.GetClientSetKubeConfig()
+	clientset, err := wds.GetClientSetKubeConfig("wds1")
 	assert.NoError(t, err)
 	assert.NotNil(t, clientset)
 }
@@ -26,6 +26,17 @@ type Claims struct {
 const (
 	tokenTypeAccess  = "access"
 	tokenTypeRefresh = "refresh"
+
+	// TokenTypeMFAPending is issued after a correct password for users with
+	// two-factor authentication enabled. It must be exchanged for an access
+	// token together with a valid TOTP or recovery code.
+	TokenTypeMFAPending = "mfa_pending"
+	// TokenTypeMFAEnroll is issued after a correct password for users who are
+	// required to use two-factor authentication but have not enrolled yet.
+	TokenTypeMFAEnroll = "mfa_enroll"
+
+	// MFATokenExpiration is how long an interim MFA token stays valid
+	MFATokenExpiration = 5 * time.Minute
 )
 
 // GenerateToken creates a JWT token for the user
@@ -48,6 +59,27 @@ func ValidateRefreshToken(tokenString string) (*Claims, error) {
 	return validateTokenOfType(tokenString, tokenTypeRefresh)
 }
 
+// GenerateMFAToken creates a short-lived interim token of the given MFA token type
+func GenerateMFAToken(username string, userID int, tokenType string) (string, error) {
+	if tokenType != TokenTypeMFAPending && tokenType != TokenTypeMFAEnroll {
+		return "", fmt.Errorf("unsupported MFA token type: %s", tokenType)
+	}
+	return generateToken(username, false, nil, userID, tokenType, MFATokenExpiration)
+}
+
+// ValidateMFAToken validates an interim MFA token of the given type and returns claims
+func ValidateMFAToken(tokenString string, tokenType string) (*Claims, error) {
+	claims, err := validateTokenOfType(tokenString, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	// Interim tokens must always carry their type, unlike legacy access tokens
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("invalid token type")
+	}
+	return claims, nil
+}
+
 func generateToken(username string, isAdmin bool, permissions map[string]string, userID int, tokenType string, expiry time.Duration) (string, error) {
 	registeredClaims := jwt.RegisteredClaims{
 		IssuedAt: jwt.NewNumericDate(time.Now()),
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Environment variables controlling brute-force protection for the login endpoint
+const (
+	LoginMaxFailuresPerUserEnv = "LOGIN_MAX_FAILED_ATTEMPTS"
+	LoginMaxFailuresPerIPEnv   = "LOGIN_MAX_FAILED_ATTEMPTS_PER_IP"
+	LoginFailureWindowEnv      = "LOGIN_FAILURE_WINDOW_MINUTES"
+	LoginLockoutDurationEnv    = "LOGIN_LOCKOUT_MINUTES"
+	LoginDelayAfterEnv         = "LOGIN_DELAY_AFTER_ATTEMPTS"
+	LoginDelayBaseEnv          = "LOGIN_DELAY_BASE_SECONDS"
+	LoginDelayMaxEnv           = "LOGIN_DELAY_MAX_SECONDS"
+)
+
+// LoginPolicy describes how failed logins are throttled and when accounts are locked
+type LoginPolicy struct {
+	MaxFailuresPerUser int
+	MaxFailuresPerIP   int
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	DelayAfter         int
+	DelayBase          time.Duration
+	DelayMax           time.Duration
+}
+
+// DefaultLoginPolicy returns the policy used when no environment overrides are set
+func DefaultLoginPolicy() LoginPolicy {
+	return LoginPolicy{
+		MaxFailuresPerUser: 5,
+		MaxFailuresPerIP:   20,
+		FailureWindow:      15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		DelayAfter:         2,
+		DelayBase:          1 * time.Second,
+		DelayMax:           30 * time.Second,
+	}
+}
+
+var (
+	loginPolicy     LoginPolicy
+	loginPolicyOnce sync.Once
+)
+
+// GetLoginPolicy returns the process-wide login policy, loading it on first
+// use so that variables from .env are seen
+func GetLoginPolicy() LoginPolicy {
+	loginPolicyOnce.Do(func() {
+		loginPolicy = LoadLoginPolicy()
+	})
+	return loginPolicy
+}
+
+// LoadLoginPolicy builds the login policy from environment variables
+func LoadLoginPolicy() LoginPolicy {
+	policy := DefaultLoginPolicy()
+	policy.MaxFailuresPerUser = envInt(LoginMaxFailuresPerUserEnv, policy.MaxFailuresPerUser)
+	policy.MaxFailuresPerIP = envInt(LoginMaxFailuresPerIPEnv, policy.MaxFailuresPerIP)
+	policy.FailureWindow = time.Duration(envInt(LoginFailureWindowEnv, int(policy.FailureWindow/time.Minute))) * time.Minute
+	policy.LockoutDuration = time.Duration(envInt(LoginLockoutDurationEnv, int(policy.LockoutDuration/time.Minute))) * time.Minute
+	policy.DelayAfter = envInt(LoginDelayAfterEnv, policy.DelayAfter)
+	policy.DelayBase = time.Duration(envInt(LoginDelayBaseEnv, int(policy.DelayBase/time.Second))) * time.Second
+	policy.DelayMax = time.Duration(envInt(LoginDelayMaxEnv, int(policy.DelayMax/time.Second))) * time.Second
+	return policy
+}
+
+// DelayFor returns how long a subject must wait before the next attempt after
+// the given number of consecutive failures. The delay doubles with every
+// failure past DelayAfter and is capped at DelayMax, where zero means no cap.
+func (p LoginPolicy) DelayFor(failures int64) time.Duration {
+	if failures <= int64(p.DelayAfter) || p.DelayBase <= 0 {
+		return 0
+	}
+
+	delay := p.DelayBase
+	for i := int64(p.DelayAfter) + 1; i < failures; i++ {
+		// Without a cap the delay stops growing before it overflows
+		if delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+		if p.DelayMax > 0 && delay >= p.DelayMax {
+			return p.DelayMax
+		}
+	}
+	if p.DelayMax > 0 && delay > p.DelayMax {
+		return p.DelayMax
+	}
+	return delay
+}
+
+// ShouldLockUser reports whether the user has reached the failed attempt limit
+func (p LoginPolicy) ShouldLockUser(failures int64) bool {
+	return p.MaxFailuresPerUser > 0 && failures >= int64(p.MaxFailuresPerUser)
+}
+
+// ShouldLockIP reports whether the client IP has reached the failed attempt limit
+func (p LoginPolicy) ShouldLockIP(failures int64) bool {
+	return p.MaxFailuresPerIP > 0 && failures >= int64(p.MaxFailuresPerIP)
+}
+
+func envInt(key string, defaultValue int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		log.Printf("Warning: Invalid %s value: %s. Using default (%d).", key, raw, defaultValue)
+		return defaultValue
+	}
+	return value
+}
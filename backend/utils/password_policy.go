@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Environment variables controlling the password policy for local accounts
+const (
+	PasswordMinLengthEnv        = "PASSWORD_MIN_LENGTH"
+	PasswordRequireUpperEnv     = "PASSWORD_REQUIRE_UPPERCASE"
+	PasswordRequireLowerEnv     = "PASSWORD_REQUIRE_LOWERCASE"
+	PasswordRequireDigitEnv     = "PASSWORD_REQUIRE_DIGIT"
+	PasswordRequireSymbolEnv    = "PASSWORD_REQUIRE_SYMBOL"
+	PasswordHistorySizeEnv      = "PASSWORD_HISTORY_SIZE"
+	PasswordMaxAgeEnv           = "PASSWORD_MAX_AGE_DAYS"
+	PasswordBreachedListFileEnv = "PASSWORD_BREACHED_LIST_FILE"
+)
+
+// Reasons returned by PasswordPolicy.ChangeRequired
+const (
+	PasswordChangeReasonForced  = "must_change"
+	PasswordChangeReasonExpired = "expired"
+)
+
+// PasswordPolicy describes the rules new passwords must satisfy and how long
+// they stay valid
+type PasswordPolicy struct {
+	MinLength     int           `json:"min_length"`
+	RequireUpper  bool          `json:"require_uppercase"`
+	RequireLower  bool          `json:"require_lowercase"`
+	RequireDigit  bool          `json:"require_digit"`
+	RequireSymbol bool          `json:"require_symbol"`
+	HistorySize   int           `json:"history_size"`
+	MaxAge        time.Duration `json:"-"`
+	MaxAgeDays    int           `json:"max_age_days"`
+
+	// breached holds lower-cased passwords from the breached-password list
+	breached map[string]struct{}
+}
+
+// PasswordPolicyError lists every rule a password violates
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// DefaultPasswordPolicy returns the policy used when no environment overrides are set
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+		HistorySize:  5,
+	}
+}
+
+// LoadPasswordPolicy builds the password policy from environment variables
+// and loads the breached-password list, if one is configured
+func LoadPasswordPolicy() PasswordPolicy {
+	policy := DefaultPasswordPolicy()
+	policy.MinLength = envInt(PasswordMinLengthEnv, policy.MinLength)
+	policy.RequireUpper = envBool(PasswordRequireUpperEnv, policy.RequireUpper)
+	policy.RequireLower = envBool(PasswordRequireLowerEnv, policy.RequireLower)
+	policy.RequireDigit = envBool(PasswordRequireDigitEnv, policy.RequireDigit)
+	policy.RequireSymbol = envBool(PasswordRequireSymbolEnv, policy.RequireSymbol)
+	policy.HistorySize = envInt(PasswordHistorySizeEnv, policy.HistorySize)
+	policy.MaxAgeDays = envInt(PasswordMaxAgeEnv, policy.MaxAgeDays)
+	policy.MaxAge = time.Duration(policy.MaxAgeDays) * 24 * time.Hour
+
+	if path := os.Getenv(PasswordBreachedListFileEnv); path != "" {
+		breached, err := LoadBreachedPasswords(path)
+		if err != nil {
+			log.Printf("Warning: Failed to load breached password list %s: %v", path, err)
+		} else {
+			policy.breached = breached
+			log.Printf("Loaded %d breached passwords from %s", len(breached), path)
+		}
+	}
+
+	return policy
+}
+
+var (
+	passwordPolicy     PasswordPolicy
+	passwordPolicyOnce sync.Once
+)
+
+// GetPasswordPolicy returns the process-wide password policy, loading it on first use
+func GetPasswordPolicy() PasswordPolicy {
+	passwordPolicyOnce.Do(func() {
+		passwordPolicy = LoadPasswordPolicy()
+	})
+	return passwordPolicy
+}
+
+// LoadBreachedPasswords reads a file with one known-breached password per
+// line. Blank lines and lines starting with # are ignored.
+func LoadBreachedPasswords(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
+
+// WithBreachedPasswords returns a copy of the policy that rejects the given passwords
+func (p PasswordPolicy) WithBreachedPasswords(breached map[string]struct{}) PasswordPolicy {
+	p.breached = breached
+	return p
+}
+
+// Validate checks a new password against the policy. Reuse of previous
+// passwords is checked separately because it needs the stored hashes.
+func (p PasswordPolicy) Validate(username, password string) error {
+	var violations []string
+
+	if len(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("password must be at least %d characters long", p.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "password must contain a symbol")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, "password must not contain the username")
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		violations = append(violations, "password appears in a list of breached passwords")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// ChangeRequired reports whether a user must change their password before
+// using the API, and why
+func (p PasswordPolicy) ChangeRequired(mustChange bool, changedAt, now time.Time) (bool, string) {
+	if mustChange {
+		return true, PasswordChangeReasonForced
+	}
+	if p.MaxAge > 0 && now.Sub(changedAt) > p.MaxAge {
+		return true, PasswordChangeReasonExpired
+	}
+	return false, ""
+}
+
+func envBool(key string, defaultValue bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("Warning: Invalid %s value: %s. Using default (%t).", key, raw, defaultValue)
+		return defaultValue
+	}
+	return value
+}
@@ -31,11 +31,8 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
-// ValidatePassword checks password requirements
-func ValidatePassword(password string) error {
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters long")
-	}
-
-	return nil
+// ValidatePassword checks a new password against the configured password
+// policy. username may be empty when it is not known.
+func ValidatePassword(username, password string) error {
+	return GetPasswordPolicy().Validate(username, password)
 }
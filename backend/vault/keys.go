@@ -0,0 +1,142 @@
+// Package vault keeps the kubeconfigs of imported and onboarded clusters
+// encrypted in PostgreSQL. Each kubeconfig is sealed with its own data key,
+// and data keys are wrapped with a master key read from the environment, so
+// rotating the master key only rewraps the data keys. Clients are built from
+// a stored credential by name and every use is recorded.
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment variables the master keys are read from. A key is 32 bytes,
+// base64 or hex encoded. Previous keys are comma or newline separated and
+// only used to open credentials that were not rotated yet.
+const (
+	MasterKeyEnv             = "CREDENTIAL_MASTER_KEY"
+	MasterKeyFileEnv         = "CREDENTIAL_MASTER_KEY_FILE"
+	PreviousMasterKeysEnv    = "CREDENTIAL_PREVIOUS_MASTER_KEYS"
+	PreviousMasterKeyFileEnv = "CREDENTIAL_PREVIOUS_MASTER_KEYS_FILE"
+)
+
+const keySize = 32
+
+// ErrNoMasterKey is returned when no master key is configured
+var ErrNoMasterKey = errors.New("no credential master key is configured, set " + MasterKeyEnv + " or " + MasterKeyFileEnv)
+
+// Key is a master key
+type Key struct {
+	// ID identifies the key without revealing it
+	ID       string
+	material []byte
+}
+
+// ParseKey decodes a 32 byte key given as base64 or hex
+func ParseKey(encoded string) (*Key, error) {
+	encoded = strings.TrimSpace(encoded)
+	var material []byte
+	if decoded, err := hex.DecodeString(encoded); err == nil && len(decoded) == keySize {
+		material = decoded
+	} else if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+		material = decoded
+	} else if decoded, err := base64.URLEncoding.DecodeString(encoded); err == nil {
+		material = decoded
+	} else {
+		return nil, errors.New("master key must be base64 or hex encoded")
+	}
+	if len(material) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(material))
+	}
+	sum := sha256.Sum256(material)
+	return &Key{ID: hex.EncodeToString(sum[:8]), material: material}, nil
+}
+
+// Keyring is the current master key, which seals, and the previous ones,
+// which only open
+type Keyring struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewKeyring builds a keyring from the current key and the previous keys
+func NewKeyring(current *Key, previous ...*Key) *Keyring {
+	keyring := &Keyring{current: current, keys: map[string]*Key{current.ID: current}}
+	for _, key := range previous {
+		if _, ok := keyring.keys[key.ID]; !ok {
+			keyring.keys[key.ID] = key
+		}
+	}
+	return keyring
+}
+
+// CurrentKeyID is the ID of the key new credentials are wrapped with
+func (k *Keyring) CurrentKeyID() string {
+	return k.current.ID
+}
+
+// KeyIDs lists the IDs of all keys, the current one first
+func (k *Keyring) KeyIDs() []string {
+	ids := []string{k.current.ID}
+	for id := range k.keys {
+		if id != k.current.ID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// readSetting returns the value of the env variable, or the content of the
+// file the file variable names
+func readSetting(env, fileEnv string) (string, error) {
+	if value := os.Getenv(env); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(fileEnv)
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", fileEnv, err)
+	}
+	return string(data), nil
+}
+
+// KeyringFromEnv loads the master keys from the environment. It returns
+// ErrNoMasterKey when none is configured.
+func KeyringFromEnv() (*Keyring, error) {
+	value, err := readSetting(MasterKeyEnv, MasterKeyFileEnv)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, ErrNoMasterKey
+	}
+	current, err := ParseKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", MasterKeyEnv, err)
+	}
+
+	value, err = readSetting(PreviousMasterKeysEnv, PreviousMasterKeyFileEnv)
+	if err != nil {
+		return nil, err
+	}
+	previous := []*Key{}
+	for _, encoded := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if strings.TrimSpace(encoded) == "" {
+			continue
+		}
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", PreviousMasterKeysEnv, err)
+		}
+		previous = append(previous, key)
+	}
+	return NewKeyring(current, previous...), nil
+}
@@ -0,0 +1,117 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownKey is returned when a credential is wrapped with a master key
+// the keyring does not hold
+var ErrUnknownKey = errors.New("credential is wrapped with an unknown master key")
+
+// Sealed is an encrypted credential: the ciphertext under a data key, and the
+// data key wrapped with the master key KeyID
+type Sealed struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+// The name of a credential is bound to both ciphertexts, so a sealed value
+// cannot be moved to another credential
+func dataAAD(name string) []byte { return []byte("kubestellar-credential/data/" + name) }
+func keyAAD(name string) []byte  { return []byte("kubestellar-credential/key/" + name) }
+
+// encrypt seals plaintext with AES-256-GCM and prefixes the nonce
+func encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func decrypt(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts the credential name with a new data key wrapped with the
+// current master key
+func (k *Keyring) Seal(name string, plaintext []byte) (*Sealed, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	ciphertext, err := encrypt(dataKey, plaintext, dataAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credential: %v", err)
+	}
+	wrapped, err := encrypt(k.current.material, dataKey, keyAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	return &Sealed{KeyID: k.current.ID, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// unwrap returns the data key of a sealed credential
+func (k *Keyring) unwrap(name string, sealed *Sealed) ([]byte, error) {
+	key, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, sealed.KeyID)
+	}
+	dataKey, err := decrypt(key.material, sealed.WrappedKey, keyAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of credential %s: %v", name, err)
+	}
+	return dataKey, nil
+}
+
+// Open decrypts a sealed credential
+func (k *Keyring) Open(name string, sealed *Sealed) ([]byte, error) {
+	dataKey, err := k.unwrap(name, sealed)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decrypt(dataKey, sealed.Ciphertext, dataAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential %s: %v", name, err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap wraps the data key of a sealed credential with the current master
+// key. The ciphertext is unchanged.
+func (k *Keyring) Rewrap(name string, sealed *Sealed) (*Sealed, error) {
+	dataKey, err := k.unwrap(name, sealed)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := encrypt(k.current.material, dataKey, keyAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	return &Sealed{KeyID: k.current.ID, WrappedKey: wrapped, Ciphertext: sealed.Ciphertext}, nil
+}
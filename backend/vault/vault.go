@@ -0,0 +1,369 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/ui/backend/health"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ComponentName is the name the vault reports its health under
+const ComponentName = "credential-vault"
+
+// ErrNotFound is returned when no credential has the name
+var ErrNotFound = errors.New("credential not found")
+
+// ErrDisabled is returned when the vault is used without a master key
+var ErrDisabled = errors.New("the credential vault is not enabled")
+
+const (
+	defaultAccessRetentionDays = 90
+	pruneInterval              = time.Hour
+)
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *Keyring
+	initOnce  sync.Once
+)
+
+// Access describes who uses a credential and why, for the access log
+type Access struct {
+	Username string
+	Purpose  string
+	// Background marks periodic reads by the backend itself, such as the
+	// inventory refresh, which are not logged so the log stays readable
+	Background bool
+}
+
+// Init loads the master keys and seals the kubeconfigs onboarding jobs kept
+// in plain text. It must be called after the database is initialized and
+// before onboarding jobs are resumed. Without a master key the vault stays
+// disabled and onboarding jobs keep their kubeconfig in plain text in the
+// onboarding_jobs table.
+func Init() {
+	initOnce.Do(func() {
+		loaded, err := KeyringFromEnv()
+		if err != nil {
+			component := health.ComponentHealth{Status: "degraded", Message: "credentials are stored in plain text", Error: err.Error()}
+			if errors.Is(err, ErrNoMasterKey) {
+				log.LogWarn("Credential vault disabled, cluster credentials are stored in plain text", zap.Error(err))
+			} else {
+				log.LogError("Failed to load the credential master key", zap.Error(err))
+			}
+			health.ReportComponent(ComponentName, component)
+			return
+		}
+		SetKeyring(loaded)
+		log.LogInfo("Credential vault enabled", zap.String("key_id", loaded.CurrentKeyID()))
+		if err := SealOnboardingJobs(); err != nil {
+			log.LogError("Failed to seal the kubeconfigs of onboarding jobs", zap.Error(err))
+		}
+		go pruneLoop()
+	})
+}
+
+// AccessRetentionDays returns how long access log entries are kept, from
+// CREDENTIAL_ACCESS_RETENTION_DAYS; zero means they are kept forever
+func AccessRetentionDays() int {
+	value := os.Getenv("CREDENTIAL_ACCESS_RETENTION_DAYS")
+	if value == "" {
+		return defaultAccessRetentionDays
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.LogWarn("Invalid CREDENTIAL_ACCESS_RETENTION_DAYS, using default",
+			zap.String("value", value),
+			zap.Int("default", defaultAccessRetentionDays))
+		return defaultAccessRetentionDays
+	}
+	return days
+}
+
+// PruneAccessLog deletes access log entries older than the retention period
+func PruneAccessLog() (int64, error) {
+	days := AccessRetentionDays()
+	if days == 0 {
+		return 0, nil
+	}
+	return models.DeleteCredentialAccessBefore(time.Now().AddDate(0, 0, -days))
+}
+
+func pruneLoop() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := PruneAccessLog()
+		if err != nil {
+			log.LogError("Failed to prune credential access log", zap.Error(err))
+		} else if deleted > 0 {
+			log.LogInfo("Pruned credential access log", zap.Int64("deleted", deleted))
+		}
+		<-ticker.C
+	}
+}
+
+// SealOnboardingJobs moves the kubeconfigs onboarding jobs created without
+// the vault kept in plain text into the vault. The newest kubeconfig of a
+// cluster is stored under the cluster name unless a credential already
+// exists, then every job of the cluster refers to that credential and its
+// plain text kubeconfig is cleared.
+func SealOnboardingJobs() error {
+	jobs, err := models.ListPlaintextOnboardingJobs()
+	if err != nil {
+		return err
+	}
+	sealed, seen := 0, map[string]bool{}
+	for _, job := range jobs {
+		if seen[job.ClusterName] {
+			continue
+		}
+		seen[job.ClusterName] = true
+
+		credential, err := models.GetClusterCredential(job.ClusterName)
+		if err != nil {
+			return err
+		}
+		if credential == nil {
+			if _, err := Store(job.ClusterName, job.Kubeconfig, Access{
+				Username: job.CreatedBy,
+				Purpose:  fmt.Sprintf("sealing onboarding job %d", job.ID),
+			}); err != nil {
+				log.LogWarn("Failed to seal the kubeconfig of onboarding job",
+					zap.Int64("job", job.ID), zap.String("cluster", job.ClusterName), zap.Error(err))
+				continue
+			}
+		}
+		if err := models.ReferenceOnboardingCredential(job.ClusterName, job.ClusterName); err != nil {
+			return err
+		}
+		sealed++
+	}
+	if sealed > 0 {
+		log.LogInfo("Sealed the kubeconfigs of onboarding jobs", zap.Int("clusters", sealed))
+	}
+	return nil
+}
+
+// SetKeyring replaces the master keys, e.g. in tests
+func SetKeyring(k *Keyring) {
+	keyringMu.Lock()
+	keyring = k
+	keyringMu.Unlock()
+	health.ReportComponent(ComponentName, health.ComponentHealth{
+		Status:   "healthy",
+		Message:  "credentials are stored encrypted",
+		Metadata: map[string]interface{}{"key_id": k.CurrentKeyID()},
+	})
+}
+
+func currentKeyring() (*Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	if keyring == nil {
+		return nil, ErrDisabled
+	}
+	return keyring, nil
+}
+
+// Enabled reports whether credentials can be stored
+func Enabled() bool {
+	_, err := currentKeyring()
+	return err == nil
+}
+
+// Describe checks that a kubeconfig can build a client and returns the
+// server and context it connects with
+func Describe(kubeconfig []byte) (string, string, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return "", "", errors.New("invalid kubeconfig: it has no current context")
+	}
+	server := ""
+	if cluster, ok := config.Clusters[context.Cluster]; ok {
+		server = cluster.Server
+	}
+	if _, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig); err != nil {
+		return "", "", fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	return server, config.CurrentContext, nil
+}
+
+func recordAccess(credential *models.ClusterCredential, action string, access Access) error {
+	id := credential.ID
+	return models.InsertCredentialAccess(&models.CredentialAccess{
+		CredentialID:   &id,
+		CredentialName: credential.Name,
+		Action:         action,
+		Purpose:        access.Purpose,
+		Username:       access.Username,
+	})
+}
+
+// Store encrypts a kubeconfig under name, replacing the credential of the
+// same name
+func Store(name string, kubeconfig []byte, access Access) (*models.ClusterCredential, error) {
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	server, contextName, err := Describe(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := k.Seal(name, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := models.SaveClusterCredential(&models.ClusterCredential{
+		Name:        name,
+		Server:      server,
+		ContextName: contextName,
+		KeyID:       sealed.KeyID,
+		WrappedKey:  sealed.WrappedKey,
+		Ciphertext:  sealed.Ciphertext,
+		CreatedBy:   access.Username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAccess(credential, models.CredentialStored, access); err != nil {
+		return nil, err
+	}
+	log.LogInfo("Stored cluster credential",
+		zap.String("credential", name),
+		zap.String("username", access.Username),
+		zap.String("purpose", access.Purpose))
+	return credential, nil
+}
+
+// Kubeconfig decrypts the credential name. Unless it is a background read,
+// the access is recorded before the kubeconfig is returned; if it cannot be
+// recorded the kubeconfig is not returned either.
+func Kubeconfig(name string, access Access) ([]byte, error) {
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	credential, err := models.GetClusterCredential(name)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	kubeconfig, err := k.Open(name, &Sealed{KeyID: credential.KeyID, WrappedKey: credential.WrappedKey, Ciphertext: credential.Ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	if !access.Background {
+		if err := recordAccess(credential, models.CredentialRead, access); err != nil {
+			return nil, err
+		}
+	}
+	if err := models.TouchClusterCredential(credential.ID); err != nil {
+		log.LogWarn("Failed to update credential last use", zap.String("credential", name), zap.Error(err))
+	}
+	return kubeconfig, nil
+}
+
+// RESTConfig builds the client config of the credential name
+func RESTConfig(name string, access Access) (*rest.Config, error) {
+	kubeconfig, err := Kubeconfig(name, access)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+}
+
+// Delete removes the credential name
+func Delete(name string, access Access) error {
+	credential, err := models.GetClusterCredential(name)
+	if err != nil {
+		return err
+	}
+	if credential == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err := recordAccess(credential, models.CredentialDeleted, access); err != nil {
+		return err
+	}
+	if _, err := models.DeleteClusterCredential(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// List returns the metadata of all stored credentials
+func List() ([]*models.ClusterCredential, error) {
+	return models.ListClusterCredentials()
+}
+
+// AccessLog returns the latest uses of the credential name
+func AccessLog(name string, limit int) ([]models.CredentialAccess, error) {
+	return models.ListCredentialAccess(name, limit)
+}
+
+// RotationResult is the outcome of Rotate
+type RotationResult struct {
+	KeyID   string   `json:"key_id"`
+	Rotated []string `json:"rotated"`
+	// Failed maps credentials that could not be rewrapped to the reason,
+	// usually a master key missing from the previous keys
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Rotate rewraps the data key of every credential that is not wrapped with
+// the current master key. Once it reports no failures the previous keys can
+// be removed from the environment.
+func Rotate(access Access) (*RotationResult, error) {
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := models.ListClusterCredentialsNotWrappedWith(k.CurrentKeyID())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RotationResult{KeyID: k.CurrentKeyID(), Rotated: []string{}, Failed: map[string]string{}}
+	for _, credential := range credentials {
+		rewrapped, err := k.Rewrap(credential.Name, &Sealed{KeyID: credential.KeyID, WrappedKey: credential.WrappedKey, Ciphertext: credential.Ciphertext})
+		if err != nil {
+			result.Failed[credential.Name] = err.Error()
+			continue
+		}
+		updated, err := models.RewrapClusterCredential(credential.ID, credential.KeyID, rewrapped.KeyID, rewrapped.WrappedKey)
+		if err != nil {
+			result.Failed[credential.Name] = err.Error()
+			continue
+		}
+		if !updated {
+			// Replaced by a new Store meanwhile, which used the current key
+			continue
+		}
+		if err := recordAccess(credential, models.CredentialRotated, access); err != nil {
+			log.LogWarn("Failed to record credential rotation", zap.String("credential", credential.Name), zap.Error(err))
+		}
+		result.Rotated = append(result.Rotated, credential.Name)
+	}
+	log.LogInfo("Rotated cluster credentials",
+		zap.String("key_id", result.KeyID),
+		zap.Int("rotated", len(result.Rotated)),
+		zap.Int("failed", len(result.Failed)))
+	return result, nil
+}
@@ -0,0 +1,893 @@
+package bp
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/yaml"
+)
+
+// Formats a bundle can be exported in
+const (
+	BundleFormatYAML    = "yaml"
+	BundleFormatTarball = "tar"
+)
+
+// maxBundleSize caps the size of an uploaded bundle, compressed or not
+const maxBundleSize = 32 << 20
+
+// Possible values of BundleChange.Action
+const (
+	BundleCreate    = "create"
+	BundleUpdate    = "update"
+	BundleUnchanged = "unchanged"
+	BundlePrune     = "prune"
+)
+
+// bundleSkippedMetadata are metadata fields set by the API server, which would
+// make exports of the same objects differ and cannot be applied elsewhere
+var bundleSkippedMetadata = []string{
+	"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+	"deletionGracePeriodSeconds", "managedFields", "selfLink", "ownerReferences",
+}
+
+// bundleSkippedAnnotations are maintained by controllers and not by users
+var bundleSkippedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	"yaml",
+}
+
+// bundleWorkloadKinds are the workload kinds a bundle may carry besides
+// BindingPolicies and namespaces. RBAC objects, Secrets and anything else
+// that grants access are left out so an upload cannot change who can do what.
+var bundleWorkloadKinds = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "Deployment"}:                     true,
+	{Group: "apps", Kind: "StatefulSet"}:                    true,
+	{Group: "apps", Kind: "DaemonSet"}:                      true,
+	{Group: "apps", Kind: "ReplicaSet"}:                     true,
+	{Group: "batch", Kind: "Job"}:                           true,
+	{Group: "batch", Kind: "CronJob"}:                       true,
+	{Group: "", Kind: "Service"}:                            true,
+	{Group: "", Kind: "ConfigMap"}:                          true,
+	{Group: "", Kind: "PersistentVolumeClaim"}:              true,
+	{Group: "networking.k8s.io", Kind: "Ingress"}:           true,
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}: true,
+	{Group: "policy", Kind: "PodDisruptionBudget"}:          true,
+}
+
+// ExportOptions selects what an export adds to the BindingPolicies
+type ExportOptions struct {
+	// IncludeWorkloads adds the objects the policies currently select
+	IncludeWorkloads bool
+	// IncludeNamespaces adds the namespaces of the selected objects
+	IncludeNamespaces bool
+}
+
+// Bundle is a set of objects ready to be encoded, in apply order
+type Bundle struct {
+	Objects  []*unstructured.Unstructured
+	Warnings []string
+}
+
+// InvalidBundleError lists everything that keeps a bundle from being imported
+type InvalidBundleError struct {
+	Problems []string
+}
+
+func (e *InvalidBundleError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// ExportBundle collects the BindingPolicies of a WDS, and optionally the
+// workloads and namespaces they select, with server-set fields removed so
+// exports of unchanged objects are identical
+func ExportBundle(ctx context.Context, clients PreviewClients, options ExportOptions) (*Bundle, error) {
+	list, err := clients.WDS.Resource(bindingPolicyGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list binding policies: %v", err)
+	}
+
+	bundle := &Bundle{}
+	for i := range list.Items {
+		bundle.Objects = append(bundle.Objects, bundleObject(&list.Items[i]))
+	}
+
+	if options.IncludeWorkloads || options.IncludeNamespaces {
+		selected := map[string]PreviewObject{}
+		for i := range list.Items {
+			bp, err := toBindingPolicy(&list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			objects, truncated, warnings, err := matchObjects(ctx, bp.Spec.Downsync, clients)
+			if err != nil {
+				bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("%s: %v", bp.Name, err))
+				continue
+			}
+			for _, warning := range warnings {
+				bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("%s: %s", bp.Name, warning))
+			}
+			if truncated {
+				bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("%s: selects more than %d objects, only the first are exported", bp.Name, maxPreviewObjects))
+			}
+			for _, obj := range objects {
+				selected[obj.key()] = obj
+			}
+		}
+
+		namespaces := map[string]bool{}
+		for _, ref := range selected {
+			if ref.Namespace != "" {
+				namespaces[ref.Namespace] = true
+			}
+			if ref.Group == "" && ref.Resource == "namespaces" {
+				namespaces[ref.Name] = true
+				continue
+			}
+			if !options.IncludeWorkloads {
+				continue
+			}
+			gvr := schema.GroupVersionResource{Group: ref.Group, Version: ref.Version, Resource: ref.Resource}
+			obj, err := clients.WDS.Resource(gvr).Namespace(ref.Namespace).Get(ctx, ref.Name, v1.GetOptions{})
+			if err != nil {
+				bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("failed to get %s %s: %v", ref.Resource, ref.Name, err))
+				continue
+			}
+			if gk := obj.GroupVersionKind().GroupKind(); !bundleKindAllowed(gk) {
+				bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("%s %s is not exported: bundles cannot carry %s", ref.Resource, ref.Name, gk.String()))
+				continue
+			}
+			bundle.Objects = append(bundle.Objects, bundleObject(obj))
+		}
+
+		if options.IncludeNamespaces {
+			for name := range namespaces {
+				obj, err := clients.WDS.Resource(namespaceGVR).Get(ctx, name, v1.GetOptions{})
+				if err != nil {
+					bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("failed to get namespace %s: %v", name, err))
+					continue
+				}
+				bundle.Objects = append(bundle.Objects, bundleObject(obj))
+			}
+		}
+	}
+
+	sortBundleObjects(bundle.Objects)
+	sort.Strings(bundle.Warnings)
+	return bundle, nil
+}
+
+// bundleObject returns a copy of obj without status and server-set metadata
+func bundleObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	clean := obj.DeepCopy()
+	delete(clean.Object, "status")
+	for _, field := range bundleSkippedMetadata {
+		unstructured.RemoveNestedField(clean.Object, "metadata", field)
+	}
+
+	annotations := clean.GetAnnotations()
+	for _, key := range bundleSkippedAnnotations {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(clean.Object, "metadata", "annotations")
+	} else {
+		clean.SetAnnotations(annotations)
+	}
+	if len(clean.GetLabels()) == 0 {
+		unstructured.RemoveNestedField(clean.Object, "metadata", "labels")
+	}
+
+	// Cluster IPs are allocated by the cluster the service lives in
+	if clean.GetAPIVersion() == "v1" && clean.GetKind() == "Service" {
+		unstructured.RemoveNestedField(clean.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(clean.Object, "spec", "clusterIPs")
+	}
+	return clean
+}
+
+// bundleRank orders namespaces first and policies last, so applying a bundle
+// in order creates everything before it is referenced
+func bundleRank(obj *unstructured.Unstructured) int {
+	switch {
+	case obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace":
+		return 0
+	case isBindingPolicy(obj):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// bundleKindAllowed reports whether a bundle may carry objects of a kind
+func bundleKindAllowed(gk schema.GroupKind) bool {
+	if gk == (schema.GroupKind{Kind: "Namespace"}) || gk == (schema.GroupKind{Group: bindingPolicyGVR.Group, Kind: "BindingPolicy"}) {
+		return true
+	}
+	return bundleWorkloadKinds[gk]
+}
+
+func isBindingPolicy(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "BindingPolicy" && obj.GroupVersionKind().Group == bindingPolicyGVR.Group
+}
+
+func sortBundleObjects(objects []*unstructured.Unstructured) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if rankA, rankB := bundleRank(a), bundleRank(b); rankA != rankB {
+			return rankA < rankB
+		}
+		return bundleKey(a) < bundleKey(b)
+	})
+}
+
+// bundleKey identifies an object in a bundle
+func bundleKey(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group + "/" + gvk.Kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// EncodeBundleYAML encodes a bundle as a multi-document YAML stream
+func EncodeBundleYAML(bundle *Bundle) ([]byte, error) {
+	var out bytes.Buffer
+	for _, obj := range bundle.Objects {
+		encoded, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %v", bundleKey(obj), err)
+		}
+		out.WriteString("---\n")
+		out.Write(encoded)
+	}
+	return out.Bytes(), nil
+}
+
+// EncodeBundleTarball encodes a bundle as a gzipped tarball with one YAML
+// file per object. Timestamps are fixed so equal bundles encode identically.
+func EncodeBundleTarball(bundle *Bundle) ([]byte, error) {
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	archive := tar.NewWriter(gz)
+
+	for _, obj := range bundle.Objects {
+		encoded, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %v", bundleKey(obj), err)
+		}
+		header := &tar.Header{
+			Name:     bundlePath(obj),
+			Mode:     0644,
+			Size:     int64(len(encoded)),
+			ModTime:  time.Unix(0, 0),
+			Typeflag: tar.TypeReg,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := archive.Write(encoded); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// bundlePath is where an object is stored in a tarball
+func bundlePath(obj *unstructured.Unstructured) string {
+	switch bundleRank(obj) {
+	case 0:
+		return path.Join("namespaces", obj.GetName()+".yaml")
+	case 2:
+		return path.Join("bindingpolicies", obj.GetName()+".yaml")
+	}
+
+	gvk := obj.GroupVersionKind()
+	group := gvk.Group
+	if group == "" {
+		group = "core"
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = "_cluster"
+	}
+	return path.Join("workloads", namespace, strings.ToLower(gvk.Kind)+"."+group, obj.GetName()+".yaml")
+}
+
+// DecodeBundle reads a bundle produced by EncodeBundleYAML or
+// EncodeBundleTarball, or any multi-document YAML or JSON stream. Lists are
+// expanded and every object is checked before anything is returned.
+func DecodeBundle(data []byte) (*Bundle, error) {
+	var documents [][]byte
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		files, err := readTarball(data)
+		if err != nil {
+			return nil, &InvalidBundleError{Problems: []string{err.Error()}}
+		}
+		documents = files
+	} else {
+		documents = [][]byte{data}
+	}
+
+	var objects []*unstructured.Unstructured
+	var problems []string
+	for _, document := range documents {
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(document), 4096)
+		for {
+			content := map[string]interface{}{}
+			if err := decoder.Decode(&content); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, &InvalidBundleError{Problems: []string{fmt.Sprintf("invalid YAML: %v", err)}}
+			}
+			if len(content) == 0 {
+				continue
+			}
+
+			obj := &unstructured.Unstructured{Object: content}
+			if obj.IsList() {
+				list, err := obj.ToList()
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("invalid list: %v", err))
+					continue
+				}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, obj := range objects {
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			problems = append(problems, fmt.Sprintf("object %q is missing apiVersion, kind or metadata.name", bundleKey(obj)))
+			continue
+		}
+		key := bundleKey(obj)
+		if gk := obj.GroupVersionKind().GroupKind(); !bundleKindAllowed(gk) {
+			problems = append(problems, fmt.Sprintf("%s: bundles cannot carry %s", key, gk.String()))
+			continue
+		}
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("%s appears more than once", key))
+		}
+		seen[key] = true
+
+		if isBindingPolicy(obj) {
+			problems = append(problems, validateBundlePolicy(obj)...)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &InvalidBundleError{Problems: problems}
+	}
+
+	bundle := &Bundle{Objects: make([]*unstructured.Unstructured, 0, len(objects))}
+	for _, obj := range objects {
+		bundle.Objects = append(bundle.Objects, bundleObject(obj))
+	}
+	sortBundleObjects(bundle.Objects)
+	return bundle, nil
+}
+
+// readTarball returns the YAML and JSON files of a gzipped tarball
+func readTarball(data []byte) ([][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	var files [][]byte
+	archive := tar.NewReader(io.LimitReader(gz, maxBundleSize))
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		switch strings.ToLower(path.Ext(header.Name)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", header.Name, err)
+		}
+		files = append(files, content)
+	}
+	return files, nil
+}
+
+func validateBundlePolicy(obj *unstructured.Unstructured) []string {
+	bp, err := toBindingPolicy(obj)
+	if err != nil {
+		return []string{fmt.Sprintf("binding policy %s: %v", obj.GetName(), err)}
+	}
+	var problems []string
+	for _, problem := range selectorProblems(bp) {
+		problems = append(problems, fmt.Sprintf("binding policy %s: %s", bp.Name, problem))
+	}
+	return problems
+}
+
+// ImportOptions control how a bundle is applied to a WDS
+type ImportOptions struct {
+	WDSContext string
+	// Prune deletes the BindingPolicies of the WDS that are not in the
+	// bundle. Workloads and namespaces are never pruned, and
+	// pruning is refused for a bundle without BindingPolicies.
+	Prune bool
+	// DryRun only reports what an import would change
+	DryRun bool
+	// Author is recorded on the revisions of imported policies
+	Author string
+}
+
+// BundleChange is what an import does, or would do, to one object
+type BundleChange struct {
+	Action     string        `json:"action"`
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Namespace  string        `json:"namespace,omitempty"`
+	Name       string        `json:"name"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// BundleSummary counts the changes of an import by action
+type BundleSummary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Unchanged int `json:"unchanged"`
+	Prune     int `json:"prune"`
+	Failed    int `json:"failed"`
+}
+
+// ImportReport lists the changes of an import in the order they are applied
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Prune   bool           `json:"prune"`
+	Changes []BundleChange `json:"changes"`
+	Summary BundleSummary  `json:"summary"`
+}
+
+// plannedChange is a BundleChange with what is needed to apply it
+type plannedChange struct {
+	BundleChange
+	gvr      schema.GroupVersionResource
+	desired  *unstructured.Unstructured
+	existing *unstructured.Unstructured
+}
+
+// ImportBundle compares a bundle with the WDS and, unless options.DryRun is
+// set, creates, updates and prunes objects to match it. Failures are recorded
+// per object and do not stop the import.
+func ImportBundle(ctx context.Context, clients PreviewClients, bundle *Bundle, options ImportOptions) (*ImportReport, error) {
+	planned, err := planBundleImport(ctx, clients, bundle, options.Prune)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: options.DryRun, Prune: options.Prune, Changes: make([]BundleChange, 0, len(planned))}
+	for _, change := range planned {
+		if !options.DryRun {
+			if err := applyBundleChange(ctx, clients, change, options); err != nil {
+				change.Error = err.Error()
+			}
+		}
+		report.Changes = append(report.Changes, change.BundleChange)
+
+		if change.Error != "" {
+			report.Summary.Failed++
+			continue
+		}
+		switch change.Action {
+		case BundleCreate:
+			report.Summary.Create++
+		case BundleUpdate:
+			report.Summary.Update++
+		case BundleUnchanged:
+			report.Summary.Unchanged++
+		case BundlePrune:
+			report.Summary.Prune++
+		}
+	}
+	return report, nil
+}
+
+func planBundleImport(ctx context.Context, clients PreviewClients, bundle *Bundle, prune bool) ([]*plannedChange, error) {
+	resources, err := bundleResources(clients.WDSDiscovery)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	policies := 0
+	planned := make([]*plannedChange, 0, len(bundle.Objects))
+	for _, obj := range bundle.Objects {
+		if gk := obj.GroupVersionKind().GroupKind(); !bundleKindAllowed(gk) {
+			problems = append(problems, fmt.Sprintf("%s: bundles cannot carry %s", bundleKey(obj), gk.String()))
+			continue
+		}
+		if isBindingPolicy(obj) {
+			policies++
+		}
+		resource, ok := resources[obj.GroupVersionKind()]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: the WDS does not serve %s", bundleKey(obj), obj.GroupVersionKind().String()))
+			continue
+		}
+		if resource.namespaced && obj.GetNamespace() == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s is namespaced but has no namespace", bundleKey(obj), obj.GetKind()))
+			continue
+		}
+		if !resource.namespaced && obj.GetNamespace() != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s is cluster-scoped but has a namespace", bundleKey(obj), obj.GetKind()))
+			continue
+		}
+		planned = append(planned, &plannedChange{
+			BundleChange: newBundleChange(obj),
+			gvr:          resource.gvr,
+			desired:      bundleObject(obj),
+		})
+	}
+	// Pruning a bundle without policies, such as a workloads-only export,
+	// would delete every BindingPolicy of the WDS
+	if prune && policies == 0 {
+		problems = append(problems, "the bundle has no binding policies, refusing to prune every binding policy of the WDS")
+	}
+	if len(problems) > 0 {
+		return nil, &InvalidBundleError{Problems: problems}
+	}
+
+	for _, change := range planned {
+		existing, err := clients.WDS.Resource(change.gvr).Namespace(change.Namespace).Get(ctx, change.Name, v1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			change.Action = BundleCreate
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to get %s: %v", bundleKey(change.desired), err)
+		}
+
+		changes, err := bundleDiff(bundleObject(existing), change.desired)
+		if err != nil {
+			return nil, err
+		}
+		change.existing = existing
+		change.Changes = changes
+		change.Action = BundleUnchanged
+		if len(changes) > 0 {
+			change.Action = BundleUpdate
+		}
+	}
+
+	if prune {
+		inBundle := map[string]bool{}
+		for _, obj := range bundle.Objects {
+			if isBindingPolicy(obj) {
+				inBundle[obj.GetName()] = true
+			}
+		}
+		list, err := clients.WDS.Resource(bindingPolicyGVR).List(ctx, v1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list binding policies: %v", err)
+		}
+		sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+		for i := range list.Items {
+			existing := &list.Items[i]
+			if inBundle[existing.GetName()] {
+				continue
+			}
+			change := &plannedChange{BundleChange: newBundleChange(existing), gvr: bindingPolicyGVR, existing: existing}
+			change.Action = BundlePrune
+			planned = append(planned, change)
+		}
+	}
+
+	return planned, nil
+}
+
+func newBundleChange(obj *unstructured.Unstructured) BundleChange {
+	return BundleChange{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// bundleDiff compares an existing object with the bundle's version of it.
+// Fields the bundle leaves out of a workload are usually defaults filled in
+// by the API server, so their absence is only a change in metadata and in
+// BindingPolicies.
+func bundleDiff(existing, desired *unstructured.Unstructured) ([]FieldChange, error) {
+	from, err := json.Marshal(existing.Object)
+	if err != nil {
+		return nil, err
+	}
+	to, err := json.Marshal(desired.Object)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := DiffSnapshots(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if isBindingPolicy(desired) {
+		return changes, nil
+	}
+
+	kept := changes[:0]
+	for _, change := range changes {
+		if change.Type == ChangeRemoved && !strings.HasPrefix(change.Path, "metadata.") {
+			continue
+		}
+		kept = append(kept, change)
+	}
+	return kept, nil
+}
+
+func applyBundleChange(ctx context.Context, clients PreviewClients, change *plannedChange, options ImportOptions) error {
+	client := clients.WDS.Resource(change.gvr).Namespace(change.Namespace)
+
+	var result *unstructured.Unstructured
+	var err error
+	switch change.Action {
+	case BundleCreate:
+		result, err = client.Create(ctx, change.desired.DeepCopy(), v1.CreateOptions{})
+	case BundleUpdate:
+		updated := change.desired.DeepCopy()
+		updated.SetResourceVersion(change.existing.GetResourceVersion())
+		result, err = client.Update(ctx, updated, v1.UpdateOptions{})
+	case BundlePrune:
+		err = client.Delete(ctx, change.Name, v1.DeleteOptions{})
+		result = change.existing
+	default:
+		return nil
+	}
+	if err != nil {
+		log.LogWarn("failed to import bundle object",
+			zap.String("action", change.Action),
+			zap.String("kind", change.Kind),
+			zap.String("namespace", change.Namespace),
+			zap.String("name", change.Name),
+			zap.Error(err))
+		return err
+	}
+
+	if change.gvr == bindingPolicyGVR {
+		if bp, err := toBindingPolicy(result); err == nil {
+			recordRevision(options.WDSContext, bp, change.Action == BundlePrune, options.Author)
+		}
+	}
+	return nil
+}
+
+type bundleResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// bundleResources maps the kinds a WDS serves to their resources
+func bundleResources(client discovery.DiscoveryInterface) (map[schema.GroupVersionKind]bundleResource, error) {
+	_, resourceLists, err := client.ServerGroupsAndResources()
+	if err != nil && len(resourceLists) == 0 {
+		return nil, fmt.Errorf("failed to discover WDS resources: %v", err)
+	}
+
+	resources := map[schema.GroupVersionKind]bundleResource{
+		bindingPolicyGVR.GroupVersion().WithKind("BindingPolicy"): {gvr: bindingPolicyGVR},
+	}
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			resources[gv.WithKind(resource.Kind)] = bundleResource{gvr: gv.WithResource(resource.Name), namespaced: resource.Namespaced}
+		}
+	}
+	return resources, nil
+}
+
+// bundleClients connects to the WDS of a request
+func bundleClients(ctx *gin.Context) (PreviewClients, string, error) {
+	space, err := hub.SelectWDS(ctx)
+	if err != nil {
+		return PreviewClients{}, "", err
+	}
+	wdsContext := space.Context
+	clientset, client, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		return PreviewClients{}, wdsContext, fmt.Errorf("failed to connect to WDS %s: %v", wdsContext, err)
+	}
+	return PreviewClients{WDS: client, WDSDiscovery: clientset.Discovery()}, wdsContext, nil
+}
+
+// ExportBps downloads the BindingPolicies of a WDS as a bundle. The "format"
+// query parameter is "yaml" (the default) or "tar", and "workloads" and
+// "namespaces" add the objects the policies select and their namespaces.
+func ExportBps(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", BundleFormatYAML)
+	if format != BundleFormatYAML && format != BundleFormatTarball {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/export", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or tar"})
+		return
+	}
+
+	clients, wdsContext, err := bundleClients(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/export", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle, err := ExportBundle(ctx.Request.Context(), clients, ExportOptions{
+		IncludeWorkloads:  ctx.Query("workloads") == "true",
+		IncludeNamespaces: ctx.Query("namespaces") == "true",
+	})
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/export", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, warning := range bundle.Warnings {
+		log.LogWarn("binding policy export", zap.String("wds", wdsContext), zap.String("warning", warning))
+	}
+
+	var encoded []byte
+	contentType, filename := "application/yaml", "bindingpolicies-"+wdsContext+".yaml"
+	if format == BundleFormatTarball {
+		encoded, err = EncodeBundleTarball(bundle)
+		contentType, filename = "application/gzip", "bindingpolicies-"+wdsContext+".tar.gz"
+	} else {
+		encoded, err = EncodeBundleYAML(bundle)
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/export", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(bundle.Warnings) > 0 {
+		ctx.Header("X-Export-Warnings", strings.Join(bundle.Warnings, "; "))
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/export", "200").Inc()
+	ctx.Data(http.StatusOK, contentType, encoded)
+}
+
+// readBundle reads an uploaded bundle, either as the request body or as the
+// "bundle" file of a multipart form
+func readBundle(ctx *gin.Context) (*Bundle, error) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBundleSize)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		var file io.ReadCloser
+		header, formErr := ctx.FormFile("bundle")
+		if formErr != nil {
+			return nil, &InvalidBundleError{Problems: []string{fmt.Sprintf("missing bundle file: %v", formErr)}}
+		}
+		if file, err = header.Open(); err == nil {
+			defer file.Close()
+			data, err = io.ReadAll(file)
+		}
+	} else {
+		data, err = io.ReadAll(ctx.Request.Body)
+	}
+	if err != nil {
+		return nil, &InvalidBundleError{Problems: []string{fmt.Sprintf("failed to read bundle: %v", err)}}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, &InvalidBundleError{Problems: []string{"bundle is empty"}}
+	}
+	return DecodeBundle(data)
+}
+
+func importBundle(ctx *gin.Context, path string, dryRun bool) {
+	bundle, err := readBundle(ctx)
+	if err != nil {
+		bundleError(ctx, path, err)
+		return
+	}
+
+	clients, wdsContext, err := bundleClients(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := ImportBundle(ctx.Request.Context(), clients, bundle, ImportOptions{
+		WDSContext: wdsContext,
+		Prune:      ctx.Query("prune") == "true",
+		DryRun:     dryRun,
+		Author:     requestAuthor(ctx),
+	})
+	if err != nil {
+		bundleError(ctx, path, err)
+		return
+	}
+
+	if !dryRun {
+		log.LogInfo("Imported binding policy bundle",
+			zap.String("wds", wdsContext),
+			zap.Int("created", report.Summary.Create),
+			zap.Int("updated", report.Summary.Update),
+			zap.Int("pruned", report.Summary.Prune),
+			zap.Int("failed", report.Summary.Failed))
+	}
+
+	status := http.StatusOK
+	if report.Summary.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", path, fmt.Sprint(status)).Inc()
+	ctx.JSON(status, report)
+}
+
+func bundleError(ctx *gin.Context, path string, err error) {
+	if invalid, ok := err.(*InvalidBundleError); ok {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle", "problems": invalid.Problems})
+		return
+	}
+	telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "500").Inc()
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// PreviewBpImport reports what importing a bundle would create, update and,
+// with "prune=true", delete, without changing anything
+func PreviewBpImport(ctx *gin.Context) {
+	importBundle(ctx, "/api/bp/import/preview", true)
+}
+
+// ImportBps applies a bundle to a WDS and reports the result for every
+// object. With "prune=true" BindingPolicies missing from the bundle are
+// deleted, which is refused for a bundle without BindingPolicies.
+func ImportBps(ctx *gin.Context) {
+	importBundle(ctx, "/api/bp/import", false)
+}
@@ -39,6 +39,12 @@ type StoredBindingPolicy struct {
 	Namespaces        []string            `json:"namespaces"`
 	SpecificWorkloads []WorkloadInfo      `json:"specificWorkloads"` // Added this field
 	RawYAML           string              `json:"rawYAML"`
+	Author            string              `json:"author,omitempty"`
+	Template          string              `json:"template,omitempty"`
+	// TemplateParameters are the parameters the template was rendered with
+	TemplateParameters map[string]interface{} `json:"templateParameters,omitempty"`
+	Description        string                 `json:"description,omitempty"`
+	CreatedAt          string                 `json:"createdAt,omitempty"`
 }
 type WorkloadInfo struct {
 	APIVersion string `json:"apiVersion"`
@@ -47,9 +53,6 @@ type WorkloadInfo struct {
 	Namespace  string `json:"namespace"`
 }
 
-// Global store for binding policies created via the UI
-var UICreatedPolicies = make(map[string]*StoredBindingPolicy)
-
 // BindingPolicyWithStatus adds status information to the BindingPolicy
 type BindingPolicyWithStatus struct {
 	v1alpha1.BindingPolicy `json:",inline"`
@@ -61,15 +64,15 @@ type BindingPolicyWithStatus struct {
 
 // GetAllBp retrieves all BindingPolicies with enhanced information
 // GetBindingPolicies retrieves all BindingPolicies with enhanced information
-func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
+func GetBindingPolicies(wdsContext, namespace string) ([]map[string]interface{}, error) {
 	log.LogDebug("retrieving all binding policies")
-	log.LogDebug("Using wds context: ", zap.String("wds_context", os.Getenv("wds_context")))
+	log.LogDebug("Using wds context: ", zap.String("wds_context", wdsContext))
 	start := time.Now()
 	defer func() {
 		telemetry.BindingPolicyOperationDuration.WithLabelValues("GetAllBp").Observe(time.Since(start).Seconds())
 	}()
 	// Try to get from Redis cache first
-	cachedPolicies, err := redis.GetAllBindingPolicies()
+	cachedPolicies, err := redis.GetAllBindingPolicies(wdsContext)
 	if err != nil {
 		telemetry.BindingPolicyCacheMisses.WithLabelValues("get", "cache_miss").Inc()
 		log.LogWarn("failed to get binding policies from Redis cache", zap.Error(err))
@@ -115,7 +118,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 	}
 
 	// If cache miss or error, proceed with normal flow
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		log.LogError("failed to create client for Bp", zap.String("error", err.Error()))
 		return nil, err
@@ -149,10 +152,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 		// Add the YAML as a string to the policy
 		bpList.Items[i].Annotations["yaml"] = string(yamlData)
 
-		status := "inactive"
-		if bpList.Items[i].ObjectMeta.Generation == bpList.Items[i].Status.ObservedGeneration {
-			status = "active"
-		}
+		status := bpStatus(&bpList.Items[i])
 
 		log.LogDebug("Determined BP status from Kubernetes",
 			zap.String("policyName", bpList.Items[i].Name),
@@ -168,7 +168,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 
 		// Check if we have stored data for this policy that might have more details
 		policyName := bpList.Items[i].Name
-		storedBP, exists := UICreatedPolicies[policyName]
+		storedBP, exists := StoredPolicyFor(&bpList.Items[i])
 
 		if exists {
 			log.LogDebug("GetAllBp - Found stored BP in memory with key", zap.String("key", policyName))
@@ -357,7 +357,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 
 		// If we still don't have workloads, fallback to a general extraction method
 		if len(workloads) == 0 {
-			workloads = extractWorkloads(&bpList.Items[i])
+			workloads = extractWorkloads(wdsContext, &bpList.Items[i])
 		}
 
 		// If still no workloads after all attempts, add a default
@@ -393,7 +393,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 		}
 		if _, exists := bpWithStatus.Annotations["yaml"]; !exists {
 			// Check if this is a quick connect policy by looking for the annotation
-			if storedBP, exists := UICreatedPolicies[bpWithStatus.Name]; exists && storedBP.RawYAML != "" {
+			if storedBP, exists := StoredPolicyFor(&bpWithStatus.BindingPolicy); exists && storedBP.RawYAML != "" {
 				// Use the original YAML for quick connect policies
 				bpWithStatus.Annotations["yaml"] = storedBP.RawYAML
 			} else {
@@ -420,6 +420,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 							(!strings.HasPrefix(k, "kubectl.kubernetes.io/") &&
 								!strings.HasPrefix(k, "kubernetes.io/") &&
 								k != "yaml" &&
+								!isMetadataAnnotation(k) &&
 								!strings.Contains(k, "managedFields")) {
 							relevantAnnotations[k] = v
 						}
@@ -549,8 +550,15 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 			"conditions":        bp.BindingPolicy.Status.Conditions,
 		}
 
+		if storedBP, exists := StoredPolicyFor(&bp.BindingPolicy); exists {
+			policyMap["author"] = storedBP.Author
+			policyMap["template"] = storedBP.Template
+			policyMap["templateParameters"] = storedBP.TemplateParameters
+			policyMap["description"] = storedBP.Description
+		}
+
 		// Check if this is a quick connect policy and use its original YAML
-		if storedBP, exists := UICreatedPolicies[bp.Name]; exists && storedBP.RawYAML != "" {
+		if storedBP, exists := StoredPolicyFor(&bp.BindingPolicy); exists && storedBP.RawYAML != "" {
 			policyMap["yaml"] = storedBP.RawYAML
 		} else {
 			policyMap["yaml"] = bp.Annotations["yaml"]
@@ -563,7 +571,7 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 	for _, bp := range bpsWithStatus {
 		// Get the YAML content from annotations or stored policies
 		yamlContent := ""
-		if storedBP, exists := UICreatedPolicies[bp.Name]; exists && storedBP.RawYAML != "" {
+		if storedBP, exists := StoredPolicyFor(&bp.BindingPolicy); exists && storedBP.RawYAML != "" {
 			yamlContent = storedBP.RawYAML
 			log.LogDebug("Using stored YAML for caching", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(yamlContent)))
 		} else if bp.Annotations != nil && bp.Annotations["yaml"] != "" {
@@ -576,7 +584,9 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 		cachedPolicy := &redis.BindingPolicyCache{
 			Name:              bp.Name,
 			Namespace:         bp.Namespace,
+			WDSContext:        wdsContext,
 			Status:            bp.Status,
+			Conditions:        bp.BindingPolicy.Status.Conditions,
 			BindingMode:       bp.BindingMode,
 			Clusters:          bp.Clusters,
 			Workloads:         bp.Workloads,
@@ -597,11 +607,15 @@ func GetBindingPolicies(namespace string) ([]map[string]interface{}, error) {
 
 // GetAllBp is the API handler for retrieving all binding policies
 func GetAllBp(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	// Optional namespace filter
 	namespace := ctx.Query("namespace")
 
 	// Call the core function to get binding policies
-	bpolicies, err := GetBindingPolicies(namespace)
+	bpolicies, err := GetBindingPolicies(wdsContext, namespace)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/binding-policies", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -617,8 +631,12 @@ func GetAllBp(ctx *gin.Context) {
 
 // CreateBp creates a new BindingPolicy
 func CreateBp(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	log.LogInfo("starting Createbp handler",
-		zap.String("wds_context", os.Getenv("wds_context")))
+		zap.String("wds_context", wdsContext))
 	// Check Content-Type header
 	var bpRawYamlBytes []byte
 	var err error
@@ -670,25 +688,35 @@ func CreateBp(ctx *gin.Context) {
 		return
 
 	}
-	c, err := getClientForBp()
+	SetPolicyMetadata(bp, &StoredBindingPolicy{
+		Name:        bp.Name,
+		Namespace:   bp.Namespace,
+		RawYAML:     string(bpRawYamlBytes),
+		Author:      requestAuthor(ctx),
+		Description: ctx.Query("description"),
+	})
+
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		log.LogInfo(err.Error())
 		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/binding-policies", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	_, err = c.BindingPolicies().Create(context.TODO(), bp, v1.CreateOptions{})
+	created, err := c.BindingPolicies().Create(context.TODO(), bp, v1.CreateOptions{})
 	if err != nil {
 		log.LogError(err.Error())
 		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/binding-policies", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordRevision(wdsContext, created, false, requestAuthor(ctx))
 
 	// After successful creation, store in Redis
 	cachedBPolicy := &redis.BindingPolicyCache{
 		Name:              bp.Name,
 		Namespace:         bp.Namespace,
+		WDSContext:        wdsContext,
 		Status:            "inactive", // New policies start as inactive
 		BindingMode:       "Downsync", // Only Downsync is supported
 		CreationTimestamp: time.Now().Format(time.RFC3339),
@@ -703,11 +731,18 @@ func CreateBp(ctx *gin.Context) {
 		log.LogInfo("Successfully cached new binding policy", zap.String("policyName", bp.Name))
 	}
 	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/binding-policies", "201").Inc()
-	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Created binding policy '%s' successfully", bp.Name)})
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Created binding policy '%s' successfully", bp.Name),
+		"warnings": lintWarnings(itsContext, wdsContext, bp.Name),
+	})
 }
 
 // DeleteBp deletes a BindingPolicy by name and namespace
 func DeleteBp(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	name := ctx.Param("name")
 
 	if name == "" {
@@ -717,19 +752,22 @@ func DeleteBp(ctx *gin.Context) {
 	}
 
 	// Delete from Redis first
-	if err := redis.DeleteBindingPolicy(name); err != nil {
+	if err := redis.DeleteBindingPolicy(wdsContext, name); err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policy/:name", "500").Inc()
 		log.LogWarn("failed to delete binding policy from Redis cache", zap.Error(err))
 	}
 
 	log.LogInfo("", zap.String("deleting bp: ", name))
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policy/:name", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Keep the last state for the revision history
+	existing, getErr := c.BindingPolicies().Get(context.TODO(), name, v1.GetOptions{})
+
 	err = c.BindingPolicies().Delete(context.TODO(), name, v1.DeleteOptions{})
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policy/:name", "500").Inc()
@@ -739,6 +777,9 @@ func DeleteBp(ctx *gin.Context) {
 		})
 		return
 	}
+	if getErr == nil {
+		recordRevision(wdsContext, existing, true, requestAuthor(ctx))
+	}
 	telemetry.TotalHTTPRequests.WithLabelValues("DELETE", "/binding-policy/:name", "200").Inc()
 	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("deleted %s", name)})
 
@@ -746,13 +787,17 @@ func DeleteBp(ctx *gin.Context) {
 
 // DeleteAllBp deletes all BindingPolicies
 func DeleteAllBp(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	// Delete from Redis first
-	if err := redis.DeleteAllBindingPolicies(); err != nil {
+	if err := redis.DeleteAllBindingPolicies(wdsContext); err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policies", "500").Inc()
 		log.LogError("failed to delete all binding policies from Redis cache", zap.Error(err))
 	}
 
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policies", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -763,6 +808,9 @@ func DeleteAllBp(ctx *gin.Context) {
 	namespace := ctx.Query("namespace")
 	listOptions := v1.ListOptions{}
 
+	// Keep the last state of each policy for the revision history
+	existing, listErr := c.BindingPolicies().List(context.TODO(), listOptions)
+
 	err = c.BindingPolicies().DeleteCollection(context.TODO(), v1.DeleteOptions{}, listOptions)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/binding-policies", "500").Inc()
@@ -771,6 +819,12 @@ func DeleteAllBp(ctx *gin.Context) {
 		})
 		return
 	}
+	if listErr == nil {
+		author := requestAuthor(ctx)
+		for i := range existing.Items {
+			recordRevision(wdsContext, &existing.Items[i], true, author)
+		}
+	}
 
 	message := "Deleted all binding policies"
 	if namespace != "" {
@@ -782,6 +836,10 @@ func DeleteAllBp(ctx *gin.Context) {
 
 // GetBpStatus retrieves the status of a specific BindingPolicy
 func GetBpStatus(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	name := ctx.Query("name")
 	namespace := ctx.Query("namespace")
 
@@ -792,7 +850,7 @@ func GetBpStatus(ctx *gin.Context) {
 	}
 
 	// Try to get from Redis cache first
-	cachedPolicy, err := redis.GetBindingPolicy(name)
+	cachedPolicy, err := redis.GetBindingPolicy(wdsContext, name)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/binding-policy/status", "500").Inc()
 		log.LogWarn("failed to get binding policy from Redis cache", zap.Error(err))
@@ -813,7 +871,8 @@ func GetBpStatus(ctx *gin.Context) {
 			"name":              cachedPolicy.Name,
 			"namespace":         cachedPolicy.Namespace,
 			"status":            cachedPolicy.Status,
-			"conditions":        nil, // We don't cache conditions
+			"conditions":        cachedPolicy.Conditions,
+			"propagation":       propagationForStatus(ctx, name),
 			"bindingMode":       cachedPolicy.BindingMode,
 			"clusters":          cachedPolicy.Clusters,
 			"workloads":         cachedPolicy.Workloads,
@@ -834,7 +893,7 @@ func GetBpStatus(ctx *gin.Context) {
 
 	log.LogDebug("GetBpStatus - Using namespace", zap.String("namespace", namespace))
 
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/binding-policy/status", "500").Inc()
 		log.LogError("GetBpStatus - Client error", zap.Error(err))
@@ -882,21 +941,18 @@ func GetBpStatus(ctx *gin.Context) {
 		log.LogDebug("GetBpStatus - Found BP with matching name in namespace", zap.String("namespace", bp.Namespace))
 	}
 
-	// Look for this binding policy in the UICreatedPolicies map
-	storedBP, exists := UICreatedPolicies[name]
+	// Look for the authoring metadata recorded on the policy
+	storedBP, exists := StoredPolicyFor(bp)
 	if exists {
-		log.LogDebug("GetBpStatus - Found stored BP in memory with key", zap.String("name", name))
+		log.LogDebug("GetBpStatus - Found stored metadata on BP", zap.String("name", name))
 		// Debug the stored policy
 		log.LogDebug(" Stored BP ClusterSelectors", zap.Any("clustersSelectors", storedBP.ClusterSelectors))
 	} else {
-		log.LogDebug("GetBpStatus - No stored BP found in memory with key", zap.String("name", name))
+		log.LogDebug("GetBpStatus - No stored metadata on BP", zap.String("name", name))
 	}
 
 	// Determine if the policy is active based on status fields
-	status := "inactive"
-	if bp.ObjectMeta.Generation == bp.Status.ObservedGeneration {
-		status = "active"
-	}
+	status := bpStatus(bp)
 	// Initialize clusters and workloads slices
 	clusters := []string{}
 	workloads := []string{}
@@ -1180,12 +1236,25 @@ func GetBpStatus(ctx *gin.Context) {
 		zap.Any("clusters", clusters),
 		zap.Int("workloads_count", len(workloads)),
 		zap.Any("workloads", workloads))
+	authoring := gin.H{}
+	if exists {
+		authoring = gin.H{
+			"author":             storedBP.Author,
+			"template":           storedBP.Template,
+			"templateParameters": storedBP.TemplateParameters,
+			"description":        storedBP.Description,
+			"createdAt":          storedBP.CreatedAt,
+		}
+	}
+
 	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/binding-policy/status", "200").Inc()
 	ctx.JSON(http.StatusOK, gin.H{
+		"authoring":         authoring,
 		"name":              bp.Name,
 		"namespace":         bp.Namespace,
 		"status":            status,
 		"conditions":        bp.Status.Conditions,
+		"propagation":       propagationForStatus(ctx, bp.Name),
 		"bindingMode":       "Downsync", // KubeStellar only supports Downsync currently
 		"clusters":          clusters,
 		"workloads":         workloads,
@@ -1198,6 +1267,10 @@ func GetBpStatus(ctx *gin.Context) {
 
 // Updates the Binding policy with the given name, Assuming that it exists
 func UpdateBp(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 
 	bpName := ctx.Param("name")
 	if bpName == "" {
@@ -1211,7 +1284,16 @@ func UpdateBp(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 
-	c, err := getClientForBp()
+	// Record who made the change, and that it is no longer a rollback
+	author := requestAuthor(ctx)
+	jsonBytes, err = withModifiedBy(jsonBytes, author)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/binding-policy/:name", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid merge patch: %v", err)})
+		return
+	}
+
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("PATCH", "/binding-policy/:name", "500").Inc()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1223,16 +1305,25 @@ func UpdateBp(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordRevision(wdsContext, updatedBp, false, author)
+
 	telemetry.TotalHTTPRequests.WithLabelValues("PATCH", "/binding-policy/:name", "200").Inc()
-	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("updated %s", updatedBp.Name)})
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("updated %s", updatedBp.Name),
+		"warnings": lintWarnings(itsContext, wdsContext, updatedBp.Name),
+	})
 
 }
 
 // CreateBpFromJson creates a new BindingPolicy from JSON data sent by the UI
 func CreateBpFromJson(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	log.LogInfo("Starting CreateBpFromJson handler")
 	log.LogDebug("KUBECONFIG", zap.String("KUBECONFIG", os.Getenv("KUBECONFIG")))
-	log.LogDebug("wds_context", zap.String("wds_context", os.Getenv("wds_context")))
+	log.LogDebug("wds_context", zap.String("wds_context", wdsContext))
 
 	// Check Content-Type header
 	contentType := ctx.GetHeader("Content-Type")
@@ -1261,6 +1352,11 @@ func CreateBpFromJson(ctx *gin.Context) {
 		CustomLabels    map[string]string `json:"customLabels"`
 		ClusterId       string            `json:"clusterId"`
 		WorkloadId      string            `json:"workloadId"`
+		Description     string            `json:"description"`
+		// Names of the StatusCollectors added to every downsync rule
+		StatusCollectors []string `json:"statusCollectors"`
+		// Whether the status reported by a single WEC is written back to the WDS objects
+		WantSingletonReportedState bool `json:"wantSingletonReportedState"`
 	}
 
 	var bpRequest BindingPolicyRequest
@@ -1440,6 +1536,15 @@ func CreateBpFromJson(ctx *gin.Context) {
 		}
 	}
 
+	for _, rule := range downsyncRules {
+		if len(bpRequest.StatusCollectors) > 0 {
+			rule.(map[string]interface{})["statusCollectors"] = bpRequest.StatusCollectors
+		}
+		if bpRequest.WantSingletonReportedState {
+			rule.(map[string]interface{})["wantSingletonReportedState"] = true
+		}
+	}
+
 	// Set the downsync rules in the policy object
 	policyObj["spec"].(map[string]interface{})["downsync"] = downsyncRules
 
@@ -1510,14 +1615,15 @@ func CreateBpFromJson(ctx *gin.Context) {
 		Namespaces:        bpRequest.WorkloadSelectors.Namespaces,
 		SpecificWorkloads: bpRequest.WorkloadSelectors.Workloads,
 		RawYAML:           rawYAML,
+		Author:            requestAuthor(ctx),
+		Description:       bpRequest.Description,
 	}
 
-	// Store policy before API call
-	UICreatedPolicies[newBP.Name] = storedBP
-	log.LogInfo("Stored policy in memory cache", zap.String("key", newBP.Name))
+	// Record the authoring metadata on the policy itself
+	SetPolicyMetadata(newBP, storedBP)
 
 	// Get client
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		log.LogError("Client creation error", zap.Error(err))
 		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/binding-policies/json", "500").Inc()
@@ -1526,7 +1632,7 @@ func CreateBpFromJson(ctx *gin.Context) {
 	}
 
 	// Create the binding policy
-	_, err = c.BindingPolicies().Create(context.TODO(), newBP, v1.CreateOptions{})
+	created, err := c.BindingPolicies().Create(context.TODO(), newBP, v1.CreateOptions{})
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			telemetry.HTTPErrorCounter.WithLabelValues("POST", "/binding-policies/json", "409").Inc()
@@ -1540,6 +1646,7 @@ func CreateBpFromJson(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create binding policy: %s", err.Error())})
 		return
 	}
+	recordRevision(wdsContext, created, false, requestAuthor(ctx))
 
 	// Extract clusters for response
 	clusters := []string{}
@@ -1601,6 +1708,7 @@ func CreateBpFromJson(ctx *gin.Context) {
 			"workloadsCount": len(workloads),
 			"yaml":           rawYAML,
 		},
+		"warnings": lintWarnings(itsContext, wdsContext, newBP.Name),
 	})
 }
 
@@ -1616,6 +1724,10 @@ func contains(slice []string, str string) bool {
 
 // CreateQuickBindingPolicy creates a simple binding policy connecting workload(s) to cluster(s)
 func CreateQuickBindingPolicy(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
 	log.LogInfo("Starting CreateQuickBindingPolicy handler")
 
 	// Define a struct to parse the quick connection request
@@ -1623,6 +1735,10 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 		Type       string `json:"type"`       // Resource type (e.g., "deployments", "namespaces")
 		CreateOnly bool   `json:"createOnly"` // Whether to use createOnly mode for this resource
 		APIGroup   string `json:"apiGroup"`   // Optional API group for the resource (for CRDs)
+		// Names of the StatusCollectors that aggregate the status of this resource
+		StatusCollectors []string `json:"statusCollectors"`
+		// Whether the status reported by a single WEC is written back to the WDS object
+		WantSingletonReportedState bool `json:"wantSingletonReportedState"`
 	}
 
 	type QuickBindingPolicyRequest struct {
@@ -1632,9 +1748,12 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 		NamespacesToSync []string          `json:"namespacesToSync"` // Namespaces to sync resources from
 		PolicyName       string            `json:"policyName"`       // Optional custom name for the policy
 		Namespace        string            `json:"namespace"`        // Optional namespace
+		Description      string            `json:"description"`      // Optional description of the policy
 		// For backward compatibility
 		ResourceTypes []string `json:"resourceTypes"` // Legacy: Resource types to sync
 		CreateOnly    bool     `json:"createOnly"`    // Legacy: Whether to use createOnly mode for all resources
+		// Request the singleton reported state for every resource
+		WantSingletonReportedState bool `json:"wantSingletonReportedState"`
 	}
 
 	var request QuickBindingPolicyRequest
@@ -1859,6 +1978,14 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 			downsyncRule["createOnly"] = true
 		}
 
+		if len(resourceCfg.StatusCollectors) > 0 {
+			downsyncRule["statusCollectors"] = resourceCfg.StatusCollectors
+		}
+
+		if resourceCfg.WantSingletonReportedState || request.WantSingletonReportedState {
+			downsyncRule["wantSingletonReportedState"] = true
+		}
+
 		// Add namespaces to the rule
 		if len(namespacesToSync) > 0 {
 			downsyncRule["namespaces"] = namespacesToSync
@@ -1889,16 +2016,17 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 		return
 	}
 
-	// Store the policy in memory for future reference
-	storedBP := &StoredBindingPolicy{
-		Name:      policyName,
-		Namespace: namespace,
-		RawYAML:   rawYAML,
-	}
-	UICreatedPolicies[policyName] = storedBP
+	// Record the authoring metadata on the policy itself
+	SetPolicyMetadata(newBP, &StoredBindingPolicy{
+		Name:        policyName,
+		Namespace:   namespace,
+		RawYAML:     rawYAML,
+		Author:      requestAuthor(ctx),
+		Description: request.Description,
+	})
 
 	// Get client and create the binding policy
-	c, err := getClientForBp()
+	c, err := getClientForBp(wdsContext)
 	if err != nil {
 		log.LogError("Client creation error", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create client: %s", err.Error())})
@@ -1906,7 +2034,7 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 	}
 
 	// Create the binding policy
-	_, err = c.BindingPolicies().Create(context.TODO(), newBP, v1.CreateOptions{})
+	created, err := c.BindingPolicies().Create(context.TODO(), newBP, v1.CreateOptions{})
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			ctx.JSON(http.StatusConflict, gin.H{
@@ -1919,6 +2047,7 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create binding policy: %s", err.Error())})
 		return
 	}
+	recordRevision(wdsContext, created, false, requestAuthor(ctx))
 
 	// Format the response
 	clusterLabelsFormatted := []string{}
@@ -1939,6 +2068,9 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 			if res.CreateOnly {
 				resourceDesc += " (createOnly)"
 			}
+			if res.WantSingletonReportedState || request.WantSingletonReportedState {
+				resourceDesc += " (singleton status)"
+			}
 			resourcesFormatted = append(resourcesFormatted, resourceDesc)
 		}
 	}
@@ -1956,6 +2088,7 @@ func CreateQuickBindingPolicy(ctx *gin.Context) {
 			"workloadsCount": len(resourcesFormatted) + len(workloadLabelsFormatted),
 			"yaml":           rawYAML,
 		},
+		"warnings": lintWarnings(itsContext, wdsContext, policyName),
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -1979,6 +2112,10 @@ func GenerateQuickBindingPolicyYAML(ctx *gin.Context) {
 		Type       string `json:"type"`       // Resource type (e.g., "deployments", "namespaces")
 		CreateOnly bool   `json:"createOnly"` // Whether to use createOnly mode for this resource
 		APIGroup   string `json:"apiGroup"`   // Optional API group for the resource (for CRDs)
+		// Names of the StatusCollectors that aggregate the status of this resource
+		StatusCollectors []string `json:"statusCollectors"`
+		// Whether the status reported by a single WEC is written back to the WDS object
+		WantSingletonReportedState bool `json:"wantSingletonReportedState"`
 	}
 
 	type QuickBindingPolicyRequest struct {
@@ -1991,6 +2128,8 @@ func GenerateQuickBindingPolicyYAML(ctx *gin.Context) {
 		// For backward compatibility
 		ResourceTypes []string `json:"resourceTypes"` // Legacy: Resource types to sync
 		CreateOnly    bool     `json:"createOnly"`    // Legacy: Whether to use createOnly mode for all resources
+		// Request the singleton reported state for every resource
+		WantSingletonReportedState bool `json:"wantSingletonReportedState"`
 	}
 
 	var request QuickBindingPolicyRequest
@@ -2216,6 +2355,14 @@ func GenerateQuickBindingPolicyYAML(ctx *gin.Context) {
 			downsyncRule["createOnly"] = true
 		}
 
+		if len(resourceCfg.StatusCollectors) > 0 {
+			downsyncRule["statusCollectors"] = resourceCfg.StatusCollectors
+		}
+
+		if resourceCfg.WantSingletonReportedState || request.WantSingletonReportedState {
+			downsyncRule["wantSingletonReportedState"] = true
+		}
+
 		// Add namespaces to the rule
 		if len(namespacesToSync) > 0 {
 			downsyncRule["namespaces"] = namespacesToSync
@@ -2258,6 +2405,9 @@ func GenerateQuickBindingPolicyYAML(ctx *gin.Context) {
 			if res.CreateOnly {
 				resourceDesc += " (createOnly)"
 			}
+			if res.WantSingletonReportedState || request.WantSingletonReportedState {
+				resourceDesc += " (singleton status)"
+			}
 			resourcesFormatted = append(resourcesFormatted, resourceDesc)
 		}
 	}
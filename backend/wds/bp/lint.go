@@ -0,0 +1,650 @@
+package bp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Severities of lint findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Lint rules
+const (
+	RuleInvalidSelector             = "invalid-selector"
+	RuleNoClusterSelectors          = "no-cluster-selectors"
+	RuleNoMatchingClusters          = "no-matching-clusters"
+	RuleNoDownsyncRules             = "no-downsync-rules"
+	RuleNoMatchingObjects           = "no-matching-objects"
+	RuleMissingNamespace            = "missing-namespace"
+	RuleUnknownResource             = "unknown-resource"
+	RuleContradictoryCreateOnly     = "contradictory-create-only"
+	RuleSingletonMultipleClusters   = "singleton-multiple-clusters"
+	RuleOverlappingSelection        = "overlapping-selection"
+	RuleConflictingCreateOnly       = "conflicting-create-only"
+	RuleConflictingStatusCollectors = "conflicting-status-collectors"
+)
+
+// maxFindingObjects caps how many objects a single finding lists
+const maxFindingObjects = 20
+
+// lintTimeout bounds the lint run that create and update handlers attach to
+// their response
+const lintTimeout = 10 * time.Second
+
+// LintFinding is one problem found in a policy or between policies
+type LintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Policy   string `json:"policy"`
+	// RelatedPolicies are the other policies involved in a conflict
+	RelatedPolicies []string        `json:"relatedPolicies,omitempty"`
+	Message         string          `json:"message"`
+	Clusters        []string        `json:"clusters,omitempty"`
+	Objects         []PreviewObject `json:"objects,omitempty"`
+}
+
+// LintSummary counts findings by severity
+type LintSummary struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Info     int `json:"info"`
+}
+
+// LintReport is the result of linting the policies of a WDS
+type LintReport struct {
+	Policies int           `json:"policies"`
+	Summary  LintSummary   `json:"summary"`
+	Findings []LintFinding `json:"findings"`
+	// Warnings are problems that kept parts of the lint from running
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// lintInventory is what the policies are evaluated against, read once per run
+type lintInventory struct {
+	clients         PreviewClients
+	clusters        []unstructured.Unstructured
+	resources       []lintResource
+	namespaceLabels map[string]map[string]string
+	objects         map[schema.GroupVersionResource][]unstructured.Unstructured
+	// listedOnly restricts selections to the resources listed so far
+	listedOnly bool
+	warnings   []string
+}
+
+type lintResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// policySelection is what one policy selects. modulations holds, for each
+// selected object, the downsync modulation of every rule selecting it.
+type policySelection struct {
+	bp          *v1alpha1.BindingPolicy
+	clusters    []string
+	objects     map[string]PreviewObject
+	modulations map[string][]v1alpha1.DownsyncModulation
+}
+
+// LintBindingPolicies checks policies individually and against each other:
+// selectors that match nothing, references to namespaces and resources that
+// do not exist in the WDS, contradictory flags, and policies that select the
+// same object for the same cluster with different createOnly settings or
+// status collectors
+func LintBindingPolicies(ctx context.Context, policies []*v1alpha1.BindingPolicy, clients PreviewClients) (*LintReport, error) {
+	inventory, err := newLintInventory(ctx, clients)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{Policies: len(policies), Findings: []LintFinding{}}
+	selections := make([]*policySelection, 0, len(policies))
+	for _, bp := range policies {
+		selection, findings := inventory.lintPolicy(ctx, bp)
+		report.Findings = append(report.Findings, findings...)
+		if selection != nil {
+			selections = append(selections, selection)
+		}
+	}
+	report.Findings = append(report.Findings, lintOverlaps(selections)...)
+	report.Warnings = inventory.warnings
+	sortFindings(report.Findings)
+	report.Summary = summarizeFindings(report.Findings)
+	return report, nil
+}
+
+// LintBindingPolicy reports the findings LintBindingPolicies reports for the
+// named policy, its own problems and its conflicts with the other policies.
+// Only the objects of the resources the named policy selects are listed, so
+// it is cheap enough to run whenever a policy is created or updated.
+func LintBindingPolicy(ctx context.Context, name string, policies []*v1alpha1.BindingPolicy, clients PreviewClients) (*LintReport, error) {
+	var target *v1alpha1.BindingPolicy
+	for _, bp := range policies {
+		if bp.Name == name {
+			target = bp
+			break
+		}
+	}
+	report := &LintReport{Policies: len(policies), Findings: []LintFinding{}}
+	if target == nil {
+		return report, nil
+	}
+
+	inventory, err := newLintInventory(ctx, clients)
+	if err != nil {
+		return nil, err
+	}
+	selection, findings := inventory.lintPolicy(ctx, target)
+	report.Findings = append(report.Findings, findings...)
+	if selection != nil && len(selection.objects) > 0 {
+		// Objects the others share with the policy are of the resources
+		// listed for it, the others are not listed again
+		inventory.listedOnly = true
+		before := true
+		for _, bp := range policies {
+			if bp == target {
+				before = false
+				continue
+			}
+			other, _ := inventory.lintPolicy(ctx, bp)
+			if other == nil {
+				continue
+			}
+			// Pairs are reported in the order of the policies, as
+			// LintBindingPolicies does
+			pair := []*policySelection{selection, other}
+			if before {
+				pair = []*policySelection{other, selection}
+			}
+			report.Findings = append(report.Findings, lintOverlaps(pair)...)
+		}
+	}
+	report.Warnings = inventory.warnings
+	sortFindings(report.Findings)
+	report.Summary = summarizeFindings(report.Findings)
+	return report, nil
+}
+
+// sortFindings orders findings by severity, policy and rule
+func sortFindings(findings []LintFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		return a.Rule < b.Rule
+	})
+}
+
+func summarizeFindings(findings []LintFinding) LintSummary {
+	var summary LintSummary
+	for _, finding := range findings {
+		switch finding.Severity {
+		case SeverityError:
+			summary.Errors++
+		case SeverityWarning:
+			summary.Warnings++
+		default:
+			summary.Info++
+		}
+	}
+	return summary
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func newLintInventory(ctx context.Context, clients PreviewClients) (*lintInventory, error) {
+	inventory := &lintInventory{
+		clients: clients,
+		objects: map[schema.GroupVersionResource][]unstructured.Unstructured{},
+	}
+
+	clusters, err := clients.ITS.Resource(managedClusterGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed clusters: %v", err)
+	}
+	inventory.clusters = clusters.Items
+
+	resourceLists, err := discovery.ServerPreferredResources(clients.WDSDiscovery)
+	if err != nil && len(resourceLists) == 0 {
+		return nil, fmt.Errorf("failed to discover WDS resources: %v", err)
+	}
+	if err != nil {
+		inventory.warnings = append(inventory.warnings, fmt.Sprintf("some API groups could not be discovered: %v", err))
+	}
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") || !containsVerb(resource.Verbs, "list") {
+				continue
+			}
+			inventory.resources = append(inventory.resources, lintResource{gv.WithResource(resource.Name), resource.Namespaced})
+		}
+	}
+
+	inventory.namespaceLabels, err = listNamespaceLabels(ctx, clients.WDS)
+	if err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// listObjects lists the objects of a resource, once per lint run
+func (inv *lintInventory) listObjects(ctx context.Context, gvr schema.GroupVersionResource) []unstructured.Unstructured {
+	if objects, ok := inv.objects[gvr]; ok || inv.listedOnly {
+		return objects
+	}
+	list, err := inv.clients.WDS.Resource(gvr).List(ctx, v1.ListOptions{})
+	if err != nil {
+		inv.warnings = append(inv.warnings, fmt.Sprintf("failed to list %s: %v", gvr.String(), err))
+		inv.objects[gvr] = nil
+		return nil
+	}
+	inv.objects[gvr] = list.Items
+	return list.Items
+}
+
+// lintPolicy checks one policy and returns what it selects, or nil when its
+// selectors are invalid
+func (inv *lintInventory) lintPolicy(ctx context.Context, bp *v1alpha1.BindingPolicy) (*policySelection, []LintFinding) {
+	var findings []LintFinding
+	finding := func(rule, severity, message string) LintFinding {
+		return LintFinding{Rule: rule, Severity: severity, Policy: bp.Name, Message: message}
+	}
+
+	compiled := make([]compiledClause, 0, len(bp.Spec.Downsync))
+	for i, clause := range bp.Spec.Downsync {
+		namespaceSelectors, err := parseSelectors(clause.NamespaceSelectors)
+		if err != nil {
+			findings = append(findings, finding(RuleInvalidSelector, SeverityError, fmt.Sprintf("invalid namespace selector in downsync rule %d: %v", i, err)))
+			continue
+		}
+		objectSelectors, err := parseSelectors(clause.ObjectSelectors)
+		if err != nil {
+			findings = append(findings, finding(RuleInvalidSelector, SeverityError, fmt.Sprintf("invalid object selector in downsync rule %d: %v", i, err)))
+			continue
+		}
+		compiled = append(compiled, compiledClause{clause, namespaceSelectors, objectSelectors})
+	}
+
+	clusters, err := matchClusters(bp.Spec.ClusterSelectors, inv.clusters)
+	if err != nil {
+		findings = append(findings, finding(RuleInvalidSelector, SeverityError, err.Error()))
+		return nil, findings
+	}
+	if len(findings) > 0 {
+		return nil, findings
+	}
+
+	switch {
+	case len(bp.Spec.ClusterSelectors) == 0:
+		findings = append(findings, finding(RuleNoClusterSelectors, SeverityWarning, "policy has no cluster selectors and selects no clusters"))
+	case len(clusters) == 0:
+		findings = append(findings, finding(RuleNoMatchingClusters, SeverityWarning, "cluster selectors match no managed cluster"))
+	}
+
+	for i, clause := range compiled {
+		findings = append(findings, inv.lintReferences(bp.Name, i, clause)...)
+	}
+
+	selection := &policySelection{
+		bp:          bp,
+		clusters:    clusters,
+		objects:     map[string]PreviewObject{},
+		modulations: map[string][]v1alpha1.DownsyncModulation{},
+	}
+	for _, resource := range inv.resources {
+		if previewSkippedGroups[resource.gvr.Group] || (resource.gvr.Group == "" && previewSkippedResources[resource.gvr.Resource]) {
+			continue
+		}
+		if !resourceSelected(compiled, resource.gvr.Group, resource.gvr.Resource) {
+			continue
+		}
+		for _, obj := range inv.listObjects(ctx, resource.gvr) {
+			obj := obj
+			for _, clause := range compiled {
+				if !objectSelected([]compiledClause{clause}, resource.gvr.Group, resource.gvr.Resource, resource.namespaced, &obj, inv.namespaceLabels) {
+					continue
+				}
+				ref := PreviewObject{
+					Group:     resource.gvr.Group,
+					Version:   resource.gvr.Version,
+					Resource:  resource.gvr.Resource,
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetName(),
+				}
+				selection.objects[ref.key()] = ref
+				selection.modulations[ref.key()] = append(selection.modulations[ref.key()], clause.DownsyncModulation)
+			}
+		}
+	}
+
+	switch {
+	case len(bp.Spec.Downsync) == 0:
+		findings = append(findings, finding(RuleNoDownsyncRules, SeverityWarning, "policy has no downsync rules and selects no objects"))
+	case len(selection.objects) == 0:
+		findings = append(findings, finding(RuleNoMatchingObjects, SeverityWarning, "downsync rules match no object in the WDS"))
+	}
+
+	var contradictory []PreviewObject
+	for key, modulations := range selection.modulations {
+		for _, modulation := range modulations[1:] {
+			if modulation.CreateOnly != modulations[0].CreateOnly {
+				contradictory = append(contradictory, selection.objects[key])
+				break
+			}
+		}
+	}
+	if len(contradictory) > 0 {
+		f := finding(RuleContradictoryCreateOnly, SeverityWarning, "downsync rules of this policy select the same objects with different createOnly settings")
+		f.Objects = limitObjects(contradictory)
+		findings = append(findings, f)
+	}
+
+	for _, clause := range bp.Spec.Downsync {
+		if clause.WantSingletonReportedState && len(clusters) > 1 {
+			f := finding(RuleSingletonMultipleClusters, SeverityWarning,
+				fmt.Sprintf("wantSingletonReportedState is set but the policy selects %d clusters, so no singleton status will be reported", len(clusters)))
+			f.Clusters = clusters
+			findings = append(findings, f)
+			break
+		}
+	}
+
+	return selection, findings
+}
+
+// lintReferences checks that the namespaces and resources a downsync rule
+// names exist in the WDS. Wildcards are not checked.
+func (inv *lintInventory) lintReferences(policy string, index int, clause compiledClause) []LintFinding {
+	var findings []LintFinding
+
+	for _, namespace := range clause.Namespaces {
+		if namespace == "*" {
+			continue
+		}
+		if _, exists := inv.namespaceLabels[namespace]; !exists {
+			findings = append(findings, LintFinding{
+				Rule:     RuleMissingNamespace,
+				Severity: SeverityWarning,
+				Policy:   policy,
+				Message:  fmt.Sprintf("downsync rule %d references namespace %q, which does not exist in the WDS", index, namespace),
+			})
+		}
+	}
+
+	for _, resourceName := range clause.Resources {
+		if resourceName == "*" {
+			continue
+		}
+		found := false
+		for _, resource := range inv.resources {
+			if resource.gvr.Resource == resourceName && groupMatches(clause.APIGroup, resource.gvr.Group) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			group := "any API group"
+			if clause.APIGroup != nil {
+				group = fmt.Sprintf("API group %q", *clause.APIGroup)
+			}
+			findings = append(findings, LintFinding{
+				Rule:     RuleUnknownResource,
+				Severity: SeverityError,
+				Policy:   policy,
+				Message:  fmt.Sprintf("downsync rule %d references resource %q, which is not served in %s; is its CRD installed?", index, resourceName, group),
+			})
+		}
+	}
+	return findings
+}
+
+// lintOverlaps reports pairs of policies that select the same objects for the
+// same clusters, as errors when they disagree on createOnly and warnings when
+// they disagree on status collectors
+func lintOverlaps(selections []*policySelection) []LintFinding {
+	var findings []LintFinding
+	for i := 0; i < len(selections); i++ {
+		for j := i + 1; j < len(selections); j++ {
+			a, b := selections[i], selections[j]
+
+			clusters := intersectStrings(a.clusters, b.clusters)
+			if len(clusters) == 0 {
+				continue
+			}
+
+			var shared, createOnly, collectors []PreviewObject
+			for key, obj := range a.objects {
+				if _, ok := b.objects[key]; !ok {
+					continue
+				}
+				shared = append(shared, obj)
+				aModulation, bModulation := mergeModulations(a.modulations[key]), mergeModulations(b.modulations[key])
+				if aModulation.CreateOnly != bModulation.CreateOnly {
+					createOnly = append(createOnly, obj)
+				}
+				if strings.Join(aModulation.StatusCollectors, ",") != strings.Join(bModulation.StatusCollectors, ",") {
+					collectors = append(collectors, obj)
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+
+			pair := func(rule, severity, message string, objects []PreviewObject) LintFinding {
+				return LintFinding{
+					Rule:            rule,
+					Severity:        severity,
+					Policy:          a.bp.Name,
+					RelatedPolicies: []string{b.bp.Name},
+					Message:         message,
+					Clusters:        clusters,
+					Objects:         limitObjects(objects),
+				}
+			}
+			if len(createOnly) > 0 {
+				findings = append(findings, pair(RuleConflictingCreateOnly, SeverityError,
+					fmt.Sprintf("policies %s and %s select %d of the same objects for the same clusters with different createOnly settings", a.bp.Name, b.bp.Name, len(createOnly)),
+					createOnly))
+			}
+			if len(collectors) > 0 {
+				findings = append(findings, pair(RuleConflictingStatusCollectors, SeverityWarning,
+					fmt.Sprintf("policies %s and %s select %d of the same objects for the same clusters with different status collectors", a.bp.Name, b.bp.Name, len(collectors)),
+					collectors))
+			}
+			if len(createOnly) == 0 && len(collectors) == 0 {
+				findings = append(findings, pair(RuleOverlappingSelection, SeverityInfo,
+					fmt.Sprintf("policies %s and %s select %d of the same objects for the same clusters", a.bp.Name, b.bp.Name, len(shared)),
+					shared))
+			}
+		}
+	}
+	return findings
+}
+
+// mergeModulations combines the modulations of the rules of one policy that
+// select the same object: createOnly if any rule asks for it, and the union
+// of the status collectors
+func mergeModulations(modulations []v1alpha1.DownsyncModulation) v1alpha1.DownsyncModulation {
+	merged := v1alpha1.DownsyncModulation{}
+	collectors := map[string]bool{}
+	for _, modulation := range modulations {
+		merged.CreateOnly = merged.CreateOnly || modulation.CreateOnly
+		merged.WantSingletonReportedState = merged.WantSingletonReportedState || modulation.WantSingletonReportedState
+		for _, collector := range modulation.StatusCollectors {
+			collectors[collector] = true
+		}
+	}
+	for collector := range collectors {
+		merged.StatusCollectors = append(merged.StatusCollectors, collector)
+	}
+	sort.Strings(merged.StatusCollectors)
+	return merged
+}
+
+func intersectStrings(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if inB[s] {
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func limitObjects(objects []PreviewObject) []PreviewObject {
+	sortObjects(objects)
+	if len(objects) > maxFindingObjects {
+		return objects[:maxFindingObjects]
+	}
+	return objects
+}
+
+// FindingsForPolicy returns the findings that involve the named policy
+func (r *LintReport) FindingsForPolicy(name string) []LintFinding {
+	findings := []LintFinding{}
+	for _, finding := range r.Findings {
+		if finding.Policy == name || contains(finding.RelatedPolicies, name) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// lintClients connects to the ITS and WDS a lint runs against
+func lintClients(itsContext, wdsContext string) (PreviewClients, error) {
+	_, itsClient, err := k8s.GetClientSetWithContext(itsContext)
+	if err != nil {
+		return PreviewClients{}, fmt.Errorf("failed to connect to ITS %s: %v", itsContext, err)
+	}
+	wdsClientset, wdsClient, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		return PreviewClients{}, fmt.Errorf("failed to connect to WDS %s: %v", wdsContext, err)
+	}
+	return PreviewClients{ITS: itsClient, WDS: wdsClient, WDSDiscovery: wdsClientset.Discovery()}, nil
+}
+
+// listPolicies returns the BindingPolicies of a WDS
+func listPolicies(ctx context.Context, clients PreviewClients) ([]*v1alpha1.BindingPolicy, error) {
+	list, err := clients.WDS.Resource(bindingPolicyGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list binding policies: %v", err)
+	}
+	policies := make([]*v1alpha1.BindingPolicy, 0, len(list.Items))
+	for i := range list.Items {
+		bp, err := toBindingPolicy(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, bp)
+	}
+	return policies, nil
+}
+
+// lintWarnings lints a policy after it was created or updated in a WDS and
+// returns the findings involving it. Lint problems are logged and never fail
+// the request.
+func lintWarnings(itsContext, wdsContext, name string) []LintFinding {
+	ctx, cancel := context.WithTimeout(context.Background(), lintTimeout)
+	defer cancel()
+
+	clients, err := lintClients(itsContext, wdsContext)
+	if err == nil {
+		var policies []*v1alpha1.BindingPolicy
+		if policies, err = listPolicies(ctx, clients); err == nil {
+			var report *LintReport
+			if report, err = LintBindingPolicy(ctx, name, policies, clients); err == nil {
+				return report.Findings
+			}
+		}
+	}
+	log.LogWarn("failed to lint binding policy", zap.String("policyName", name), zap.Error(err))
+	return []LintFinding{}
+}
+
+// LintBps lints every BindingPolicy of a WDS. The "policy" query parameter
+// limits the findings to one policy and "severity" to one severity.
+func LintBps(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	clients, err := lintClients(itsContext, wdsContext)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/lint", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	policies, err := listPolicies(ctx.Request.Context(), clients)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/lint", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var report *LintReport
+	if name := ctx.Query("policy"); name != "" {
+		report, err = LintBindingPolicy(ctx.Request.Context(), name, policies, clients)
+	} else {
+		report, err = LintBindingPolicies(ctx.Request.Context(), policies, clients)
+	}
+	if err != nil {
+		log.LogError("failed to lint binding policies", zap.String("wdsContext", wdsContext), zap.Error(err))
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/lint", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if severity := ctx.Query("severity"); severity != "" {
+		filtered := []LintFinding{}
+		for _, finding := range report.Findings {
+			if finding.Severity == severity {
+				filtered = append(filtered, finding)
+			}
+		}
+		report.Findings = filtered
+	}
+	report.Summary = summarizeFindings(report.Findings)
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/lint", "200").Inc()
+	ctx.JSON(http.StatusOK, report)
+}
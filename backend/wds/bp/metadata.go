@@ -0,0 +1,149 @@
+package bp
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/utils"
+	"go.uber.org/zap"
+)
+
+// Authoring metadata is kept in annotations on the BindingPolicy itself, so
+// it survives restarts and every replica of the backend sees the same data
+const (
+	metadataAnnotationPrefix = "ui.kubestellar.io/"
+
+	// AnnotationOriginalYAML holds the YAML the policy was created from
+	AnnotationOriginalYAML = metadataAnnotationPrefix + "original-yaml"
+	// AnnotationAuthor is the UI user who created the policy
+	AnnotationAuthor = metadataAnnotationPrefix + "author"
+	// AnnotationTemplate is the template the policy was created from, if any
+	AnnotationTemplate = metadataAnnotationPrefix + "template"
+	// AnnotationTemplateParameters holds the template parameters as JSON
+	AnnotationTemplateParameters = metadataAnnotationPrefix + "template-parameters"
+	// AnnotationDescription is a free-form description of the policy
+	AnnotationDescription = metadataAnnotationPrefix + "description"
+	// AnnotationCreatedAt is when the policy was created through the UI
+	AnnotationCreatedAt = metadataAnnotationPrefix + "created-at"
+	// AnnotationSelectors holds the selectors entered in the UI as JSON
+	AnnotationSelectors = metadataAnnotationPrefix + "selectors"
+)
+
+// storedSelectors is the part of StoredBindingPolicy kept in AnnotationSelectors
+type storedSelectors struct {
+	ClusterSelectors  []map[string]string `json:"clusterSelectors,omitempty"`
+	APIGroups         []string            `json:"apiGroups,omitempty"`
+	Resources         []string            `json:"resources,omitempty"`
+	Namespaces        []string            `json:"namespaces,omitempty"`
+	SpecificWorkloads []WorkloadInfo      `json:"specificWorkloads,omitempty"`
+}
+
+// SetPolicyMetadata records the authoring metadata of stored as annotations
+// on a policy being created. The author and creation time always come from
+// the server; other annotations already present on bp, for example set in
+// uploaded YAML, are kept.
+func SetPolicyMetadata(bp *v1alpha1.BindingPolicy, stored *StoredBindingPolicy) {
+	if bp.Annotations == nil {
+		bp.Annotations = map[string]string{}
+	}
+
+	setIfEmpty := func(key, value string) {
+		if value != "" && bp.Annotations[key] == "" {
+			bp.Annotations[key] = value
+		}
+	}
+	setIfEmpty(AnnotationOriginalYAML, stored.RawYAML)
+	setIfEmpty(AnnotationTemplate, stored.Template)
+	setIfEmpty(AnnotationDescription, stored.Description)
+
+	if stored.Author != "" {
+		bp.Annotations[AnnotationAuthor] = stored.Author
+	} else {
+		delete(bp.Annotations, AnnotationAuthor)
+	}
+	bp.Annotations[AnnotationCreatedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	selectors := storedSelectors{
+		ClusterSelectors:  stored.ClusterSelectors,
+		APIGroups:         stored.APIGroups,
+		Resources:         stored.Resources,
+		Namespaces:        stored.Namespaces,
+		SpecificWorkloads: stored.SpecificWorkloads,
+	}
+	if encoded, err := json.Marshal(selectors); err == nil && string(encoded) != "{}" {
+		setIfEmpty(AnnotationSelectors, string(encoded))
+	}
+	if len(stored.TemplateParameters) > 0 {
+		if encoded, err := json.Marshal(stored.TemplateParameters); err == nil {
+			setIfEmpty(AnnotationTemplateParameters, string(encoded))
+		}
+	}
+}
+
+// StoredPolicyFor returns the authoring metadata recorded on a policy, and
+// false for policies that were not created through the UI
+func StoredPolicyFor(bp *v1alpha1.BindingPolicy) (*StoredBindingPolicy, bool) {
+	annotations := bp.Annotations
+	if annotations[AnnotationOriginalYAML] == "" && annotations[AnnotationSelectors] == "" && annotations[AnnotationTemplate] == "" {
+		return nil, false
+	}
+
+	stored := &StoredBindingPolicy{
+		Name:        bp.Name,
+		Namespace:   bp.Namespace,
+		RawYAML:     annotations[AnnotationOriginalYAML],
+		Author:      annotations[AnnotationAuthor],
+		Template:    annotations[AnnotationTemplate],
+		Description: annotations[AnnotationDescription],
+		CreatedAt:   annotations[AnnotationCreatedAt],
+	}
+
+	if raw := annotations[AnnotationSelectors]; raw != "" {
+		var selectors storedSelectors
+		if err := json.Unmarshal([]byte(raw), &selectors); err != nil {
+			log.LogWarn("ignoring invalid selectors annotation", zap.String("policyName", bp.Name), zap.Error(err))
+		} else {
+			stored.ClusterSelectors = selectors.ClusterSelectors
+			stored.APIGroups = selectors.APIGroups
+			stored.Resources = selectors.Resources
+			stored.Namespaces = selectors.Namespaces
+			stored.SpecificWorkloads = selectors.SpecificWorkloads
+		}
+	}
+
+	if raw := annotations[AnnotationTemplateParameters]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &stored.TemplateParameters); err != nil {
+			log.LogWarn("ignoring invalid template parameters annotation", zap.String("policyName", bp.Name), zap.Error(err))
+		}
+	}
+
+	return stored, true
+}
+
+// isMetadataAnnotation reports whether an annotation holds authoring metadata
+func isMetadataAnnotation(key string) bool {
+	return strings.HasPrefix(key, metadataAnnotationPrefix)
+}
+
+// requestAuthor returns the UI user making the request, or "" if the request
+// carries no valid token. The binding policy routes are not behind the
+// authentication middleware, so the token is checked here.
+func requestAuthor(ctx *gin.Context) string {
+	if username := ctx.GetString("username"); username != "" {
+		return username
+	}
+
+	authHeader := ctx.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
+	}
+	claims, err := utils.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		return ""
+	}
+	return claims.Username
+}
@@ -0,0 +1,544 @@
+package bp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"github.com/kubestellar/ui/backend/utils"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxPreviewObjects caps how many workload objects a preview returns
+const maxPreviewObjects = 1000
+
+var (
+	managedClusterGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	bindingGVR        = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindings"}
+	namespaceGVR      = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+// previewSkippedGroups hold objects that KubeStellar never downsyncs
+var previewSkippedGroups = map[string]bool{
+	"control.kubestellar.io":             true,
+	"events.k8s.io":                      true,
+	"cluster.open-cluster-management.io": true,
+	"work.open-cluster-management.io":    true,
+}
+
+// previewSkippedResources are core resources that are never downsynced
+var previewSkippedResources = map[string]bool{
+	"events":            true,
+	"nodes":             true,
+	"componentstatuses": true,
+	"bindings":          true,
+}
+
+// PreviewClients are the clients a preview reads from
+type PreviewClients struct {
+	// ITS lists ManagedClusters
+	ITS dynamic.Interface
+	// WDS lists workload objects and the current Binding of the policy
+	WDS          dynamic.Interface
+	WDSDiscovery discovery.DiscoveryInterface
+}
+
+// PreviewObject identifies a workload object selected by a policy
+type PreviewObject struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (o PreviewObject) key() string {
+	return o.Group + "/" + o.Resource + "/" + o.Namespace + "/" + o.Name
+}
+
+// ClusterPreview is the set of clusters a policy selects, compared with the
+// clusters its Binding currently lists
+type ClusterPreview struct {
+	Matched   []string `json:"matched"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// ObjectPreview is the set of workload objects a policy selects, compared with
+// the objects its Binding currently lists
+type ObjectPreview struct {
+	Matched   []PreviewObject `json:"matched"`
+	Added     []PreviewObject `json:"added"`
+	Removed   []PreviewObject `json:"removed"`
+	Truncated bool            `json:"truncated"`
+}
+
+// PreviewResult is what applying a BindingPolicy would select
+type PreviewResult struct {
+	Policy string `json:"policy"`
+	// CurrentSelectionFound is false when the policy has no Binding yet, in
+	// which case everything matched is reported as added
+	CurrentSelectionFound bool           `json:"currentSelectionFound"`
+	Clusters              ClusterPreview `json:"clusters"`
+	Objects               ObjectPreview  `json:"objects"`
+	Warnings              []string       `json:"warnings,omitempty"`
+}
+
+// InvalidSelectorError lists the selectors of a policy that cannot be parsed
+type InvalidSelectorError struct {
+	Problems []string
+}
+
+func (e *InvalidSelectorError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// selectorProblems describes every cluster, namespace and object selector of
+// a policy that cannot be parsed
+func selectorProblems(bp *v1alpha1.BindingPolicy) []string {
+	var problems []string
+	if _, err := parseSelectors(bp.Spec.ClusterSelectors); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid cluster selector: %v", err))
+	}
+	for i, clause := range bp.Spec.Downsync {
+		if _, err := parseSelectors(clause.NamespaceSelectors); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid namespace selector in downsync rule %d: %v", i, err))
+		}
+		if _, err := parseSelectors(clause.ObjectSelectors); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid object selector in downsync rule %d: %v", i, err))
+		}
+	}
+	return problems
+}
+
+// PreviewBindingPolicy evaluates a policy without applying it. It lists the
+// ManagedClusters matching the cluster selectors and the WDS objects matching
+// the downsync rules, and diffs both against the policy's current Binding.
+// An *InvalidSelectorError is returned if a selector cannot be parsed.
+func PreviewBindingPolicy(ctx context.Context, bp *v1alpha1.BindingPolicy, clients PreviewClients) (*PreviewResult, error) {
+	if problems := selectorProblems(bp); len(problems) > 0 {
+		return nil, &InvalidSelectorError{Problems: problems}
+	}
+	result := &PreviewResult{Policy: bp.Name}
+
+	clusters, err := clients.ITS.Resource(managedClusterGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed clusters: %v", err)
+	}
+	matchedClusters, err := matchClusters(bp.Spec.ClusterSelectors, clusters.Items)
+	if err != nil {
+		return nil, err
+	}
+	if len(bp.Spec.ClusterSelectors) == 0 {
+		result.Warnings = append(result.Warnings, "policy has no cluster selectors and selects no clusters")
+	}
+
+	matchedObjects, truncated, warnings, err := matchObjects(ctx, bp.Spec.Downsync, clients)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+
+	currentClusters, currentObjects, found, err := currentSelection(ctx, clients.WDS, bp.Name)
+	if err != nil {
+		return nil, err
+	}
+	result.CurrentSelectionFound = found
+
+	result.Clusters = diffClusters(matchedClusters, currentClusters)
+	result.Objects = diffObjects(matchedObjects, currentObjects)
+	result.Objects.Truncated = truncated
+
+	return result, nil
+}
+
+// matchClusters returns the names of the clusters matching any of the selectors
+func matchClusters(selectors []v1.LabelSelector, clusters []unstructured.Unstructured) ([]string, error) {
+	parsed, err := parseSelectors(selectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster selector: %v", err)
+	}
+
+	matched := []string{}
+	for _, cluster := range clusters {
+		if matchesAny(parsed, cluster.GetLabels()) {
+			matched = append(matched, cluster.GetName())
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+func parseSelectors(selectors []v1.LabelSelector) ([]labels.Selector, error) {
+	parsed := make([]labels.Selector, 0, len(selectors))
+	for i := range selectors {
+		selector, err := v1.LabelSelectorAsSelector(&selectors[i])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, selector)
+	}
+	return parsed, nil
+}
+
+func matchesAny(selectors []labels.Selector, objLabels map[string]string) bool {
+	for _, selector := range selectors {
+		if selector.Matches(labels.Set(objLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledClause is a downsync rule with its label selectors parsed
+type compiledClause struct {
+	v1alpha1.DownsyncPolicyClause
+	namespaceSelectors []labels.Selector
+	objectSelectors    []labels.Selector
+}
+
+// matchObjects lists the WDS objects selected by any of the downsync rules
+func matchObjects(ctx context.Context, clauses []v1alpha1.DownsyncPolicyClause, clients PreviewClients) ([]PreviewObject, bool, []string, error) {
+	var warnings []string
+	if len(clauses) == 0 {
+		return []PreviewObject{}, false, []string{"policy has no downsync rules and selects no objects"}, nil
+	}
+
+	compiled := make([]compiledClause, 0, len(clauses))
+	for i, clause := range clauses {
+		namespaceSelectors, err := parseSelectors(clause.NamespaceSelectors)
+		if err != nil {
+			return nil, false, nil, fmt.Errorf("invalid namespace selector in downsync rule %d: %v", i, err)
+		}
+		objectSelectors, err := parseSelectors(clause.ObjectSelectors)
+		if err != nil {
+			return nil, false, nil, fmt.Errorf("invalid object selector in downsync rule %d: %v", i, err)
+		}
+		compiled = append(compiled, compiledClause{clause, namespaceSelectors, objectSelectors})
+	}
+
+	resourceLists, err := discovery.ServerPreferredResources(clients.WDSDiscovery)
+	if err != nil && len(resourceLists) == 0 {
+		return nil, false, nil, fmt.Errorf("failed to discover WDS resources: %v", err)
+	}
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("some API groups could not be discovered: %v", err))
+	}
+
+	namespaceLabels, err := listNamespaceLabels(ctx, clients.WDS)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	matched := []PreviewObject{}
+	truncated := false
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || previewSkippedGroups[gv.Group] {
+			continue
+		}
+
+		for _, resource := range list.APIResources {
+			// Skip subresources and resources that cannot be listed
+			if strings.Contains(resource.Name, "/") || !containsVerb(resource.Verbs, "list") {
+				continue
+			}
+			if gv.Group == "" && previewSkippedResources[resource.Name] {
+				continue
+			}
+			if !resourceSelected(compiled, gv.Group, resource.Name) {
+				continue
+			}
+
+			gvr := gv.WithResource(resource.Name)
+			objects, err := clients.WDS.Resource(gvr).List(ctx, v1.ListOptions{})
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("failed to list %s: %v", gvr.String(), err))
+				continue
+			}
+
+			for _, obj := range objects.Items {
+				if !objectSelected(compiled, gv.Group, resource.Name, resource.Namespaced, &obj, namespaceLabels) {
+					continue
+				}
+				if len(matched) >= maxPreviewObjects {
+					truncated = true
+					break
+				}
+				matched = append(matched, PreviewObject{
+					Group:     gv.Group,
+					Version:   gv.Version,
+					Resource:  resource.Name,
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetName(),
+				})
+			}
+		}
+	}
+
+	sortObjects(matched)
+	return matched, truncated, warnings, nil
+}
+
+func listNamespaceLabels(ctx context.Context, client dynamic.Interface) (map[string]map[string]string, error) {
+	namespaces, err := client.Resource(namespaceGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+
+	result := make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		result[ns.GetName()] = ns.GetLabels()
+	}
+	return result, nil
+}
+
+func containsVerb(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesList reports whether value is listed, treating an empty list or "*"
+// as matching everything
+func matchesList(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == "*" || item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMatches compares a rule's apiGroup with a discovered group. A nil
+// apiGroup matches every group, and "core" is accepted for the core group as
+// the JSON policy API writes it that way.
+func groupMatches(apiGroup *string, group string) bool {
+	if apiGroup == nil {
+		return true
+	}
+	if *apiGroup == "core" {
+		return group == ""
+	}
+	return *apiGroup == group
+}
+
+// resourceSelected reports whether any rule could select objects of the resource
+func resourceSelected(clauses []compiledClause, group, resource string) bool {
+	for _, clause := range clauses {
+		if groupMatches(clause.APIGroup, group) && matchesList(clause.Resources, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectSelected reports whether any rule selects the object. Namespace
+// tests only apply to namespaced objects.
+func objectSelected(clauses []compiledClause, group, resource string, namespaced bool, obj *unstructured.Unstructured, namespaceLabels map[string]map[string]string) bool {
+	for _, clause := range clauses {
+		if !groupMatches(clause.APIGroup, group) || !matchesList(clause.Resources, resource) {
+			continue
+		}
+		if !matchesList(clause.ObjectNames, obj.GetName()) {
+			continue
+		}
+		if len(clause.objectSelectors) > 0 && !matchesAny(clause.objectSelectors, obj.GetLabels()) {
+			continue
+		}
+		if namespaced {
+			if !matchesList(clause.Namespaces, obj.GetNamespace()) {
+				continue
+			}
+			if len(clause.namespaceSelectors) > 0 && !matchesAny(clause.namespaceSelectors, namespaceLabels[obj.GetNamespace()]) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// currentSelection reads the clusters and objects from the policy's Binding
+func currentSelection(ctx context.Context, client dynamic.Interface, name string) ([]string, []PreviewObject, bool, error) {
+	binding, err := client.Resource(bindingGVR).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, false, nil
+		}
+		return nil, nil, false, fmt.Errorf("failed to get binding: %v", err)
+	}
+
+	clusters := []string{}
+	destinations, _, _ := unstructured.NestedSlice(binding.Object, "spec", "destinations")
+	for _, item := range destinations {
+		if dest, ok := item.(map[string]interface{}); ok {
+			if id, ok := dest["clusterId"].(string); ok && id != "" {
+				clusters = append(clusters, id)
+			}
+		}
+	}
+
+	objects := []PreviewObject{}
+	for _, scope := range []string{"clusterScope", "namespaceScope"} {
+		items, _, _ := unstructured.NestedSlice(binding.Object, "spec", "workload", scope)
+		for _, item := range items {
+			ref, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			obj := PreviewObject{}
+			obj.Group, _ = ref["group"].(string)
+			obj.Version, _ = ref["version"].(string)
+			obj.Resource, _ = ref["resource"].(string)
+			obj.Namespace, _ = ref["namespace"].(string)
+			obj.Name, _ = ref["name"].(string)
+			if obj.Resource != "" && obj.Name != "" {
+				objects = append(objects, obj)
+			}
+		}
+	}
+
+	return clusters, objects, true, nil
+}
+
+func diffClusters(matched, current []string) ClusterPreview {
+	preview := ClusterPreview{Matched: matched, Added: []string{}, Removed: []string{}, Unchanged: []string{}}
+
+	currentSet := make(map[string]bool, len(current))
+	for _, name := range current {
+		currentSet[name] = true
+	}
+	matchedSet := make(map[string]bool, len(matched))
+	for _, name := range matched {
+		matchedSet[name] = true
+		if currentSet[name] {
+			preview.Unchanged = append(preview.Unchanged, name)
+		} else {
+			preview.Added = append(preview.Added, name)
+		}
+	}
+	for _, name := range current {
+		if !matchedSet[name] {
+			preview.Removed = append(preview.Removed, name)
+		}
+	}
+	sort.Strings(preview.Removed)
+	return preview
+}
+
+func diffObjects(matched, current []PreviewObject) ObjectPreview {
+	preview := ObjectPreview{Matched: matched, Added: []PreviewObject{}, Removed: []PreviewObject{}}
+
+	currentSet := make(map[string]bool, len(current))
+	for _, obj := range current {
+		currentSet[obj.key()] = true
+	}
+	matchedSet := make(map[string]bool, len(matched))
+	for _, obj := range matched {
+		matchedSet[obj.key()] = true
+		if !currentSet[obj.key()] {
+			preview.Added = append(preview.Added, obj)
+		}
+	}
+	for _, obj := range current {
+		if !matchedSet[obj.key()] {
+			preview.Removed = append(preview.Removed, obj)
+		}
+	}
+	sortObjects(preview.Removed)
+	return preview
+}
+
+func sortObjects(objects []PreviewObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].key() < objects[j].key()
+	})
+}
+
+// PreviewBp evaluates a BindingPolicy sent as YAML, JSON or a bpYaml form
+// file without creating it
+func PreviewBp(ctx *gin.Context) {
+	var raw []byte
+	var err error
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		raw, err = utils.GetFormFileBytes("bpYaml", ctx)
+	} else {
+		raw, err = io.ReadAll(ctx.Request.Body)
+	}
+	if err != nil || len(raw) == 0 {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "a BindingPolicy is required in the request body"})
+		return
+	}
+
+	// The deserializer accepts both YAML and JSON
+	bp, err := getBpObjFromYaml(raw)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+
+	_, itsClient, err := k8s.GetClientSetWithContext(itsContext)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to connect to ITS %s: %v", itsContext, err)})
+		return
+	}
+	wdsClientset, wdsClient, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to connect to WDS %s: %v", wdsContext, err)})
+		return
+	}
+
+	result, err := PreviewBindingPolicy(ctx.Request.Context(), bp, PreviewClients{
+		ITS:          itsClient,
+		WDS:          wdsClient,
+		WDSDiscovery: wdsClientset.Discovery(),
+	})
+	var selectorErr *InvalidSelectorError
+	if errors.As(err, &selectorErr) {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "problems": selectorErr.Problems})
+		return
+	}
+	if err != nil {
+		log.LogError("failed to preview binding policy", zap.String("policy", bp.Name), zap.Error(err))
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/preview", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/api/bp/preview", "200").Inc()
+	ctx.JSON(http.StatusOK, result)
+}
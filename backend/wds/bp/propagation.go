@@ -0,0 +1,555 @@
+package bp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// bindingOwnerLabel is set by the KubeStellar transport controller on the
+// ManifestWorks it creates for a Binding
+const bindingOwnerLabel = "transport.kubestellar.io/originOwnerReferenceBindingKey"
+
+var (
+	bindingPolicyGVR = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "bindingpolicies"}
+	manifestWorkGVR  = schema.GroupVersionResource{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"}
+	workStatusGVR    = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "workstatuses"}
+)
+
+// ErrPolicyNotFound is returned when the BindingPolicy does not exist in the WDS
+var ErrPolicyNotFound = errors.New("binding policy not found")
+
+// Object propagation phases, in the order an object moves through them
+const (
+	PhasePending   = "Pending"
+	PhaseDelivered = "Delivered"
+	PhaseApplied   = "Applied"
+	PhaseHealthy   = "Healthy"
+	PhaseDegraded  = "Degraded"
+)
+
+// Policy propagation phases
+const (
+	PolicyPhaseNoBinding   = "NoBinding"
+	PolicyPhaseNoTargets   = "NoTargets"
+	PolicyPhaseProgressing = "Progressing"
+	PolicyPhaseApplied     = "Applied"
+	PolicyPhaseHealthy     = "Healthy"
+	PolicyPhaseDegraded    = "Degraded"
+)
+
+// Object health values
+const (
+	HealthHealthy  = "Healthy"
+	HealthDegraded = "Degraded"
+	HealthUnknown  = "Unknown"
+)
+
+// PropagationClients are the clients propagation status is read from
+type PropagationClients struct {
+	// ITS holds the ManifestWorks and WorkStatuses in the cluster namespaces
+	ITS dynamic.Interface
+	// WDS holds the BindingPolicy and its Binding
+	WDS dynamic.Interface
+}
+
+// ObjectPropagation is the state of one selected object on one cluster
+type ObjectPropagation struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Delivered is true once the object is in a ManifestWork for the cluster
+	Delivered bool `json:"delivered"`
+	// Applied is true once the cluster reports the object as applied
+	Applied bool   `json:"applied"`
+	Health  string `json:"health"`
+	Phase   string `json:"phase"`
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterPropagation is the state of all selected objects on one cluster
+type ClusterPropagation struct {
+	Cluster   string              `json:"cluster"`
+	Phase     string              `json:"phase"`
+	Delivered int                 `json:"delivered"`
+	Applied   int                 `json:"applied"`
+	Healthy   int                 `json:"healthy"`
+	Degraded  int                 `json:"degraded"`
+	Objects   []ObjectPropagation `json:"objects"`
+}
+
+// PropagationSummary counts objects over all target clusters
+type PropagationSummary struct {
+	Clusters  int `json:"clusters"`
+	Objects   int `json:"objects"`
+	Delivered int `json:"delivered"`
+	Applied   int `json:"applied"`
+	Healthy   int `json:"healthy"`
+	Degraded  int `json:"degraded"`
+}
+
+// PropagationStatus is the per-cluster propagation state of a BindingPolicy
+type PropagationStatus struct {
+	Policy string `json:"policy"`
+	Phase  string `json:"phase"`
+	// Reconciled is true when the KubeStellar controller has observed the
+	// latest generation of the policy
+	Reconciled bool                 `json:"reconciled"`
+	Conditions []interface{}        `json:"conditions"`
+	Errors     []string             `json:"errors,omitempty"`
+	Summary    PropagationSummary   `json:"summary"`
+	Clusters   []ClusterPropagation `json:"clusters"`
+	CheckedAt  time.Time            `json:"checkedAt"`
+	Warnings   []string             `json:"warnings,omitempty"`
+}
+
+// GetPropagationStatus builds the propagation status of a policy from its
+// Binding in the WDS and, for each destination cluster, the ManifestWorks and
+// WorkStatuses in the cluster's namespace in the ITS
+func GetPropagationStatus(ctx context.Context, name string, clients PropagationClients) (*PropagationStatus, error) {
+	policy, err := clients.WDS.Resource(bindingPolicyGVR).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get binding policy: %v", err)
+	}
+
+	status := &PropagationStatus{
+		Policy:     name,
+		Conditions: []interface{}{},
+		Clusters:   []ClusterPropagation{},
+		CheckedAt:  time.Now().UTC(),
+	}
+	observed, _, _ := unstructured.NestedInt64(policy.Object, "status", "observedGeneration")
+	status.Reconciled = observed == policy.GetGeneration()
+	if conditions, found, _ := unstructured.NestedSlice(policy.Object, "status", "conditions"); found {
+		status.Conditions = conditions
+	}
+	status.Errors, _, _ = unstructured.NestedStringSlice(policy.Object, "status", "errors")
+
+	clusters, objects, found, err := currentSelection(ctx, clients.WDS, name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		status.Phase = PolicyPhaseNoBinding
+		return status, nil
+	}
+	sort.Strings(clusters)
+	sortObjects(objects)
+
+	for _, cluster := range clusters {
+		clusterStatus, warnings := clusterPropagation(ctx, clients.ITS, name, cluster, objects)
+		status.Warnings = append(status.Warnings, warnings...)
+		status.Clusters = append(status.Clusters, clusterStatus)
+
+		status.Summary.Objects += len(clusterStatus.Objects)
+		status.Summary.Delivered += clusterStatus.Delivered
+		status.Summary.Applied += clusterStatus.Applied
+		status.Summary.Healthy += clusterStatus.Healthy
+		status.Summary.Degraded += clusterStatus.Degraded
+	}
+	status.Summary.Clusters = len(clusters)
+	status.Phase = policyPhase(status.Summary)
+
+	return status, nil
+}
+
+// clusterPropagation matches the Binding's objects against what the
+// ManifestWorks and WorkStatuses in the cluster namespace report
+func clusterPropagation(ctx context.Context, its dynamic.Interface, bindingName, cluster string, objects []PreviewObject) (ClusterPropagation, []string) {
+	var warnings []string
+	result := ClusterPropagation{Cluster: cluster, Objects: []ObjectPropagation{}}
+
+	manifests := map[string][]interface{}{}
+	works, err := its.Resource(manifestWorkGVR).Namespace(cluster).List(ctx, v1.ListOptions{
+		LabelSelector: bindingOwnerLabel + "=" + bindingName,
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to list manifestworks for cluster %s: %v", cluster, err))
+	} else {
+		for _, work := range works.Items {
+			entries, _, _ := unstructured.NestedSlice(work.Object, "status", "resourceStatus", "manifests")
+			for _, entry := range entries {
+				manifest, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				resourceMeta, _ := manifest["resourceMeta"].(map[string]interface{})
+				conditions, _ := manifest["conditions"].([]interface{})
+				manifests[refKey(resourceMeta)] = conditions
+			}
+			// A ManifestWork that has not reported status yet still counts as delivered
+			for _, obj := range deliveredObjects(&work) {
+				if _, ok := manifests[obj]; !ok {
+					manifests[obj] = nil
+				}
+			}
+		}
+	}
+
+	reported := map[string]map[string]interface{}{}
+	workStatuses, err := its.Resource(workStatusGVR).Namespace(cluster).List(ctx, v1.ListOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("failed to list workstatuses for cluster %s: %v", cluster, err))
+		}
+	} else {
+		for _, ws := range workStatuses.Items {
+			ref, _, _ := unstructured.NestedMap(ws.Object, "spec", "sourceRef")
+			objStatus, _, _ := unstructured.NestedMap(ws.Object, "status")
+			reported[refKey(ref)] = objStatus
+		}
+	}
+
+	for _, obj := range objects {
+		state := ObjectPropagation{
+			Group:     obj.Group,
+			Version:   obj.Version,
+			Resource:  obj.Resource,
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Health:    HealthUnknown,
+		}
+
+		conditions, delivered := manifests[obj.key()]
+		state.Delivered = delivered
+		if delivered {
+			state.Applied = conditionIs(conditions, "Applied", "True")
+			if conditionIs(conditions, "Available", "False") {
+				state.Health = HealthDegraded
+				state.Message = conditionMessage(conditions, "Available")
+			} else if conditionIs(conditions, "Available", "True") {
+				state.Health = HealthHealthy
+			}
+		}
+
+		// The status reported back from the cluster is more precise than the
+		// ManifestWork conditions
+		if objStatus, ok := reported[obj.key()]; ok {
+			state.Applied = state.Applied || delivered
+			state.Health, state.Message = objectHealth(objStatus)
+		}
+
+		state.Phase = objectPhase(state)
+		if state.Delivered {
+			result.Delivered++
+		}
+		if state.Applied {
+			result.Applied++
+		}
+		switch state.Phase {
+		case PhaseHealthy:
+			result.Healthy++
+		case PhaseDegraded:
+			result.Degraded++
+		}
+		result.Objects = append(result.Objects, state)
+	}
+
+	result.Phase = policyPhase(PropagationSummary{
+		Clusters:  1,
+		Objects:   len(result.Objects),
+		Delivered: result.Delivered,
+		Applied:   result.Applied,
+		Healthy:   result.Healthy,
+		Degraded:  result.Degraded,
+	})
+	return result, warnings
+}
+
+// deliveredObjects returns the keys of the objects embedded in a ManifestWork
+func deliveredObjects(work *unstructured.Unstructured) []string {
+	var keys []string
+	entries, _, _ := unstructured.NestedSlice(work.Object, "spec", "workload", "manifests")
+	for _, entry := range entries {
+		manifest, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		obj := unstructured.Unstructured{Object: manifest}
+		// The manifest only carries its kind, so the resource name is guessed
+		// the same way kubectl does for unknown kinds
+		gvr, _ := meta.UnsafeGuessKindToResource(obj.GroupVersionKind())
+		keys = append(keys, PreviewObject{
+			Group:     gvr.Group,
+			Resource:  gvr.Resource,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		}.key())
+	}
+	return keys
+}
+
+// refKey builds an object key from a ManifestWork resourceMeta or a
+// WorkStatus sourceRef
+func refKey(ref map[string]interface{}) string {
+	obj := PreviewObject{}
+	obj.Group, _ = ref["group"].(string)
+	obj.Resource, _ = ref["resource"].(string)
+	obj.Namespace, _ = ref["namespace"].(string)
+	obj.Name, _ = ref["name"].(string)
+	return obj.key()
+}
+
+func findCondition(conditions []interface{}, conditionType string) map[string]interface{} {
+	for _, item := range conditions {
+		if condition, ok := item.(map[string]interface{}); ok && condition["type"] == conditionType {
+			return condition
+		}
+	}
+	return nil
+}
+
+func conditionIs(conditions []interface{}, conditionType, status string) bool {
+	condition := findCondition(conditions, conditionType)
+	return condition != nil && condition["status"] == status
+}
+
+func conditionMessage(conditions []interface{}, conditionType string) string {
+	if condition := findCondition(conditions, conditionType); condition != nil {
+		if message, ok := condition["message"].(string); ok && message != "" {
+			return message
+		}
+		if reason, ok := condition["reason"].(string); ok {
+			return reason
+		}
+	}
+	return ""
+}
+
+// objectHealth judges an object from the status reported by its cluster,
+// using the conventions shared by the built-in workload types
+func objectHealth(objStatus map[string]interface{}) (string, string) {
+	if len(objStatus) == 0 {
+		return HealthUnknown, ""
+	}
+
+	conditions, _ := objStatus["conditions"].([]interface{})
+	for _, conditionType := range []string{"Available", "Ready"} {
+		if conditionIs(conditions, conditionType, "False") {
+			return HealthDegraded, conditionMessage(conditions, conditionType)
+		}
+	}
+	for _, conditionType := range []string{"Failed", "Degraded", "ReplicaFailure"} {
+		if conditionIs(conditions, conditionType, "True") {
+			return HealthDegraded, conditionMessage(conditions, conditionType)
+		}
+	}
+	if condition := findCondition(conditions, "Progressing"); condition != nil && condition["reason"] == "ProgressDeadlineExceeded" {
+		return HealthDegraded, conditionMessage(conditions, "Progressing")
+	}
+
+	if replicas, ok := statusInt(objStatus, "replicas"); ok && replicas > 0 {
+		ready, ok := statusInt(objStatus, "readyReplicas")
+		if !ok {
+			ready, _ = statusInt(objStatus, "availableReplicas")
+		}
+		if ready < replicas {
+			return HealthDegraded, fmt.Sprintf("%d/%d replicas ready", ready, replicas)
+		}
+	}
+
+	if failed, ok := statusInt(objStatus, "failed"); ok && failed > 0 {
+		if succeeded, _ := statusInt(objStatus, "succeeded"); succeeded == 0 {
+			return HealthDegraded, fmt.Sprintf("%d failed pods", failed)
+		}
+	}
+
+	return HealthHealthy, ""
+}
+
+func statusInt(objStatus map[string]interface{}, field string) (int64, bool) {
+	switch value := objStatus[field].(type) {
+	case int64:
+		return value, true
+	case int:
+		return int64(value), true
+	case float64:
+		return int64(value), true
+	}
+	return 0, false
+}
+
+func objectPhase(state ObjectPropagation) string {
+	switch {
+	case !state.Delivered:
+		return PhasePending
+	case !state.Applied:
+		return PhaseDelivered
+	case state.Health == HealthDegraded:
+		return PhaseDegraded
+	case state.Health == HealthHealthy:
+		return PhaseHealthy
+	default:
+		return PhaseApplied
+	}
+}
+
+func policyPhase(summary PropagationSummary) string {
+	switch {
+	case summary.Clusters == 0 || summary.Objects == 0:
+		return PolicyPhaseNoTargets
+	case summary.Degraded > 0:
+		return PolicyPhaseDegraded
+	case summary.Healthy == summary.Objects:
+		return PolicyPhaseHealthy
+	case summary.Applied == summary.Objects:
+		return PolicyPhaseApplied
+	default:
+		return PolicyPhaseProgressing
+	}
+}
+
+// propagationClientsFromRequest connects to the ITS and WDS selected by the request
+func propagationClientsFromRequest(ctx *gin.Context) (PropagationClients, error) {
+	itsContext, wdsContext, err := spacesFromRequest(ctx)
+	if err != nil {
+		return PropagationClients{}, err
+	}
+
+	_, itsClient, err := k8s.GetClientSetWithContext(itsContext)
+	if err != nil {
+		return PropagationClients{}, fmt.Errorf("failed to connect to ITS %s: %v", itsContext, err)
+	}
+	_, wdsClient, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		return PropagationClients{}, fmt.Errorf("failed to connect to WDS %s: %v", wdsContext, err)
+	}
+	return PropagationClients{ITS: itsClient, WDS: wdsClient}, nil
+}
+
+// propagationForStatus adds the propagation status to GetBpStatus responses.
+// Failures only drop the field, as the rest of the status is still useful.
+func propagationForStatus(ctx *gin.Context, name string) *PropagationStatus {
+	clients, err := propagationClientsFromRequest(ctx)
+	if err != nil {
+		log.LogWarn("failed to connect for propagation status", zap.String("policy", name), zap.Error(err))
+		return nil
+	}
+	status, err := GetPropagationStatus(ctx.Request.Context(), name, clients)
+	if err != nil {
+		log.LogWarn("failed to get propagation status", zap.String("policy", name), zap.Error(err))
+		return nil
+	}
+	return status
+}
+
+// GetBpPropagation returns the per-cluster propagation status of a BindingPolicy
+func GetBpPropagation(ctx *gin.Context) {
+	name := ctx.Param("name")
+
+	clients, err := propagationClientsFromRequest(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/propagation/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := GetPropagationStatus(ctx.Request.Context(), name, clients)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNotFound) {
+			telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/propagation/:name", "404").Inc()
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Binding policy '%s' not found", name)})
+			return
+		}
+		log.LogError("failed to get propagation status", zap.String("policy", name), zap.Error(err))
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/propagation/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/propagation/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, status)
+}
+
+// WatchBpPropagation streams the propagation status of a BindingPolicy as
+// server-sent events. A "status" event is sent whenever the status changes,
+// and a "deleted" event ends the stream when the policy goes away.
+func WatchBpPropagation(ctx *gin.Context) {
+	name := ctx.Param("name")
+
+	interval := 5 * time.Second
+	if raw := ctx.Query("interval"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "interval must be a positive number of seconds"})
+			return
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	clients, err := propagationClientsFromRequest(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/propagation/:name/watch", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.WriteHeader(http.StatusOK)
+	ctx.Writer.Flush()
+
+	sendEvent := func(event string, data any) {
+		jsonData, _ := json.Marshal(data)
+		fmt.Fprintf(ctx.Writer, "event: %s\n", event)
+		fmt.Fprintf(ctx.Writer, "data: %s\n\n", jsonData)
+		ctx.Writer.Flush()
+	}
+
+	reqCtx := ctx.Request.Context()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastHash [32]byte
+	for {
+		status, err := GetPropagationStatus(reqCtx, name, clients)
+		switch {
+		case errors.Is(err, ErrPolicyNotFound):
+			sendEvent("deleted", gin.H{"policy": name})
+			return
+		case err != nil:
+			sendEvent("error", gin.H{"error": err.Error()})
+		default:
+			// Only the state is compared, not the time it was checked
+			checkedAt := status.CheckedAt
+			status.CheckedAt = time.Time{}
+			encoded, _ := json.Marshal(status)
+			status.CheckedAt = checkedAt
+
+			if hash := sha256.Sum256(encoded); hash != lastHash {
+				lastHash = hash
+				sendEvent("status", status)
+			}
+		}
+
+		select {
+		case <-reqCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package bp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationLastModifiedBy is the UI user who last changed the policy
+	AnnotationLastModifiedBy = metadataAnnotationPrefix + "last-modified-by"
+	// AnnotationRollbackOf is set to the revision a policy was rolled back to
+	AnnotationRollbackOf = metadataAnnotationPrefix + "rollback-of"
+)
+
+// snapshotSkippedAnnotations change without the policy changing
+var snapshotSkippedAnnotations = map[string]bool{
+	"kubectl.kubernetes.io/last-applied-configuration": true,
+	"yaml": true,
+}
+
+// policySnapshot is what a revision records of a BindingPolicy
+type policySnapshot struct {
+	APIVersion string                     `json:"apiVersion"`
+	Kind       string                     `json:"kind"`
+	Metadata   snapshotMeta               `json:"metadata"`
+	Spec       v1alpha1.BindingPolicySpec `json:"spec"`
+}
+
+type snapshotMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PolicySnapshot returns the revision snapshot of a policy and its hash. Only
+// the spec and user-set metadata are kept, so status updates and server-side
+// fields do not count as changes.
+func PolicySnapshot(bp *v1alpha1.BindingPolicy) (json.RawMessage, string, error) {
+	snapshot := policySnapshot{
+		APIVersion: "control.kubestellar.io/v1alpha1",
+		Kind:       "BindingPolicy",
+		Metadata: snapshotMeta{
+			Name:      bp.Name,
+			Namespace: bp.Namespace,
+			Labels:    bp.Labels,
+		},
+		Spec: bp.Spec,
+	}
+	for key, value := range bp.Annotations {
+		if snapshotSkippedAnnotations[key] {
+			continue
+		}
+		if snapshot.Metadata.Annotations == nil {
+			snapshot.Metadata.Annotations = map[string]string{}
+		}
+		snapshot.Metadata.Annotations[key] = value
+	}
+
+	// encoding/json sorts map keys, so equal policies hash the same
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode policy snapshot: %v", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return encoded, hex.EncodeToString(sum[:]), nil
+}
+
+// recordRevision records the current state of a policy as a new revision
+// by author. Changes seen by the policy watcher are recorded with
+// models.BPRevisionExternalAuthor, and only the first revision of a policy
+// falls back to the author in its annotations.
+// It is a no-op without a database and never fails the caller.
+func recordRevision(wdsContext string, bp *v1alpha1.BindingPolicy, deleted bool, author string) {
+	if database.DB == nil {
+		return
+	}
+
+	snapshot, hash, err := PolicySnapshot(bp)
+	if err != nil {
+		log.LogWarn("failed to snapshot binding policy", zap.String("policyName", bp.Name), zap.Error(err))
+		return
+	}
+
+	change := &models.BPRevisionChange{
+		WDSContext:   wdsContext,
+		PolicyName:   bp.Name,
+		Author:       author,
+		Snapshot:     snapshot,
+		SnapshotHash: hash,
+		Deleted:      deleted,
+	}
+	if !deleted {
+		if author == models.BPRevisionExternalAuthor {
+			change.InitialAuthor = bp.Annotations[AnnotationAuthor]
+		}
+		if raw := bp.Annotations[AnnotationRollbackOf]; raw != "" {
+			if revision, err := strconv.Atoi(raw); err == nil {
+				change.RollbackOf = &revision
+			}
+		}
+	}
+
+	revision, err := models.RecordBPRevision(change)
+	if err != nil {
+		log.LogWarn("failed to record binding policy revision", zap.String("policyName", bp.Name), zap.Error(err))
+		return
+	}
+	if revision != nil {
+		log.LogInfo("Recorded binding policy revision",
+			zap.String("policyName", bp.Name),
+			zap.Int("revision", revision.Revision),
+			zap.String("action", revision.Action))
+	}
+}
+
+// withModifiedBy adds the author annotation to a merge patch and clears the
+// rollback marker, so the resulting revision is attributed correctly
+func withModifiedBy(patch []byte, author string) ([]byte, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(patch, &body); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+
+	metadata, _ := body["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		body["metadata"] = metadata
+	}
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+
+	// A null value removes the annotation in a merge patch
+	annotations[AnnotationRollbackOf] = nil
+	if author != "" {
+		annotations[AnnotationLastModifiedBy] = author
+	} else {
+		annotations[AnnotationLastModifiedBy] = nil
+	}
+
+	return json.Marshal(body)
+}
+
+// Possible values of FieldChange.Type
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange is one difference between two policy snapshots
+type FieldChange struct {
+	// Path is a dotted path such as spec.clusterSelectors[0].matchLabels.env
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// DiffSnapshots compares two policy snapshots field by field
+func DiffSnapshots(from, to json.RawMessage) ([]FieldChange, error) {
+	var fromValue, toValue interface{}
+	if err := json.Unmarshal(from, &fromValue); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	if err := json.Unmarshal(to, &toValue); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+
+	changes := []FieldChange{}
+	diffValues("", fromValue, toValue, &changes)
+	return changes, nil
+}
+
+func diffValues(path string, from, to interface{}, changes *[]FieldChange) {
+	switch {
+	case from == nil && to == nil:
+		return
+	case from == nil:
+		*changes = append(*changes, FieldChange{Path: path, Type: ChangeAdded, To: to})
+		return
+	case to == nil:
+		*changes = append(*changes, FieldChange{Path: path, Type: ChangeRemoved, From: from})
+		return
+	}
+
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := map[string]bool{}
+		for key := range fromMap {
+			keys[key] = true
+		}
+		for key := range toMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			diffValues(childPath, fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			var fromItem, toItem interface{}
+			if i < len(fromList) {
+				fromItem = fromList[i]
+			}
+			if i < len(toList) {
+				toItem = toList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), fromItem, toItem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, FieldChange{Path: path, Type: ChangeChanged, From: from, To: to})
+	}
+}
+
+// revisionsAvailable answers with 503 when revisions cannot be stored
+func revisionsAvailable(ctx *gin.Context) bool {
+	if database.DB == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "revision history requires a database"})
+		return false
+	}
+	return true
+}
+
+// revisionParam parses a revision number from a route or query parameter
+func revisionParam(value string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("invalid revision %q", value)
+	}
+	return revision, nil
+}
+
+// ListBpRevisions lists the revisions of a BindingPolicy, newest first
+func ListBpRevisions(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	if !revisionsAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	revisions, err := models.ListBPRevisions(wdsContext, name)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/revisions/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions", "details": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/revisions/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"policy": name, "revisions": revisions, "count": len(revisions)})
+}
+
+// GetBpRevision returns one revision of a BindingPolicy with its snapshot
+func GetBpRevision(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	if !revisionsAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	number, err := revisionParam(ctx.Param("revision"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revision, err := models.GetBPRevision(wdsContext, name, number)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/revisions/:name/:revision", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision", "details": err.Error()})
+		return
+	}
+	if revision == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("revision %d of '%s' not found", number, name)})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/revisions/:name/:revision", "200").Inc()
+	ctx.JSON(http.StatusOK, revision)
+}
+
+// DiffBpRevisions compares two revisions of a BindingPolicy. "to" defaults to
+// the latest revision and "from" to the one before "to".
+func DiffBpRevisions(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	if !revisionsAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	var toNumber int
+	if raw := ctx.Query("to"); raw != "" {
+		number, err := revisionParam(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		toNumber = number
+	} else {
+		revisions, err := models.ListBPRevisions(wdsContext, name)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions", "details": err.Error()})
+			return
+		}
+		if len(revisions) == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no revisions recorded for '%s'", name)})
+			return
+		}
+		toNumber = revisions[0].Revision
+	}
+
+	fromNumber := toNumber - 1
+	if raw := ctx.Query("from"); raw != "" {
+		number, err := revisionParam(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		fromNumber = number
+	}
+	if fromNumber < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "no earlier revision to compare with"})
+		return
+	}
+
+	from, err := models.GetBPRevision(wdsContext, name, fromNumber)
+	if err == nil && from == nil {
+		err = fmt.Errorf("revision %d not found", fromNumber)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := models.GetBPRevision(wdsContext, name, toNumber)
+	if err == nil && to == nil {
+		err = fmt.Errorf("revision %d not found", toNumber)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := DiffSnapshots(from.Snapshot, to.Snapshot)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff revisions", "details": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/revisions/:name/diff", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{
+		"policy":  name,
+		"from":    fromNumber,
+		"to":      toNumber,
+		"changes": changes,
+	})
+}
+
+// RollbackBp restores a BindingPolicy to the spec and metadata of a revision,
+// recreating it if it was deleted. The rollback is recorded as a new revision.
+func RollbackBp(ctx *gin.Context) {
+	_, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	if !revisionsAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	number, err := revisionParam(ctx.Param("revision"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revision, err := models.GetBPRevision(wdsContext, name, number)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/revisions/:name/:revision/rollback", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision", "details": err.Error()})
+		return
+	}
+	if revision == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("revision %d of '%s' not found", number, name)})
+		return
+	}
+	if revision.Action == models.BPRevisionDelete {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot roll back to a deletion; choose the revision before it"})
+		return
+	}
+
+	var snapshot policySnapshot
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid revision snapshot", "details": err.Error()})
+		return
+	}
+
+	author := requestAuthor(ctx)
+	annotations := map[string]string{}
+	for key, value := range snapshot.Metadata.Annotations {
+		annotations[key] = value
+	}
+	annotations[AnnotationRollbackOf] = strconv.Itoa(number)
+	if author != "" {
+		annotations[AnnotationLastModifiedBy] = author
+	} else {
+		delete(annotations, AnnotationLastModifiedBy)
+	}
+
+	c, err := getClientForBp(wdsContext)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/revisions/:name/:revision/rollback", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var result *v1alpha1.BindingPolicy
+	current, err := c.BindingPolicies().Get(context.TODO(), name, v1.GetOptions{})
+	switch {
+	case err == nil:
+		current.Spec = snapshot.Spec
+		current.Labels = snapshot.Metadata.Labels
+		current.Annotations = annotations
+		result, err = c.BindingPolicies().Update(context.TODO(), current, v1.UpdateOptions{})
+	case apierrors.IsNotFound(err):
+		restored := &v1alpha1.BindingPolicy{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        name,
+				Namespace:   snapshot.Metadata.Namespace,
+				Labels:      snapshot.Metadata.Labels,
+				Annotations: annotations,
+			},
+			Spec: snapshot.Spec,
+		}
+		result, err = c.BindingPolicies().Create(context.TODO(), restored, v1.CreateOptions{})
+	}
+	if err != nil {
+		log.LogError("failed to roll back binding policy", zap.String("policyName", name), zap.Int("revision", number), zap.Error(err))
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/revisions/:name/:revision/rollback", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to roll back '%s': %v", name, err)})
+		return
+	}
+
+	recordRevision(wdsContext, result, false, author)
+
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/api/bp/revisions/:name/:revision/rollback", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("rolled back %s to revision %d", name, number)})
+}
@@ -0,0 +1,133 @@
+package bp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// SingletonStatus tells whether the status of a workload object in the WDS
+// is the status reported back by a single WEC. KubeStellar only writes it
+// back when a policy selecting the object sets wantSingletonReportedState
+// and the object is delivered to exactly one cluster.
+type SingletonStatus struct {
+	// Requested is true when a policy asks for the singleton reported state
+	Requested bool `json:"requested"`
+	// Policies are the policies that request it
+	Policies []string `json:"policies"`
+	// Clusters are the WECs those policies deliver the object to
+	Clusters []string `json:"clusters"`
+	// Reported is true once the WDS object carries the status of Cluster
+	Reported bool `json:"reported"`
+	// Cluster is the single WEC the status is, or will be, reported by
+	Cluster string `json:"cluster,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// GetSingletonStatus works out whether the status of a workload object in
+// the WDS is reported back from a single WEC
+func GetSingletonStatus(ctx context.Context, client dynamic.Interface, object PreviewObject, namespaced bool) (*SingletonStatus, error) {
+	policies, err := listPolicies(ctx, PreviewClients{WDS: client})
+	if err != nil {
+		return nil, err
+	}
+
+	gvr := schema.GroupVersionResource{Group: object.Group, Version: object.Version, Resource: object.Resource}
+	obj, err := client.Resource(gvr).Namespace(object.Namespace).Get(ctx, object.Name, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %v", object.Resource, object.Name, err)
+	}
+
+	var namespaceLabels map[string]map[string]string
+	status := &SingletonStatus{Policies: []string{}, Clusters: []string{}}
+	clusters := map[string]bool{}
+	for _, bp := range policies {
+		clauses, err := singletonClauses(bp)
+		if err != nil || len(clauses) == 0 {
+			continue
+		}
+		if namespaced && namespaceLabels == nil {
+			if namespaceLabels, err = listNamespaceLabels(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+		if !objectSelected(clauses, object.Group, object.Resource, namespaced, obj, namespaceLabels) {
+			continue
+		}
+
+		status.Requested = true
+		status.Policies = append(status.Policies, bp.Name)
+		current, _, _, err := currentSelection(ctx, client, bp.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range current {
+			clusters[cluster] = true
+		}
+	}
+
+	for cluster := range clusters {
+		status.Clusters = append(status.Clusters, cluster)
+	}
+	sort.Strings(status.Clusters)
+	sort.Strings(status.Policies)
+
+	switch {
+	case !status.Requested:
+		status.Message = "no binding policy requests the singleton reported state of this object"
+	case len(status.Clusters) == 0:
+		status.Message = "the object is not delivered to any cluster yet"
+	case len(status.Clusters) > 1:
+		status.Message = fmt.Sprintf("the object is delivered to %d clusters; singleton status is only reported for exactly one", len(status.Clusters))
+	case !statusWrittenBack(obj):
+		status.Cluster = status.Clusters[0]
+		status.Message = fmt.Sprintf("waiting for the cluster %s to report the status of this object", status.Cluster)
+	default:
+		status.Reported = true
+		status.Cluster = status.Clusters[0]
+		status.Message = fmt.Sprintf("status is reported by %s", status.Cluster)
+	}
+	return status, nil
+}
+
+// statusWrittenBack reports whether the status of a WDS object has been
+// written back from its WEC. Objects whose status tracks a generation must
+// have it match the object's current generation; others only need a status.
+func statusWrittenBack(obj *unstructured.Unstructured) bool {
+	reported, found, err := unstructured.NestedMap(obj.Object, "status")
+	if err != nil || !found || len(reported) == 0 {
+		return false
+	}
+	observed, found, err := unstructured.NestedInt64(reported, "observedGeneration")
+	if err != nil {
+		return false
+	}
+	return !found || observed == obj.GetGeneration()
+}
+
+// singletonClauses returns the downsync rules of a policy that request the
+// singleton reported state
+func singletonClauses(bp *v1alpha1.BindingPolicy) ([]compiledClause, error) {
+	var clauses []compiledClause
+	for _, clause := range bp.Spec.Downsync {
+		if !clause.WantSingletonReportedState {
+			continue
+		}
+		namespaceSelectors, err := parseSelectors(clause.NamespaceSelectors)
+		if err != nil {
+			return nil, err
+		}
+		objectSelectors, err := parseSelectors(clause.ObjectSelectors)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, compiledClause{clause, namespaceSelectors, objectSelectors})
+	}
+	return clauses, nil
+}
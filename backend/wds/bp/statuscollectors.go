@@ -0,0 +1,691 @@
+package bp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	statusCollectorGVR = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "statuscollectors"}
+	combinedStatusGVR  = schema.GroupVersionResource{Group: "control.kubestellar.io", Version: "v1alpha1", Resource: "combinedstatuses"}
+)
+
+// Labels KubeStellar puts on a CombinedStatus to identify the workload object
+// and BindingPolicy it combines the status of
+const (
+	combinedStatusGroupLabel     = "status.kubestellar.io/api-group"
+	combinedStatusResourceLabel  = "status.kubestellar.io/resource"
+	combinedStatusNamespaceLabel = "status.kubestellar.io/namespace"
+	combinedStatusNameLabel      = "status.kubestellar.io/name"
+	combinedStatusPolicyLabel    = "status.kubestellar.io/bindingpolicy"
+)
+
+// defaultStatusCollectorLimit is the row limit KubeStellar applies when a
+// StatusCollector does not set one
+const defaultStatusCollectorLimit = 20
+
+// Aggregator types of a StatusCollector's combined fields
+var aggregatorTypes = map[string]bool{"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+// NamedExpression is a named CEL expression of a StatusCollector
+type NamedExpression struct {
+	Name string `json:"name"`
+	Def  string `json:"def"`
+}
+
+// NamedAggregator combines a CEL expression over the rows of a group
+type NamedAggregator struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Subject is the expression being aggregated; COUNT has none
+	Subject string `json:"subject,omitempty"`
+}
+
+// StatusCollectorSpec describes how KubeStellar combines the status a
+// workload object reports from each WEC
+type StatusCollectorSpec struct {
+	Filter         string            `json:"filter,omitempty"`
+	GroupBy        []NamedExpression `json:"groupBy,omitempty"`
+	CombinedFields []NamedAggregator `json:"combinedFields,omitempty"`
+	Select         []NamedExpression `json:"select,omitempty"`
+	Limit          *int64            `json:"limit,omitempty"`
+}
+
+// StatusCollectorRequest is the body of the StatusCollector endpoints
+type StatusCollectorRequest struct {
+	Name string `json:"name"`
+	StatusCollectorSpec
+}
+
+// StatusCollectorInfo is a StatusCollector with the policies that use it
+type StatusCollectorInfo struct {
+	Name   string              `json:"name"`
+	Spec   StatusCollectorSpec `json:"spec"`
+	UsedBy []string            `json:"usedBy"`
+}
+
+// NewStatusCollector validates a request and returns the StatusCollector it
+// describes
+func NewStatusCollector(request *StatusCollectorRequest) (*unstructured.Unstructured, error) {
+	var problems []string
+	if errs := validation.IsDNS1123Subdomain(request.Name); len(errs) > 0 {
+		problems = append(problems, fmt.Sprintf("invalid name %q: %s", request.Name, strings.Join(errs, ", ")))
+	}
+
+	spec := request.StatusCollectorSpec
+	if len(spec.Select) > 0 && (len(spec.GroupBy) > 0 || len(spec.CombinedFields) > 0) {
+		problems = append(problems, "select cannot be combined with groupBy or combinedFields")
+	}
+	if len(spec.Select) == 0 && len(spec.CombinedFields) == 0 && len(spec.GroupBy) == 0 {
+		problems = append(problems, "one of select, groupBy or combinedFields is required")
+	}
+	if spec.Limit != nil && *spec.Limit < 0 {
+		problems = append(problems, "limit cannot be negative")
+	}
+
+	columns := map[string]bool{}
+	checkColumn := func(field, name string) {
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("%s: every entry needs a name", field))
+			return
+		}
+		if columns[name] {
+			problems = append(problems, fmt.Sprintf("%s: column %s is defined twice", field, name))
+		}
+		columns[name] = true
+	}
+	for _, expression := range spec.GroupBy {
+		checkColumn("groupBy", expression.Name)
+		if strings.TrimSpace(expression.Def) == "" {
+			problems = append(problems, fmt.Sprintf("groupBy %s: def is required", expression.Name))
+		}
+	}
+	for _, expression := range spec.Select {
+		checkColumn("select", expression.Name)
+		if strings.TrimSpace(expression.Def) == "" {
+			problems = append(problems, fmt.Sprintf("select %s: def is required", expression.Name))
+		}
+	}
+	for _, aggregator := range spec.CombinedFields {
+		checkColumn("combinedFields", aggregator.Name)
+		switch {
+		case !aggregatorTypes[aggregator.Type]:
+			problems = append(problems, fmt.Sprintf("combinedFields %s: type must be one of COUNT, SUM, AVG, MIN or MAX", aggregator.Name))
+		case aggregator.Type == "COUNT" && aggregator.Subject != "":
+			problems = append(problems, fmt.Sprintf("combinedFields %s: COUNT takes no subject", aggregator.Name))
+		case aggregator.Type != "COUNT" && strings.TrimSpace(aggregator.Subject) == "":
+			problems = append(problems, fmt.Sprintf("combinedFields %s: %s needs a subject", aggregator.Name, aggregator.Type))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid status collector: %s", strings.Join(problems, "; "))
+	}
+
+	if spec.Limit == nil {
+		limit := int64(defaultStatusCollectorLimit)
+		spec.Limit = &limit
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return nil, err
+	}
+	collector := &unstructured.Unstructured{Object: map[string]interface{}{"spec": content}}
+	collector.SetAPIVersion(statusCollectorGVR.GroupVersion().String())
+	collector.SetKind("StatusCollector")
+	collector.SetName(request.Name)
+	return collector, nil
+}
+
+// StatusCollectorUsage maps each StatusCollector to the policies whose
+// downsync rules use it
+func StatusCollectorUsage(policies []*v1alpha1.BindingPolicy) map[string][]string {
+	usage := map[string][]string{}
+	for _, bp := range policies {
+		used := map[string]bool{}
+		for _, clause := range bp.Spec.Downsync {
+			for _, name := range clause.StatusCollectors {
+				if !used[name] {
+					used[name] = true
+					usage[name] = append(usage[name], bp.Name)
+				}
+			}
+		}
+	}
+	for name := range usage {
+		sort.Strings(usage[name])
+	}
+	return usage
+}
+
+// AttachStatusCollectors sets the StatusCollectors of the given downsync
+// rules of a policy, or of all of them when rules is empty
+func AttachStatusCollectors(bp *v1alpha1.BindingPolicy, collectors []string, rules []int) error {
+	if len(bp.Spec.Downsync) == 0 {
+		return fmt.Errorf("policy %s has no downsync rules", bp.Name)
+	}
+	if len(rules) == 0 {
+		for i := range bp.Spec.Downsync {
+			rules = append(rules, i)
+		}
+	}
+	for _, rule := range rules {
+		if rule < 0 || rule >= len(bp.Spec.Downsync) {
+			return fmt.Errorf("policy %s has no downsync rule %d", bp.Name, rule)
+		}
+	}
+
+	// Deduplicate while keeping the requested order
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range collectors {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	for _, rule := range rules {
+		if len(unique) == 0 {
+			bp.Spec.Downsync[rule].StatusCollectors = nil
+		} else {
+			bp.Spec.Downsync[rule].StatusCollectors = append([]string(nil), unique...)
+		}
+	}
+	return nil
+}
+
+// CombinedStatusQuery identifies a workload object, and optionally one of
+// the policies distributing it
+type CombinedStatusQuery struct {
+	APIGroup  string `json:"apiGroup"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Policy    string `json:"policy,omitempty"`
+}
+
+// CombinedStatusTable is what one StatusCollector produced for an object
+type CombinedStatusTable struct {
+	Collector string          `json:"collector"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+}
+
+// CombinedStatusResult is the combined status of an object under one policy
+type CombinedStatusResult struct {
+	Policy string                `json:"policy"`
+	Tables []CombinedStatusTable `json:"tables"`
+}
+
+// GetCombinedStatus returns the status KubeStellar combined from every WEC
+// for a workload object, one result per policy that distributes it
+func GetCombinedStatus(ctx context.Context, client dynamic.Interface, query CombinedStatusQuery) ([]CombinedStatusResult, error) {
+	selector := labels.Set{
+		combinedStatusGroupLabel:    query.APIGroup,
+		combinedStatusResourceLabel: query.Resource,
+		combinedStatusNameLabel:     query.Name,
+	}
+	if query.Namespace != "" {
+		selector[combinedStatusNamespaceLabel] = query.Namespace
+	}
+	if query.Policy != "" {
+		selector[combinedStatusPolicyLabel] = query.Policy
+	}
+
+	// CombinedStatus objects live in the namespace of the workload object
+	list, err := client.Resource(combinedStatusGVR).Namespace(query.Namespace).List(ctx, v1.ListOptions{
+		LabelSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list combined statuses: %v", err)
+	}
+
+	results := []CombinedStatusResult{}
+	for _, item := range list.Items {
+		results = append(results, combinedStatusResult(&item))
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Policy < results[j].Policy })
+	return results, nil
+}
+
+func combinedStatusResult(obj *unstructured.Unstructured) CombinedStatusResult {
+	result := CombinedStatusResult{Policy: obj.GetLabels()[combinedStatusPolicyLabel], Tables: []CombinedStatusTable{}}
+
+	named, _, _ := unstructured.NestedSlice(obj.Object, "results")
+	for _, entry := range named {
+		combination, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		table := CombinedStatusTable{Columns: []string{}, Rows: [][]interface{}{}}
+		table.Collector, _ = combination["name"].(string)
+		columnNames, _, _ := unstructured.NestedStringSlice(combination, "columnNames")
+		if columnNames != nil {
+			table.Columns = columnNames
+		}
+
+		rows, _ := combination["rows"].([]interface{})
+		for _, row := range rows {
+			fields, ok := row.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			columns, _, _ := unstructured.NestedSlice(fields, "columns")
+			values := make([]interface{}, 0, len(columns))
+			for _, column := range columns {
+				value, _ := column.(map[string]interface{})
+				values = append(values, combinedStatusValue(value))
+			}
+			table.Rows = append(table.Rows, values)
+		}
+		result.Tables = append(result.Tables, table)
+	}
+	return result
+}
+
+// combinedStatusValue converts a typed CombinedStatus value to plain JSON
+func combinedStatusValue(value map[string]interface{}) interface{} {
+	switch value["type"] {
+	case "String":
+		return value["string"]
+	case "Number":
+		// Numbers are sent as strings so no precision is lost
+		if number, ok := value["number"].(string); ok {
+			if parsed, err := strconv.ParseFloat(number, 64); err == nil {
+				return parsed
+			}
+		}
+		return value["number"]
+	case "Boolean":
+		return value["bool"]
+	case "Object":
+		return value["object"]
+	case "Array":
+		return value["array"]
+	}
+	return nil
+}
+
+// statusCollectorClient connects to the WDS of a request
+func statusCollectorClient(ctx *gin.Context) (dynamic.Interface, string, error) {
+	space, err := hub.SelectWDS(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	wdsContext := space.Context
+	_, client, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		return nil, wdsContext, fmt.Errorf("failed to connect to WDS %s: %v", wdsContext, err)
+	}
+	return client, wdsContext, nil
+}
+
+func statusCollectorInfo(obj *unstructured.Unstructured, usage map[string][]string) StatusCollectorInfo {
+	info := StatusCollectorInfo{Name: obj.GetName(), UsedBy: usage[obj.GetName()]}
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &info.Spec); err != nil {
+			log.LogWarn("failed to decode status collector", zap.String("name", obj.GetName()), zap.Error(err))
+		}
+	}
+	if info.UsedBy == nil {
+		info.UsedBy = []string{}
+	}
+	return info
+}
+
+// policyUsage lists the policies of a WDS and returns StatusCollectorUsage
+func policyUsage(ctx context.Context, client dynamic.Interface) (map[string][]string, error) {
+	policies, err := listPolicies(ctx, PreviewClients{WDS: client})
+	if err != nil {
+		return nil, err
+	}
+	return StatusCollectorUsage(policies), nil
+}
+
+// ListStatusCollectors returns the StatusCollectors of a WDS and the
+// policies that use each of them
+func ListStatusCollectors(ctx *gin.Context) {
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := client.Resource(statusCollectorGVR).List(ctx.Request.Context(), v1.ListOptions{})
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list status collectors", "details": err.Error()})
+		return
+	}
+	usage, err := policyUsage(ctx.Request.Context(), client)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	collectors := make([]StatusCollectorInfo, 0, len(list.Items))
+	for i := range list.Items {
+		collectors = append(collectors, statusCollectorInfo(&list.Items[i], usage))
+	}
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].Name < collectors[j].Name })
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/statuscollectors", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"statusCollectors": collectors, "count": len(collectors)})
+}
+
+// GetStatusCollector returns one StatusCollector
+func GetStatusCollector(ctx *gin.Context) {
+	name := ctx.Param("name")
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	collector, err := client.Resource(statusCollectorGVR).Get(ctx.Request.Context(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors/:name", "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("status collector %s not found", name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	usage, err := policyUsage(ctx.Request.Context(), client)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/statuscollectors/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, statusCollectorInfo(collector, usage))
+}
+
+// CreateStatusCollector creates a StatusCollector
+func CreateStatusCollector(ctx *gin.Context) {
+	var request StatusCollectorRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/statuscollectors", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	collector, err := NewStatusCollector(&request)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/statuscollectors", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/statuscollectors", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := client.Resource(statusCollectorGVR).Create(ctx.Request.Context(), collector, v1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/statuscollectors", "409").Inc()
+		ctx.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("status collector %s already exists", request.Name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/bp/statuscollectors", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status collector", "details": err.Error()})
+		return
+	}
+
+	log.LogInfo("Created status collector", zap.String("name", created.GetName()))
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/api/bp/statuscollectors", "201").Inc()
+	ctx.JSON(http.StatusCreated, statusCollectorInfo(created, nil))
+}
+
+// UpdateStatusCollector replaces the spec of a StatusCollector
+func UpdateStatusCollector(ctx *gin.Context) {
+	name := ctx.Param("name")
+	var request StatusCollectorRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	request.Name = name
+	collector, err := NewStatusCollector(&request)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	existing, err := client.Resource(statusCollectorGVR).Get(ctx.Request.Context(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("status collector %s not found", name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing.Object["spec"] = collector.Object["spec"]
+	updated, err := client.Resource(statusCollectorGVR).Update(ctx.Request.Context(), existing, v1.UpdateOptions{})
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status collector", "details": err.Error()})
+		return
+	}
+
+	log.LogInfo("Updated status collector", zap.String("name", name))
+	telemetry.TotalHTTPRequests.WithLabelValues("PUT", "/api/bp/statuscollectors/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, statusCollectorInfo(updated, nil))
+}
+
+// DeleteStatusCollector deletes a StatusCollector. It refuses while policies
+// still use it, unless "force=true" is given.
+func DeleteStatusCollector(ctx *gin.Context) {
+	name := ctx.Param("name")
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if ctx.Query("force") != "true" {
+		usage, err := policyUsage(ctx.Request.Context(), client)
+		if err != nil {
+			telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "500").Inc()
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if usedBy := usage[name]; len(usedBy) > 0 {
+			telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "409").Inc()
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":  fmt.Sprintf("status collector %s is used by %d binding policies", name, len(usedBy)),
+				"usedBy": usedBy,
+			})
+			return
+		}
+	}
+
+	err = client.Resource(statusCollectorGVR).Delete(ctx.Request.Context(), name, v1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("status collector %s not found", name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete status collector", "details": err.Error()})
+		return
+	}
+
+	log.LogInfo("Deleted status collector", zap.String("name", name))
+	telemetry.TotalHTTPRequests.WithLabelValues("DELETE", "/api/bp/statuscollectors/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("deleted status collector %s", name)})
+}
+
+// SetBpStatusCollectors attaches StatusCollectors to the downsync rules of a
+// policy. The body lists the collectors and, optionally, the indexes of the
+// rules to change; an empty list of collectors detaches them. A policy changed
+// since it was read is reported with 409.
+func SetBpStatusCollectors(ctx *gin.Context) {
+	const path = "/api/bp/update/:name/statuscollectors"
+	name := ctx.Param("name")
+
+	var request struct {
+		StatusCollectors []string `json:"statusCollectors"`
+		Rules            []int    `json:"rules"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	client, wdsContext, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var missing []string
+	for _, collector := range request.StatusCollectors {
+		if _, err := client.Resource(statusCollectorGVR).Get(ctx.Request.Context(), collector, v1.GetOptions{}); apierrors.IsNotFound(err) {
+			missing = append(missing, collector)
+		}
+	}
+	if len(missing) > 0 {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("status collectors not found: %s", strings.Join(missing, ", "))})
+		return
+	}
+
+	existing, err := client.Resource(bindingPolicyGVR).Get(ctx.Request.Context(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("binding policy %s not found", name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	bp, err := toBindingPolicy(existing)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := AttachStatusCollectors(bp, request.StatusCollectors, request.Rules); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Patch only the downsync rules, and only if the policy has not changed
+	// since it was read, so a concurrent edit of the rules is not overwritten
+	author := requestAuthor(ctx)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": existing.GetResourceVersion()},
+		"spec":     map[string]interface{}{"downsync": bp.Spec.Downsync},
+	})
+	if err == nil {
+		patch, err = withModifiedBy(patch, author)
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patched, err := client.Resource(bindingPolicyGVR).Patch(ctx.Request.Context(), name, "application/merge-patch+json", patch, v1.PatchOptions{})
+	if apierrors.IsConflict(err) {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "409").Inc()
+		ctx.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("binding policy %s was changed concurrently, reload it and try again", name)})
+		return
+	}
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("PUT", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update binding policy", "details": err.Error()})
+		return
+	}
+	if updated, err := toBindingPolicy(patched); err == nil {
+		recordRevision(wdsContext, updated, false, author)
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("PUT", path, "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("updated the status collectors of %s", name),
+		"downsync": bp.Spec.Downsync,
+	})
+}
+
+// GetCombinedStatusHandler returns the status of a workload object combined
+// across the WECs it was delivered to. The object is given by the apiGroup,
+// resource, namespace and name query parameters; "policy" limits the result
+// to one BindingPolicy.
+func GetCombinedStatusHandler(ctx *gin.Context) {
+	query := CombinedStatusQuery{
+		APIGroup:  ctx.Query("apiGroup"),
+		Resource:  ctx.Query("resource"),
+		Namespace: ctx.Query("namespace"),
+		Name:      ctx.Query("name"),
+		Policy:    ctx.Query("policy"),
+	}
+	if query.Resource == "" || query.Name == "" {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/combinedstatus", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "resource and name are required"})
+		return
+	}
+
+	client, _, err := statusCollectorClient(ctx)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/combinedstatus", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := GetCombinedStatus(ctx.Request.Context(), client, query)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/combinedstatus", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/combinedstatus", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"object": query, "results": results, "count": len(results)})
+}
@@ -0,0 +1,757 @@
+package bp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
+	database "github.com/kubestellar/ui/backend/postgresql/Database"
+	"github.com/kubestellar/ui/backend/redis"
+	"github.com/kubestellar/ui/backend/telemetry"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Types of template parameters
+const (
+	ParamString     = "string"
+	ParamInteger    = "integer"
+	ParamBoolean    = "boolean"
+	ParamStringList = "stringList"
+	// ParamLabels is a map of label keys to values, such as a cluster selector
+	ParamLabels = "labels"
+)
+
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TemplateParameter declares one parameter of a BindingPolicy template
+type TemplateParameter struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	// Enum lists the allowed values of a string parameter
+	Enum []string `json:"enum,omitempty"`
+	// Pattern is a regular expression that string values and the items of
+	// string lists must match
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// BPTemplateDefinition is a template as submitted by an admin. Body is a Go
+// text/template that renders BindingPolicy YAML; parameters are available as
+// .name and the functions quote, toJSON and join help to emit valid YAML.
+type BPTemplateDefinition struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Parameters  []TemplateParameter `json:"parameters"`
+	Body        string              `json:"body"`
+}
+
+// TemplateValidationError lists everything wrong with a template or with the
+// parameters it was given
+type TemplateValidationError struct {
+	Problems []string
+}
+
+func (e *TemplateValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// RenderedTemplate is a BindingPolicy rendered from a template
+type RenderedTemplate struct {
+	// Template references the template version, as "name@vN"
+	Template   string                  `json:"template"`
+	Parameters map[string]interface{}  `json:"parameters"`
+	YAML       string                  `json:"yaml"`
+	Policy     *v1alpha1.BindingPolicy `json:"policy"`
+}
+
+// TemplateRef returns the reference recorded on policies created from a
+// template version
+func TemplateRef(name string, version int) string {
+	return fmt.Sprintf("%s@v%d", name, version)
+}
+
+var templateFuncs = template.FuncMap{
+	// quote renders a string as a YAML double-quoted scalar
+	"quote": strconv.Quote,
+	// toJSON renders any value as JSON, which is valid YAML flow style
+	"toJSON": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+	"join": func(separator string, values []string) string {
+		return strings.Join(values, separator)
+	},
+}
+
+// ValidateTemplateDefinition checks a template before it is stored: its
+// name, its parameter declarations and defaults, and that its body parses
+// and renders a valid BindingPolicy from the defaults and sample values
+func ValidateTemplateDefinition(def *BPTemplateDefinition) error {
+	var problems []string
+
+	if errs := validation.IsDNS1123Subdomain(def.Name); len(errs) > 0 {
+		problems = append(problems, fmt.Sprintf("invalid template name %q: %s", def.Name, strings.Join(errs, ", ")))
+	}
+	if strings.TrimSpace(def.Body) == "" {
+		problems = append(problems, "template body is required")
+	}
+
+	seen := map[string]bool{}
+	for i, param := range def.Parameters {
+		if !parameterNamePattern.MatchString(param.Name) {
+			problems = append(problems, fmt.Sprintf("parameter %d: invalid name %q", i, param.Name))
+			continue
+		}
+		if seen[param.Name] {
+			problems = append(problems, fmt.Sprintf("parameter %s is declared twice", param.Name))
+		}
+		seen[param.Name] = true
+
+		switch param.Type {
+		case ParamString, ParamInteger, ParamBoolean, ParamStringList, ParamLabels:
+		default:
+			problems = append(problems, fmt.Sprintf("parameter %s: unknown type %q", param.Name, param.Type))
+			continue
+		}
+		if len(param.Enum) > 0 && param.Type != ParamString {
+			problems = append(problems, fmt.Sprintf("parameter %s: enum is only supported for string parameters", param.Name))
+		}
+		if param.Pattern != "" {
+			if param.Type != ParamString && param.Type != ParamStringList {
+				problems = append(problems, fmt.Sprintf("parameter %s: pattern is only supported for string and stringList parameters", param.Name))
+			} else if _, err := regexp.Compile(param.Pattern); err != nil {
+				problems = append(problems, fmt.Sprintf("parameter %s: invalid pattern: %v", param.Name, err))
+			}
+		}
+		if param.Default != nil {
+			if _, err := coerceParameter(param, param.Default); err != nil {
+				problems = append(problems, fmt.Sprintf("parameter %s: invalid default: %v", param.Name, err))
+			}
+		}
+	}
+
+	if _, err := template.New(def.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(def.Body); err != nil {
+		problems = append(problems, fmt.Sprintf("template body does not parse: %v", err))
+	}
+
+	if len(problems) > 0 {
+		return &TemplateValidationError{Problems: problems}
+	}
+
+	// Render once with sample values so broken templates are caught now and
+	// not when someone first uses them
+	if samples, ok := sampleParameters(def.Parameters); ok {
+		if _, err := renderTemplate(def, samples); err != nil {
+			return &TemplateValidationError{Problems: []string{fmt.Sprintf("template does not render a valid BindingPolicy with sample parameters: %v", err)}}
+		}
+	}
+	return nil
+}
+
+// sampleParameters returns a valid placeholder for every parameter without
+// a default. It reports false when a required parameter has a pattern, since
+// no placeholder can be relied on to match it.
+func sampleParameters(params []TemplateParameter) (map[string]interface{}, bool) {
+	values := map[string]interface{}{}
+	for _, param := range params {
+		if param.Default != nil {
+			continue
+		}
+		if param.Pattern != "" {
+			if param.Required {
+				return nil, false
+			}
+			continue
+		}
+		switch param.Type {
+		case ParamString:
+			values[param.Name] = "sample"
+			if len(param.Enum) > 0 {
+				values[param.Name] = param.Enum[0]
+			}
+		case ParamInteger:
+			values[param.Name] = 1
+		case ParamBoolean:
+			values[param.Name] = false
+		case ParamStringList:
+			values[param.Name] = []interface{}{"sample"}
+		case ParamLabels:
+			values[param.Name] = map[string]interface{}{"sample": "sample"}
+		}
+	}
+	return values, true
+}
+
+// ResolveTemplateParameters checks values against the declared parameters
+// and returns them with defaults applied and converted to their types
+func ResolveTemplateParameters(params []TemplateParameter, values map[string]interface{}) (map[string]interface{}, error) {
+	var problems []string
+	resolved := map[string]interface{}{}
+
+	declared := map[string]bool{}
+	for _, param := range params {
+		declared[param.Name] = true
+
+		value, given := values[param.Name]
+		if !given || value == nil {
+			switch {
+			case param.Default != nil:
+				value = param.Default
+			case param.Required:
+				problems = append(problems, fmt.Sprintf("parameter %s is required", param.Name))
+				continue
+			default:
+				resolved[param.Name] = zeroParameter(param.Type)
+				continue
+			}
+		}
+
+		coerced, err := coerceParameter(param, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parameter %s: %v", param.Name, err))
+			continue
+		}
+		resolved[param.Name] = coerced
+	}
+
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown parameter %s", name))
+	}
+
+	if len(problems) > 0 {
+		return nil, &TemplateValidationError{Problems: problems}
+	}
+	return resolved, nil
+}
+
+func zeroParameter(paramType string) interface{} {
+	switch paramType {
+	case ParamInteger:
+		return int64(0)
+	case ParamBoolean:
+		return false
+	case ParamStringList:
+		return []string{}
+	case ParamLabels:
+		return map[string]string{}
+	default:
+		return ""
+	}
+}
+
+// coerceParameter converts a value decoded from JSON to the parameter's type
+func coerceParameter(param TemplateParameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case ParamString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string")
+		}
+		if len(param.Enum) > 0 && !contains(param.Enum, s) {
+			return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(param.Enum, ", "))
+		}
+		if err := matchPattern(param.Pattern, s); err != nil {
+			return nil, err
+		}
+		return s, nil
+
+	case ParamInteger:
+		switch n := value.(type) {
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("expected an integer")
+			}
+			return int64(n), nil
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case json.Number:
+			return n.Int64()
+		}
+		return nil, fmt.Errorf("expected an integer")
+
+	case ParamBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean")
+		}
+		return b, nil
+
+	case ParamStringList:
+		var items []string
+		switch list := value.(type) {
+		case []string:
+			items = list
+		case []interface{}:
+			for _, item := range list {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected a list of strings")
+				}
+				items = append(items, s)
+			}
+		default:
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		for _, item := range items {
+			if err := matchPattern(param.Pattern, item); err != nil {
+				return nil, err
+			}
+		}
+		if items == nil {
+			items = []string{}
+		}
+		return items, nil
+
+	case ParamLabels:
+		labels := map[string]string{}
+		switch m := value.(type) {
+		case map[string]string:
+			for k, v := range m {
+				labels[k] = v
+			}
+		case map[string]interface{}:
+			for k, v := range m {
+				s, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("label %s must have a string value", k)
+				}
+				labels[k] = s
+			}
+		default:
+			return nil, fmt.Errorf("expected a map of labels")
+		}
+		for k, v := range labels {
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, ", "))
+			}
+			if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid value for label %s: %s", k, strings.Join(errs, ", "))
+			}
+		}
+		return labels, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", param.Type)
+}
+
+func matchPattern(pattern, value string) error {
+	if pattern == "" {
+		return nil
+	}
+	matched, err := regexp.MatchString(pattern, value)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return fmt.Errorf("%q does not match %s", value, pattern)
+	}
+	return nil
+}
+
+// RenderBPTemplate renders a stored template version with the given
+// parameters and validates the resulting BindingPolicy
+func RenderBPTemplate(stored *models.BPTemplate, values map[string]interface{}) (*RenderedTemplate, error) {
+	def, err := templateDefinition(stored)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderTemplate(def, values)
+	if err != nil {
+		return nil, err
+	}
+	rendered.Template = TemplateRef(stored.Name, stored.Version)
+	return rendered, nil
+}
+
+func templateDefinition(stored *models.BPTemplate) (*BPTemplateDefinition, error) {
+	def := &BPTemplateDefinition{Name: stored.Name, Description: stored.Description, Body: stored.Body}
+	if len(stored.Parameters) > 0 {
+		if err := json.Unmarshal(stored.Parameters, &def.Parameters); err != nil {
+			return nil, fmt.Errorf("stored template %s has invalid parameters: %v", TemplateRef(stored.Name, stored.Version), err)
+		}
+	}
+	return def, nil
+}
+
+func renderTemplate(def *BPTemplateDefinition, values map[string]interface{}) (*RenderedTemplate, error) {
+	resolved, err := ResolveTemplateParameters(def.Parameters, values)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(def.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(def.Body)
+	if err != nil {
+		return nil, fmt.Errorf("template body does not parse: %v", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, resolved); err != nil {
+		return nil, &TemplateValidationError{Problems: []string{fmt.Sprintf("failed to render template: %v", err)}}
+	}
+
+	bp, err := getBpObjFromYaml(out.Bytes())
+	if err != nil {
+		return nil, &TemplateValidationError{Problems: []string{fmt.Sprintf("rendered template is not a BindingPolicy: %v", err)}}
+	}
+	if problems := validateRenderedPolicy(bp); len(problems) > 0 {
+		return nil, &TemplateValidationError{Problems: problems}
+	}
+
+	return &RenderedTemplate{Parameters: resolved, YAML: out.String(), Policy: bp}, nil
+}
+
+// validateRenderedPolicy checks what the API server would not: that the
+// policy selects some clusters and objects, with valid selectors
+func validateRenderedPolicy(bp *v1alpha1.BindingPolicy) []string {
+	var problems []string
+	if errs := validation.IsDNS1123Subdomain(bp.Name); len(errs) > 0 {
+		problems = append(problems, fmt.Sprintf("invalid policy name %q: %s", bp.Name, strings.Join(errs, ", ")))
+	}
+	if len(bp.Spec.ClusterSelectors) == 0 {
+		problems = append(problems, "rendered policy has no cluster selectors")
+	}
+	if len(bp.Spec.Downsync) == 0 {
+		problems = append(problems, "rendered policy has no downsync rules")
+	}
+	return append(problems, selectorProblems(bp)...)
+}
+
+// templateResponse is a stored template version with its parameters decoded
+type templateResponse struct {
+	*models.BPTemplate
+	Ref        string              `json:"ref"`
+	Parameters []TemplateParameter `json:"parameters"`
+}
+
+func newTemplateResponse(stored *models.BPTemplate) templateResponse {
+	response := templateResponse{BPTemplate: stored, Ref: TemplateRef(stored.Name, stored.Version), Parameters: []TemplateParameter{}}
+	if def, err := templateDefinition(stored); err == nil && def.Parameters != nil {
+		response.Parameters = def.Parameters
+	}
+	return response
+}
+
+func templatesAvailable(ctx *gin.Context) bool {
+	if database.DB == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "binding policy templates require a database"})
+		return false
+	}
+	return true
+}
+
+// templateVersionParam parses an optional version, 0 meaning the latest
+func templateVersionParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("version must be a positive integer")
+	}
+	return version, nil
+}
+
+func templateError(ctx *gin.Context, method, path string, err error) {
+	if validationErr, ok := err.(*TemplateValidationError); ok {
+		telemetry.HTTPErrorCounter.WithLabelValues(method, path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid template or parameters", "problems": validationErr.Problems})
+		return
+	}
+	telemetry.HTTPErrorCounter.WithLabelValues(method, path, "500").Inc()
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// CreateBpTemplate stores a new template, or a new version of an existing one
+func CreateBpTemplate(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+
+	var def BPTemplateDefinition
+	if err := ctx.ShouldBindJSON(&def); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/admin/bp/templates", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if err := ValidateTemplateDefinition(&def); err != nil {
+		templateError(ctx, "POST", "/api/admin/bp/templates", err)
+		return
+	}
+
+	parameters, err := json.Marshal(def.Parameters)
+	if err != nil {
+		templateError(ctx, "POST", "/api/admin/bp/templates", err)
+		return
+	}
+	stored, err := models.CreateBPTemplateVersion(&models.BPTemplate{
+		Name:        def.Name,
+		Description: def.Description,
+		Parameters:  parameters,
+		Body:        def.Body,
+		CreatedBy:   requestAuthor(ctx),
+	})
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/api/admin/bp/templates", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store template", "details": err.Error()})
+		return
+	}
+
+	log.LogInfo("Stored binding policy template", zap.String("template", TemplateRef(stored.Name, stored.Version)))
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/api/admin/bp/templates", "201").Inc()
+	ctx.JSON(http.StatusCreated, newTemplateResponse(stored))
+}
+
+// DeleteBpTemplate removes every version of a template. Policies created from
+// it keep working and keep their template reference.
+func DeleteBpTemplate(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	deleted, err := models.DeleteBPTemplate(name)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/admin/bp/templates/:name", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template", "details": err.Error()})
+		return
+	}
+	if !deleted {
+		telemetry.HTTPErrorCounter.WithLabelValues("DELETE", "/api/admin/bp/templates/:name", "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("template %s not found", name)})
+		return
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("DELETE", "/api/admin/bp/templates/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("deleted template %s", name)})
+}
+
+// ListBpTemplates returns the latest version of every template
+func ListBpTemplates(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+
+	stored, err := models.ListBPTemplates()
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/templates", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list templates", "details": err.Error()})
+		return
+	}
+
+	templates := make([]templateResponse, 0, len(stored))
+	for _, t := range stored {
+		templates = append(templates, newTemplateResponse(t))
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/templates", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"templates": templates, "count": len(templates)})
+}
+
+// ListBpTemplateVersions returns every version of a template, newest first
+func ListBpTemplateVersions(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+	name := ctx.Param("name")
+
+	stored, err := models.ListBPTemplateVersions(name)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/templates/:name/versions", "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list template versions", "details": err.Error()})
+		return
+	}
+	if len(stored) == 0 {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/templates/:name/versions", "404").Inc()
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("template %s not found", name)})
+		return
+	}
+
+	versions := make([]templateResponse, 0, len(stored))
+	for _, t := range stored {
+		versions = append(versions, newTemplateResponse(t))
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/templates/:name/versions", "200").Inc()
+	ctx.JSON(http.StatusOK, gin.H{"template": name, "versions": versions, "count": len(versions)})
+}
+
+// lookupTemplate loads the template version a request names, writing the
+// error response and returning nil when it cannot
+func lookupTemplate(ctx *gin.Context, method, path string, version int) *models.BPTemplate {
+	name := ctx.Param("name")
+	stored, err := models.GetBPTemplate(name, version)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues(method, path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get template", "details": err.Error()})
+		return nil
+	}
+	if stored == nil {
+		telemetry.HTTPErrorCounter.WithLabelValues(method, path, "404").Inc()
+		if version > 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("template %s not found", TemplateRef(name, version))})
+		} else {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("template %s not found", name)})
+		}
+		return nil
+	}
+	return stored
+}
+
+// GetBpTemplate returns a template, the latest version unless the "version"
+// query parameter names one
+func GetBpTemplate(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+	version, err := templateVersionParam(ctx.Query("version"))
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/bp/templates/:name", "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored := lookupTemplate(ctx, "GET", "/api/bp/templates/:name", version)
+	if stored == nil {
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/bp/templates/:name", "200").Inc()
+	ctx.JSON(http.StatusOK, newTemplateResponse(stored))
+}
+
+// templateRequest is the body of the render and create endpoints
+type templateRequest struct {
+	// Version of the template, the latest when 0
+	Version     int                    `json:"version"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	Description string                 `json:"description"`
+}
+
+// renderFromRequest binds a templateRequest and renders it, writing the
+// error response and returning nil when it cannot
+func renderFromRequest(ctx *gin.Context, path string) (*RenderedTemplate, *templateRequest) {
+	var request templateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return nil, nil
+	}
+	if request.Version < 0 {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "400").Inc()
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+		return nil, nil
+	}
+
+	stored := lookupTemplate(ctx, "POST", path, request.Version)
+	if stored == nil {
+		return nil, nil
+	}
+	rendered, err := RenderBPTemplate(stored, request.Parameters)
+	if err != nil {
+		templateError(ctx, "POST", path, err)
+		return nil, nil
+	}
+	return rendered, &request
+}
+
+// RenderBpTemplate renders a template with parameters without creating the
+// policy
+func RenderBpTemplate(ctx *gin.Context) {
+	if !templatesAvailable(ctx) {
+		return
+	}
+	rendered, _ := renderFromRequest(ctx, "/api/bp/templates/:name/render")
+	if rendered == nil {
+		return
+	}
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", "/api/bp/templates/:name/render", "200").Inc()
+	ctx.JSON(http.StatusOK, rendered)
+}
+
+// CreateBpFromTemplate renders a template and creates the policy in the WDS.
+// The policy records the template version and parameters it came from.
+func CreateBpFromTemplate(ctx *gin.Context) {
+	itsContext, wdsContext, ok := requestSpaces(ctx)
+	if !ok {
+		return
+	}
+	if !templatesAvailable(ctx) {
+		return
+	}
+	const path = "/api/bp/templates/:name/create"
+	rendered, request := renderFromRequest(ctx, path)
+	if rendered == nil {
+		return
+	}
+
+	bp := rendered.Policy
+	SetPolicyMetadata(bp, &StoredBindingPolicy{
+		Name:               bp.Name,
+		Namespace:          bp.Namespace,
+		RawYAML:            rendered.YAML,
+		Author:             requestAuthor(ctx),
+		Template:           rendered.Template,
+		TemplateParameters: rendered.Parameters,
+		Description:        request.Description,
+	})
+
+	c, err := getClientForBp(wdsContext)
+	if err != nil {
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := c.BindingPolicies().Create(context.TODO(), bp, v1.CreateOptions{})
+	if err != nil {
+		log.LogError("failed to create binding policy from template", zap.String("template", rendered.Template), zap.Error(err))
+		telemetry.HTTPErrorCounter.WithLabelValues("POST", path, "500").Inc()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordRevision(wdsContext, created, false, requestAuthor(ctx))
+
+	cachedPolicy := &redis.BindingPolicyCache{
+		Name:              created.Name,
+		Namespace:         created.Namespace,
+		WDSContext:        wdsContext,
+		Status:            "inactive", // New policies start as inactive
+		BindingMode:       "Downsync",
+		Clusters:          extractTargetClusters(created),
+		Workloads:         extractWorkloads(wdsContext, created),
+		CreationTimestamp: time.Now().Format(time.RFC3339),
+		RawYAML:           rendered.YAML,
+	}
+	if err := redis.StoreBindingPolicy(cachedPolicy); err != nil {
+		log.LogWarn("failed to cache new binding policy", zap.Error(err))
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("POST", path, "201").Inc()
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Created binding policy '%s' from template %s", created.Name, rendered.Template),
+		"template":   rendered.Template,
+		"parameters": rendered.Parameters,
+		"yaml":       rendered.YAML,
+		"warnings":   lintWarnings(itsContext, wdsContext, created.Name),
+	})
+}
@@ -3,49 +3,56 @@ package bp
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
 	"github.com/kubestellar/kubestellar/pkg/generated/clientset/versioned/scheme"
 	bpv1alpha1 "github.com/kubestellar/kubestellar/pkg/generated/clientset/versioned/typed/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/models"
 	"github.com/kubestellar/ui/backend/redis"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
-// DefaultWDSContext is the default context to use for the workload distribution service
+// DefaultWDSContext is the WDS of the KubeStellar getting-started setup. The
+// WDS a request works on is selected through the hub registry.
 const DefaultWDSContext = "wds1"
 
-// clientCache caches the BP client to avoid recreating it for each request
+// clientCache caches the BP client of each WDS to avoid recreating it for
+// each request
 var (
-	clientCache     *bpv1alpha1.ControlV1alpha1Client
+	clientCache     = map[string]*bpv1alpha1.ControlV1alpha1Client{}
 	clientCacheLock sync.Mutex
 )
 
-// getClientForBp creates a new client for BindingPolicy operations
-func getClientForBp() (*bpv1alpha1.ControlV1alpha1Client, error) {
+// getClientForBp creates a new client for BindingPolicy operations in a WDS
+func getClientForBp(wdsContext string) (*bpv1alpha1.ControlV1alpha1Client, error) {
 	clientCacheLock.Lock()
 	defer clientCacheLock.Unlock()
 
 	// Return cached client if available
-	if clientCache != nil {
-		return clientCache, nil
+	if c, ok := clientCache[wdsContext]; ok {
+		return c, nil
 	}
 
-	// Set wds context to "wds1"
-	wdsContext := "wds1"
+	requestedContext := wdsContext
 	log.LogDebug("Using wds context", zap.String("context", wdsContext))
 
 	// Get kubeconfig path
@@ -108,11 +115,51 @@ func getClientForBp() (*bpv1alpha1.ControlV1alpha1Client, error) {
 	}
 
 	// Cache the client for future use
-	clientCache = c
+	clientCache[requestedContext] = c
 
 	return c, nil
 }
 
+// spacesFromRequest returns the kubeconfig contexts of the ITS and WDS a
+// request works on, as selected by the hub registry
+func spacesFromRequest(ctx *gin.Context) (string, string, error) {
+	itsSpace, err := hub.SelectITS(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	wdsSpace, err := hub.SelectWDS(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return itsSpace.Context, wdsSpace.Context, nil
+}
+
+// requestSpaces is spacesFromRequest for handlers; on an unknown space it
+// responds with 400 and returns false
+func requestSpaces(ctx *gin.Context) (string, string, bool) {
+	itsContext, wdsContext, err := spacesFromRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	return itsContext, wdsContext, true
+}
+
+// bpStatus reports a policy as "active" once the controller has observed its
+// latest generation without reporting errors or a failed condition, and as
+// "inactive" otherwise
+func bpStatus(bp *v1alpha1.BindingPolicy) string {
+	if bp.ObjectMeta.Generation != bp.Status.ObservedGeneration || len(bp.Status.Errors) > 0 {
+		return "inactive"
+	}
+	for _, condition := range bp.Status.Conditions {
+		if condition.Status == corev1.ConditionFalse {
+			return "inactive"
+		}
+	}
+	return "active"
+}
+
 // get BP struct from YAML
 func getBpObjFromYaml(bpRawYamlBytes []byte) (*v1alpha1.BindingPolicy, error) {
 	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(bpRawYamlBytes, nil, nil)
@@ -138,7 +185,7 @@ func containsAny(s string, substrings []string) bool {
 }
 
 // extractWorkloads gets a list of workloads affected by this BP
-func extractWorkloads(bp *v1alpha1.BindingPolicy) []string {
+func extractWorkloads(wdsContext string, bp *v1alpha1.BindingPolicy) []string {
 	workloads := []string{}
 
 	// Safety check
@@ -152,23 +199,23 @@ func extractWorkloads(bp *v1alpha1.BindingPolicy) []string {
 	// Load kubeconfig
 	kubeconfigPath := clientcmd.RecommendedHomeFile
 
-	// Load raw kubeconfig and select context "wds1"
+	// Load raw kubeconfig and select the WDS context
 	rawConfig, err := clientcmd.LoadFromFile(kubeconfigPath)
 	if err != nil {
 		log.LogError("failed to load kubeconfig file", zap.String("path", kubeconfigPath), zap.Error(err))
 		return workloads // Return an empty list of workloads on failure
 	}
 
-	// Explicitly set context to "wds1"
-	rawConfig.CurrentContext = "wds1"
+	// Explicitly set the WDS context
+	rawConfig.CurrentContext = wdsContext
 
 	// Build config from modified rawConfig
 	config, err := clientcmd.NewDefaultClientConfig(
 		*rawConfig,
-		&clientcmd.ConfigOverrides{CurrentContext: "wds1"},
+		&clientcmd.ConfigOverrides{CurrentContext: wdsContext},
 	).ClientConfig()
 	if err != nil {
-		log.LogError("failed to load kubeconfig for context 'wds1'", zap.Error(err))
+		log.LogError("failed to load kubeconfig for WDS context", zap.String("context", wdsContext), zap.Error(err))
 		return workloads // Return an empty list of workloads on failure
 	}
 
@@ -322,212 +369,170 @@ func contentTypeValid(t string) bool {
 	return false
 }
 
-// watches on all binding policy resources , PROTOTYPE just for now
-func watchOnBps() {
-	c, err := getClientForBp()
-	if err != nil {
-		log.LogError("failed to watch on BP", zap.String("error", err.Error()))
-		return
-	}
+// policyWatchers watches the binding policies of every WDS and keeps the
+// Redis cache of each in line with it
+var policyWatchers *PolicyWatchSupervisor
 
-	for {
+// reconcileStart tracks when each policy generation was first seen, to measure
+// how long KubeStellar takes to reconcile it
+var (
+	reconcileStart     = map[string]time.Time{}
+	reconcileStartLock sync.Mutex
+)
 
-		w, err := c.BindingPolicies().Watch(context.TODO(), v1.ListOptions{})
-		if err != nil {
-			log.LogError("failed to watch on BP", zap.String("error", err.Error()))
-			return
-		}
-		start := time.Now()
-		eventChan := w.ResultChan()
-		for event := range eventChan {
-			switch event.Type {
-			case "MODIFIED":
-				telemetry.BindingPolicyReconciliationDuration.Observe(time.Since(start).Seconds())
-				bp, _ := event.Object.(*v1alpha1.BindingPolicy)
-
-				// Determine the correct status
-				status := "inactive"
-				if bp.ObjectMeta.Generation == bp.Status.ObservedGeneration {
-					status = "active"
-					telemetry.BindingPolicyReconciliationDuration.Observe(time.Since(start).Seconds())
-					telemetry.BindingPolicyWatchEvents.WithLabelValues("modified", "reconciled").Inc()
-					log.LogInfo("BP reconciled successfully - updating cache to active", zap.String("name", bp.Name))
-				} else {
-					log.LogInfo("BP reconciling - keeping as inactive", zap.String("name", bp.Name))
-				}
+// cachePolicyEvent applies a change seen by the policy watcher of a WDS to
+// that WDS's cache and revision history
+func cachePolicyEvent(eventType watch.EventType, wdsContext string, bp *v1alpha1.BindingPolicy) {
+	if eventType == watch.Deleted {
+		telemetry.BindingPolicyWatchEvents.WithLabelValues("deleted", "success").Inc()
+		forgetReconcileStart(wdsContext, bp)
 
-				log.LogInfo("BP modified", zap.String("name", bp.Name), zap.String("newStatus", status))
+		recordRevision(wdsContext, bp, true, models.BPRevisionExternalAuthor)
 
-				// preserve existing YAML content from cache
-				existingYAML := ""
-				if existingPolicy, err := redis.GetBindingPolicy(bp.Name); err == nil && existingPolicy != nil {
-					existingYAML = existingPolicy.RawYAML
-					log.LogDebug("Preserving existing YAML content from cache", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(existingYAML)))
-				}
+		if err := redis.DeleteBindingPolicy(wdsContext, bp.Name); err != nil {
+			log.LogError("Error deleting bp from redis", zap.String("error", err.Error()))
+		}
+		log.LogInfo("BP deleted", zap.String("name", bp.Name), zap.String("wdsContext", wdsContext))
+		return
+	}
 
-				// If don't have existing YAML, try to generate it from the current BP
-				if existingYAML == "" {
-					if yamlBytes, err := yaml.Marshal(bp); err == nil {
-						existingYAML = string(yamlBytes)
-						log.LogDebug("Generated YAML for modified policy", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(existingYAML)))
-					}
-				}
+	status := bpStatus(bp)
+	switch eventType {
+	case watch.Added:
+		telemetry.BindingPolicyWatchEvents.WithLabelValues("added", "success").Inc()
+		log.LogInfo("BP added", zap.String("name", bp.Name), zap.String("wdsContext", wdsContext))
+	case watch.Modified:
+		log.LogInfo("BP modified", zap.String("name", bp.Name), zap.String("wdsContext", wdsContext), zap.String("newStatus", status))
+	}
+	observeReconciliation(wdsContext, bp, status)
+
+	// Prefer the YAML the policy was created from, then the YAML already in
+	// the cache, and generate it from the current object as a last resort
+	yamlContent := ""
+	if storedBP, exists := StoredPolicyFor(bp); exists && storedBP.RawYAML != "" {
+		yamlContent = storedBP.RawYAML
+	} else if existingPolicy, err := redis.GetBindingPolicy(wdsContext, bp.Name); err == nil && existingPolicy != nil {
+		yamlContent = existingPolicy.RawYAML
+	}
+	if yamlContent == "" {
+		if yamlBytes, err := yaml.Marshal(bp); err == nil {
+			yamlContent = string(yamlBytes)
+			log.LogDebug("Generated YAML for policy", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(yamlContent)))
+		}
+	}
 
-				cachedPolicy := &redis.BindingPolicyCache{
-					Name:              bp.Name,
-					Namespace:         bp.Namespace,
-					Status:            status,
-					BindingMode:       "Downsync",
-					Clusters:          extractTargetClusters(bp),
-					Workloads:         extractWorkloads(bp),
-					CreationTimestamp: bp.CreationTimestamp.Format("2006-01-02T15:04:05Z"),
-					RawYAML:           existingYAML, // Preserve existing YAML content
-				}
+	cachedPolicy := &redis.BindingPolicyCache{
+		Name:              bp.Name,
+		Namespace:         bp.Namespace,
+		WDSContext:        wdsContext,
+		Status:            status,
+		Conditions:        bp.Status.Conditions,
+		BindingMode:       "Downsync",
+		Clusters:          extractTargetClusters(bp),
+		Workloads:         extractWorkloads(wdsContext, bp),
+		CreationTimestamp: bp.CreationTimestamp.Format("2006-01-02T15:04:05Z"),
+		RawYAML:           yamlContent,
+	}
+	if err := redis.StoreBindingPolicy(cachedPolicy); err != nil {
+		log.LogWarn("failed to update binding policy in cache", zap.String("policyName", bp.Name), zap.Error(err))
+	}
 
-				log.LogInfo("Updating binding policy cache", zap.String("policyName", bp.Name), zap.String("status", status))
-				if err := redis.StoreBindingPolicy(cachedPolicy); err != nil {
-					log.LogWarn("failed to update binding policy in cache", zap.Error(err))
-				} else {
-					log.LogInfo("Successfully updated binding policy cache", zap.String("policyName", bp.Name), zap.String("status", status))
-				}
+	// Resyncs re-deliver unchanged policies, which are already recorded
+	if eventType != PolicyResynced {
+		recordRevision(wdsContext, bp, false, models.BPRevisionExternalAuthor)
+	}
+}
 
-			case "ADDED":
-				bp, _ := event.Object.(*v1alpha1.BindingPolicy)
-				telemetry.BindingPolicyWatchEvents.WithLabelValues("added", "success").Inc()
-				log.LogInfo("BP added: ", zap.String("name", bp.Name))
-
-				//  YAML content from stored policies or generate it
-				yamlContent := ""
-				if storedBP, exists := UICreatedPolicies[bp.Name]; exists && storedBP.RawYAML != "" {
-					yamlContent = storedBP.RawYAML
-					log.LogDebug("Using stored YAML for new policy", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(yamlContent)))
-				} else {
-					// Generate YAML from the binding policy object
-					if yamlBytes, err := yaml.Marshal(bp); err == nil {
-						yamlContent = string(yamlBytes)
-						log.LogDebug("Generated YAML for new policy", zap.String("policyName", bp.Name), zap.Int("yamlLength", len(yamlContent)))
-					}
-				}
+// observeReconciliation records the time from a new policy generation being
+// seen until KubeStellar reports it active
+func observeReconciliation(wdsContext string, bp *v1alpha1.BindingPolicy, status string) {
+	key := wdsContext + "/" + bp.Name
+	reconcileStartLock.Lock()
+	defer reconcileStartLock.Unlock()
 
-				// Add the new binding policy to cache
-				cachedPolicy := &redis.BindingPolicyCache{
-					Name:              bp.Name,
-					Namespace:         bp.Namespace,
-					Status:            "inactive", // New policies start as inactive
-					BindingMode:       "Downsync",
-					Clusters:          extractTargetClusters(bp),
-					Workloads:         extractWorkloads(bp),
-					CreationTimestamp: bp.CreationTimestamp.Format("2006-01-02T15:04:05Z"),
-					RawYAML:           yamlContent, // Use the YAML content we found or generated
-				}
+	if status != "active" {
+		if _, pending := reconcileStart[key]; !pending {
+			reconcileStart[key] = time.Now()
+		}
+		return
+	}
+	if start, pending := reconcileStart[key]; pending {
+		telemetry.BindingPolicyReconciliationDuration.Observe(time.Since(start).Seconds())
+		telemetry.BindingPolicyWatchEvents.WithLabelValues("modified", "reconciled").Inc()
+		delete(reconcileStart, key)
+		log.LogInfo("BP reconciled successfully - updating cache to active", zap.String("name", bp.Name), zap.String("wdsContext", wdsContext))
+	}
+}
 
-				if err := redis.StoreBindingPolicy(cachedPolicy); err != nil {
-					log.LogWarn("failed to cache new binding policy from watch", zap.Error(err))
-				}
+func forgetReconcileStart(wdsContext string, bp *v1alpha1.BindingPolicy) {
+	reconcileStartLock.Lock()
+	defer reconcileStartLock.Unlock()
+	delete(reconcileStart, wdsContext+"/"+bp.Name)
+}
 
-			case "DELETED":
-				bp, _ := event.Object.(*v1alpha1.BindingPolicy)
-				telemetry.BindingPolicyWatchEvents.WithLabelValues("deleted", "success").Inc()
+// pruneBindingPolicyCache removes the cached policies of a WDS that were
+// deleted while its watcher was not running
+func pruneBindingPolicyCache(wdsContext string) {
+	policies, ok := policyWatchers.Policies(wdsContext)
+	if !ok {
+		return
+	}
+	live := make(map[string]bool, len(policies))
+	for _, bp := range policies {
+		live[bp.Name] = true
+	}
 
-				err := redis.DeleteBindingPolicy(bp.Name)
-				if err != nil {
-					log.LogError("Error deleting bp from redis", zap.String("error", err.Error()))
-				}
-				log.LogInfo("BP deleted: ", zap.String("name", bp.Name))
-			case "ERROR":
-				log.LogWarn("Some error occured while watching ON BP")
-			}
+	cached, err := redis.GetAllBindingPolicies(wdsContext)
+	if err != nil {
+		log.LogWarn("failed to read binding policy cache for pruning", zap.String("wdsContext", wdsContext), zap.Error(err))
+		return
+	}
+	for _, policy := range cached {
+		if live[policy.Name] {
+			continue
+		}
+		log.LogInfo("Removing stale binding policy from cache", zap.String("policyName", policy.Name), zap.String("wdsContext", wdsContext))
+		if err := redis.DeleteBindingPolicy(wdsContext, policy.Name); err != nil {
+			log.LogWarn("failed to remove stale binding policy from cache", zap.String("policyName", policy.Name), zap.Error(err))
 		}
-
 	}
 }
 
-// forces a refresh of all binding policies in the cache
+// RefreshBindingPolicyCache rewrites the cache of every watched WDS from the
+// policies its watcher currently holds
 func RefreshBindingPolicyCache() error {
-	log.LogInfo("Refreshing binding policy cache from Kubernetes")
+	log.LogInfo("Refreshing binding policy cache from the policy watchers")
 	start := time.Now()
 	defer func() {
 		telemetry.BindingPolicyOperationDuration.WithLabelValues("cache_refresh").Observe(time.Since(start).Seconds())
 	}()
-	c, err := getClientForBp()
-	if err != nil {
-		log.LogError("failed to create client for cache refresh", zap.Error(err))
-		return err
-	}
-
-	// Get all binding policies from Kubernetes
-	bpList, err := c.BindingPolicies().List(context.TODO(), v1.ListOptions{})
-	if err != nil {
-		log.LogError("failed to list binding policies for cache refresh", zap.Error(err))
-		return err
-	}
-
-	log.LogInfo("Refreshing cache with policies from Kubernetes", zap.Int("policyCount", len(bpList.Items)))
 
-	// Update cache for each policy
-	for _, bp := range bpList.Items {
-		status := "inactive"
-		if bp.ObjectMeta.Generation == bp.Status.ObservedGeneration {
-			status = "active"
-		}
-
-		//  preserve existing YAML content from cache
-		existingYAML := ""
-		if existingPolicy, err := redis.GetBindingPolicy(bp.Name); err == nil && existingPolicy != nil {
-			existingYAML = existingPolicy.RawYAML
-		}
-
-		// don't have existing YAML, generate it from the current BP
-		if existingYAML == "" {
-			if yamlBytes, err := yaml.Marshal(bp); err == nil {
-				existingYAML = string(yamlBytes)
-			}
+	var notSynced []string
+	for _, wdsContext := range policyWatchers.Contexts() {
+		policies, ok := policyWatchers.Policies(wdsContext)
+		if !ok {
+			notSynced = append(notSynced, wdsContext)
+			continue
 		}
-
-		cachedPolicy := &redis.BindingPolicyCache{
-			Name:              bp.Name,
-			Namespace:         bp.Namespace,
-			Status:            status,
-			BindingMode:       "Downsync",
-			Clusters:          extractTargetClusters(&bp),
-			Workloads:         extractWorkloads(&bp),
-			CreationTimestamp: bp.CreationTimestamp.Format("2006-01-02T15:04:05Z"),
-			RawYAML:           existingYAML,
-		}
-
-		log.LogDebug("Refreshing cache for policy",
-			zap.String("policyName", bp.Name),
-			zap.String("status", status),
-			zap.Int64("generation", bp.ObjectMeta.Generation),
-			zap.Int64("observedGeneration", bp.Status.ObservedGeneration))
-
-		if err := redis.StoreBindingPolicy(cachedPolicy); err != nil {
-			log.LogWarn("failed to refresh binding policy in cache", zap.String("policyName", bp.Name), zap.Error(err))
+		for _, bp := range policies {
+			cachePolicyEvent(PolicyResynced, wdsContext, bp)
 		}
+		pruneBindingPolicyCache(wdsContext)
 	}
 
+	if len(notSynced) > 0 {
+		telemetry.BindingPolicyOperationsTotal.WithLabelValues("cache_refresh", "error").Inc()
+		return fmt.Errorf("binding policy watchers not synced for: %s", strings.Join(notSynced, ", "))
+	}
 	log.LogInfo("Completed binding policy cache refresh")
 	telemetry.BindingPolicyOperationsTotal.WithLabelValues("cache_refresh", "success").Inc()
 	return nil
 }
 
 func init() {
-	go watchOnBps()
-
-	// Refresh the cache on startup after a short delay to allow Redis to be ready
-	go func() {
-		time.Sleep(5 * time.Second)
-		if err := RefreshBindingPolicyCache(); err != nil {
-			log.LogWarn("failed to refresh binding policy cache on startup", zap.Error(err))
-		}
-
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			if err := RefreshBindingPolicyCache(); err != nil {
-				log.LogWarn("failed to refresh binding policy cache periodically", zap.Error(err))
-			}
-		}
-	}()
+	policyWatchers = NewPolicyWatchSupervisor(PolicyWatchConfig{
+		Handler:  cachePolicyEvent,
+		OnSynced: pruneBindingPolicyCache,
+	})
+	go policyWatchers.Run(context.Background())
 }
@@ -0,0 +1,504 @@
+package bp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubestellar/kubestellar/api/control/v1alpha1"
+	"github.com/kubestellar/ui/backend/health"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/k8s"
+	"github.com/kubestellar/ui/backend/log"
+	"github.com/kubestellar/ui/backend/wds"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PolicyResynced is the event type of the periodic re-delivery of a policy
+// that has not changed since it was last seen
+const PolicyResynced watch.EventType = "RESYNCED"
+
+// PolicyWatcherComponentPrefix prefixes the name under which each watcher
+// reports its health, followed by the WDS context
+const PolicyWatcherComponentPrefix = "bindingpolicy-watcher/"
+
+// watchErrorGrace is how long a watcher stays degraded after a watch error.
+// The informer retries failed watches well within this time, so a watcher
+// that keeps failing never becomes healthy in between.
+const watchErrorGrace = time.Minute
+
+// PolicyEventHandler is called for every BindingPolicy change seen in a WDS
+type PolicyEventHandler func(eventType watch.EventType, wdsContext string, bp *v1alpha1.BindingPolicy)
+
+// PolicyWatchConfig configures a PolicyWatchSupervisor. Zero values are
+// replaced by the defaults used by the backend.
+type PolicyWatchConfig struct {
+	// ListContexts returns the WDS contexts to watch
+	ListContexts func() ([]string, error)
+	// NewClient returns a client for a WDS context
+	NewClient func(wdsContext string) (dynamic.Interface, error)
+	// Handler receives the policy events of every WDS
+	Handler PolicyEventHandler
+	// OnSynced is called each time the watcher of a WDS has listed all of its
+	// policies, which is when state left over from before can be cleaned up
+	OnSynced func(wdsContext string)
+	// Backoff is the delay between attempts to (re)start a watcher
+	Backoff wait.Backoff
+	// Resync is how often unchanged policies are delivered again
+	Resync time.Duration
+	// SyncTimeout bounds the initial list of a WDS
+	SyncTimeout time.Duration
+	// DiscoveryInterval is how often WDS contexts are listed again
+	DiscoveryInterval time.Duration
+}
+
+// PolicyWatchSupervisor runs a PolicyWatcher for each WDS context, restarts
+// watchers that fail with exponential backoff, and starts and stops watchers
+// as WDS contexts come and go
+type PolicyWatchSupervisor struct {
+	config PolicyWatchConfig
+
+	mu       sync.Mutex
+	watchers map[string]*runningWatcher
+}
+
+type runningWatcher struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	current *PolicyWatcher
+}
+
+// NewPolicyWatchSupervisor returns a supervisor for config
+func NewPolicyWatchSupervisor(config PolicyWatchConfig) *PolicyWatchSupervisor {
+	if config.ListContexts == nil {
+		config.ListContexts = listWDSContexts
+	}
+	if config.NewClient == nil {
+		config.NewClient = newWDSDynamicClient
+	}
+	if config.Backoff.Duration == 0 {
+		config.Backoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.2, Steps: math.MaxInt32, Cap: 5 * time.Minute}
+	}
+	if config.Resync == 0 {
+		config.Resync = 10 * time.Minute
+	}
+	if config.SyncTimeout == 0 {
+		config.SyncTimeout = time.Minute
+	}
+	if config.DiscoveryInterval == 0 {
+		config.DiscoveryInterval = time.Minute
+	}
+	return &PolicyWatchSupervisor{config: config, watchers: map[string]*runningWatcher{}}
+}
+
+// Run watches every WDS until ctx is done
+func (s *PolicyWatchSupervisor) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.DiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.reconcileContexts(ctx)
+		select {
+		case <-ctx.Done():
+			s.stopAll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Contexts returns the WDS contexts currently watched
+func (s *PolicyWatchSupervisor) Contexts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	contexts := make([]string, 0, len(s.watchers))
+	for wdsContext := range s.watchers {
+		contexts = append(contexts, wdsContext)
+	}
+	sort.Strings(contexts)
+	return contexts
+}
+
+// Policies returns the policies currently known in a WDS, and false if the
+// WDS is not watched or its watcher has not synced
+func (s *PolicyWatchSupervisor) Policies(wdsContext string) ([]*v1alpha1.BindingPolicy, bool) {
+	s.mu.Lock()
+	running, ok := s.watchers[wdsContext]
+	var current *PolicyWatcher
+	if ok {
+		current = running.current
+	}
+	s.mu.Unlock()
+
+	if current == nil {
+		return nil, false
+	}
+	return current.Policies()
+}
+
+// reconcileContexts starts watchers for new WDS contexts and stops those of
+// contexts that are gone. Contexts are kept when they cannot be listed.
+func (s *PolicyWatchSupervisor) reconcileContexts(ctx context.Context) {
+	contexts, err := s.config.ListContexts()
+	if err != nil {
+		log.LogWarn("failed to list WDS contexts for binding policy watchers", zap.Error(err))
+		return
+	}
+
+	wanted := make(map[string]bool, len(contexts))
+	for _, wdsContext := range contexts {
+		wanted[wdsContext] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for wdsContext := range wanted {
+		if _, ok := s.watchers[wdsContext]; ok {
+			continue
+		}
+		watcherCtx, cancel := context.WithCancel(ctx)
+		running := &runningWatcher{cancel: cancel, done: make(chan struct{})}
+		s.watchers[wdsContext] = running
+		log.LogInfo("starting binding policy watcher", zap.String("wdsContext", wdsContext))
+		go s.supervise(watcherCtx, wdsContext, running)
+	}
+
+	for wdsContext, running := range s.watchers {
+		if wanted[wdsContext] {
+			continue
+		}
+		log.LogInfo("stopping binding policy watcher", zap.String("wdsContext", wdsContext))
+		running.cancel()
+		delete(s.watchers, wdsContext)
+	}
+}
+
+func (s *PolicyWatchSupervisor) stopAll() {
+	s.mu.Lock()
+	watchers := s.watchers
+	s.watchers = map[string]*runningWatcher{}
+	s.mu.Unlock()
+
+	for _, running := range watchers {
+		running.cancel()
+		<-running.done
+	}
+}
+
+// supervise runs the watcher of one WDS, starting a new one with increasing
+// delay each time it cannot be started or fails to sync
+func (s *PolicyWatchSupervisor) supervise(ctx context.Context, wdsContext string, running *runningWatcher) {
+	defer close(running.done)
+	defer health.RemoveComponent(PolicyWatcherComponentPrefix + wdsContext)
+
+	backoff := s.config.Backoff
+	restarts := 0
+	for {
+		err := s.runWatcher(ctx, wdsContext, running, restarts, func() {
+			// The watcher synced, so the next failure starts a fresh backoff
+			backoff = s.config.Backoff
+			if s.config.OnSynced != nil {
+				s.config.OnSynced(wdsContext)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := backoff.Step()
+		restarts++
+		log.LogWarn("binding policy watcher stopped, restarting",
+			zap.String("wdsContext", wdsContext),
+			zap.Error(err),
+			zap.Duration("retryIn", delay),
+			zap.Int("restarts", restarts))
+		health.ReportComponent(PolicyWatcherComponentPrefix+wdsContext, health.ComponentHealth{
+			Status:  "degraded",
+			Message: fmt.Sprintf("watcher restarting in %s", delay.Round(time.Second)),
+			Error:   err.Error(),
+			Metadata: map[string]interface{}{
+				"wds_context": wdsContext,
+				"synced":      false,
+				"restarts":    restarts,
+			},
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *PolicyWatchSupervisor) runWatcher(ctx context.Context, wdsContext string, running *runningWatcher, restarts int, onSynced func()) error {
+	client, err := s.config.NewClient(wdsContext)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+
+	watcher := NewPolicyWatcher(wdsContext, client, s.config.Handler)
+	watcher.resync = s.config.Resync
+	watcher.syncTimeout = s.config.SyncTimeout
+	watcher.restarts = restarts
+	watcher.onSynced = onSynced
+
+	s.mu.Lock()
+	running.current = watcher
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		running.current = nil
+		s.mu.Unlock()
+	}()
+
+	return watcher.Run(ctx)
+}
+
+// PolicyWatcher keeps a shared informer on the BindingPolicies of one WDS.
+// The informer resumes interrupted watches from the last resource version it
+// saw and relists when that version has expired, delivering the changes it
+// missed as regular events.
+type PolicyWatcher struct {
+	wdsContext  string
+	client      dynamic.Interface
+	handler     PolicyEventHandler
+	resync      time.Duration
+	syncTimeout time.Duration
+	restarts    int
+	onSynced    func()
+
+	mu          sync.Mutex
+	informer    cache.SharedIndexInformer
+	synced      bool
+	lastEvent   time.Time
+	lastError   string
+	lastErrorAt time.Time
+	watchErrors int
+}
+
+// NewPolicyWatcher returns a watcher on the policies of wdsContext that calls
+// handler for every change
+func NewPolicyWatcher(wdsContext string, client dynamic.Interface, handler PolicyEventHandler) *PolicyWatcher {
+	return &PolicyWatcher{
+		wdsContext:  wdsContext,
+		client:      client,
+		handler:     handler,
+		resync:      10 * time.Minute,
+		syncTimeout: time.Minute,
+	}
+}
+
+// Run watches until ctx is done. It returns early with an error only when the
+// initial list does not complete within the sync timeout.
+func (w *PolicyWatcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(w.client, w.resync, metav1.NamespaceAll, nil)
+	informer := factory.ForResource(bindingPolicyGVR).Informer()
+
+	if err := informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *cache.Reflector, err error) {
+		w.recordWatchError(err)
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	}); err != nil {
+		return err
+	}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.deliver(watch.Added, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldMeta, oldErr := metaAccessor(oldObj)
+			newMeta, newErr := metaAccessor(newObj)
+			if oldErr == nil && newErr == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				w.deliver(PolicyResynced, newObj)
+				return
+			}
+			w.deliver(watch.Modified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			// Deletions missed while the watch was down arrive after the
+			// relist with only the last known state
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			w.deliver(watch.Deleted, obj)
+		},
+	}); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.informer = informer
+	w.mu.Unlock()
+	w.report()
+
+	go informer.RunWithContext(ctx)
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, w.syncTimeout)
+	synced := cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced)
+	syncCancel()
+	if !synced {
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.mu.Lock()
+		lastError := w.lastError
+		w.mu.Unlock()
+		if lastError != "" {
+			return fmt.Errorf("initial list did not complete within %s: %s", w.syncTimeout, lastError)
+		}
+		return fmt.Errorf("initial list did not complete within %s", w.syncTimeout)
+	}
+
+	w.mu.Lock()
+	w.synced = true
+	w.mu.Unlock()
+	log.LogInfo("binding policy watcher synced",
+		zap.String("wdsContext", w.wdsContext),
+		zap.Int("policies", len(informer.GetStore().ListKeys())))
+	if w.onSynced != nil {
+		w.onSynced()
+	}
+	w.report()
+
+	ticker := time.NewTicker(watchErrorGrace / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.report()
+		}
+	}
+}
+
+// Policies returns the policies in the informer's cache, and false before the
+// informer has synced
+func (w *PolicyWatcher) Policies() ([]*v1alpha1.BindingPolicy, bool) {
+	w.mu.Lock()
+	informer, synced := w.informer, w.synced
+	w.mu.Unlock()
+	if informer == nil || !synced {
+		return nil, false
+	}
+
+	policies := []*v1alpha1.BindingPolicy{}
+	for _, obj := range informer.GetStore().List() {
+		if bp, err := toBindingPolicy(obj); err == nil {
+			policies = append(policies, bp)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies, true
+}
+
+func (w *PolicyWatcher) deliver(eventType watch.EventType, obj interface{}) {
+	bp, err := toBindingPolicy(obj)
+	if err != nil {
+		log.LogWarn("ignoring binding policy event", zap.String("wdsContext", w.wdsContext), zap.Error(err))
+		return
+	}
+
+	w.mu.Lock()
+	w.lastEvent = time.Now()
+	w.mu.Unlock()
+
+	if w.handler != nil {
+		w.handler(eventType, w.wdsContext, bp)
+	}
+}
+
+func (w *PolicyWatcher) recordWatchError(err error) {
+	w.mu.Lock()
+	w.lastError = err.Error()
+	w.lastErrorAt = time.Now()
+	w.watchErrors++
+	w.mu.Unlock()
+
+	log.LogWarn("binding policy watch failed, resuming", zap.String("wdsContext", w.wdsContext), zap.Error(err))
+	w.report()
+}
+
+// report publishes the state of the watcher to the health package
+func (w *PolicyWatcher) report() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	metadata := map[string]interface{}{
+		"wds_context":  w.wdsContext,
+		"synced":       w.synced,
+		"restarts":     w.restarts,
+		"watch_errors": w.watchErrors,
+	}
+	if w.informer != nil && w.synced {
+		metadata["policies"] = len(w.informer.GetStore().ListKeys())
+	}
+	if !w.lastEvent.IsZero() {
+		metadata["last_event"] = w.lastEvent.UTC().Format(time.RFC3339)
+	}
+
+	component := health.ComponentHealth{Status: "healthy", Message: "watching binding policies", Metadata: metadata}
+	switch {
+	case !w.synced:
+		component.Status = "degraded"
+		component.Message = "waiting for initial list"
+		component.Error = w.lastError
+	case !w.lastErrorAt.IsZero() && time.Since(w.lastErrorAt) < watchErrorGrace:
+		component.Status = "degraded"
+		component.Message = "watch interrupted, resuming"
+		component.Error = w.lastError
+	}
+	health.ReportComponent(PolicyWatcherComponentPrefix+w.wdsContext, component)
+}
+
+func metaAccessor(obj interface{}) (metav1.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	return u, nil
+}
+
+func toBindingPolicy(obj interface{}) (*v1alpha1.BindingPolicy, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	bp := &v1alpha1.BindingPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, bp); err != nil {
+		return nil, fmt.Errorf("failed to convert binding policy %s: %v", u.GetName(), err)
+	}
+	return bp, nil
+}
+
+// listWDSContexts returns the WDS contexts of the kubeconfig, or the default
+// one when none can be found
+func listWDSContexts() ([]string, error) {
+	_, contexts, err := wds.ListContexts()
+	if err != nil || len(contexts) == 0 {
+		log.LogDebug("no WDS contexts listed, watching the default one", zap.Error(err))
+		return []string{hub.DefaultWDS()}, nil
+	}
+	return contexts, nil
+}
+
+func newWDSDynamicClient(wdsContext string) (dynamic.Interface, error) {
+	_, client, err := k8s.GetClientSetWithContext(wdsContext)
+	return client, err
+}
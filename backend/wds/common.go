@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -41,16 +42,15 @@ func getKubeConfig() (*api.Config, error) {
 	return config, nil
 }
 
-// only for wds1
-func GetClientSetKubeConfig() (*kubernetes.Clientset, error) {
+// GetClientSetKubeConfig returns the clientset of a WDS
+func GetClientSetKubeConfig(wdsContext string) (*kubernetes.Clientset, error) {
 	config, err := getKubeConfig()
 	if err != nil {
 		// c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load kubeconfig"})
 		return nil, fmt.Errorf("failed to load kubeconfig")
 	}
 
-	// Use WDS1 context specifically
-	ctxContext := config.Contexts["wds1"]
+	ctxContext := config.Contexts[wdsContext]
 	if ctxContext == nil {
 		// c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ctxConfig"})
 		return nil, fmt.Errorf("failed to create ctxConfig")
@@ -60,7 +60,7 @@ func GetClientSetKubeConfig() (*kubernetes.Clientset, error) {
 	clientConfig := clientcmd.NewDefaultClientConfig(
 		*config,
 		&clientcmd.ConfigOverrides{
-			CurrentContext: "wds1",
+			CurrentContext: wdsContext,
 		},
 	)
 
@@ -87,11 +87,11 @@ func ListContexts() (string, []string, error) {
 	// Get the current context
 	currentContext := config.CurrentContext
 
-	// Filter contexts that contain "wds"
+	// The WDS contexts are those of the WDSes in the hub registry
 	var wdsContexts []string
-	for name, _ := range config.Contexts {
-		if strings.Contains(name, "wds") {
-			wdsContexts = append(wdsContexts, name)
+	for _, space := range hub.SpacesOfType(hub.WDS) {
+		if _, ok := config.Contexts[space.Context]; ok {
+			wdsContexts = append(wdsContexts, space.Context)
 		}
 	}
 
@@ -130,20 +130,7 @@ func SetWdsContextCookies(c *gin.Context) {
 		return
 	}
 
-	_, context, err := ListContexts()
-	if err != nil {
-		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/wds/context", "500").Inc()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	isContextPresent := false
-	for _, value := range context {
-		if strings.EqualFold(request.Context, value) {
-			isContextPresent = true
-			break
-		}
-	}
-	if !isContextPresent {
+	if _, err := hub.Resolve(hub.WDS, request.Context); err != nil || request.Context == "" {
 		telemetry.HTTPErrorCounter.WithLabelValues("POST", "/wds/context", "404").Inc()
 		msg := fmt.Sprintf("no context with %s present", request.Context)
 		c.JSON(http.StatusOK, gin.H{
@@ -178,7 +165,7 @@ func GetWdsContextCookies(c *gin.Context) {
 		if strings.Contains("wds", currentContext) {
 			cookieContext = currentContext // Default to Kubernetes API context
 		} else {
-			cookieContext = "wds1"
+			cookieContext = hub.DefaultWDS()
 		}
 	}
 	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/wds/context", "200").Inc()
@@ -304,5 +291,8 @@ func CreateWDSContextUsingCommand(w http.ResponseWriter, r *http.Request, c *gin
 	}
 
 	writeMessage(conn, fmt.Sprintf("Context '%s' set successfully:\n%s\n", newWdsContext, string(kflexOutput)))
-
+	// Register the new WDS right away instead of waiting for the registry to expire
+	if _, err := hub.Refresh(); err != nil {
+		log.Printf("Failed to refresh hub registry: %v", err)
+	}
 }
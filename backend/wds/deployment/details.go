@@ -13,8 +13,12 @@ import (
 	"github.com/kubestellar/ui/backend/k8s"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
+	"github.com/kubestellar/ui/backend/log"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"github.com/kubestellar/ui/backend/wds"
+	"github.com/kubestellar/ui/backend/wds/bp"
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -34,7 +38,11 @@ func GetDeploymentByName(c *gin.Context) {
 		namespace = "default" // Use "default" namespace if not provided
 	}
 
-	clientset, err := wds.GetClientSetKubeConfig()
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
+	}
+	clientset, err := wds.GetClientSetKubeConfig(space.Context)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/wds/"+name, "400").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -60,23 +68,62 @@ func GetDeploymentByName(c *gin.Context) {
 	if len(deployment.Status.Conditions) > 0 {
 		status["conditions"] = deployment.Status.Conditions
 	}
-	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/wds/"+name, "200").Inc()
-	telemetry.HTTPRequestDuration.WithLabelValues("GET", "/api/wds/"+name).Observe(time.Since(startTime).Seconds())
-	c.JSON(http.StatusOK, gin.H{
+
+	response := gin.H{
 		"apiVersion": deployment.APIVersion,
 		"kind":       deployment.Kind,
 		"metadata":   deployment.ObjectMeta,
 		"spec":       deployment.Spec,
 		"status":     status,
-	})
+	}
+
+	// When a single WEC reports its status back, the WDS object carries the
+	// full status of the deployment running there
+	if singleton := singletonStatus(c.Request.Context(), space.Context, namespace, name); singleton != nil {
+		response["singletonStatus"] = singleton
+		if singleton.Reported {
+			status["replicas"] = deployment.Status.Replicas
+			status["readyReplicas"] = deployment.Status.ReadyReplicas
+			status["updatedReplicas"] = deployment.Status.UpdatedReplicas
+			status["observedGeneration"] = deployment.Status.ObservedGeneration
+			status["reportedBy"] = singleton.Cluster
+		}
+	}
+
+	telemetry.TotalHTTPRequests.WithLabelValues("GET", "/api/wds/"+name, "200").Inc()
+	telemetry.HTTPRequestDuration.WithLabelValues("GET", "/api/wds/"+name).Observe(time.Since(startTime).Seconds())
+	c.JSON(http.StatusOK, response)
+}
+
+// singletonStatus reports whether the status of a deployment in the WDS comes
+// from a single WEC. It returns nil if that cannot be determined.
+func singletonStatus(ctx context.Context, wdsContext, namespace, name string) *bp.SingletonStatus {
+	_, client, err := k8s.GetClientSetWithContext(wdsContext)
+	if err != nil {
+		log.LogWarn("failed to connect to WDS for singleton status", zap.Error(err))
+		return nil
+	}
+	status, err := bp.GetSingletonStatus(ctx, client, bp.PreviewObject{
+		Group:     "apps",
+		Version:   "v1",
+		Resource:  "deployments",
+		Namespace: namespace,
+		Name:      name,
+	}, true)
+	if err != nil {
+		log.LogWarn("failed to get singleton status", zap.String("deployment", name), zap.Error(err))
+		return nil
+	}
+	return status
 }
 
 func GetWDSWorkloads(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
 	startTime := time.Now()
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, _, err := k8s.GetClientSetWithContext(cookieContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/wds/workloads", "400").Inc()
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"github.com/kubestellar/ui/backend/wds"
 	v1 "k8s.io/api/apps/v1"
@@ -49,7 +50,14 @@ func HandleDeploymentLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientset, err := wds.GetClientSetKubeConfig()
+	space, err := hub.SelectWDSFromRequest(r)
+	if err != nil {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("Error: "+err.Error())); err != nil {
+			log.Printf("Failed to send WebSocket message: %v", err)
+		}
+		return
+	}
+	clientset, err := wds.GetClientSetKubeConfig(space.Context)
 	if err != nil {
 		if err := conn.WriteMessage(websocket.TextMessage, []byte("Error: Failed to create Kubernetes clientset - "+err.Error())); err != nil {
 			log.Printf("Failed to send WebSocket message: %v", err)
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/telemetry"
 	"github.com/kubestellar/ui/backend/wds"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,7 +15,11 @@ import (
 
 // Get deployment status by name
 func GetDeploymentStatus(c *gin.Context) {
-	clientset, err := wds.GetClientSetKubeConfig()
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
+	}
+	clientset, err := wds.GetClientSetKubeConfig(space.Context)
 	startTime := time.Now()
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/wds/status", "500").Inc()
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubestellar/ui/backend/hub"
 	"github.com/kubestellar/ui/backend/k8s"
 	"github.com/kubestellar/ui/backend/redis"
 	"github.com/kubestellar/ui/backend/telemetry"
@@ -41,10 +42,11 @@ type ResourceListResponse struct {
 
 // ListAllResourcesByNamespace api/wds/list/:namespace
 func ListAllResourcesByNamespace(c *gin.Context) {
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	clientset, dynamicClient, err := k8s.GetClientSetWithContext(cookieContext)
 	if err != nil {
 		telemetry.HTTPErrorCounter.WithLabelValues("GET", "/api/wds/list/:namespace", "500").Inc()
@@ -144,10 +146,11 @@ func ListAllResourcesDetailsSSE(c *gin.Context) {
 		c.Writer.(http.Flusher).Flush()
 	}
 
-	cookieContext, err := c.Cookie("ui-wds-context")
-	if err != nil {
-		cookieContext = "wds1"
+	space, ok := hub.MustSelectWDS(c)
+	if !ok {
+		return
 	}
+	cookieContext := space.Context
 	cacheKey := getCacheKey(cookieContext, "list")
 	result := ResourceListResponse{
 		Namespaced:    make(map[string]map[string][]map[string]interface{}),